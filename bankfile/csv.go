@@ -0,0 +1,161 @@
+package bankfile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CSVColumn is one column of a CSV layout: Field names the payment value it holds, and Value is
+// the text of a "constant" column.
+type CSVColumn struct {
+	Header string `json:"header"`
+	Field  string `json:"field"`
+	Value  string `json:"value,omitempty"`
+}
+
+// CSVLayout describes the CSV file a bank expects.
+type CSVLayout struct {
+	// Delimiter is a single character, "," by default.
+	Delimiter string `json:"delimiter"`
+	// NoHeader leaves out the header line.
+	NoHeader bool `json:"no_header"`
+	// DateFormat is YYYY-MM-DD (the default), DD/MM/YYYY, MM/DD/YYYY, DD.MM.YYYY or YYYYMMDD.
+	DateFormat string `json:"date_format"`
+	// DecimalComma writes amounts as 1234,56.
+	DecimalComma bool        `json:"decimal_comma"`
+	Columns      []CSVColumn `json:"columns"`
+}
+
+var csvDateFormats = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"DD/MM/YYYY": "02/01/2006",
+	"MM/DD/YYYY": "01/02/2006",
+	"DD.MM.YYYY": "02.01.2006",
+	"YYYYMMDD":   "20060102",
+}
+
+// CSVFields lists the values a CSV column can hold.
+var CSVFields = []string{
+	"reference", "payee_id", "name", "bank_name", "account", "routing", "amount", "amount_cents",
+	"currency", "pay_date", "description", "company_name", "company_account", "constant",
+}
+
+// DefaultCSVLayout is used when a CSV profile has no layout of its own.
+func DefaultCSVLayout() *CSVLayout {
+	return &CSVLayout{Delimiter: ",", DateFormat: "YYYY-MM-DD", Columns: []CSVColumn{
+		{Header: "Reference", Field: "reference"},
+		{Header: "Employee", Field: "payee_id"},
+		{Header: "Name", Field: "name"},
+		{Header: "Bank", Field: "bank_name"},
+		{Header: "Account", Field: "account"},
+		{Header: "Routing", Field: "routing"},
+		{Header: "Amount", Field: "amount"},
+		{Header: "Currency", Field: "currency"},
+		{Header: "Date", Field: "pay_date"},
+	}}
+}
+
+// ParseCSVLayout validates a layout and fills in its defaults; an empty one is the default
+// layout.
+func ParseCSVLayout(raw json.RawMessage) (*CSVLayout, error) {
+	if len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return DefaultCSVLayout(), nil
+	}
+	var l CSVLayout
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&l); err != nil {
+		return nil, fmt.Errorf("invalid CSV layout: %w", err)
+	}
+	if l.Delimiter == "" {
+		l.Delimiter = ","
+	}
+	if r, size := utf8.DecodeRuneInString(l.Delimiter); size != len(l.Delimiter) || r == '"' || r == '\r' || r == '\n' {
+		return nil, errors.New("delimiter must be a single character other than a quote or line break")
+	}
+	if l.DateFormat == "" {
+		l.DateFormat = "YYYY-MM-DD"
+	}
+	if _, ok := csvDateFormats[l.DateFormat]; !ok {
+		return nil, fmt.Errorf("unsupported date_format %q", l.DateFormat)
+	}
+	if len(l.Columns) == 0 {
+		return nil, errors.New("a CSV layout needs at least one column")
+	}
+	for i, c := range l.Columns {
+		known := false
+		for _, f := range CSVFields {
+			known = known || c.Field == f
+		}
+		if !known {
+			return nil, fmt.Errorf("column %d: unknown field %q; use one of %s", i+1, c.Field, strings.Join(CSVFields, ", "))
+		}
+	}
+	return &l, nil
+}
+
+func writeCSV(b *Batch, l *CSVLayout) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma, _ = utf8.DecodeRuneInString(l.Delimiter)
+	if !l.NoHeader {
+		header := make([]string, len(l.Columns))
+		for i, c := range l.Columns {
+			header[i] = c.Header
+		}
+		if err := w.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	o := b.Originator
+	for _, p := range b.Payments {
+		amount := cents(p.Amount)
+		row := make([]string, len(l.Columns))
+		for i, c := range l.Columns {
+			switch c.Field {
+			case "reference":
+				row[i] = p.Reference
+			case "payee_id":
+				row[i] = p.PayeeID
+			case "name":
+				row[i] = p.Name
+			case "bank_name":
+				row[i] = p.BankName
+			case "account":
+				row[i] = Normalize(p.Account)
+			case "routing":
+				row[i] = Normalize(p.Routing)
+			case "amount":
+				row[i] = amountText(amount)
+				if l.DecimalComma {
+					row[i] = strings.Replace(row[i], ".", ",", 1)
+				}
+			case "amount_cents":
+				row[i] = strconv.FormatInt(amount, 10)
+			case "currency":
+				row[i] = o.Currency
+			case "pay_date":
+				row[i] = b.PayDate.Format(csvDateFormats[l.DateFormat])
+			case "description":
+				row[i] = b.Description
+			case "company_name":
+				row[i] = o.Name
+			case "company_account":
+				row[i] = Normalize(o.Account)
+			case "constant":
+				row[i] = c.Value
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
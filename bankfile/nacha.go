@@ -0,0 +1,93 @@
+package bankfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NACHA records are 94 characters; files are padded with lines of nines to a multiple of the
+// blocking factor of ten.
+const (
+	nachaRecordSize = 94
+	nachaBlocking   = 10
+)
+
+// alpha left-justifies upper case text in a field of n characters.
+func alpha(s string, n int) string {
+	return fmt.Sprintf("%-*s", n, truncate(strings.ToUpper(ascii(s)), n))
+}
+
+// numeric right-justifies v with zeros in a field of n digits, keeping its low digits.
+func numeric(v int64, n int) string {
+	s := fmt.Sprintf("%0*d", n, v)
+	return s[len(s)-n:]
+}
+
+// writeNACHA writes a PPD credit batch (service class 220, transaction code 22 to checking
+// accounts) without addenda.
+func writeNACHA(b *Batch) ([]byte, error) {
+	o := b.Originator
+	odfi := Normalize(o.Routing)[:8]
+	var lines []string
+	lines = append(lines, "1"+"01"+
+		" "+Normalize(o.Routing)+
+		fmt.Sprintf("%10s", truncate(Normalize(o.ID), 10))+
+		b.CreatedAt.Format("060102")+b.CreatedAt.Format("1504")+
+		"A"+"094"+"10"+"1"+
+		alpha(o.BankName, 23)+
+		alpha(o.Name, 23)+
+		alpha(b.ID, 8))
+	lines = append(lines, "5"+"220"+
+		alpha(o.Name, 16)+
+		alpha("", 20)+
+		alpha(o.ID, 10)+
+		"PPD"+
+		alpha("PAYROLL", 10)+
+		alpha(b.PayDate.Format("Jan 06"), 6)+
+		b.PayDate.Format("060102")+
+		"   "+"1"+odfi+numeric(1, 7))
+
+	var hash, credit int64
+	for i, p := range b.Payments {
+		routing := Normalize(p.Routing)
+		rdfi, _ := strconv.ParseInt(routing[:8], 10, 64)
+		hash += rdfi
+		amount := cents(p.Amount)
+		credit += amount
+		lines = append(lines, "6"+"22"+
+			routing+
+			fmt.Sprintf("%-17s", Normalize(p.Account))+
+			numeric(amount, 10)+
+			alpha(p.PayeeID, 15)+
+			alpha(p.Name, 22)+
+			"  "+"0"+
+			odfi+numeric(int64(i+1), 7))
+	}
+	entries := int64(len(b.Payments))
+	lines = append(lines, "8"+"220"+
+		numeric(entries, 6)+
+		numeric(hash, 10)+
+		numeric(0, 12)+numeric(credit, 12)+
+		alpha(o.ID, 10)+
+		strings.Repeat(" ", 19)+strings.Repeat(" ", 6)+
+		odfi+numeric(1, 7))
+	records := len(lines) + 1
+	blocks := (records + nachaBlocking - 1) / nachaBlocking
+	lines = append(lines, "9"+
+		numeric(1, 6)+
+		numeric(int64(blocks), 6)+
+		numeric(entries, 8)+
+		numeric(hash, 10)+
+		numeric(0, 12)+numeric(credit, 12)+
+		strings.Repeat(" ", 39))
+	for len(lines)%nachaBlocking != 0 {
+		lines = append(lines, strings.Repeat("9", nachaRecordSize))
+	}
+	for i, l := range lines {
+		if len(l) != nachaRecordSize {
+			return nil, fmt.Errorf("nacha: record %d has %d characters", i+1, len(l))
+		}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
@@ -0,0 +1,163 @@
+package bankfile
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+const sepaNamespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"
+
+type sepaDocument struct {
+	XMLName xml.Name       `xml:"Document"`
+	Xmlns   string         `xml:"xmlns,attr"`
+	Init    sepaInitiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type sepaInitiation struct {
+	GrpHdr sepaGroupHeader
+	PmtInf sepaPaymentInfo
+}
+
+type sepaGroupHeader struct {
+	MsgId    string
+	CreDtTm  string
+	NbOfTxs  int
+	CtrlSum  string
+	InitgPty sepaParty
+}
+
+type sepaParty struct {
+	Nm string
+	ID *sepaOther `xml:"Id>OrgId>Othr,omitempty"`
+}
+
+// sepaOther is an identification other than a BIC. Optional elements are pointers, as
+// omitempty on a path like Id>OrgId>Othr>Id would still write the empty parents.
+type sepaOther struct {
+	Id string
+}
+
+// sepaAgent is a bank by BIC; without one, the IBAN-only form NOTPROVIDED is used.
+type sepaAgent struct {
+	BIC   string     `xml:"FinInstnId>BIC,omitempty"`
+	Other *sepaOther `xml:"FinInstnId>Othr,omitempty"`
+}
+
+type sepaAmount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+type sepaPaymentInfo struct {
+	PmtInfId    string
+	PmtMtd      string
+	BtchBookg   bool
+	NbOfTxs     int
+	CtrlSum     string
+	SvcLvl      string `xml:"PmtTpInf>SvcLvl>Cd"`
+	CtgyPurp    string `xml:"PmtTpInf>CtgyPurp>Cd"`
+	ReqdExctnDt string
+	Dbtr        sepaParty
+	DbtrAcct    string `xml:"DbtrAcct>Id>IBAN"`
+	DbtrAgt     sepaAgent
+	ChrgBr      string
+	Txs         []sepaTransaction `xml:"CdtTrfTxInf"`
+}
+
+type sepaTransaction struct {
+	EndToEndId string     `xml:"PmtId>EndToEndId"`
+	Amt        sepaAmount `xml:"Amt>InstdAmt"`
+	CdtrAgt    *sepaAgent `xml:"CdtrAgt,omitempty"`
+	Cdtr       string     `xml:"Cdtr>Nm"`
+	CdtrAcct   string     `xml:"CdtrAcct>Id>IBAN"`
+	Purp       string     `xml:"Purp>Cd"`
+	Ustrd      string     `xml:"RmtInf>Ustrd,omitempty"`
+}
+
+// sepaText restricts s to the SEPA character set (Latin letters, digits and / - ? : ( ) . , ' +
+// and space) and n characters.
+func sepaText(s string, n int) string {
+	s = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/-?:().,'+ ", r) {
+			return r
+		}
+		return ' '
+	}, ascii(s))
+	return strings.TrimSpace(truncate(s, n))
+}
+
+func amountText(c int64) string { return strconv.FormatFloat(float64(c)/100, 'f', 2, 64) }
+
+func agent(bic string) sepaAgent {
+	if bic = Normalize(bic); bic != "" {
+		return sepaAgent{BIC: bic}
+	}
+	return sepaAgent{Other: &sepaOther{Id: "NOTPROVIDED"}}
+}
+
+func party(name, id string) sepaParty {
+	p := sepaParty{Nm: sepaText(name, 70)}
+	if id = sepaText(id, 35); id != "" {
+		p.ID = &sepaOther{Id: id}
+	}
+	return p
+}
+
+// writeSEPA writes a pain.001.001.03 customer credit transfer initiation with one batch-booked
+// payment of category SALA, so that banks keep the individual salaries off the company's
+// statement.
+func writeSEPA(b *Batch) ([]byte, error) {
+	o := b.Originator
+	id := sepaText(b.ID, 35)
+	var total int64
+	txs := make([]sepaTransaction, len(b.Payments))
+	for i, p := range b.Payments {
+		c := cents(p.Amount)
+		total += c
+		txs[i] = sepaTransaction{
+			EndToEndId: sepaText(p.Reference, 35),
+			Amt:        sepaAmount{Ccy: "EUR", Value: amountText(c)},
+			Cdtr:       sepaText(p.Name, 70),
+			CdtrAcct:   Normalize(p.Account),
+			Purp:       "SALA",
+			Ustrd:      sepaText(b.Description, 140),
+		}
+		if p.Routing != "" {
+			a := agent(p.Routing)
+			txs[i].CdtrAgt = &a
+		}
+	}
+	doc := sepaDocument{
+		Xmlns: sepaNamespace,
+		Init: sepaInitiation{
+			GrpHdr: sepaGroupHeader{
+				MsgId:    id,
+				CreDtTm:  b.CreatedAt.UTC().Format("2006-01-02T15:04:05"),
+				NbOfTxs:  len(txs),
+				CtrlSum:  amountText(total),
+				InitgPty: party(o.Name, o.ID),
+			},
+			PmtInf: sepaPaymentInfo{
+				PmtInfId:    id,
+				PmtMtd:      "TRF",
+				BtchBookg:   true,
+				NbOfTxs:     len(txs),
+				CtrlSum:     amountText(total),
+				SvcLvl:      "SEPA",
+				CtgyPurp:    "SALA",
+				ReqdExctnDt: b.PayDate.Format("2006-01-02"),
+				Dbtr:        party(o.Name, ""),
+				DbtrAcct:    Normalize(o.Account),
+				DbtrAgt:     agent(o.Routing),
+				ChrgBr:      "SLEV",
+				Txs:         txs,
+			},
+		},
+	}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
@@ -0,0 +1,78 @@
+package bankfile
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ibanPattern    = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+	bicPattern     = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	routingPattern = regexp.MustCompile(`^[0-9]{9}$`)
+	// nachaAccount is what fits the 17-character DFI account number field.
+	nachaAccount = regexp.MustCompile(`^[0-9A-Z-]{1,17}$`)
+)
+
+// ibanLengths holds the IBAN length of the SEPA countries and a few others; IBANs of other
+// countries are only checked against the general format.
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AT": 20, "BE": 16, "BG": 22, "CH": 21, "CY": 28, "CZ": 24, "DE": 22,
+	"DK": 18, "EE": 20, "ES": 24, "FI": 18, "FR": 27, "GB": 22, "GI": 23, "GR": 27, "HR": 21,
+	"HU": 28, "IE": 22, "IL": 23, "IS": 26, "IT": 27, "LI": 21, "LT": 20, "LU": 20, "LV": 21,
+	"MC": 27, "MT": 31, "NL": 18, "NO": 15, "PL": 28, "PT": 25, "RO": 24, "SA": 24, "SE": 24,
+	"SI": 19, "SK": 24, "SM": 27, "TR": 26, "VA": 22,
+}
+
+// Normalize uppercases an account, routing code or IBAN and drops spaces.
+func Normalize(v string) string { return strings.ToUpper(strings.ReplaceAll(v, " ", "")) }
+
+// ValidateIBAN checks an IBAN's format, its length for the country and its ISO 7064 mod 97
+// check digits.
+func ValidateIBAN(iban string) error {
+	iban = Normalize(iban)
+	if !ibanPattern.MatchString(iban) {
+		return errors.New("IBAN must be a country code, two check digits and 11 to 30 letters or digits")
+	}
+	if n, ok := ibanLengths[iban[:2]]; ok && len(iban) != n {
+		return fmt.Errorf("%s IBANs have %d characters", iban[:2], n)
+	}
+	rem := 0
+	for _, c := range iban[4:] + iban[:4] {
+		if c >= 'A' {
+			rem = (rem*100 + int(c-'A') + 10) % 97
+		} else {
+			rem = (rem*10 + int(c-'0')) % 97
+		}
+	}
+	if rem != 1 {
+		return errors.New("IBAN check digits do not match")
+	}
+	return nil
+}
+
+// ValidateBIC checks the format of a BIC (SWIFT code) of 8 or 11 characters.
+func ValidateBIC(bic string) error {
+	if !bicPattern.MatchString(Normalize(bic)) {
+		return errors.New("BIC must have 8 or 11 letters and digits")
+	}
+	return nil
+}
+
+// ValidateABA checks a US ABA routing number: nine digits whose weighted sum with weights
+// 3, 7, 1 is a multiple of ten.
+func ValidateABA(routing string) error {
+	routing = Normalize(routing)
+	if !routingPattern.MatchString(routing) {
+		return errors.New("routing number must have 9 digits")
+	}
+	sum := 0
+	for i, c := range routing {
+		sum += int(c-'0') * [3]int{3, 7, 1}[i%3]
+	}
+	if sum%10 != 0 {
+		return errors.New("routing number check digit does not match")
+	}
+	return nil
+}
@@ -29,7 +29,7 @@ func Connect() (*gorm.DB, error) {
 		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC", host, user, pass, name, port)
 
 		var db *gorm.DB
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 		if err != nil {
 			return
 		}
@@ -57,10 +57,248 @@ func GetDB() *gorm.DB {
 }
 
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		&models.User{},
 		&models.Employee{},
+		&models.EmergencyContact{},
+		&models.ProfileChangeRequest{},
+		&models.JobRecord{},
+		&models.Department{},
+		&models.Location{},
+		&models.JobGrade{},
+		&models.Position{},
 		&models.Attendance{},
 		&models.Leave{},
-	)
+		&models.LeaveAttachment{},
+		&models.LeaveComment{},
+		&models.LeaveAttachmentPolicy{},
+		&models.LeaveEntitlement{},
+		&models.LeaveBlackout{},
+		&models.StaffingRule{},
+		&models.ChecklistTemplate{},
+		&models.ChecklistTemplateItem{},
+		&models.Checklist{},
+		&models.ChecklistTask{},
+		&models.Notification{},
+		&models.DocumentCategory{},
+		&models.Document{},
+		&models.DocumentVersion{},
+		&models.CustomFieldDefinition{},
+		&models.PayPeriod{},
+		&models.PayrollRun{},
+		&models.PayrollLine{},
+		&models.DeductionRule{},
+		&models.OvertimeEntry{},
+		&models.SalaryStructure{},
+		&models.SalaryComponent{},
+		&models.SalaryStructureAssignment{},
+		&models.PayslipTemplate{},
+		&models.Payslip{},
+		&models.PayslipPreference{},
+		&models.StatutoryRuleSet{},
+		&models.TaxDeclaration{},
+		&models.PaymentProfile{},
+		&models.Disbursement{},
+		&models.PaymentFile{},
+		&models.ExpenseCategory{},
+		&models.CurrencyRate{},
+		&models.ExpenseClaim{},
+		&models.ExpenseItem{},
+		&models.ExpenseReceipt{},
+		&models.CompensationCycle{},
+		&models.CompensationBudget{},
+		&models.CompensationProposal{},
+		&models.Loan{},
+		&models.LoanInstallment{},
+		&models.LoanRepayment{},
+		&models.FinalSettlement{},
+	); err != nil {
+		return err
+	}
+	return migrateData(db)
+}
+
+// migrateData runs the schema and data steps AutoMigrate cannot express and seeds baseline
+// policy rows. Every step is idempotent; existing rows are left untouched.
+func migrateData(db *gorm.DB) error {
+	sick := models.LeaveAttachmentPolicy{
+		LeaveType:   models.LeaveSick,
+		MinDays:     3,
+		Description: "medical certificate required for sick leave over two days",
+	}
+	if err := db.Where(models.LeaveAttachmentPolicy{LeaveType: sick.LeaveType}).FirstOrCreate(&sick).Error; err != nil {
+		return err
+	}
+	if err := seedOnboardingTemplate(db); err != nil {
+		return err
+	}
+	if err := seedOffboardingTemplate(db); err != nil {
+		return err
+	}
+	if err := seedDocumentCategories(db); err != nil {
+		return err
+	}
+	if err := migrateOrgStrings(db); err != nil {
+		return err
+	}
+	if err := migrateEmployeeArchive(db); err != nil {
+		return err
+	}
+	// full-text search on employee names; gorm tags cannot express expression indexes
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_employees_name_fts ON employees USING gin (to_tsvector('simple', name))`).Error; err != nil {
+		return err
+	}
+	// custom field filters use jsonb containment
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_employees_custom_fields ON employees USING gin (custom_fields jsonb_path_ops)`).Error; err != nil {
+		return err
+	}
+	return backfillJobRecords(db)
+}
+
+// seedOnboardingTemplate creates the default onboarding checklist when no onboarding template
+// exists yet. Due offsets are days relative to the start date.
+func seedOnboardingTemplate(db *gorm.DB) error {
+	var n int64
+	if err := db.Model(&models.ChecklistTemplate{}).Where("kind = ?", models.ChecklistOnboarding).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	return db.Create(&models.ChecklistTemplate{
+		Kind:        models.ChecklistOnboarding,
+		Name:        "Standard onboarding",
+		Description: "Default steps for a new hire",
+		IsDefault:   true,
+		Items: []models.ChecklistTemplateItem{
+			{Position: 1, Title: "Create accounts and grant system access", Category: models.TaskAccountSetup, Owner: models.OwnerIT, DueOffsetDays: -2},
+			{Position: 2, Title: "Prepare laptop, badge and equipment", Category: models.TaskEquipment, Owner: models.OwnerIT, DueOffsetDays: -1},
+			{Position: 3, Title: "Collect contract, ID and tax forms", Category: models.TaskPaperwork, Owner: models.OwnerHR, DueOffsetDays: 0},
+			{Position: 4, Title: "Welcome meeting and team introduction", Category: models.TaskOrientation, Owner: models.OwnerManager, DueOffsetDays: 0},
+			{Position: 5, Title: "Complete mandatory trainings", Category: models.TaskTraining, Owner: models.OwnerEmployee, DueOffsetDays: 14},
+			{Position: 6, Title: "Agree probation goals", Category: models.TaskOrientation, Owner: models.OwnerManager, DueOffsetDays: 14},
+		},
+	}).Error
+}
+
+// seedOffboardingTemplate creates the default offboarding checklist when no offboarding template
+// exists yet. Due offsets are days relative to the last working day.
+func seedOffboardingTemplate(db *gorm.DB) error {
+	var n int64
+	if err := db.Model(&models.ChecklistTemplate{}).Where("kind = ?", models.ChecklistOffboarding).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	return db.Create(&models.ChecklistTemplate{
+		Kind:        models.ChecklistOffboarding,
+		Name:        "Standard offboarding",
+		Description: "Default steps for a departing employee",
+		IsDefault:   true,
+		Items: []models.ChecklistTemplateItem{
+			{Position: 1, Title: "Exit interview", Category: models.TaskExitInterview, Owner: models.OwnerHR, DueOffsetDays: -3},
+			{Position: 2, Title: "Hand over work and documentation", Category: models.TaskOther, Owner: models.OwnerEmployee, DueOffsetDays: -1},
+			{Position: 3, Title: "Return laptop, badge and other company assets", Category: models.TaskAssetReturn, Owner: models.OwnerManager, DueOffsetDays: 0},
+			{Position: 4, Title: "Revoke system and building access", Category: models.TaskAccessRevocation, Owner: models.OwnerHR, DueOffsetDays: 0},
+			{Position: 5, Title: "Prepare final settlement", Category: models.TaskFinalSettlement, Owner: models.OwnerHR, DueOffsetDays: 7},
+		},
+	}).Error
+}
+
+// seedDocumentCategories creates the baseline document categories when none exist yet.
+func seedDocumentCategories(db *gorm.DB) error {
+	var n int64
+	if err := db.Model(&models.DocumentCategory{}).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	return db.Create([]models.DocumentCategory{
+		{Name: "Contract", Description: "Employment contracts and amendments", EmployeeCanView: true, ReminderDays: 30},
+		{Name: "Offer letter", EmployeeCanView: true, ReminderDays: 30},
+		{Name: "ID proof", Description: "Passport or national ID", EmployeeCanView: true, EmployeeCanUpload: true, RequiresExpiry: true, ReminderDays: 60},
+		{Name: "Work permit", Description: "Visa and work authorization", EmployeeCanView: true, EmployeeCanUpload: true, ManagerCanView: true, RequiresExpiry: true, ReminderDays: 90},
+		{Name: "Certificate", Description: "Degrees and professional certifications", EmployeeCanView: true, EmployeeCanUpload: true, ManagerCanView: true, ReminderDays: 30},
+	}).Error
+}
+
+// employeeRefs are the tables whose rows belong to an employee and must outlive their termination.
+var employeeRefs = []string{"attendances", "leaves", "job_records"}
+
+// migrateEmployeeArchive replaces the one-employee-per-user unique index with a partial one, so a
+// rehired user can have terminated records besides the current one, and adds foreign keys that stop
+// employee rows from being hard-deleted under their attendance, leaves and job history.
+// The keys are NOT VALID: rows orphaned by earlier hard deletes are kept as they are.
+func migrateEmployeeArchive(db *gorm.DB) error {
+	stmts := []string{
+		`DROP INDEX IF EXISTS idx_employees_user_id`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_employees_active_user ON employees (user_id) WHERE status <> 'TERMINATED'`,
+	}
+	for _, t := range employeeRefs {
+		stmts = append(stmts, fmt.Sprintf(`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_%[1]s_employee') THEN
+				ALTER TABLE %[1]s ADD CONSTRAINT fk_%[1]s_employee
+					FOREIGN KEY (employee_id) REFERENCES employees (id) ON DELETE RESTRICT NOT VALID;
+			END IF;
+		END $$`, t))
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orgMigrations turns free-text org columns into entity rows. Each entry names the entity table,
+// its display column, and the text/id column pair on the tables referencing it.
+var orgMigrations = []struct {
+	table, nameCol, textCol, idCol string
+	referencing                    []string
+}{
+	{"departments", "name", "department", "department_id", []string{"employees", "job_records", "leave_blackouts", "staffing_rules"}},
+	{"positions", "title", "position", "position_id", []string{"employees", "job_records"}},
+	{"job_grades", "code", "grade", "grade_id", []string{"employees", "job_records"}},
+	{"locations", "name", "location", "location_id", []string{"employees", "job_records"}},
+}
+
+// migrateOrgStrings creates one entity per distinct normalized name (trimmed, whitespace collapsed,
+// case-insensitive), links unlinked rows to it by id and rewrites their text to the canonical name.
+// It only touches rows whose id column is still NULL, so it is safe to run on every start.
+func migrateOrgStrings(db *gorm.DB) error {
+	const norm = "btrim(regexp_replace(%s, '\\s+', ' ', 'g'))"
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, m := range orgMigrations {
+			for _, ref := range m.referencing {
+				text := fmt.Sprintf(norm, ref+"."+m.textCol)
+				insert := fmt.Sprintf(`INSERT INTO %[1]s (created_at, updated_at, %[2]s, name_key)
+					SELECT DISTINCT ON (lower(%[3]s)) now(), now(), %[3]s, lower(%[3]s) FROM %[4]s
+					WHERE %[4]s.%[5]s IS NULL AND %[3]s <> ''
+					ON CONFLICT (name_key) DO NOTHING`, m.table, m.nameCol, text, ref, m.idCol)
+				if err := tx.Exec(insert).Error; err != nil {
+					return err
+				}
+				link := fmt.Sprintf(`UPDATE %[4]s SET %[5]s = e.id, %[6]s = e.%[2]s FROM %[1]s e
+					WHERE %[4]s.%[5]s IS NULL AND e.name_key = lower(%[3]s)`, m.table, m.nameCol, text, ref, m.idCol, m.textCol)
+				if err := tx.Exec(link).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// backfillJobRecords gives employees created before job history existed an initial record
+// effective from their creation date.
+func backfillJobRecords(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO job_records
+		(created_at, updated_at, employee_id, effective_date, position, position_id, department, department_id,
+		 grade, grade_id, salary, manager_id, location, location_id, reason, created_by, applied_at)
+		SELECT now(), now(), e.id, e.created_at::date, e.position, e.position_id, e.department, e.department_id,
+		 COALESCE(e.grade, ''), e.grade_id, e.salary, e.manager_id, COALESCE(e.location, ''), e.location_id, 'initial', 0, now()
+		FROM employees e
+		WHERE NOT EXISTS (SELECT 1 FROM job_records j WHERE j.employee_id = e.id)`).Error
 }
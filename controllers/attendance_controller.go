@@ -2,8 +2,10 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -16,12 +18,13 @@ import (
 )
 
 type AttendanceController struct {
-	db  *gorm.DB
-	svc *services.AttendanceService
+	db        *gorm.DB
+	svc       *services.AttendanceService
+	employees *services.EmployeeService
 }
 
 func NewAttendanceController(db *gorm.DB) *AttendanceController {
-	return &AttendanceController{db: db, svc: services.NewAttendanceService(db)}
+	return &AttendanceController{db: db, svc: services.NewAttendanceService(db), employees: services.NewEmployeeService(db)}
 }
 
 type attendanceReq struct {
@@ -32,8 +35,8 @@ type attendanceReq struct {
 // Employee
 func (c *AttendanceController) AddMine(w http.ResponseWriter, r *http.Request) {
 	uid := r.Context().Value(middlewares.CtxUserID).(uint)
-	var emp models.Employee
-	if err := c.db.Where("user_id = ?", uid).First(&emp).Error; err != nil {
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
 		utils.Error(w, "employee not found", http.StatusBadRequest)
 		return
 	}
@@ -48,7 +51,7 @@ func (c *AttendanceController) AddMine(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := c.svc.Add(emp.ID, d, models.AttendanceStatus(req.Status)); err != nil {
-		utils.Error(w, "save error", http.StatusBadRequest)
+		attendanceError(w, err, "save error")
 		return
 	}
 	utils.Success(w, "saved", nil, http.StatusCreated)
@@ -56,8 +59,8 @@ func (c *AttendanceController) AddMine(w http.ResponseWriter, r *http.Request) {
 
 func (c *AttendanceController) DeleteMine(w http.ResponseWriter, r *http.Request) {
 	uid := r.Context().Value(middlewares.CtxUserID).(uint)
-	var emp models.Employee
-	if err := c.db.Where("user_id = ?", uid).First(&emp).Error; err != nil {
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
 		utils.Error(w, "employee not found", http.StatusBadRequest)
 		return
 	}
@@ -67,7 +70,7 @@ func (c *AttendanceController) DeleteMine(w http.ResponseWriter, r *http.Request
 		return
 	}
 	if err := c.svc.Delete(emp.ID, uint(id64)); err != nil {
-		utils.Error(w, "delete error", http.StatusBadRequest)
+		attendanceError(w, err, "delete error")
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -75,8 +78,8 @@ func (c *AttendanceController) DeleteMine(w http.ResponseWriter, r *http.Request
 
 func (c *AttendanceController) ListMine(w http.ResponseWriter, r *http.Request) {
 	uid := r.Context().Value(middlewares.CtxUserID).(uint)
-	var emp models.Employee
-	if err := c.db.Where("user_id = ?", uid).First(&emp).Error; err != nil {
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
 		utils.Error(w, "employee not found", http.StatusBadRequest)
 		return
 	}
@@ -88,9 +91,43 @@ func (c *AttendanceController) ListMine(w http.ResponseWriter, r *http.Request)
 	utils.Success(w, "ok", list, http.StatusOK)
 }
 
+// attendanceError reports a failed change; days locked by payroll are a conflict.
+func attendanceError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, services.ErrPayrollLocked) {
+		utils.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	utils.Error(w, msg, http.StatusBadRequest)
+}
+
+// parseAttendanceFilter reads employee_id, status (comma-separated) and the from/to date range.
+func parseAttendanceFilter(r *http.Request) (services.AttendanceFilter, error) {
+	v := r.URL.Query()
+	var f services.AttendanceFilter
+	var err error
+	if f.EmployeeIDs, err = parseIDList(v.Get("employee_id")); err != nil {
+		return f, errors.New("invalid employee_id")
+	}
+	for _, st := range splitList(v.Get("status")) {
+		f.Statuses = append(f.Statuses, models.AttendanceStatus(strings.ToUpper(st)))
+	}
+	if f.From, err = utils.ParseOptionalDate(v.Get("from")); err != nil {
+		return f, errors.New("invalid from")
+	}
+	if f.To, err = utils.ParseOptionalDate(v.Get("to")); err != nil {
+		return f, errors.New("invalid to")
+	}
+	return f, nil
+}
+
 // HR
 func (c *AttendanceController) ListAll(w http.ResponseWriter, r *http.Request) {
-	list, err := c.svc.ListAll()
+	f, err := parseAttendanceFilter(r)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.ListAll(f)
 	if err != nil {
 		utils.Error(w, "error", http.StatusInternalServerError)
 		return
@@ -110,7 +147,7 @@ func (c *AttendanceController) UpdateAny(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	if err := c.svc.UpdateAny(uint(id64), models.AttendanceStatus(req.Status)); err != nil {
-		utils.Error(w, "update error", http.StatusBadRequest)
+		attendanceError(w, err, "update error")
 		return
 	}
 	utils.Success(w, "updated", nil, http.StatusOK)
@@ -62,6 +62,7 @@ func (c *AuthController) Login(w http.ResponseWriter, r *http.Request) {
     var user models.User
     if err := c.db.Where("username = ?", req.Username).First(&user).Error; err != nil { utils.Error(w, "invalid credentials", http.StatusUnauthorized); return }
     if !c.svc.CheckPassword(user.PasswordHash, req.Password) { utils.Error(w, "invalid credentials", http.StatusUnauthorized); return }
+    if user.Disabled { utils.Error(w, "account disabled", http.StatusForbidden); return }
     at, err := c.svc.GenerateAccessToken(&user)
     if err != nil { utils.Error(w, "token error", http.StatusInternalServerError); return }
     rt, err := c.svc.GenerateRefreshToken(&user)
@@ -80,10 +81,11 @@ type refreshReq struct { RefreshToken string `json:"refresh_token"` }
 func (c *AuthController) Refresh(w http.ResponseWriter, r *http.Request) {
     var req refreshReq
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil { utils.Error(w, "invalid body", http.StatusBadRequest); return }
-    uid, err := c.svc.ParseRefresh(req.RefreshToken)
+    uid, tv, err := c.svc.ParseRefresh(req.RefreshToken)
     if err != nil { utils.Error(w, "invalid refresh", http.StatusUnauthorized); return }
     var user models.User
     if err := c.db.First(&user, uid).Error; err != nil { utils.Error(w, "user not found", http.StatusUnauthorized); return }
+    if user.Disabled || user.TokenVersion != tv { utils.Error(w, "invalid refresh", http.StatusUnauthorized); return }
     at, err := c.svc.GenerateAccessToken(&user)
     if err != nil { utils.Error(w, "token error", http.StatusInternalServerError); return }
     utils.Success(w, "ok", map[string]string{"access_token": at}, http.StatusOK)
@@ -0,0 +1,342 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/utils"
+)
+
+type ChecklistController struct {
+	db  *gorm.DB
+	svc *services.ChecklistService
+}
+
+func NewChecklistController(db *gorm.DB) *ChecklistController {
+	return &ChecklistController{db: db, svc: services.NewChecklistService(db)}
+}
+
+// checklistError maps checklist service errors to responses.
+func checklistError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrTaskForbidden):
+		utils.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrChecklistClosed), errors.Is(err, services.ErrChecklistExists),
+		errors.Is(err, services.ErrEmployeeTerminated):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+func userRole(r *http.Request) models.UserRole {
+	role, _ := r.Context().Value(middlewares.CtxUserRole).(string)
+	return models.UserRole(role)
+}
+
+// Templates
+
+func (c *ChecklistController) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	kind := models.ChecklistKind(strings.ToUpper(r.URL.Query().Get("kind")))
+	list, err := c.svc.ListTemplates(kind)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *ChecklistController) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	t, err := c.svc.GetTemplate(id)
+	if err != nil {
+		checklistError(w, err)
+		return
+	}
+	utils.Success(w, "ok", t, http.StatusOK)
+}
+
+// SaveTemplate handles both POST (create) and PUT /{id} (replace).
+func (c *ChecklistController) SaveTemplate(w http.ResponseWriter, r *http.Request) {
+	var t models.ChecklistTemplate
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	t.ID = 0
+	code := http.StatusCreated
+	if _, ok := mux.Vars(r)["id"]; ok {
+		id, err := routeID(r)
+		if err != nil {
+			utils.Error(w, "invalid ID", http.StatusBadRequest)
+			return
+		}
+		t.ID, code = id, http.StatusOK
+	}
+	t.Kind = models.ChecklistKind(strings.ToUpper(string(t.Kind)))
+	if err := c.svc.SaveTemplate(&t); err != nil {
+		checklistError(w, err)
+		return
+	}
+	utils.Success(w, "saved", t, code)
+}
+
+func (c *ChecklistController) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteTemplate(id); err != nil {
+		checklistError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Checklists
+
+type offboardingReq struct {
+	LastWorkingDay string `json:"last_working_day"`
+	Reason         string `json:"reason"`
+	TemplateID     *uint  `json:"template_id"`
+}
+
+// @Summary Start offboarding (HR)
+// @Description Creates the offboarding checklist from a template. On the last working day the login is disabled, tokens are revoked and the employee is terminated.
+// @Tags Checklists
+// @Security BearerAuth
+// @Param id path int true "Employee ID"
+// @Param input body offboardingReq true "Offboarding"
+// @Success 201 {object} utils.APIResponse
+// @Router /employees/{id}/offboarding [post]
+func (c *ChecklistController) StartOffboarding(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	var req offboardingReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	lwd, err := utils.ParseDate(req.LastWorkingDay)
+	if err != nil {
+		utils.Error(w, "invalid last_working_day", http.StatusBadRequest)
+		return
+	}
+	cl, err := c.svc.Start(services.StartChecklist{
+		Kind:          models.ChecklistOffboarding,
+		EmployeeID:    id,
+		TemplateID:    req.TemplateID,
+		ReferenceDate: lwd,
+		Reason:        req.Reason,
+		StartedBy:     r.Context().Value(middlewares.CtxUserID).(uint),
+	})
+	if err != nil {
+		checklistError(w, err)
+		return
+	}
+	utils.Success(w, "started", cl, http.StatusCreated)
+}
+
+type onboardingReq struct {
+	StartDate  string `json:"start_date"`
+	TemplateID *uint  `json:"template_id"`
+}
+
+// @Summary Start onboarding (HR)
+// @Description Creates the onboarding checklist from a template; new hires get one automatically when a template matches. Due dates are relative to start_date (default today).
+// @Tags Checklists
+// @Security BearerAuth
+// @Param id path int true "Employee ID"
+// @Param input body onboardingReq false "Onboarding"
+// @Success 201 {object} utils.APIResponse
+// @Router /employees/{id}/onboarding [post]
+func (c *ChecklistController) StartOnboarding(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	var req onboardingReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	start, err := utils.ParseOptionalDate(req.StartDate)
+	if err != nil {
+		utils.Error(w, "invalid start_date", http.StatusBadRequest)
+		return
+	}
+	var ref time.Time
+	if start != nil {
+		ref = *start
+	}
+	cl, err := c.svc.Start(services.StartChecklist{
+		Kind:          models.ChecklistOnboarding,
+		EmployeeID:    id,
+		TemplateID:    req.TemplateID,
+		ReferenceDate: ref,
+		Reason:        "new hire",
+		StartedBy:     r.Context().Value(middlewares.CtxUserID).(uint),
+	})
+	if err != nil {
+		checklistError(w, err)
+		return
+	}
+	utils.Success(w, "started", cl, http.StatusCreated)
+}
+
+// EmployeeChecklists lists every checklist of one employee.
+func (c *ChecklistController) EmployeeChecklists(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.List(services.ChecklistFilter{EmployeeID: id})
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *ChecklistController) List(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	f := services.ChecklistFilter{
+		Kind:   models.ChecklistKind(strings.ToUpper(v.Get("kind"))),
+		Status: models.ChecklistStatus(strings.ToUpper(v.Get("status"))),
+	}
+	if e := v.Get("employee_id"); e != "" {
+		id, err := strconv.ParseUint(e, 10, 64)
+		if err != nil {
+			utils.Error(w, "invalid employee_id", http.StatusBadRequest)
+			return
+		}
+		f.EmployeeID = uint(id)
+	}
+	list, err := c.svc.List(f)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// Get returns a checklist to HR or to anyone who can work on one of its tasks.
+func (c *ChecklistController) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	cl, err := c.svc.Get(id)
+	if err != nil {
+		checklistError(w, err)
+		return
+	}
+	if role := userRole(r); role != models.RoleHR {
+		uid := r.Context().Value(middlewares.CtxUserID).(uint)
+		assigned := false
+		for _, t := range cl.Tasks {
+			if services.CanWorkTask(t, uid, role) {
+				assigned = true
+			}
+		}
+		if !assigned {
+			utils.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+	utils.Success(w, "ok", cl, http.StatusOK)
+}
+
+// MyTasks lists open tasks assigned to the caller; HR and IT also see their team's unassigned tasks.
+func (c *ChecklistController) MyTasks(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	list, err := c.svc.OpenTasks(uid, userRole(r))
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+type taskUpdateReq struct {
+	Status         *string `json:"status"`
+	Note           *string `json:"note"`
+	AssigneeUserID *uint   `json:"assignee_user_id"`
+	DueDate        *string `json:"due_date"`
+}
+
+func (c *ChecklistController) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	tid, err := strconv.ParseUint(mux.Vars(r)["tid"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid task ID", http.StatusBadRequest)
+		return
+	}
+	var req taskUpdateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	u := services.TaskUpdate{Note: req.Note, AssigneeUserID: req.AssigneeUserID}
+	if req.Status != nil {
+		st := models.TaskStatus(strings.ToUpper(*req.Status))
+		u.Status = &st
+	}
+	if req.DueDate != nil {
+		d, err := utils.ParseDate(*req.DueDate)
+		if err != nil {
+			utils.Error(w, "invalid due_date", http.StatusBadRequest)
+			return
+		}
+		u.DueDate = &d
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	task, err := c.svc.UpdateTask(id, uint(tid), u, uid, userRole(r))
+	if err != nil {
+		checklistError(w, err)
+		return
+	}
+	utils.Success(w, "updated", task, http.StatusOK)
+}
+
+func (c *ChecklistController) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	cl, err := c.svc.Cancel(id)
+	if err != nil {
+		checklistError(w, err)
+		return
+	}
+	utils.Success(w, "cancelled", cl, http.StatusOK)
+}
@@ -0,0 +1,544 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/storage"
+	"github.com/example/hrms-backend/utils"
+)
+
+type CompensationController struct {
+	svc       *services.CompensationService
+	employees *services.EmployeeService
+}
+
+func NewCompensationController(db *gorm.DB) *CompensationController {
+	return &CompensationController{
+		svc:       services.NewCompensationService(db, storage.Default()),
+		employees: services.NewEmployeeService(db),
+	}
+}
+
+// compensationError maps compensation service errors to responses.
+func compensationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrNotProposer), errors.Is(err, services.ErrProposalsOverdue):
+		utils.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrCycleStatus), errors.Is(err, services.ErrProposalStatus),
+		errors.Is(err, services.ErrProposalsPending), errors.Is(err, services.ErrLetterUnavailable),
+		errors.Is(err, services.ErrPayrollLocked):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrBudgetExceeded):
+		utils.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// proposer describes the caller for proposals and decisions.
+func (c *CompensationController) proposer(r *http.Request) services.Proposer {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	p := services.Proposer{UserID: uid, HR: userRole(r) == models.RoleHR}
+	if emp, err := c.employees.GetByUser(uid); err == nil {
+		p.EmployeeID = emp.ID
+	}
+	return p
+}
+
+// reviewer answers 403 unless the caller takes part in reviews as HR or a manager.
+func reviewer(w http.ResponseWriter, r *http.Request) bool {
+	if role := userRole(r); role == models.RoleHR || role == models.RoleManager {
+		return true
+	}
+	utils.Error(w, "forbidden", http.StatusForbidden)
+	return false
+}
+
+// Cycles
+
+// @Summary List compensation review cycles (HR, Manager)
+// @Tags Compensation
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/cycles [get]
+func (c *CompensationController) ListCycles(w http.ResponseWriter, r *http.Request) {
+	if !reviewer(w, r) {
+		return
+	}
+	list, err := c.svc.ListCycles()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Get a compensation cycle with its department budgets and what is committed against them (HR, Manager)
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Cycle ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/cycles/{id} [get]
+func (c *CompensationController) GetCycle(w http.ResponseWriter, r *http.Request) {
+	if !reviewer(w, r) {
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	cycle, err := c.svc.GetCycle(id)
+	if err != nil {
+		compensationError(w, err)
+		return
+	}
+	utils.Success(w, "ok", cycle, http.StatusOK)
+}
+
+type cycleReq struct {
+	Name           string `json:"name"`
+	EffectiveDate  string `json:"effective_date"`
+	ProposalsDue   string `json:"proposals_due"`
+	LetterTemplate string `json:"letter_template"`
+}
+
+// @Summary Create or update a compensation cycle (HR)
+// @Description letter_template is the text of the revision letters with placeholders {{employee_name}}, {{employee_id}}, {{position}}, {{department}}, {{cycle}}, {{effective_date}}, {{current_salary}}, {{new_salary}}, {{increase}}, {{increase_percent}} and {{date}}; empty uses a built-in text. Managers cannot change proposals after proposals_due.
+// @Tags Compensation
+// @Security BearerAuth
+// @Param input body cycleReq true "Cycle; dates as YYYY-MM-DD"
+// @Success 201 {object} utils.APIResponse
+// @Router /compensation/cycles [post]
+func (c *CompensationController) SaveCycle(w http.ResponseWriter, r *http.Request) {
+	var req cycleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	eff, err := utils.ParseDate(req.EffectiveDate)
+	if err != nil {
+		utils.Error(w, "invalid effective_date", http.StatusBadRequest)
+		return
+	}
+	cycle := models.CompensationCycle{
+		Name:           req.Name,
+		EffectiveDate:  eff,
+		LetterTemplate: req.LetterTemplate,
+		CreatedBy:      r.Context().Value(middlewares.CtxUserID).(uint),
+	}
+	if req.ProposalsDue != "" {
+		due, err := utils.ParseDate(req.ProposalsDue)
+		if err != nil {
+			utils.Error(w, "invalid proposals_due", http.StatusBadRequest)
+			return
+		}
+		cycle.ProposalsDue = &due
+	}
+	code := http.StatusCreated
+	if r.Method == http.MethodPut {
+		if cycle.ID, err = routeID(r); err != nil {
+			utils.Error(w, "invalid ID", http.StatusBadRequest)
+			return
+		}
+		code = http.StatusOK
+	}
+	if err := c.svc.SaveCycle(&cycle); err != nil {
+		compensationError(w, err)
+		return
+	}
+	utils.Success(w, "saved", cycle, code)
+}
+
+// @Summary Delete a draft compensation cycle (HR)
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Cycle ID"
+// @Success 204 {object} nil
+// @Router /compensation/cycles/{id} [delete]
+func (c *CompensationController) DeleteCycle(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteCycle(id); err != nil {
+		compensationError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Open a draft cycle for proposals; managers are notified (HR)
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Cycle ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/cycles/{id}/open [post]
+func (c *CompensationController) OpenCycle(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	cycle, err := c.svc.OpenCycle(id)
+	if err != nil {
+		compensationError(w, err)
+		return
+	}
+	utils.Success(w, "opened", cycle, http.StatusOK)
+}
+
+// @Summary Close a cycle, applying the approved revisions (HR)
+// @Description Each approved revision becomes a job record effective on the cycle date, and a letter is generated for it. Submitted proposals must be decided first. Approved revisions of employees who have left are listed under lapsed.
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Cycle ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/cycles/{id}/close [post]
+func (c *CompensationController) CloseCycle(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	out, err := c.svc.CloseCycle(id, r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		compensationError(w, err)
+		return
+	}
+	utils.Success(w, "closed", out, http.StatusOK)
+}
+
+type budgetReq struct {
+	Amount float64 `json:"amount"`
+}
+
+// departmentID reads the {department} route variable.
+func departmentID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(mux.Vars(r)["department"], 10, 64)
+	return uint(id), err
+}
+
+// @Summary Set a department's budget for annual salary increases in a cycle (HR)
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Cycle ID"
+// @Param department path int true "Department ID"
+// @Param input body budgetReq true "Budget"
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/cycles/{id}/budgets/{department} [put]
+func (c *CompensationController) SetBudget(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	dept, err := departmentID(r)
+	if err != nil {
+		utils.Error(w, "invalid department ID", http.StatusBadRequest)
+		return
+	}
+	var req budgetReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	b, err := c.svc.SetBudget(id, dept, req.Amount)
+	if err != nil {
+		compensationError(w, err)
+		return
+	}
+	utils.Success(w, "saved", b, http.StatusOK)
+}
+
+// @Summary Remove a department's budget from a cycle (HR)
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Cycle ID"
+// @Param department path int true "Department ID"
+// @Success 204 {object} nil
+// @Router /compensation/cycles/{id}/budgets/{department} [delete]
+func (c *CompensationController) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	dept, err := departmentID(r)
+	if err != nil {
+		utils.Error(w, "invalid department ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteBudget(id, dept); err != nil {
+		compensationError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Proposals
+
+// @Summary List my direct reports with their salaries and proposals in a cycle (Manager)
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Cycle ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/cycles/{id}/team [get]
+func (c *CompensationController) Team(w http.ResponseWriter, r *http.Request) {
+	if !reviewer(w, r) {
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	emp, err := c.employees.GetByUser(r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return
+	}
+	rows, err := c.svc.Worksheet(id, emp.ID)
+	if err != nil {
+		compensationError(w, err)
+		return
+	}
+	utils.Success(w, "ok", rows, http.StatusOK)
+}
+
+type proposalReq struct {
+	EmployeeID     uint    `json:"employee_id"`
+	ProposedSalary float64 `json:"proposed_salary"`
+	Reason         string  `json:"reason"`
+}
+
+// @Summary Propose a new annual salary for a report in an open cycle (Manager, HR)
+// @Description Replaces a draft or rejected proposal for the employee. Proposals are drafts until submitted.
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Cycle ID"
+// @Param input body proposalReq true "Proposal"
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/cycles/{id}/proposals [post]
+func (c *CompensationController) SaveProposal(w http.ResponseWriter, r *http.Request) {
+	if !reviewer(w, r) {
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req proposalReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	p, err := c.svc.SaveProposal(id, req.EmployeeID, req.ProposedSalary, req.Reason, c.proposer(r))
+	if err != nil {
+		compensationError(w, err)
+		return
+	}
+	utils.Success(w, "saved", p, http.StatusOK)
+}
+
+// @Summary Submit a draft proposal to HR (Manager, HR)
+// @Description Fails with 422 when the increase exceeds what is left of the department's budget.
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/proposals/{id}/submit [post]
+func (c *CompensationController) SubmitProposal(w http.ResponseWriter, r *http.Request) {
+	if !reviewer(w, r) {
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	p, err := c.svc.SubmitProposal(id, c.proposer(r))
+	if err != nil {
+		compensationError(w, err)
+		return
+	}
+	utils.Success(w, "submitted", p, http.StatusOK)
+}
+
+// @Summary Withdraw a draft or rejected proposal (Manager, HR)
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 204 {object} nil
+// @Router /compensation/proposals/{id} [delete]
+func (c *CompensationController) DeleteProposal(w http.ResponseWriter, r *http.Request) {
+	if !reviewer(w, r) {
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteProposal(id, c.proposer(r)); err != nil {
+		compensationError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List salary revision proposals (HR)
+// @Tags Compensation
+// @Security BearerAuth
+// @Param cycle_id query int false "Cycle ID"
+// @Param employee_id query int false "Employee ID"
+// @Param department_id query int false "Department ID"
+// @Param status query string false "DRAFT, SUBMITTED, APPROVED, REJECTED or APPLIED"
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/proposals [get]
+func (c *CompensationController) ListProposals(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	f := services.ProposalFilter{Status: models.ProposalStatus(strings.ToUpper(v.Get("status")))}
+	for name, dst := range map[string]*uint{"cycle_id": &f.CycleID, "employee_id": &f.EmployeeID, "department_id": &f.DepartmentID} {
+		if s := v.Get(name); s != "" {
+			id, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				utils.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dst = uint(id)
+		}
+	}
+	list, err := c.svc.ListProposals(f)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+type proposalDecisionReq struct {
+	Note string `json:"note"`
+}
+
+// @Summary Approve a submitted proposal (HR)
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Param input body proposalDecisionReq false "Optional note"
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/proposals/{id}/approve [post]
+func (c *CompensationController) ApproveProposal(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, true)
+}
+
+// @Summary Reject a submitted proposal; the manager may revise and resubmit it (HR)
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Param input body proposalDecisionReq true "Reason in note"
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/proposals/{id}/reject [post]
+func (c *CompensationController) RejectProposal(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, false)
+}
+
+func (c *CompensationController) decide(w http.ResponseWriter, r *http.Request, approve bool) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req proposalDecisionReq
+	_ = json.NewDecoder(r.Body).Decode(&req) // the note is optional for approvals
+	p, err := c.svc.DecideProposal(id, approve, c.proposer(r), req.Note)
+	if err != nil {
+		compensationError(w, err)
+		return
+	}
+	utils.Success(w, strings.ToLower(string(p.Status)), p, http.StatusOK)
+}
+
+// Letters
+
+// @Summary List my salary revisions with letters (Employee)
+// @Tags Compensation
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /compensation/letters/me [get]
+func (c *CompensationController) MyLetters(w http.ResponseWriter, r *http.Request) {
+	emp, err := c.employees.GetByUser(r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusNotFound)
+		return
+	}
+	list, err := c.svc.ListLetters(emp.ID)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Download the letter of an applied salary revision (the employee or HR)
+// @Tags Compensation
+// @Security BearerAuth
+// @Param id path int true "Proposal ID"
+// @Success 200 {file} file
+// @Router /compensation/proposals/{id}/letter [get]
+func (c *CompensationController) DownloadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if userRole(r) != models.RoleHR {
+		emp, err := c.employees.GetByUser(r.Context().Value(middlewares.CtxUserID).(uint))
+		if err != nil {
+			utils.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		mine, err := c.svc.ListLetters(emp.ID)
+		if err != nil {
+			utils.Error(w, "error", http.StatusInternalServerError)
+			return
+		}
+		found := false
+		for _, p := range mine {
+			if p.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			utils.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+	}
+	p, rc, err := c.svc.OpenLetter(id)
+	if err != nil {
+		compensationError(w, err)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", services.LetterFileName(p)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = io.Copy(w, rc)
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/utils"
+)
+
+type CustomFieldController struct {
+	svc *services.CustomFieldService
+}
+
+func NewCustomFieldController(db *gorm.DB) *CustomFieldController {
+	return &CustomFieldController{svc: services.NewCustomFieldService(db)}
+}
+
+// customFieldError maps custom field service errors to responses.
+func customFieldError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrCustomFieldInUse):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Summary List custom field definitions
+// @Description HR sees every field; other roles see the fields visible to them.
+// @Tags Custom fields
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /custom-fields [get]
+func (c *CustomFieldController) List(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.List(userRole(r))
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Create or replace a custom field definition (HR)
+// @Description Types: TEXT (optional pattern), NUMBER (optional min and max), DATE, BOOLEAN, SELECT (options). The key cannot change once created.
+// @Tags Custom fields
+// @Security BearerAuth
+// @Param input body models.CustomFieldDefinition true "Definition"
+// @Success 201 {object} utils.APIResponse
+// @Router /custom-fields [post]
+func (c *CustomFieldController) Save(w http.ResponseWriter, r *http.Request) {
+	var d models.CustomFieldDefinition
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	d.ID = 0
+	code := http.StatusCreated
+	if _, ok := mux.Vars(r)["id"]; ok {
+		id, err := routeID(r)
+		if err != nil {
+			utils.Error(w, "invalid ID", http.StatusBadRequest)
+			return
+		}
+		d.ID, code = id, http.StatusOK
+	}
+	if err := c.svc.Save(&d); err != nil {
+		customFieldError(w, err)
+		return
+	}
+	utils.Success(w, "saved", d, code)
+}
+
+// @Summary Delete a custom field definition and its values (HR)
+// @Tags Custom fields
+// @Security BearerAuth
+// @Param id path int true "Definition ID"
+// @Success 204 {object} nil
+// @Router /custom-fields/{id} [delete]
+func (c *CustomFieldController) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.Delete(id); err != nil {
+		customFieldError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,318 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/bankfile"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/utils"
+)
+
+type DisbursementController struct {
+	svc       *services.DisbursementService
+	employees *services.EmployeeService
+}
+
+func NewDisbursementController(db *gorm.DB) *DisbursementController {
+	return &DisbursementController{svc: services.NewDisbursementService(db), employees: services.NewEmployeeService(db)}
+}
+
+// disbursementError maps disbursement service errors to responses.
+func disbursementError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrRunNotPayable), errors.Is(err, services.ErrFileReported):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Summary List payment profiles (Payroll)
+// @Tags Disbursements
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /payment-profiles [get]
+func (c *DisbursementController) ListProfiles(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListProfiles()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Get a payment profile (Payroll)
+// @Tags Disbursements
+// @Security BearerAuth
+// @Param id path int true "Profile ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payment-profiles/{id} [get]
+func (c *DisbursementController) GetProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	p, err := c.svc.GetProfile(id)
+	if err != nil {
+		disbursementError(w, err)
+		return
+	}
+	utils.Success(w, "ok", p, http.StatusOK)
+}
+
+// @Summary Create or replace a payment profile (Payroll)
+// @Description format is NACHA (ABA routing number, USD), SEPA (IBAN and optional BIC, EUR) or CSV with a csv_layout of columns, each a header and one of the fields reference, payee_id, name, bank_name, account, routing, amount, amount_cents, currency, pay_date, description, company_name, company_account or constant (with value).
+// @Tags Disbursements
+// @Security BearerAuth
+// @Param input body models.PaymentProfile true "Profile"
+// @Success 201 {object} utils.APIResponse
+// @Router /payment-profiles [post]
+func (c *DisbursementController) SaveProfile(w http.ResponseWriter, r *http.Request) {
+	var p models.PaymentProfile
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	p.ID = 0
+	code := http.StatusCreated
+	if r.Method == http.MethodPut {
+		id, err := routeID(r)
+		if err != nil {
+			utils.Error(w, "invalid ID", http.StatusBadRequest)
+			return
+		}
+		p.ID, code = id, http.StatusOK
+	}
+	if err := c.svc.SaveProfile(&p); err != nil {
+		disbursementError(w, err)
+		return
+	}
+	utils.Success(w, "saved", p, code)
+}
+
+// @Summary Delete a payment profile (Payroll)
+// @Tags Disbursements
+// @Security BearerAuth
+// @Param id path int true "Profile ID"
+// @Success 204 {object} nil
+// @Router /payment-profiles/{id} [delete]
+func (c *DisbursementController) DeleteProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteProfile(id); err != nil {
+		disbursementError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List the payments of a finalized payroll run with their status (Payroll)
+// @Tags Disbursements
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/runs/{id}/disbursements [get]
+func (c *DisbursementController) ListRun(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.ListDisbursements(id)
+	if err != nil {
+		disbursementError(w, err)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary List the payment files of a payroll run (Payroll)
+// @Tags Disbursements
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/runs/{id}/payment-files [get]
+func (c *DisbursementController) ListFiles(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.ListFiles(id)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+type paymentFileReq struct {
+	ProfileID uint `json:"profile_id"`
+}
+
+// @Summary Generate a bank payment file for the unpaid payments of a finalized run (Payroll)
+// @Description Pending and failed payments are included and become EXPORTED. Payments whose bank details the format cannot use (IBAN checksum, routing number) are left out and listed under skipped; when none can be paid the response is 422.
+// @Tags Disbursements
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Param input body paymentFileReq true "Payment profile"
+// @Success 201 {object} utils.APIResponse
+// @Router /payroll/runs/{id}/payment-files [post]
+func (c *DisbursementController) GenerateFile(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req paymentFileReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	out, err := c.svc.GenerateFile(id, req.ProfileID, uid)
+	if errors.Is(err, services.ErrNothingToExport) {
+		utils.ErrorWithData(w, err.Error(), out, http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		disbursementError(w, err)
+		return
+	}
+	utils.Success(w, "generated", out, http.StatusCreated)
+}
+
+// @Summary Download a payment file (Payroll)
+// @Tags Disbursements
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Success 200 {file} file
+// @Router /payment-files/{id}/download [get]
+func (c *DisbursementController) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	f, err := c.svc.OpenFile(id)
+	if err != nil {
+		disbursementError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", bankfile.Format(f.Format).ContentType())
+	w.Header().Set("Content-Length", strconv.Itoa(len(f.Content)))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", f.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = w.Write([]byte(f.Content))
+}
+
+// @Summary Void a payment file the bank did not process; its payments return to pending (Payroll)
+// @Tags Disbursements
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Success 204 {object} nil
+// @Router /payment-files/{id} [delete]
+func (c *DisbursementController) VoidFile(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.VoidFile(id); err != nil {
+		disbursementError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Mark every exported payment of a file paid (Payroll)
+// @Description The run becomes PAID once all of its payments are.
+// @Tags Disbursements
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payment-files/{id}/paid [post]
+func (c *DisbursementController) MarkFilePaid(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.MarkFilePaid(id, r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		disbursementError(w, err)
+		return
+	}
+	utils.Success(w, "paid", list, http.StatusOK)
+}
+
+type disbursementStatusReq struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// @Summary Record a payment as PAID or FAILED, as reported by the bank (Payroll)
+// @Description Failed payments go into the next payment file of the run.
+// @Tags Disbursements
+// @Security BearerAuth
+// @Param id path int true "Disbursement ID"
+// @Param input body disbursementStatusReq true "Status and optional note, e.g. the return reason"
+// @Success 200 {object} utils.APIResponse
+// @Router /disbursements/{id}/status [put]
+func (c *DisbursementController) SetStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req disbursementStatusReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Note) > 500 {
+		utils.Error(w, "note must be at most 500 characters", http.StatusBadRequest)
+		return
+	}
+	d, err := c.svc.SetStatus(id, models.DisbursementStatus(strings.ToUpper(req.Status)), req.Note,
+		r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		disbursementError(w, err)
+		return
+	}
+	utils.Success(w, "updated", d, http.StatusOK)
+}
+
+// @Summary List the status of my salary payments (Employee)
+// @Tags Disbursements
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /disbursements/me [get]
+func (c *DisbursementController) ListMine(w http.ResponseWriter, r *http.Request) {
+	emp, err := c.employees.GetByUser(r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusNotFound)
+		return
+	}
+	list, err := c.svc.ListMine(emp.ID)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/storage"
+	"github.com/example/hrms-backend/utils"
+)
+
+type DocumentController struct {
+	db        *gorm.DB
+	svc       *services.DocumentService
+	employees *services.EmployeeService
+}
+
+func NewDocumentController(db *gorm.DB) *DocumentController {
+	return &DocumentController{
+		db:        db,
+		svc:       services.NewDocumentService(db, storage.Default()),
+		employees: services.NewEmployeeService(db),
+	}
+}
+
+// viewer describes the caller for document access checks.
+func (c *DocumentController) viewer(r *http.Request) services.Viewer {
+	v := services.Viewer{UserID: r.Context().Value(middlewares.CtxUserID).(uint), Role: userRole(r)}
+	if emp, err := c.employees.GetByUser(v.UserID); err == nil {
+		v.EmployeeID = emp.ID
+	}
+	return v
+}
+
+// documentError maps document service errors to responses.
+func documentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, storage.ErrNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrDocumentForbidden):
+		utils.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrUploadTooLarge):
+		utils.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, services.ErrUploadBadFormat):
+		utils.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, services.ErrCategoryInUse), errors.Is(err, services.ErrEmployeeTerminated):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// Categories
+
+func (c *DocumentController) ListCategories(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListCategories()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// SaveCategory handles both POST (create) and PUT /{id} (replace).
+func (c *DocumentController) SaveCategory(w http.ResponseWriter, r *http.Request) {
+	var cat models.DocumentCategory
+	if err := json.NewDecoder(r.Body).Decode(&cat); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	cat.ID = 0
+	code := http.StatusCreated
+	if _, ok := mux.Vars(r)["id"]; ok {
+		id, err := routeID(r)
+		if err != nil {
+			utils.Error(w, "invalid ID", http.StatusBadRequest)
+			return
+		}
+		cat.ID, code = id, http.StatusOK
+	}
+	if err := c.svc.SaveCategory(&cat); err != nil {
+		documentError(w, err)
+		return
+	}
+	utils.Success(w, "saved", cat, code)
+}
+
+func (c *DocumentController) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteCategory(id); err != nil {
+		documentError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Documents
+
+// @Summary List an employee's documents
+// @Description Only documents of categories the caller may see are returned.
+// @Tags Documents
+// @Security BearerAuth
+// @Param id path int true "Employee ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/{id}/documents [get]
+func (c *DocumentController) ListForEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	c.list(w, r, id)
+}
+
+// @Summary List my documents
+// @Tags Documents
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/me/documents [get]
+func (c *DocumentController) ListMine(w http.ResponseWriter, r *http.Request) {
+	v := c.viewer(r)
+	if v.EmployeeID == 0 {
+		utils.Error(w, "employee not found", http.StatusNotFound)
+		return
+	}
+	c.list(w, r, v.EmployeeID)
+}
+
+func (c *DocumentController) list(w http.ResponseWriter, r *http.Request, employeeID uint) {
+	list, err := c.svc.List(c.viewer(r), employeeID)
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Upload a document for an employee
+// @Description Multipart form with file, category_id, and optional title, expires_at (YYYY-MM-DD) and note. HR may upload any category; employees only categories open to employee uploads.
+// @Tags Documents
+// @Security BearerAuth
+// @Param id path int true "Employee ID"
+// @Success 201 {object} utils.APIResponse
+// @Router /employees/{id}/documents [post]
+func (c *DocumentController) UploadForEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	c.upload(w, r, id)
+}
+
+// @Summary Upload one of my documents
+// @Tags Documents
+// @Security BearerAuth
+// @Success 201 {object} utils.APIResponse
+// @Router /employees/me/documents [post]
+func (c *DocumentController) UploadMine(w http.ResponseWriter, r *http.Request) {
+	v := c.viewer(r)
+	if v.EmployeeID == 0 {
+		utils.Error(w, "employee not found", http.StatusNotFound)
+		return
+	}
+	c.upload(w, r, v.EmployeeID)
+}
+
+func (c *DocumentController) upload(w http.ResponseWriter, r *http.Request, employeeID uint) {
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxUploadBytes()+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.Error(w, "missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	cat, err := strconv.ParseUint(r.FormValue("category_id"), 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid category_id", http.StatusBadRequest)
+		return
+	}
+	exp, err := utils.ParseOptionalDate(r.FormValue("expires_at"))
+	if err != nil {
+		utils.Error(w, "invalid expires_at", http.StatusBadRequest)
+		return
+	}
+	doc, err := c.svc.Upload(c.viewer(r), services.NewDocument{
+		EmployeeID: employeeID,
+		CategoryID: uint(cat),
+		Title:      r.FormValue("title"),
+		ExpiresAt:  exp,
+		FileName:   header.Filename,
+		Note:       r.FormValue("note"),
+	}, file)
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+	utils.Success(w, "uploaded", doc, http.StatusCreated)
+}
+
+// @Summary Get a document with its versions
+// @Tags Documents
+// @Security BearerAuth
+// @Param id path int true "Document ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /documents/{id} [get]
+func (c *DocumentController) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	doc, err := c.svc.Get(c.viewer(r), id)
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+	utils.Success(w, "ok", doc, http.StatusOK)
+}
+
+// @Summary Upload a new version of a document
+// @Description Multipart form with file and optional note and expires_at; a new expiry date replaces the old one.
+// @Tags Documents
+// @Security BearerAuth
+// @Param id path int true "Document ID"
+// @Success 201 {object} utils.APIResponse
+// @Router /documents/{id}/versions [post]
+func (c *DocumentController) AddVersion(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxUploadBytes()+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.Error(w, "missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	exp, err := utils.ParseOptionalDate(r.FormValue("expires_at"))
+	if err != nil {
+		utils.Error(w, "invalid expires_at", http.StatusBadRequest)
+		return
+	}
+	doc, err := c.svc.AddVersion(c.viewer(r), id, header.Filename, r.FormValue("note"), exp, file)
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+	utils.Success(w, "uploaded", doc, http.StatusCreated)
+}
+
+// @Summary Download a document
+// @Tags Documents
+// @Security BearerAuth
+// @Param id path int true "Document ID"
+// @Param version query int false "Version (default current)"
+// @Success 200 {file} file
+// @Router /documents/{id}/download [get]
+func (c *DocumentController) Download(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		if version, err = strconv.Atoi(v); err != nil || version < 1 {
+			utils.Error(w, "invalid version", http.StatusBadRequest)
+			return
+		}
+	}
+	ver, rc, err := c.svc.Open(c.viewer(r), id, version)
+	if err != nil {
+		documentError(w, err)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", ver.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(ver.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", ver.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = io.Copy(w, rc)
+}
+
+// @Summary Delete a document with all versions (HR)
+// @Tags Documents
+// @Security BearerAuth
+// @Param id path int true "Document ID"
+// @Success 204 {object} nil
+// @Router /documents/{id} [delete]
+func (c *DocumentController) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.Delete(c.viewer(r), id); err != nil {
+		documentError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
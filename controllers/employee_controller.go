@@ -2,8 +2,14 @@ package controllers
 
 import (
     "encoding/json"
+    "errors"
+    "io"
+    "log"
     "net/http"
+    "path/filepath"
     "strconv"
+    "strings"
+    "time"
 
     "github.com/gorilla/mux"
     "gorm.io/gorm"
@@ -11,34 +17,173 @@ import (
     "github.com/example/hrms-backend/middlewares"
     "github.com/example/hrms-backend/models"
     "github.com/example/hrms-backend/services"
+    "github.com/example/hrms-backend/shaping"
     "github.com/example/hrms-backend/utils"
 )
 
-type EmployeeController struct { db *gorm.DB; svc *services.EmployeeService }
+type EmployeeController struct {
+    db         *gorm.DB
+    svc        *services.EmployeeService
+    importer   *services.EmployeeImportService
+    checklists *services.ChecklistService
+    probation  *services.ProbationService
+    profiles   *services.ProfileService
+}
 
-func NewEmployeeController(db *gorm.DB) *EmployeeController { return &EmployeeController{db: db, svc: services.NewEmployeeService(db)} }
+func NewEmployeeController(db *gorm.DB) *EmployeeController {
+    return &EmployeeController{
+        db:         db,
+        svc:        services.NewEmployeeService(db),
+        importer:   services.NewEmployeeImportService(db),
+        checklists: services.NewChecklistService(db),
+        probation:  services.NewProbationService(db),
+        profiles:   services.NewProfileService(db),
+    }
+}
 
-// @Summary List employees (HR)
+// @Summary List employees
+// @Description Every authenticated user can list employees; each record is redacted to what the caller may see. Without full employee access only directory fields can be sorted on.
 // @Tags Employees
 // @Security BearerAuth
+// @Param q query string false "Full-text search on name"
+// @Param department_id query string false "Comma-separated department ids"
+// @Param position_id query string false "Comma-separated position ids"
+// @Param manager_id query string false "Comma-separated manager employee ids"
+// @Param status query string false "Comma-separated employment statuses"
+// @Param sort query string false "Comma-separated fields, prefix - for descending"
+// @Param limit query int false "Page size (max 200)"
+// @Param offset query int false "Rows to skip"
+// @Param fields query string false "Comma-separated fields to return"
+// @Param as_of query string false "Reconstruct job fields as of this date (YYYY-MM-DD)"
+// @Param cf.{key} query string false "Custom field filter, repeat for any of several values"
+// @Param bank_account query string false "Exact bank account number"
 // @Success 200 {object} utils.APIResponse
 // @Router /employees [get]
 func (c *EmployeeController) List(w http.ResponseWriter, r *http.Request) {
-    list, err := c.svc.List(); if err != nil { utils.Error(w, "error", http.StatusInternalServerError); return }
-    utils.Success(w, "ok", list, http.StatusOK)
+    q, err := parseEmployeeQuery(r)
+    if err != nil { utils.Error(w, err.Error(), http.StatusBadRequest); return }
+    q.Role = userRole(r)
+    page, err := c.svc.Search(q)
+    if errors.Is(err, services.ErrInvalidQuery) { utils.Error(w, err.Error(), http.StatusBadRequest); return }
+    if err != nil { utils.Error(w, "error", http.StatusInternalServerError); return }
+    // redact before picking fields, so hidden fields come back empty
+    items, err := shaping.Shape(shaping.ViewerOf(w), page.Items)
+    if err != nil { utils.Error(w, "error", http.StatusInternalServerError); return }
+    data, err := services.SparseFields(items, q.Fields)
+    if err != nil { utils.Error(w, "error", http.StatusInternalServerError); return }
+    utils.SuccessWithMeta(w, "ok", data, page.Meta(), http.StatusOK)
+}
+
+// @Summary Get an employee
+// @Description The record is redacted to what the caller may see: HR and auditors see it in full (salary and bank details need salary access), managers see more of their reports than others do, everyone sees the directory fields.
+// @Tags Employees
+// @Security BearerAuth
+// @Param id path int true "ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/{id} [get]
+func (c *EmployeeController) Get(w http.ResponseWriter, r *http.Request) {
+    id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+    if err != nil { utils.Error(w, "invalid employee ID", http.StatusBadRequest); return }
+    emp, err := c.svc.Get(uint(id64))
+    if err != nil { utils.Error(w, "not found", http.StatusNotFound); return }
+    utils.Success(w, "ok", emp, http.StatusOK)
+}
+
+// splitList splits a comma-separated query value, dropping blanks.
+func splitList(v string) []string {
+    var out []string
+    for _, p := range strings.Split(v, ",") {
+        if p = strings.TrimSpace(p); p != "" { out = append(out, p) }
+    }
+    return out
+}
+
+func parseIDList(v string) ([]uint, error) {
+    var ids []uint
+    for _, p := range splitList(v) {
+        id, err := strconv.ParseUint(p, 10, 64)
+        if err != nil { return nil, err }
+        ids = append(ids, uint(id))
+    }
+    return ids, nil
+}
+
+func parseEmployeeQuery(r *http.Request) (services.EmployeeQuery, error) {
+    v := r.URL.Query()
+    q := services.EmployeeQuery{Search: v.Get("q"), Sort: splitList(v.Get("sort")), Fields: splitList(v.Get("fields")), BankAccount: v.Get("bank_account")}
+    var err error
+    if q.DepartmentIDs, err = parseIDList(v.Get("department_id")); err != nil { return q, errors.New("invalid department_id") }
+    if q.PositionIDs, err = parseIDList(v.Get("position_id")); err != nil { return q, errors.New("invalid position_id") }
+    if q.ManagerIDs, err = parseIDList(v.Get("manager_id")); err != nil { return q, errors.New("invalid manager_id") }
+    for k, vals := range v {
+        if key := strings.TrimPrefix(k, "cf."); key != k {
+            if q.CustomFields == nil { q.CustomFields = map[string][]string{} }
+            q.CustomFields[key] = append(q.CustomFields[key], vals...)
+        }
+    }
+    for _, st := range splitList(v.Get("status")) {
+        q.Statuses = append(q.Statuses, models.EmploymentStatus(strings.ToUpper(st)))
+    }
+    if l := v.Get("limit"); l != "" {
+        if q.Limit, err = strconv.Atoi(l); err != nil || q.Limit < 1 { return q, errors.New("invalid limit") }
+    }
+    if o := v.Get("offset"); o != "" {
+        if q.Offset, err = strconv.Atoi(o); err != nil || q.Offset < 0 { return q, errors.New("invalid offset") }
+    }
+    if err := services.ValidateFields(q.Fields); err != nil { return q, err }
+    if asOf := v.Get("as_of"); asOf != "" {
+        d, err := utils.ParseDate(asOf)
+        if err != nil { return q, errors.New("invalid as_of") }
+        q.AsOf = &d
+    }
+    return q, nil
+}
+
+// hireReq is the body of create and rehire: the employee plus an optional probation, given either
+// as an end date or as a length in months from today.
+type hireReq struct {
+    models.Employee
+    ProbationEndDate string `json:"probation_end_date"`
+    ProbationMonths  int    `json:"probation_months"`
+}
+
+func (req *hireReq) employee() (*models.Employee, error) {
+    e := req.Employee
+    end, err := utils.ParseOptionalDate(req.ProbationEndDate)
+    if err != nil { return nil, errors.New("invalid probation_end_date") }
+    if req.ProbationMonths < 0 { return nil, errors.New("invalid probation_months") }
+    if req.ProbationMonths > 0 {
+        if end != nil { return nil, errors.New("give probation_end_date or probation_months, not both") }
+        y, m, d := time.Now().UTC().Date()
+        t := utils.AddMonths(time.Date(y, m, d, 0, 0, 0, 0, time.UTC), req.ProbationMonths)
+        end = &t
+    }
+    e.ProbationEndDate = end
+    return &e, nil
+}
+
+// startOnboarding spawns the new hire's onboarding checklist. A failure is logged instead of
+// failing the hire; HR can start it later through POST /employees/{id}/onboarding.
+func (c *EmployeeController) startOnboarding(r *http.Request, e *models.Employee) {
+    by := r.Context().Value(middlewares.CtxUserID).(uint)
+    if _, err := c.checklists.StartOnboarding(e, by); err != nil { log.Printf("onboarding for employee %d: %v", e.ID, err) }
 }
 
 // @Summary Create employee (HR)
+// @Description Employees hired with a probation start in status PROBATION. The onboarding checklist is started from the best matching template.
 // @Tags Employees
 // @Security BearerAuth
-// @Param input body models.Employee true "Employee"
+// @Param input body hireReq true "Employee"
 // @Success 201 {object} utils.APIResponse
 // @Router /employees [post]
 func (c *EmployeeController) Create(w http.ResponseWriter, r *http.Request) {
-    var req models.Employee
+    var req hireReq
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil { utils.Error(w, "invalid body", http.StatusBadRequest); return }
-    if err := c.svc.Create(&req); err != nil { utils.Error(w, "create error", http.StatusBadRequest); return }
-    utils.Success(w, "created", req, http.StatusCreated)
+    emp, err := req.employee()
+    if err != nil { utils.Error(w, err.Error(), http.StatusBadRequest); return }
+    if err := c.svc.Create(emp); err != nil { utils.Error(w, employeeSaveError(err, "create error"), http.StatusBadRequest); return }
+    c.startOnboarding(r, emp)
+    utils.Success(w, "created", emp, http.StatusCreated)
 }
 
 // @Summary Update employee (HR)
@@ -52,22 +197,164 @@ func (c *EmployeeController) Update(w http.ResponseWriter, r *http.Request) {
     id64, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
     var req models.Employee
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil { utils.Error(w, "invalid body", http.StatusBadRequest); return }
-    if err := c.svc.Update(uint(id64), &req); err != nil { utils.Error(w, "update error", http.StatusBadRequest); return }
+    uid := r.Context().Value(middlewares.CtxUserID).(uint)
+    if err := c.svc.Update(uint(id64), &req, uid); err != nil { utils.Error(w, employeeSaveError(err, "update error"), http.StatusBadRequest); return }
     utils.Success(w, "updated", req, http.StatusOK)
 }
 
+// employeeSaveError spells out custom field validation failures and hides other errors behind msg.
+func employeeSaveError(err error, msg string) string {
+    if errors.Is(err, services.ErrInvalidCustomField) || errors.Is(err, services.ErrPayrollLocked) { return err.Error() }
+    return msg
+}
+
+type terminateReq struct {
+    TerminationDate string `json:"termination_date"`
+    Reason          string `json:"reason"`
+}
+
+// employeeStateError maps termination, restore and rehire errors to responses.
+func employeeStateError(w http.ResponseWriter, err error) {
+    switch {
+    case errors.Is(err, gorm.ErrRecordNotFound):
+        utils.Error(w, "employee not found", http.StatusNotFound)
+    case errors.Is(err, services.ErrEmployeeTerminated), errors.Is(err, services.ErrEmployeeNotTerminated),
+        errors.Is(err, services.ErrActiveEmployeeExists), errors.Is(err, services.ErrPayrollLocked):
+        utils.Error(w, err.Error(), http.StatusConflict)
+    default:
+        utils.Error(w, err.Error(), http.StatusBadRequest)
+    }
+}
+
+// terminate reads an optional terminateReq; an empty body terminates today with a generic reason.
+func (c *EmployeeController) terminate(w http.ResponseWriter, r *http.Request) (*models.Employee, bool) {
+    id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+    if err != nil { utils.Error(w, "invalid employee ID", http.StatusBadRequest); return nil, false }
+    var req terminateReq
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) { utils.Error(w, "invalid body", http.StatusBadRequest); return nil, false }
+    t := services.Termination{Reason: strings.TrimSpace(req.Reason), By: r.Context().Value(middlewares.CtxUserID).(uint)}
+    if t.Reason == "" { t.Reason = "terminated" }
+    if req.TerminationDate != "" {
+        if t.Date, err = utils.ParseDate(req.TerminationDate); err != nil { utils.Error(w, "invalid termination_date", http.StatusBadRequest); return nil, false }
+    }
+    emp, err := c.svc.Terminate(uint(id64), t)
+    if err != nil { employeeStateError(w, err); return nil, false }
+    return emp, true
+}
+
 // @Summary Delete employee (HR)
+// @Description Soft delete: the employee is terminated and archived, see POST /employees/{id}/terminate.
 // @Tags Employees
 // @Security BearerAuth
 // @Param id path int true "ID"
 // @Success 204 {object} nil
 // @Router /employees/{id} [delete]
 func (c *EmployeeController) Delete(w http.ResponseWriter, r *http.Request) {
-    id64, _ := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
-    if err := c.svc.Delete(uint(id64)); err != nil { utils.Error(w, "delete error", http.StatusBadRequest); return }
+    if _, ok := c.terminate(w, r); !ok { return }
     w.WriteHeader(http.StatusNoContent)
 }
 
+// @Summary Terminate employee (HR)
+// @Description Archives the employee and cancels their pending and future leaves. The date defaults to today.
+// @Tags Employees
+// @Security BearerAuth
+// @Param id path int true "ID"
+// @Param input body terminateReq false "Termination"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/{id}/terminate [post]
+func (c *EmployeeController) Terminate(w http.ResponseWriter, r *http.Request) {
+    emp, ok := c.terminate(w, r)
+    if !ok { return }
+    utils.Success(w, "terminated", emp, http.StatusOK)
+}
+
+// @Summary Restore a terminated employee (HR)
+// @Tags Employees
+// @Security BearerAuth
+// @Param id path int true "ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/{id}/restore [post]
+func (c *EmployeeController) Restore(w http.ResponseWriter, r *http.Request) {
+    id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+    if err != nil { utils.Error(w, "invalid employee ID", http.StatusBadRequest); return }
+    emp, err := c.svc.Restore(uint(id64))
+    if err != nil { employeeStateError(w, err); return }
+    utils.Success(w, "restored", emp, http.StatusOK)
+}
+
+// @Summary Rehire a terminated employee (HR)
+// @Description Creates a new employee record for the same user, linked by previous_employee_id. Omitted job fields are copied from the old record.
+// @Tags Employees
+// @Security BearerAuth
+// @Param id path int true "ID of the terminated employee"
+// @Param input body hireReq false "Job and probation for the new tenure"
+// @Success 201 {object} utils.APIResponse
+// @Router /employees/{id}/rehire [post]
+func (c *EmployeeController) Rehire(w http.ResponseWriter, r *http.Request) {
+    id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+    if err != nil { utils.Error(w, "invalid employee ID", http.StatusBadRequest); return }
+    var req hireReq
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) { utils.Error(w, "invalid body", http.StatusBadRequest); return }
+    hire, err := req.employee()
+    if err != nil { utils.Error(w, err.Error(), http.StatusBadRequest); return }
+    emp, err := c.svc.Rehire(uint(id64), hire)
+    if err != nil { employeeStateError(w, err); return }
+    c.startOnboarding(r, emp)
+    utils.Success(w, "rehired", emp, http.StatusCreated)
+}
+
+type extendProbationReq struct {
+    ProbationEndDate string `json:"probation_end_date"`
+}
+
+type confirmProbationReq struct {
+    Note string `json:"note"`
+}
+
+// probationError maps probation errors to responses.
+func probationError(w http.ResponseWriter, err error) {
+    if errors.Is(err, services.ErrNotOnProbation) { utils.Error(w, err.Error(), http.StatusConflict); return }
+    employeeStateError(w, err)
+}
+
+// @Summary Extend probation (HR)
+// @Tags Employees
+// @Security BearerAuth
+// @Param id path int true "ID"
+// @Param input body extendProbationReq true "New end date"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/{id}/probation/extend [post]
+func (c *EmployeeController) ExtendProbation(w http.ResponseWriter, r *http.Request) {
+    id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+    if err != nil { utils.Error(w, "invalid employee ID", http.StatusBadRequest); return }
+    var req extendProbationReq
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil { utils.Error(w, "invalid body", http.StatusBadRequest); return }
+    end, err := utils.ParseDate(req.ProbationEndDate)
+    if err != nil { utils.Error(w, "invalid probation_end_date", http.StatusBadRequest); return }
+    emp, err := c.probation.Extend(uint(id64), end)
+    if err != nil { probationError(w, err); return }
+    utils.Success(w, "extended", emp, http.StatusOK)
+}
+
+// @Summary Confirm probation (HR)
+// @Description Marks the probation as passed; the employee becomes ACTIVE and is notified.
+// @Tags Employees
+// @Security BearerAuth
+// @Param id path int true "ID"
+// @Param input body confirmProbationReq false "Confirmation"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/{id}/probation/confirm [post]
+func (c *EmployeeController) ConfirmProbation(w http.ResponseWriter, r *http.Request) {
+    id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+    if err != nil { utils.Error(w, "invalid employee ID", http.StatusBadRequest); return }
+    var req confirmProbationReq
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) { utils.Error(w, "invalid body", http.StatusBadRequest); return }
+    uid := r.Context().Value(middlewares.CtxUserID).(uint)
+    emp, err := c.probation.Confirm(uint(id64), uid, req.Note)
+    if err != nil { probationError(w, err); return }
+    utils.Success(w, "confirmed", emp, http.StatusOK)
+}
+
 // @Summary Get my employee profile (Employee)
 // @Tags Employees
 // @Security BearerAuth
@@ -75,9 +362,99 @@ func (c *EmployeeController) Delete(w http.ResponseWriter, r *http.Request) {
 // @Router /employees/me [get]
 func (c *EmployeeController) GetMe(w http.ResponseWriter, r *http.Request) {
     uid := r.Context().Value(middlewares.CtxUserID).(uint)
-    var emp models.Employee
-    if err := c.db.Where("user_id = ?", uid).First(&emp).Error; err != nil { utils.Error(w, "not found", http.StatusNotFound); return }
+    emp, err := c.profiles.Mine(uid)
+    if err != nil { utils.Error(w, "not found", http.StatusNotFound); return }
     utils.Success(w, "ok", emp, http.StatusOK)
 }
 
 
+
+type jobChangeReq struct {
+    EffectiveDate string   `json:"effective_date"`
+    Position      *string  `json:"position"`
+    Department    *string  `json:"department"`
+    Grade         *string  `json:"grade"`
+    Salary        *float64 `json:"salary"`
+    ManagerID     *uint    `json:"manager_id"`
+    Location      *string  `json:"location"`
+    Reason        string   `json:"reason"`
+}
+
+// @Summary Record an effective-dated job change (HR)
+// @Tags Employees
+// @Security BearerAuth
+// @Param id path int true "ID"
+// @Param input body jobChangeReq true "Job change"
+// @Success 201 {object} utils.APIResponse
+// @Router /employees/{id}/job-changes [post]
+func (c *EmployeeController) RecordJobChange(w http.ResponseWriter, r *http.Request) {
+    id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+    if err != nil { utils.Error(w, "invalid employee ID", http.StatusBadRequest); return }
+    var req jobChangeReq
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil { utils.Error(w, "invalid body", http.StatusBadRequest); return }
+    eff, err := utils.ParseDate(req.EffectiveDate)
+    if err != nil { utils.Error(w, "invalid effective_date", http.StatusBadRequest); return }
+    uid := r.Context().Value(middlewares.CtxUserID).(uint)
+    rec, err := c.svc.RecordJobChange(uint(id64), services.JobChange{
+        EffectiveDate: eff,
+        Position:      req.Position,
+        Department:    req.Department,
+        Grade:         req.Grade,
+        Salary:        req.Salary,
+        ManagerID:     req.ManagerID,
+        Location:      req.Location,
+        Reason:        req.Reason,
+        CreatedBy:     uid,
+    })
+    if errors.Is(err, services.ErrPayrollLocked) { utils.Error(w, err.Error(), http.StatusConflict); return }
+    if err != nil { utils.Error(w, err.Error(), http.StatusBadRequest); return }
+    utils.Success(w, "recorded", rec, http.StatusCreated)
+}
+
+// @Summary Employment history timeline (HR)
+// @Tags Employees
+// @Security BearerAuth
+// @Param id path int true "ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/{id}/history [get]
+func (c *EmployeeController) History(w http.ResponseWriter, r *http.Request) {
+    id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+    if err != nil { utils.Error(w, "invalid employee ID", http.StatusBadRequest); return }
+    if _, err := c.svc.Get(uint(id64)); err != nil { utils.Error(w, "not found", http.StatusNotFound); return }
+    list, err := c.svc.Timeline(uint(id64))
+    if err != nil { utils.Error(w, "error", http.StatusInternalServerError); return }
+    utils.Success(w, "ok", list, http.StatusOK)
+}
+
+const maxImportBytes = 20 << 20
+
+// @Summary Bulk import employees from CSV or XLSX (HR)
+// @Description Columns: name, position, department, salary, grade, location, user_id or username (+ password, role to create the user), manager_username, and cf_<key> per custom field.
+// @Tags Employees
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Param file formData file true "CSV or XLSX file"
+// @Param dry_run query bool false "Validate only"
+// @Success 200 {object} utils.APIResponse
+// @Failure 422 {object} utils.APIResponse
+// @Router /employees/import [post]
+func (c *EmployeeController) Import(w http.ResponseWriter, r *http.Request) {
+    r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+    file, header, err := r.FormFile("file")
+    if err != nil { utils.Error(w, "missing file", http.StatusBadRequest); return }
+    defer file.Close()
+    format := r.URL.Query().Get("format")
+    if format == "" { format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".") }
+    records, err := services.ReadEmployeeRows(file, format)
+    if err != nil { utils.Error(w, err.Error(), http.StatusBadRequest); return }
+    dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+    report, err := c.importer.Import(records, dryRun)
+    if err != nil { utils.Error(w, "import error: "+err.Error(), http.StatusInternalServerError); return }
+    if report.Failed > 0 {
+        utils.ErrorWithData(w, "import has invalid rows; nothing was saved", report, http.StatusUnprocessableEntity)
+        return
+    }
+    msg := "imported"
+    if dryRun { msg = "validated" }
+    utils.Success(w, msg, report, http.StatusOK)
+}
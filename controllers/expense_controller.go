@@ -0,0 +1,693 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/storage"
+	"github.com/example/hrms-backend/utils"
+)
+
+type ExpenseController struct {
+	svc       *services.ExpenseService
+	employees *services.EmployeeService
+	exports   *services.ExportService
+}
+
+func NewExpenseController(db *gorm.DB) *ExpenseController {
+	return &ExpenseController{
+		svc:       services.NewExpenseService(db, storage.Default()),
+		employees: services.NewEmployeeService(db),
+		exports:   services.NewExportService(db),
+	}
+}
+
+// expenseError maps expense service errors to responses.
+func expenseError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrNotClaimApprover), errors.Is(err, services.ErrOwnClaim):
+		utils.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrClaimNotDraft), errors.Is(err, services.ErrClaimStatus),
+		errors.Is(err, services.ErrExpenseCategoryUsed):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrExpensePolicy), errors.Is(err, services.ErrNoExchangeRate):
+		utils.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, services.ErrUploadTooLarge):
+		utils.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, services.ErrUploadBadFormat):
+		utils.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// me returns the caller's employee record, answering for them when there is none.
+func (c *ExpenseController) me(w http.ResponseWriter, r *http.Request) (*models.Employee, bool) {
+	emp, err := c.employees.GetByUser(r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return nil, false
+	}
+	return emp, true
+}
+
+// Categories
+
+// @Summary List expense categories and their policies
+// @Tags Expenses
+// @Security BearerAuth
+// @Param archived query bool false "Include archived categories"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/categories [get]
+func (c *ExpenseController) ListCategories(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListCategories(r.URL.Query().Get("archived") == "true")
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Create or replace an expense category (Payroll)
+// @Description Limits are in the base currency; 0 means no limit. monthly_limit caps what an employee claims in the category per calendar month. With receipt_required, items above receipt_threshold need a receipt.
+// @Tags Expenses
+// @Security BearerAuth
+// @Param input body models.ExpenseCategory true "Category"
+// @Success 201 {object} utils.APIResponse
+// @Router /expenses/categories [post]
+func (c *ExpenseController) SaveCategory(w http.ResponseWriter, r *http.Request) {
+	var cat models.ExpenseCategory
+	if err := json.NewDecoder(r.Body).Decode(&cat); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	cat.ID = 0
+	code := http.StatusCreated
+	if r.Method == http.MethodPut {
+		id, err := routeID(r)
+		if err != nil {
+			utils.Error(w, "invalid ID", http.StatusBadRequest)
+			return
+		}
+		cat.ID, code = id, http.StatusOK
+	}
+	if err := c.svc.SaveCategory(&cat); err != nil {
+		expenseError(w, err)
+		return
+	}
+	utils.Success(w, "saved", cat, code)
+}
+
+// @Summary Delete an unused expense category (Payroll)
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Success 204 {object} nil
+// @Router /expenses/categories/{id} [delete]
+func (c *ExpenseController) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteCategory(id); err != nil {
+		expenseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Exchange rates
+
+// @Summary List exchange rates to the base currency
+// @Tags Expenses
+// @Security BearerAuth
+// @Param currency query string false "Currency code"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/rates [get]
+func (c *ExpenseController) ListRates(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListRates(r.URL.Query().Get("currency"))
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", map[string]interface{}{"base_currency": services.BaseCurrency(), "rates": list}, http.StatusOK)
+}
+
+type rateReq struct {
+	Currency      string  `json:"currency"`
+	EffectiveDate string  `json:"effective_date"`
+	Rate          float64 `json:"rate"`
+}
+
+// @Summary Set the exchange rate of a currency from a date (Payroll)
+// @Description rate is the value of one unit of currency in the base currency. It applies to expenses dated from effective_date until the currency's next rate and replaces a rate of the same date.
+// @Tags Expenses
+// @Security BearerAuth
+// @Param input body rateReq true "Rate; effective_date as YYYY-MM-DD"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/rates [put]
+func (c *ExpenseController) SaveRate(w http.ResponseWriter, r *http.Request) {
+	var req rateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	day, err := utils.ParseDate(req.EffectiveDate)
+	if err != nil {
+		utils.Error(w, "invalid effective_date", http.StatusBadRequest)
+		return
+	}
+	rate := models.CurrencyRate{Currency: req.Currency, EffectiveDate: day, Rate: req.Rate}
+	if err := c.svc.SaveRate(&rate); err != nil {
+		expenseError(w, err)
+		return
+	}
+	utils.Success(w, "saved", rate, http.StatusOK)
+}
+
+// @Summary Delete an exchange rate (Payroll)
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Rate ID"
+// @Success 204 {object} nil
+// @Router /expenses/rates/{id} [delete]
+func (c *ExpenseController) DeleteRate(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteRate(id); err != nil {
+		expenseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Claims
+
+type expenseItemReq struct {
+	ID          uint    `json:"id"`
+	CategoryID  uint    `json:"category_id"`
+	Date        string  `json:"date"`
+	Merchant    string  `json:"merchant"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+}
+
+type expenseClaimReq struct {
+	Title string           `json:"title"`
+	Items []expenseItemReq `json:"items"`
+}
+
+// @Summary Create a draft expense claim (Employee)
+// @Description Amounts are in the currency paid, defaulting to the base currency, and are converted at the rate of the item's date.
+// @Tags Expenses
+// @Security BearerAuth
+// @Param input body expenseClaimReq true "Claim; item dates as YYYY-MM-DD"
+// @Success 201 {object} utils.APIResponse
+// @Router /expenses/claims [post]
+func (c *ExpenseController) CreateClaim(w http.ResponseWriter, r *http.Request) {
+	c.saveDraft(w, r, 0)
+}
+
+// @Summary Replace the title and items of my draft claim (Employee)
+// @Description Items keep their receipts when sent with their id; items left out are removed.
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Claim ID"
+// @Param input body expenseClaimReq true "Claim; item dates as YYYY-MM-DD"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/claims/{id} [put]
+func (c *ExpenseController) UpdateClaim(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	c.saveDraft(w, r, id)
+}
+
+func (c *ExpenseController) saveDraft(w http.ResponseWriter, r *http.Request, id uint) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	var req expenseClaimReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	items := make([]services.ExpenseItemInput, 0, len(req.Items))
+	for i, it := range req.Items {
+		day, err := utils.ParseDate(it.Date)
+		if err != nil {
+			utils.Error(w, fmt.Sprintf("item %d: invalid date", i+1), http.StatusBadRequest)
+			return
+		}
+		items = append(items, services.ExpenseItemInput{
+			ID: it.ID, CategoryID: it.CategoryID, Date: day, Merchant: it.Merchant,
+			Description: it.Description, Amount: it.Amount, Currency: it.Currency,
+		})
+	}
+	claim, err := c.svc.SaveDraft(emp.ID, id, req.Title, items)
+	if err != nil {
+		expenseError(w, err)
+		return
+	}
+	code := http.StatusCreated
+	if id != 0 {
+		code = http.StatusOK
+	}
+	utils.Success(w, "saved", claim, code)
+}
+
+// @Summary List my expense claims (Employee)
+// @Tags Expenses
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/claims/me [get]
+func (c *ExpenseController) ListMine(w http.ResponseWriter, r *http.Request) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	list, err := c.svc.ListClaims(services.ExpenseFilter{EmployeeID: emp.ID})
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary List the claims submitted to me as manager (Manager)
+// @Tags Expenses
+// @Security BearerAuth
+// @Param status query string false "Status; defaults to SUBMITTED"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/claims/team [get]
+func (c *ExpenseController) ListTeam(w http.ResponseWriter, r *http.Request) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	status := models.ExpenseStatus(strings.ToUpper(r.URL.Query().Get("status")))
+	if status == "" {
+		status = models.ExpenseSubmitted
+	}
+	list, err := c.svc.ListClaims(services.ExpenseFilter{ManagerID: emp.ID, Status: status})
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// expenseFilter reads the employee_id, status and payout filters of the payroll listings.
+func expenseFilter(r *http.Request) (services.ExpenseFilter, error) {
+	v := r.URL.Query()
+	f := services.ExpenseFilter{
+		Status: models.ExpenseStatus(strings.ToUpper(v.Get("status"))),
+		Payout: models.ExpensePayout(strings.ToUpper(v.Get("payout"))),
+	}
+	if e := v.Get("employee_id"); e != "" {
+		id, err := strconv.ParseUint(e, 10, 64)
+		if err != nil {
+			return f, errors.New("invalid employee_id")
+		}
+		f.EmployeeID = uint(id)
+	}
+	return f, nil
+}
+
+// @Summary List expense claims (Payroll)
+// @Tags Expenses
+// @Security BearerAuth
+// @Param employee_id query int false "Employee ID"
+// @Param status query string false "DRAFT, SUBMITTED, MANAGER_APPROVED, APPROVED, REJECTED or PAID"
+// @Param payout query string false "PAYROLL or SEPARATE"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/claims [get]
+func (c *ExpenseController) ListClaims(w http.ResponseWriter, r *http.Request) {
+	f, err := expenseFilter(r)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.ListClaims(f)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Export expense claims as CSV, XLSX or NDJSON, e.g. approved claims paid separately (Payroll)
+// @Description Accepts the filters of GET /expenses/claims. Bank detail columns need permission to view salaries.
+// @Tags Expenses
+// @Security BearerAuth
+// @Param format query string false "csv (default), xlsx or ndjson"
+// @Param columns query string false "Comma-separated columns"
+// @Param status query string false "Status"
+// @Param payout query string false "PAYROLL or SEPARATE"
+// @Success 200 {file} file
+// @Router /expenses/export [get]
+func (c *ExpenseController) Export(w http.ResponseWriter, r *http.Request) {
+	req, err := exportRequest(r)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, err := expenseFilter(r)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exp, err := c.exports.Expenses(req, f)
+	sendExport(w, "expenses", exp, err)
+}
+
+// authorizedClaim loads the claim of the route for its claimant, their approving manager, HR and
+// payroll. Others get a 404 so claim ids are not revealed.
+func (c *ExpenseController) authorizedClaim(w http.ResponseWriter, r *http.Request) (*models.ExpenseClaim, bool) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+	claim, err := c.svc.GetClaim(id)
+	if err != nil {
+		expenseError(w, err)
+		return nil, false
+	}
+	if middlewares.HasPermission(r, models.PermRunPayroll) {
+		return claim, true
+	}
+	emp, err := c.employees.GetByUser(r.Context().Value(middlewares.CtxUserID).(uint))
+	if err == nil && (claim.EmployeeID == emp.ID || (claim.ManagerID != nil && *claim.ManagerID == emp.ID)) {
+		return claim, true
+	}
+	utils.Error(w, "not found", http.StatusNotFound)
+	return nil, false
+}
+
+// @Summary Get an expense claim with its items and receipts
+// @Description Visible to the claimant, their approving manager and payroll.
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Claim ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/claims/{id} [get]
+func (c *ExpenseController) GetClaim(w http.ResponseWriter, r *http.Request) {
+	claim, ok := c.authorizedClaim(w, r)
+	if !ok {
+		return
+	}
+	utils.Success(w, "ok", claim, http.StatusOK)
+}
+
+// @Summary Delete my draft claim (Employee)
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Claim ID"
+// @Success 204 {object} nil
+// @Router /expenses/claims/{id} [delete]
+func (c *ExpenseController) DeleteClaim(w http.ResponseWriter, r *http.Request) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteDraft(emp.ID, id); err != nil {
+		expenseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Submit my draft claim for approval (Employee)
+// @Description Items are converted at the current rates and checked against the category limits and receipt rules; violations are listed in a 422. The claim goes to my manager, or to finance when I have none.
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Claim ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/claims/{id}/submit [post]
+func (c *ExpenseController) Submit(w http.ResponseWriter, r *http.Request) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	claim, err := c.svc.Submit(emp.ID, id)
+	if err != nil {
+		expenseError(w, err)
+		return
+	}
+	utils.Success(w, "submitted", claim, http.StatusOK)
+}
+
+// Receipts
+
+// @Summary Attach a receipt to an item of my draft claim (Employee)
+// @Tags Expenses
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Param id path int true "Claim ID"
+// @Param item path int true "Item ID"
+// @Param file formData file true "Receipt (PDF, PNG or JPEG)"
+// @Success 201 {object} utils.APIResponse
+// @Router /expenses/claims/{id}/items/{item}/receipts [post]
+func (c *ExpenseController) UploadReceipt(w http.ResponseWriter, r *http.Request) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	item, err := strconv.ParseUint(mux.Vars(r)["item"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid item ID", http.StatusBadRequest)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxUploadBytes()+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.Error(w, "missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	rec, err := c.svc.UploadReceipt(emp.ID, id, uint(item), uid, header.Filename, file)
+	if err != nil {
+		expenseError(w, err)
+		return
+	}
+	utils.Success(w, "uploaded", rec, http.StatusCreated)
+}
+
+// @Summary Download a receipt of a claim
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Claim ID"
+// @Param rid path int true "Receipt ID"
+// @Success 200 {file} file
+// @Router /expenses/claims/{id}/receipts/{rid} [get]
+func (c *ExpenseController) DownloadReceipt(w http.ResponseWriter, r *http.Request) {
+	claim, ok := c.authorizedClaim(w, r)
+	if !ok {
+		return
+	}
+	rid, err := strconv.ParseUint(mux.Vars(r)["rid"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+	rec, rc, err := c.svc.OpenReceipt(claim.ID, uint(rid))
+	if err != nil {
+		utils.Error(w, "receipt not found", http.StatusNotFound)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", rec.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(rec.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", rec.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = io.Copy(w, rc)
+}
+
+// @Summary Remove a receipt from my draft claim (Employee)
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Claim ID"
+// @Param rid path int true "Receipt ID"
+// @Success 204 {object} nil
+// @Router /expenses/claims/{id}/receipts/{rid} [delete]
+func (c *ExpenseController) DeleteReceipt(w http.ResponseWriter, r *http.Request) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	rid, err := strconv.ParseUint(mux.Vars(r)["rid"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteReceipt(emp.ID, id, uint(rid)); err != nil {
+		expenseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Approval
+
+type claimDecisionReq struct {
+	Note   string `json:"note"`
+	Payout string `json:"payout"`
+}
+
+// approver describes the caller for claim decisions.
+func (c *ExpenseController) approver(r *http.Request) services.ClaimApprover {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	a := services.ClaimApprover{UserID: uid, HR: userRole(r) == models.RoleHR}
+	if emp, err := c.employees.GetByUser(uid); err == nil {
+		a.EmployeeID = emp.ID
+	}
+	return a
+}
+
+func (c *ExpenseController) decide(w http.ResponseWriter, r *http.Request, finance, approve bool) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req claimDecisionReq
+	_ = json.NewDecoder(r.Body).Decode(&req) // the body is optional for approvals
+	d := services.ClaimDecision{Approve: approve, Note: req.Note, Payout: models.ExpensePayout(strings.ToUpper(req.Payout))}
+	decide := c.svc.ManagerDecide
+	if finance {
+		decide = c.svc.FinanceDecide
+	}
+	claim, err := decide(id, c.approver(r), d)
+	if err != nil {
+		expenseError(w, err)
+		return
+	}
+	utils.Success(w, strings.ToLower(string(claim.Status)), claim, http.StatusOK)
+}
+
+// @Summary Approve a submitted claim as the claimant's manager, or HR
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Claim ID"
+// @Param input body claimDecisionReq false "Optional note"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/claims/{id}/manager-approve [post]
+func (c *ExpenseController) ManagerApprove(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, false, true)
+}
+
+// @Summary Reject a submitted claim as the claimant's manager, or HR
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Claim ID"
+// @Param input body claimDecisionReq true "Reason in note"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/claims/{id}/manager-reject [post]
+func (c *ExpenseController) ManagerReject(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, false, false)
+}
+
+// @Summary Approve a manager-approved claim for payment (Payroll)
+// @Description payout PAYROLL (default) adds the claim to the next payroll run calculated; SEPARATE leaves it to be paid outside payroll and marked paid.
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Claim ID"
+// @Param input body claimDecisionReq false "Optional note and payout"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/claims/{id}/approve [post]
+func (c *ExpenseController) FinanceApprove(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, true, true)
+}
+
+// @Summary Reject a manager-approved claim (Payroll)
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Claim ID"
+// @Param input body claimDecisionReq true "Reason in note"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/claims/{id}/reject [post]
+func (c *ExpenseController) FinanceReject(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, true, false)
+}
+
+type claimPaidReq struct {
+	PaymentReference string `json:"payment_reference"`
+	PaidAt           string `json:"paid_at"`
+}
+
+// @Summary Record the separate payment of an approved claim (Payroll)
+// @Tags Expenses
+// @Security BearerAuth
+// @Param id path int true "Claim ID"
+// @Param input body claimPaidReq true "Payment reference; paid_at as YYYY-MM-DD, defaulting to today"
+// @Success 200 {object} utils.APIResponse
+// @Router /expenses/claims/{id}/paid [post]
+func (c *ExpenseController) MarkPaid(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req claimPaidReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	var paidAt time.Time
+	if req.PaidAt != "" {
+		if paidAt, err = utils.ParseDate(req.PaidAt); err != nil {
+			utils.Error(w, "invalid paid_at", http.StatusBadRequest)
+			return
+		}
+	}
+	claim, err := c.svc.MarkPaid(id, req.PaymentReference, paidAt)
+	if err != nil {
+		expenseError(w, err)
+		return
+	}
+	utils.Success(w, "paid", claim, http.StatusOK)
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/utils"
+)
+
+type ExportController struct {
+	svc *services.ExportService
+}
+
+func NewExportController(db *gorm.DB) *ExportController {
+	return &ExportController{svc: services.NewExportService(db)}
+}
+
+// exportRequest reads ?format= and ?columns=; salary columns are only exported to callers allowed to view salaries.
+func exportRequest(r *http.Request) (services.ExportRequest, error) {
+	format, err := services.ParseExportFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		return services.ExportRequest{}, err
+	}
+	return services.ExportRequest{
+		Format:     format,
+		Columns:    splitList(r.URL.Query().Get("columns")),
+		ViewSalary: middlewares.HasPermission(r, models.PermViewSalary),
+		Role:       userRole(r),
+	}, nil
+}
+
+// sendExport streams an opened export as a file download. Once rows are being written the status is
+// already sent, so later failures can only be logged and end the response early.
+func sendExport(w http.ResponseWriter, name string, exp *services.Export, err error) {
+	if errors.Is(err, services.ErrInvalidExport) {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		utils.Error(w, "export error", http.StatusInternalServerError)
+		return
+	}
+	defer exp.Close()
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102"), exp.Format)
+	w.Header().Set("Content-Type", exp.Format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	if err := exp.Stream(w); err != nil {
+		log.Printf("export %s: %v", name, err)
+	}
+}
+
+// @Summary Export employees as CSV, XLSX or NDJSON
+// @Description Accepts the same filters and sort as GET /employees. Salary is omitted without salary access; custom fields appear as cf_<key> columns when visible to the caller.
+// @Tags Exports
+// @Security BearerAuth
+// @Param format query string false "csv (default), xlsx or ndjson"
+// @Param columns query string false "Comma-separated columns"
+// @Success 200 {file} file
+// @Router /employees/export [get]
+func (c *ExportController) Employees(w http.ResponseWriter, r *http.Request) {
+	req, err := exportRequest(r)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q, err := parseEmployeeQuery(r)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exp, err := c.svc.Employees(req, q)
+	sendExport(w, "employees", exp, err)
+}
+
+// @Summary Export attendance as CSV, XLSX or NDJSON
+// @Description Accepts the same filters as GET /attendance (HR): employee_id, status, from, to.
+// @Tags Exports
+// @Security BearerAuth
+// @Param format query string false "csv (default), xlsx or ndjson"
+// @Param columns query string false "Comma-separated columns"
+// @Success 200 {file} file
+// @Router /attendance/export [get]
+func (c *ExportController) Attendance(w http.ResponseWriter, r *http.Request) {
+	req, err := exportRequest(r)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, err := parseAttendanceFilter(r)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exp, err := c.svc.Attendance(req, f)
+	sendExport(w, "attendance", exp, err)
+}
+
+// @Summary Export leaves as CSV, XLSX or NDJSON
+// @Description Accepts the same filters as GET /leaves (HR): employee_id, status, type, from, to.
+// @Tags Exports
+// @Security BearerAuth
+// @Param format query string false "csv (default), xlsx or ndjson"
+// @Param columns query string false "Comma-separated columns"
+// @Success 200 {file} file
+// @Router /leaves/export [get]
+func (c *ExportController) Leaves(w http.ResponseWriter, r *http.Request) {
+	req, err := exportRequest(r)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f, err := parseLeaveFilter(r)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exp, err := c.svc.Leaves(req, f)
+	sendExport(w, "leaves", exp, err)
+}
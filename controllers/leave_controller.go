@@ -2,8 +2,12 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -12,19 +16,28 @@ import (
 	"github.com/example/hrms-backend/middlewares"
 	"github.com/example/hrms-backend/models"
 	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/storage"
 	"github.com/example/hrms-backend/utils"
 )
 
 type LeaveController struct {
-	db  *gorm.DB
-	svc *services.LeaveService
+	db          *gorm.DB
+	svc         *services.LeaveService
+	attachments *services.LeaveAttachmentService
+	employees   *services.EmployeeService
 }
 
 func NewLeaveController(db *gorm.DB) *LeaveController {
-	return &LeaveController{db: db, svc: services.NewLeaveService(db)}
+	return &LeaveController{
+		db:          db,
+		svc:         services.NewLeaveService(db),
+		attachments: services.NewLeaveAttachmentService(db, storage.Default()),
+		employees:   services.NewEmployeeService(db),
+	}
 }
 
 type leaveReq struct {
+	Type      string `json:"type"`
 	StartDate string `json:"start_date"`
 	EndDate   string `json:"end_date"`
 	Reason    string `json:"reason"`
@@ -33,8 +46,8 @@ type leaveReq struct {
 // Employee routes
 func (c *LeaveController) Apply(w http.ResponseWriter, r *http.Request) {
 	uid := r.Context().Value(middlewares.CtxUserID).(uint)
-	var emp models.Employee
-	if err := c.db.Where("user_id = ?", uid).First(&emp).Error; err != nil {
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
 		utils.Error(w, "employee not found", http.StatusBadRequest)
 		return
 	}
@@ -53,21 +66,31 @@ func (c *LeaveController) Apply(w http.ResponseWriter, r *http.Request) {
 		utils.Error(w, "invalid end", http.StatusBadRequest)
 		return
 	}
-	if err := c.svc.Apply(emp.ID, s, e, req.Reason); err != nil {
-		utils.Error(w, "apply error", http.StatusBadRequest)
+	lv, err := c.svc.Apply(emp.ID, models.LeaveType(req.Type), s, e, req.Reason)
+	if err != nil {
+		utils.Error(w, "apply error: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if lv.RuleWarning != "" {
+		utils.Success(w, "applied; approval needs an HR override: "+lv.RuleWarning, lv, http.StatusCreated)
 		return
 	}
-	utils.Success(w, "applied", nil, http.StatusCreated)
+	utils.Success(w, "applied", lv, http.StatusCreated)
 }
 
 func (c *LeaveController) DeleteMine(w http.ResponseWriter, r *http.Request) {
 	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return
+	}
 	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
 		utils.Error(w, "invalid leave ID", http.StatusBadRequest)
 		return
 	}
-	if err := c.svc.DeleteMine(uid, uint(id64)); err != nil {
+	if err := c.svc.DeleteMine(emp.ID, uint(id64)); err != nil {
 		utils.Error(w, "delete error", http.StatusBadRequest)
 		return
 	}
@@ -76,7 +99,12 @@ func (c *LeaveController) DeleteMine(w http.ResponseWriter, r *http.Request) {
 
 func (c *LeaveController) ListMine(w http.ResponseWriter, r *http.Request) {
 	uid := r.Context().Value(middlewares.CtxUserID).(uint)
-	list, err := c.svc.ListMine(uid)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.ListMine(emp.ID)
 	if err != nil {
 		utils.Error(w, "error", http.StatusInternalServerError)
 		return
@@ -84,9 +112,37 @@ func (c *LeaveController) ListMine(w http.ResponseWriter, r *http.Request) {
 	utils.Success(w, "ok", list, http.StatusOK)
 }
 
+// parseLeaveFilter reads employee_id, status and type (comma-separated) and the from/to date range.
+func parseLeaveFilter(r *http.Request) (services.LeaveFilter, error) {
+	v := r.URL.Query()
+	var f services.LeaveFilter
+	var err error
+	if f.EmployeeIDs, err = parseIDList(v.Get("employee_id")); err != nil {
+		return f, errors.New("invalid employee_id")
+	}
+	for _, st := range splitList(v.Get("status")) {
+		f.Statuses = append(f.Statuses, models.LeaveStatus(strings.ToUpper(st)))
+	}
+	for _, t := range splitList(v.Get("type")) {
+		f.Types = append(f.Types, models.LeaveType(strings.ToUpper(t)))
+	}
+	if f.From, err = utils.ParseOptionalDate(v.Get("from")); err != nil {
+		return f, errors.New("invalid from")
+	}
+	if f.To, err = utils.ParseOptionalDate(v.Get("to")); err != nil {
+		return f, errors.New("invalid to")
+	}
+	return f, nil
+}
+
 // HR routes
 func (c *LeaveController) ListAll(w http.ResponseWriter, r *http.Request) {
-	list, err := c.svc.ListAll()
+	f, err := parseLeaveFilter(r)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.ListAll(f)
 	if err != nil {
 		utils.Error(w, "error", http.StatusInternalServerError)
 		return
@@ -94,14 +150,48 @@ func (c *LeaveController) ListAll(w http.ResponseWriter, r *http.Request) {
 	utils.Success(w, "ok", list, http.StatusOK)
 }
 
+type decisionReq struct {
+	Comment       string `json:"comment"`
+	Reason        string `json:"reason"`
+	Override      bool   `json:"override"`
+	Justification string `json:"justification"`
+}
+
+// decodeDecision reads an optional decision body; an empty body means no comment and no override.
+func decodeDecision(r *http.Request) (decisionReq, error) {
+	var req decisionReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return req, err
+	}
+	return req, nil
+}
+
 func (c *LeaveController) Approve(w http.ResponseWriter, r *http.Request) {
 	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
 		utils.Error(w, "invalid leave ID", http.StatusBadRequest)
 		return
 	}
-	if err := c.svc.Approve(uint(id64)); err != nil {
-		utils.Error(w, "approve error", http.StatusBadRequest)
+	req, err := decodeDecision(r)
+	if err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	d := services.LeaveDecision{DecidedBy: uid, Comment: req.Comment, Override: req.Override, Justification: req.Justification}
+	if err := c.svc.Approve(uint(id64), d); err != nil {
+		var attErr *services.AttachmentRequiredError
+		var ce *services.LeaveConstraintError
+		switch {
+		case errors.As(err, &attErr):
+			utils.Error(w, attErr.Error(), http.StatusConflict)
+		case errors.As(err, &ce):
+			utils.Error(w, ce.Error()+"; resend with override and justification to approve anyway", http.StatusConflict)
+		case errors.Is(err, services.ErrPayrollLocked):
+			utils.Error(w, err.Error(), http.StatusConflict)
+		default:
+			utils.Error(w, "approve error: "+err.Error(), http.StatusBadRequest)
+		}
 		return
 	}
 	utils.Success(w, "approved", nil, http.StatusOK)
@@ -113,9 +203,271 @@ func (c *LeaveController) Reject(w http.ResponseWriter, r *http.Request) {
 		utils.Error(w, "invalid leave ID", http.StatusBadRequest)
 		return
 	}
-	if err := c.svc.Reject(uint(id64)); err != nil {
-		utils.Error(w, "reject error", http.StatusBadRequest)
+	req, err := decodeDecision(r)
+	if err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	// "reason" is the documented field for rejections; "comment" is accepted for symmetry with approve
+	reason := req.Reason
+	if utils.IsBlank(reason) {
+		reason = req.Comment
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	if err := c.svc.Reject(uint(id64), services.LeaveDecision{DecidedBy: uid, Comment: reason}); err != nil {
+		utils.Error(w, "reject error: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 	utils.Success(w, "rejected", nil, http.StatusOK)
 }
+
+// Attachments
+
+// authorizedLeave loads the leave in the route and checks the caller is HR or the leave's owner.
+func (c *LeaveController) authorizedLeave(w http.ResponseWriter, r *http.Request) (*models.Leave, bool) {
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid leave ID", http.StatusBadRequest)
+		return nil, false
+	}
+	lv, err := c.svc.Get(uint(id64))
+	if err != nil {
+		utils.Error(w, "leave not found", http.StatusNotFound)
+		return nil, false
+	}
+	if role, _ := r.Context().Value(middlewares.CtxUserRole).(string); role == string(models.RoleHR) {
+		return lv, true
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	if emp, err := c.employees.GetByUser(uid); err != nil || emp.ID != lv.EmployeeID {
+		utils.Error(w, "forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return lv, true
+}
+
+func (c *LeaveController) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	lv, ok := c.authorizedLeave(w, r)
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxUploadBytes()+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.Error(w, "missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	att, err := c.attachments.Upload(lv.ID, uid, header.Filename, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUploadTooLarge):
+			utils.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, services.ErrUploadBadFormat):
+			utils.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		default:
+			utils.Error(w, "upload error: "+err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	utils.Success(w, "uploaded", att, http.StatusCreated)
+}
+
+func (c *LeaveController) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	lv, ok := c.authorizedLeave(w, r)
+	if !ok {
+		return
+	}
+	list, err := c.attachments.List(lv.ID)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *LeaveController) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	lv, ok := c.authorizedLeave(w, r)
+	if !ok {
+		return
+	}
+	aid, err := strconv.ParseUint(mux.Vars(r)["aid"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+	att, rc, err := c.attachments.Open(lv.ID, uint(aid))
+	if err != nil {
+		utils.Error(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", att.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(att.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", att.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = io.Copy(w, rc)
+}
+
+func (c *LeaveController) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	lv, ok := c.authorizedLeave(w, r)
+	if !ok {
+		return
+	}
+	aid, err := strconv.ParseUint(mux.Vars(r)["aid"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.attachments.Delete(lv.ID, uint(aid)); err != nil {
+		utils.Error(w, "delete error", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Discussion (leave owner or HR)
+type commentReq struct {
+	Body string `json:"body"`
+}
+
+func (c *LeaveController) ListComments(w http.ResponseWriter, r *http.Request) {
+	lv, ok := c.authorizedLeave(w, r)
+	if !ok {
+		return
+	}
+	list, err := c.svc.ListComments(lv.ID)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *LeaveController) AddComment(w http.ResponseWriter, r *http.Request) {
+	lv, ok := c.authorizedLeave(w, r)
+	if !ok {
+		return
+	}
+	var req commentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	role, _ := r.Context().Value(middlewares.CtxUserRole).(string)
+	cm, err := c.svc.AddComment(lv.ID, uid, models.UserRole(role), req.Body)
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	utils.Success(w, "created", cm, http.StatusCreated)
+}
+
+// Attachment policies (HR)
+func (c *LeaveController) ListAttachmentPolicies(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListAttachmentPolicies()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *LeaveController) SaveAttachmentPolicy(w http.ResponseWriter, r *http.Request) {
+	var req models.LeaveAttachmentPolicy
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.SaveAttachmentPolicy(&req); err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	utils.Success(w, "saved", req, http.StatusOK)
+}
+
+func (c *LeaveController) DeleteAttachmentPolicy(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid policy ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteAttachmentPolicy(uint(id64)); err != nil {
+		utils.Error(w, "delete error", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *LeaveController) ListEntitlements(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListEntitlements()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *LeaveController) SaveEntitlement(w http.ResponseWriter, r *http.Request) {
+	var req models.LeaveEntitlement
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.SaveEntitlement(&req); err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	utils.Success(w, "saved", req, http.StatusOK)
+}
+
+func (c *LeaveController) DeleteEntitlement(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid entitlement ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteEntitlement(uint(id64)); err != nil {
+		utils.Error(w, "delete error", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *LeaveController) MyBalances(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return
+	}
+	c.balances(w, r, emp.ID)
+}
+
+func (c *LeaveController) EmployeeBalances(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	c.balances(w, r, uint(id64))
+}
+
+// balances reports leave balances as of the date query parameter, default today.
+func (c *LeaveController) balances(w http.ResponseWriter, r *http.Request, employeeID uint) {
+	day := time.Now().UTC()
+	if d, err := utils.ParseOptionalDate(r.URL.Query().Get("date")); err != nil {
+		utils.Error(w, "invalid date", http.StatusBadRequest)
+		return
+	} else if d != nil {
+		day = *d
+	}
+	list, err := c.svc.Balances(employeeID, day)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/utils"
+)
+
+type LeaveRuleController struct {
+	db  *gorm.DB
+	svc *services.LeaveRuleService
+}
+
+func NewLeaveRuleController(db *gorm.DB) *LeaveRuleController {
+	return &LeaveRuleController{db: db, svc: services.NewLeaveRuleService(db)}
+}
+
+type blackoutReq struct {
+	DepartmentID uint   `json:"department_id"`
+	Department   string `json:"department"`
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+	Reason       string `json:"reason"`
+}
+
+type staffingRuleReq struct {
+	DepartmentID uint   `json:"department_id"`
+	Department   string `json:"department"`
+	MaxOut       int    `json:"max_out"`
+	MinOnDuty    int    `json:"min_on_duty"`
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+	Description  string `json:"description"`
+}
+
+// HR: blackout periods
+func (c *LeaveRuleController) ListBlackouts(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListBlackouts()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *LeaveRuleController) CreateBlackout(w http.ResponseWriter, r *http.Request) {
+	var req blackoutReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	start, err := utils.ParseDate(req.StartDate)
+	if err != nil {
+		utils.Error(w, "invalid start", http.StatusBadRequest)
+		return
+	}
+	end, err := utils.ParseDate(req.EndDate)
+	if err != nil {
+		utils.Error(w, "invalid end", http.StatusBadRequest)
+		return
+	}
+	b := models.LeaveBlackout{DepartmentID: req.DepartmentID, Department: req.Department, StartDate: start, EndDate: end, Reason: req.Reason}
+	if err := c.svc.CreateBlackout(&b); err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	utils.Success(w, "created", b, http.StatusCreated)
+}
+
+func (c *LeaveRuleController) DeleteBlackout(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid blackout ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteBlackout(uint(id64)); err != nil {
+		utils.Error(w, "delete error", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HR: staffing rules
+func (c *LeaveRuleController) ListStaffingRules(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListStaffingRules()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *LeaveRuleController) CreateStaffingRule(w http.ResponseWriter, r *http.Request) {
+	var req staffingRuleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	start, err := utils.ParseOptionalDate(req.StartDate)
+	if err != nil {
+		utils.Error(w, "invalid start", http.StatusBadRequest)
+		return
+	}
+	end, err := utils.ParseOptionalDate(req.EndDate)
+	if err != nil {
+		utils.Error(w, "invalid end", http.StatusBadRequest)
+		return
+	}
+	rule := models.StaffingRule{
+		DepartmentID: req.DepartmentID,
+		Department:   req.Department,
+		MaxOut:       req.MaxOut,
+		MinOnDuty:    req.MinOnDuty,
+		StartDate:    start,
+		EndDate:      end,
+		Description:  req.Description,
+	}
+	if err := c.svc.CreateStaffingRule(&rule); err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	utils.Success(w, "created", rule, http.StatusCreated)
+}
+
+func (c *LeaveRuleController) DeleteStaffingRule(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid rule ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteStaffingRule(uint(id64)); err != nil {
+		utils.Error(w, "delete error", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/utils"
+)
+
+type LoanController struct {
+	svc       *services.LoanService
+	employees *services.EmployeeService
+}
+
+func NewLoanController(db *gorm.DB) *LoanController {
+	return &LoanController{svc: services.NewLoanService(db), employees: services.NewEmployeeService(db)}
+}
+
+// loanError maps loan service errors to responses.
+func loanError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrOwnLoan):
+		utils.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrLoanStatus), errors.Is(err, services.ErrLoanRunPending),
+		errors.Is(err, services.ErrEmployeeTerminated):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrLoanOverpaid):
+		utils.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// me returns the caller's employee record, answering for them when there is none.
+func (c *LoanController) me(w http.ResponseWriter, r *http.Request) (*models.Employee, bool) {
+	emp, err := c.employees.GetByUser(r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return nil, false
+	}
+	return emp, true
+}
+
+type loanTermsReq struct {
+	Amount       float64 `json:"amount"`
+	InterestRate float64 `json:"interest_rate"`
+	Installments int     `json:"installments"`
+	FirstDueDate string  `json:"first_due_date"`
+}
+
+func (t loanTermsReq) terms() (services.LoanTerms, error) {
+	out := services.LoanTerms{Amount: t.Amount, InterestRate: t.InterestRate, Installments: t.Installments}
+	if t.FirstDueDate != "" {
+		d, err := utils.ParseDate(t.FirstDueDate)
+		if err != nil {
+			return out, errors.New("invalid first_due_date")
+		}
+		out.FirstDueDate = d
+	}
+	return out, nil
+}
+
+// @Summary Preview a repayment schedule
+// @Description Monthly installments with interest at interest_rate / 12 percent on the principal still owed. first_due_date defaults to a month from today.
+// @Tags Loans
+// @Security BearerAuth
+// @Param amount query number true "Amount"
+// @Param installments query int true "Number of monthly installments"
+// @Param interest_rate query number false "Yearly interest rate in percent"
+// @Param first_due_date query string false "YYYY-MM-DD"
+// @Success 200 {object} utils.APIResponse
+// @Router /loans/schedule [get]
+func (c *LoanController) Schedule(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	var req loanTermsReq
+	var err error
+	if req.Amount, err = strconv.ParseFloat(v.Get("amount"), 64); err != nil {
+		utils.Error(w, "invalid amount", http.StatusBadRequest)
+		return
+	}
+	if req.Installments, err = strconv.Atoi(v.Get("installments")); err != nil {
+		utils.Error(w, "invalid installments", http.StatusBadRequest)
+		return
+	}
+	if s := v.Get("interest_rate"); s != "" {
+		if req.InterestRate, err = strconv.ParseFloat(s, 64); err != nil {
+			utils.Error(w, "invalid interest_rate", http.StatusBadRequest)
+			return
+		}
+	}
+	req.FirstDueDate = v.Get("first_due_date")
+	t, err := req.terms()
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	schedule, err := c.svc.PreviewSchedule(t)
+	if err != nil {
+		loanError(w, err)
+		return
+	}
+	utils.Success(w, "ok", schedule, http.StatusOK)
+}
+
+type loanReq struct {
+	Kind         string  `json:"kind"`
+	Amount       float64 `json:"amount"`
+	Installments int     `json:"installments"`
+	FirstDueDate string  `json:"first_due_date"`
+	Reason       string  `json:"reason"`
+}
+
+// @Summary Request a salary advance or loan (Employee)
+// @Description kind ADVANCE (default) or LOAN. Interest, if any, is set on approval. first_due_date defaults to a month from today.
+// @Tags Loans
+// @Security BearerAuth
+// @Param input body loanReq true "Request"
+// @Success 201 {object} utils.APIResponse
+// @Router /loans [post]
+func (c *LoanController) Request(w http.ResponseWriter, r *http.Request) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	var req loanReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	terms, err := loanTermsReq{Amount: req.Amount, Installments: req.Installments, FirstDueDate: req.FirstDueDate}.terms()
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	loan, err := c.svc.Request(emp.ID, uid, services.LoanRequest{
+		Kind: models.LoanKind(strings.ToUpper(req.Kind)), Terms: terms, Reason: req.Reason,
+	})
+	if err != nil {
+		loanError(w, err)
+		return
+	}
+	utils.Success(w, "requested", loan, http.StatusCreated)
+}
+
+// @Summary List my loans and advances with their balances (Employee)
+// @Tags Loans
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /loans/me [get]
+func (c *LoanController) ListMine(w http.ResponseWriter, r *http.Request) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	list, err := c.svc.List(services.LoanFilter{EmployeeID: emp.ID})
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Withdraw my loan request before it is decided (Employee)
+// @Tags Loans
+// @Security BearerAuth
+// @Param id path int true "Loan ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /loans/{id}/cancel [post]
+func (c *LoanController) Cancel(w http.ResponseWriter, r *http.Request) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	loan, err := c.svc.Cancel(emp.ID, id)
+	if err != nil {
+		loanError(w, err)
+		return
+	}
+	utils.Success(w, "cancelled", loan, http.StatusOK)
+}
+
+// @Summary Get a loan with its schedule, repayments and balance
+// @Description Visible to the borrower and payroll.
+// @Tags Loans
+// @Security BearerAuth
+// @Param id path int true "Loan ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /loans/{id} [get]
+func (c *LoanController) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	loan, err := c.svc.Get(id)
+	if err != nil {
+		loanError(w, err)
+		return
+	}
+	if !middlewares.HasPermission(r, models.PermRunPayroll) {
+		emp, err := c.employees.GetByUser(r.Context().Value(middlewares.CtxUserID).(uint))
+		if err != nil || emp.ID != loan.EmployeeID {
+			utils.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+	}
+	utils.Success(w, "ok", loan, http.StatusOK)
+}
+
+// @Summary List loans and advances (Payroll)
+// @Tags Loans
+// @Security BearerAuth
+// @Param employee_id query int false "Employee ID"
+// @Param status query string false "REQUESTED, REJECTED, CANCELLED, ACTIVE or CLOSED"
+// @Param kind query string false "ADVANCE or LOAN"
+// @Success 200 {object} utils.APIResponse
+// @Router /loans [get]
+func (c *LoanController) List(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	f := services.LoanFilter{
+		Status: models.LoanStatus(strings.ToUpper(v.Get("status"))),
+		Kind:   models.LoanKind(strings.ToUpper(v.Get("kind"))),
+	}
+	if e := v.Get("employee_id"); e != "" {
+		id, err := strconv.ParseUint(e, 10, 64)
+		if err != nil {
+			utils.Error(w, "invalid employee_id", http.StatusBadRequest)
+			return
+		}
+		f.EmployeeID = uint(id)
+	}
+	list, err := c.svc.List(f)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+type loanDecisionReq struct {
+	Note string `json:"note"`
+	loanTermsReq
+}
+
+func (c *LoanController) decide(w http.ResponseWriter, r *http.Request, approve bool) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req loanDecisionReq
+	_ = json.NewDecoder(r.Body).Decode(&req) // the body is optional for approvals
+	terms, err := req.terms()
+	if err != nil {
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	by := services.LoanApprover{UserID: uid}
+	if emp, err := c.employees.GetByUser(uid); err == nil {
+		by.EmployeeID = emp.ID
+	}
+	loan, err := c.svc.Decide(id, by, services.LoanDecision{Approve: approve, Note: req.Note, Terms: terms})
+	if err != nil {
+		loanError(w, err)
+		return
+	}
+	utils.Success(w, strings.ToLower(string(loan.Status)), loan, http.StatusOK)
+}
+
+// @Summary Approve a loan request and fix its repayment schedule (Payroll)
+// @Description amount, installments and first_due_date default to the request; interest_rate is the yearly rate in percent, 0 when omitted. Installments are deducted from the payroll runs whose periods end on or after their due dates.
+// @Tags Loans
+// @Security BearerAuth
+// @Param id path int true "Loan ID"
+// @Param input body loanDecisionReq false "Optional note and terms"
+// @Success 200 {object} utils.APIResponse
+// @Router /loans/{id}/approve [post]
+func (c *LoanController) Approve(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, true)
+}
+
+// @Summary Reject a loan request (Payroll)
+// @Tags Loans
+// @Security BearerAuth
+// @Param id path int true "Loan ID"
+// @Param input body loanDecisionReq true "Reason in note"
+// @Success 200 {object} utils.APIResponse
+// @Router /loans/{id}/reject [post]
+func (c *LoanController) Reject(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, false)
+}
+
+type loanDisburseReq struct {
+	Reference string `json:"reference"`
+	Date      string `json:"date"`
+}
+
+// @Summary Record the payment of an approved loan to the employee (Payroll)
+// @Tags Loans
+// @Security BearerAuth
+// @Param id path int true "Loan ID"
+// @Param input body loanDisburseReq true "Payment reference; date as YYYY-MM-DD, defaulting to today"
+// @Success 200 {object} utils.APIResponse
+// @Router /loans/{id}/disburse [post]
+func (c *LoanController) Disburse(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req loanDisburseReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	var on time.Time
+	if req.Date != "" {
+		if on, err = utils.ParseDate(req.Date); err != nil {
+			utils.Error(w, "invalid date", http.StatusBadRequest)
+			return
+		}
+	}
+	loan, err := c.svc.Disburse(id, req.Reference, on)
+	if err != nil {
+		loanError(w, err)
+		return
+	}
+	utils.Success(w, "disbursed", loan, http.StatusOK)
+}
+
+type loanPaymentReq struct {
+	Source    string  `json:"source"`
+	Amount    float64 `json:"amount"`
+	Date      string  `json:"date"`
+	Reference string  `json:"reference"`
+}
+
+// @Summary Record a repayment outside payroll or write off a balance (Payroll)
+// @Description source DIRECT or WRITE_OFF. amount 0 or omitted settles the loan: the installments due by date in full plus the principal of the later ones. The loan closes once nothing is left to pay.
+// @Tags Loans
+// @Security BearerAuth
+// @Param id path int true "Loan ID"
+// @Param input body loanPaymentReq true "Payment; date as YYYY-MM-DD, defaulting to today"
+// @Success 200 {object} utils.APIResponse
+// @Router /loans/{id}/repayments [post]
+func (c *LoanController) RecordPayment(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req loanPaymentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	p := services.LoanPayment{
+		Source:    models.LoanRepaymentSource(strings.ToUpper(req.Source)),
+		Amount:    req.Amount,
+		Reference: req.Reference,
+		By:        r.Context().Value(middlewares.CtxUserID).(uint),
+	}
+	if req.Date != "" {
+		if p.Date, err = utils.ParseDate(req.Date); err != nil {
+			utils.Error(w, "invalid date", http.StatusBadRequest)
+			return
+		}
+	}
+	loan, err := c.svc.RecordPayment(id, p)
+	if err != nil {
+		loanError(w, err)
+		return
+	}
+	utils.Success(w, "recorded", loan, http.StatusOK)
+}
+
+// @Summary Quote what settles an active loan on a day (Payroll)
+// @Tags Loans
+// @Security BearerAuth
+// @Param id path int true "Loan ID"
+// @Param date query string false "YYYY-MM-DD, defaulting to today"
+// @Success 200 {object} utils.APIResponse
+// @Router /loans/{id}/payoff [get]
+func (c *LoanController) Payoff(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	on := time.Now().UTC().Truncate(24 * time.Hour)
+	if s := r.URL.Query().Get("date"); s != "" {
+		if on, err = utils.ParseDate(s); err != nil {
+			utils.Error(w, "invalid date", http.StatusBadRequest)
+			return
+		}
+	}
+	amount, err := c.svc.Payoff(id, on)
+	if err != nil {
+		loanError(w, err)
+		return
+	}
+	utils.Success(w, "ok", map[string]interface{}{"loan_id": id, "date": on.Format("2006-01-02"), "payoff": amount}, http.StatusOK)
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/utils"
+)
+
+type NotificationController struct {
+	db  *gorm.DB
+	svc *services.NotificationService
+}
+
+func NewNotificationController(db *gorm.DB) *NotificationController {
+	return &NotificationController{db: db, svc: services.NewNotificationService(db)}
+}
+
+// @Summary List my notifications
+// @Tags Notifications
+// @Security BearerAuth
+// @Param unread query bool false "Only unread notifications"
+// @Param limit query int false "Maximum number returned (default 50, max 200)"
+// @Success 200 {object} utils.APIResponse
+// @Router /notifications [get]
+func (c *NotificationController) List(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	v := r.URL.Query()
+	unread, _ := strconv.ParseBool(v.Get("unread"))
+	limit := 0
+	if l := v.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil {
+			utils.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	list, err := c.svc.List(uid, unread, limit)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Mark a notification as read
+// @Tags Notifications
+// @Security BearerAuth
+// @Param id path int true "ID"
+// @Success 204 {object} nil
+// @Router /notifications/{id}/read [post]
+func (c *NotificationController) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	if err := c.svc.MarkRead(uid, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Mark all my notifications as read
+// @Tags Notifications
+// @Security BearerAuth
+// @Success 204 {object} nil
+// @Router /notifications/read-all [post]
+func (c *NotificationController) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	if err := c.svc.MarkAllRead(uid); err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
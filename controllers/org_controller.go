@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/utils"
+)
+
+type OrgController struct {
+	db  *gorm.DB
+	svc *services.OrgService
+}
+
+func NewOrgController(db *gorm.DB) *OrgController {
+	return &OrgController{db: db, svc: services.NewOrgService(db)}
+}
+
+func routeID(r *http.Request) (uint, error) {
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	return uint(id64), err
+}
+
+// orgError maps org service errors to responses.
+func orgError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrOrgInUse):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		utils.Error(w, "name already exists", http.StatusConflict)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// Departments
+
+type departmentView struct {
+	models.Department
+	Children []models.Department `json:"children"`
+}
+
+func (c *OrgController) ListDepartments(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListDepartments()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *OrgController) GetDepartment(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid department ID", http.StatusBadRequest)
+		return
+	}
+	d, err := c.svc.GetDepartment(id)
+	if err != nil {
+		orgError(w, err)
+		return
+	}
+	children, err := c.svc.SubDepartments(id)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", departmentView{Department: *d, Children: children}, http.StatusOK)
+}
+
+func (c *OrgController) CreateDepartment(w http.ResponseWriter, r *http.Request) {
+	var req models.Department
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	req.ID = 0
+	if err := c.svc.CreateDepartment(&req); err != nil {
+		orgError(w, err)
+		return
+	}
+	utils.Success(w, "created", req, http.StatusCreated)
+}
+
+func (c *OrgController) UpdateDepartment(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid department ID", http.StatusBadRequest)
+		return
+	}
+	var req models.Department
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	req.ID = id
+	if err := c.svc.UpdateDepartment(&req); err != nil {
+		orgError(w, err)
+		return
+	}
+	utils.Success(w, "updated", req, http.StatusOK)
+}
+
+func (c *OrgController) DeleteDepartment(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid department ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteDepartment(id); err != nil {
+		orgError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Locations
+
+func (c *OrgController) ListLocations(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListLocations()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *OrgController) GetLocation(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid location ID", http.StatusBadRequest)
+		return
+	}
+	l, err := c.svc.GetLocation(id)
+	if err != nil {
+		orgError(w, err)
+		return
+	}
+	utils.Success(w, "ok", l, http.StatusOK)
+}
+
+func (c *OrgController) SaveLocation(w http.ResponseWriter, r *http.Request) {
+	var req models.Location
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	req.ID = 0
+	code := http.StatusCreated
+	if _, ok := mux.Vars(r)["id"]; ok {
+		id, err := routeID(r)
+		if err != nil {
+			utils.Error(w, "invalid location ID", http.StatusBadRequest)
+			return
+		}
+		req.ID, code = id, http.StatusOK
+	}
+	if err := c.svc.SaveLocation(&req); err != nil {
+		orgError(w, err)
+		return
+	}
+	utils.Success(w, "saved", req, code)
+}
+
+func (c *OrgController) DeleteLocation(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid location ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteLocation(id); err != nil {
+		orgError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Job grades
+
+func (c *OrgController) ListGrades(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListGrades()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *OrgController) GetGrade(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid grade ID", http.StatusBadRequest)
+		return
+	}
+	g, err := c.svc.GetGrade(id)
+	if err != nil {
+		orgError(w, err)
+		return
+	}
+	utils.Success(w, "ok", g, http.StatusOK)
+}
+
+func (c *OrgController) SaveGrade(w http.ResponseWriter, r *http.Request) {
+	var req models.JobGrade
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	req.ID = 0
+	code := http.StatusCreated
+	if _, ok := mux.Vars(r)["id"]; ok {
+		id, err := routeID(r)
+		if err != nil {
+			utils.Error(w, "invalid grade ID", http.StatusBadRequest)
+			return
+		}
+		req.ID, code = id, http.StatusOK
+	}
+	if err := c.svc.SaveGrade(&req); err != nil {
+		orgError(w, err)
+		return
+	}
+	utils.Success(w, "saved", req, code)
+}
+
+func (c *OrgController) DeleteGrade(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid grade ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteGrade(id); err != nil {
+		orgError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Positions
+
+func (c *OrgController) ListPositions(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListPositions()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+func (c *OrgController) GetPosition(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid position ID", http.StatusBadRequest)
+		return
+	}
+	p, err := c.svc.GetPosition(id)
+	if err != nil {
+		orgError(w, err)
+		return
+	}
+	utils.Success(w, "ok", p, http.StatusOK)
+}
+
+func (c *OrgController) SavePosition(w http.ResponseWriter, r *http.Request) {
+	var req models.Position
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	req.ID = 0
+	code := http.StatusCreated
+	if _, ok := mux.Vars(r)["id"]; ok {
+		id, err := routeID(r)
+		if err != nil {
+			utils.Error(w, "invalid position ID", http.StatusBadRequest)
+			return
+		}
+		req.ID, code = id, http.StatusOK
+	}
+	if err := c.svc.SavePosition(&req); err != nil {
+		orgError(w, err)
+		return
+	}
+	utils.Success(w, "saved", req, code)
+}
+
+func (c *OrgController) DeletePosition(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid position ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeletePosition(id); err != nil {
+		orgError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,513 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/storage"
+	"github.com/example/hrms-backend/utils"
+)
+
+type PayrollController struct {
+	svc       *services.PayrollService
+	payslips  *services.PayslipService
+	employees *services.EmployeeService
+}
+
+func NewPayrollController(db *gorm.DB) *PayrollController {
+	return &PayrollController{
+		svc:       services.NewPayrollService(db),
+		payslips:  services.NewPayslipService(db, storage.Default()),
+		employees: services.NewEmployeeService(db),
+	}
+}
+
+// payrollError maps payroll service errors to responses.
+func payrollError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrPayrollLocked), errors.Is(err, services.ErrPayrollStatus),
+		errors.Is(err, services.ErrPayrollStale), errors.Is(err, services.ErrPayPeriodInUse):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+type payPeriodReq struct {
+	Name      string              `json:"name"`
+	Frequency models.PayFrequency `json:"frequency"`
+	StartDate string              `json:"start_date"`
+	EndDate   string              `json:"end_date"`
+	PayDate   string              `json:"pay_date"`
+}
+
+// @Summary List pay periods (Payroll)
+// @Tags Payroll
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/periods [get]
+func (c *PayrollController) ListPeriods(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListPeriods()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Create a pay period (Payroll)
+// @Description Frequency is MONTHLY (default), SEMI_MONTHLY, BIWEEKLY or WEEKLY. pay_date defaults to end_date. Periods cannot overlap.
+// @Tags Payroll
+// @Security BearerAuth
+// @Param input body payPeriodReq true "Period; dates as YYYY-MM-DD"
+// @Success 201 {object} utils.APIResponse
+// @Router /payroll/periods [post]
+func (c *PayrollController) CreatePeriod(w http.ResponseWriter, r *http.Request) {
+	var req payPeriodReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	p := models.PayPeriod{Name: req.Name, Frequency: models.PayFrequency(strings.ToUpper(string(req.Frequency)))}
+	var err error
+	if p.StartDate, err = utils.ParseDate(req.StartDate); err != nil {
+		utils.Error(w, "invalid start_date", http.StatusBadRequest)
+		return
+	}
+	if p.EndDate, err = utils.ParseDate(req.EndDate); err != nil {
+		utils.Error(w, "invalid end_date", http.StatusBadRequest)
+		return
+	}
+	if req.PayDate != "" {
+		if p.PayDate, err = utils.ParseDate(req.PayDate); err != nil {
+			utils.Error(w, "invalid pay_date", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := c.svc.CreatePeriod(&p); err != nil {
+		payrollError(w, err)
+		return
+	}
+	utils.Success(w, "created", p, http.StatusCreated)
+}
+
+// @Summary Delete a pay period without a run (Payroll)
+// @Tags Payroll
+// @Security BearerAuth
+// @Param id path int true "Period ID"
+// @Success 204 {object} nil
+// @Router /payroll/periods/{id} [delete]
+func (c *PayrollController) DeletePeriod(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeletePeriod(id); err != nil {
+		payrollError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List deduction rules (Payroll)
+// @Tags Payroll
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/deductions [get]
+func (c *PayrollController) ListDeductions(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListDeductions()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Create or replace a deduction rule (Payroll)
+// @Description PERCENT deducts rate percent of gross pay, FIXED deducts rate per period. cap limits the amount (0 for none); department_id restricts the rule to one department. Draft runs must be recalculated afterwards.
+// @Tags Payroll
+// @Security BearerAuth
+// @Param input body models.DeductionRule true "Rule"
+// @Success 201 {object} utils.APIResponse
+// @Router /payroll/deductions [post]
+func (c *PayrollController) SaveDeduction(w http.ResponseWriter, r *http.Request) {
+	var d models.DeductionRule
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	d.ID = 0
+	code := http.StatusCreated
+	if r.Method == http.MethodPut {
+		id, err := routeID(r)
+		if err != nil {
+			utils.Error(w, "invalid ID", http.StatusBadRequest)
+			return
+		}
+		d.ID, code = id, http.StatusOK
+	}
+	if err := c.svc.SaveDeduction(&d); err != nil {
+		payrollError(w, err)
+		return
+	}
+	utils.Success(w, "saved", d, code)
+}
+
+// @Summary Delete a deduction rule (Payroll)
+// @Tags Payroll
+// @Security BearerAuth
+// @Param id path int true "Rule ID"
+// @Success 204 {object} nil
+// @Router /payroll/deductions/{id} [delete]
+func (c *PayrollController) DeleteDeduction(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteDeduction(id); err != nil {
+		payrollError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List payroll runs (Payroll)
+// @Tags Payroll
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/runs [get]
+func (c *PayrollController) ListRuns(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListRuns()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Get a payroll run with its lines and line items (Payroll)
+// @Tags Payroll
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/runs/{id} [get]
+func (c *PayrollController) GetRun(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	run, err := c.svc.GetRun(id)
+	if err != nil {
+		payrollError(w, err)
+		return
+	}
+	utils.Success(w, "ok", run, http.StatusOK)
+}
+
+type payrollRunReq struct {
+	PeriodID uint `json:"period_id"`
+}
+
+// @Summary Calculate a draft payroll run for a period (Payroll)
+// @Description Pays every employee employed during the period its share of their salary, less loss of pay for absences and approved unpaid leave, plus approved overtime, less active deduction rules.
+// @Tags Payroll
+// @Security BearerAuth
+// @Param input body payrollRunReq true "Period"
+// @Success 201 {object} utils.APIResponse
+// @Router /payroll/runs [post]
+func (c *PayrollController) CreateRun(w http.ResponseWriter, r *http.Request) {
+	var req payrollRunReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PeriodID == 0 {
+		utils.Error(w, "period_id is required", http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	run, err := c.svc.CreateRun(req.PeriodID, uid)
+	if err != nil {
+		payrollError(w, err)
+		return
+	}
+	utils.Success(w, "created", run, http.StatusCreated)
+}
+
+// @Summary Recalculate a draft payroll run (Payroll)
+// @Tags Payroll
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/runs/{id}/recalculate [post]
+func (c *PayrollController) RecalculateRun(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	run, err := c.svc.RecalculateRun(id)
+	if err != nil {
+		payrollError(w, err)
+		return
+	}
+	utils.Success(w, "recalculated", run, http.StatusOK)
+}
+
+// @Summary Delete a draft payroll run (Payroll)
+// @Tags Payroll
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 204 {object} nil
+// @Router /payroll/runs/{id} [delete]
+func (c *PayrollController) DeleteRun(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteRun(id); err != nil {
+		payrollError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Mark a draft payroll run reviewed (Payroll)
+// @Description Locks the period: attendance, leave approvals, overtime and job changes dated in it are rejected until the run is reopened. A run whose inputs changed must be recalculated first.
+// @Tags Payroll
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/runs/{id}/review [post]
+func (c *PayrollController) Review(w http.ResponseWriter, r *http.Request) {
+	c.transition(w, r, c.svc.Review, "reviewed")
+}
+
+// @Summary Reopen a reviewed payroll run as draft (Payroll)
+// @Tags Payroll
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/runs/{id}/reopen [post]
+func (c *PayrollController) Reopen(w http.ResponseWriter, r *http.Request) {
+	c.transition(w, r, func(id, _ uint) (*models.PayrollRun, error) { return c.svc.Reopen(id) }, "reopened")
+}
+
+// @Summary Finalize a reviewed payroll run and generate its payslips (Payroll)
+// @Description The run stays finalized when payslip generation fails; the message says so and the payslips can be generated again.
+// @Tags Payroll
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/runs/{id}/finalize [post]
+func (c *PayrollController) Finalize(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	run, err := c.svc.Finalize(id, r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		payrollError(w, err)
+		return
+	}
+	msg := "finalized"
+	if _, err := c.payslips.GenerateRun(run.ID); err != nil {
+		log.Printf("payslips of payroll run %d: %v", run.ID, err)
+		msg = "finalized, but generating payslips failed; retry with POST /payroll/runs/" + strconv.FormatUint(uint64(run.ID), 10) + "/payslips"
+	}
+	utils.Success(w, msg, run, http.StatusOK)
+}
+
+// @Summary Mark a finalized payroll run paid (Payroll)
+// @Tags Payroll
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/runs/{id}/pay [post]
+func (c *PayrollController) MarkPaid(w http.ResponseWriter, r *http.Request) {
+	c.transition(w, r, c.svc.MarkPaid, "paid")
+}
+
+func (c *PayrollController) transition(w http.ResponseWriter, r *http.Request,
+	fn func(id, by uint) (*models.PayrollRun, error), msg string) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	run, err := fn(id, uid)
+	if err != nil {
+		payrollError(w, err)
+		return
+	}
+	utils.Success(w, msg, run, http.StatusOK)
+}
+
+type overtimeReq struct {
+	Date       string  `json:"date"`
+	Hours      float64 `json:"hours"`
+	Multiplier float64 `json:"multiplier"`
+	Reason     string  `json:"reason"`
+}
+
+// @Summary Submit overtime for approval (Employee)
+// @Description multiplier defaults to 1.5.
+// @Tags Overtime
+// @Security BearerAuth
+// @Param input body overtimeReq true "Overtime; date as YYYY-MM-DD"
+// @Success 201 {object} utils.APIResponse
+// @Router /overtime [post]
+func (c *PayrollController) SubmitOvertime(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return
+	}
+	var req overtimeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	d, err := utils.ParseDate(req.Date)
+	if err != nil {
+		utils.Error(w, "invalid date", http.StatusBadRequest)
+		return
+	}
+	o := models.OvertimeEntry{EmployeeID: emp.ID, Date: d, Hours: req.Hours, Multiplier: req.Multiplier, Reason: strings.TrimSpace(req.Reason)}
+	if err := c.svc.SubmitOvertime(&o); err != nil {
+		payrollError(w, err)
+		return
+	}
+	utils.Success(w, "submitted", o, http.StatusCreated)
+}
+
+// @Summary List my overtime (Employee)
+// @Tags Overtime
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /overtime/me [get]
+func (c *PayrollController) MyOvertime(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.ListOvertime(services.OvertimeFilter{EmployeeID: emp.ID})
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Withdraw my pending overtime (Employee)
+// @Tags Overtime
+// @Security BearerAuth
+// @Param id path int true "Overtime ID"
+// @Success 204 {object} nil
+// @Router /overtime/me/{id} [delete]
+func (c *PayrollController) DeleteMyOvertime(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteMyOvertime(emp.ID, id); err != nil {
+		payrollError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List overtime (HR)
+// @Tags Overtime
+// @Security BearerAuth
+// @Param status query string false "PENDING, APPROVED or REJECTED"
+// @Param employee_id query int false "Employee ID"
+// @Param from query string false "From date (YYYY-MM-DD)"
+// @Param to query string false "To date (YYYY-MM-DD)"
+// @Success 200 {object} utils.APIResponse
+// @Router /overtime [get]
+func (c *PayrollController) ListOvertime(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	f := services.OvertimeFilter{Status: models.OvertimeStatus(strings.ToUpper(v.Get("status")))}
+	if e := v.Get("employee_id"); e != "" {
+		id, err := strconv.ParseUint(e, 10, 64)
+		if err != nil {
+			utils.Error(w, "invalid employee_id", http.StatusBadRequest)
+			return
+		}
+		f.EmployeeID = uint(id)
+	}
+	var err error
+	if f.From, err = utils.ParseOptionalDate(v.Get("from")); err != nil {
+		utils.Error(w, "invalid from", http.StatusBadRequest)
+		return
+	}
+	if f.To, err = utils.ParseOptionalDate(v.Get("to")); err != nil {
+		utils.Error(w, "invalid to", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.ListOvertime(f)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Approve overtime (HR)
+// @Tags Overtime
+// @Security BearerAuth
+// @Param id path int true "Overtime ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /overtime/{id}/approve [post]
+func (c *PayrollController) ApproveOvertime(w http.ResponseWriter, r *http.Request) {
+	c.decideOvertime(w, r, true)
+}
+
+// @Summary Reject overtime (HR)
+// @Tags Overtime
+// @Security BearerAuth
+// @Param id path int true "Overtime ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /overtime/{id}/reject [post]
+func (c *PayrollController) RejectOvertime(w http.ResponseWriter, r *http.Request) {
+	c.decideOvertime(w, r, false)
+}
+
+func (c *PayrollController) decideOvertime(w http.ResponseWriter, r *http.Request, approve bool) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	o, err := c.svc.DecideOvertime(id, approve, uid)
+	if err != nil {
+		payrollError(w, err)
+		return
+	}
+	utils.Success(w, strings.ToLower(string(o.Status)), o, http.StatusOK)
+}
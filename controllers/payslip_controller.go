@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/storage"
+	"github.com/example/hrms-backend/utils"
+)
+
+type PayslipController struct {
+	svc       *services.PayslipService
+	employees *services.EmployeeService
+}
+
+func NewPayslipController(db *gorm.DB) *PayslipController {
+	return &PayslipController{svc: services.NewPayslipService(db, storage.Default()), employees: services.NewEmployeeService(db)}
+}
+
+// payslipError maps payslip service errors to responses.
+func payslipError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, storage.ErrNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrPayslipForbidden):
+		utils.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrRunNotFinalized):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrUploadTooLarge):
+		utils.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// me resolves the caller's employee record, answering 404 when there is none.
+func (c *PayslipController) me(w http.ResponseWriter, r *http.Request) (*models.Employee, bool) {
+	emp, err := c.employees.GetByUser(r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusNotFound)
+		return nil, false
+	}
+	return emp, true
+}
+
+// @Summary List my payslips, newest first (Employee)
+// @Tags Payslips
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /payslips [get]
+func (c *PayslipController) ListMine(w http.ResponseWriter, r *http.Request) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	list, err := c.svc.ListMine(emp.ID)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Download a payslip PDF (owner or Payroll)
+// @Tags Payslips
+// @Security BearerAuth
+// @Produce application/pdf
+// @Param id path int true "Payslip ID"
+// @Success 200 {file} file
+// @Router /payslips/{id}/download [get]
+func (c *PayslipController) Download(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	v := services.Viewer{UserID: r.Context().Value(middlewares.CtxUserID).(uint), Role: userRole(r)}
+	if emp, err := c.employees.GetByUser(v.UserID); err == nil {
+		v.EmployeeID = emp.ID
+	}
+	slip, rc, err := c.svc.Open(v, id)
+	if err != nil {
+		payslipError(w, err)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Length", strconv.FormatInt(slip.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", slip.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = io.Copy(w, rc)
+}
+
+type payslipPasswordReq struct {
+	Password string `json:"password"`
+}
+
+// @Summary Set or remove my payslip password (Employee)
+// @Description Payslips are encrypted with the password from then on; existing payslips are regenerated with it. An empty password removes the protection.
+// @Tags Payslips
+// @Security BearerAuth
+// @Param input body payslipPasswordReq true "Password of 6 to 32 characters, or empty"
+// @Success 200 {object} utils.APIResponse
+// @Router /payslips/password [put]
+func (c *PayslipController) SetPassword(w http.ResponseWriter, r *http.Request) {
+	emp, ok := c.me(w, r)
+	if !ok {
+		return
+	}
+	var req payslipPasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	n, err := c.svc.SetPassword(emp.ID, req.Password)
+	if err != nil {
+		payslipError(w, err)
+		return
+	}
+	utils.Success(w, "saved", map[string]interface{}{"protected": req.Password != "", "regenerated": n}, http.StatusOK)
+}
+
+// @Summary List the payslips of a payroll run (Payroll)
+// @Tags Payslips
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/runs/{id}/payslips [get]
+func (c *PayslipController) ListRun(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.ListRun(id)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Generate the payslips of a finalized payroll run again (Payroll)
+// @Description Uses the current default template and replaces the existing payslips.
+// @Tags Payslips
+// @Security BearerAuth
+// @Param id path int true "Run ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payroll/runs/{id}/payslips [post]
+func (c *PayslipController) GenerateRun(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.GenerateRun(id)
+	if err != nil {
+		payslipError(w, err)
+		return
+	}
+	utils.Success(w, "generated", list, http.StatusOK)
+}
+
+// @Summary List payslip templates (Payroll)
+// @Tags Payslips
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /payslip-templates [get]
+func (c *PayslipController) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListTemplates()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Get a payslip template (Payroll)
+// @Tags Payslips
+// @Security BearerAuth
+// @Param id path int true "Template ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /payslip-templates/{id} [get]
+func (c *PayslipController) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	t, err := c.svc.GetTemplate(id)
+	if err != nil {
+		payslipError(w, err)
+		return
+	}
+	utils.Success(w, "ok", t, http.StatusOK)
+}
+
+// @Summary Create or replace a payslip template (Payroll)
+// @Description The default template (is_default) is used for new payslips; without one a built-in layout applies. brand_color is a "#rrggbb" color.
+// @Tags Payslips
+// @Security BearerAuth
+// @Param input body models.PayslipTemplate true "Template"
+// @Success 201 {object} utils.APIResponse
+// @Router /payslip-templates [post]
+func (c *PayslipController) SaveTemplate(w http.ResponseWriter, r *http.Request) {
+	var t models.PayslipTemplate
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	t.ID = 0
+	code := http.StatusCreated
+	if r.Method == http.MethodPut {
+		id, err := routeID(r)
+		if err != nil {
+			utils.Error(w, "invalid ID", http.StatusBadRequest)
+			return
+		}
+		t.ID, code = id, http.StatusOK
+	}
+	if err := c.svc.SaveTemplate(&t); err != nil {
+		payslipError(w, err)
+		return
+	}
+	utils.Success(w, "saved", t, code)
+}
+
+// @Summary Delete a payslip template (Payroll)
+// @Tags Payslips
+// @Security BearerAuth
+// @Param id path int true "Template ID"
+// @Success 204 {object} nil
+// @Router /payslip-templates/{id} [delete]
+func (c *PayslipController) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteTemplate(id); err != nil {
+		payslipError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Upload a payslip template logo (Payroll)
+// @Description Multipart form with a JPEG "file" of up to 512 KB; without a file the logo is removed.
+// @Tags Payslips
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Param id path int true "Template ID"
+// @Param file formData file false "JPEG logo"
+// @Success 200 {object} utils.APIResponse
+// @Router /payslip-templates/{id}/logo [put]
+func (c *PayslipController) SetLogo(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var logo io.Reader = http.NoBody
+	file, _, err := r.FormFile("file")
+	switch {
+	case err == nil:
+		defer file.Close()
+		logo = file
+	case !errors.Is(err, http.ErrMissingFile):
+		utils.Error(w, "invalid upload", http.StatusBadRequest)
+		return
+	}
+	t, err := c.svc.SetLogo(id, logo)
+	if err != nil {
+		payslipError(w, err)
+		return
+	}
+	utils.Success(w, "saved", t, http.StatusOK)
+}
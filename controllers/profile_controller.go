@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/utils"
+)
+
+type ProfileController struct {
+	db        *gorm.DB
+	svc       *services.ProfileService
+	employees *services.EmployeeService
+}
+
+func NewProfileController(db *gorm.DB) *ProfileController {
+	return &ProfileController{
+		db:        db,
+		svc:       services.NewProfileService(db),
+		employees: services.NewEmployeeService(db),
+	}
+}
+
+// profileError maps profile service errors to responses.
+func profileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrNotYourRequest):
+		utils.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrChangeRequestClosed), errors.Is(err, services.ErrEmployeeTerminated):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Summary Update my profile (Employee)
+// @Description Contact details, address and emergency contacts are applied at once. Changes to the legal name or bank account are queued for HR approval and returned as change_request.
+// @Tags Profile
+// @Security BearerAuth
+// @Param input body services.ProfileUpdate true "Fields to change"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/me [patch]
+func (c *ProfileController) UpdateMine(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusNotFound)
+		return
+	}
+	c.update(w, r, emp.ID, false)
+}
+
+// @Summary Update an employee's personal details (HR)
+// @Description Applies every change directly, including legal name and bank account.
+// @Tags Profile
+// @Security BearerAuth
+// @Param id path int true "Employee ID"
+// @Param input body services.ProfileUpdate true "Fields to change"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/{id}/profile [patch]
+func (c *ProfileController) UpdateEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	c.update(w, r, id, true)
+}
+
+func (c *ProfileController) update(w http.ResponseWriter, r *http.Request, employeeID uint, direct bool) {
+	var req services.ProfileUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	res, err := c.svc.Update(employeeID, req, uid, direct)
+	if err != nil {
+		profileError(w, err)
+		return
+	}
+	msg := "updated"
+	if res.ChangeRequest != nil {
+		msg = "updated; sensitive changes await HR approval"
+	}
+	utils.Success(w, msg, res, http.StatusOK)
+}
+
+// @Summary Get an employee's profile with emergency contacts (HR)
+// @Tags Profile
+// @Security BearerAuth
+// @Param id path int true "Employee ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/{id}/profile [get]
+func (c *ProfileController) GetEmployee(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	emp, err := c.svc.Get(id)
+	if err != nil {
+		profileError(w, err)
+		return
+	}
+	utils.Success(w, "ok", emp, http.StatusOK)
+}
+
+// @Summary List my profile change requests (Employee)
+// @Tags Profile
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/me/change-requests [get]
+func (c *ProfileController) MyChangeRequests(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusNotFound)
+		return
+	}
+	list, err := c.svc.ListChangeRequests(services.ChangeRequestFilter{EmployeeID: emp.ID})
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Withdraw my pending profile change request (Employee)
+// @Tags Profile
+// @Security BearerAuth
+// @Param id path int true "Change request ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/me/change-requests/{id}/cancel [post]
+func (c *ProfileController) CancelMine(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	req, err := c.svc.Cancel(id, uid)
+	if err != nil {
+		profileError(w, err)
+		return
+	}
+	utils.Success(w, "cancelled", req, http.StatusOK)
+}
+
+// @Summary List profile change requests (HR)
+// @Tags Profile
+// @Security BearerAuth
+// @Param status query string false "PENDING, APPROVED, REJECTED or CANCELLED"
+// @Param employee_id query int false "Employee ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /profile-changes [get]
+func (c *ProfileController) ListChangeRequests(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	f := services.ChangeRequestFilter{Status: models.ChangeRequestStatus(strings.ToUpper(v.Get("status")))}
+	if e := v.Get("employee_id"); e != "" {
+		id, err := strconv.ParseUint(e, 10, 64)
+		if err != nil {
+			utils.Error(w, "invalid employee_id", http.StatusBadRequest)
+			return
+		}
+		f.EmployeeID = uint(id)
+	}
+	list, err := c.svc.ListChangeRequests(f)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Approve a profile change request (HR)
+// @Tags Profile
+// @Security BearerAuth
+// @Param id path int true "Change request ID"
+// @Param input body decisionReq false "Decision"
+// @Success 200 {object} utils.APIResponse
+// @Router /profile-changes/{id}/approve [post]
+func (c *ProfileController) Approve(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, c.svc.Approve, "approved")
+}
+
+// @Summary Reject a profile change request (HR)
+// @Tags Profile
+// @Security BearerAuth
+// @Param id path int true "Change request ID"
+// @Param input body decisionReq true "Decision; comment is required"
+// @Success 200 {object} utils.APIResponse
+// @Router /profile-changes/{id}/reject [post]
+func (c *ProfileController) Reject(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, c.svc.Reject, "rejected")
+}
+
+func (c *ProfileController) decide(w http.ResponseWriter, r *http.Request,
+	fn func(id, by uint, note string) (*models.ProfileChangeRequest, error), msg string) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	req, err := decodeDecision(r)
+	if err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	cr, err := fn(id, uid, req.Comment)
+	if err != nil {
+		profileError(w, err)
+		return
+	}
+	utils.Success(w, msg, cr, http.StatusOK)
+}
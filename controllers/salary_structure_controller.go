@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/utils"
+)
+
+type SalaryStructureController struct {
+	svc       *services.SalaryStructureService
+	employees *services.EmployeeService
+}
+
+func NewSalaryStructureController(db *gorm.DB) *SalaryStructureController {
+	return &SalaryStructureController{svc: services.NewSalaryStructureService(db), employees: services.NewEmployeeService(db)}
+}
+
+// salaryStructureError maps salary structure service errors to responses.
+func salaryStructureError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrNoSalaryStructure):
+		utils.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrSalaryStructureInUse), errors.Is(err, services.ErrPayrollLocked),
+		errors.Is(err, services.ErrEmployeeTerminated):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Summary List salary structures with their components (Payroll)
+// @Tags Salary structures
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /salary-structures [get]
+func (c *SalaryStructureController) List(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.List()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Get a salary structure (Payroll)
+// @Tags Salary structures
+// @Security BearerAuth
+// @Param id path int true "Structure ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /salary-structures/{id} [get]
+func (c *SalaryStructureController) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	st, err := c.svc.Get(id)
+	if err != nil {
+		salaryStructureError(w, err)
+		return
+	}
+	utils.Success(w, "ok", st, http.StatusOK)
+}
+
+// @Summary Create or replace a salary structure (Payroll)
+// @Description Components are EARNING or DEDUCTION and of type FIXED (monthly amount), PERCENT_OF_BASIC (percent), FORMULA (formula over CTC and other component codes, e.g. "min(BASIC * 0.12, 1800)") or BALANCE (the rest of the monthly CTC). An earning with code BASIC is required.
+// @Tags Salary structures
+// @Security BearerAuth
+// @Param input body models.SalaryStructure true "Structure with components"
+// @Success 201 {object} utils.APIResponse
+// @Router /salary-structures [post]
+func (c *SalaryStructureController) Save(w http.ResponseWriter, r *http.Request) {
+	var st models.SalaryStructure
+	if err := json.NewDecoder(r.Body).Decode(&st); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	st.ID = 0
+	code := http.StatusCreated
+	if r.Method == http.MethodPut {
+		id, err := routeID(r)
+		if err != nil {
+			utils.Error(w, "invalid ID", http.StatusBadRequest)
+			return
+		}
+		st.ID, code = id, http.StatusOK
+	}
+	if err := c.svc.Save(&st); err != nil {
+		salaryStructureError(w, err)
+		return
+	}
+	utils.Success(w, "saved", st, code)
+}
+
+// @Summary Delete an unassigned salary structure (Payroll)
+// @Tags Salary structures
+// @Security BearerAuth
+// @Param id path int true "Structure ID"
+// @Success 204 {object} nil
+// @Router /salary-structures/{id} [delete]
+func (c *SalaryStructureController) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.Delete(id); err != nil {
+		salaryStructureError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Expand an annual CTC with a salary structure (Payroll)
+// @Tags Salary structures
+// @Security BearerAuth
+// @Param id path int true "Structure ID"
+// @Param ctc query number true "Annual CTC"
+// @Success 200 {object} utils.APIResponse
+// @Router /salary-structures/{id}/preview [get]
+func (c *SalaryStructureController) Preview(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	ctc, err := strconv.ParseFloat(r.URL.Query().Get("ctc"), 64)
+	if err != nil {
+		utils.Error(w, "invalid ctc", http.StatusBadRequest)
+		return
+	}
+	b, err := c.svc.Preview(id, ctc)
+	if err != nil {
+		salaryStructureError(w, err)
+		return
+	}
+	utils.Success(w, "ok", b, http.StatusOK)
+}
+
+// @Summary List an employee's salary structure assignments (HR, Finance)
+// @Tags Salary structures
+// @Security BearerAuth
+// @Param id path int true "Employee ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/{id}/salary-structures [get]
+func (c *SalaryStructureController) ListAssignments(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.ListAssignments(id)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+type salaryAssignmentReq struct {
+	StructureID   uint   `json:"structure_id"`
+	EffectiveDate string `json:"effective_date"`
+	Note          string `json:"note"`
+}
+
+// @Summary Assign a salary structure to an employee from a date (Payroll)
+// @Tags Salary structures
+// @Security BearerAuth
+// @Param id path int true "Employee ID"
+// @Param input body salaryAssignmentReq true "Structure and effective date (YYYY-MM-DD)"
+// @Success 201 {object} utils.APIResponse
+// @Router /employees/{id}/salary-structures [post]
+func (c *SalaryStructureController) Assign(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	var req salaryAssignmentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	eff, err := utils.ParseDate(req.EffectiveDate)
+	if err != nil {
+		utils.Error(w, "invalid effective_date", http.StatusBadRequest)
+		return
+	}
+	a := models.SalaryStructureAssignment{
+		EmployeeID:    id,
+		StructureID:   req.StructureID,
+		EffectiveDate: eff,
+		Note:          req.Note,
+		CreatedBy:     r.Context().Value(middlewares.CtxUserID).(uint),
+	}
+	if err := c.svc.Assign(&a); err != nil {
+		salaryStructureError(w, err)
+		return
+	}
+	utils.Success(w, "assigned", a, http.StatusCreated)
+}
+
+// @Summary Delete a salary structure assignment (Payroll)
+// @Tags Salary structures
+// @Security BearerAuth
+// @Param id path int true "Employee ID"
+// @Param aid path int true "Assignment ID"
+// @Success 204 {object} nil
+// @Router /employees/{id}/salary-structures/{aid} [delete]
+func (c *SalaryStructureController) DeleteAssignment(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	aid, err := strconv.ParseUint(mux.Vars(r)["aid"], 10, 64)
+	if err != nil {
+		utils.Error(w, "invalid assignment ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteAssignment(id, uint(aid)); err != nil {
+		salaryStructureError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary An employee's monthly salary components (HR, Finance)
+// @Tags Salary structures
+// @Security BearerAuth
+// @Param id path int true "Employee ID"
+// @Param date query string false "As of date (YYYY-MM-DD), default today"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/{id}/salary-breakdown [get]
+func (c *SalaryStructureController) Breakdown(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid employee ID", http.StatusBadRequest)
+		return
+	}
+	c.breakdown(w, r, id)
+}
+
+// @Summary My monthly salary components (Employee)
+// @Tags Salary structures
+// @Security BearerAuth
+// @Param date query string false "As of date (YYYY-MM-DD), default today"
+// @Success 200 {object} utils.APIResponse
+// @Router /employees/me/salary-breakdown [get]
+func (c *SalaryStructureController) MyBreakdown(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusNotFound)
+		return
+	}
+	c.breakdown(w, r, emp.ID)
+}
+
+func (c *SalaryStructureController) breakdown(w http.ResponseWriter, r *http.Request, employeeID uint) {
+	day := time.Now().UTC()
+	if d, err := utils.ParseOptionalDate(r.URL.Query().Get("date")); err != nil {
+		utils.Error(w, "invalid date", http.StatusBadRequest)
+		return
+	} else if d != nil {
+		day = *d
+	}
+	b, err := c.svc.EmployeeBreakdown(employeeID, day)
+	if err != nil {
+		salaryStructureError(w, err)
+		return
+	}
+	utils.Success(w, "ok", b, http.StatusOK)
+}
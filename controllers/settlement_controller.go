@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/storage"
+	"github.com/example/hrms-backend/utils"
+)
+
+type SettlementController struct {
+	svc *services.SettlementService
+}
+
+func NewSettlementController(db *gorm.DB) *SettlementController {
+	return &SettlementController{svc: services.NewSettlementService(db, storage.Default())}
+}
+
+// settlementError maps final settlement service errors to responses.
+func settlementError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrSettlementStatus), errors.Is(err, services.ErrSettlementStale),
+		errors.Is(err, services.ErrSettlementRunsOpen), errors.Is(err, services.ErrNotTerminated):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrNotLeaving):
+		utils.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+type settlementReq struct {
+	EmployeeID       uint   `json:"employee_id"`
+	LastWorkingDay   string `json:"last_working_day"`
+	NoticeDate       string `json:"notice_date"`
+	NoticePeriodDays *int   `json:"notice_period_days"`
+}
+
+// @Summary Calculate an employee's final settlement (HR)
+// @Description Creates the employee's draft settlement, or recalculates it, keeping HR adjustments. last_working_day defaults to the termination date or the offboarding's last working day; notice_date to the day the offboarding started; notice_period_days to the notice of the employee's grade.
+// @Tags Settlements
+// @Security BearerAuth
+// @Param input body settlementReq true "Settlement inputs"
+// @Success 200 {object} utils.APIResponse
+// @Router /settlements [post]
+func (c *SettlementController) Calculate(w http.ResponseWriter, r *http.Request) {
+	var req settlementReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.EmployeeID == 0 {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	in := services.SettlementRequest{NoticePeriodDays: req.NoticePeriodDays, By: r.Context().Value(middlewares.CtxUserID).(uint)}
+	if req.LastWorkingDay != "" {
+		d, err := utils.ParseDate(req.LastWorkingDay)
+		if err != nil {
+			utils.Error(w, "invalid last_working_day", http.StatusBadRequest)
+			return
+		}
+		in.LastWorkingDay = d
+	}
+	if req.NoticeDate != "" {
+		d, err := utils.ParseDate(req.NoticeDate)
+		if err != nil {
+			utils.Error(w, "invalid notice_date", http.StatusBadRequest)
+			return
+		}
+		in.NoticeDate = &d
+	}
+	st, err := c.svc.Calculate(req.EmployeeID, in)
+	if err != nil {
+		settlementError(w, err)
+		return
+	}
+	utils.Success(w, "calculated", st, http.StatusOK)
+}
+
+// @Summary List final settlements (HR)
+// @Tags Settlements
+// @Security BearerAuth
+// @Param status query string false "DRAFT or FINALIZED"
+// @Success 200 {object} utils.APIResponse
+// @Router /settlements [get]
+func (c *SettlementController) List(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.List(models.SettlementStatus(strings.ToUpper(r.URL.Query().Get("status"))))
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Get a final settlement with its items (HR)
+// @Tags Settlements
+// @Security BearerAuth
+// @Param id path int true "Settlement ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /settlements/{id} [get]
+func (c *SettlementController) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	st, err := c.svc.Get(id)
+	if err != nil {
+		settlementError(w, err)
+		return
+	}
+	utils.Success(w, "ok", st, http.StatusOK)
+}
+
+// @Summary Recalculate a draft settlement from the current records (HR)
+// @Description Keeps the last working day, notice inputs and adjustments.
+// @Tags Settlements
+// @Security BearerAuth
+// @Param id path int true "Settlement ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /settlements/{id}/recalculate [post]
+func (c *SettlementController) Recalculate(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	st, err := c.svc.Recalculate(id, r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		settlementError(w, err)
+		return
+	}
+	utils.Success(w, "recalculated", st, http.StatusOK)
+}
+
+type settlementAdjustmentReq struct {
+	Label  string  `json:"label"`
+	Amount float64 `json:"amount"`
+	Basis  string  `json:"basis"`
+}
+
+// @Summary Replace the adjustments of a draft settlement (HR)
+// @Description Positive amounts are paid to the employee, negative ones recovered. An empty list removes them.
+// @Tags Settlements
+// @Security BearerAuth
+// @Param id path int true "Settlement ID"
+// @Param input body []settlementAdjustmentReq true "Adjustments"
+// @Success 200 {object} utils.APIResponse
+// @Router /settlements/{id}/adjustments [put]
+func (c *SettlementController) SetAdjustments(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req []settlementAdjustmentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	adj := make([]services.SettlementAdjustment, 0, len(req))
+	for _, a := range req {
+		adj = append(adj, services.SettlementAdjustment{Label: a.Label, Amount: a.Amount, Basis: a.Basis})
+	}
+	st, err := c.svc.SetAdjustments(id, adj)
+	if err != nil {
+		settlementError(w, err)
+		return
+	}
+	utils.Success(w, "updated", st, http.StatusOK)
+}
+
+// @Summary Finalize a settlement (HR)
+// @Description The employee must be terminated and every payroll run paying them finalized; the figures must match the current records. Settles outstanding loans and unpaid claims and excludes the employee from later payroll runs.
+// @Tags Settlements
+// @Security BearerAuth
+// @Param id path int true "Settlement ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /settlements/{id}/finalize [post]
+func (c *SettlementController) Finalize(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	st, err := c.svc.Finalize(id, r.Context().Value(middlewares.CtxUserID).(uint))
+	if err != nil {
+		settlementError(w, err)
+		return
+	}
+	utils.Success(w, "finalized", st, http.StatusOK)
+}
+
+// @Summary Delete a draft settlement (HR)
+// @Tags Settlements
+// @Security BearerAuth
+// @Param id path int true "Settlement ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /settlements/{id} [delete]
+func (c *SettlementController) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteDraft(id); err != nil {
+		settlementError(w, err)
+		return
+	}
+	utils.Success(w, "deleted", nil, http.StatusOK)
+}
+
+// @Summary Download a settlement statement (HR)
+// @Description PDF on the default payslip template's branding; drafts are marked as such.
+// @Tags Settlements
+// @Security BearerAuth
+// @Produce application/pdf
+// @Param id path int true "Settlement ID"
+// @Success 200 {file} file
+// @Router /settlements/{id}/statement [get]
+func (c *SettlementController) Statement(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	doc, st, err := c.svc.Statement(id)
+	if err != nil {
+		settlementError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("settlement-%d-%s.pdf", st.EmployeeID, st.LastWorkingDay.Format("2006-01-02"))))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = w.Write(doc)
+}
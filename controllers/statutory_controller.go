@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/statutory"
+	"github.com/example/hrms-backend/utils"
+)
+
+type StatutoryController struct {
+	svc       *services.StatutoryService
+	employees *services.EmployeeService
+}
+
+func NewStatutoryController(db *gorm.DB) *StatutoryController {
+	return &StatutoryController{svc: services.NewStatutoryService(db), employees: services.NewEmployeeService(db)}
+}
+
+// statutoryError maps statutory service errors to responses.
+func statutoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrPayrollLocked):
+		utils.Error(w, err.Error(), http.StatusConflict)
+	default:
+		utils.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// @Summary List the statutory rule engines (Payroll)
+// @Tags Statutory
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /statutory/engines [get]
+func (c *StatutoryController) ListEngines(w http.ResponseWriter, r *http.Request) {
+	utils.Success(w, "ok", c.svc.ListEngines(), http.StatusOK)
+}
+
+// @Summary List statutory rule sets, newest version per country first (Payroll)
+// @Tags Statutory
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /statutory/rule-sets [get]
+func (c *StatutoryController) ListRuleSets(w http.ResponseWriter, r *http.Request) {
+	list, err := c.svc.ListRuleSets()
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Get a statutory rule set (Payroll)
+// @Tags Statutory
+// @Security BearerAuth
+// @Param id path int true "Rule set ID"
+// @Success 200 {object} utils.APIResponse
+// @Router /statutory/rule-sets/{id} [get]
+func (c *StatutoryController) GetRuleSet(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	rs, err := c.svc.GetRuleSet(id)
+	if err != nil {
+		statutoryError(w, err)
+		return
+	}
+	utils.Success(w, "ok", rs, http.StatusOK)
+}
+
+type ruleSetReq struct {
+	Name              string          `json:"name"`
+	Country           string          `json:"country"`
+	EffectiveFrom     string          `json:"effective_from"`
+	Engine            string          `json:"engine"`
+	TaxYearStartMonth int             `json:"tax_year_start_month"`
+	Params            json.RawMessage `json:"params"`
+}
+
+// @Summary Create or replace a statutory rule set version (Payroll)
+// @Description A version applies to pay periods ending from effective_from until the next version of the same country; an empty country is the default. params are interpreted by the engine. Versions used by a locked payroll period cannot change.
+// @Tags Statutory
+// @Security BearerAuth
+// @Param input body ruleSetReq true "Rule set; effective_from as YYYY-MM-DD"
+// @Success 201 {object} utils.APIResponse
+// @Router /statutory/rule-sets [post]
+func (c *StatutoryController) SaveRuleSet(w http.ResponseWriter, r *http.Request) {
+	var req ruleSetReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	eff, err := utils.ParseDate(req.EffectiveFrom)
+	if err != nil {
+		utils.Error(w, "invalid effective_from", http.StatusBadRequest)
+		return
+	}
+	rs := models.StatutoryRuleSet{
+		Name:              req.Name,
+		Country:           req.Country,
+		EffectiveFrom:     eff,
+		Engine:            req.Engine,
+		TaxYearStartMonth: req.TaxYearStartMonth,
+		Params:            req.Params,
+		CreatedBy:         r.Context().Value(middlewares.CtxUserID).(uint),
+	}
+	code := http.StatusCreated
+	if r.Method == http.MethodPut {
+		if rs.ID, err = routeID(r); err != nil {
+			utils.Error(w, "invalid ID", http.StatusBadRequest)
+			return
+		}
+		code = http.StatusOK
+	}
+	if err := c.svc.SaveRuleSet(&rs); err != nil {
+		statutoryError(w, err)
+		return
+	}
+	utils.Success(w, "saved", rs, code)
+}
+
+// @Summary Delete a statutory rule set version (Payroll)
+// @Tags Statutory
+// @Security BearerAuth
+// @Param id path int true "Rule set ID"
+// @Success 204 {object} nil
+// @Router /statutory/rule-sets/{id} [delete]
+func (c *StatutoryController) DeleteRuleSet(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteRuleSet(id); err != nil {
+		statutoryError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Compute a rule set's deductions for a sample period (Payroll)
+// @Description gross defaults to the sum of earnings.
+// @Tags Statutory
+// @Security BearerAuth
+// @Param id path int true "Rule set ID"
+// @Param input body statutory.Input true "Period pay, year-to-date amounts and declarations"
+// @Success 200 {object} utils.APIResponse
+// @Router /statutory/rule-sets/{id}/preview [post]
+func (c *StatutoryController) Preview(w http.ResponseWriter, r *http.Request) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var in statutory.Input
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.Preview(id, in)
+	if err != nil {
+		statutoryError(w, err)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+type declarationReq struct {
+	TaxYear     int     `json:"tax_year"`
+	Category    string  `json:"category"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+// @Summary Declare an amount for a tax year, e.g. investments, for approval (Employee)
+// @Description tax_year is the calendar year the tax year starts in; category is an exemption category of the rule sets.
+// @Tags Statutory
+// @Security BearerAuth
+// @Param input body declarationReq true "Declaration"
+// @Success 201 {object} utils.APIResponse
+// @Router /tax-declarations [post]
+func (c *StatutoryController) SubmitDeclaration(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return
+	}
+	var req declarationReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	d := models.TaxDeclaration{EmployeeID: emp.ID, TaxYear: req.TaxYear, Category: req.Category, Description: req.Description, Amount: req.Amount}
+	if err := c.svc.SubmitDeclaration(&d); err != nil {
+		statutoryError(w, err)
+		return
+	}
+	utils.Success(w, "submitted", d, http.StatusCreated)
+}
+
+// @Summary List my tax declarations (Employee)
+// @Tags Statutory
+// @Security BearerAuth
+// @Success 200 {object} utils.APIResponse
+// @Router /tax-declarations/me [get]
+func (c *StatutoryController) MyDeclarations(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return
+	}
+	list, err := c.svc.ListDeclarations(services.DeclarationFilter{EmployeeID: emp.ID})
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+// @Summary Withdraw my pending tax declaration (Employee)
+// @Tags Statutory
+// @Security BearerAuth
+// @Param id path int true "Declaration ID"
+// @Success 204 {object} nil
+// @Router /tax-declarations/me/{id} [delete]
+func (c *StatutoryController) DeleteMyDeclaration(w http.ResponseWriter, r *http.Request) {
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	emp, err := c.employees.GetByUser(uid)
+	if err != nil {
+		utils.Error(w, "employee not found", http.StatusBadRequest)
+		return
+	}
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := c.svc.DeleteMyDeclaration(emp.ID, id); err != nil {
+		statutoryError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List tax declarations (Payroll)
+// @Tags Statutory
+// @Security BearerAuth
+// @Param employee_id query int false "Employee ID"
+// @Param tax_year query int false "Tax year"
+// @Param status query string false "PENDING, APPROVED or REJECTED"
+// @Success 200 {object} utils.APIResponse
+// @Router /tax-declarations [get]
+func (c *StatutoryController) ListDeclarations(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	f := services.DeclarationFilter{Status: models.TaxDeclarationStatus(strings.ToUpper(v.Get("status")))}
+	if e := v.Get("employee_id"); e != "" {
+		id, err := strconv.ParseUint(e, 10, 64)
+		if err != nil {
+			utils.Error(w, "invalid employee_id", http.StatusBadRequest)
+			return
+		}
+		f.EmployeeID = uint(id)
+	}
+	if y := v.Get("tax_year"); y != "" {
+		year, err := strconv.Atoi(y)
+		if err != nil {
+			utils.Error(w, "invalid tax_year", http.StatusBadRequest)
+			return
+		}
+		f.TaxYear = year
+	}
+	list, err := c.svc.ListDeclarations(f)
+	if err != nil {
+		utils.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	utils.Success(w, "ok", list, http.StatusOK)
+}
+
+type declarationDecisionReq struct {
+	Note string `json:"note"`
+}
+
+// @Summary Approve a tax declaration (Payroll)
+// @Tags Statutory
+// @Security BearerAuth
+// @Param id path int true "Declaration ID"
+// @Param input body declarationDecisionReq false "Optional note"
+// @Success 200 {object} utils.APIResponse
+// @Router /tax-declarations/{id}/approve [post]
+func (c *StatutoryController) ApproveDeclaration(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, true)
+}
+
+// @Summary Reject a tax declaration (Payroll)
+// @Tags Statutory
+// @Security BearerAuth
+// @Param id path int true "Declaration ID"
+// @Param input body declarationDecisionReq false "Optional note"
+// @Success 200 {object} utils.APIResponse
+// @Router /tax-declarations/{id}/reject [post]
+func (c *StatutoryController) RejectDeclaration(w http.ResponseWriter, r *http.Request) {
+	c.decide(w, r, false)
+}
+
+func (c *StatutoryController) decide(w http.ResponseWriter, r *http.Request, approve bool) {
+	id, err := routeID(r)
+	if err != nil {
+		utils.Error(w, "invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req declarationDecisionReq
+	_ = json.NewDecoder(r.Body).Decode(&req) // the note is optional
+	uid := r.Context().Value(middlewares.CtxUserID).(uint)
+	d, err := c.svc.DecideDeclaration(id, approve, uid, req.Note)
+	if err != nil {
+		statutoryError(w, err)
+		return
+	}
+	utils.Success(w, strings.ToLower(string(d.Status)), d, http.StatusOK)
+}
@@ -0,0 +1,271 @@
+// Package encryption protects sensitive columns with envelope encryption: every value is
+// encrypted with its own random data key, and the data key is wrapped with a key-encryption key
+// from the keyring. Rotating the primary key only requires re-wrapping data keys.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrNoKeys     = errors.New("encryption: no keys configured")
+	ErrUnknownKey = errors.New("encryption: unknown key id")
+	ErrMalformed  = errors.New("encryption: malformed ciphertext")
+)
+
+// prefix marks ciphertexts: enc:v1:<key id>:<wrapped data key>:<nonce and sealed data>.
+const prefix = "enc:v1:"
+
+// KeySize is the size of key-encryption, data and index keys (AES-256).
+const KeySize = 32
+
+var keyID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+var b64 = base64.RawURLEncoding
+
+// KeySet is the key material a KeyProvider supplies. New values are encrypted under Primary; the
+// other keys are kept to decrypt older values. IndexKey keys blind indexes and is not rotated,
+// as that would invalidate every index.
+type KeySet struct {
+	Primary  string
+	Keys     map[string][]byte
+	IndexKey []byte
+}
+
+// Keyring encrypts and decrypts values with a KeySet.
+type Keyring struct {
+	primary  string
+	keks     map[string]cipher.AEAD
+	indexKey []byte
+}
+
+func NewKeyring(ks *KeySet) (*Keyring, error) {
+	if ks == nil || len(ks.Keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	k := &Keyring{primary: ks.Primary, keks: map[string]cipher.AEAD{}}
+	for id, secret := range ks.Keys {
+		if !keyID.MatchString(id) {
+			return nil, fmt.Errorf("encryption: invalid key id %q", id)
+		}
+		aead, err := newAEAD(secret)
+		if err != nil {
+			return nil, fmt.Errorf("encryption: key %q: %w", id, err)
+		}
+		k.keks[id] = aead
+	}
+	if _, ok := k.keks[ks.Primary]; !ok {
+		return nil, fmt.Errorf("encryption: primary key %q is not in the keyring", ks.Primary)
+	}
+	if len(ks.IndexKey) < KeySize {
+		return nil, fmt.Errorf("encryption: index key must be at least %d bytes", KeySize)
+	}
+	k.indexKey = ks.IndexKey
+	return k, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plain with aead under a random nonce, returning nonce||ciphertext.
+func seal(aead cipher.AEAD, plain, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plain, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	n := aead.NonceSize()
+	return aead.Open(nil, sealed[:n], sealed[n:], aad)
+}
+
+// Primary returns the id of the key new values are encrypted under.
+func (k *Keyring) Primary() string { return k.primary }
+
+// wrap seals a data key under key id; the id is authenticated so a wrapped key cannot be relabeled.
+func (k *Keyring) wrap(id string, dek []byte) (string, error) {
+	w, err := seal(k.keks[id], dek, []byte(id))
+	if err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(w), nil
+}
+
+func (k *Keyring) unwrap(id, wrapped string) ([]byte, error) {
+	kek, ok := k.keks[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	w, err := b64.DecodeString(wrapped)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	dek, err := open(kek, w, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("encryption: cannot unwrap data key: %w", err)
+	}
+	return dek, nil
+}
+
+// Encrypt seals plain under a fresh data key wrapped with the primary key.
+func (k *Keyring) Encrypt(plain []byte) (string, error) {
+	dek := make([]byte, KeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	data, err := seal(aead, plain, nil)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := k.wrap(k.primary, dek)
+	if err != nil {
+		return "", err
+	}
+	return prefix + k.primary + ":" + wrapped + ":" + b64.EncodeToString(data), nil
+}
+
+// parse splits a ciphertext into key id, wrapped data key and sealed data.
+func parse(s string) (id, wrapped, data string, err error) {
+	if !IsEncrypted(s) {
+		return "", "", "", ErrMalformed
+	}
+	parts := strings.Split(strings.TrimPrefix(s, prefix), ":")
+	if len(parts) != 3 {
+		return "", "", "", ErrMalformed
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+func (k *Keyring) Decrypt(s string) ([]byte, error) {
+	id, wrapped, data, err := parse(s)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := k.unwrap(id, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	sealed, err := b64.DecodeString(data)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	plain, err := open(aead, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: cannot decrypt: %w", err)
+	}
+	return plain, nil
+}
+
+// Rewrap brings a stored value up to date: plaintext is encrypted, and a ciphertext under an
+// older key gets its data key re-wrapped with the primary key, leaving the data itself untouched.
+// changed is false when s is already under the primary key.
+func (k *Keyring) Rewrap(s string) (out string, changed bool, err error) {
+	if !IsEncrypted(s) {
+		out, err = k.Encrypt([]byte(s))
+		return out, err == nil, err
+	}
+	id, wrapped, data, err := parse(s)
+	if err != nil {
+		return "", false, err
+	}
+	if id == k.primary {
+		return s, false, nil
+	}
+	dek, err := k.unwrap(id, wrapped)
+	if err != nil {
+		return "", false, err
+	}
+	if wrapped, err = k.wrap(k.primary, dek); err != nil {
+		return "", false, err
+	}
+	return prefix + k.primary + ":" + wrapped + ":" + data, true, nil
+}
+
+// BlindIndex returns a keyed hash of value for equality lookups on an encrypted column. purpose
+// separates indexes, so equal values in different columns do not share an index. Callers
+// normalize value first; an empty value has no index.
+func (k *Keyring) BlindIndex(purpose, value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted reports whether s is a ciphertext rather than a legacy plaintext value.
+func IsEncrypted(s string) bool { return strings.HasPrefix(s, prefix) }
+
+// KeyPrefix is the prefix of every ciphertext under key id, for finding values to rotate.
+func KeyPrefix(id string) string { return prefix + id + ":" }
+
+// defaultKeyring is used by the column serializer; nil until SetDefault.
+var defaultKeyring *Keyring
+
+// SetDefault installs the keyring used for sensitive columns. Call it once at startup, before
+// the database is used. Without a keyring values are written in plaintext.
+func SetDefault(k *Keyring) { defaultKeyring = k }
+
+// Default returns the installed keyring, or nil.
+func Default() *Keyring { return defaultKeyring }
+
+// Seal encrypts plain with the default keyring; without one, plain is returned unchanged.
+func Seal(plain string) (string, error) {
+	if defaultKeyring == nil {
+		return plain, nil
+	}
+	return defaultKeyring.Encrypt([]byte(plain))
+}
+
+// Open decrypts s with the default keyring. Legacy plaintext values are returned unchanged.
+func Open(s string) (string, error) {
+	if !IsEncrypted(s) {
+		return s, nil
+	}
+	if defaultKeyring == nil {
+		return "", ErrNoKeys
+	}
+	plain, err := defaultKeyring.Decrypt(s)
+	return string(plain), err
+}
+
+// BlindIndex computes a blind index with the default keyring; without one there is no index.
+func BlindIndex(purpose, value string) string {
+	if defaultKeyring == nil {
+		return ""
+	}
+	return defaultKeyring.BlindIndex(purpose, value)
+}
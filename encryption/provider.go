@@ -0,0 +1,119 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyProvider supplies key material. Env and file providers are built in; a KMS or vault backed
+// provider only has to implement Load.
+type KeyProvider interface {
+	Load() (*KeySet, error)
+}
+
+// EnvProvider reads ENCRYPTION_KEYS, a comma-separated list of id:base64key pairs whose first
+// entry is the primary key, and ENCRYPTION_INDEX_KEY, the base64 blind index key. To rotate,
+// prepend a new key and keep the old ones until re-encryption has finished.
+type EnvProvider struct{}
+
+func (EnvProvider) Load() (*KeySet, error) {
+	raw := strings.TrimSpace(os.Getenv("ENCRYPTION_KEYS"))
+	if raw == "" {
+		return nil, ErrNoKeys
+	}
+	ks := &KeySet{Keys: map[string][]byte{}}
+	for i, pair := range strings.Split(raw, ",") {
+		id, enc, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("encryption: ENCRYPTION_KEYS entry %d is not id:key", i+1)
+		}
+		key, err := decodeKey(enc)
+		if err != nil {
+			return nil, fmt.Errorf("encryption: key %q: %w", id, err)
+		}
+		if _, dup := ks.Keys[id]; dup {
+			return nil, fmt.Errorf("encryption: duplicate key id %q", id)
+		}
+		ks.Keys[id] = key
+		if i == 0 {
+			ks.Primary = id
+		}
+	}
+	index, err := decodeKey(os.Getenv("ENCRYPTION_INDEX_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("encryption: ENCRYPTION_INDEX_KEY: %w", err)
+	}
+	ks.IndexKey = index
+	return ks, nil
+}
+
+// FileProvider reads a JSON key file, e.g. a mounted secret:
+//
+//	{"primary": "2024-06", "keys": {"2024-06": "<base64>", "2023-01": "<base64>"}, "index_key": "<base64>"}
+type FileProvider struct {
+	Path string
+}
+
+func (p FileProvider) Load() (*KeySet, error) {
+	b, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+	var f struct {
+		Primary  string            `json:"primary"`
+		Keys     map[string]string `json:"keys"`
+		IndexKey string            `json:"index_key"`
+	}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("encryption: invalid key file: %w", err)
+	}
+	ks := &KeySet{Primary: f.Primary, Keys: map[string][]byte{}}
+	for id, enc := range f.Keys {
+		if ks.Keys[id], err = decodeKey(enc); err != nil {
+			return nil, fmt.Errorf("encryption: key %q: %w", id, err)
+		}
+	}
+	if ks.IndexKey, err = decodeKey(f.IndexKey); err != nil {
+		return nil, fmt.Errorf("encryption: index_key: %w", err)
+	}
+	return ks, nil
+}
+
+func decodeKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errors.New("missing key")
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("key is not valid base64")
+	}
+	return b, nil
+}
+
+// ProviderFromEnv returns a FileProvider when ENCRYPTION_KEY_FILE is set, else an EnvProvider.
+func ProviderFromEnv() KeyProvider {
+	if path := os.Getenv("ENCRYPTION_KEY_FILE"); path != "" {
+		return FileProvider{Path: path}
+	}
+	return EnvProvider{}
+}
+
+// Init loads keys from p and installs them as the default keyring. It returns ErrNoKeys when
+// nothing is configured, leaving sensitive columns in plaintext.
+func Init(p KeyProvider) error {
+	ks, err := p.Load()
+	if err != nil {
+		return err
+	}
+	k, err := NewKeyring(ks)
+	if err != nil {
+		return err
+	}
+	SetDefault(k)
+	return nil
+}
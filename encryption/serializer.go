@@ -0,0 +1,79 @@
+package encryption
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", Serializer{})
+}
+
+// Serializer stores a model field encrypted with the default keyring; tag the field with
+// `gorm:"type:text;serializer:encrypted"`. Strings are encrypted as they are, other types as
+// JSON. Empty strings and nil values are stored as empty strings. Legacy plaintext is read transparently,
+// so a column can be switched to encryption and converted by re-encryption afterwards.
+//
+// Serializers only apply to struct writes: a map passed to Updates must hold SealValue'd values.
+type Serializer struct{}
+
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+	if dbValue != nil {
+		var s string
+		switch v := dbValue.(type) {
+		case []byte:
+			s = string(v)
+		case string:
+			s = v
+		default:
+			return fmt.Errorf("encryption: cannot scan %T into %s", dbValue, field.Name)
+		}
+		plain, err := Open(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+		if plain != "" {
+			if field.FieldType.Kind() == reflect.String {
+				fieldValue.Elem().SetString(plain)
+			} else if err := json.Unmarshal([]byte(plain), fieldValue.Interface()); err != nil {
+				return fmt.Errorf("encryption: %s: %w", field.Name, err)
+			}
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	return SealValue(fieldValue)
+}
+
+// SealValue encodes and encrypts v like Serializer does, for use in map updates.
+func SealValue(v interface{}) (string, error) {
+	plain, err := plaintext(v)
+	if err != nil || plain == "" {
+		return plain, err
+	}
+	return Seal(plain)
+}
+
+// plaintext encodes v for encryption: strings as they are, anything else as JSON.
+func plaintext(v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.String {
+		return rv.String(), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	if string(b) == "null" {
+		return "", nil
+	}
+	return string(b), nil
+}
@@ -0,0 +1,428 @@
+// Package formula parses and evaluates the arithmetic expressions used by formula-based salary
+// components, e.g. "min(BASIC * 0.12, 1800)" or "if(CTC > 600000, 2500, 1600)".
+//
+// Expressions support numbers, variables, + - * /, unary minus, parentheses, the comparisons
+// < <= > >= == != (true is 1, false 0) and the functions min, max, abs, floor, ceil,
+// round(x) or round(x, places) and if(cond, then, else). Variable and function names are
+// case-insensitive; variables are looked up in upper case.
+package formula
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrDivisionByZero is returned by Eval when a divisor evaluates to zero.
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Expr is a parsed expression. It is immutable and safe for concurrent use.
+type Expr struct {
+	src  string
+	root node
+	vars []string
+}
+
+// Parse compiles src. Errors name the offending position.
+func Parse(src string) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+	}
+	seen := map[string]bool{}
+	var vars []string
+	walk(root, func(n node) {
+		if v, ok := n.(varNode); ok && !seen[string(v)] {
+			seen[string(v)] = true
+			vars = append(vars, string(v))
+		}
+	})
+	sort.Strings(vars)
+	return &Expr{src: src, root: root, vars: vars}, nil
+}
+
+// String returns the source the expression was parsed from.
+func (e *Expr) String() string { return e.src }
+
+// Vars lists the variables the expression references, sorted.
+func (e *Expr) Vars() []string { return e.vars }
+
+// Eval computes the expression; every variable it references must be in vars.
+func (e *Expr) Eval(vars map[string]float64) (float64, error) {
+	return e.root.eval(vars)
+}
+
+// lexer
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	num  float64
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			v, err := strconv.ParseFloat(string(rs[i:j]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", string(rs[i:j]), i+1)
+			}
+			toks = append(toks, token{kind: tokNum, text: string(rs[i:j]), num: v, pos: i})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: strings.ToUpper(string(rs[i:j])), pos: i})
+			i = j
+		default:
+			op := string(r)
+			if i+1 < len(rs) && rs[i+1] == '=' && strings.ContainsRune("<>=!", r) {
+				op = string(rs[i : i+2])
+			}
+			switch op {
+			case "+", "-", "*", "/", "(", ")", ",", "<", ">", "<=", ">=", "==", "!=":
+			default:
+				return nil, fmt.Errorf("unexpected %q at position %d", op, i+1)
+			}
+			toks = append(toks, token{kind: tokOp, text: op, pos: i})
+			i += len([]rune(op))
+		}
+	}
+	return append(toks, token{kind: tokEOF, text: "end of formula", pos: len(rs)}), nil
+}
+
+// parser: recursive descent, lowest precedence first
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.i++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		t := p.peek()
+		return fmt.Errorf("expected %q at position %d, found %q", op, t.pos+1, t.text)
+	}
+	return nil
+}
+
+func (p *parser) expr() (node, error) {
+	left, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("<", "<=", ">", ">=", "==", "!="); ok {
+		right, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		return binNode{op: op, l: left, r: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) sum() (node, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		left = binNode{op: op, l: left, r: right}
+	}
+}
+
+func (p *parser) product() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = binNode{op: op, l: left, r: right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{n}, nil
+	}
+	if _, ok := p.accept("+"); ok {
+		return p.unary()
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokNum:
+		return numNode(t.num), nil
+	case t.kind == tokIdent:
+		if _, ok := p.accept("("); !ok {
+			return varNode(t.text), nil
+		}
+		fn, ok := functions[strings.ToLower(t.text)]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %s at position %d", strings.ToLower(t.text), t.pos+1)
+		}
+		var args []node
+		if _, ok := p.accept(")"); !ok {
+			for {
+				a, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, a)
+				if _, ok := p.accept(","); !ok {
+					break
+				}
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+		}
+		if len(args) < fn.min || fn.max >= 0 && len(args) > fn.max {
+			return nil, fmt.Errorf("wrong number of arguments to %s at position %d", strings.ToLower(t.text), t.pos+1)
+		}
+		return callNode{name: strings.ToLower(t.text), fn: fn, args: args}, nil
+	case t.kind == tokOp && t.text == "(":
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+}
+
+// evaluation
+
+type node interface {
+	eval(vars map[string]float64) (float64, error)
+}
+
+type numNode float64
+
+func (n numNode) eval(map[string]float64) (float64, error) { return float64(n), nil }
+
+type varNode string
+
+func (n varNode) eval(vars map[string]float64) (float64, error) {
+	v, ok := vars[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("unknown variable %s", string(n))
+	}
+	return v, nil
+}
+
+type negNode struct{ n node }
+
+func (n negNode) eval(vars map[string]float64) (float64, error) {
+	v, err := n.n.eval(vars)
+	return -v, err
+}
+
+type binNode struct {
+	op   string
+	l, r node
+}
+
+func (n binNode) eval(vars map[string]float64) (float64, error) {
+	a, err := n.l.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	b, err := n.r.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return a / b, nil
+	case "<":
+		return truth(a < b), nil
+	case "<=":
+		return truth(a <= b), nil
+	case ">":
+		return truth(a > b), nil
+	case ">=":
+		return truth(a >= b), nil
+	case "==":
+		return truth(a == b), nil
+	default: // "!="
+		return truth(a != b), nil
+	}
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type function struct {
+	min, max int // argument count; max -1 for any
+	call     func(args []float64) float64
+}
+
+var functions = map[string]function{
+	"min": {1, -1, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Min(m, v)
+		}
+		return m
+	}},
+	"max": {1, -1, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Max(m, v)
+		}
+		return m
+	}},
+	"abs":   {1, 1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"floor": {1, 1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"ceil":  {1, 1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"round": {1, 2, func(a []float64) float64 {
+		scale := 1.0
+		if len(a) == 2 {
+			scale = math.Pow(10, math.Round(a[1]))
+		}
+		return math.Round(a[0]*scale) / scale
+	}},
+	// if is evaluated lazily by callNode; call is unused
+	"if": {3, 3, nil},
+}
+
+type callNode struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n callNode) eval(vars map[string]float64) (float64, error) {
+	if n.name == "if" {
+		c, err := n.args[0].eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		if c != 0 {
+			return n.args[1].eval(vars)
+		}
+		return n.args[2].eval(vars)
+	}
+	args := make([]float64, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	return n.fn.call(args), nil
+}
+
+func walk(n node, fn func(node)) {
+	fn(n)
+	switch n := n.(type) {
+	case negNode:
+		walk(n.n, fn)
+	case binNode:
+		walk(n.l, fn)
+		walk(n.r, fn)
+	case callNode:
+		for _, a := range n.args {
+			walk(a, fn)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -16,7 +17,10 @@ import (
 	_ "github.com/example/hrms-backend/docs" // swagger docs generated at build
 
 	"github.com/example/hrms-backend/config"
+	"github.com/example/hrms-backend/encryption"
 	"github.com/example/hrms-backend/routes"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/storage"
 )
 
 // @title HRMS Backend API
@@ -31,6 +35,14 @@ func main() {
 	// Load env
 	_ = godotenv.Load()
 
+	// Keys for sensitive columns, needed before any query touches them
+	initEncryption()
+
+	// Administrative subcommands, e.g. "hrms import employees.csv"
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	// Connect DB
 	db, err := config.Connect()
 	if err != nil {
@@ -149,6 +161,17 @@ func main() {
 	api := r.PathPrefix("/api/v1").Subrouter()
 	routes.Register(api, db)
 
+	// Background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	jobs := services.NewScheduler()
+	jobs.Every("apply job changes", time.Hour, services.NewEmployeeService(db).ApplyDueJobChanges)
+	jobs.Every("close out offboardings", time.Hour, services.NewChecklistService(db).CloseOutOffboardings)
+	jobs.Every("probation reminders", time.Hour, services.NewProbationService(db).SendReminders)
+	jobs.Every("document expiry reminders", time.Hour, services.NewDocumentService(db, storage.Default()).ExpiryReminders)
+	jobs.Every("re-encrypt sensitive columns", time.Hour, services.NewEncryptionService(db).Rotate)
+	jobs.Start(jobsCtx)
+
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
 		port = "8082"
@@ -174,6 +197,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -184,6 +208,19 @@ func main() {
 	log.Println("server gracefully stopped")
 }
 
+// initEncryption installs the keyring for sensitive columns. Without keys the server still runs
+// and stores them in plaintext, which is only acceptable in development.
+func initEncryption() {
+	err := encryption.Init(encryption.ProviderFromEnv())
+	if errors.Is(err, encryption.ErrNoKeys) {
+		log.Println("warning: no encryption keys configured, sensitive fields are stored in plaintext")
+		return
+	}
+	if err != nil {
+		log.Fatalf("failed to load encryption keys: %v", err)
+	}
+}
+
 func simpleCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
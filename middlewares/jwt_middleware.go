@@ -7,8 +7,15 @@ import (
     "strings"
 
     "github.com/golang-jwt/jwt/v5"
+
+    "github.com/example/hrms-backend/models"
+    "github.com/example/hrms-backend/shaping"
 )
 
+// TokenCheck, when set, is asked whether a token's user may still use it (account enabled, token
+// not revoked). It is installed by the routes package, which has database access.
+var TokenCheck func(userID, tokenVersion uint) error
+
 type ctxKey string
 
 const (
@@ -38,9 +45,18 @@ func JWTAuth(next http.Handler) http.Handler {
         }
         uid, _ := claims["sub"].(float64)
         role, _ := claims["role"].(string)
+        if TokenCheck != nil {
+            tv, _ := claims["tv"].(float64)
+            if err := TokenCheck(uint(uid), uint(tv)); err != nil {
+                http.Error(w, "token revoked", http.StatusUnauthorized)
+                return
+            }
+        }
         ctx := context.WithValue(r.Context(), CtxUserID, uint(uid))
         ctx = context.WithValue(ctx, CtxUserRole, role)
-        next.ServeHTTP(w, r.WithContext(ctx))
+        // responses are redacted for the caller by the utils response helpers
+        sw := shaping.NewWriter(w, shaping.NewViewer(uint(uid), models.UserRole(role)))
+        next.ServeHTTP(sw, r.WithContext(ctx))
     })
 }
 
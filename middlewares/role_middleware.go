@@ -2,6 +2,8 @@ package middlewares
 
 import (
     "net/http"
+
+    "github.com/example/hrms-backend/models"
 )
 
 func RequireRole(role string) func(http.Handler) http.Handler {
@@ -17,4 +19,22 @@ func RequireRole(role string) func(http.Handler) http.Handler {
     }
 }
 
+// RequirePermission admits callers whose role has been granted perm.
+func RequirePermission(perm models.Permission) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if !HasPermission(r, perm) {
+                http.Error(w, "forbidden", http.StatusForbidden)
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+// HasPermission reports whether the authenticated caller of r has perm.
+func HasPermission(r *http.Request, perm models.Permission) bool {
+    role, _ := r.Context().Value(CtxUserRole).(string)
+    return models.HasPermission(models.UserRole(role), perm)
+}
 
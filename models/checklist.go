@@ -0,0 +1,121 @@
+package models
+
+import "time"
+
+// ChecklistKind identifies the workflow a checklist belongs to.
+type ChecklistKind string
+
+const (
+    ChecklistOnboarding  ChecklistKind = "ONBOARDING"
+    ChecklistOffboarding ChecklistKind = "OFFBOARDING"
+)
+
+// TaskCategory groups checklist tasks for reporting.
+type TaskCategory string
+
+const (
+    TaskAssetReturn      TaskCategory = "ASSET_RETURN"
+    TaskAccessRevocation TaskCategory = "ACCESS_REVOCATION"
+    TaskFinalSettlement  TaskCategory = "FINAL_SETTLEMENT"
+    TaskExitInterview    TaskCategory = "EXIT_INTERVIEW"
+    TaskAccountSetup     TaskCategory = "ACCOUNT_SETUP"
+    TaskEquipment        TaskCategory = "EQUIPMENT"
+    TaskPaperwork        TaskCategory = "PAPERWORK"
+    TaskOrientation      TaskCategory = "ORIENTATION"
+    TaskTraining         TaskCategory = "TRAINING"
+    TaskOther            TaskCategory = "OTHER"
+)
+
+// TaskOwner says who a template item is assigned to when a checklist is started: the HR or IT
+// team, the employee the checklist is about, their manager, or a named user.
+type TaskOwner string
+
+const (
+    OwnerHR       TaskOwner = "HR"
+    OwnerIT       TaskOwner = "IT"
+    OwnerEmployee TaskOwner = "EMPLOYEE"
+    OwnerManager  TaskOwner = "MANAGER"
+    OwnerUser     TaskOwner = "USER"
+)
+
+type ChecklistStatus string
+
+const (
+    ChecklistOpen      ChecklistStatus = "OPEN"
+    ChecklistCompleted ChecklistStatus = "COMPLETED"
+    ChecklistCancelled ChecklistStatus = "CANCELLED"
+)
+
+type TaskStatus string
+
+const (
+    TaskPending    TaskStatus = "PENDING"
+    TaskInProgress TaskStatus = "IN_PROGRESS"
+    TaskDone       TaskStatus = "DONE"
+    TaskSkipped    TaskStatus = "SKIPPED"
+)
+
+// ChecklistTemplate is a reusable list of tasks; starting a checklist copies its items into tasks.
+// DepartmentID and PositionID scope a template; the most specific template matching the employee wins.
+type ChecklistTemplate struct {
+    ID           uint                    `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time               `json:"created_at"`
+    UpdatedAt    time.Time               `json:"updated_at"`
+    Kind         ChecklistKind           `gorm:"type:varchar(16);not null;index" json:"kind"`
+    Name         string                  `gorm:"size:120;not null" json:"name"`
+    Description  string                  `gorm:"size:500" json:"description"`
+    DepartmentID *uint                   `gorm:"index" json:"department_id,omitempty"`
+    PositionID   *uint                   `gorm:"index" json:"position_id,omitempty"`
+    IsDefault    bool                    `gorm:"not null;default:false" json:"is_default"`
+    Items        []ChecklistTemplateItem `gorm:"constraint:OnDelete:CASCADE" json:"items"`
+}
+
+// ChecklistTemplateItem is one task of a template. DueOffsetDays is relative to the checklist's
+// reference date (the last working day for offboarding); negative values fall before it.
+type ChecklistTemplateItem struct {
+    ID            uint         `gorm:"primaryKey" json:"id"`
+    TemplateID    uint         `gorm:"index;not null" json:"template_id"`
+    Position      int          `gorm:"not null;default:0" json:"position"`
+    Title         string       `gorm:"size:200;not null" json:"title"`
+    Category      TaskCategory `gorm:"type:varchar(24);not null;default:OTHER" json:"category"`
+    Owner         TaskOwner    `gorm:"type:varchar(16);not null;default:HR" json:"owner"`
+    OwnerUserID   *uint        `json:"owner_user_id,omitempty"`
+    DueOffsetDays int          `gorm:"not null;default:0" json:"due_offset_days"`
+}
+
+// Checklist is a started workflow for one employee. For offboarding, ReferenceDate is the last
+// working day and ClosedOutAt is set once the login was disabled and the employee terminated.
+type Checklist struct {
+    ID            uint            `gorm:"primaryKey" json:"id"`
+    CreatedAt     time.Time       `json:"created_at"`
+    UpdatedAt     time.Time       `json:"updated_at"`
+    Kind          ChecklistKind   `gorm:"type:varchar(16);not null;index" json:"kind"`
+    EmployeeID    uint            `gorm:"index;not null" json:"employee_id"`
+    TemplateID    *uint           `json:"template_id,omitempty"`
+    Status        ChecklistStatus `gorm:"type:varchar(16);not null;default:OPEN;index" json:"status"`
+    ReferenceDate time.Time       `gorm:"type:date;not null" json:"reference_date"`
+    Reason        string          `gorm:"size:500" json:"reason"`
+    StartedBy     uint            `json:"started_by"`
+    CompletedAt   *time.Time      `json:"completed_at,omitempty"`
+    ClosedOutAt   *time.Time      `json:"closed_out_at,omitempty"`
+    Tasks         []ChecklistTask `gorm:"constraint:OnDelete:CASCADE" json:"tasks,omitempty"`
+    Version       uint            `gorm:"default:1" json:"version"`
+}
+
+// ChecklistTask is one assigned task. HR tasks have no AssigneeUserID and can be done by any HR user.
+type ChecklistTask struct {
+    ID             uint         `gorm:"primaryKey" json:"id"`
+    CreatedAt      time.Time    `json:"created_at"`
+    UpdatedAt      time.Time    `json:"updated_at"`
+    ChecklistID    uint         `gorm:"index;not null" json:"checklist_id"`
+    Position       int          `gorm:"not null;default:0" json:"position"`
+    Title          string       `gorm:"size:200;not null" json:"title"`
+    Category       TaskCategory `gorm:"type:varchar(24);not null;default:OTHER" json:"category"`
+    Owner          TaskOwner    `gorm:"type:varchar(16);not null" json:"owner"`
+    AssigneeUserID *uint        `gorm:"index" json:"assignee_user_id,omitempty"`
+    DueDate        time.Time    `gorm:"type:date;not null" json:"due_date"`
+    Status         TaskStatus   `gorm:"type:varchar(16);not null;default:PENDING" json:"status"`
+    Note           string       `gorm:"size:1000" json:"note,omitempty"`
+    CompletedBy    *uint        `json:"completed_by,omitempty"`
+    CompletedAt    *time.Time   `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+type CompensationCycleStatus string
+
+const (
+    // CycleDraft: HR sets up the cycle and its budgets.
+    CycleDraft CompensationCycleStatus = "DRAFT"
+    // CycleOpen: managers propose revisions for their reports and HR decides them.
+    CycleOpen CompensationCycleStatus = "OPEN"
+    // CycleClosed: approved revisions have been applied; nothing changes any more.
+    CycleClosed CompensationCycleStatus = "CLOSED"
+)
+
+// CompensationCycle is a salary review round. Revisions approved in the cycle take effect on
+// EffectiveDate as job records. LetterTemplate is the text of the revision letters; placeholders
+// like {{new_salary}} are filled in per employee, and an empty template uses a built-in text.
+type CompensationCycle struct {
+    ID             uint                    `gorm:"primaryKey" json:"id"`
+    CreatedAt      time.Time               `json:"created_at"`
+    UpdatedAt      time.Time               `json:"updated_at"`
+    Name           string                  `gorm:"size:120;not null;uniqueIndex" json:"name"`
+    Status         CompensationCycleStatus `gorm:"type:varchar(16);not null;default:DRAFT" json:"status"`
+    EffectiveDate  time.Time               `gorm:"type:date;not null" json:"effective_date"`
+    ProposalsDue   *time.Time              `gorm:"type:date" json:"proposals_due,omitempty"`
+    LetterTemplate string                  `gorm:"type:text" json:"letter_template"`
+    CreatedBy      uint                    `gorm:"not null" json:"created_by"`
+    ClosedBy       *uint                   `json:"closed_by,omitempty"`
+    ClosedAt       *time.Time              `json:"closed_at,omitempty"`
+    Budgets        []CompensationBudget    `gorm:"foreignKey:CycleID;constraint:OnDelete:CASCADE" json:"budgets,omitempty"`
+}
+
+// CompensationBudget caps the annual salary increases proposed for a department in a cycle.
+// Proposals that are submitted, approved or applied count against it.
+type CompensationBudget struct {
+    ID           uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time `json:"created_at"`
+    UpdatedAt    time.Time `json:"updated_at"`
+    CycleID      uint      `gorm:"not null;uniqueIndex:idx_comp_budget_dept" json:"cycle_id"`
+    DepartmentID uint      `gorm:"not null;uniqueIndex:idx_comp_budget_dept" json:"department_id"`
+    Amount       float64   `gorm:"not null" json:"amount"`
+    // Committed and Remaining are computed when budgets are listed.
+    Committed float64 `gorm:"-" json:"committed"`
+    Remaining float64 `gorm:"-" json:"remaining"`
+}
+
+type ProposalStatus string
+
+const (
+    ProposalDraft     ProposalStatus = "DRAFT"
+    ProposalSubmitted ProposalStatus = "SUBMITTED"
+    ProposalApproved  ProposalStatus = "APPROVED"
+    ProposalRejected  ProposalStatus = "REJECTED"
+    ProposalApplied   ProposalStatus = "APPLIED"
+)
+
+// CompensationProposal is a proposed salary revision for one employee in a cycle, made by their
+// manager (or HR) and decided by HR. CurrentSalary is the salary when the proposal was last
+// saved; DepartmentID the department whose budget it counts against. Once applied, JobRecordID
+// is the resulting job record and the revision letter is in blob storage under LetterKey.
+// Salaries are encrypted at rest.
+type CompensationProposal struct {
+    ID                uint           `gorm:"primaryKey" json:"id"`
+    CreatedAt         time.Time      `json:"created_at"`
+    UpdatedAt         time.Time      `json:"updated_at"`
+    CycleID           uint           `gorm:"not null;uniqueIndex:idx_comp_proposal_emp" json:"cycle_id"`
+    EmployeeID        uint           `gorm:"not null;uniqueIndex:idx_comp_proposal_emp;index" json:"employee_id"`
+    EmployeeName      string         `gorm:"size:120" json:"employee_name"`
+    DepartmentID      *uint          `gorm:"index" json:"department_id,omitempty"`
+    CurrentSalary     float64        `gorm:"type:text;not null;serializer:encrypted" json:"current_salary"`
+    ProposedSalary    float64        `gorm:"type:text;not null;serializer:encrypted" json:"proposed_salary"`
+    Reason            string         `gorm:"size:1000" json:"reason"`
+    Status            ProposalStatus `gorm:"type:varchar(16);not null;default:DRAFT;index" json:"status"`
+    ProposedBy        uint           `gorm:"not null" json:"proposed_by"`
+    SubmittedAt       *time.Time     `json:"submitted_at,omitempty"`
+    DecidedBy         *uint          `json:"decided_by,omitempty"`
+    DecidedAt         *time.Time     `json:"decided_at,omitempty"`
+    DecisionNote      string         `gorm:"size:500" json:"decision_note,omitempty"`
+    JobRecordID       *uint          `json:"job_record_id,omitempty"`
+    LetterKey         string         `gorm:"size:255" json:"-"`
+    LetterGeneratedAt *time.Time     `json:"letter_generated_at,omitempty"`
+}
+
+// Increase is the proposed change of annual salary.
+func (p *CompensationProposal) Increase() float64 { return p.ProposedSalary - p.CurrentSalary }
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type CustomFieldType string
+
+const (
+    CustomFieldText    CustomFieldType = "TEXT"
+    CustomFieldNumber  CustomFieldType = "NUMBER"
+    CustomFieldDate    CustomFieldType = "DATE"
+    CustomFieldBoolean CustomFieldType = "BOOLEAN"
+    // CustomFieldSelect takes one of Options.
+    CustomFieldSelect CustomFieldType = "SELECT"
+)
+
+// CustomFieldValues maps definition keys to values: strings for text, select and date
+// (YYYY-MM-DD) fields, float64 for numbers and bool for booleans.
+type CustomFieldValues map[string]interface{}
+
+// CustomFieldDefinition is an admin-defined employee attribute such as a T-shirt size or cost
+// center. Values live in Employee.CustomFields under Key. Pattern (text) and Min/Max (number)
+// constrain values. Besides HR, the employee sees their own value when EmployeeCanView is set,
+// and users holding one of ViewRoles see it on every employee.
+type CustomFieldDefinition struct {
+    ID              uint            `gorm:"primaryKey" json:"id"`
+    CreatedAt       time.Time       `json:"created_at"`
+    UpdatedAt       time.Time       `json:"updated_at"`
+    Key             string          `gorm:"size:40;not null;uniqueIndex" json:"key"`
+    Label           string          `gorm:"size:120;not null" json:"label"`
+    Description     string          `gorm:"size:500" json:"description"`
+    Type            CustomFieldType `gorm:"type:varchar(16);not null" json:"type"`
+    Required        bool            `gorm:"not null;default:false" json:"required"`
+    Options         []string        `gorm:"type:jsonb;serializer:json" json:"options,omitempty"`
+    Pattern         string          `gorm:"size:200" json:"pattern,omitempty"`
+    Min             *float64        `json:"min,omitempty"`
+    Max             *float64        `json:"max,omitempty"`
+    EmployeeCanView bool            `gorm:"not null;default:false" json:"employee_can_view"`
+    ViewRoles       []UserRole      `gorm:"type:jsonb;serializer:json" json:"view_roles"`
+    SortOrder       int             `gorm:"not null;default:0" json:"sort_order"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// PaymentProfile is a company bank account salaries are paid from, with the file format its
+// bank accepts: NACHA, SEPA or CSV. CompanyID is the NACHA company identification or the SEPA
+// organisation id; Routing is the ABA routing number for NACHA and the BIC for SEPA. CSVLayout
+// describes the columns of CSV files. The account details are encrypted at rest.
+type PaymentProfile struct {
+    ID          uint            `gorm:"primaryKey" json:"id"`
+    CreatedAt   time.Time       `json:"created_at"`
+    UpdatedAt   time.Time       `json:"updated_at"`
+    Name        string          `gorm:"size:120;not null;uniqueIndex" json:"name"`
+    Format      string          `gorm:"type:varchar(8);not null" json:"format"`
+    CompanyName string          `gorm:"size:120;not null" json:"company_name"`
+    CompanyID   string          `gorm:"size:35" json:"company_id"`
+    BankName    string          `gorm:"size:120" json:"bank_name"`
+    Account     string          `gorm:"type:text;serializer:encrypted" json:"account"`
+    Routing     string          `gorm:"type:text;serializer:encrypted" json:"routing"`
+    Currency    string          `gorm:"size:3;not null" json:"currency"`
+    CSVLayout   json.RawMessage `gorm:"type:jsonb;serializer:json" json:"csv_layout,omitempty"`
+}
+
+type DisbursementStatus string
+
+const (
+    DisbursementPending  DisbursementStatus = "PENDING"
+    DisbursementExported DisbursementStatus = "EXPORTED"
+    DisbursementPaid     DisbursementStatus = "PAID"
+    DisbursementFailed   DisbursementStatus = "FAILED"
+)
+
+// Disbursement tracks the payment of one employee's net pay in a finalized run. It is PENDING
+// until a payment file includes it, EXPORTED once one does, then PAID or FAILED as the bank
+// reports back. Pending and failed payments go into the next file of the run; Problem says why
+// the last file left one out. Reference is the end-to-end id of the latest attempt.
+type Disbursement struct {
+    ID           uint               `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time          `json:"created_at"`
+    UpdatedAt    time.Time          `json:"updated_at"`
+    RunID        uint               `gorm:"not null;uniqueIndex:idx_disbursement_run_emp" json:"run_id"`
+    EmployeeID   uint               `gorm:"not null;uniqueIndex:idx_disbursement_run_emp;index" json:"employee_id"`
+    LineID       uint               `gorm:"not null" json:"line_id"`
+    EmployeeName string             `gorm:"size:120" json:"employee_name"`
+    Amount       float64            `gorm:"type:text;not null;serializer:encrypted" json:"amount"`
+    Status       DisbursementStatus `gorm:"type:varchar(16);not null;default:PENDING;index" json:"status"`
+    FileID       *uint              `gorm:"index" json:"file_id,omitempty"`
+    Attempts     int                `gorm:"not null;default:0" json:"attempts"`
+    Reference    string             `gorm:"size:35" json:"reference"`
+    Problem      string             `gorm:"size:255" json:"problem,omitempty"`
+    Note         string             `gorm:"size:500" json:"note,omitempty"`
+    UpdatedBy    *uint              `json:"updated_by,omitempty"`
+    PaidAt       *time.Time         `json:"paid_at,omitempty"`
+}
+
+// PaymentFile is a bank file generated for a run. Content holds account numbers, so it is
+// encrypted at rest.
+type PaymentFile struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt time.Time `json:"created_at"`
+    RunID     uint      `gorm:"not null;index" json:"run_id"`
+    ProfileID uint      `gorm:"not null" json:"profile_id"`
+    Format    string    `gorm:"type:varchar(8);not null" json:"format"`
+    FileName  string    `gorm:"size:255;not null" json:"file_name"`
+    Payments  int       `gorm:"not null" json:"payments"`
+    Total     float64   `gorm:"not null" json:"total"`
+    Content   string    `gorm:"type:text;serializer:encrypted" json:"-"`
+    CreatedBy uint      `gorm:"not null" json:"created_by"`
+}
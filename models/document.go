@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// DocumentCategory groups employee documents and decides who besides HR may see them: the employee
+// the document belongs to, their manager, and users holding one of ViewRoles. Documents of a
+// category with RequiresExpiry must carry an expiry date; reminders go out ReminderDays before it.
+type DocumentCategory struct {
+    ID                uint       `gorm:"primaryKey" json:"id"`
+    CreatedAt         time.Time  `json:"created_at"`
+    UpdatedAt         time.Time  `json:"updated_at"`
+    Name              string     `gorm:"size:120;not null;uniqueIndex" json:"name"`
+    Description       string     `gorm:"size:500" json:"description"`
+    EmployeeCanView   bool       `gorm:"not null;default:false" json:"employee_can_view"`
+    EmployeeCanUpload bool       `gorm:"not null;default:false" json:"employee_can_upload"`
+    ManagerCanView    bool       `gorm:"not null;default:false" json:"manager_can_view"`
+    ViewRoles         []UserRole `gorm:"type:jsonb;serializer:json" json:"view_roles"`
+    RequiresExpiry    bool       `gorm:"not null;default:false" json:"requires_expiry"`
+    ReminderDays      int        `gorm:"not null;default:30" json:"reminder_days"`
+}
+
+// Document is one employee document, e.g. a contract or a work permit. Uploading a new file adds
+// a version; CurrentVersion is the latest.
+type Document struct {
+    ID             uint              `gorm:"primaryKey" json:"id"`
+    CreatedAt      time.Time         `json:"created_at"`
+    UpdatedAt      time.Time         `json:"updated_at"`
+    EmployeeID     uint              `gorm:"index;not null" json:"employee_id"`
+    CategoryID     uint              `gorm:"index;not null" json:"category_id"`
+    Title          string            `gorm:"size:200;not null" json:"title"`
+    ExpiresAt      *time.Time        `gorm:"type:date;index" json:"expires_at,omitempty"`
+    CurrentVersion int               `gorm:"not null;default:1" json:"current_version"`
+    UploadedBy     uint              `gorm:"not null" json:"uploaded_by"`
+    Versions       []DocumentVersion `gorm:"constraint:OnDelete:CASCADE" json:"versions,omitempty"`
+}
+
+// DocumentVersion is one uploaded file of a document. The file lives in blob storage under StorageKey.
+type DocumentVersion struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt   time.Time `json:"created_at"`
+    DocumentID  uint      `gorm:"uniqueIndex:idx_document_versions_doc_version;not null" json:"document_id"`
+    Version     int       `gorm:"uniqueIndex:idx_document_versions_doc_version;not null" json:"version"`
+    FileName    string    `gorm:"size:255;not null" json:"file_name"`
+    ContentType string    `gorm:"size:100;not null" json:"content_type"`
+    Size        int64     `gorm:"not null" json:"size"`
+    SHA256      string    `gorm:"size:64;not null" json:"sha256"`
+    StorageKey  string    `gorm:"size:255;not null" json:"-"`
+    Note        string    `gorm:"size:500" json:"note,omitempty"`
+    UploadedBy  uint      `gorm:"not null" json:"uploaded_by"`
+}
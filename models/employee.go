@@ -1,17 +1,82 @@
 package models
 
-import "time"
+import (
+    "time"
 
+    _ "github.com/example/hrms-backend/encryption" // registers the "encrypted" serializer
+)
+
+type EmploymentStatus string
+
+const (
+    EmploymentActive EmploymentStatus = "ACTIVE"
+    // EmploymentProbation is a new hire whose probation has not been confirmed yet.
+    EmploymentProbation EmploymentStatus = "PROBATION"
+    EmploymentInactive  EmploymentStatus = "INACTIVE"
+    // EmploymentTerminated marks an archived employee. The row is kept so attendance,
+    // leave and job history stay linked; terminated employees are hidden from active lists.
+    EmploymentTerminated EmploymentStatus = "TERMINATED"
+)
+
+// Employee job fields (Position, Department, Grade, Salary, ManagerID, Location) mirror the
+// employee's current JobRecord; change them by recording a job change. Department, Position,
+// Grade and Location reference org entities by id; the name columns are kept in sync for readability.
+// A user has at most one non-terminated employee; a rehire is a new row linked by PreviousEmployeeID.
+// Name is the legal name. Employees edit their contact details themselves; changes to the legal name
+// and bank account go through a ProfileChangeRequest approved by HR.
+// CustomFields holds the values of the admin-defined CustomFieldDefinitions.
+// Salary and the bank account and routing numbers are encrypted at rest; BankAccountIndex is a
+// blind index of the account number for lookups.
 type Employee struct {
-    ID         uint      `gorm:"primaryKey" json:"id"`
-    CreatedAt  time.Time `json:"created_at"`
-    UpdatedAt  time.Time `json:"updated_at"`
-    UserID     uint      `gorm:"uniqueIndex;not null" json:"user_id"`
-    Name       string    `gorm:"size:120;not null" json:"name"`
-    Position   string    `gorm:"size:120;not null" json:"position"`
-    Department string    `gorm:"size:120;not null" json:"department"`
-    Salary     float64   `gorm:"not null" json:"salary"`
-    Version    uint      `gorm:"default:1" json:"version"`
+    ID                 uint               `gorm:"primaryKey" json:"id"`
+    CreatedAt          time.Time          `json:"created_at"`
+    UpdatedAt          time.Time          `json:"updated_at"`
+    UserID             uint               `gorm:"index:idx_employees_user;not null" json:"user_id"`
+    Name               string             `gorm:"size:120;not null" json:"name"`
+    PreferredName      string             `gorm:"size:120" json:"preferred_name"`
+    Phone              string             `gorm:"size:32" json:"phone"`
+    PersonalEmail      string             `gorm:"size:200" json:"personal_email"`
+    AddressLine1       string             `gorm:"size:200" json:"address_line1"`
+    AddressLine2       string             `gorm:"size:200" json:"address_line2"`
+    City               string             `gorm:"size:120" json:"city"`
+    PostalCode         string             `gorm:"size:20" json:"postal_code"`
+    Country            string             `gorm:"size:2" json:"country"`
+    BankAccountHolder  string             `gorm:"size:120" json:"bank_account_holder"`
+    BankName           string             `gorm:"size:120" json:"bank_name"`
+    BankAccountNumber  string             `gorm:"type:text;serializer:encrypted" json:"bank_account_number"`
+    BankAccountIndex   string             `gorm:"size:64;index" json:"-"`
+    BankRoutingCode    string             `gorm:"type:text;serializer:encrypted" json:"bank_routing_code"`
+    Position           string             `gorm:"size:120;not null" json:"position"`
+    PositionID         *uint              `gorm:"index" json:"position_id,omitempty"`
+    Department         string             `gorm:"size:120;not null" json:"department"`
+    DepartmentID       *uint              `gorm:"index" json:"department_id,omitempty"`
+    Grade              string             `gorm:"size:40" json:"grade"`
+    GradeID            *uint              `gorm:"index" json:"grade_id,omitempty"`
+    Salary             float64            `gorm:"type:text;not null;serializer:encrypted" json:"salary"`
+    ManagerID          *uint              `gorm:"index" json:"manager_id,omitempty"`
+    Location           string             `gorm:"size:120" json:"location"`
+    LocationID         *uint              `gorm:"index" json:"location_id,omitempty"`
+    Status             EmploymentStatus   `gorm:"type:varchar(16);not null;default:ACTIVE;index" json:"status"`
+    ProbationEndDate   *time.Time         `gorm:"type:date" json:"probation_end_date,omitempty"`
+    ConfirmedAt        *time.Time         `json:"confirmed_at,omitempty"`
+    ConfirmedBy        *uint              `json:"confirmed_by,omitempty"`
+    TerminationDate    *time.Time         `gorm:"type:date" json:"termination_date,omitempty"`
+    TerminationReason  string             `gorm:"size:500" json:"termination_reason,omitempty"`
+    TerminatedBy       *uint              `json:"terminated_by,omitempty"`
+    PreviousEmployeeID *uint              `gorm:"index" json:"previous_employee_id,omitempty"`
+    Version            uint               `gorm:"default:1" json:"version"`
+    CustomFields       CustomFieldValues  `gorm:"type:jsonb;serializer:json" json:"custom_fields,omitempty"`
+    EmergencyContacts  []EmergencyContact `gorm:"constraint:OnDelete:CASCADE" json:"emergency_contacts,omitempty"`
 }
 
-
+// EmergencyContact is a person to call for an employee; Priority orders them, 1 first.
+type EmergencyContact struct {
+    ID           uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time `json:"created_at"`
+    EmployeeID   uint      `gorm:"index;not null" json:"employee_id"`
+    Priority     int       `gorm:"not null;default:1" json:"priority"`
+    Name         string    `gorm:"size:120;not null" json:"name"`
+    Relationship string    `gorm:"size:60" json:"relationship"`
+    Phone        string    `gorm:"size:32;not null" json:"phone"`
+    Email        string    `gorm:"size:200" json:"email"`
+}
@@ -0,0 +1,111 @@
+package models
+
+import "time"
+
+// ExpenseCategory groups expense items under a policy. Limits are in the base currency; 0 means
+// no limit. PerItemLimit caps one item, MonthlyLimit what an employee claims in a calendar month
+// by item date. With ReceiptRequired, items above ReceiptThreshold need a receipt.
+// Archived categories cannot be used on new items.
+type ExpenseCategory struct {
+    ID               uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt        time.Time `json:"created_at"`
+    UpdatedAt        time.Time `json:"updated_at"`
+    Name             string    `gorm:"size:120;not null;uniqueIndex" json:"name"`
+    Description      string    `gorm:"size:500" json:"description"`
+    PerItemLimit     float64   `gorm:"not null;default:0" json:"per_item_limit"`
+    MonthlyLimit     float64   `gorm:"not null;default:0" json:"monthly_limit"`
+    ReceiptRequired  bool      `gorm:"not null;default:false" json:"receipt_required"`
+    ReceiptThreshold float64   `gorm:"not null;default:0" json:"receipt_threshold"`
+    Archived         bool      `gorm:"not null;default:false" json:"archived"`
+}
+
+// CurrencyRate is what one unit of Currency is worth in the base currency from EffectiveDate
+// until the next rate of the currency.
+type CurrencyRate struct {
+    ID            uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt     time.Time `json:"created_at"`
+    UpdatedAt     time.Time `json:"updated_at"`
+    Currency      string    `gorm:"size:3;not null;uniqueIndex:idx_currency_rate_date" json:"currency"`
+    EffectiveDate time.Time `gorm:"type:date;not null;uniqueIndex:idx_currency_rate_date" json:"effective_date"`
+    Rate          float64   `gorm:"not null" json:"rate"`
+}
+
+type ExpenseStatus string
+
+const (
+    ExpenseDraft           ExpenseStatus = "DRAFT"
+    ExpenseSubmitted       ExpenseStatus = "SUBMITTED"
+    ExpenseManagerApproved ExpenseStatus = "MANAGER_APPROVED"
+    ExpenseApproved        ExpenseStatus = "APPROVED"
+    ExpenseRejected        ExpenseStatus = "REJECTED"
+    ExpensePaid            ExpenseStatus = "PAID"
+)
+
+// ExpensePayout is how an approved claim is reimbursed.
+type ExpensePayout string
+
+const (
+    // ExpensePayoutPayroll adds the claim to the next payroll run calculated after approval.
+    ExpensePayoutPayroll ExpensePayout = "PAYROLL"
+    // ExpensePayoutSeparate leaves the claim to a separate payment, recorded as paid by finance.
+    ExpensePayoutSeparate ExpensePayout = "SEPARATE"
+)
+
+// ExpenseClaim is an employee's request to be reimbursed for one or more expense items. A draft
+// is submitted to the employee's manager (ManagerID, taken at submission; claims of employees
+// without a manager go straight to finance), then approved by finance, who choose the payout.
+// Total is the sum of the items in the base currency, Currency. Claims paid through payroll
+// record their run in PayrollRunID and become PAID when the run is finalized.
+type ExpenseClaim struct {
+    ID               uint          `gorm:"primaryKey" json:"id"`
+    CreatedAt        time.Time     `json:"created_at"`
+    UpdatedAt        time.Time     `json:"updated_at"`
+    EmployeeID       uint          `gorm:"not null;index" json:"employee_id"`
+    Title            string        `gorm:"size:200;not null" json:"title"`
+    Status           ExpenseStatus `gorm:"type:varchar(20);not null;default:DRAFT;index" json:"status"`
+    Currency         string        `gorm:"size:3;not null" json:"currency"`
+    Total            float64       `gorm:"not null" json:"total"`
+    SubmittedAt      *time.Time    `json:"submitted_at,omitempty"`
+    ManagerID        *uint         `gorm:"index" json:"manager_id,omitempty"`
+    ManagerDecidedBy *uint         `json:"manager_decided_by,omitempty"`
+    ManagerDecidedAt *time.Time    `json:"manager_decided_at,omitempty"`
+    FinanceDecidedBy *uint         `json:"finance_decided_by,omitempty"`
+    FinanceDecidedAt *time.Time    `json:"finance_decided_at,omitempty"`
+    DecisionNote     string        `gorm:"size:500" json:"decision_note,omitempty"`
+    Payout           ExpensePayout `gorm:"type:varchar(16)" json:"payout,omitempty"`
+    PayrollRunID     *uint         `gorm:"index" json:"payroll_run_id,omitempty"`
+    PaidAt           *time.Time    `json:"paid_at,omitempty"`
+    PaymentReference string        `gorm:"size:100" json:"payment_reference,omitempty"`
+    Items            []ExpenseItem `gorm:"foreignKey:ClaimID;constraint:OnDelete:CASCADE" json:"items,omitempty"`
+}
+
+// ExpenseItem is one expense of a claim in the currency it was paid in. Rate converts Amount to
+// BaseAmount in the claim's currency; it is taken from the currency rates on the item's date.
+type ExpenseItem struct {
+    ID          uint             `gorm:"primaryKey" json:"id"`
+    CreatedAt   time.Time        `json:"created_at"`
+    UpdatedAt   time.Time        `json:"updated_at"`
+    ClaimID     uint             `gorm:"not null;index" json:"claim_id"`
+    CategoryID  uint             `gorm:"not null;index" json:"category_id"`
+    Date        time.Time        `gorm:"type:date;not null" json:"date"`
+    Merchant    string           `gorm:"size:200" json:"merchant"`
+    Description string           `gorm:"size:500" json:"description"`
+    Amount      float64          `gorm:"not null" json:"amount"`
+    Currency    string           `gorm:"size:3;not null" json:"currency"`
+    Rate        float64          `gorm:"not null" json:"rate"`
+    BaseAmount  float64          `gorm:"not null" json:"base_amount"`
+    Receipts    []ExpenseReceipt `gorm:"foreignKey:ItemID;constraint:OnDelete:CASCADE" json:"receipts,omitempty"`
+}
+
+// ExpenseReceipt is a receipt for an expense item; the file lives in blob storage under
+// StorageKey.
+type ExpenseReceipt struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt   time.Time `json:"created_at"`
+    ItemID      uint      `gorm:"not null;index" json:"item_id"`
+    FileName    string    `gorm:"size:255;not null" json:"file_name"`
+    ContentType string    `gorm:"size:100;not null" json:"content_type"`
+    Size        int64     `gorm:"not null" json:"size"`
+    StorageKey  string    `gorm:"size:255;not null" json:"-"`
+    UploadedBy  uint      `gorm:"not null" json:"uploaded_by"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// JobRecord is an effective-dated snapshot of an employee's job. The employee row mirrors the
+// latest record whose EffectiveDate has been reached; AppliedAt marks records already mirrored.
+// Salary is encrypted at rest like the employee's.
+type JobRecord struct {
+    ID            uint       `gorm:"primaryKey" json:"id"`
+    CreatedAt     time.Time  `json:"created_at"`
+    UpdatedAt     time.Time  `json:"updated_at"`
+    EmployeeID    uint       `gorm:"index:idx_job_emp_date;not null" json:"employee_id"`
+    EffectiveDate time.Time  `gorm:"type:date;index:idx_job_emp_date;not null" json:"effective_date"`
+    Position      string     `gorm:"size:120;not null" json:"position"`
+    PositionID    *uint      `json:"position_id,omitempty"`
+    Department    string     `gorm:"size:120;not null" json:"department"`
+    DepartmentID  *uint      `json:"department_id,omitempty"`
+    Grade         string     `gorm:"size:40" json:"grade"`
+    GradeID       *uint      `json:"grade_id,omitempty"`
+    Salary        float64    `gorm:"type:text;not null;serializer:encrypted" json:"salary"`
+    ManagerID     *uint      `json:"manager_id,omitempty"`
+    Location      string     `gorm:"size:120" json:"location"`
+    LocationID    *uint      `json:"location_id,omitempty"`
+    Reason        string     `gorm:"size:255" json:"reason"`
+    CreatedBy     uint       `json:"created_by"`
+    AppliedAt     *time.Time `gorm:"index" json:"applied_at,omitempty"`
+}
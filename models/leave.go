@@ -8,18 +8,70 @@ const (
     LeavePending  LeaveStatus = "PENDING"
     LeaveApproved LeaveStatus = "APPROVED"
     LeaveRejected LeaveStatus = "REJECTED"
+    // LeaveCancelled is set on open and future leaves when the employee is terminated.
+    LeaveCancelled LeaveStatus = "CANCELLED"
+)
+
+type LeaveType string
+
+const (
+    LeaveAnnual LeaveType = "ANNUAL"
+    LeaveSick   LeaveType = "SICK"
+    LeaveCasual LeaveType = "CASUAL"
+    LeaveUnpaid LeaveType = "UNPAID"
 )
 
 type Leave struct {
-    ID         uint        `gorm:"primaryKey" json:"id"`
-    CreatedAt  time.Time   `json:"created_at"`
-    UpdatedAt  time.Time   `json:"updated_at"`
-    EmployeeID uint        `gorm:"index;not null" json:"employee_id"`
-    StartDate  time.Time   `gorm:"type:date;not null" json:"start_date"`
-    EndDate    time.Time   `gorm:"type:date;not null" json:"end_date"`
-    Reason     string      `gorm:"size:255" json:"reason"`
-    Status     LeaveStatus `gorm:"type:varchar(16);not null;default:PENDING" json:"status"`
-    Version    uint        `gorm:"default:1" json:"version"`
+    ID          uint              `gorm:"primaryKey" json:"id"`
+    CreatedAt   time.Time         `json:"created_at"`
+    UpdatedAt   time.Time         `json:"updated_at"`
+    EmployeeID  uint              `gorm:"index;not null" json:"employee_id"`
+    Type        LeaveType         `gorm:"type:varchar(16);not null;default:ANNUAL" json:"type"`
+    StartDate   time.Time         `gorm:"type:date;not null" json:"start_date"`
+    EndDate     time.Time         `gorm:"type:date;not null" json:"end_date"`
+    Reason      string            `gorm:"size:255" json:"reason"`
+    Status      LeaveStatus       `gorm:"type:varchar(16);not null;default:PENDING" json:"status"`
+    Attachments []LeaveAttachment `gorm:"constraint:OnDelete:CASCADE" json:"attachments,omitempty"`
+    Comments    []LeaveComment    `gorm:"constraint:OnDelete:CASCADE" json:"comments,omitempty"`
+    // Decision details; DecisionComment is mandatory for rejections.
+    DecidedBy       *uint      `json:"decided_by,omitempty"`
+    DecidedAt       *time.Time `json:"decided_at,omitempty"`
+    DecisionComment string     `gorm:"size:1000" json:"decision_comment,omitempty"`
+    // RuleWarning is the blackout or staffing rule the request broke when it was filed. Approval
+    // checks the rules again and needs an HR override if one is still broken.
+    RuleWarning string `gorm:"size:500" json:"rule_warning,omitempty"`
+    // Set when HR approved the leave despite a blackout or staffing rule.
+    OverriddenBy          *uint      `json:"overridden_by,omitempty"`
+    OverriddenAt          *time.Time `json:"overridden_at,omitempty"`
+    OverrideJustification string     `gorm:"size:500" json:"override_justification,omitempty"`
+    Version               uint       `gorm:"default:1" json:"version"`
 }
 
+// LeaveComment is one message in the discussion between an employee and approvers on a leave.
+type LeaveComment struct {
+    ID         uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt  time.Time `json:"created_at"`
+    LeaveID    uint      `gorm:"index;not null" json:"leave_id"`
+    AuthorID   uint      `gorm:"not null" json:"author_id"`
+    AuthorRole UserRole  `gorm:"type:varchar(16);not null" json:"author_role"`
+    Body       string    `gorm:"size:2000;not null" json:"body"`
+}
+
+// LeaveEntitlement is the number of days of a leave type every employee may take per calendar year.
+// Types without an entitlement have no balance.
+type LeaveEntitlement struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+    LeaveType   LeaveType `gorm:"type:varchar(16);uniqueIndex;not null" json:"leave_type"`
+    DaysPerYear float64   `gorm:"not null" json:"days_per_year"`
+}
 
+// LeaveBalance is what is left of an entitlement in a calendar year as of a date. Taken counts
+// the calendar days of approved leave from January 1 up to that date.
+type LeaveBalance struct {
+    LeaveType   LeaveType `json:"leave_type"`
+    Entitlement float64   `json:"entitlement"`
+    Taken       float64   `json:"taken"`
+    Remaining   float64   `json:"remaining"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// LeaveAttachment is supporting evidence (e.g. a medical certificate) for a leave request.
+// The file itself lives in blob storage under StorageKey.
+type LeaveAttachment struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt   time.Time `json:"created_at"`
+    LeaveID     uint      `gorm:"index;not null" json:"leave_id"`
+    FileName    string    `gorm:"size:255;not null" json:"file_name"`
+    ContentType string    `gorm:"size:100;not null" json:"content_type"`
+    Size        int64     `gorm:"not null" json:"size"`
+    StorageKey  string    `gorm:"size:255;not null" json:"-"`
+    UploadedBy  uint      `gorm:"not null" json:"uploaded_by"`
+}
+
+// LeaveAttachmentPolicy requires evidence for leaves of Type lasting at least MinDays days.
+type LeaveAttachmentPolicy struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+    LeaveType   LeaveType `gorm:"type:varchar(16);uniqueIndex;not null" json:"leave_type"`
+    MinDays     int       `gorm:"not null;default:1" json:"min_days"`
+    Description string    `gorm:"size:255" json:"description"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// LeaveBlackout forbids leave for a department between StartDate and EndDate (inclusive).
+type LeaveBlackout struct {
+    ID           uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time `json:"created_at"`
+    UpdatedAt    time.Time `json:"updated_at"`
+    DepartmentID uint      `gorm:"index" json:"department_id"`
+    Department   string    `gorm:"size:120;not null" json:"department"`
+    StartDate    time.Time `gorm:"type:date;not null" json:"start_date"`
+    EndDate      time.Time `gorm:"type:date;not null" json:"end_date"`
+    Reason       string    `gorm:"size:255" json:"reason"`
+}
+
+// StaffingRule bounds how many people of a department may be on leave on any day.
+// A zero MaxOut or MinOnDuty disables that check; a nil window applies the rule year-round.
+type StaffingRule struct {
+    ID           uint       `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time  `json:"created_at"`
+    UpdatedAt    time.Time  `json:"updated_at"`
+    DepartmentID uint       `gorm:"index" json:"department_id"`
+    Department   string     `gorm:"size:120;not null" json:"department"`
+    MaxOut       int        `gorm:"not null;default:0" json:"max_out"`
+    MinOnDuty    int        `gorm:"not null;default:0" json:"min_on_duty"`
+    StartDate    *time.Time `gorm:"type:date" json:"start_date,omitempty"`
+    EndDate      *time.Time `gorm:"type:date" json:"end_date,omitempty"`
+    Description  string     `gorm:"size:255" json:"description"`
+}
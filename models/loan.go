@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+// LoanKind tells a salary advance from a longer loan; both are repaid the same way.
+type LoanKind string
+
+const (
+    LoanAdvance LoanKind = "ADVANCE"
+    LoanTerm    LoanKind = "LOAN"
+)
+
+type LoanStatus string
+
+const (
+    LoanRequested LoanStatus = "REQUESTED"
+    LoanRejected  LoanStatus = "REJECTED"
+    LoanCancelled LoanStatus = "CANCELLED"
+    // LoanActive loans are being repaid.
+    LoanActive LoanStatus = "ACTIVE"
+    // LoanClosed loans are repaid in full, settled or written off.
+    LoanClosed LoanStatus = "CLOSED"
+)
+
+// Loan is an employee's request for a salary advance or loan. Approval fixes the terms and the
+// monthly repayment Schedule; Total is what the schedule repays, principal and interest.
+// Installments are deducted from the payroll runs whose periods end on or after their due dates;
+// Repayments record what was actually repaid, so installments a run could not cover are caught
+// up later. Repaid and Outstanding are filled in when the loan is loaded.
+type Loan struct {
+    ID         uint       `gorm:"primaryKey" json:"id"`
+    CreatedAt  time.Time  `json:"created_at"`
+    UpdatedAt  time.Time  `json:"updated_at"`
+    EmployeeID uint       `gorm:"not null;index" json:"employee_id"`
+    Kind       LoanKind   `gorm:"type:varchar(16);not null" json:"kind"`
+    Status     LoanStatus `gorm:"type:varchar(16);not null;default:REQUESTED;index" json:"status"`
+    Amount     float64    `gorm:"not null" json:"amount"`
+    // InterestRate is the yearly rate in percent, charged monthly on the unpaid principal.
+    InterestRate float64    `gorm:"not null;default:0" json:"interest_rate"`
+    Installments int        `gorm:"not null" json:"installments"`
+    FirstDueDate time.Time  `gorm:"type:date;not null" json:"first_due_date"`
+    Reason       string     `gorm:"size:500" json:"reason"`
+    RequestedBy  uint       `gorm:"not null" json:"requested_by"`
+    DecidedBy    *uint      `json:"decided_by,omitempty"`
+    DecidedAt    *time.Time `json:"decided_at,omitempty"`
+    DecisionNote string     `gorm:"size:500" json:"decision_note,omitempty"`
+    Total        float64    `gorm:"not null;default:0" json:"total"`
+    // DisbursementReference records how the approved amount was paid to the employee.
+    DisbursementReference string            `gorm:"size:100" json:"disbursement_reference,omitempty"`
+    DisbursedAt           *time.Time        `json:"disbursed_at,omitempty"`
+    ClosedAt              *time.Time        `json:"closed_at,omitempty"`
+    ClosedReason          string            `gorm:"size:200" json:"closed_reason,omitempty"`
+    Schedule              []LoanInstallment `gorm:"foreignKey:LoanID;constraint:OnDelete:CASCADE" json:"schedule,omitempty"`
+    Repayments            []LoanRepayment   `gorm:"foreignKey:LoanID;constraint:OnDelete:CASCADE" json:"repayments,omitempty"`
+    Repaid                float64           `gorm:"-" json:"repaid"`
+    Outstanding           float64           `gorm:"-" json:"outstanding"`
+}
+
+// LoanInstallment is one monthly repayment of a loan's schedule. Balance is the principal still
+// owed after it.
+type LoanInstallment struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    LoanID    uint      `gorm:"not null;uniqueIndex:idx_loan_installment" json:"loan_id"`
+    Number    int       `gorm:"not null;uniqueIndex:idx_loan_installment" json:"number"`
+    DueDate   time.Time `gorm:"type:date;not null" json:"due_date"`
+    Principal float64   `gorm:"not null" json:"principal"`
+    Interest  float64   `gorm:"not null" json:"interest"`
+    Amount    float64   `gorm:"not null" json:"amount"`
+    Balance   float64   `gorm:"not null" json:"balance"`
+}
+
+// LoanRepaymentSource is where a repayment came from.
+type LoanRepaymentSource string
+
+const (
+    // LoanRepaidPayroll repayments are deducted by a payroll run.
+    LoanRepaidPayroll LoanRepaymentSource = "PAYROLL"
+    // LoanRepaidDirect repayments are made by the employee outside payroll.
+    LoanRepaidDirect LoanRepaymentSource = "DIRECT"
+    // LoanWrittenOff is a balance the company waives.
+    LoanWrittenOff LoanRepaymentSource = "WRITE_OFF"
+    // LoanRepaidSettlement is a balance deducted in an employee's final settlement.
+    LoanRepaidSettlement LoanRepaymentSource = "SETTLEMENT"
+)
+
+// LoanRepayment is an amount repaid on a loan. Payroll repayments are recorded when their run is
+// calculated and Posted when it is finalized; until then they only hold the amount back from
+// other runs.
+type LoanRepayment struct {
+    ID           uint                `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time           `json:"created_at"`
+    LoanID       uint                `gorm:"not null;index" json:"loan_id"`
+    Source       LoanRepaymentSource `gorm:"type:varchar(16);not null" json:"source"`
+    Date         time.Time           `gorm:"type:date;not null" json:"date"`
+    Amount       float64             `gorm:"not null" json:"amount"`
+    PayrollRunID *uint               `gorm:"index" json:"payroll_run_id,omitempty"`
+    Posted       bool                `gorm:"not null;default:false" json:"posted"`
+    Reference    string              `gorm:"size:200" json:"reference,omitempty"`
+    RecordedBy   *uint               `json:"recorded_by,omitempty"`
+}
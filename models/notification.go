@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Notification is an in-app message for one user. Messages for a team are fanned out to every
+// member. DedupKey, when set, stops a scheduled job from sending the same message twice.
+type Notification struct {
+    ID         uint       `gorm:"primaryKey" json:"id"`
+    CreatedAt  time.Time  `json:"created_at"`
+    UserID     uint       `gorm:"index;not null" json:"user_id"`
+    Kind       string     `gorm:"size:40;not null;index" json:"kind"`
+    Title      string     `gorm:"size:200;not null" json:"title"`
+    Body       string     `gorm:"size:1000" json:"body"`
+    EntityType string     `gorm:"size:40" json:"entity_type,omitempty"`
+    EntityID   *uint      `json:"entity_id,omitempty"`
+    ReadAt     *time.Time `json:"read_at,omitempty"`
+    DedupKey   *string    `gorm:"size:200;uniqueIndex" json:"-"`
+}
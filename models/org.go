@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// NameKey fields hold the trimmed, space-collapsed, lower-cased name and enforce uniqueness,
+// so "Engineering" and "engineering " resolve to the same row.
+
+type Department struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+    Name      string    `gorm:"size:120;not null" json:"name"`
+    NameKey   string    `gorm:"size:120;uniqueIndex;not null" json:"-"`
+    ParentID  *uint     `gorm:"index" json:"parent_id,omitempty"`
+    HeadID    *uint     `json:"head_id,omitempty"` // employee heading the department
+}
+
+type Location struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+    Name      string    `gorm:"size:120;not null" json:"name"`
+    NameKey   string    `gorm:"size:120;uniqueIndex;not null" json:"-"`
+    Address   string    `gorm:"size:255" json:"address"`
+    City      string    `gorm:"size:120" json:"city"`
+    Country   string    `gorm:"size:2" json:"country"` // ISO 3166-1 alpha-2
+}
+
+type JobGrade struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+    Code      string    `gorm:"size:40;not null" json:"code"`
+    NameKey   string    `gorm:"size:40;uniqueIndex;not null" json:"-"`
+    Name      string    `gorm:"size:120" json:"name"`
+    Level     int       `gorm:"not null;default:0" json:"level"`
+    MinSalary float64   `gorm:"not null;default:0" json:"min_salary"`
+    MaxSalary float64   `gorm:"not null;default:0" json:"max_salary"` // 0 means unbounded
+    // NoticePeriodDays is the notice, in calendar days, employees of the grade give before leaving.
+    NoticePeriodDays int `gorm:"not null;default:0" json:"notice_period_days"`
+}
+
+type Position struct {
+    ID           uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time `json:"created_at"`
+    UpdatedAt    time.Time `json:"updated_at"`
+    Title        string    `gorm:"size:120;not null" json:"title"`
+    NameKey      string    `gorm:"size:120;uniqueIndex;not null" json:"-"`
+    DepartmentID *uint     `gorm:"index" json:"department_id,omitempty"`
+    GradeID      *uint     `json:"grade_id,omitempty"`
+}
@@ -0,0 +1,177 @@
+package models
+
+import "time"
+
+// PayFrequency is how often a pay period recurs; it decides the share of the annual salary a
+// period pays.
+type PayFrequency string
+
+const (
+    PayMonthly     PayFrequency = "MONTHLY"
+    PaySemiMonthly PayFrequency = "SEMI_MONTHLY"
+    PayBiweekly    PayFrequency = "BIWEEKLY"
+    PayWeekly      PayFrequency = "WEEKLY"
+)
+
+// PeriodsPerYear returns how many periods of f make a year, or 0 for an unknown frequency.
+func (f PayFrequency) PeriodsPerYear() int {
+    switch f {
+    case PayMonthly:
+        return 12
+    case PaySemiMonthly:
+        return 24
+    case PayBiweekly:
+        return 26
+    case PayWeekly:
+        return 52
+    }
+    return 0
+}
+
+// PayPeriod is a span of days paid together on PayDate. Periods do not overlap.
+type PayPeriod struct {
+    ID        uint         `gorm:"primaryKey" json:"id"`
+    CreatedAt time.Time    `json:"created_at"`
+    UpdatedAt time.Time    `json:"updated_at"`
+    Name      string       `gorm:"size:60;not null;uniqueIndex" json:"name"`
+    Frequency PayFrequency `gorm:"type:varchar(16);not null;default:MONTHLY" json:"frequency"`
+    StartDate time.Time    `gorm:"type:date;not null;index" json:"start_date"`
+    EndDate   time.Time    `gorm:"type:date;not null" json:"end_date"`
+    PayDate   time.Time    `gorm:"type:date;not null" json:"pay_date"`
+}
+
+type PayrollRunStatus string
+
+const (
+    PayrollDraft     PayrollRunStatus = "DRAFT"
+    PayrollReviewed  PayrollRunStatus = "REVIEWED"
+    PayrollFinalized PayrollRunStatus = "FINALIZED"
+    PayrollPaid      PayrollRunStatus = "PAID"
+)
+
+// Locked reports whether a run in status s freezes its period: from review on, the attendance,
+// leave, overtime and job data the run was computed from cannot change.
+func (s PayrollRunStatus) Locked() bool { return s != PayrollDraft }
+
+// PayrollRun computes the pay of every employee employed during a period. A draft run can be
+// recalculated; review locks the period's inputs, and finalizing also freezes the run for good.
+// Totals are the sums of the lines.
+type PayrollRun struct {
+    ID              uint             `gorm:"primaryKey" json:"id"`
+    CreatedAt       time.Time        `json:"created_at"`
+    UpdatedAt       time.Time        `json:"updated_at"`
+    PeriodID        uint             `gorm:"not null;uniqueIndex" json:"period_id"`
+    Period          *PayPeriod       `json:"period,omitempty"`
+    Status          PayrollRunStatus `gorm:"type:varchar(16);not null;default:DRAFT;index" json:"status"`
+    WorkingDays     int              `gorm:"not null" json:"working_days"`
+    Employees       int              `gorm:"not null" json:"employees"`
+    TotalGross      float64          `gorm:"not null" json:"total_gross"`
+    TotalDeductions float64          `gorm:"not null" json:"total_deductions"`
+    TotalNet        float64          `gorm:"not null" json:"total_net"`
+    CalculatedAt    time.Time        `json:"calculated_at"`
+    // Stale is set when inputs of a draft run change after calculation; review needs a recalculation.
+    Stale       bool          `gorm:"not null;default:false" json:"stale"`
+    CreatedBy   uint          `gorm:"not null" json:"created_by"`
+    ReviewedBy  *uint         `json:"reviewed_by,omitempty"`
+    ReviewedAt  *time.Time    `json:"reviewed_at,omitempty"`
+    FinalizedBy *uint         `json:"finalized_by,omitempty"`
+    FinalizedAt *time.Time    `json:"finalized_at,omitempty"`
+    PaidBy      *uint         `json:"paid_by,omitempty"`
+    PaidAt      *time.Time    `json:"paid_at,omitempty"`
+    Version     uint          `gorm:"default:1" json:"version"`
+    Lines       []PayrollLine `gorm:"foreignKey:RunID;constraint:OnDelete:CASCADE" json:"lines,omitempty"`
+}
+
+// PayrollLine is one employee's pay in a run. Items record how the amounts were derived, for
+// audit; the money columns are encrypted like salaries. Net pay is gross pay less deductions
+// plus reimbursements.
+type PayrollLine struct {
+    ID              uint          `gorm:"primaryKey" json:"id"`
+    CreatedAt       time.Time     `json:"created_at"`
+    RunID           uint          `gorm:"not null;uniqueIndex:idx_payroll_line_run_emp" json:"run_id"`
+    EmployeeID      uint          `gorm:"not null;uniqueIndex:idx_payroll_line_run_emp;index" json:"employee_id"`
+    EmployeeName    string        `gorm:"size:120" json:"employee_name"`
+    Department      string        `gorm:"size:120" json:"department"`
+    WorkingDays     int           `gorm:"not null" json:"working_days"`
+    EmployedDays    int           `gorm:"not null" json:"employed_days"`
+    AbsentDays      int           `gorm:"not null" json:"absent_days"`
+    UnpaidLeaveDays int           `gorm:"not null" json:"unpaid_leave_days"`
+    OvertimeHours   float64       `gorm:"not null" json:"overtime_hours"`
+    Gross           float64       `gorm:"type:text;not null;serializer:encrypted" json:"gross"`
+    Deductions      float64       `gorm:"type:text;not null;serializer:encrypted" json:"deductions"`
+    Net             float64       `gorm:"type:text;not null;serializer:encrypted" json:"net"`
+    Reimbursements  float64       `gorm:"type:text;serializer:encrypted" json:"reimbursements"`
+    Items           []PayrollItem `gorm:"type:text;serializer:encrypted" json:"items"`
+    // LoanRepayments is what the calculation deducted by loan, for the run to record.
+    LoanRepayments map[uint]float64 `gorm:"-" json:"-"`
+}
+
+type PayrollItemKind string
+
+const (
+    PayrollEarning   PayrollItemKind = "EARNING"
+    PayrollDeduction PayrollItemKind = "DEDUCTION"
+    // PayrollReimbursement repays expenses with net pay; it is not pay, so not part of gross.
+    PayrollReimbursement PayrollItemKind = "REIMBURSEMENT"
+)
+
+// PayrollItem is one amount on a payroll line. Earnings add up to gross pay (loss of pay is a
+// negative earning); deductions are positive and subtracted from it. Reimbursements are added
+// after deductions. Basis explains the figure.
+type PayrollItem struct {
+    Code   string          `json:"code"`
+    Label  string          `json:"label"`
+    Kind   PayrollItemKind `json:"kind"`
+    Amount float64         `json:"amount"`
+    Basis  string          `json:"basis,omitempty"`
+}
+
+type DeductionKind string
+
+const (
+    // DeductionPercent deducts Rate percent of gross pay.
+    DeductionPercent DeductionKind = "PERCENT"
+    // DeductionFixed deducts Rate every period.
+    DeductionFixed DeductionKind = "FIXED"
+)
+
+// DeductionRule is a configurable deduction applied by payroll runs, e.g. a pension contribution.
+// Cap limits the amount per period (0 for none); a rule with a DepartmentID only applies to that
+// department's employees. Rules apply in SortOrder and never take net pay below zero.
+type DeductionRule struct {
+    ID           uint          `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time     `json:"created_at"`
+    UpdatedAt    time.Time     `json:"updated_at"`
+    Code         string        `gorm:"size:40;not null;uniqueIndex" json:"code"`
+    Name         string        `gorm:"size:120;not null" json:"name"`
+    Kind         DeductionKind `gorm:"type:varchar(16);not null" json:"kind"`
+    Rate         float64       `gorm:"not null" json:"rate"`
+    Cap          float64       `gorm:"not null" json:"cap"`
+    DepartmentID *uint         `gorm:"index" json:"department_id,omitempty"`
+    Active       bool          `gorm:"not null" json:"active"`
+    SortOrder    int           `gorm:"not null;default:0" json:"sort_order"`
+}
+
+type OvertimeStatus string
+
+const (
+    OvertimePending  OvertimeStatus = "PENDING"
+    OvertimeApproved OvertimeStatus = "APPROVED"
+    OvertimeRejected OvertimeStatus = "REJECTED"
+)
+
+// OvertimeEntry is extra time worked on a day. Approved entries are paid by the payroll run of
+// their period at the hourly rate times Multiplier.
+type OvertimeEntry struct {
+    ID         uint           `gorm:"primaryKey" json:"id"`
+    CreatedAt  time.Time      `json:"created_at"`
+    UpdatedAt  time.Time      `json:"updated_at"`
+    EmployeeID uint           `gorm:"index;not null" json:"employee_id"`
+    Date       time.Time      `gorm:"type:date;not null;index" json:"date"`
+    Hours      float64        `gorm:"not null" json:"hours"`
+    Multiplier float64        `gorm:"not null;default:1.5" json:"multiplier"`
+    Reason     string         `gorm:"size:255" json:"reason"`
+    Status     OvertimeStatus `gorm:"type:varchar(16);not null;default:PENDING;index" json:"status"`
+    DecidedBy  *uint          `json:"decided_by,omitempty"`
+    DecidedAt  *time.Time     `json:"decided_at,omitempty"`
+}
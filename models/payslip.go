@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// PayslipTemplate decides how payslips look: branding, title, footer and which optional
+// sections are printed. The default template is used for every run; without one a plain
+// built-in layout applies. The logo is a JPEG kept in blob storage under LogoKey.
+type PayslipTemplate struct {
+    ID                uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt         time.Time `json:"created_at"`
+    UpdatedAt         time.Time `json:"updated_at"`
+    Name              string    `gorm:"size:120;not null;uniqueIndex" json:"name"`
+    IsDefault         bool      `gorm:"not null;default:false" json:"is_default"`
+    CompanyName       string    `gorm:"size:200" json:"company_name"`
+    CompanyAddress    string    `gorm:"size:500" json:"company_address"`
+    BrandColor        string    `gorm:"size:7" json:"brand_color"`
+    Title             string    `gorm:"size:120" json:"title"`
+    FooterText        string    `gorm:"size:500" json:"footer_text"`
+    ShowYTD           bool      `gorm:"not null;default:false" json:"show_ytd"`
+    ShowLeaveBalances bool      `gorm:"not null;default:false" json:"show_leave_balances"`
+    // ShowBankAccount prints the bank name and the last four digits of the account.
+    ShowBankAccount bool   `gorm:"not null;default:false" json:"show_bank_account"`
+    LogoKey         string `gorm:"size:255" json:"-"`
+    HasLogo         bool   `gorm:"-" json:"has_logo"`
+}
+
+// Payslip is the PDF of one payroll line, generated when its run is finalized and stored in blob
+// storage under StorageKey. Protected payslips open only with the employee's payslip password.
+type Payslip struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+    RunID       uint      `gorm:"not null;uniqueIndex:idx_payslip_run_emp" json:"run_id"`
+    EmployeeID  uint      `gorm:"not null;uniqueIndex:idx_payslip_run_emp;index" json:"employee_id"`
+    LineID      uint      `gorm:"not null" json:"line_id"`
+    PeriodName  string    `gorm:"size:60;not null" json:"period_name"`
+    PayDate     time.Time `gorm:"type:date;not null" json:"pay_date"`
+    TemplateID  *uint     `json:"template_id,omitempty"`
+    FileName    string    `gorm:"size:255;not null" json:"file_name"`
+    Size        int64     `gorm:"not null" json:"size"`
+    StorageKey  string    `gorm:"size:255;not null" json:"-"`
+    Protected   bool      `gorm:"not null;default:false" json:"protected"`
+    GeneratedAt time.Time `json:"generated_at"`
+}
+
+// PayslipPreference holds an employee's payslip password, encrypted at rest. Payslips generated
+// while a password is set are protected with it.
+type PayslipPreference struct {
+    ID         uint      `gorm:"primaryKey" json:"id"`
+    CreatedAt  time.Time `json:"created_at"`
+    UpdatedAt  time.Time `json:"updated_at"`
+    EmployeeID uint      `gorm:"not null;uniqueIndex" json:"employee_id"`
+    Password   string    `gorm:"type:text;serializer:encrypted" json:"-"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// Permission is a capability granted to roles, for checks finer than "is HR".
+type Permission string
+
+const (
+	// PermExport allows bulk data exports.
+	PermExport Permission = "export"
+	// PermViewSalary allows reading salary figures.
+	PermViewSalary Permission = "view_salary"
+	// PermViewEmployees allows reading every employee record, not just the directory view.
+	PermViewEmployees Permission = "view_employees"
+	// PermRunPayroll allows managing pay periods, deductions and payroll runs.
+	PermRunPayroll Permission = "run_payroll"
+)
+
+var rolePermissions = map[UserRole][]Permission{
+	RoleHR:      {PermExport, PermViewSalary, PermViewEmployees, PermRunPayroll},
+	RoleAuditor: {PermExport, PermViewEmployees},
+	RoleFinance: {PermViewSalary, PermRunPayroll},
+}
+
+// HasPermission reports whether role has been granted p.
+func HasPermission(role UserRole, p Permission) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
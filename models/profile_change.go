@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type ChangeRequestStatus string
+
+const (
+    ChangePending   ChangeRequestStatus = "PENDING"
+    ChangeApproved  ChangeRequestStatus = "APPROVED"
+    ChangeRejected  ChangeRequestStatus = "REJECTED"
+    ChangeCancelled ChangeRequestStatus = "CANCELLED"
+)
+
+// ProfileChangeRequest holds an employee's changes to sensitive profile fields until HR decides
+// on them. Changes and Previous map employee column names to the requested and the old values.
+// An employee has at most one pending request; further changes are merged into it. Both maps
+// hold bank details, so they are encrypted at rest.
+type ProfileChangeRequest struct {
+    ID           uint                `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time           `json:"created_at"`
+    UpdatedAt    time.Time           `json:"updated_at"`
+    EmployeeID   uint                `gorm:"index;not null" json:"employee_id"`
+    RequestedBy  uint                `gorm:"not null" json:"requested_by"`
+    Changes      map[string]string   `gorm:"type:text;serializer:encrypted;not null" json:"changes"`
+    Previous     map[string]string   `gorm:"type:text;serializer:encrypted" json:"previous"`
+    Status       ChangeRequestStatus `gorm:"type:varchar(16);not null;default:PENDING;index" json:"status"`
+    DecidedBy    *uint               `json:"decided_by,omitempty"`
+    DecidedAt    *time.Time          `json:"decided_at,omitempty"`
+    DecisionNote string              `gorm:"size:500" json:"decision_note,omitempty"`
+    Version      uint                `gorm:"default:1" json:"version"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// SalaryComponentType decides how a component's monthly amount is derived.
+type SalaryComponentType string
+
+const (
+    // ComponentFixed pays Amount every month.
+    ComponentFixed SalaryComponentType = "FIXED"
+    // ComponentPercentOfBasic is Percent of the BASIC component.
+    ComponentPercentOfBasic SalaryComponentType = "PERCENT_OF_BASIC"
+    // ComponentFormula evaluates Formula over CTC (annual) and the monthly amounts of other
+    // components, referenced by code.
+    ComponentFormula SalaryComponentType = "FORMULA"
+    // ComponentBalance is what is left of the monthly CTC after every other earning, e.g. a
+    // special allowance. A structure has at most one.
+    ComponentBalance SalaryComponentType = "BALANCE"
+)
+
+// BasicComponent is the code of the component every structure must have; percent-of-basic
+// components refer to it.
+const BasicComponent = "BASIC"
+
+// SalaryStructure splits an employee's annual salary (cost to company, CTC) into monthly
+// earning and deduction components.
+type SalaryStructure struct {
+    ID          uint              `gorm:"primaryKey" json:"id"`
+    CreatedAt   time.Time         `json:"created_at"`
+    UpdatedAt   time.Time         `json:"updated_at"`
+    Name        string            `gorm:"size:120;not null;uniqueIndex" json:"name"`
+    Description string            `gorm:"size:500" json:"description"`
+    Components  []SalaryComponent `gorm:"foreignKey:StructureID;constraint:OnDelete:CASCADE" json:"components"`
+}
+
+// SalaryComponent is one line of a structure. Earnings make up the CTC; deductions, e.g. an
+// employee pension contribution, are withheld from them.
+type SalaryComponent struct {
+    ID          uint                `gorm:"primaryKey" json:"id"`
+    StructureID uint                `gorm:"not null;uniqueIndex:idx_salary_component_code" json:"structure_id"`
+    Code        string              `gorm:"size:40;not null;uniqueIndex:idx_salary_component_code" json:"code"`
+    Name        string              `gorm:"size:120;not null" json:"name"`
+    Kind        PayrollItemKind     `gorm:"type:varchar(16);not null" json:"kind"`
+    Type        SalaryComponentType `gorm:"type:varchar(20);not null" json:"type"`
+    Amount      float64             `gorm:"not null" json:"amount,omitempty"`
+    Percent     float64             `gorm:"not null" json:"percent,omitempty"`
+    Formula     string              `gorm:"size:500" json:"formula,omitempty"`
+    SortOrder   int                 `gorm:"not null;default:0" json:"sort_order"`
+}
+
+// SalaryStructureAssignment puts an employee on a structure from EffectiveDate until their next
+// assignment. The CTC expanded is the salary in effect on each day.
+type SalaryStructureAssignment struct {
+    ID            uint             `gorm:"primaryKey" json:"id"`
+    CreatedAt     time.Time        `json:"created_at"`
+    EmployeeID    uint             `gorm:"not null;uniqueIndex:idx_salary_assignment_emp_date" json:"employee_id"`
+    StructureID   uint             `gorm:"not null;index" json:"structure_id"`
+    Structure     *SalaryStructure `json:"structure,omitempty"`
+    EffectiveDate time.Time        `gorm:"type:date;not null;uniqueIndex:idx_salary_assignment_emp_date" json:"effective_date"`
+    Note          string           `gorm:"size:255" json:"note,omitempty"`
+    CreatedBy     uint             `gorm:"not null" json:"created_by"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+type SettlementStatus string
+
+const (
+    SettlementDraft     SettlementStatus = "DRAFT"
+    SettlementFinalized SettlementStatus = "FINALIZED"
+)
+
+// FinalSettlement is what a leaving employee is owed, or owes, beyond the payroll runs that paid
+// them: salary from PayFrom, the day after the last pay period they were paid in, to the last
+// working day; leave encashment; expense claims not yet reimbursed; less the notice period not
+// served and outstanding loans, as far as the rest covers them. Items record how the amounts were
+// derived like a payroll line's, with HR adjustments under the code ADJUSTMENT; a negative Net is
+// owed by the employee. The money columns are encrypted like salaries. A draft is recalculated
+// until HR finalizes it, which repays the loans and settles the claims and keeps later payroll
+// runs from paying the employee again.
+type FinalSettlement struct {
+    ID               uint             `gorm:"primaryKey" json:"id"`
+    CreatedAt        time.Time        `json:"created_at"`
+    UpdatedAt        time.Time        `json:"updated_at"`
+    EmployeeID       uint             `gorm:"not null;uniqueIndex" json:"employee_id"`
+    EmployeeName     string           `gorm:"size:120" json:"employee_name"`
+    Status           SettlementStatus `gorm:"type:varchar(16);not null;default:DRAFT;index" json:"status"`
+    LastWorkingDay   time.Time        `gorm:"type:date;not null" json:"last_working_day"`
+    PayFrom          time.Time        `gorm:"type:date;not null" json:"pay_from"`
+    NoticeDate       *time.Time       `gorm:"type:date" json:"notice_date,omitempty"`
+    NoticePeriodDays int              `gorm:"not null;default:0" json:"notice_period_days"`
+    NoticeServedDays int              `gorm:"not null;default:0" json:"notice_served_days"`
+    ShortfallDays    int              `gorm:"not null;default:0" json:"shortfall_days"`
+    WorkedDays       int              `gorm:"not null;default:0" json:"worked_days"`
+    UnpaidDays       int              `gorm:"not null;default:0" json:"unpaid_days"`
+    AnnualSalary     float64          `gorm:"type:text;not null;serializer:encrypted" json:"annual_salary"`
+    Earnings         float64          `gorm:"type:text;not null;serializer:encrypted" json:"earnings"`
+    Deductions       float64          `gorm:"type:text;not null;serializer:encrypted" json:"deductions"`
+    Reimbursements   float64          `gorm:"type:text;not null;serializer:encrypted" json:"reimbursements"`
+    Net              float64          `gorm:"type:text;not null;serializer:encrypted" json:"net"`
+    Items            []PayrollItem    `gorm:"type:text;serializer:encrypted" json:"items"`
+    CalculatedBy     uint             `gorm:"not null" json:"calculated_by"`
+    CalculatedAt     time.Time        `json:"calculated_at"`
+    FinalizedBy      *uint            `json:"finalized_by,omitempty"`
+    FinalizedAt      *time.Time       `json:"finalized_at,omitempty"`
+    Version          uint             `gorm:"default:1" json:"version"`
+    // LoanRepayments is what the calculation recovers by loan, for finalizing to post.
+    LoanRepayments map[uint]float64 `gorm:"-" json:"-"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// StatutoryRuleSet is one version of the statutory deduction rules of a country: income tax,
+// social security, pension. A version applies to pay periods ending from EffectiveFrom until the
+// next version of the same country. Engine names the registered rule engine that interprets Params.
+// An empty Country applies to employees whose country has no rule set of its own.
+// The tax year starts on the first of TaxYearStartMonth.
+type StatutoryRuleSet struct {
+    ID                uint            `gorm:"primaryKey" json:"id"`
+    CreatedAt         time.Time       `json:"created_at"`
+    UpdatedAt         time.Time       `json:"updated_at"`
+    Name              string          `gorm:"size:120;not null" json:"name"`
+    Country           string          `gorm:"size:2;not null;default:'';uniqueIndex:idx_statutory_country_from" json:"country"`
+    EffectiveFrom     time.Time       `gorm:"type:date;not null;uniqueIndex:idx_statutory_country_from" json:"effective_from"`
+    Engine            string          `gorm:"size:40;not null" json:"engine"`
+    TaxYearStartMonth int             `gorm:"not null;default:1" json:"tax_year_start_month"`
+    Params            json.RawMessage `gorm:"type:jsonb;serializer:json" json:"params"`
+    Categories        []string        `gorm:"-" json:"categories,omitempty"`
+    CreatedBy         uint            `gorm:"not null" json:"created_by"`
+}
+
+type TaxDeclarationStatus string
+
+const (
+    DeclarationPending  TaxDeclarationStatus = "PENDING"
+    DeclarationApproved TaxDeclarationStatus = "APPROVED"
+    DeclarationRejected TaxDeclarationStatus = "REJECTED"
+)
+
+// TaxDeclaration is an amount an employee declares for a tax year, e.g. investments or rent,
+// that lowers their taxable income once approved. TaxYear is the calendar year the tax year
+// starts in; Category matches an exemption of the employee's rule set. The amount is encrypted.
+type TaxDeclaration struct {
+    ID           uint                 `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time            `json:"created_at"`
+    UpdatedAt    time.Time            `json:"updated_at"`
+    EmployeeID   uint                 `gorm:"index;not null" json:"employee_id"`
+    TaxYear      int                  `gorm:"not null;index" json:"tax_year"`
+    Category     string               `gorm:"size:40;not null" json:"category"`
+    Description  string               `gorm:"size:500" json:"description"`
+    Amount       float64              `gorm:"type:text;not null;serializer:encrypted" json:"amount"`
+    Status       TaxDeclarationStatus `gorm:"type:varchar(16);not null;default:PENDING;index" json:"status"`
+    DecidedBy    *uint                `json:"decided_by,omitempty"`
+    DecidedAt    *time.Time           `json:"decided_at,omitempty"`
+    DecisionNote string               `gorm:"size:500" json:"decision_note,omitempty"`
+}
@@ -7,17 +7,36 @@ type UserRole string
 const (
     RoleHR       UserRole = "HR"
     RoleEmployee UserRole = "EMPLOYEE"
+    // RoleAuditor can export data for audits but cannot see salaries.
+    RoleAuditor UserRole = "AUDITOR"
+    // RoleIT works the IT tasks of onboarding and offboarding checklists.
+    RoleIT UserRole = "IT"
+    // RoleManager sees more of the employees in their reporting line than of other colleagues.
+    RoleManager UserRole = "MANAGER"
+    // RoleFinance runs payroll.
+    RoleFinance UserRole = "FINANCE"
 )
 
+// ValidRole reports whether r is one of the known roles.
+func ValidRole(r UserRole) bool {
+    switch r {
+    case RoleHR, RoleEmployee, RoleAuditor, RoleIT, RoleManager, RoleFinance:
+        return true
+    }
+    return false
+}
+
 type User struct {
-    ID           uint      `gorm:"primaryKey" json:"id"`
-    CreatedAt    time.Time `json:"created_at"`
-    UpdatedAt    time.Time `json:"updated_at"`
-    Username     string    `gorm:"uniqueIndex;size:80;not null" json:"username"`
-    PasswordHash string    `gorm:"not null" json:"-"`
-    Role         UserRole  `gorm:"type:varchar(16);not null" json:"role"`
+    ID           uint       `gorm:"primaryKey" json:"id"`
+    CreatedAt    time.Time  `json:"created_at"`
+    UpdatedAt    time.Time  `json:"updated_at"`
+    Username     string     `gorm:"uniqueIndex;size:80;not null" json:"username"`
+    PasswordHash string     `gorm:"not null" json:"-"`
+    Role         UserRole   `gorm:"type:varchar(16);not null" json:"role"`
+    Disabled     bool       `gorm:"not null;default:false" json:"disabled"`
+    DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+    // TokenVersion is embedded in issued tokens; bumping it revokes every outstanding token.
+    TokenVersion uint `gorm:"not null;default:0" json:"-"`
     // Optimistic locking version
     Version uint `gorm:"default:1" json:"version"`
 }
-
-
@@ -0,0 +1,63 @@
+package models
+
+// Audience is how a caller relates to a record, which decides the record fields they see.
+type Audience string
+
+const (
+	// AudienceFull sees whole records: callers with PermViewEmployees.
+	AudienceFull Audience = "FULL"
+	// AudienceSelf is the employee the record belongs to.
+	AudienceSelf Audience = "SELF"
+	// AudienceManager is a MANAGER the employee reports to, directly or indirectly.
+	AudienceManager Audience = "MANAGER"
+	// AudiencePeer is any other colleague, who sees the directory view.
+	AudiencePeer Audience = "PEER"
+)
+
+// employeeDirectoryFields are the employee fields everyone may see.
+var employeeDirectoryFields = []string{"id", "name", "preferred_name", "position", "position_id",
+	"department", "department_id", "location", "location_id", "manager_id", "status"}
+
+// employeeManagerFields are what managers see of their reports on top of the directory view.
+var employeeManagerFields = []string{"grade", "grade_id", "phone", "probation_end_date", "confirmed_at",
+	"termination_date", "emergency_contacts", "custom_fields"}
+
+// employeeFieldPermissions gates employee fields behind a permission for everyone but the
+// employee themselves, whatever their audience.
+var employeeFieldPermissions = map[string]Permission{
+	"salary":              PermViewSalary,
+	"bank_account_holder": PermViewSalary,
+	"bank_name":           PermViewSalary,
+	"bank_account_number": PermViewSalary,
+	"bank_routing_code":   PermViewSalary,
+}
+
+var employeeAudienceFields = map[Audience]map[string]bool{
+	AudiencePeer:    fieldSet(employeeDirectoryFields),
+	AudienceManager: fieldSet(employeeDirectoryFields, employeeManagerFields),
+}
+
+func fieldSet(lists ...[]string) map[string]bool {
+	set := map[string]bool{}
+	for _, l := range lists {
+		for _, f := range l {
+			set[f] = true
+		}
+	}
+	return set
+}
+
+// EmployeeFieldVisible reports whether a caller with role, relating to an employee as aud, may see
+// the employee's json field f. Custom field values are further limited by their definitions.
+func EmployeeFieldVisible(aud Audience, role UserRole, f string) bool {
+	if aud == AudienceSelf {
+		return true
+	}
+	if p, ok := employeeFieldPermissions[f]; ok && !HasPermission(role, p) {
+		return false
+	}
+	if fields, ok := employeeAudienceFields[aud]; ok {
+		return fields[f]
+	}
+	return aud == AudienceFull
+}
@@ -0,0 +1,107 @@
+package pdf
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"encoding/binary"
+	"fmt"
+)
+
+// permissions granted to anyone who opens the document: every bit an R4 handler defines.
+const permissions int32 = -4
+
+// passwordPadding pads passwords to 32 bytes (PDF 1.7, 7.6.3.3).
+var passwordPadding = []byte{
+	0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41, 0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+	0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80, 0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+}
+
+// encryption implements the standard security handler, revision 4, with AES-128 (AESV2) for
+// strings and streams. The owner password is random, so nobody can lift the protection.
+type encryption struct {
+	key []byte
+}
+
+func padPassword(password string) []byte {
+	b := encode(password)
+	if len(b) > 32 {
+		b = b[:32]
+	}
+	return append(b, passwordPadding[:32-len(b)]...)
+}
+
+func rc4Rounds(key, data []byte) []byte {
+	out := append([]byte(nil), data...)
+	k := make([]byte, len(key))
+	for i := 0; i < 20; i++ {
+		for j := range key {
+			k[j] = key[j] ^ byte(i)
+		}
+		c, _ := rc4.NewCipher(k) // key length is always valid
+		c.XORKeyStream(out, out)
+	}
+	return out
+}
+
+// newEncryption derives the file key for userPassword and returns the /Encrypt dictionary.
+func newEncryption(userPassword string, id []byte) (*encryption, string, error) {
+	owner := make([]byte, 32)
+	if _, err := rand.Read(owner); err != nil {
+		return nil, "", err
+	}
+	user := padPassword(userPassword)
+
+	// O entry (algorithm 3)
+	h := md5.Sum(owner)
+	for i := 0; i < 50; i++ {
+		h = md5.Sum(h[:])
+	}
+	o := rc4Rounds(h[:], user)
+
+	// file key (algorithm 2)
+	buf := append(append(append([]byte(nil), user...), o...), make([]byte, 4)...)
+	p := permissions
+	binary.LittleEndian.PutUint32(buf[64:], uint32(p))
+	buf = append(buf, id...)
+	h = md5.Sum(buf)
+	for i := 0; i < 50; i++ {
+		h = md5.Sum(h[:])
+	}
+	key := append([]byte(nil), h[:]...)
+
+	// U entry (algorithm 5)
+	h = md5.Sum(append(append([]byte(nil), passwordPadding...), id...))
+	u := append(rc4Rounds(key, h[:]), make([]byte, 16)...)
+
+	dict := fmt.Sprintf("<< /Filter /Standard /V 4 /R 4 /Length 128 "+
+		"/CF << /StdCF << /CFM /AESV2 /AuthEvent /DocOpen /Length 16 >> >> /StmF /StdCF /StrF /StdCF "+
+		"/O <%x> /U <%x> /P %d >>", o, u, permissions)
+	return &encryption{key: key}, dict, nil
+}
+
+// encrypt encrypts a string or stream of object n (generation 0) as AES-128-CBC with a random
+// IV in front (algorithm 1).
+func (e *encryption) encrypt(n int, data []byte) ([]byte, error) {
+	k := append([]byte(nil), e.key...)
+	k = append(k, byte(n), byte(n>>8), byte(n>>16), 0, 0)
+	k = append(k, "sAlT"...)
+	objKey := md5.Sum(k)
+	block, err := aes.NewCipher(objKey[:])
+	if err != nil {
+		return nil, err
+	}
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	out := make([]byte, aes.BlockSize+len(data)+pad)
+	if _, err := rand.Read(out[:aes.BlockSize]); err != nil {
+		return nil, err
+	}
+	copy(out[aes.BlockSize:], data)
+	for i := len(out) - pad; i < len(out); i++ {
+		out[i] = byte(pad)
+	}
+	cipher.NewCBCEncrypter(block, out[:aes.BlockSize]).CryptBlocks(out[aes.BlockSize:], out[aes.BlockSize:])
+	return out, nil
+}
@@ -0,0 +1,368 @@
+// Package pdf writes simple A4 PDF documents: text in the standard Helvetica fonts, lines,
+// filled rectangles and JPEG images, optionally protected by a password. It covers what
+// generated reports such as payslips need without an external dependency.
+//
+// Coordinates are in points (1/72 inch) from the top-left corner of the page, y growing
+// downwards. Text is encoded as Windows-1252; other characters print as '?'.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // registers the JPEG decoder for image.DecodeConfig
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A4 page size in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Font selects one of the built-in fonts.
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+var fontNames = [...]string{Helvetica: "Helvetica", HelveticaBold: "Helvetica-Bold"}
+
+// Document is a PDF under construction. It is not safe for concurrent use.
+type Document struct {
+	pages    []*Page
+	images   []*Image
+	title    string
+	author   string
+	password string
+	now      func() time.Time
+}
+
+func New() *Document { return &Document{now: time.Now} }
+
+// SetInfo sets the title and author shown by PDF viewers.
+func (d *Document) SetInfo(title, author string) {
+	d.title, d.author = title, author
+}
+
+// SetPassword encrypts the document with AES-128; viewers ask for password before opening it.
+// An empty password leaves the document unencrypted.
+func (d *Document) SetPassword(password string) { d.password = password }
+
+// Image is a JPEG added to a document; it can be drawn on any of its pages.
+type Image struct {
+	name          string
+	data          []byte
+	Width, Height int // pixels
+	colorSpace    string
+}
+
+// AddJPEG adds a JPEG image to the document.
+func (d *Document) AddJPEG(data []byte) (*Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if format != "jpeg" {
+		return nil, errors.New("pdf: only JPEG images are supported")
+	}
+	cs := "/DeviceRGB"
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		cs = "/DeviceGray"
+	case color.CMYKModel:
+		cs = "/DeviceCMYK"
+	}
+	img := &Image{name: fmt.Sprintf("Im%d", len(d.images)+1), data: data, Width: cfg.Width, Height: cfg.Height, colorSpace: cs}
+	d.images = append(d.images, img)
+	return img, nil
+}
+
+// Page is one page of a document; drawing methods append to its content.
+type Page struct {
+	buf bytes.Buffer
+}
+
+// AddPage appends an empty A4 page.
+func (d *Document) AddPage() *Page {
+	p := &Page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+func num(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+// SetFillColor sets the color of text and filled shapes; components range from 0 to 1.
+func (p *Page) SetFillColor(r, g, b float64) {
+	fmt.Fprintf(&p.buf, "%s %s %s rg\n", num(r), num(g), num(b))
+}
+
+// SetStrokeColor sets the color of lines.
+func (p *Page) SetStrokeColor(r, g, b float64) {
+	fmt.Fprintf(&p.buf, "%s %s %s RG\n", num(r), num(g), num(b))
+}
+
+// Text draws s with its baseline starting at (x, y).
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.buf, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n", font+1, num(size), num(x), num(PageHeight-y), escape(encode(s)))
+}
+
+// TextRight draws s so that it ends at x.
+func (p *Page) TextRight(x, y float64, font Font, size float64, s string) {
+	p.Text(x-Width(font, size, s), y, font, size, s)
+}
+
+// Rect fills the rectangle with its top-left corner at (x, y).
+func (p *Page) Rect(x, y, w, h float64) {
+	fmt.Fprintf(&p.buf, "%s %s %s %s re f\n", num(x), num(PageHeight-y-h), num(w), num(h))
+}
+
+// Line strokes a line of the given width.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.buf, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+// Image draws img scaled to w by h with its top-left corner at (x, y).
+func (p *Page) Image(img *Image, x, y, w, h float64) {
+	fmt.Fprintf(&p.buf, "q %s 0 0 %s %s %s cm /%s Do Q\n", num(w), num(h), num(x), num(PageHeight-y-h), img.name)
+}
+
+// Width returns the width of s in points when set in font at size.
+func Width(font Font, size float64, s string) float64 {
+	widths := &helveticaWidths
+	if font == HelveticaBold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, c := range encode(s) {
+		if c >= 32 && c <= 126 {
+			total += widths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// ParseColor parses a "#rrggbb" color into components from 0 to 1.
+func ParseColor(hex string) (r, g, b float64, err error) {
+	hex = strings.TrimPrefix(hex, "#")
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return 0, 0, 0, fmt.Errorf("pdf: invalid color %q", hex)
+	}
+	return float64(v>>16&0xff) / 255, float64(v>>8&0xff) / 255, float64(v&0xff) / 255, nil
+}
+
+// cp1252 maps the characters Windows-1252 places in 0x80-0x9F.
+var cp1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88, '‰': 0x89,
+	'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95,
+	'–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			out = append(out, ' ')
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		default:
+			if b, ok := cp1252[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if c == '\\' || c == '(' || c == ')' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// Bytes renders the document.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := d.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writer numbers objects and records their offsets for the cross-reference table.
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+	enc     *encryption
+}
+
+// begin starts the next object and returns its number.
+func (w *writer) begin() int {
+	w.offsets = append(w.offsets, w.buf.Len())
+	n := len(w.offsets)
+	fmt.Fprintf(&w.buf, "%d 0 obj\n", n)
+	return n
+}
+
+func (w *writer) end() { w.buf.WriteString("endobj\n") }
+
+// object writes a dictionary object.
+func (w *writer) object(dict string) int {
+	n := w.begin()
+	w.buf.WriteString(dict + "\n")
+	w.end()
+	return n
+}
+
+// stream writes a stream object; extra holds further dictionary entries.
+func (w *writer) stream(extra string, data []byte) (int, error) {
+	n := w.begin()
+	if w.enc != nil {
+		var err error
+		if data, err = w.enc.encrypt(n, data); err != nil {
+			return 0, err
+		}
+	}
+	fmt.Fprintf(&w.buf, "<< %s /Length %d >>\nstream\n", extra, len(data))
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\n")
+	w.end()
+	return n, nil
+}
+
+// text returns a string literal for object n, encrypted when the document is.
+func (w *writer) text(n int, s string) (string, error) {
+	b := encode(s)
+	if w.enc == nil {
+		return "(" + escape(b) + ")", nil
+	}
+	enc, err := w.enc.encrypt(n, b)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("<%x>", enc), nil
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write renders the document to out. Objects are numbered in writing order: the catalog and
+// page tree come first so that their numbers are known to the pages.
+func (d *Document) Write(out io.Writer) error {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	w := &writer{}
+	var encDict string
+	if d.password != "" {
+		enc, dict, err := newEncryption(d.password, id)
+		if err != nil {
+			return err
+		}
+		w.enc, encDict = enc, dict
+	}
+
+	w.buf.WriteString("%PDF-1.6\n%\xe2\xe3\xcf\xd3\n")
+	const catalog, pages, resources = 1, 2, 3
+	firstPage := resources + 3 + len(d.images) // after the fonts and images
+	w.object(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	w.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	var xobjects []string
+	for i, img := range d.images {
+		xobjects = append(xobjects, fmt.Sprintf("/%s %d 0 R", img.name, resources+3+i))
+	}
+	w.object(fmt.Sprintf("<< /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject << %s >> >>", resources+1, resources+2, strings.Join(xobjects, " ")))
+	for _, name := range fontNames {
+		w.object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	for _, img := range d.images {
+		if _, err := w.stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode",
+			img.Width, img.Height, img.colorSpace), img.data); err != nil {
+			return err
+		}
+	}
+	for i, p := range d.pages {
+		w.object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %d 0 R /Contents %d 0 R >>",
+			pages, num(PageWidth), num(PageHeight), resources, firstPage+2*i+1))
+		content, err := deflate(p.buf.Bytes())
+		if err != nil {
+			return err
+		}
+		if _, err := w.stream("/Filter /FlateDecode", content); err != nil {
+			return err
+		}
+	}
+
+	infoNum := len(w.offsets) + 1
+	var info []string
+	for _, kv := range [][2]string{
+		{"Title", d.title},
+		{"Author", d.author},
+		{"Producer", "hrms-backend"},
+		{"CreationDate", d.now().UTC().Format("D:20060102150405Z")},
+	} {
+		if kv[1] == "" {
+			continue
+		}
+		s, err := w.text(infoNum, kv[1])
+		if err != nil {
+			return err
+		}
+		info = append(info, "/"+kv[0]+" "+s)
+	}
+	w.object("<< " + strings.Join(info, " ") + " >>")
+	encNum := 0
+	if encDict != "" {
+		encNum = w.object(encDict)
+	}
+	trailer := fmt.Sprintf("/Size %d /Root %d 0 R /Info %d 0 R /ID [<%x> <%x>]", len(w.offsets)+1, catalog, infoNum, id, id)
+	if encNum != 0 {
+		trailer += fmt.Sprintf(" /Encrypt %d 0 R", encNum)
+	}
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, off := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< %s >>\nstartxref\n%d\n%%%%EOF\n", trailer, xref)
+	_, err := out.Write(w.buf.Bytes())
+	return err
+}
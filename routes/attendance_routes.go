@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/example/hrms-backend/controllers"
 	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
@@ -17,6 +18,11 @@ func registerAttendanceRoutes(r *mux.Router, db *gorm.DB) {
 	s.HandleFunc("", c.AddMine).Methods("POST")
 	s.HandleFunc("/{id:[0-9]+}", c.DeleteMine).Methods("DELETE")
 
+	// Exports
+	ex := s.NewRoute().Subrouter()
+	ex.Use(middlewares.RequirePermission(models.PermExport))
+	ex.HandleFunc("/export", controllers.NewExportController(db).Attendance).Methods("GET")
+
 	// HR
 	hr := s.NewRoute().Subrouter()
 	hr.Use(middlewares.RequireRole("HR"))
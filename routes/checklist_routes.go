@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerChecklistRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewChecklistController(db)
+
+	// Templates (HR)
+	t := r.PathPrefix("/checklist-templates").Subrouter()
+	t.Use(middlewares.JWTAuth, middlewares.RequireRole("HR"))
+	t.HandleFunc("", c.ListTemplates).Methods("GET")
+	t.HandleFunc("", c.SaveTemplate).Methods("POST")
+	t.HandleFunc("/{id:[0-9]+}", c.GetTemplate).Methods("GET")
+	t.HandleFunc("/{id:[0-9]+}", c.SaveTemplate).Methods("PUT")
+	t.HandleFunc("/{id:[0-9]+}", c.DeleteTemplate).Methods("DELETE")
+
+	s := r.PathPrefix("/checklists").Subrouter()
+	s.Use(middlewares.JWTAuth)
+
+	// Task owners (HR or the assignee)
+	s.HandleFunc("/tasks/mine", c.MyTasks).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}", c.Get).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/tasks/{tid:[0-9]+}", c.UpdateTask).Methods("PATCH")
+
+	// HR
+	hr := s.NewRoute().Subrouter()
+	hr.Use(middlewares.RequireRole("HR"))
+	hr.HandleFunc("", c.List).Methods("GET")
+	hr.HandleFunc("/{id:[0-9]+}/cancel", c.Cancel).Methods("POST")
+}
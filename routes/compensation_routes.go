@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerCompensationRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewCompensationController(db)
+	s := r.PathPrefix("/compensation").Subrouter()
+	s.Use(middlewares.JWTAuth)
+
+	// Employees download their own letters
+	s.HandleFunc("/letters/me", c.MyLetters).Methods("GET")
+	s.HandleFunc("/proposals/{id:[0-9]+}/letter", c.DownloadLetter).Methods("GET")
+
+	// Managers propose for their reports; the controller admits managers and HR
+	s.HandleFunc("/cycles", c.ListCycles).Methods("GET")
+	s.HandleFunc("/cycles/{id:[0-9]+}", c.GetCycle).Methods("GET")
+	s.HandleFunc("/cycles/{id:[0-9]+}/team", c.Team).Methods("GET")
+	s.HandleFunc("/cycles/{id:[0-9]+}/proposals", c.SaveProposal).Methods("POST")
+	s.HandleFunc("/proposals/{id:[0-9]+}/submit", c.SubmitProposal).Methods("POST")
+	s.HandleFunc("/proposals/{id:[0-9]+}", c.DeleteProposal).Methods("DELETE")
+
+	// HR
+	hr := s.NewRoute().Subrouter()
+	hr.Use(middlewares.RequireRole("HR"))
+	hr.HandleFunc("/cycles", c.SaveCycle).Methods("POST")
+	hr.HandleFunc("/cycles/{id:[0-9]+}", c.SaveCycle).Methods("PUT")
+	hr.HandleFunc("/cycles/{id:[0-9]+}", c.DeleteCycle).Methods("DELETE")
+	hr.HandleFunc("/cycles/{id:[0-9]+}/open", c.OpenCycle).Methods("POST")
+	hr.HandleFunc("/cycles/{id:[0-9]+}/close", c.CloseCycle).Methods("POST")
+	hr.HandleFunc("/cycles/{id:[0-9]+}/budgets/{department:[0-9]+}", c.SetBudget).Methods("PUT")
+	hr.HandleFunc("/cycles/{id:[0-9]+}/budgets/{department:[0-9]+}", c.DeleteBudget).Methods("DELETE")
+	hr.HandleFunc("/proposals", c.ListProposals).Methods("GET")
+	hr.HandleFunc("/proposals/{id:[0-9]+}/approve", c.ApproveProposal).Methods("POST")
+	hr.HandleFunc("/proposals/{id:[0-9]+}/reject", c.RejectProposal).Methods("POST")
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerCustomFieldRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewCustomFieldController(db)
+	s := r.PathPrefix("/custom-fields").Subrouter()
+	s.Use(middlewares.JWTAuth)
+	s.HandleFunc("", c.List).Methods("GET")
+	// Definitions are managed by HR
+	hr := s.NewRoute().Subrouter()
+	hr.Use(middlewares.RequireRole("HR"))
+	hr.HandleFunc("", c.Save).Methods("POST")
+	hr.HandleFunc("/{id:[0-9]+}", c.Save).Methods("PUT")
+	hr.HandleFunc("/{id:[0-9]+}", c.Delete).Methods("DELETE")
+}
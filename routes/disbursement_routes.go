@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerDisbursementRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewDisbursementController(db)
+
+	p := r.PathPrefix("/payment-profiles").Subrouter()
+	p.Use(middlewares.JWTAuth, middlewares.RequirePermission(models.PermRunPayroll))
+	p.HandleFunc("", c.ListProfiles).Methods("GET")
+	p.HandleFunc("", c.SaveProfile).Methods("POST")
+	p.HandleFunc("/{id:[0-9]+}", c.GetProfile).Methods("GET")
+	p.HandleFunc("/{id:[0-9]+}", c.SaveProfile).Methods("PUT")
+	p.HandleFunc("/{id:[0-9]+}", c.DeleteProfile).Methods("DELETE")
+
+	f := r.PathPrefix("/payment-files").Subrouter()
+	f.Use(middlewares.JWTAuth, middlewares.RequirePermission(models.PermRunPayroll))
+	f.HandleFunc("/{id:[0-9]+}", c.VoidFile).Methods("DELETE")
+	f.HandleFunc("/{id:[0-9]+}/download", c.DownloadFile).Methods("GET")
+	f.HandleFunc("/{id:[0-9]+}/paid", c.MarkFilePaid).Methods("POST")
+
+	// Employees follow their own payments; payroll records what the bank reports
+	d := r.PathPrefix("/disbursements").Subrouter()
+	d.Use(middlewares.JWTAuth)
+	d.HandleFunc("/me", c.ListMine).Methods("GET")
+	pr := d.NewRoute().Subrouter()
+	pr.Use(middlewares.RequirePermission(models.PermRunPayroll))
+	pr.HandleFunc("/{id:[0-9]+}/status", c.SetStatus).Methods("PUT")
+
+	run := r.NewRoute().Subrouter()
+	run.Use(middlewares.JWTAuth, middlewares.RequirePermission(models.PermRunPayroll))
+	run.HandleFunc("/payroll/runs/{id:[0-9]+}/disbursements", c.ListRun).Methods("GET")
+	run.HandleFunc("/payroll/runs/{id:[0-9]+}/payment-files", c.ListFiles).Methods("GET")
+	run.HandleFunc("/payroll/runs/{id:[0-9]+}/payment-files", c.GenerateFile).Methods("POST")
+}
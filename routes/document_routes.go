@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerDocumentRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewDocumentController(db)
+
+	// Categories: readable by everyone, managed by HR
+	cat := r.PathPrefix("/document-categories").Subrouter()
+	cat.Use(middlewares.JWTAuth)
+	cat.HandleFunc("", c.ListCategories).Methods("GET")
+	hrCat := cat.NewRoute().Subrouter()
+	hrCat.Use(middlewares.RequireRole("HR"))
+	hrCat.HandleFunc("", c.SaveCategory).Methods("POST")
+	hrCat.HandleFunc("/{id:[0-9]+}", c.SaveCategory).Methods("PUT")
+	hrCat.HandleFunc("/{id:[0-9]+}", c.DeleteCategory).Methods("DELETE")
+
+	// Documents; access is checked per category in the service
+	s := r.NewRoute().Subrouter()
+	s.Use(middlewares.JWTAuth)
+	s.HandleFunc("/employees/me/documents", c.ListMine).Methods("GET")
+	s.HandleFunc("/employees/me/documents", c.UploadMine).Methods("POST")
+	s.HandleFunc("/employees/{id:[0-9]+}/documents", c.ListForEmployee).Methods("GET")
+	s.HandleFunc("/employees/{id:[0-9]+}/documents", c.UploadForEmployee).Methods("POST")
+	s.HandleFunc("/documents/{id:[0-9]+}", c.Get).Methods("GET")
+	s.HandleFunc("/documents/{id:[0-9]+}", c.Delete).Methods("DELETE")
+	s.HandleFunc("/documents/{id:[0-9]+}/versions", c.AddVersion).Methods("POST")
+	s.HandleFunc("/documents/{id:[0-9]+}/download", c.Download).Methods("GET")
+}
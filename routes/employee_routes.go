@@ -5,21 +5,46 @@ import (
     "gorm.io/gorm"
     "github.com/example/hrms-backend/controllers"
     "github.com/example/hrms-backend/middlewares"
+    "github.com/example/hrms-backend/models"
 )
 
 func registerEmployeeRoutes(r *mux.Router, db *gorm.DB) {
     c := controllers.NewEmployeeController(db)
+    checklists := controllers.NewChecklistController(db)
+    profiles := controllers.NewProfileController(db)
     s := r.PathPrefix("/employees").Subrouter()
     s.Use(middlewares.JWTAuth)
+    // Exports (export permission; salary needs salary access)
+    ex := s.NewRoute().Subrouter()
+    ex.Use(middlewares.RequirePermission(models.PermExport))
+    ex.HandleFunc("/export", controllers.NewExportController(db).Employees).Methods("GET")
     // HR routes
     hr := s.NewRoute().Subrouter()
     hr.Use(middlewares.RequireRole("HR"))
-    hr.HandleFunc("", c.List).Methods("GET")
     hr.HandleFunc("", c.Create).Methods("POST")
+    hr.HandleFunc("/import", c.Import).Methods("POST")
     hr.HandleFunc("/{id}", c.Update).Methods("PUT")
     hr.HandleFunc("/{id}", c.Delete).Methods("DELETE")
+    hr.HandleFunc("/{id:[0-9]+}/history", c.History).Methods("GET")
+    hr.HandleFunc("/{id:[0-9]+}/job-changes", c.RecordJobChange).Methods("POST")
+    hr.HandleFunc("/{id:[0-9]+}/terminate", c.Terminate).Methods("POST")
+    hr.HandleFunc("/{id:[0-9]+}/restore", c.Restore).Methods("POST")
+    hr.HandleFunc("/{id:[0-9]+}/rehire", c.Rehire).Methods("POST")
+    hr.HandleFunc("/{id:[0-9]+}/probation/extend", c.ExtendProbation).Methods("POST")
+    hr.HandleFunc("/{id:[0-9]+}/probation/confirm", c.ConfirmProbation).Methods("POST")
+    hr.HandleFunc("/{id:[0-9]+}/onboarding", checklists.StartOnboarding).Methods("POST")
+    hr.HandleFunc("/{id:[0-9]+}/offboarding", checklists.StartOffboarding).Methods("POST")
+    hr.HandleFunc("/{id:[0-9]+}/checklists", checklists.EmployeeChecklists).Methods("GET")
+    hr.HandleFunc("/{id:[0-9]+}/profile", profiles.GetEmployee).Methods("GET")
+    hr.HandleFunc("/{id:[0-9]+}/profile", profiles.UpdateEmployee).Methods("PATCH")
+    // Everyone; responses are redacted to what the caller may see
+    s.HandleFunc("", c.List).Methods("GET")
+    s.HandleFunc("/{id:[0-9]+}", c.Get).Methods("GET")
     // Employee self
     s.HandleFunc("/me", c.GetMe).Methods("GET")
+    s.HandleFunc("/me", profiles.UpdateMine).Methods("PATCH")
+    s.HandleFunc("/me/change-requests", profiles.MyChangeRequests).Methods("GET")
+    s.HandleFunc("/me/change-requests/{id:[0-9]+}/cancel", profiles.CancelMine).Methods("POST")
 }
 
 
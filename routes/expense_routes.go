@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerExpenseRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewExpenseController(db)
+	s := r.PathPrefix("/expenses").Subrouter()
+	s.Use(middlewares.JWTAuth)
+
+	// Employee self-service; the claim and its receipts are also visible to the approvers
+	s.HandleFunc("/categories", c.ListCategories).Methods("GET")
+	s.HandleFunc("/rates", c.ListRates).Methods("GET")
+	s.HandleFunc("/claims", c.CreateClaim).Methods("POST")
+	s.HandleFunc("/claims/me", c.ListMine).Methods("GET")
+	s.HandleFunc("/claims/{id:[0-9]+}", c.GetClaim).Methods("GET")
+	s.HandleFunc("/claims/{id:[0-9]+}", c.UpdateClaim).Methods("PUT")
+	s.HandleFunc("/claims/{id:[0-9]+}", c.DeleteClaim).Methods("DELETE")
+	s.HandleFunc("/claims/{id:[0-9]+}/submit", c.Submit).Methods("POST")
+	s.HandleFunc("/claims/{id:[0-9]+}/items/{item:[0-9]+}/receipts", c.UploadReceipt).Methods("POST")
+	s.HandleFunc("/claims/{id:[0-9]+}/receipts/{rid:[0-9]+}", c.DownloadReceipt).Methods("GET")
+	s.HandleFunc("/claims/{id:[0-9]+}/receipts/{rid:[0-9]+}", c.DeleteReceipt).Methods("DELETE")
+
+	// Managers decide their reports' claims (HR may stand in); the service checks who may
+	s.HandleFunc("/claims/team", c.ListTeam).Methods("GET")
+	s.HandleFunc("/claims/{id:[0-9]+}/manager-approve", c.ManagerApprove).Methods("POST")
+	s.HandleFunc("/claims/{id:[0-9]+}/manager-reject", c.ManagerReject).Methods("POST")
+
+	// Finance
+	pr := s.NewRoute().Subrouter()
+	pr.Use(middlewares.RequirePermission(models.PermRunPayroll))
+	pr.HandleFunc("/categories", c.SaveCategory).Methods("POST")
+	pr.HandleFunc("/categories/{id:[0-9]+}", c.SaveCategory).Methods("PUT")
+	pr.HandleFunc("/categories/{id:[0-9]+}", c.DeleteCategory).Methods("DELETE")
+	pr.HandleFunc("/rates", c.SaveRate).Methods("PUT")
+	pr.HandleFunc("/rates/{id:[0-9]+}", c.DeleteRate).Methods("DELETE")
+	pr.HandleFunc("/claims", c.ListClaims).Methods("GET")
+	pr.HandleFunc("/claims/{id:[0-9]+}/approve", c.FinanceApprove).Methods("POST")
+	pr.HandleFunc("/claims/{id:[0-9]+}/reject", c.FinanceReject).Methods("POST")
+	pr.HandleFunc("/claims/{id:[0-9]+}/paid", c.MarkPaid).Methods("POST")
+	pr.HandleFunc("/export", c.Export).Methods("GET")
+}
@@ -3,13 +3,32 @@ package routes
 import (
     "github.com/gorilla/mux"
     "gorm.io/gorm"
+    "github.com/example/hrms-backend/middlewares"
+    "github.com/example/hrms-backend/services"
 )
 
 func Register(r *mux.Router, db *gorm.DB) {
+    middlewares.TokenCheck = services.NewAuthService(db).CheckToken
+    services.NewVisibilityService(db).Register()
     registerAuthRoutes(r, db)
     registerEmployeeRoutes(r, db)
     registerAttendanceRoutes(r, db)
     registerLeaveRoutes(r, db)
+    registerOrgRoutes(r, db)
+    registerChecklistRoutes(r, db)
+    registerNotificationRoutes(r, db)
+    registerProfileRoutes(r, db)
+    registerDocumentRoutes(r, db)
+    registerCustomFieldRoutes(r, db)
+    registerPayrollRoutes(r, db)
+    registerSalaryStructureRoutes(r, db)
+    registerPayslipRoutes(r, db)
+    registerStatutoryRoutes(r, db)
+    registerDisbursementRoutes(r, db)
+    registerExpenseRoutes(r, db)
+    registerCompensationRoutes(r, db)
+    registerLoanRoutes(r, db)
+    registerSettlementRoutes(r, db)
 }
 
 
@@ -3,12 +3,14 @@ package routes
 import (
 	"github.com/example/hrms-backend/controllers"
 	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
 func registerLeaveRoutes(r *mux.Router, db *gorm.DB) {
 	c := controllers.NewLeaveController(db)
+	rules := controllers.NewLeaveRuleController(db)
 	s := r.PathPrefix("/leaves").Subrouter()
 	s.Use(middlewares.JWTAuth)
 
@@ -16,6 +18,22 @@ func registerLeaveRoutes(r *mux.Router, db *gorm.DB) {
 	s.HandleFunc("", c.Apply).Methods("POST")
 	s.HandleFunc("", c.ListMine).Methods("GET")
 	s.HandleFunc("/{id:[0-9]+}", c.DeleteMine).Methods("DELETE")
+	s.HandleFunc("/me/balances", c.MyBalances).Methods("GET")
+
+	// Attachments (leave owner or HR)
+	s.HandleFunc("/{id:[0-9]+}/attachments", c.ListAttachments).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/attachments", c.UploadAttachment).Methods("POST")
+	s.HandleFunc("/{id:[0-9]+}/attachments/{aid:[0-9]+}", c.DownloadAttachment).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/attachments/{aid:[0-9]+}", c.DeleteAttachment).Methods("DELETE")
+
+	// Discussion (leave owner or HR)
+	s.HandleFunc("/{id:[0-9]+}/comments", c.ListComments).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/comments", c.AddComment).Methods("POST")
+
+	// Exports
+	ex := s.NewRoute().Subrouter()
+	ex.Use(middlewares.RequirePermission(models.PermExport))
+	ex.HandleFunc("/export", controllers.NewExportController(db).Leaves).Methods("GET")
 
 	// HR
 	hr := s.NewRoute().Subrouter()
@@ -23,4 +41,17 @@ func registerLeaveRoutes(r *mux.Router, db *gorm.DB) {
 	hr.HandleFunc("", c.ListAll).Methods("GET")
 	hr.HandleFunc("/{id:[0-9]+}/approve", c.Approve).Methods("POST")
 	hr.HandleFunc("/{id:[0-9]+}/reject", c.Reject).Methods("POST")
+	hr.HandleFunc("/attachment-policies", c.ListAttachmentPolicies).Methods("GET")
+	hr.HandleFunc("/attachment-policies", c.SaveAttachmentPolicy).Methods("PUT")
+	hr.HandleFunc("/attachment-policies/{id:[0-9]+}", c.DeleteAttachmentPolicy).Methods("DELETE")
+	hr.HandleFunc("/entitlements", c.ListEntitlements).Methods("GET")
+	hr.HandleFunc("/entitlements", c.SaveEntitlement).Methods("PUT")
+	hr.HandleFunc("/entitlements/{id:[0-9]+}", c.DeleteEntitlement).Methods("DELETE")
+	hr.HandleFunc("/employees/{id:[0-9]+}/balances", c.EmployeeBalances).Methods("GET")
+	hr.HandleFunc("/blackouts", rules.ListBlackouts).Methods("GET")
+	hr.HandleFunc("/blackouts", rules.CreateBlackout).Methods("POST")
+	hr.HandleFunc("/blackouts/{id:[0-9]+}", rules.DeleteBlackout).Methods("DELETE")
+	hr.HandleFunc("/staffing-rules", rules.ListStaffingRules).Methods("GET")
+	hr.HandleFunc("/staffing-rules", rules.CreateStaffingRule).Methods("POST")
+	hr.HandleFunc("/staffing-rules/{id:[0-9]+}", rules.DeleteStaffingRule).Methods("DELETE")
 }
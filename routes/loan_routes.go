@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerLoanRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewLoanController(db)
+	s := r.PathPrefix("/loans").Subrouter()
+	s.Use(middlewares.JWTAuth)
+
+	// Employee self-service; a loan is also visible to payroll
+	s.HandleFunc("/schedule", c.Schedule).Methods("GET")
+	s.HandleFunc("", c.Request).Methods("POST")
+	s.HandleFunc("/me", c.ListMine).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}", c.Get).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/cancel", c.Cancel).Methods("POST")
+
+	// Finance
+	pr := s.NewRoute().Subrouter()
+	pr.Use(middlewares.RequirePermission(models.PermRunPayroll))
+	pr.HandleFunc("", c.List).Methods("GET")
+	pr.HandleFunc("/{id:[0-9]+}/approve", c.Approve).Methods("POST")
+	pr.HandleFunc("/{id:[0-9]+}/reject", c.Reject).Methods("POST")
+	pr.HandleFunc("/{id:[0-9]+}/disburse", c.Disburse).Methods("POST")
+	pr.HandleFunc("/{id:[0-9]+}/repayments", c.RecordPayment).Methods("POST")
+	pr.HandleFunc("/{id:[0-9]+}/payoff", c.Payoff).Methods("GET")
+}
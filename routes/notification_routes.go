@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerNotificationRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewNotificationController(db)
+	s := r.PathPrefix("/notifications").Subrouter()
+	s.Use(middlewares.JWTAuth)
+	s.HandleFunc("", c.List).Methods("GET")
+	s.HandleFunc("/read-all", c.MarkAllRead).Methods("POST")
+	s.HandleFunc("/{id:[0-9]+}/read", c.MarkRead).Methods("POST")
+}
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerOrgRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewOrgController(db)
+	s := r.NewRoute().Subrouter()
+	s.Use(middlewares.JWTAuth)
+
+	// Read access for every authenticated user
+	s.HandleFunc("/departments", c.ListDepartments).Methods("GET")
+	s.HandleFunc("/departments/{id:[0-9]+}", c.GetDepartment).Methods("GET")
+	s.HandleFunc("/locations", c.ListLocations).Methods("GET")
+	s.HandleFunc("/locations/{id:[0-9]+}", c.GetLocation).Methods("GET")
+	s.HandleFunc("/grades", c.ListGrades).Methods("GET")
+	s.HandleFunc("/grades/{id:[0-9]+}", c.GetGrade).Methods("GET")
+	s.HandleFunc("/positions", c.ListPositions).Methods("GET")
+	s.HandleFunc("/positions/{id:[0-9]+}", c.GetPosition).Methods("GET")
+
+	// HR
+	hr := s.NewRoute().Subrouter()
+	hr.Use(middlewares.RequireRole("HR"))
+	hr.HandleFunc("/departments", c.CreateDepartment).Methods("POST")
+	hr.HandleFunc("/departments/{id:[0-9]+}", c.UpdateDepartment).Methods("PUT")
+	hr.HandleFunc("/departments/{id:[0-9]+}", c.DeleteDepartment).Methods("DELETE")
+	hr.HandleFunc("/locations", c.SaveLocation).Methods("POST")
+	hr.HandleFunc("/locations/{id:[0-9]+}", c.SaveLocation).Methods("PUT")
+	hr.HandleFunc("/locations/{id:[0-9]+}", c.DeleteLocation).Methods("DELETE")
+	hr.HandleFunc("/grades", c.SaveGrade).Methods("POST")
+	hr.HandleFunc("/grades/{id:[0-9]+}", c.SaveGrade).Methods("PUT")
+	hr.HandleFunc("/grades/{id:[0-9]+}", c.DeleteGrade).Methods("DELETE")
+	hr.HandleFunc("/positions", c.SavePosition).Methods("POST")
+	hr.HandleFunc("/positions/{id:[0-9]+}", c.SavePosition).Methods("PUT")
+	hr.HandleFunc("/positions/{id:[0-9]+}", c.DeletePosition).Methods("DELETE")
+}
@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerPayrollRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewPayrollController(db)
+
+	s := r.PathPrefix("/payroll").Subrouter()
+	s.Use(middlewares.JWTAuth, middlewares.RequirePermission(models.PermRunPayroll))
+	s.HandleFunc("/periods", c.ListPeriods).Methods("GET")
+	s.HandleFunc("/periods", c.CreatePeriod).Methods("POST")
+	s.HandleFunc("/periods/{id:[0-9]+}", c.DeletePeriod).Methods("DELETE")
+	s.HandleFunc("/deductions", c.ListDeductions).Methods("GET")
+	s.HandleFunc("/deductions", c.SaveDeduction).Methods("POST")
+	s.HandleFunc("/deductions/{id:[0-9]+}", c.SaveDeduction).Methods("PUT")
+	s.HandleFunc("/deductions/{id:[0-9]+}", c.DeleteDeduction).Methods("DELETE")
+	s.HandleFunc("/runs", c.ListRuns).Methods("GET")
+	s.HandleFunc("/runs", c.CreateRun).Methods("POST")
+	s.HandleFunc("/runs/{id:[0-9]+}", c.GetRun).Methods("GET")
+	s.HandleFunc("/runs/{id:[0-9]+}", c.DeleteRun).Methods("DELETE")
+	s.HandleFunc("/runs/{id:[0-9]+}/recalculate", c.RecalculateRun).Methods("POST")
+	s.HandleFunc("/runs/{id:[0-9]+}/review", c.Review).Methods("POST")
+	s.HandleFunc("/runs/{id:[0-9]+}/reopen", c.Reopen).Methods("POST")
+	s.HandleFunc("/runs/{id:[0-9]+}/finalize", c.Finalize).Methods("POST")
+	s.HandleFunc("/runs/{id:[0-9]+}/pay", c.MarkPaid).Methods("POST")
+
+	// Overtime: employees submit their own, HR decides
+	ot := r.PathPrefix("/overtime").Subrouter()
+	ot.Use(middlewares.JWTAuth)
+	ot.HandleFunc("", c.SubmitOvertime).Methods("POST")
+	ot.HandleFunc("/me", c.MyOvertime).Methods("GET")
+	ot.HandleFunc("/me/{id:[0-9]+}", c.DeleteMyOvertime).Methods("DELETE")
+	hr := ot.NewRoute().Subrouter()
+	hr.Use(middlewares.RequireRole("HR"))
+	hr.HandleFunc("", c.ListOvertime).Methods("GET")
+	hr.HandleFunc("/{id:[0-9]+}/approve", c.ApproveOvertime).Methods("POST")
+	hr.HandleFunc("/{id:[0-9]+}/reject", c.RejectOvertime).Methods("POST")
+}
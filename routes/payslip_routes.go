@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerPayslipRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewPayslipController(db)
+
+	// Employees download their own payslips; payroll staff may download any
+	s := r.PathPrefix("/payslips").Subrouter()
+	s.Use(middlewares.JWTAuth)
+	s.HandleFunc("", c.ListMine).Methods("GET")
+	s.HandleFunc("/password", c.SetPassword).Methods("PUT")
+	s.HandleFunc("/{id:[0-9]+}/download", c.Download).Methods("GET")
+
+	t := r.PathPrefix("/payslip-templates").Subrouter()
+	t.Use(middlewares.JWTAuth, middlewares.RequirePermission(models.PermRunPayroll))
+	t.HandleFunc("", c.ListTemplates).Methods("GET")
+	t.HandleFunc("", c.SaveTemplate).Methods("POST")
+	t.HandleFunc("/{id:[0-9]+}", c.GetTemplate).Methods("GET")
+	t.HandleFunc("/{id:[0-9]+}", c.SaveTemplate).Methods("PUT")
+	t.HandleFunc("/{id:[0-9]+}", c.DeleteTemplate).Methods("DELETE")
+	t.HandleFunc("/{id:[0-9]+}/logo", c.SetLogo).Methods("PUT")
+
+	run := r.NewRoute().Subrouter()
+	run.Use(middlewares.JWTAuth, middlewares.RequirePermission(models.PermRunPayroll))
+	run.HandleFunc("/payroll/runs/{id:[0-9]+}/payslips", c.ListRun).Methods("GET")
+	run.HandleFunc("/payroll/runs/{id:[0-9]+}/payslips", c.GenerateRun).Methods("POST")
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerProfileRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewProfileController(db)
+	s := r.PathPrefix("/profile-changes").Subrouter()
+	s.Use(middlewares.JWTAuth, middlewares.RequireRole("HR"))
+	s.HandleFunc("", c.ListChangeRequests).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/approve", c.Approve).Methods("POST")
+	s.HandleFunc("/{id:[0-9]+}/reject", c.Reject).Methods("POST")
+}
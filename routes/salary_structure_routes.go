@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerSalaryStructureRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewSalaryStructureController(db)
+
+	s := r.PathPrefix("/salary-structures").Subrouter()
+	s.Use(middlewares.JWTAuth, middlewares.RequirePermission(models.PermRunPayroll))
+	s.HandleFunc("", c.List).Methods("GET")
+	s.HandleFunc("", c.Save).Methods("POST")
+	s.HandleFunc("/{id:[0-9]+}", c.Get).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}", c.Save).Methods("PUT")
+	s.HandleFunc("/{id:[0-9]+}", c.Delete).Methods("DELETE")
+	s.HandleFunc("/{id:[0-9]+}/preview", c.Preview).Methods("GET")
+
+	me := r.NewRoute().Subrouter()
+	me.Use(middlewares.JWTAuth)
+	me.HandleFunc("/employees/me/salary-breakdown", c.MyBreakdown).Methods("GET")
+
+	view := r.NewRoute().Subrouter()
+	view.Use(middlewares.JWTAuth, middlewares.RequirePermission(models.PermViewSalary))
+	view.HandleFunc("/employees/{id:[0-9]+}/salary-structures", c.ListAssignments).Methods("GET")
+	view.HandleFunc("/employees/{id:[0-9]+}/salary-breakdown", c.Breakdown).Methods("GET")
+
+	run := r.NewRoute().Subrouter()
+	run.Use(middlewares.JWTAuth, middlewares.RequirePermission(models.PermRunPayroll))
+	run.HandleFunc("/employees/{id:[0-9]+}/salary-structures", c.Assign).Methods("POST")
+	run.HandleFunc("/employees/{id:[0-9]+}/salary-structures/{aid:[0-9]+}", c.DeleteAssignment).Methods("DELETE")
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerSettlementRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewSettlementController(db)
+	s := r.PathPrefix("/settlements").Subrouter()
+	s.Use(middlewares.JWTAuth)
+	s.Use(middlewares.RequireRole("HR"))
+
+	s.HandleFunc("", c.Calculate).Methods("POST")
+	s.HandleFunc("", c.List).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}", c.Get).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}", c.Delete).Methods("DELETE")
+	s.HandleFunc("/{id:[0-9]+}/recalculate", c.Recalculate).Methods("POST")
+	s.HandleFunc("/{id:[0-9]+}/adjustments", c.SetAdjustments).Methods("PUT")
+	s.HandleFunc("/{id:[0-9]+}/finalize", c.Finalize).Methods("POST")
+	s.HandleFunc("/{id:[0-9]+}/statement", c.Statement).Methods("GET")
+}
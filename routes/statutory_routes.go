@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/example/hrms-backend/controllers"
+	"github.com/example/hrms-backend/middlewares"
+	"github.com/example/hrms-backend/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func registerStatutoryRoutes(r *mux.Router, db *gorm.DB) {
+	c := controllers.NewStatutoryController(db)
+
+	s := r.PathPrefix("/statutory").Subrouter()
+	s.Use(middlewares.JWTAuth, middlewares.RequirePermission(models.PermRunPayroll))
+	s.HandleFunc("/engines", c.ListEngines).Methods("GET")
+	s.HandleFunc("/rule-sets", c.ListRuleSets).Methods("GET")
+	s.HandleFunc("/rule-sets", c.SaveRuleSet).Methods("POST")
+	s.HandleFunc("/rule-sets/{id:[0-9]+}", c.GetRuleSet).Methods("GET")
+	s.HandleFunc("/rule-sets/{id:[0-9]+}", c.SaveRuleSet).Methods("PUT")
+	s.HandleFunc("/rule-sets/{id:[0-9]+}", c.DeleteRuleSet).Methods("DELETE")
+	s.HandleFunc("/rule-sets/{id:[0-9]+}/preview", c.Preview).Methods("POST")
+
+	// Tax declarations: employees submit their own, payroll decides
+	d := r.PathPrefix("/tax-declarations").Subrouter()
+	d.Use(middlewares.JWTAuth)
+	d.HandleFunc("", c.SubmitDeclaration).Methods("POST")
+	d.HandleFunc("/me", c.MyDeclarations).Methods("GET")
+	d.HandleFunc("/me/{id:[0-9]+}", c.DeleteMyDeclaration).Methods("DELETE")
+	pr := d.NewRoute().Subrouter()
+	pr.Use(middlewares.RequirePermission(models.PermRunPayroll))
+	pr.HandleFunc("", c.ListDeclarations).Methods("GET")
+	pr.HandleFunc("/{id:[0-9]+}/approve", c.ApproveDeclaration).Methods("POST")
+	pr.HandleFunc("/{id:[0-9]+}/reject", c.RejectDeclaration).Methods("POST")
+}
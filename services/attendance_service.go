@@ -21,6 +21,7 @@ func (s *AttendanceService) Add(employeeID uint, date time.Time, status models.A
     s.mu.Lock()
     defer s.mu.Unlock()
     return s.db.Transaction(func(tx *gorm.DB) error {
+        if err := guardPayroll(tx, date, date); err != nil { return err }
         var existing models.Attendance
         err := tx.Where("employee_id = ? AND date = ?", employeeID, date.Format("2006-01-02")).First(&existing).Error
         if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -38,7 +39,14 @@ func (s *AttendanceService) Add(employeeID uint, date time.Time, status models.A
 }
 
 func (s *AttendanceService) Delete(employeeID, id uint) error {
-    return s.db.Where("id = ? AND employee_id = ?", id, employeeID).Delete(&models.Attendance{}).Error
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        var a models.Attendance
+        err := tx.Where("id = ? AND employee_id = ?", id, employeeID).First(&a).Error
+        if errors.Is(err, gorm.ErrRecordNotFound) { return nil }
+        if err != nil { return err }
+        if err := guardPayroll(tx, a.Date, a.Date); err != nil { return err }
+        return tx.Delete(&a).Error
+    })
 }
 
 func (s *AttendanceService) ListByEmployee(employeeID uint) ([]models.Attendance, error) {
@@ -47,14 +55,35 @@ func (s *AttendanceService) ListByEmployee(employeeID uint) ([]models.Attendance
     return list, nil
 }
 
-func (s *AttendanceService) ListAll() ([]models.Attendance, error) {
+// AttendanceFilter narrows HR listings and exports; zero values match everything.
+type AttendanceFilter struct {
+    EmployeeIDs []uint
+    Statuses    []models.AttendanceStatus
+    From        *time.Time
+    To          *time.Time
+}
+
+func (f AttendanceFilter) apply(tx *gorm.DB) *gorm.DB {
+    if len(f.EmployeeIDs) > 0 { tx = tx.Where("attendances.employee_id IN ?", f.EmployeeIDs) }
+    if len(f.Statuses) > 0 { tx = tx.Where("attendances.status IN ?", f.Statuses) }
+    if f.From != nil { tx = tx.Where("attendances.date >= ?", f.From.Format("2006-01-02")) }
+    if f.To != nil { tx = tx.Where("attendances.date <= ?", f.To.Format("2006-01-02")) }
+    return tx
+}
+
+func (s *AttendanceService) ListAll(f AttendanceFilter) ([]models.Attendance, error) {
     var list []models.Attendance
-    if err := s.db.Order("date desc").Find(&list).Error; err != nil { return nil, err }
+    if err := f.apply(s.db.Model(&models.Attendance{})).Order("date desc").Find(&list).Error; err != nil { return nil, err }
     return list, nil
 }
 
 func (s *AttendanceService) UpdateAny(id uint, status models.AttendanceStatus) error {
-    return s.db.Model(&models.Attendance{ID: id}).Update("status", status).Error
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        var a models.Attendance
+        if err := tx.First(&a, id).Error; err != nil { return err }
+        if err := guardPayroll(tx, a.Date, a.Date); err != nil { return err }
+        return tx.Model(&a).Update("status", status).Error
+    })
 }
 
 
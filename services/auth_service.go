@@ -12,6 +12,11 @@ import (
     "github.com/example/hrms-backend/models"
 )
 
+var (
+    ErrAccountDisabled = errors.New("account disabled")
+    ErrTokenRevoked    = errors.New("token revoked")
+)
+
 type AuthService struct {
     db *gorm.DB
 }
@@ -31,6 +36,7 @@ func (s *AuthService) GenerateAccessToken(user *models.User) (string, error) {
     claims := jwt.MapClaims{
         "sub":  user.ID,
         "role": string(user.Role),
+        "tv":   user.TokenVersion,
         "exp":  time.Now().Add(15 * time.Minute).Unix(),
     }
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -40,27 +46,49 @@ func (s *AuthService) GenerateAccessToken(user *models.User) (string, error) {
 func (s *AuthService) GenerateRefreshToken(user *models.User) (string, error) {
     claims := jwt.MapClaims{
         "sub": user.ID,
+        "tv":  user.TokenVersion,
         "exp": time.Now().Add(7 * 24 * time.Hour).Unix(),
     }
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
     return token.SignedString([]byte(os.Getenv("JWT_REFRESH_SECRET")))
 }
 
-func (s *AuthService) ParseRefresh(tokenStr string) (uint, error) {
+// ParseRefresh returns the user id and token version of a valid refresh token.
+func (s *AuthService) ParseRefresh(tokenStr string) (uint, uint, error) {
     token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
         return []byte(os.Getenv("JWT_REFRESH_SECRET")), nil
     })
     if err != nil || !token.Valid {
-        return 0, errors.New("invalid refresh token")
+        return 0, 0, errors.New("invalid refresh token")
     }
     claims, ok := token.Claims.(jwt.MapClaims)
     if !ok {
-        return 0, errors.New("invalid claims")
+        return 0, 0, errors.New("invalid claims")
     }
+    tv, _ := claims["tv"].(float64)
     if sub, ok := claims["sub"].(float64); ok {
-        return uint(sub), nil
+        return uint(sub), uint(tv), nil
     }
-    return 0, errors.New("invalid subject")
+    return 0, 0, errors.New("invalid subject")
+}
+
+// CheckToken verifies that the user behind a token may still use it: the account is enabled and
+// the token was issued after the last revocation.
+func (s *AuthService) CheckToken(userID, tokenVersion uint) error {
+    var u models.User
+    if err := s.db.Select("id", "disabled", "token_version").First(&u, userID).Error; err != nil { return err }
+    if u.Disabled { return ErrAccountDisabled }
+    if u.TokenVersion != tokenVersion { return ErrTokenRevoked }
+    return nil
+}
+
+// DisableUser blocks the user's logins and revokes all tokens already issued to them.
+func (s *AuthService) DisableUser(tx *gorm.DB, userID uint) error {
+    return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+        "disabled":      true,
+        "disabled_at":   time.Now(),
+        "token_version": gorm.Expr("token_version + 1"),
+    }).Error
 }
 
 
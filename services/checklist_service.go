@@ -0,0 +1,592 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+)
+
+var (
+	ErrChecklistClosed     = errors.New("checklist is not open")
+	ErrChecklistExists     = errors.New("employee already has an open checklist of this kind")
+	ErrNoChecklistTemplate = errors.New("no checklist template for this kind")
+	ErrTaskForbidden       = errors.New("task is assigned to someone else")
+)
+
+// ChecklistService manages checklist templates and the checklists started from them.
+type ChecklistService struct {
+	db        *gorm.DB
+	employees *EmployeeService
+	auth      *AuthService
+	notify    *NotificationService
+}
+
+func NewChecklistService(db *gorm.DB) *ChecklistService {
+	return &ChecklistService{db: db, employees: NewEmployeeService(db), auth: NewAuthService(db), notify: NewNotificationService(db)}
+}
+
+func validChecklistKind(k models.ChecklistKind) bool {
+	return k == models.ChecklistOnboarding || k == models.ChecklistOffboarding
+}
+
+func validTaskCategory(c models.TaskCategory) bool {
+	switch c {
+	case models.TaskAssetReturn, models.TaskAccessRevocation, models.TaskFinalSettlement,
+		models.TaskExitInterview, models.TaskAccountSetup, models.TaskEquipment, models.TaskPaperwork,
+		models.TaskOrientation, models.TaskTraining, models.TaskOther:
+		return true
+	}
+	return false
+}
+
+func validTaskOwner(o models.TaskOwner) bool {
+	switch o {
+	case models.OwnerHR, models.OwnerIT, models.OwnerEmployee, models.OwnerManager, models.OwnerUser:
+		return true
+	}
+	return false
+}
+
+// teamOwners maps the owners whose unassigned tasks are worked by everyone with a role.
+var teamOwners = map[models.TaskOwner]models.UserRole{
+	models.OwnerHR: models.RoleHR,
+	models.OwnerIT: models.RoleIT,
+}
+
+// CanWorkTask reports whether a user may work on a task: HR may work on any task, other users
+// on tasks assigned to them and on their team's unassigned tasks.
+func CanWorkTask(t models.ChecklistTask, userID uint, role models.UserRole) bool {
+	if role == models.RoleHR {
+		return true
+	}
+	if t.AssigneeUserID != nil {
+		return *t.AssigneeUserID == userID
+	}
+	team, ok := teamOwners[t.Owner]
+	return ok && team == role
+}
+
+// Templates
+
+func (s *ChecklistService) ListTemplates(kind models.ChecklistKind) ([]models.ChecklistTemplate, error) {
+	tx := s.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") })
+	if kind != "" {
+		tx = tx.Where("kind = ?", kind)
+	}
+	var list []models.ChecklistTemplate
+	if err := tx.Order("kind, name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *ChecklistService) GetTemplate(id uint) (*models.ChecklistTemplate, error) {
+	var t models.ChecklistTemplate
+	if err := s.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		First(&t, id).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SaveTemplate creates the template (ID 0) or replaces an existing one including all its items.
+// Marking a template as default clears the flag on the other templates of its kind and scope.
+func (s *ChecklistService) SaveTemplate(t *models.ChecklistTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if !validChecklistKind(t.Kind) {
+		return fmt.Errorf("invalid kind %q", t.Kind)
+	}
+	if len(t.Items) == 0 {
+		return errors.New("a template needs at least one item")
+	}
+	if t.DepartmentID != nil {
+		if err := s.db.First(&models.Department{}, *t.DepartmentID).Error; err != nil {
+			return errors.New("department not found")
+		}
+	}
+	if t.PositionID != nil {
+		if err := s.db.First(&models.Position{}, *t.PositionID).Error; err != nil {
+			return errors.New("position not found")
+		}
+	}
+	for i := range t.Items {
+		it := &t.Items[i]
+		it.ID, it.TemplateID = 0, 0
+		if it.Position == 0 {
+			it.Position = i + 1
+		}
+		if it.Category == "" {
+			it.Category = models.TaskOther
+		}
+		if it.Owner == "" {
+			it.Owner = models.OwnerHR
+		}
+		if strings.TrimSpace(it.Title) == "" {
+			return fmt.Errorf("item %d: title is required", i+1)
+		}
+		if !validTaskCategory(it.Category) {
+			return fmt.Errorf("item %d: invalid category %q", i+1, it.Category)
+		}
+		if !validTaskOwner(it.Owner) {
+			return fmt.Errorf("item %d: invalid owner %q", i+1, it.Owner)
+		}
+		if it.Owner == models.OwnerUser {
+			if it.OwnerUserID == nil {
+				return fmt.Errorf("item %d: owner_user_id is required for owner USER", i+1)
+			}
+			if err := s.db.First(&models.User{}, *it.OwnerUserID).Error; err != nil {
+				return fmt.Errorf("item %d: owner user not found", i+1)
+			}
+		} else {
+			it.OwnerUserID = nil
+		}
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if t.ID != 0 {
+			var cur models.ChecklistTemplate
+			if err := tx.First(&cur, t.ID).Error; err != nil {
+				return err
+			}
+			t.CreatedAt = cur.CreatedAt
+			if err := tx.Where("template_id = ?", t.ID).Delete(&models.ChecklistTemplateItem{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit("Items").Save(t).Error; err != nil {
+			return err
+		}
+		for i := range t.Items {
+			t.Items[i].TemplateID = t.ID
+		}
+		if err := tx.Create(&t.Items).Error; err != nil {
+			return err
+		}
+		if t.IsDefault {
+			return tx.Model(&models.ChecklistTemplate{}).
+				Where("kind = ? AND id <> ? AND department_id IS NOT DISTINCT FROM ? AND position_id IS NOT DISTINCT FROM ?",
+					t.Kind, t.ID, t.DepartmentID, t.PositionID).
+				Update("is_default", false).Error
+		}
+		return nil
+	})
+}
+
+// DeleteTemplate removes a template; checklists already started from it keep their tasks.
+func (s *ChecklistService) DeleteTemplate(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.ChecklistTemplate{}, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Checklist{}).Where("template_id = ?", id).Update("template_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ChecklistTemplate{}, id).Error
+	})
+}
+
+// Checklists
+
+// StartChecklist describes a checklist to start. ReferenceDate is the start date of an onboarding
+// (default today) or the last working day of an offboarding. Without TemplateID the most specific
+// template of the kind matching the employee's position and department is used; see templateFor.
+type StartChecklist struct {
+	Kind          models.ChecklistKind
+	EmployeeID    uint
+	TemplateID    *uint
+	ReferenceDate time.Time
+	Reason        string
+	StartedBy     uint
+}
+
+// Start copies the template's items into tasks for the employee, resolving each owner to a user
+// and each due offset to a date.
+func (s *ChecklistService) Start(req StartChecklist) (*models.Checklist, error) {
+	if !validChecklistKind(req.Kind) {
+		return nil, fmt.Errorf("invalid kind %q", req.Kind)
+	}
+	if req.ReferenceDate.IsZero() {
+		if req.Kind != models.ChecklistOnboarding {
+			return nil, errors.New("reference date is required")
+		}
+		req.ReferenceDate = today()
+	}
+	emp, err := s.employees.Get(req.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	if emp.Status == models.EmploymentTerminated {
+		return nil, ErrEmployeeTerminated
+	}
+	tmpl, err := s.templateFor(req.Kind, req.TemplateID, emp)
+	if err != nil {
+		return nil, err
+	}
+	var itUsers int64
+	if err := s.db.Model(&models.User{}).Where("role = ? AND NOT disabled", models.RoleIT).Count(&itUsers).Error; err != nil {
+		return nil, err
+	}
+	var managerUser *uint
+	if emp.ManagerID != nil {
+		if mgr, err := s.employees.Get(*emp.ManagerID); err == nil && mgr.Status != models.EmploymentTerminated {
+			managerUser = &mgr.UserID
+		}
+	}
+
+	cl := models.Checklist{
+		Kind:          req.Kind,
+		EmployeeID:    emp.ID,
+		TemplateID:    &tmpl.ID,
+		Status:        models.ChecklistOpen,
+		ReferenceDate: req.ReferenceDate,
+		Reason:        strings.TrimSpace(req.Reason),
+		StartedBy:     req.StartedBy,
+	}
+	for _, it := range tmpl.Items {
+		task := models.ChecklistTask{
+			Position: it.Position,
+			Title:    it.Title,
+			Category: it.Category,
+			Owner:    it.Owner,
+			DueDate:  req.ReferenceDate.AddDate(0, 0, it.DueOffsetDays),
+			Status:   models.TaskPending,
+		}
+		switch it.Owner {
+		case models.OwnerIT:
+			// without anyone in IT the task falls back to HR
+			if itUsers == 0 {
+				task.Owner = models.OwnerHR
+			}
+		case models.OwnerEmployee:
+			task.AssigneeUserID = &emp.UserID
+		case models.OwnerManager:
+			// without an active manager the task falls back to HR
+			if task.AssigneeUserID = managerUser; managerUser == nil {
+				task.Owner = models.OwnerHR
+			}
+		case models.OwnerUser:
+			task.AssigneeUserID = it.OwnerUserID
+		}
+		cl.Tasks = append(cl.Tasks, task)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var open int64
+		if err := tx.Model(&models.Checklist{}).
+			Where("employee_id = ? AND kind = ? AND status = ?", emp.ID, req.Kind, models.ChecklistOpen).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrChecklistExists
+		}
+		if err := tx.Create(&cl).Error; err != nil {
+			return err
+		}
+		return s.notifyAssignees(tx, &cl, emp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cl, nil
+}
+
+// notifyAssignees tells each task's assignee, or the owning team for unassigned tasks, about the
+// new checklist. Everyone gets one notification however many tasks they have.
+func (s *ChecklistService) notifyAssignees(tx *gorm.DB, cl *models.Checklist, emp *models.Employee) error {
+	id := cl.ID
+	n := models.Notification{
+		Kind:       "CHECKLIST_TASKS",
+		Title:      fmt.Sprintf("New %s tasks for %s", strings.ToLower(string(cl.Kind)), emp.Name),
+		Body:       fmt.Sprintf("You have tasks on the %s checklist of %s.", strings.ToLower(string(cl.Kind)), emp.Name),
+		EntityType: "checklist",
+		EntityID:   &id,
+	}
+	users := map[uint]bool{}
+	teams := map[models.UserRole]bool{}
+	for _, t := range cl.Tasks {
+		if t.AssigneeUserID != nil {
+			users[*t.AssigneeUserID] = true
+		} else if role, ok := teamOwners[t.Owner]; ok {
+			teams[role] = true
+		}
+	}
+	for uid := range users {
+		if err := s.notify.Notify(tx, forUser(n, uid)); err != nil {
+			return err
+		}
+	}
+	for role := range teams {
+		if err := s.notify.NotifyRole(tx, role, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateFor returns the template with the given id, or else the best template of the kind for
+// the employee: templates scoped to another department or position are skipped, a position match
+// beats a department match, which beats an unscoped template; ties go to the default template,
+// then to the newest.
+func (s *ChecklistService) templateFor(kind models.ChecklistKind, id *uint, emp *models.Employee) (*models.ChecklistTemplate, error) {
+	if id != nil {
+		t, err := s.GetTemplate(*id)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind != kind {
+			return nil, fmt.Errorf("template %d is not a %s template", t.ID, kind)
+		}
+		return t, nil
+	}
+	var t models.ChecklistTemplate
+	err := s.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Where("kind = ?", kind).
+		Where("department_id IS NULL OR department_id = ?", emp.DepartmentID).
+		Where("position_id IS NULL OR position_id = ?", emp.PositionID).
+		Order("position_id IS NOT NULL DESC, department_id IS NOT NULL DESC, is_default DESC, created_at DESC").
+		First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoChecklistTemplate
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// StartOnboarding starts the onboarding checklist of a new hire, starting today, from the best
+// matching template. Having no onboarding template is not an error; nil is returned then.
+func (s *ChecklistService) StartOnboarding(emp *models.Employee, by uint) (*models.Checklist, error) {
+	cl, err := s.Start(StartChecklist{
+		Kind:       models.ChecklistOnboarding,
+		EmployeeID: emp.ID,
+		Reason:     "new hire",
+		StartedBy:  by,
+	})
+	if errors.Is(err, ErrNoChecklistTemplate) {
+		return nil, nil
+	}
+	return cl, err
+}
+
+func withTasks(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tasks", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") })
+}
+
+func (s *ChecklistService) Get(id uint) (*models.Checklist, error) {
+	var cl models.Checklist
+	if err := withTasks(s.db).First(&cl, id).Error; err != nil {
+		return nil, err
+	}
+	return &cl, nil
+}
+
+// ChecklistFilter narrows checklist listings; zero values match everything.
+type ChecklistFilter struct {
+	Kind       models.ChecklistKind
+	Status     models.ChecklistStatus
+	EmployeeID uint
+}
+
+func (s *ChecklistService) List(f ChecklistFilter) ([]models.Checklist, error) {
+	tx := withTasks(s.db)
+	if f.Kind != "" {
+		tx = tx.Where("kind = ?", f.Kind)
+	}
+	if f.Status != "" {
+		tx = tx.Where("status = ?", f.Status)
+	}
+	if f.EmployeeID != 0 {
+		tx = tx.Where("employee_id = ?", f.EmployeeID)
+	}
+	var list []models.Checklist
+	if err := tx.Order("reference_date, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// OpenTasks lists the unfinished tasks of open checklists assigned to the user, plus the
+// unassigned tasks of the user's team (HR or IT).
+func (s *ChecklistService) OpenTasks(userID uint, role models.UserRole) ([]models.ChecklistTask, error) {
+	tx := s.db.Select("checklist_tasks.*").Joins("JOIN checklists ON checklists.id = checklist_tasks.checklist_id").
+		Where("checklists.status = ? AND checklist_tasks.status IN ?", models.ChecklistOpen,
+			[]models.TaskStatus{models.TaskPending, models.TaskInProgress})
+	var owners []models.TaskOwner
+	for owner, team := range teamOwners {
+		if team == role {
+			owners = append(owners, owner)
+		}
+	}
+	if len(owners) > 0 {
+		tx = tx.Where("(checklist_tasks.assignee_user_id = ? OR (checklist_tasks.assignee_user_id IS NULL AND checklist_tasks.owner IN ?))",
+			userID, owners)
+	} else {
+		tx = tx.Where("checklist_tasks.assignee_user_id = ?", userID)
+	}
+	var list []models.ChecklistTask
+	if err := tx.Order("checklist_tasks.due_date, checklist_tasks.id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// TaskUpdate lists the task fields to change; nil fields are left alone. Only HR may reassign a
+// task or move its due date.
+type TaskUpdate struct {
+	Status         *models.TaskStatus
+	Note           *string
+	AssigneeUserID *uint
+	DueDate        *time.Time
+}
+
+// UpdateTask changes a task of an open checklist. Non-HR callers may only update tasks they can
+// work on (see CanWorkTask). The checklist completes once every task is done or skipped.
+func (s *ChecklistService) UpdateTask(checklistID, taskID uint, u TaskUpdate, by uint, role models.UserRole) (*models.ChecklistTask, error) {
+	var task models.ChecklistTask
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var cl models.Checklist
+		if err := tx.First(&cl, checklistID).Error; err != nil {
+			return err
+		}
+		if cl.Status != models.ChecklistOpen {
+			return ErrChecklistClosed
+		}
+		if err := tx.Where("checklist_id = ?", checklistID).First(&task, taskID).Error; err != nil {
+			return err
+		}
+		if role != models.RoleHR {
+			if !CanWorkTask(task, by, role) {
+				return ErrTaskForbidden
+			}
+			if u.AssigneeUserID != nil || u.DueDate != nil {
+				return errors.New("only HR can reassign tasks or change due dates")
+			}
+		}
+		changes := map[string]interface{}{}
+		if u.Status != nil {
+			switch *u.Status {
+			case models.TaskDone, models.TaskSkipped:
+				now := time.Now()
+				changes["completed_by"], changes["completed_at"] = by, now
+			case models.TaskPending, models.TaskInProgress:
+				changes["completed_by"], changes["completed_at"] = nil, nil
+			default:
+				return fmt.Errorf("invalid status %q", *u.Status)
+			}
+			changes["status"] = *u.Status
+		}
+		if u.Note != nil {
+			changes["note"] = *u.Note
+		}
+		if u.AssigneeUserID != nil {
+			if err := tx.First(&models.User{}, *u.AssigneeUserID).Error; err != nil {
+				return errors.New("assignee not found")
+			}
+			changes["assignee_user_id"], changes["owner"] = *u.AssigneeUserID, models.OwnerUser
+		}
+		if u.DueDate != nil {
+			changes["due_date"] = *u.DueDate
+		}
+		if len(changes) == 0 {
+			return errors.New("nothing to update")
+		}
+		if err := tx.Model(&task).Updates(changes).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&task, task.ID).Error; err != nil {
+			return err
+		}
+
+		var open int64
+		if err := tx.Model(&models.ChecklistTask{}).
+			Where("checklist_id = ? AND status IN ?", checklistID, []models.TaskStatus{models.TaskPending, models.TaskInProgress}).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open == 0 {
+			return tx.Model(&cl).Updates(map[string]interface{}{
+				"status":       models.ChecklistCompleted,
+				"completed_at": time.Now(),
+				"version":      cl.Version + 1,
+			}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Cancel stops an open checklist. An offboarding that was already closed out cannot be cancelled;
+// restore the employee instead.
+func (s *ChecklistService) Cancel(id uint) (*models.Checklist, error) {
+	cl, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if cl.Status != models.ChecklistOpen || cl.ClosedOutAt != nil {
+		return nil, ErrChecklistClosed
+	}
+	res := s.db.Model(&models.Checklist{}).Where("id = ? AND version = ?", cl.ID, cl.Version).
+		Updates(map[string]interface{}{"status": models.ChecklistCancelled, "version": cl.Version + 1})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errors.New("checklist was modified concurrently")
+	}
+	return s.Get(id)
+}
+
+// CloseOutOffboardings runs on the scheduler. Once an offboarding's last working day has passed,
+// the employee's login is disabled, their tokens are revoked and the employee is terminated as of
+// that day, whether or not every task is done.
+func (s *ChecklistService) CloseOutOffboardings(now time.Time) error {
+	var due []models.Checklist
+	// the leaver keeps their access through the last working day
+	if err := s.db.Where("kind = ? AND status IN ? AND closed_out_at IS NULL AND reference_date < ?",
+		models.ChecklistOffboarding, []models.ChecklistStatus{models.ChecklistOpen, models.ChecklistCompleted},
+		now.UTC().Format("2006-01-02")).Find(&due).Error; err != nil {
+		return err
+	}
+	// one failing offboarding must not hold up the others; the next run retries it
+	var errs []error
+	for _, cl := range due {
+		if err := s.closeOut(&cl, now); err != nil {
+			errs = append(errs, fmt.Errorf("offboarding %d: %w", cl.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *ChecklistService) closeOut(cl *models.Checklist, now time.Time) error {
+	emp, err := s.employees.Get(cl.EmployeeID)
+	if err != nil {
+		return err
+	}
+	if emp.Status != models.EmploymentTerminated {
+		reason := cl.Reason
+		if reason == "" {
+			reason = "offboarding"
+		}
+		if _, err := s.employees.Terminate(emp.ID, Termination{Date: cl.ReferenceDate, Reason: reason, By: cl.StartedBy}); err != nil {
+			return err
+		}
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.auth.DisableUser(tx, emp.UserID); err != nil {
+			return err
+		}
+		return tx.Model(&models.Checklist{}).Where("id = ?", cl.ID).Update("closed_out_at", now).Error
+	})
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/pdf"
+)
+
+// builtinLetterTemplate is the revision letter of cycles without their own text.
+const builtinLetterTemplate = `Dear {{employee_name}},
+
+Following the {{cycle}} compensation review, we are pleased to confirm that your annual salary will change from {{current_salary}} to {{new_salary}}, an increase of {{increase}} ({{increase_percent}}%), with effect from {{effective_date}}.
+
+All other terms of your employment remain unchanged.
+
+Thank you for your contribution.`
+
+// LetterPlaceholders are the fields a letter template can use, written {{name}}.
+var LetterPlaceholders = []string{
+	"employee_name", "employee_id", "position", "department", "cycle", "effective_date",
+	"current_salary", "new_salary", "increase", "increase_percent", "date",
+}
+
+// LetterData is everything printed on one revision letter. Branding comes from the default
+// payslip template.
+type LetterData struct {
+	Template    models.PayslipTemplate
+	Logo        []byte // JPEG, optional
+	Text        string
+	Cycle       models.CompensationCycle
+	Proposal    models.CompensationProposal
+	Employee    models.Employee
+	GeneratedAt time.Time
+}
+
+// FillLetter replaces the placeholders of text with the revision's values. Unknown
+// placeholders are left as they are.
+func FillLetter(text string, d *LetterData) string {
+	if strings.TrimSpace(text) == "" {
+		text = builtinLetterTemplate
+	}
+	pct := 0.0
+	if d.Proposal.CurrentSalary != 0 {
+		pct = d.Proposal.Increase() / d.Proposal.CurrentSalary * 100
+	}
+	values := map[string]string{
+		"employee_name":    d.Employee.Name,
+		"employee_id":      fmt.Sprint(d.Employee.ID),
+		"position":         d.Employee.Position,
+		"department":       d.Employee.Department,
+		"cycle":            d.Cycle.Name,
+		"effective_date":   d.Cycle.EffectiveDate.Format("02 January 2006"),
+		"current_salary":   money(d.Proposal.CurrentSalary),
+		"new_salary":       money(d.Proposal.ProposedSalary),
+		"increase":         money(d.Proposal.Increase()),
+		"increase_percent": fmt.Sprintf("%.1f", pct),
+		"date":             d.GeneratedAt.Format("02 January 2006"),
+	}
+	pairs := make([]string, 0, 2*len(values))
+	for _, k := range LetterPlaceholders {
+		pairs = append(pairs, "{{"+k+"}}", values[k])
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// wrapText breaks s into lines no wider than width; paragraphs are kept and long words are not
+// split.
+func wrapText(font pdf.Font, size float64, s string, width float64) []string {
+	var out []string
+	for _, para := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		words := strings.Fields(para)
+		if len(words) == 0 {
+			out = append(out, "")
+			continue
+		}
+		line := words[0]
+		for _, w := range words[1:] {
+			if pdf.Width(font, size, line+" "+w) > width {
+				out = append(out, line)
+				line = w
+				continue
+			}
+			line += " " + w
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// letterhead starts a branded document titled title: a band in the brand colour with the logo,
+// company name and address. The layout continues below it.
+func letterhead(doc *pdf.Document, t models.PayslipTemplate, logo []byte, title string) (*slipLayout, error) {
+	r, g, b, err := pdf.ParseColor(t.BrandColor)
+	if err != nil {
+		r, g, b, _ = pdf.ParseColor(builtinPayslipTemplate.BrandColor)
+	}
+	l := &slipLayout{doc: doc, r: r, g: g, b: b, footer: t.FooterText}
+	l.newPage()
+	p := l.page
+
+	const band = 80.0
+	p.SetFillColor(r, g, b)
+	p.Rect(0, 0, pdf.PageWidth, band)
+	x := slipMargin
+	if len(logo) > 0 {
+		img, err := doc.AddJPEG(logo)
+		if err != nil {
+			return nil, fmt.Errorf("logo: %w", err)
+		}
+		h := 50.0
+		w := h * float64(img.Width) / float64(img.Height)
+		if w > 150 {
+			w, h = 150, 150*float64(img.Height)/float64(img.Width)
+		}
+		p.Image(img, x, (band-h)/2, w, h)
+		x += w + 12
+	}
+	p.SetFillColor(1, 1, 1)
+	p.Text(x, 34, pdf.HelveticaBold, 15, t.CompanyName)
+	for i, line := range strings.Split(strings.TrimSpace(t.CompanyAddress), "\n") {
+		if i == 3 {
+			break
+		}
+		p.Text(x, 48+float64(i)*10, pdf.Helvetica, 8, strings.TrimSpace(line))
+	}
+	p.TextRight(slipRight, 34, pdf.HelveticaBold, 18, title)
+	p.SetFillColor(0, 0, 0)
+	l.y = band + 36
+	return l, nil
+}
+
+// RenderLetter lays out a salary revision letter as a PDF.
+func RenderLetter(d *LetterData) ([]byte, error) {
+	t := d.Template
+	doc := pdf.New()
+	doc.SetInfo(fmt.Sprintf("Salary revision %s - %s", d.Cycle.Name, d.Employee.Name), t.CompanyName)
+	l, err := letterhead(doc, t, d.Logo, "Salary revision")
+	if err != nil {
+		return nil, err
+	}
+	p := l.page
+
+	p.TextRight(slipRight, l.y, pdf.Helvetica, 10, d.GeneratedAt.Format("02 January 2006"))
+	p.Text(slipMargin, l.y, pdf.HelveticaBold, 10, d.Employee.Name)
+	l.y += 14
+	for _, s := range []string{d.Employee.Position, d.Employee.Department} {
+		if s != "" {
+			p.Text(slipMargin, l.y, pdf.Helvetica, 10, s)
+			l.y += 14
+		}
+	}
+	l.y += 2 * slipRow
+
+	for _, line := range wrapText(pdf.Helvetica, 10, FillLetter(d.Text, d), slipRight-slipMargin) {
+		l.need(14)
+		l.page.Text(slipMargin, l.y, pdf.Helvetica, 10, line)
+		l.y += 14
+	}
+
+	l.heading("Salary revision", "Annual salary")
+	l.row(pdf.Helvetica, "Current salary", money(d.Proposal.CurrentSalary))
+	l.row(pdf.Helvetica, "New salary from "+d.Cycle.EffectiveDate.Format("02 Jan 2006"), money(d.Proposal.ProposedSalary))
+	l.row(pdf.HelveticaBold, "Increase", money(d.Proposal.Increase()))
+	return doc.Bytes()
+}
@@ -0,0 +1,681 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/storage"
+)
+
+var (
+	ErrCycleStatus       = errors.New("the compensation cycle does not allow this in its current status")
+	ErrProposalStatus    = errors.New("the proposal does not allow this in its current status")
+	ErrNotProposer       = errors.New("only the employee's manager or HR can propose a revision")
+	ErrBudgetExceeded    = errors.New("the proposal exceeds the department's remaining budget")
+	ErrProposalsPending  = errors.New("submitted proposals must be decided before the cycle is closed")
+	ErrProposalsOverdue  = errors.New("the proposal deadline of the cycle has passed")
+	ErrLetterUnavailable = errors.New("letters exist for applied revisions only")
+)
+
+// CompensationService runs salary review cycles: HR sets department budgets, managers propose
+// revisions for their reports, HR approves them, and closing the cycle records the approved
+// salaries in the employment history and produces a letter for every revision.
+type CompensationService struct {
+	db        *gorm.DB
+	store     storage.BlobStore
+	employees *EmployeeService
+	payslips  *PayslipService
+	notify    *NotificationService
+}
+
+func NewCompensationService(db *gorm.DB, store storage.BlobStore) *CompensationService {
+	return &CompensationService{
+		db:        db,
+		store:     store,
+		employees: NewEmployeeService(db),
+		payslips:  NewPayslipService(db, store),
+		notify:    NewNotificationService(db),
+	}
+}
+
+// Cycles
+
+func (s *CompensationService) ListCycles() ([]models.CompensationCycle, error) {
+	var list []models.CompensationCycle
+	return list, s.db.Order("effective_date DESC, id DESC").Find(&list).Error
+}
+
+// GetCycle returns the cycle with its budgets and what is committed against them.
+func (s *CompensationService) GetCycle(id uint) (*models.CompensationCycle, error) {
+	var c models.CompensationCycle
+	if err := s.db.Preload("Budgets", func(db *gorm.DB) *gorm.DB { return db.Order("department_id") }).
+		First(&c, id).Error; err != nil {
+		return nil, err
+	}
+	committed, err := committedByDepartment(s.db, c.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range c.Budgets {
+		b := &c.Budgets[i]
+		b.Committed = committed[b.DepartmentID]
+		b.Remaining = roundMoney(b.Amount - b.Committed)
+	}
+	return &c, nil
+}
+
+// SaveCycle creates c, or updates the name, dates and letter of a cycle that is not closed.
+func (s *CompensationService) SaveCycle(c *models.CompensationCycle) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.EffectiveDate.IsZero() {
+		return errors.New("effective_date is required")
+	}
+	if c.ProposalsDue != nil && c.ProposalsDue.After(c.EffectiveDate) {
+		return errors.New("proposals_due must not be after effective_date")
+	}
+	c.Budgets = nil
+	if c.ID == 0 {
+		c.Status = models.CycleDraft
+		return s.db.Create(c).Error
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var cur models.CompensationCycle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cur, c.ID).Error; err != nil {
+			return err
+		}
+		if cur.Status == models.CycleClosed {
+			return ErrCycleStatus
+		}
+		c.Status, c.CreatedAt, c.CreatedBy = cur.Status, cur.CreatedAt, cur.CreatedBy
+		return tx.Omit("Budgets").Save(c).Error
+	})
+}
+
+// DeleteCycle removes a draft cycle with its budgets.
+func (s *CompensationService) DeleteCycle(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var c models.CompensationCycle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, id).Error; err != nil {
+			return err
+		}
+		if c.Status != models.CycleDraft {
+			return ErrCycleStatus
+		}
+		return tx.Delete(&c).Error
+	})
+}
+
+// OpenCycle starts taking proposals and tells the managers.
+func (s *CompensationService) OpenCycle(id uint) (*models.CompensationCycle, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		c, err := lockCycle(tx, id, models.CycleDraft)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(c).Update("status", models.CycleOpen).Error; err != nil {
+			return err
+		}
+		body := fmt.Sprintf("Propose salary revisions for your reports, effective %s.", c.EffectiveDate.Format("02 Jan 2006"))
+		if c.ProposalsDue != nil {
+			body += fmt.Sprintf(" Proposals are due by %s.", c.ProposalsDue.Format("02 Jan 2006"))
+		}
+		return s.notify.NotifyRole(tx, models.RoleManager, models.Notification{
+			Kind:       "COMPENSATION_REVIEW",
+			Title:      fmt.Sprintf("Compensation review %s is open", c.Name),
+			Body:       body,
+			EntityType: "compensation_cycle",
+			EntityID:   &c.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCycle(id)
+}
+
+func lockCycle(tx *gorm.DB, id uint, status ...models.CompensationCycleStatus) (*models.CompensationCycle, error) {
+	var c models.CompensationCycle
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, id).Error; err != nil {
+		return nil, err
+	}
+	for _, st := range status {
+		if c.Status == st {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: cycle is %s", ErrCycleStatus, strings.ToLower(string(c.Status)))
+}
+
+// Budgets
+
+// SetBudget sets the increase budget of a department in a cycle that is not closed.
+func (s *CompensationService) SetBudget(cycleID, departmentID uint, amount float64) (*models.CompensationBudget, error) {
+	if amount < 0 {
+		return nil, errors.New("amount must not be negative")
+	}
+	b := models.CompensationBudget{CycleID: cycleID, DepartmentID: departmentID, Amount: roundMoney(amount)}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockCycle(tx, cycleID, models.CycleDraft, models.CycleOpen); err != nil {
+			return err
+		}
+		if err := tx.First(&models.Department{}, departmentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("department not found")
+			}
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "cycle_id"}, {Name: "department_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"amount", "updated_at"}),
+		}).Create(&b).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (s *CompensationService) DeleteBudget(cycleID, departmentID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockCycle(tx, cycleID, models.CycleDraft, models.CycleOpen); err != nil {
+			return err
+		}
+		res := tx.Where("cycle_id = ? AND department_id = ?", cycleID, departmentID).Delete(&models.CompensationBudget{})
+		if res.Error == nil && res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return res.Error
+	})
+}
+
+// committedByDepartment sums the increases of the cycle's submitted, approved and applied
+// proposals by department, leaving out proposal except.
+func committedByDepartment(tx *gorm.DB, cycleID, except uint) (map[uint]float64, error) {
+	var list []models.CompensationProposal
+	if err := tx.Where("cycle_id = ? AND id <> ? AND status IN ?", cycleID, except,
+		[]models.ProposalStatus{models.ProposalSubmitted, models.ProposalApproved, models.ProposalApplied}).
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	out := map[uint]float64{}
+	for _, p := range list {
+		if p.DepartmentID != nil {
+			out[*p.DepartmentID] = roundMoney(out[*p.DepartmentID] + p.Increase())
+		}
+	}
+	return out, nil
+}
+
+// checkBudget fails when p would take its department over budget. Departments without a budget
+// in the cycle are not capped.
+func checkBudget(tx *gorm.DB, p *models.CompensationProposal) error {
+	if p.DepartmentID == nil {
+		return nil
+	}
+	var b models.CompensationBudget
+	err := tx.Where("cycle_id = ? AND department_id = ?", p.CycleID, *p.DepartmentID).First(&b).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	committed, err := committedByDepartment(tx, p.CycleID, p.ID)
+	if err != nil {
+		return err
+	}
+	if remaining := roundMoney(b.Amount - committed[*p.DepartmentID]); p.Increase() > remaining {
+		return fmt.Errorf("%w: increase %.2f, remaining %.2f", ErrBudgetExceeded, p.Increase(), remaining)
+	}
+	return nil
+}
+
+// Proposals
+
+// Proposer is who proposes or decides a revision: the user and, when they are an employee, their
+// employee record. HR may propose for anyone and decides proposals.
+type Proposer struct {
+	UserID     uint
+	EmployeeID uint
+	HR         bool
+}
+
+// WorksheetRow is an employee under review with their current job and any proposal.
+type WorksheetRow struct {
+	EmployeeID    uint                         `json:"employee_id"`
+	Name          string                       `json:"name"`
+	Position      string                       `json:"position"`
+	Department    string                       `json:"department"`
+	DepartmentID  *uint                        `json:"department_id,omitempty"`
+	CurrentSalary float64                      `json:"current_salary"`
+	Proposal      *models.CompensationProposal `json:"proposal,omitempty"`
+}
+
+// Worksheet lists the active direct reports of managerID with their proposals in the cycle.
+func (s *CompensationService) Worksheet(cycleID, managerID uint) ([]WorksheetRow, error) {
+	if err := s.db.First(&models.CompensationCycle{}, cycleID).Error; err != nil {
+		return nil, err
+	}
+	var emps []models.Employee
+	if err := s.db.Where("manager_id = ? AND status <> ?", managerID, models.EmploymentTerminated).
+		Order("name").Find(&emps).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(emps))
+	for _, e := range emps {
+		ids = append(ids, e.ID)
+	}
+	var props []models.CompensationProposal
+	if err := s.db.Where("cycle_id = ? AND employee_id IN ?", cycleID, ids).Find(&props).Error; err != nil {
+		return nil, err
+	}
+	byEmp := make(map[uint]*models.CompensationProposal, len(props))
+	for i := range props {
+		byEmp[props[i].EmployeeID] = &props[i]
+	}
+	rows := make([]WorksheetRow, 0, len(emps))
+	for _, e := range emps {
+		rows = append(rows, WorksheetRow{
+			EmployeeID: e.ID, Name: e.Name, Position: e.Position, Department: e.Department,
+			DepartmentID: e.DepartmentID, CurrentSalary: e.Salary, Proposal: byEmp[e.ID],
+		})
+	}
+	return rows, nil
+}
+
+// ProposalFilter narrows proposal listings; zero values match everything.
+type ProposalFilter struct {
+	CycleID      uint
+	EmployeeID   uint
+	DepartmentID uint
+	Status       models.ProposalStatus
+}
+
+func (s *CompensationService) ListProposals(f ProposalFilter) ([]models.CompensationProposal, error) {
+	tx := s.db.Order("cycle_id DESC, employee_name, id")
+	if f.CycleID != 0 {
+		tx = tx.Where("cycle_id = ?", f.CycleID)
+	}
+	if f.EmployeeID != 0 {
+		tx = tx.Where("employee_id = ?", f.EmployeeID)
+	}
+	if f.DepartmentID != 0 {
+		tx = tx.Where("department_id = ?", f.DepartmentID)
+	}
+	if f.Status != "" {
+		tx = tx.Where("status = ?", f.Status)
+	}
+	var list []models.CompensationProposal
+	return list, tx.Find(&list).Error
+}
+
+// mayPropose fails unless by may propose for emp in cycle c.
+func mayPropose(c *models.CompensationCycle, emp *models.Employee, by Proposer) error {
+	if by.EmployeeID != 0 && by.EmployeeID == emp.ID {
+		return fmt.Errorf("%w: not for yourself", ErrNotProposer)
+	}
+	if by.HR {
+		return nil
+	}
+	if emp.ManagerID == nil || *emp.ManagerID != by.EmployeeID || by.EmployeeID == 0 {
+		return ErrNotProposer
+	}
+	if c.ProposalsDue != nil && today().After(*c.ProposalsDue) {
+		return ErrProposalsOverdue
+	}
+	return nil
+}
+
+// SaveProposal proposes a new annual salary for an employee in an open cycle, replacing a draft
+// or rejected proposal. The employee's current salary and department are taken now.
+func (s *CompensationService) SaveProposal(cycleID, employeeID uint, salary float64, reason string, by Proposer) (*models.CompensationProposal, error) {
+	if salary <= 0 {
+		return nil, errors.New("proposed_salary must be positive")
+	}
+	var p models.CompensationProposal
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		c, err := lockCycle(tx, cycleID, models.CycleOpen)
+		if err != nil {
+			return err
+		}
+		var emp models.Employee
+		if err := tx.Where("id = ? AND status <> ?", employeeID, models.EmploymentTerminated).First(&emp).Error; err != nil {
+			return err
+		}
+		if err := mayPropose(c, &emp, by); err != nil {
+			return err
+		}
+		if err := tx.Where("cycle_id = ? AND employee_id = ?", cycleID, employeeID).Limit(1).Find(&p).Error; err != nil {
+			return err
+		}
+		if p.ID != 0 && p.Status != models.ProposalDraft && p.Status != models.ProposalRejected {
+			return ErrProposalStatus
+		}
+		p.CycleID, p.EmployeeID, p.EmployeeName, p.DepartmentID = cycleID, emp.ID, emp.Name, emp.DepartmentID
+		p.CurrentSalary, p.ProposedSalary = emp.Salary, roundMoney(salary)
+		p.Reason, p.Status, p.ProposedBy = strings.TrimSpace(reason), models.ProposalDraft, by.UserID
+		p.SubmittedAt, p.DecidedBy, p.DecidedAt, p.DecisionNote = nil, nil, nil, ""
+		return tx.Save(&p).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// lockProposal loads a proposal with its cycle for update and checks that by may act on it.
+func lockProposal(tx *gorm.DB, id uint, by Proposer) (*models.CompensationProposal, *models.CompensationCycle, error) {
+	var p models.CompensationProposal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&p, id).Error; err != nil {
+		return nil, nil, err
+	}
+	c, err := lockCycle(tx, p.CycleID, models.CycleOpen)
+	if err != nil {
+		return nil, nil, err
+	}
+	var emp models.Employee
+	if err := tx.First(&emp, p.EmployeeID).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := mayPropose(c, &emp, by); err != nil {
+		return nil, nil, err
+	}
+	return &p, c, nil
+}
+
+// SubmitProposal sends a draft proposal to HR, provided the department budget allows it.
+func (s *CompensationService) SubmitProposal(id uint, by Proposer) (*models.CompensationProposal, error) {
+	var p *models.CompensationProposal
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var c *models.CompensationCycle
+		var err error
+		if p, c, err = lockProposal(tx, id, by); err != nil {
+			return err
+		}
+		if p.Status != models.ProposalDraft {
+			return ErrProposalStatus
+		}
+		if err := checkBudget(tx, p); err != nil {
+			return err
+		}
+		now := time.Now()
+		p.Status, p.SubmittedAt = models.ProposalSubmitted, &now
+		if err := tx.Model(p).Updates(map[string]interface{}{"status": p.Status, "submitted_at": now}).Error; err != nil {
+			return err
+		}
+		return s.notify.NotifyRole(tx, models.RoleHR, models.Notification{
+			Kind:       "COMPENSATION_PROPOSAL",
+			Title:      fmt.Sprintf("Salary revision proposed for %s", p.EmployeeName),
+			Body:       fmt.Sprintf("Compensation review %s: awaiting approval.", c.Name),
+			EntityType: "compensation_proposal",
+			EntityID:   &p.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DeleteProposal withdraws a draft or rejected proposal.
+func (s *CompensationService) DeleteProposal(id uint, by Proposer) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		p, _, err := lockProposal(tx, id, by)
+		if err != nil {
+			return err
+		}
+		if p.Status != models.ProposalDraft && p.Status != models.ProposalRejected {
+			return ErrProposalStatus
+		}
+		return tx.Delete(p).Error
+	})
+}
+
+// DecideProposal records HR's decision on a submitted proposal and tells the proposer.
+// Rejections need a note.
+func (s *CompensationService) DecideProposal(id uint, approve bool, by Proposer, note string) (*models.CompensationProposal, error) {
+	note = strings.TrimSpace(note)
+	if !approve && note == "" {
+		return nil, errors.New("a reason is required to reject a proposal")
+	}
+	var p models.CompensationProposal
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&p, id).Error; err != nil {
+			return err
+		}
+		if _, err := lockCycle(tx, p.CycleID, models.CycleOpen); err != nil {
+			return err
+		}
+		if p.Status != models.ProposalSubmitted {
+			return ErrProposalStatus
+		}
+		if by.EmployeeID != 0 && by.EmployeeID == p.EmployeeID {
+			return errors.New("you cannot decide your own salary revision")
+		}
+		now := time.Now()
+		p.Status, p.DecidedBy, p.DecidedAt, p.DecisionNote = models.ProposalRejected, &by.UserID, &now, note
+		if approve {
+			p.Status = models.ProposalApproved
+		}
+		if err := tx.Model(&p).Updates(map[string]interface{}{
+			"status": p.Status, "decided_by": by.UserID, "decided_at": now, "decision_note": note,
+		}).Error; err != nil {
+			return err
+		}
+		return s.notify.Notify(tx, forUser(models.Notification{
+			Kind:       "COMPENSATION_PROPOSAL",
+			Title:      fmt.Sprintf("Salary revision for %s %s", p.EmployeeName, strings.ToLower(string(p.Status))),
+			Body:       note,
+			EntityType: "compensation_proposal",
+			EntityID:   &p.ID,
+		}, p.ProposedBy))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Closing
+
+// CloseResult tells what closing a cycle did.
+type CloseResult struct {
+	Cycle   *models.CompensationCycle     `json:"cycle"`
+	Applied []models.CompensationProposal `json:"applied"`
+	// Lapsed are approved proposals of employees who left before the cycle closed.
+	Lapsed []models.CompensationProposal `json:"lapsed,omitempty"`
+}
+
+// CloseCycle applies every approved proposal as a job record effective on the cycle date,
+// writes its letter and tells the employee, then closes the cycle. Proposals still awaiting a
+// decision block closing. Each revision is recorded and marked applied in one transaction, so
+// after a failure closing again picks up the rest and writes the letters that are missing.
+func (s *CompensationService) CloseCycle(id, by uint) (*CloseResult, error) {
+	c, err := s.GetCycle(id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != models.CycleOpen {
+		return nil, fmt.Errorf("%w: cycle is %s", ErrCycleStatus, strings.ToLower(string(c.Status)))
+	}
+	var pending int64
+	if err := s.db.Model(&models.CompensationProposal{}).
+		Where("cycle_id = ? AND status = ?", id, models.ProposalSubmitted).Count(&pending).Error; err != nil {
+		return nil, err
+	}
+	if pending > 0 {
+		return nil, fmt.Errorf("%w: %d pending", ErrProposalsPending, pending)
+	}
+	var approved []models.CompensationProposal
+	if err := s.db.Where("cycle_id = ? AND status = ?", id, models.ProposalApproved).Order("id").Find(&approved).Error; err != nil {
+		return nil, err
+	}
+	tpl, logo, err := s.payslips.defaultTemplate()
+	if err != nil {
+		return nil, err
+	}
+	out := &CloseResult{Applied: []models.CompensationProposal{}}
+	// revisions applied by an earlier attempt whose letter was not written
+	var unwritten []models.CompensationProposal
+	if err := s.db.Where("cycle_id = ? AND status = ? AND (letter_key IS NULL OR letter_key = '')", id, models.ProposalApplied).
+		Order("id").Find(&unwritten).Error; err != nil {
+		return nil, err
+	}
+	for i := range unwritten {
+		p := &unwritten[i]
+		if err := s.writeLetter(c, p, tpl, logo); err != nil {
+			return nil, fmt.Errorf("letter for %s: %w", p.EmployeeName, err)
+		}
+		out.Applied = append(out.Applied, *p)
+	}
+	for i := range approved {
+		p := &approved[i]
+		lapsed := false
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			salary := p.ProposedSalary
+			rec, err := NewEmployeeService(tx).RecordJobChange(p.EmployeeID, JobChange{
+				EffectiveDate: c.EffectiveDate,
+				Salary:        &salary,
+				Reason:        "Compensation review " + c.Name,
+				CreatedBy:     by,
+			})
+			if errors.Is(err, ErrEmployeeTerminated) {
+				lapsed = true
+				return tx.Model(p).Where("status = ?", models.ProposalApproved).Updates(map[string]interface{}{
+					"status": models.ProposalRejected, "decision_note": "employee left before the cycle closed",
+				}).Error
+			}
+			if err != nil {
+				return err
+			}
+			res := tx.Model(p).Where("status = ?", models.ProposalApproved).
+				Updates(map[string]interface{}{"status": models.ProposalApplied, "job_record_id": rec.ID})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errors.New("proposal was modified concurrently")
+			}
+			p.Status, p.JobRecordID = models.ProposalApplied, &rec.ID
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.EmployeeName, err)
+		}
+		if lapsed {
+			p.Status = models.ProposalRejected
+			out.Lapsed = append(out.Lapsed, *p)
+			continue
+		}
+		if err := s.writeLetter(c, p, tpl, logo); err != nil {
+			return nil, fmt.Errorf("letter for %s: %w", p.EmployeeName, err)
+		}
+		out.Applied = append(out.Applied, *p)
+	}
+	now := time.Now()
+	if err := s.db.Model(&models.CompensationCycle{}).Where("id = ? AND status = ?", id, models.CycleOpen).
+		Updates(map[string]interface{}{"status": models.CycleClosed, "closed_by": by, "closed_at": now}).Error; err != nil {
+		return nil, err
+	}
+	if out.Cycle, err = s.GetCycle(id); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// writeLetter renders and stores the letter of an applied proposal and tells the employee.
+func (s *CompensationService) writeLetter(c *models.CompensationCycle, p *models.CompensationProposal, tpl *models.PayslipTemplate, logo []byte) error {
+	var emp models.Employee
+	if err := s.db.First(&emp, p.EmployeeID).Error; err != nil {
+		return err
+	}
+	data := LetterData{Template: *tpl, Logo: logo, Text: c.LetterTemplate, Cycle: *c, Proposal: *p, Employee: emp, GeneratedAt: time.Now()}
+	file, err := RenderLetter(&data)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("compensation/%d/%d-%d.pdf", p.EmployeeID, c.ID, time.Now().UnixNano())
+	if err := s.store.Put(key, bytes.NewReader(file)); err != nil {
+		return err
+	}
+	old := p.LetterKey
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(p).Updates(map[string]interface{}{"letter_key": key, "letter_generated_at": data.GeneratedAt}).Error; err != nil {
+			return err
+		}
+		if emp.UserID == 0 || old != "" {
+			return nil
+		}
+		return s.notify.Notify(tx, forUser(models.Notification{
+			Kind:       "SALARY_REVISION",
+			Title:      "Your salary revision letter",
+			Body:       fmt.Sprintf("Your salary changes with effect from %s.", c.EffectiveDate.Format("02 Jan 2006")),
+			EntityType: "compensation_proposal",
+			EntityID:   &p.ID,
+		}, emp.UserID))
+	})
+	if err != nil {
+		_ = s.store.Delete(key)
+		return err
+	}
+	p.LetterKey, p.LetterGeneratedAt = key, &data.GeneratedAt
+	if old != "" {
+		_ = s.store.Delete(old)
+	}
+	return nil
+}
+
+// Letters
+
+// ListLetters returns the employee's applied revisions, newest first.
+func (s *CompensationService) ListLetters(employeeID uint) ([]models.CompensationProposal, error) {
+	var list []models.CompensationProposal
+	return list, s.db.Where("employee_id = ? AND status = ?", employeeID, models.ProposalApplied).
+		Order("id DESC").Find(&list).Error
+}
+
+// OpenLetter returns an applied proposal and a reader over its letter, writing the letter first
+// when it is missing. Callers must close the reader.
+func (s *CompensationService) OpenLetter(id uint) (*models.CompensationProposal, io.ReadCloser, error) {
+	var p models.CompensationProposal
+	if err := s.db.First(&p, id).Error; err != nil {
+		return nil, nil, err
+	}
+	if p.Status != models.ProposalApplied {
+		return nil, nil, ErrLetterUnavailable
+	}
+	if p.LetterKey == "" {
+		var c models.CompensationCycle
+		if err := s.db.First(&c, p.CycleID).Error; err != nil {
+			return nil, nil, err
+		}
+		tpl, logo, err := s.payslips.defaultTemplate()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.writeLetter(&c, &p, tpl, logo); err != nil {
+			return nil, nil, err
+		}
+	}
+	rc, err := s.store.Open(p.LetterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &p, rc, nil
+}
+
+// LetterFileName names the downloaded letter of p.
+func LetterFileName(p *models.CompensationProposal) string {
+	return fmt.Sprintf("salary-revision-%d-%s.pdf", p.CycleID, fileSlug(p.EmployeeName))
+}
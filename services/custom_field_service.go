@@ -0,0 +1,326 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/utils"
+)
+
+var (
+	ErrInvalidCustomField = errors.New("invalid custom field")
+	ErrCustomFieldInUse   = errors.New("custom field has values; its type cannot change")
+)
+
+// CustomFieldColumnPrefix prefixes custom field columns in imports and exports: key t_shirt_size
+// is the column cf_t_shirt_size.
+const CustomFieldColumnPrefix = "cf_"
+
+const maxCustomTextLength = 500
+
+// customFieldKey keeps keys usable as column names and inside SQL json paths.
+var customFieldKey = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+type CustomFieldService struct {
+	db *gorm.DB
+}
+
+func NewCustomFieldService(db *gorm.DB) *CustomFieldService { return &CustomFieldService{db: db} }
+
+func customFieldDefinitions(tx *gorm.DB) ([]models.CustomFieldDefinition, error) {
+	var defs []models.CustomFieldDefinition
+	err := tx.Order("sort_order, id").Find(&defs).Error
+	return defs, err
+}
+
+// customFieldVisible reports whether role sees the field; own is set when the record is the caller's.
+func customFieldVisible(d *models.CustomFieldDefinition, role models.UserRole, own bool) bool {
+	if role == models.RoleHR || own && d.EmployeeCanView {
+		return true
+	}
+	for _, r := range d.ViewRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the definitions role can see on its own record or on others'; HR sees all.
+func (s *CustomFieldService) List(role models.UserRole) ([]models.CustomFieldDefinition, error) {
+	defs, err := customFieldDefinitions(s.db)
+	if err != nil {
+		return nil, err
+	}
+	out := defs[:0]
+	for i := range defs {
+		if customFieldVisible(&defs[i], role, true) {
+			out = append(out, defs[i])
+		}
+	}
+	return out, nil
+}
+
+// Save creates the definition (ID 0) or replaces an existing one. The key cannot change, and
+// the type only while no employee has a value.
+func (s *CustomFieldService) Save(d *models.CustomFieldDefinition) error {
+	if err := normalizeDefinition(d); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&models.CustomFieldDefinition{}).Where("key = ? AND id <> ?", d.Key, d.ID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("custom field %q already exists", d.Key)
+		}
+		if d.ID != 0 {
+			var cur models.CustomFieldDefinition
+			if err := tx.First(&cur, d.ID).Error; err != nil {
+				return err
+			}
+			if d.Key != cur.Key {
+				return errors.New("key cannot be changed")
+			}
+			if d.Type != cur.Type {
+				if err := tx.Model(&models.Employee{}).Where("custom_fields -> ?::text IS NOT NULL", d.Key).Count(&n).Error; err != nil {
+					return err
+				}
+				if n > 0 {
+					return ErrCustomFieldInUse
+				}
+			}
+			d.CreatedAt = cur.CreatedAt
+		}
+		return tx.Save(d).Error
+	})
+}
+
+func normalizeDefinition(d *models.CustomFieldDefinition) error {
+	d.Key = strings.ToLower(strings.TrimSpace(d.Key))
+	if !customFieldKey.MatchString(d.Key) {
+		return errors.New("key must be 1-40 lowercase letters, digits or underscores, starting with a letter")
+	}
+	d.Label = strings.TrimSpace(d.Label)
+	if d.Label == "" {
+		return errors.New("label is required")
+	}
+	d.Type = models.CustomFieldType(strings.ToUpper(string(d.Type)))
+	switch d.Type {
+	case models.CustomFieldText, models.CustomFieldNumber, models.CustomFieldDate, models.CustomFieldBoolean, models.CustomFieldSelect:
+	default:
+		return fmt.Errorf("invalid type %q", d.Type)
+	}
+
+	if d.Type == models.CustomFieldSelect {
+		seen := map[string]bool{}
+		opts := make([]string, 0, len(d.Options))
+		for _, o := range d.Options {
+			o = strings.TrimSpace(o)
+			if o == "" || seen[strings.ToLower(o)] {
+				continue
+			}
+			seen[strings.ToLower(o)] = true
+			opts = append(opts, o)
+		}
+		if len(opts) == 0 {
+			return errors.New("select fields need at least one option")
+		}
+		d.Options = opts
+	} else {
+		d.Options = nil
+	}
+	if d.Type == models.CustomFieldText && d.Pattern != "" {
+		if _, err := regexp.Compile(d.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+	} else {
+		d.Pattern = ""
+	}
+	if d.Type == models.CustomFieldNumber {
+		if d.Min != nil && d.Max != nil && *d.Min > *d.Max {
+			return errors.New("min must not exceed max")
+		}
+	} else {
+		d.Min, d.Max = nil, nil
+	}
+
+	for i, r := range d.ViewRoles {
+		d.ViewRoles[i] = models.UserRole(strings.ToUpper(string(r)))
+		if !models.ValidRole(d.ViewRoles[i]) {
+			return fmt.Errorf("invalid role %q", r)
+		}
+	}
+	return nil
+}
+
+// Delete removes the definition and its values from every employee.
+func (s *CustomFieldService) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var d models.CustomFieldDefinition
+		if err := tx.First(&d, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&d).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Employee{}).Where("custom_fields -> ?::text IS NOT NULL", d.Key).
+			UpdateColumn("custom_fields", gorm.Expr("custom_fields - ?::text", d.Key)).Error
+	})
+}
+
+// redactCustomFields drops the custom field values role may not see from e; own is set when e is
+// the caller's record.
+func redactCustomFields(e *models.Employee, defs []models.CustomFieldDefinition, role models.UserRole, own bool) {
+	if role == models.RoleHR || len(e.CustomFields) == 0 {
+		return
+	}
+	visible := map[string]bool{}
+	for i := range defs {
+		visible[defs[i].Key] = customFieldVisible(&defs[i], role, own)
+	}
+	for k := range e.CustomFields {
+		if !visible[k] {
+			delete(e.CustomFields, k)
+		}
+	}
+}
+
+// ApplyCustomFields validates patch against defs and merges it into cur, returning a new map. A nil
+// or empty value removes the key. Strings are accepted for every type, so imported values parse;
+// values are stored normalized (numbers as float64, booleans as bool, dates as YYYY-MM-DD, select
+// values in their option's spelling). With requireAll every required field must end up set.
+func ApplyCustomFields(defs []models.CustomFieldDefinition, cur, patch models.CustomFieldValues, requireAll bool) (models.CustomFieldValues, error) {
+	byKey := make(map[string]*models.CustomFieldDefinition, len(defs))
+	for i := range defs {
+		byKey[defs[i].Key] = &defs[i]
+	}
+	out := make(models.CustomFieldValues, len(cur)+len(patch))
+	for k, v := range cur {
+		out[k] = v
+	}
+	keys := make([]string, 0, len(patch))
+	for k := range patch {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		d, ok := byKey[k]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidCustomField, k)
+		}
+		v, err := customFieldValue(d, patch[k])
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			delete(out, k)
+		} else {
+			out[k] = v
+		}
+	}
+	if requireAll {
+		for i := range defs {
+			if _, ok := out[defs[i].Key]; defs[i].Required && !ok {
+				return nil, fmt.Errorf("%w: %s is required", ErrInvalidCustomField, defs[i].Key)
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// customFieldValue validates and normalizes one value; nil means the value is empty.
+func customFieldValue(d *models.CustomFieldDefinition, v interface{}) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		if s = strings.TrimSpace(s); s == "" {
+			return nil, nil
+		}
+		v = s
+	}
+	if v == nil {
+		return nil, nil
+	}
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s %s", ErrInvalidCustomField, d.Key, fmt.Sprintf(format, args...))
+	}
+	switch d.Type {
+	case models.CustomFieldText:
+		s, ok := v.(string)
+		if !ok {
+			return nil, invalid("must be text")
+		}
+		if len(s) > maxCustomTextLength {
+			return nil, invalid("must be at most %d characters", maxCustomTextLength)
+		}
+		if d.Pattern != "" {
+			if ok, _ := regexp.MatchString(`^(?:`+d.Pattern+`)$`, s); !ok {
+				return nil, invalid("does not match the required format")
+			}
+		}
+		return s, nil
+	case models.CustomFieldNumber:
+		var f float64
+		switch t := v.(type) {
+		case float64:
+			f = t
+		case string:
+			var err error
+			if f, err = strconv.ParseFloat(strings.ReplaceAll(t, ",", ""), 64); err != nil {
+				return nil, invalid("must be a number")
+			}
+		default:
+			return nil, invalid("must be a number")
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, invalid("must be a number")
+		}
+		if d.Min != nil && f < *d.Min {
+			return nil, invalid("must be at least %v", *d.Min)
+		}
+		if d.Max != nil && f > *d.Max {
+			return nil, invalid("must be at most %v", *d.Max)
+		}
+		return f, nil
+	case models.CustomFieldDate:
+		s, _ := v.(string)
+		t, err := utils.ParseDate(s)
+		if err != nil {
+			return nil, invalid("must be a date (YYYY-MM-DD)")
+		}
+		return t.Format("2006-01-02"), nil
+	case models.CustomFieldBoolean:
+		switch t := v.(type) {
+		case bool:
+			return t, nil
+		case string:
+			switch strings.ToLower(t) {
+			case "true", "yes", "y", "1":
+				return true, nil
+			case "false", "no", "n", "0":
+				return false, nil
+			}
+		}
+		return nil, invalid("must be true or false")
+	case models.CustomFieldSelect:
+		s, _ := v.(string)
+		for _, o := range d.Options {
+			if strings.EqualFold(o, s) {
+				return o, nil
+			}
+		}
+		return nil, invalid("must be one of %s", strings.Join(d.Options, ", "))
+	}
+	return nil, invalid("has an unknown type")
+}
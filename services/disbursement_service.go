@@ -0,0 +1,424 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/hrms-backend/bankfile"
+	"github.com/example/hrms-backend/models"
+)
+
+var (
+	ErrRunNotPayable   = errors.New("payments are made for finalized runs only")
+	ErrNothingToExport = errors.New("no payments to export")
+	ErrFileReported    = errors.New("payments of this file have already been reported as paid or failed")
+)
+
+// DisbursementService pays out finalized payroll runs: it writes bank payment files from the
+// payroll lines and the employees' bank details, and tracks every employee's payment until the
+// bank confirms it.
+type DisbursementService struct {
+	db *gorm.DB
+}
+
+func NewDisbursementService(db *gorm.DB) *DisbursementService {
+	return &DisbursementService{db: db}
+}
+
+// Payment profiles
+
+func (s *DisbursementService) ListProfiles() ([]models.PaymentProfile, error) {
+	var list []models.PaymentProfile
+	return list, s.db.Order("name").Find(&list).Error
+}
+
+func (s *DisbursementService) GetProfile(id uint) (*models.PaymentProfile, error) {
+	var p models.PaymentProfile
+	if err := s.db.First(&p, id).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func originator(p *models.PaymentProfile) bankfile.Originator {
+	return bankfile.Originator{
+		Name:     p.CompanyName,
+		ID:       p.CompanyID,
+		BankName: p.BankName,
+		Account:  p.Account,
+		Routing:  p.Routing,
+		Currency: p.Currency,
+	}
+}
+
+// SaveProfile creates the profile (ID 0) or replaces an existing one after checking the
+// company's bank details for the format. NACHA pays in USD and SEPA in EUR; CSV files need a
+// currency and take the default layout when none is given.
+func (s *DisbursementService) SaveProfile(p *models.PaymentProfile) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	f := bankfile.Format(strings.ToUpper(p.Format))
+	if !f.Valid() {
+		return fmt.Errorf("format must be one of %v", bankfile.Formats)
+	}
+	p.Format = string(f)
+	p.Account, p.Routing = bankfile.Normalize(p.Account), bankfile.Normalize(p.Routing)
+	p.Currency = strings.ToUpper(p.Currency)
+	if p.Currency == "" {
+		p.Currency = f.Currency()
+	}
+	if len(p.Currency) != 3 {
+		return errors.New("currency must be a three-letter ISO code")
+	}
+	if err := bankfile.CheckOriginator(f, originator(p)); err != nil {
+		return err
+	}
+	if f == bankfile.CSV {
+		layout, err := bankfile.ParseCSVLayout(p.CSVLayout)
+		if err != nil {
+			return err
+		}
+		if p.CSVLayout, err = json.Marshal(layout); err != nil {
+			return err
+		}
+	} else {
+		p.CSVLayout = nil
+	}
+	var n int64
+	if err := s.db.Model(&models.PaymentProfile{}).Where("name = ? AND id <> ?", p.Name, p.ID).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("a payment profile named %q already exists", p.Name)
+	}
+	if p.ID != 0 {
+		if _, err := s.GetProfile(p.ID); err != nil {
+			return err
+		}
+	}
+	return s.db.Save(p).Error
+}
+
+func (s *DisbursementService) DeleteProfile(id uint) error {
+	res := s.db.Delete(&models.PaymentProfile{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Disbursements
+
+// payableRun loads a run with its period, locked for update when lock is set, and checks that
+// it has been finalized.
+func payableRun(tx *gorm.DB, id uint, lock bool) (*models.PayrollRun, error) {
+	var run models.PayrollRun
+	q := tx.Preload("Period")
+	if lock {
+		q = q.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: clause.CurrentTable}})
+	}
+	if err := q.First(&run, id).Error; err != nil {
+		return nil, err
+	}
+	if run.Status != models.PayrollFinalized && run.Status != models.PayrollPaid {
+		return nil, ErrRunNotPayable
+	}
+	return &run, nil
+}
+
+// ensureDisbursements adds a pending disbursement for every line of the run with net pay that
+// does not have one yet.
+func ensureDisbursements(tx *gorm.DB, runID uint) error {
+	var lines []models.PayrollLine
+	if err := tx.Where("run_id = ? AND employee_id NOT IN (?)", runID,
+		tx.Model(&models.Disbursement{}).Select("employee_id").Where("run_id = ?", runID)).
+		Find(&lines).Error; err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if l.Net <= 0 {
+			continue
+		}
+		d := models.Disbursement{RunID: runID, EmployeeID: l.EmployeeID, LineID: l.ID, EmployeeName: l.EmployeeName,
+			Amount: l.Net, Status: models.DisbursementPending}
+		if err := tx.Create(&d).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListDisbursements returns the payments of a finalized run by employee name.
+func (s *DisbursementService) ListDisbursements(runID uint) ([]models.Disbursement, error) {
+	var list []models.Disbursement
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := payableRun(tx, runID, false); err != nil {
+			return err
+		}
+		if err := ensureDisbursements(tx, runID); err != nil {
+			return err
+		}
+		return tx.Where("run_id = ?", runID).Order("employee_name, id").Find(&list).Error
+	})
+	return list, err
+}
+
+// ListMine returns an employee's payments, newest first.
+func (s *DisbursementService) ListMine(employeeID uint) ([]models.Disbursement, error) {
+	var list []models.Disbursement
+	return list, s.db.Where("employee_id = ?", employeeID).Order("id DESC").Find(&list).Error
+}
+
+// PaymentExport is the outcome of generating a payment file: the file, and the payments left out
+// with the problem of each.
+type PaymentExport struct {
+	File    *models.PaymentFile   `json:"file"`
+	Skipped []models.Disbursement `json:"skipped"`
+}
+
+// GenerateFile writes a payment file with the profile's format for the pending and failed
+// payments of a finalized run; those become EXPORTED with a fresh reference. Payments whose
+// bank details the format cannot use are left pending with the problem recorded. When none can
+// be paid, no file is written and the error wraps ErrNothingToExport.
+func (s *DisbursementService) GenerateFile(runID, profileID, by uint) (*PaymentExport, error) {
+	out := &PaymentExport{Skipped: []models.Disbursement{}}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		run, err := payableRun(tx, runID, true)
+		if err != nil {
+			return err
+		}
+		var profile models.PaymentProfile
+		if err := tx.First(&profile, profileID).Error; err != nil {
+			return fmt.Errorf("payment profile: %w", err)
+		}
+		format := bankfile.Format(profile.Format)
+		var layout *bankfile.CSVLayout
+		if format == bankfile.CSV {
+			if layout, err = bankfile.ParseCSVLayout(profile.CSVLayout); err != nil {
+				return err
+			}
+		}
+		if err := ensureDisbursements(tx, runID); err != nil {
+			return err
+		}
+		var due []models.Disbursement
+		if err := tx.Where("run_id = ? AND status IN ?", runID,
+			[]models.DisbursementStatus{models.DisbursementPending, models.DisbursementFailed}).
+			Order("employee_name, id").Find(&due).Error; err != nil {
+			return err
+		}
+		ids := make([]uint, len(due))
+		for i, d := range due {
+			ids[i] = d.EmployeeID
+		}
+		var emps []models.Employee
+		if err := tx.Where("id IN ?", ids).Find(&emps).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]*models.Employee, len(emps))
+		for i := range emps {
+			byID[emps[i].ID] = &emps[i]
+		}
+
+		batch := &bankfile.Batch{
+			Originator:  originator(&profile),
+			Description: "Salary " + run.Period.Name,
+			PayDate:     run.Period.PayDate,
+			CreatedAt:   time.Now(),
+		}
+		var included []models.Disbursement
+		for _, d := range due {
+			p := bankfile.Payment{
+				Reference: fmt.Sprintf("PR%d-E%d-%d", runID, d.EmployeeID, d.Attempts+1),
+				PayeeID:   strconv.FormatUint(uint64(d.EmployeeID), 10),
+				Name:      d.EmployeeName,
+				Amount:    d.Amount,
+			}
+			if e := byID[d.EmployeeID]; e != nil {
+				p.Name = e.Name
+				if e.BankAccountHolder != "" {
+					p.Name = e.BankAccountHolder
+				}
+				p.BankName, p.Account, p.Routing = e.BankName, e.BankAccountNumber, e.BankRoutingCode
+			}
+			if err := bankfile.CheckPayment(format, p); err != nil {
+				d.Problem = truncateText(err.Error(), 255)
+				if err := tx.Model(&models.Disbursement{}).Where("id = ?", d.ID).Update("problem", d.Problem).Error; err != nil {
+					return err
+				}
+				out.Skipped = append(out.Skipped, d)
+				continue
+			}
+			d.Reference = p.Reference
+			batch.Payments = append(batch.Payments, p)
+			included = append(included, d)
+		}
+		if len(included) == 0 {
+			return nil
+		}
+
+		file := models.PaymentFile{RunID: runID, ProfileID: profile.ID, Format: profile.Format,
+			Payments: len(included), CreatedBy: by}
+		for _, p := range batch.Payments {
+			file.Total += p.Amount
+		}
+		file.Total = roundMoney(file.Total)
+		if err := tx.Create(&file).Error; err != nil {
+			return err
+		}
+		batch.ID = fmt.Sprintf("PAYROLL-%d-%d", runID, file.ID)
+		content, err := bankfile.Write(format, batch, layout)
+		if err != nil {
+			return err
+		}
+		file.FileName = fmt.Sprintf("payroll-%s-%d%s", fileSlug(run.Period.Name), file.ID, format.Extension())
+		file.Content = string(content)
+		if err := tx.Save(&file).Error; err != nil {
+			return err
+		}
+		for _, d := range included {
+			if err := tx.Model(&models.Disbursement{}).Where("id = ?", d.ID).Updates(map[string]interface{}{
+				"status": models.DisbursementExported, "file_id": file.ID, "attempts": d.Attempts + 1,
+				"reference": d.Reference, "problem": "", "note": "", "updated_by": by,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		out.File = &file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.File == nil {
+		if len(out.Skipped) > 0 {
+			return out, fmt.Errorf("%w: %d payments have unusable bank details", ErrNothingToExport, len(out.Skipped))
+		}
+		return out, fmt.Errorf("%w: every payment of the run has been exported", ErrNothingToExport)
+	}
+	return out, nil
+}
+
+func truncateText(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// ListFiles returns the payment files of a run, newest first.
+func (s *DisbursementService) ListFiles(runID uint) ([]models.PaymentFile, error) {
+	var list []models.PaymentFile
+	return list, s.db.Omit("content").Where("run_id = ?", runID).Order("id DESC").Find(&list).Error
+}
+
+// OpenFile returns a payment file with its content.
+func (s *DisbursementService) OpenFile(id uint) (*models.PaymentFile, error) {
+	var f models.PaymentFile
+	if err := s.db.First(&f, id).Error; err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// VoidFile deletes a payment file the bank did not process, returning its payments to pending
+// so that the next file includes them. Files with payments reported paid or failed stay.
+func (s *DisbursementService) VoidFile(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var f models.PaymentFile
+		if err := tx.Omit("content").First(&f, id).Error; err != nil {
+			return err
+		}
+		var n int64
+		if err := tx.Model(&models.Disbursement{}).Where("file_id = ? AND status <> ?", id, models.DisbursementExported).
+			Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrFileReported
+		}
+		if err := tx.Model(&models.Disbursement{}).Where("file_id = ?", id).
+			Updates(map[string]interface{}{"status": models.DisbursementPending, "file_id": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.PaymentFile{}, id).Error
+	})
+}
+
+// MarkFilePaid records that the bank paid every exported payment of a file.
+func (s *DisbursementService) MarkFilePaid(id, by uint) ([]models.Disbursement, error) {
+	var list []models.Disbursement
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var f models.PaymentFile
+		if err := tx.Omit("content").First(&f, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Disbursement{}).Where("file_id = ? AND status = ?", id, models.DisbursementExported).
+			Updates(map[string]interface{}{"status": models.DisbursementPaid, "paid_at": time.Now(), "updated_by": by}).Error; err != nil {
+			return err
+		}
+		if err := settleRun(tx, f.RunID, by); err != nil {
+			return err
+		}
+		return tx.Where("file_id = ?", id).Order("employee_name, id").Find(&list).Error
+	})
+	return list, err
+}
+
+// SetStatus records what the bank reported for one payment: an exported payment is PAID or
+// FAILED, and a paid one can still fail when the bank returns it. A failed payment is included
+// in the next file of the run.
+func (s *DisbursementService) SetStatus(id uint, status models.DisbursementStatus, note string, by uint) (*models.Disbursement, error) {
+	var d models.Disbursement
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&d, id).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"status": status, "note": note, "updated_by": by}
+		switch {
+		case status == models.DisbursementPaid && d.Status == models.DisbursementExported:
+			updates["paid_at"] = time.Now()
+		case status == models.DisbursementFailed && (d.Status == models.DisbursementExported || d.Status == models.DisbursementPaid):
+			updates["paid_at"] = nil
+		case status != models.DisbursementPaid && status != models.DisbursementFailed:
+			return errors.New("status must be PAID or FAILED")
+		default:
+			return fmt.Errorf("a %s payment cannot become %s", d.Status, status)
+		}
+		if err := tx.Model(&d).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := settleRun(tx, d.RunID, by); err != nil {
+			return err
+		}
+		return tx.First(&d, id).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// settleRun marks a finalized run paid once every one of its payments is.
+func settleRun(tx *gorm.DB, runID, by uint) error {
+	var open int64
+	if err := tx.Model(&models.Disbursement{}).Where("run_id = ? AND status <> ?", runID, models.DisbursementPaid).
+		Count(&open).Error; err != nil || open > 0 {
+		return err
+	}
+	return tx.Model(&models.PayrollRun{}).Where("id = ? AND status = ?", runID, models.PayrollFinalized).
+		Updates(map[string]interface{}{"status": models.PayrollPaid, "paid_by": by, "paid_at": time.Now(),
+			"version": gorm.Expr("version + 1")}).Error
+}
@@ -0,0 +1,404 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/storage"
+)
+
+var (
+	ErrDocumentForbidden = errors.New("not allowed to access these documents")
+	ErrCategoryInUse     = errors.New("category still has documents")
+)
+
+// defaultReminderDays is used for categories saved without a reminder lead time.
+const defaultReminderDays = 30
+
+// Viewer is the user accessing documents: their id, role and current employee record (0 if none).
+type Viewer struct {
+	UserID     uint
+	Role       models.UserRole
+	EmployeeID uint
+}
+
+// canView applies the category's access rules to a document of emp. HR sees everything.
+func (v Viewer) canView(cat *models.DocumentCategory, emp *models.Employee) bool {
+	switch {
+	case v.Role == models.RoleHR:
+		return true
+	case cat.EmployeeCanView && v.EmployeeID != 0 && v.EmployeeID == emp.ID:
+		return true
+	case cat.ManagerCanView && v.EmployeeID != 0 && emp.ManagerID != nil && *emp.ManagerID == v.EmployeeID:
+		return true
+	}
+	for _, r := range cat.ViewRoles {
+		if r == v.Role {
+			return true
+		}
+	}
+	return false
+}
+
+// canUpload reports whether the viewer may add documents of the category for emp: HR always,
+// employees for themselves when the category allows it.
+func (v Viewer) canUpload(cat *models.DocumentCategory, emp *models.Employee) bool {
+	if v.Role == models.RoleHR {
+		return true
+	}
+	return cat.EmployeeCanUpload && v.EmployeeID != 0 && v.EmployeeID == emp.ID
+}
+
+// DocumentService stores employee documents and their versions, enforcing category access rules.
+type DocumentService struct {
+	db     *gorm.DB
+	store  storage.BlobStore
+	notify *NotificationService
+}
+
+func NewDocumentService(db *gorm.DB, store storage.BlobStore) *DocumentService {
+	return &DocumentService{db: db, store: store, notify: NewNotificationService(db)}
+}
+
+// Categories
+
+func (s *DocumentService) ListCategories() ([]models.DocumentCategory, error) {
+	var list []models.DocumentCategory
+	if err := s.db.Order("name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// SaveCategory creates the category (ID 0) or replaces an existing one.
+func (s *DocumentService) SaveCategory(c *models.DocumentCategory) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	for i, r := range c.ViewRoles {
+		c.ViewRoles[i] = models.UserRole(strings.ToUpper(string(r)))
+		if !models.ValidRole(c.ViewRoles[i]) {
+			return fmt.Errorf("invalid role %q", r)
+		}
+	}
+	if c.ReminderDays <= 0 {
+		c.ReminderDays = defaultReminderDays
+	}
+	if c.ID != 0 {
+		var cur models.DocumentCategory
+		if err := s.db.First(&cur, c.ID).Error; err != nil {
+			return err
+		}
+		c.CreatedAt = cur.CreatedAt
+	}
+	return s.db.Save(c).Error
+}
+
+// DeleteCategory removes a category that no document uses any more.
+func (s *DocumentService) DeleteCategory(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.DocumentCategory{}, id).Error; err != nil {
+			return err
+		}
+		var n int64
+		if err := tx.Model(&models.Document{}).Where("category_id = ?", id).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrCategoryInUse
+		}
+		return tx.Delete(&models.DocumentCategory{}, id).Error
+	})
+}
+
+// Documents
+
+// NewDocument describes an upload starting a new document.
+type NewDocument struct {
+	EmployeeID uint
+	CategoryID uint
+	Title      string
+	ExpiresAt  *time.Time
+	FileName   string
+	Note       string
+}
+
+func (s *DocumentService) load(categoryID, employeeID uint) (*models.DocumentCategory, *models.Employee, error) {
+	var cat models.DocumentCategory
+	if err := s.db.First(&cat, categoryID).Error; err != nil {
+		return nil, nil, err
+	}
+	var emp models.Employee
+	if err := s.db.First(&emp, employeeID).Error; err != nil {
+		return nil, nil, err
+	}
+	return &cat, &emp, nil
+}
+
+// storeFile validates the upload and writes it to blob storage as the given version.
+func (s *DocumentService) storeFile(employeeID uint, version int, fileName, note string, by uint, r io.Reader) (*models.DocumentVersion, error) {
+	data, ct, err := readUpload(r, MaxUploadBytes(), defaultAllowedTypes)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("documents/%d/%d%s", employeeID, time.Now().UnixNano(), filepath.Ext(fileName))
+	if err := s.store.Put(key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return &models.DocumentVersion{
+		Version:     version,
+		FileName:    filepath.Base(fileName),
+		ContentType: ct,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		StorageKey:  key,
+		Note:        strings.TrimSpace(note),
+		UploadedBy:  by,
+	}, nil
+}
+
+// Upload creates a document with its first version.
+func (s *DocumentService) Upload(v Viewer, d NewDocument, r io.Reader) (*models.Document, error) {
+	cat, emp, err := s.load(d.CategoryID, d.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	if !v.canUpload(cat, emp) {
+		return nil, ErrDocumentForbidden
+	}
+	if emp.Status == models.EmploymentTerminated && v.Role != models.RoleHR {
+		return nil, ErrEmployeeTerminated
+	}
+	title := strings.TrimSpace(d.Title)
+	if title == "" {
+		title = cat.Name
+	}
+	if cat.RequiresExpiry && d.ExpiresAt == nil {
+		return nil, fmt.Errorf("%s documents need an expiry date", cat.Name)
+	}
+	ver, err := s.storeFile(emp.ID, 1, d.FileName, d.Note, v.UserID, r)
+	if err != nil {
+		return nil, err
+	}
+	doc := models.Document{
+		EmployeeID:     emp.ID,
+		CategoryID:     cat.ID,
+		Title:          title,
+		ExpiresAt:      d.ExpiresAt,
+		CurrentVersion: 1,
+		UploadedBy:     v.UserID,
+		Versions:       []models.DocumentVersion{*ver},
+	}
+	if err := s.db.Create(&doc).Error; err != nil {
+		_ = s.store.Delete(ver.StorageKey)
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// AddVersion uploads a new file for a document. A new expiry date replaces the old one, e.g. for
+// a renewed work permit.
+func (s *DocumentService) AddVersion(v Viewer, id uint, fileName, note string, expiresAt *time.Time, r io.Reader) (*models.Document, error) {
+	var doc models.Document
+	if err := s.db.First(&doc, id).Error; err != nil {
+		return nil, err
+	}
+	cat, emp, err := s.load(doc.CategoryID, doc.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	if !v.canUpload(cat, emp) {
+		return nil, ErrDocumentForbidden
+	}
+	ver, err := s.storeFile(emp.ID, 0, fileName, note, v.UserID, r)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// the row lock serializes concurrent uploads so version numbers stay consecutive
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&doc, id).Error; err != nil {
+			return err
+		}
+		ver.DocumentID, ver.Version = doc.ID, doc.CurrentVersion+1
+		if err := tx.Create(ver).Error; err != nil {
+			return err
+		}
+		changes := map[string]interface{}{"current_version": ver.Version}
+		if expiresAt != nil {
+			changes["expires_at"] = *expiresAt
+		}
+		return tx.Model(&doc).Updates(changes).Error
+	})
+	if err != nil {
+		_ = s.store.Delete(ver.StorageKey)
+		return nil, err
+	}
+	return s.Get(v, id)
+}
+
+// List returns the employee's documents the viewer may see, with their versions.
+func (s *DocumentService) List(v Viewer, employeeID uint) ([]models.Document, error) {
+	var emp models.Employee
+	if err := s.db.First(&emp, employeeID).Error; err != nil {
+		return nil, err
+	}
+	var cats []models.DocumentCategory
+	if err := s.db.Find(&cats).Error; err != nil {
+		return nil, err
+	}
+	var visible []uint
+	for i := range cats {
+		if v.canView(&cats[i], &emp) {
+			visible = append(visible, cats[i].ID)
+		}
+	}
+	if len(visible) == 0 {
+		if v.Role != models.RoleHR && v.EmployeeID != emp.ID {
+			return nil, ErrDocumentForbidden
+		}
+		return []models.Document{}, nil
+	}
+	var list []models.Document
+	if err := s.db.Preload("Versions", func(db *gorm.DB) *gorm.DB { return db.Order("version DESC") }).
+		Where("employee_id = ? AND category_id IN ?", employeeID, visible).
+		Order("category_id, title, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Get returns a document with its versions, newest first.
+func (s *DocumentService) Get(v Viewer, id uint) (*models.Document, error) {
+	var doc models.Document
+	if err := s.db.Preload("Versions", func(db *gorm.DB) *gorm.DB { return db.Order("version DESC") }).
+		First(&doc, id).Error; err != nil {
+		return nil, err
+	}
+	cat, emp, err := s.load(doc.CategoryID, doc.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	if !v.canView(cat, emp) {
+		return nil, ErrDocumentForbidden
+	}
+	return &doc, nil
+}
+
+// Open returns a version's metadata and a reader over its file; version 0 means the current one.
+// Callers must close the reader.
+func (s *DocumentService) Open(v Viewer, id uint, version int) (*models.DocumentVersion, io.ReadCloser, error) {
+	doc, err := s.Get(v, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version == 0 {
+		version = doc.CurrentVersion
+	}
+	for i := range doc.Versions {
+		if doc.Versions[i].Version == version {
+			ver := &doc.Versions[i]
+			rc, err := s.store.Open(ver.StorageKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			return ver, rc, nil
+		}
+	}
+	return nil, nil, gorm.ErrRecordNotFound
+}
+
+// Delete removes a document with all its versions and files. Only HR may delete documents.
+func (s *DocumentService) Delete(v Viewer, id uint) error {
+	if v.Role != models.RoleHR {
+		return ErrDocumentForbidden
+	}
+	var versions []models.DocumentVersion
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.Document{}, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", id).Find(&versions).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", id).Delete(&models.DocumentVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Document{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+	for _, ver := range versions {
+		if err := s.store.Delete(ver.StorageKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExpiryReminders runs on the scheduler. HR, and the employee when their category lets them see
+// it, are reminded of a document the category's ReminderDays before it expires and told again once
+// it has expired. Each reminder is sent once per expiry date, so a renewal triggers new ones.
+func (s *DocumentService) ExpiryReminders(now time.Time) error {
+	y, m, d := now.UTC().Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	var docs []models.Document
+	if err := s.db.Select("documents.*").
+		Joins("JOIN document_categories c ON c.id = documents.category_id").
+		Joins("JOIN employees e ON e.id = documents.employee_id").
+		Where("documents.expires_at IS NOT NULL AND documents.expires_at <= ?::date + c.reminder_days", day).
+		Where("e.status <> ?", models.EmploymentTerminated).
+		Find(&docs).Error; err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := s.remind(&doc, day); err != nil {
+			return fmt.Errorf("document %d: %w", doc.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *DocumentService) remind(doc *models.Document, day time.Time) error {
+	cat, emp, err := s.load(doc.CategoryID, doc.EmployeeID)
+	if err != nil {
+		return err
+	}
+	exp := doc.ExpiresAt.Format("2006-01-02")
+	n := models.Notification{
+		Kind:       "DOCUMENT_EXPIRING",
+		Title:      fmt.Sprintf("%s of %s expires on %s", doc.Title, emp.Name, exp),
+		Body:       fmt.Sprintf("Upload a renewed %s before it expires.", strings.ToLower(cat.Name)),
+		EntityType: "document",
+		EntityID:   &doc.ID,
+	}
+	key := fmt.Sprintf("document-expiring:%d:%s", doc.ID, exp)
+	if doc.ExpiresAt.Before(day) {
+		n.Kind = "DOCUMENT_EXPIRED"
+		n.Title = fmt.Sprintf("%s of %s expired on %s", doc.Title, emp.Name, exp)
+		key = fmt.Sprintf("document-expired:%d:%s", doc.ID, exp)
+	}
+	n.DedupKey = &key
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.notify.NotifyRole(tx, models.RoleHR, n); err != nil {
+			return err
+		}
+		if !cat.EmployeeCanView {
+			return nil
+		}
+		return s.notify.Notify(tx, forUser(n, emp.UserID))
+	})
+}
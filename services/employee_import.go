@@ -0,0 +1,427 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+)
+
+// Import row statuses.
+const (
+	ImportRowCreated = "created"
+	ImportRowValid   = "valid"
+	ImportRowError   = "error"
+)
+
+var errDryRunRollback = errors.New("dry run")
+
+// ImportRowResult reports the outcome of one data row; Row is the 1-based line in the file.
+type ImportRowResult struct {
+	Row        int      `json:"row"`
+	Username   string   `json:"username,omitempty"`
+	Status     string   `json:"status"`
+	Errors     []string `json:"errors,omitempty"`
+	UserID     uint     `json:"user_id,omitempty"`
+	EmployeeID uint     `json:"employee_id,omitempty"`
+}
+
+// ImportReport summarizes an import. Nothing is committed unless every row is valid and DryRun is false.
+type ImportReport struct {
+	DryRun    bool              `json:"dry_run"`
+	Committed bool              `json:"committed"`
+	Total     int               `json:"total"`
+	Valid     int               `json:"valid"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows"`
+}
+
+// ReadEmployeeRows parses a CSV or XLSX file into records keyed by normalized header
+// ("Manager Username" -> "manager_username"). For XLSX the first sheet is read.
+func ReadEmployeeRows(r io.Reader, format string) ([]map[string]string, error) {
+	var table [][]string
+	switch strings.ToLower(format) {
+	case "csv":
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+		rows, err := cr.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		table = rows
+	case "xlsx":
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xlsx: %w", err)
+		}
+		defer f.Close()
+		rows, err := f.GetRows(f.GetSheetName(0))
+		if err != nil {
+			return nil, err
+		}
+		table = rows
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if len(table) == 0 {
+		return nil, errors.New("file has no header row")
+	}
+	header := make([]string, len(table[0]))
+	for i, h := range table[0] {
+		header[i] = strings.ReplaceAll(strings.ToLower(NormalizeName(strings.TrimPrefix(h, "\ufeff"))), " ", "_")
+	}
+	out := make([]map[string]string, 0, len(table)-1)
+	for _, rec := range table[1:] {
+		m := make(map[string]string, len(header))
+		for i, h := range header {
+			if i < len(rec) {
+				m[h] = strings.TrimSpace(rec[i])
+			}
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+type EmployeeImportService struct {
+	db   *gorm.DB
+	auth *AuthService
+}
+
+func NewEmployeeImportService(db *gorm.DB) *EmployeeImportService {
+	return &EmployeeImportService{db: db, auth: NewAuthService(db)}
+}
+
+// importRow is a validated row waiting to be created.
+type importRow struct {
+	res        *ImportRowResult
+	user       *models.User // existing user to link, nil when one is created
+	password   string
+	role       models.UserRole
+	employee   models.Employee
+	managerKey string // username of a manager created by the same file
+}
+
+// Import validates every row and creates users and employees in a single transaction.
+// Any invalid row, or dryRun, rolls the whole import back; the report still lists every row.
+func (s *EmployeeImportService) Import(records []map[string]string, dryRun bool) (*ImportReport, error) {
+	report := &ImportReport{DryRun: dryRun, Total: len(records), Rows: make([]ImportRowResult, len(records))}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		defs, err := customFieldDefinitions(tx)
+		if err != nil {
+			return err
+		}
+		rows, err := s.validate(tx, defs, records, report.Rows)
+		if err != nil {
+			return err
+		}
+		if !hasErrors(rows) {
+			if err := s.create(tx, rows); err != nil {
+				return err
+			}
+		}
+		for _, r := range rows {
+			if len(r.res.Errors) > 0 {
+				r.res.Status = ImportRowError
+				report.Failed++
+			} else {
+				report.Valid++
+			}
+		}
+		if report.Failed > 0 || dryRun {
+			return errDryRunRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRunRollback) {
+		return nil, err
+	}
+	report.Committed = err == nil
+	if !report.Committed {
+		// ids from a rolled back transaction do not exist
+		for i := range report.Rows {
+			report.Rows[i].UserID, report.Rows[i].EmployeeID = 0, 0
+			if report.Rows[i].Status == ImportRowCreated {
+				report.Rows[i].Status = ImportRowValid
+			}
+		}
+	}
+	return report, nil
+}
+
+func hasErrors(rows []*importRow) bool {
+	for _, r := range rows {
+		if len(r.res.Errors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportEmployee builds the employee of one import record and lists what is wrong with it.
+// Columns named cf_<key> set custom fields.
+func ImportEmployee(defs []models.CustomFieldDefinition, rec map[string]string) (models.Employee, []string) {
+	var e models.Employee
+	var errs []string
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+	e.Name = NormalizeName(rec["name"])
+	e.Position, e.Department = rec["position"], rec["department"]
+	e.Grade, e.Location = rec["grade"], rec["location"]
+	if e.Name == "" {
+		fail("name is required")
+	}
+	if NormalizeName(e.Position) == "" {
+		fail("position is required")
+	}
+	if NormalizeName(e.Department) == "" {
+		fail("department is required")
+	}
+	if sal, err := strconv.ParseFloat(strings.ReplaceAll(rec["salary"], ",", ""), 64); err != nil || sal <= 0 || math.IsInf(sal, 0) || math.IsNaN(sal) {
+		fail("salary %q is not a positive number", rec["salary"])
+	} else {
+		e.Salary = sal
+	}
+	custom := models.CustomFieldValues{}
+	for col, v := range rec {
+		if strings.HasPrefix(col, CustomFieldColumnPrefix) {
+			custom[strings.TrimPrefix(col, CustomFieldColumnPrefix)] = v
+		}
+	}
+	if values, err := ApplyCustomFields(defs, nil, custom, true); err != nil {
+		fail("%v", err)
+	} else {
+		e.CustomFields = values
+	}
+	return e, errs
+}
+
+// ImportDuplicates tracks the users claimed by the rows of an import file. Usernames compare
+// case-insensitively.
+type ImportDuplicates struct {
+	users map[string]int
+	ids   map[uint]int
+}
+
+func NewImportDuplicates() *ImportDuplicates {
+	return &ImportDuplicates{users: map[string]int{}, ids: map[uint]int{}}
+}
+
+// Claim records that row links username and, for an existing user, userID (0 for a new user).
+// It returns an error for each that an earlier row already claimed.
+func (d *ImportDuplicates) Claim(row int, username string, userID uint) []string {
+	var errs []string
+	if username != "" {
+		key := strings.ToLower(username)
+		if prev, dup := d.users[key]; dup {
+			errs = append(errs, fmt.Sprintf("duplicate of row %d (username %q)", prev, username))
+		} else {
+			d.users[key] = row
+		}
+	}
+	if userID != 0 {
+		if prev, dup := d.ids[userID]; dup {
+			errs = append(errs, fmt.Sprintf("duplicate of row %d (user %d)", prev, userID))
+		} else {
+			d.ids[userID] = row
+		}
+	}
+	return errs
+}
+
+// Has tells whether a row of the file links username.
+func (d *ImportDuplicates) Has(username string) bool {
+	_, ok := d.users[strings.ToLower(username)]
+	return ok
+}
+
+// ImportLink is a row's lower-cased username and the username of its manager when the manager is
+// another row of the same file.
+type ImportLink struct {
+	Username string
+	Manager  string
+}
+
+// ImportOrder returns the order to create rows in so that managers defined in the file exist
+// before their reports, keeping file order otherwise. Rows whose manager chain loops are returned
+// as cyclic instead.
+func ImportOrder(rows []ImportLink) (order, cyclic []int) {
+	pos := map[string]int{}
+	for i, r := range rows {
+		if r.Username != "" {
+			pos[r.Username] = i
+		}
+	}
+	done := make([]bool, len(rows))
+	pending := make([]int, len(rows))
+	for i := range pending {
+		pending[i] = i
+	}
+	for len(pending) > 0 {
+		var next []int
+		for _, i := range pending {
+			if j, ok := pos[rows[i].Manager]; rows[i].Manager != "" && ok && !done[j] {
+				next = append(next, i)
+				continue
+			}
+			done[i] = true
+			order = append(order, i)
+		}
+		if len(next) == len(pending) {
+			return order, next
+		}
+		pending = next
+	}
+	return order, nil
+}
+
+// validate checks every record, filling results in place, and returns the rows to create.
+func (s *EmployeeImportService) validate(tx *gorm.DB, defs []models.CustomFieldDefinition, records []map[string]string, results []ImportRowResult) ([]*importRow, error) {
+	seen := NewImportDuplicates()
+	rows := make([]*importRow, 0, len(records))
+	for i, rec := range records {
+		res := &results[i]
+		*res = ImportRowResult{Row: i + 2, Username: rec["username"], Status: ImportRowValid}
+		fail := func(format string, args ...interface{}) {
+			res.Errors = append(res.Errors, fmt.Sprintf(format, args...))
+		}
+		row := &importRow{res: res}
+		row.employee, res.Errors = ImportEmployee(defs, rec)
+		e := &row.employee
+
+		// user link: an existing user by user_id or username, or a new user with a password
+		username := strings.TrimSpace(rec["username"])
+		switch {
+		case rec["user_id"] != "":
+			id, err := strconv.ParseUint(rec["user_id"], 10, 64)
+			var u models.User
+			if err != nil || tx.First(&u, id).Error != nil {
+				fail("user_id %q does not exist", rec["user_id"])
+				break
+			}
+			row.user, username, res.Username = &u, u.Username, u.Username
+		case username != "":
+			// usernames match case-insensitively, as duplicates and managers do
+			var u models.User
+			err := tx.Where("lower(username) = lower(?)", username).First(&u).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if err == nil {
+				row.user, username, res.Username = &u, u.Username, u.Username
+			} else if rec["password"] == "" {
+				fail("user %q does not exist and no password was given to create it", username)
+			} else {
+				row.password = rec["password"]
+				row.role = models.RoleEmployee
+				if rec["role"] != "" {
+					row.role = models.UserRole(strings.ToUpper(rec["role"]))
+				}
+				if !models.ValidRole(row.role) {
+					fail("unknown role %q", rec["role"])
+				}
+			}
+		default:
+			fail("missing user link: set user_id or username")
+		}
+		var userID uint
+		if row.user != nil {
+			userID = row.user.ID
+		}
+		res.Errors = append(res.Errors, seen.Claim(res.Row, username, userID)...)
+		if row.user != nil {
+			var active, former int64
+			if err := tx.Model(&models.Employee{}).Where("user_id = ? AND status <> ?", row.user.ID, models.EmploymentTerminated).Count(&active).Error; err != nil {
+				return nil, err
+			}
+			if err := tx.Model(&models.Employee{}).Where("user_id = ? AND status = ?", row.user.ID, models.EmploymentTerminated).Count(&former).Error; err != nil {
+				return nil, err
+			}
+			if active > 0 {
+				fail("user %q already has an employee record", row.user.Username)
+			} else if former > 0 {
+				fail("user %q is a former employee; rehire them instead", row.user.Username)
+			}
+		}
+
+		// manager: an existing employee's username, or another row of this file. A rehired user
+		// also has terminated records; only the current one can manage.
+		if m := strings.TrimSpace(rec["manager_username"]); m != "" {
+			var mgr models.Employee
+			err := tx.Joins("JOIN users ON users.id = employees.user_id").
+				Where("lower(users.username) = lower(?) AND employees.status <> ?", m, models.EmploymentTerminated).First(&mgr).Error
+			switch {
+			case err == nil:
+				e.ManagerID = &mgr.ID
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				row.managerKey = strings.ToLower(m)
+			default:
+				return nil, err
+			}
+		}
+		rows = append(rows, row)
+	}
+	for _, row := range rows {
+		if row.managerKey != "" {
+			if !seen.Has(row.managerKey) {
+				row.res.Errors = append(row.res.Errors, fmt.Sprintf("manager %q not found", row.managerKey))
+			}
+		}
+	}
+	return rows, nil
+}
+
+// create inserts rows so that managers defined in the file exist before their reports.
+func (s *EmployeeImportService) create(tx *gorm.DB, rows []*importRow) error {
+	links := make([]ImportLink, len(rows))
+	for i, row := range rows {
+		links[i] = ImportLink{Username: strings.ToLower(row.res.Username), Manager: row.managerKey}
+	}
+	order, cyclic := ImportOrder(links)
+	if len(cyclic) > 0 {
+		for _, i := range cyclic {
+			rows[i].res.Errors = append(rows[i].res.Errors, "manager chain contains a cycle")
+		}
+		return nil
+	}
+	emps := NewEmployeeService(tx)
+	created := map[string]uint{} // lower-cased username -> employee id
+	for _, i := range order {
+		row := rows[i]
+		if row.managerKey != "" {
+			id, ok := created[row.managerKey]
+			if !ok {
+				return fmt.Errorf("row %d: manager %q was not created", row.res.Row, row.managerKey)
+			}
+			row.employee.ManagerID = &id
+		}
+		user := row.user
+		if user == nil {
+			hash, err := s.auth.HashPassword(row.password)
+			if err != nil {
+				return err
+			}
+			user = &models.User{Username: row.res.Username, PasswordHash: hash, Role: row.role}
+			if err := tx.Create(user).Error; err != nil {
+				return fmt.Errorf("row %d: %w", row.res.Row, err)
+			}
+		}
+		row.employee.UserID = user.ID
+		if err := emps.Create(&row.employee); err != nil {
+			return fmt.Errorf("row %d: %w", row.res.Row, err)
+		}
+		row.res.UserID, row.res.EmployeeID, row.res.Status = user.ID, row.employee.ID, ImportRowCreated
+		created[strings.ToLower(user.Username)] = row.employee.ID
+	}
+	return nil
+}
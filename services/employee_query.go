@@ -0,0 +1,359 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+)
+
+// ErrInvalidQuery wraps problems with the query itself, such as an unknown sort or filter field.
+var ErrInvalidQuery = errors.New("invalid query")
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// EmployeeQuery describes a filtered, sorted and paginated employee listing.
+// Empty filters match everything except terminated employees, which are only listed when Statuses
+// asks for them; Sort entries are json field names, "-" prefixed for descending. CustomFields filters
+// by custom field key; an employee matches when its value equals any of the given values.
+// BankAccount finds employees by account number through its blind index. Role, when set, limits
+// filters and sorting to fields that role may see; see checkAccess.
+type EmployeeQuery struct {
+	Search        string
+	DepartmentIDs []uint
+	PositionIDs   []uint
+	ManagerIDs    []uint
+	Statuses      []models.EmploymentStatus
+	Sort          []string
+	Limit         int
+	Offset        int
+	Fields        []string
+	AsOf          *time.Time
+	CustomFields  map[string][]string
+	BankAccount   string
+	Role          models.UserRole
+}
+
+// EmployeePage is one page of results plus the total number of matches.
+type EmployeePage struct {
+	Items  []models.Employee
+	Total  int64
+	Limit  int
+	Offset int
+}
+
+// PageMeta is returned alongside paginated list responses.
+type PageMeta struct {
+	Total      int64 `json:"total"`
+	Limit      int   `json:"limit"`
+	Offset     int   `json:"offset"`
+	NextOffset *int  `json:"next_offset,omitempty"`
+}
+
+func (p *EmployeePage) Meta() PageMeta {
+	m := PageMeta{Total: p.Total, Limit: p.Limit, Offset: p.Offset}
+	if next := p.Offset + len(p.Items); int64(next) < p.Total {
+		m.NextOffset = &next
+	}
+	return m
+}
+
+// sortableEmployeeFields excludes salary: it is encrypted, so the database cannot order by it.
+var sortableEmployeeFields = map[string]bool{
+	"id": true, "name": true, "created_at": true, "updated_at": true, "position": true,
+	"department": true, "grade": true, "location": true, "status": true,
+}
+
+// jobColumns are the columns reconstructed from job_records for as-of queries.
+var jobColumns = []string{"position", "position_id", "department", "department_id", "grade", "grade_id",
+	"salary", "manager_id", "location", "location_id"}
+
+// EmployeeColumns lists the scalar json field names of models.Employee, which equal its column names.
+func EmployeeColumns() []string {
+	t := reflect.TypeOf(models.Employee{})
+	cols := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || f.Tag.Get("gorm") == "-" {
+			continue
+		}
+		k := f.Type.Kind()
+		if k == reflect.Ptr {
+			k = f.Type.Elem().Kind()
+		}
+		if k == reflect.Slice && f.Type.Elem().Kind() != reflect.Uint8 || k == reflect.Struct && f.Type != reflect.TypeOf(time.Time{}) {
+			continue
+		}
+		cols = append(cols, name)
+	}
+	return cols
+}
+
+// SearchTSQuery turns free text into a prefix-matching tsquery: "jo smi" -> "jo:* & smi:*".
+func SearchTSQuery(search string) string {
+	words := strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// employeeSource is the relation listings read from: the employees table, or for as-of queries the
+// employees joined with the job record in effect on that date.
+func (s *EmployeeService) employeeSource(asOf *time.Time) *gorm.DB {
+	if asOf == nil {
+		return s.db.Table("employees")
+	}
+	cols := make([]string, 0)
+	for _, c := range EmployeeColumns() {
+		src := "e." + c
+		for _, j := range jobColumns {
+			if c == j {
+				src = "jr." + c
+			}
+		}
+		cols = append(cols, src+" AS "+c)
+	}
+	sub := s.db.Raw(fmt.Sprintf(`SELECT %s FROM employees e JOIN (
+			SELECT DISTINCT ON (employee_id) * FROM job_records WHERE effective_date <= ?
+			ORDER BY employee_id, effective_date DESC, id DESC) jr ON jr.employee_id = e.id`, strings.Join(cols, ", ")),
+		asOf.Format("2006-01-02"))
+	return s.db.Table("(?) AS employees", sub)
+}
+
+// filtered applies q's search and filters, without sorting or pagination.
+func (s *EmployeeService) filtered(q EmployeeQuery) (*gorm.DB, error) {
+	tx := s.employeeSource(q.AsOf)
+	if ts := SearchTSQuery(q.Search); ts != "" {
+		tx = tx.Where("to_tsvector('simple', employees.name) @@ to_tsquery('simple', ?)", ts)
+	}
+	if len(q.DepartmentIDs) > 0 {
+		tx = tx.Where("employees.department_id IN ?", q.DepartmentIDs)
+	}
+	if len(q.PositionIDs) > 0 {
+		tx = tx.Where("employees.position_id IN ?", q.PositionIDs)
+	}
+	if len(q.ManagerIDs) > 0 {
+		tx = tx.Where("employees.manager_id IN ?", q.ManagerIDs)
+	}
+	switch {
+	case len(q.Statuses) > 0:
+		tx = tx.Where("employees.status IN ?", q.Statuses)
+	case q.AsOf != nil:
+		// employed on that date: not yet terminated then
+		tx = tx.Where("(employees.termination_date IS NULL OR employees.termination_date > ?)", q.AsOf.Format("2006-01-02"))
+	default:
+		tx = tx.Where("employees.status <> ?", models.EmploymentTerminated)
+	}
+	if q.BankAccount != "" {
+		// without a keyring there is no index, and an empty one would match every employee
+		idx := bankAccountIndex(q.BankAccount)
+		if idx == "" || NormalizeCode(q.BankAccount) == "" {
+			return nil, fmt.Errorf("%w: bank account lookup needs a bank account number and encryption to be configured", ErrInvalidQuery)
+		}
+		// the index column is not part of as-of rows, so match on the employee table itself
+		tx = tx.Where("employees.id IN (SELECT id FROM employees WHERE bank_account_index = ?)", idx)
+	}
+	if len(q.CustomFields) > 0 {
+		defs, err := customFieldDefinitions(s.db)
+		if err != nil {
+			return nil, err
+		}
+		if tx, err = customFieldFilter(tx, defs, q.CustomFields); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+	}
+	return tx, nil
+}
+
+// checkAccess rejects filters and sorting that would reveal fields q.Role cannot see on every
+// employee, which results are redacted for: only directory fields sort for callers without
+// PermViewEmployees, custom fields must be visible to the role, and bank account lookups need
+// salary access like the bank columns.
+func (s *EmployeeService) checkAccess(q EmployeeQuery) error {
+	if q.BankAccount != "" && !models.HasPermission(q.Role, models.PermViewSalary) {
+		return errors.New("cannot filter by bank_account")
+	}
+	for _, f := range q.Sort {
+		f = strings.TrimPrefix(f, "-")
+		if !models.EmployeeFieldVisible(models.AudiencePeer, q.Role, f) &&
+			!models.HasPermission(q.Role, models.PermViewEmployees) {
+			return fmt.Errorf("cannot sort by %q", f)
+		}
+	}
+	if len(q.CustomFields) == 0 {
+		return nil
+	}
+	defs, err := customFieldDefinitions(s.db)
+	if err != nil {
+		return err
+	}
+	visible := map[string]bool{}
+	for i := range defs {
+		visible[defs[i].Key] = customFieldVisible(&defs[i], q.Role, false)
+	}
+	for k := range q.CustomFields {
+		if !visible[k] {
+			return fmt.Errorf("unknown custom field %q", k)
+		}
+	}
+	return nil
+}
+
+// customFieldFilter adds one jsonb containment condition per filtered key, so the GIN index on
+// custom_fields serves it. Values are normalized like stored ones: "42" matches 42.0, "yes" matches true.
+func customFieldFilter(tx *gorm.DB, defs []models.CustomFieldDefinition, filters map[string][]string) (*gorm.DB, error) {
+	byKey := make(map[string]*models.CustomFieldDefinition, len(defs))
+	for i := range defs {
+		byKey[defs[i].Key] = &defs[i]
+	}
+	keys := make([]string, 0, len(filters))
+	for k := range filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		d, ok := byKey[k]
+		if !ok {
+			return nil, fmt.Errorf("unknown custom field %q", k)
+		}
+		conds := make([]string, 0, len(filters[k]))
+		args := make([]interface{}, 0, len(filters[k]))
+		for _, raw := range filters[k] {
+			v, err := customFieldValue(d, raw)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				return nil, fmt.Errorf("empty filter value for custom field %q", k)
+			}
+			b, err := json.Marshal(map[string]interface{}{k: v})
+			if err != nil {
+				return nil, err
+			}
+			conds = append(conds, "employees.custom_fields @> ?::jsonb")
+			args = append(args, string(b))
+		}
+		if len(conds) > 0 {
+			tx = tx.Where("("+strings.Join(conds, " OR ")+")", args...)
+		}
+	}
+	return tx, nil
+}
+
+// EmployeeOrder builds the ORDER BY clause for a sort list, ending with id so pages are stable. A
+// leading "-" sorts a field descending; only sortableEmployeeFields reach the SQL.
+func EmployeeOrder(sort []string) (string, error) {
+	order := make([]string, 0, len(sort)+1)
+	for _, f := range sort {
+		dir := "ASC"
+		if strings.HasPrefix(f, "-") {
+			f, dir = f[1:], "DESC"
+		}
+		if !sortableEmployeeFields[f] {
+			return "", fmt.Errorf("cannot sort by %q", f)
+		}
+		order = append(order, "employees."+f+" "+dir)
+	}
+	order = append(order, "employees.id ASC")
+	return strings.Join(order, ", "), nil
+}
+
+// Search runs q with all filtering, sorting and pagination done in SQL.
+func (s *EmployeeService) Search(q EmployeeQuery) (*EmployeePage, error) {
+	if q.Role != "" {
+		if err := s.checkAccess(q); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+	}
+	tx, err := s.filtered(q)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &EmployeePage{Limit: q.Limit, Offset: q.Offset}
+	if page.Limit <= 0 {
+		page.Limit = defaultPageSize
+	}
+	if page.Limit > maxPageSize {
+		page.Limit = maxPageSize
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+	if err := tx.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	order, err := EmployeeOrder(q.Sort)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	tx = tx.Order(order)
+
+	if len(q.Fields) > 0 {
+		// id and user_id tell response shaping how the caller relates to each row
+		cols := []string{"employees.id", "employees.user_id"}
+		for _, f := range q.Fields {
+			cols = append(cols, "employees."+f)
+		}
+		tx = tx.Select(cols)
+	}
+	if err := tx.Limit(page.Limit).Offset(page.Offset).Find(&page.Items).Error; err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// ValidateFields checks requested sparse fieldset names against the employee columns.
+func ValidateFields(fields []string) error {
+	known := map[string]bool{}
+	for _, c := range EmployeeColumns() {
+		known[c] = true
+	}
+	for _, f := range fields {
+		if !known[f] {
+			return fmt.Errorf("unknown field %q", f)
+		}
+	}
+	return nil
+}
+
+// SparseFields reduces each item of a list to the requested json fields. With no fields the items
+// are returned as-is. Items can be already shaped generic JSON objects.
+func SparseFields(items interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return items, nil
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var list []map[string]interface{}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, errors.New("items are not a list of objects")
+	}
+	out := make([]map[string]interface{}, 0, len(list))
+	for _, full := range list {
+		m := make(map[string]interface{}, len(fields))
+		for _, f := range fields {
+			m[f] = full[f]
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
@@ -1,29 +1,567 @@
 package services
 
 import (
+    "errors"
+    "time"
+
     "gorm.io/gorm"
+    "github.com/example/hrms-backend/encryption"
     "github.com/example/hrms-backend/models"
 )
 
-type EmployeeService struct { db *gorm.DB }
+// JobChange lists the job fields to change; nil fields keep their value as of the effective date.
+// Org entities may be referenced by id or by name; unknown names create the entity.
+type JobChange struct {
+    EffectiveDate time.Time
+    Position      *string
+    PositionID    *uint
+    Department    *string
+    DepartmentID  *uint
+    Grade         *string
+    GradeID       *uint
+    Salary        *float64
+    ManagerID     *uint
+    Location      *string
+    LocationID    *uint
+    Reason        string
+    CreatedBy     uint
+}
+
+func (c JobChange) empty() bool {
+    return c.Position == nil && c.PositionID == nil && c.Department == nil && c.DepartmentID == nil &&
+        c.Grade == nil && c.GradeID == nil && c.Salary == nil && c.ManagerID == nil &&
+        c.Location == nil && c.LocationID == nil
+}
+
+func deref(s *string) string {
+    if s == nil { return "" }
+    return *s
+}
 
-func NewEmployeeService(db *gorm.DB) *EmployeeService { return &EmployeeService{db: db} }
+var (
+    ErrEmployeeTerminated    = errors.New("employee is terminated")
+    ErrEmployeeNotTerminated = errors.New("employee is not terminated")
+    ErrActiveEmployeeExists  = errors.New("user already has an active employee record")
+)
 
-func (s *EmployeeService) Create(e *models.Employee) error { return s.db.Create(e).Error }
-func (s *EmployeeService) Update(id uint, e *models.Employee) error {
-    e.ID = id
-    return s.db.Model(&models.Employee{ID: id}).Updates(e).Error
+type EmployeeService struct {
+    db  *gorm.DB
+    org *OrgService
 }
-func (s *EmployeeService) Delete(id uint) error { return s.db.Delete(&models.Employee{}, id).Error }
+
+func NewEmployeeService(db *gorm.DB) *EmployeeService { return &EmployeeService{db: db, org: NewOrgService(db)} }
+
+func today() time.Time {
+    y, m, d := time.Now().UTC().Date()
+    return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func jobRecordFor(e *models.Employee) models.JobRecord {
+    return models.JobRecord{
+        EmployeeID:   e.ID,
+        Position:     e.Position,
+        PositionID:   e.PositionID,
+        Department:   e.Department,
+        DepartmentID: e.DepartmentID,
+        Grade:        e.Grade,
+        GradeID:      e.GradeID,
+        Salary:       e.Salary,
+        ManagerID:    e.ManagerID,
+        Location:     e.Location,
+        LocationID:   e.LocationID,
+    }
+}
+
+// applyOrgChange resolves the org references in c and writes their ids and canonical names to rec.
+func (s *EmployeeService) applyOrgChange(tx *gorm.DB, c JobChange, rec *models.JobRecord) error {
+    if c.DepartmentID != nil || c.Department != nil {
+        d, err := s.org.ResolveDepartment(tx, OrgRef{ID: c.DepartmentID, Name: deref(c.Department)})
+        if err != nil { return err }
+        rec.DepartmentID, rec.Department = &d.ID, d.Name
+    }
+    if c.PositionID != nil || c.Position != nil {
+        p, err := s.org.ResolvePosition(tx, OrgRef{ID: c.PositionID, Name: deref(c.Position)})
+        if err != nil { return err }
+        rec.PositionID, rec.Position = &p.ID, p.Title
+    }
+    if c.GradeID != nil || c.Grade != nil {
+        g, err := s.org.ResolveGrade(tx, OrgRef{ID: c.GradeID, Name: deref(c.Grade)})
+        if err != nil { return err }
+        rec.GradeID, rec.Grade = &g.ID, g.Code
+    }
+    if c.LocationID != nil || c.Location != nil {
+        l, err := s.org.ResolveLocation(tx, OrgRef{ID: c.LocationID, Name: deref(c.Location)})
+        if err != nil { return err }
+        rec.LocationID, rec.Location = &l.ID, l.Name
+    }
+    return nil
+}
+
+// resolveOrg links a new employee's department, position, grade and location to org entities.
+// Department and position are required; grade and location are optional.
+func (s *EmployeeService) resolveOrg(tx *gorm.DB, e *models.Employee) error {
+    c := JobChange{DepartmentID: e.DepartmentID, PositionID: e.PositionID, GradeID: e.GradeID, LocationID: e.LocationID}
+    if e.DepartmentID == nil { c.Department = &e.Department }
+    if e.PositionID == nil { c.Position = &e.Position }
+    if e.GradeID == nil && NormalizeName(e.Grade) != "" { c.Grade = &e.Grade }
+    if e.LocationID == nil && NormalizeName(e.Location) != "" { c.Location = &e.Location }
+    rec := jobRecordFor(e)
+    if err := s.applyOrgChange(tx, c, &rec); err != nil { return err }
+    e.DepartmentID, e.Department = rec.DepartmentID, rec.Department
+    e.PositionID, e.Position = rec.PositionID, rec.Position
+    e.GradeID, e.Grade = rec.GradeID, rec.Grade
+    e.LocationID, e.Location = rec.LocationID, rec.Location
+    return nil
+}
+
+// Create inserts the employee together with its initial job record, effective today.
+// Termination and rehire fields are managed by Terminate and Rehire and are ignored here.
+func (s *EmployeeService) Create(e *models.Employee) error {
+    if e.Status == models.EmploymentTerminated { return errors.New("cannot create a terminated employee") }
+    e.TerminationDate, e.TerminationReason, e.TerminatedBy, e.PreviousEmployeeID = nil, "", nil, nil
+    if err := startProbation(e); err != nil { return err }
+    return s.db.Transaction(func(tx *gorm.DB) error { return s.create(tx, e, "hire") })
+}
+
+// startProbation puts a new hire with a probation end date on probation. Confirmation fields are
+// set by ProbationService.Confirm only.
+func startProbation(e *models.Employee) error {
+    e.ConfirmedAt, e.ConfirmedBy = nil, nil
+    if e.ProbationEndDate == nil {
+        if e.Status == models.EmploymentProbation { return errors.New("probation_end_date is required for status PROBATION") }
+        return nil
+    }
+    if !e.ProbationEndDate.After(today()) { return errors.New("probation end date must be in the future") }
+    e.Status = models.EmploymentProbation
+    return nil
+}
+
+func (s *EmployeeService) create(tx *gorm.DB, e *models.Employee, reason string) error {
+    var n int64
+    if err := tx.Model(&models.Employee{}).Where("user_id = ? AND status <> ?", e.UserID, models.EmploymentTerminated).Count(&n).Error; err != nil { return err }
+    if n > 0 { return ErrActiveEmployeeExists }
+    if err := guardPayroll(tx, today(), time.Time{}); err != nil { return err }
+    if err := s.resolveOrg(tx, e); err != nil { return err }
+    defs, err := customFieldDefinitions(tx)
+    if err != nil { return err }
+    if e.CustomFields, err = ApplyCustomFields(defs, nil, e.CustomFields, true); err != nil { return err }
+    if err := cleanBankDetails(e); err != nil { return err }
+    e.BankAccountIndex = bankAccountIndex(e.BankAccountNumber)
+    if err := tx.Create(e).Error; err != nil { return err }
+    now := time.Now()
+    rec := jobRecordFor(e)
+    rec.EffectiveDate = today()
+    rec.Reason = reason
+    rec.AppliedAt = &now
+    return tx.Create(&rec).Error
+}
+
+// Update changes personal fields in place. Job fields that differ are recorded as a job change
+// effective today, so the previous values stay in the employee's history. Custom fields, when
+// given, are merged into the current ones; a null value clears a field.
+func (s *EmployeeService) Update(id uint, e *models.Employee, by uint) error {
+    cur, err := s.Get(id)
+    if err != nil { return err }
+    if cur.Status == models.EmploymentTerminated { return ErrEmployeeTerminated }
+    change := JobChange{EffectiveDate: today(), Reason: "profile update", CreatedBy: by}
+    if orgDiffers(e.PositionID, e.Position, cur.PositionID, cur.Position) { change.PositionID, change.Position = e.PositionID, &e.Position }
+    if orgDiffers(e.DepartmentID, e.Department, cur.DepartmentID, cur.Department) { change.DepartmentID, change.Department = e.DepartmentID, &e.Department }
+    if orgDiffers(e.GradeID, e.Grade, cur.GradeID, cur.Grade) { change.GradeID, change.Grade = e.GradeID, &e.Grade }
+    if orgDiffers(e.LocationID, e.Location, cur.LocationID, cur.Location) { change.LocationID, change.Location = e.LocationID, &e.Location }
+    if e.Salary != 0 && e.Salary != cur.Salary { change.Salary = &e.Salary }
+    if e.ManagerID != nil && (cur.ManagerID == nil || *e.ManagerID != *cur.ManagerID) { change.ManagerID = e.ManagerID }
+
+    if e.CustomFields != nil {
+        defs, err := customFieldDefinitions(s.db)
+        if err != nil { return err }
+        values, err := ApplyCustomFields(defs, cur.CustomFields, e.CustomFields, true)
+        if err != nil { return err }
+        if err := s.db.Model(&models.Employee{ID: id}).Select("custom_fields").Updates(&models.Employee{CustomFields: values}).Error; err != nil { return err }
+    }
+    if e.Name != "" && e.Name != cur.Name {
+        if err := s.db.Model(&models.Employee{ID: id}).Update("name", e.Name).Error; err != nil { return err }
+    }
+    if !change.empty() {
+        if _, err := s.RecordJobChange(id, change); err != nil { return err }
+    }
+    return nil
+}
+
+// orgDiffers reports whether an update references a different org entity (by id, else by name) than the current one.
+func orgDiffers(id *uint, name string, curID *uint, curName string) bool {
+    if id != nil { return curID == nil || *id != *curID }
+    return name != "" && NameKey(name) != NameKey(curName)
+}
+
 func (s *EmployeeService) Get(id uint) (*models.Employee, error) {
     var m models.Employee
     if err := s.db.First(&m, id).Error; err != nil { return nil, err }
     return &m, nil
 }
-func (s *EmployeeService) List() ([]models.Employee, error) {
-    var list []models.Employee
-    if err := s.db.Find(&list).Error; err != nil { return nil, err }
-    return list, nil
+
+// GetByUser returns the user's current, non-terminated employee record.
+func (s *EmployeeService) GetByUser(userID uint) (*models.Employee, error) {
+    var m models.Employee
+    if err := s.db.Where("user_id = ? AND status <> ?", userID, models.EmploymentTerminated).First(&m).Error; err != nil { return nil, err }
+    return &m, nil
+}
+
+// Termination describes an employee's departure. Date defaults to today and may not be in the future.
+type Termination struct {
+    Date   time.Time
+    Reason string
+    By     uint
+}
+
+// Terminate archives the employee instead of deleting it, so attendance, leave and job history
+// stay linked. Pending leaves and approved leaves starting after the termination date are
+// cancelled, job changes not yet in effect are dropped, and finance is told about loans still
+// being repaid.
+func (s *EmployeeService) Terminate(id uint, t Termination) (*models.Employee, error) {
+    if t.Date.IsZero() { t.Date = today() }
+    if t.Date.After(today()) { return nil, errors.New("termination date cannot be in the future") }
+    if t.Reason == "" { return nil, errors.New("termination reason is required") }
+    var e models.Employee
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.First(&e, id).Error; err != nil { return err }
+        if e.Status == models.EmploymentTerminated { return ErrEmployeeTerminated }
+        if err := guardPayroll(tx, t.Date, time.Time{}); err != nil { return err }
+        res := tx.Model(&models.Employee{}).Where("id = ? AND version = ?", e.ID, e.Version).
+            Updates(map[string]interface{}{
+                "status":             models.EmploymentTerminated,
+                "termination_date":   t.Date,
+                "termination_reason": t.Reason,
+                "terminated_by":      t.By,
+                "version":            e.Version + 1,
+            })
+        if res.Error != nil { return res.Error }
+        if res.RowsAffected == 0 { return errors.New("employee was modified concurrently") }
+        day := t.Date.Format("2006-01-02")
+        if err := tx.Model(&models.Leave{}).
+            Where("employee_id = ? AND (status = ? OR (status = ? AND start_date > ?))", id, models.LeavePending, models.LeaveApproved, day).
+            Updates(map[string]interface{}{
+                "status":           models.LeaveCancelled,
+                "decided_by":       t.By,
+                "decided_at":       time.Now(),
+                "decision_comment": "cancelled: employee terminated",
+                "version":          gorm.Expr("version + 1"),
+            }).Error; err != nil {
+            return err
+        }
+        if err := tx.Where("employee_id = ? AND applied_at IS NULL AND effective_date > ?", id, day).
+            Delete(&models.JobRecord{}).Error; err != nil {
+            return err
+        }
+        if err := notifyExitLoans(tx, &e, t.Date); err != nil { return err }
+        return tx.First(&e, id).Error
+    })
+    if err != nil { return nil, err }
+    return &e, nil
 }
 
+// Restore reverses a termination, e.g. one recorded by mistake, and re-enables a login disabled by
+// offboarding. An employee terminated during an unconfirmed probation goes back on probation.
+// Leaves cancelled by the termination stay cancelled.
+func (s *EmployeeService) Restore(id uint) (*models.Employee, error) {
+    var e models.Employee
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.First(&e, id).Error; err != nil { return err }
+        if e.Status != models.EmploymentTerminated { return ErrEmployeeNotTerminated }
+        var n int64
+        if err := tx.Model(&models.Employee{}).Where("user_id = ? AND status <> ?", e.UserID, models.EmploymentTerminated).Count(&n).Error; err != nil { return err }
+        if n > 0 { return ErrActiveEmployeeExists }
+        if e.TerminationDate != nil {
+            if err := guardPayroll(tx, *e.TerminationDate, time.Time{}); err != nil { return err }
+        }
+        status := models.EmploymentActive
+        if e.ProbationEndDate != nil && e.ConfirmedAt == nil { status = models.EmploymentProbation }
+        res := tx.Model(&models.Employee{}).Where("id = ? AND version = ?", e.ID, e.Version).
+            Updates(map[string]interface{}{
+                "status":             status,
+                "termination_date":   nil,
+                "termination_reason": "",
+                "terminated_by":      nil,
+                "version":            e.Version + 1,
+            })
+        if res.Error != nil { return res.Error }
+        if res.RowsAffected == 0 { return errors.New("employee was modified concurrently") }
+        if err := enableUser(tx, e.UserID); err != nil { return err }
+        return tx.First(&e, id).Error
+    })
+    if err != nil { return nil, err }
+    return &e, nil
+}
 
+// enableUser lifts a login block set when the employee was offboarded.
+func enableUser(tx *gorm.DB, userID uint) error {
+    return tx.Model(&models.User{}).Where("id = ? AND disabled", userID).
+        Updates(map[string]interface{}{"disabled": false, "disabled_at": nil}).Error
+}
+
+// Rehire starts a new tenure for a terminated employee's user as a new employee row linked to
+// the old one by PreviousEmployeeID. Job fields left empty in e are taken from the old record;
+// personal details, bank account and emergency contacts are carried over.
+func (s *EmployeeService) Rehire(id uint, e *models.Employee) (*models.Employee, error) {
+    old, err := s.Get(id)
+    if err != nil { return nil, err }
+    if old.Status != models.EmploymentTerminated { return nil, ErrEmployeeNotTerminated }
+    hire := models.Employee{
+        UserID:             old.UserID,
+        Name:               old.Name,
+        Position:           e.Position,
+        PositionID:         e.PositionID,
+        Department:         e.Department,
+        DepartmentID:       e.DepartmentID,
+        Grade:              e.Grade,
+        GradeID:            e.GradeID,
+        Salary:             e.Salary,
+        ManagerID:          e.ManagerID,
+        Location:           e.Location,
+        LocationID:         e.LocationID,
+        ProbationEndDate:   e.ProbationEndDate,
+        PreviousEmployeeID: &old.ID,
+        PreferredName:      old.PreferredName,
+        Phone:              old.Phone,
+        PersonalEmail:      old.PersonalEmail,
+        AddressLine1:       old.AddressLine1,
+        AddressLine2:       old.AddressLine2,
+        City:               old.City,
+        PostalCode:         old.PostalCode,
+        Country:            old.Country,
+        BankAccountHolder:  old.BankAccountHolder,
+        BankName:           old.BankName,
+        BankAccountNumber:  old.BankAccountNumber,
+        BankRoutingCode:    old.BankRoutingCode,
+    }
+    if e.Name != "" { hire.Name = e.Name }
+    // custom fields carry over; given values override them
+    hire.CustomFields = models.CustomFieldValues{}
+    for k, v := range old.CustomFields { hire.CustomFields[k] = v }
+    for k, v := range e.CustomFields { hire.CustomFields[k] = v }
+    if hire.Position == "" && hire.PositionID == nil { hire.Position, hire.PositionID = old.Position, old.PositionID }
+    if hire.Department == "" && hire.DepartmentID == nil { hire.Department, hire.DepartmentID = old.Department, old.DepartmentID }
+    if hire.Grade == "" && hire.GradeID == nil { hire.Grade, hire.GradeID = old.Grade, old.GradeID }
+    if hire.Location == "" && hire.LocationID == nil { hire.Location, hire.LocationID = old.Location, old.LocationID }
+    if hire.Salary == 0 { hire.Salary = old.Salary }
+    if hire.Salary < 0 { return nil, errors.New("salary must not be negative") }
+    if err := startProbation(&hire); err != nil { return nil, err }
+    if hire.ManagerID != nil {
+        if err := s.activeEmployee(s.db, *hire.ManagerID); err != nil { return nil, errors.New("manager not found") }
+    }
+    err = s.db.Transaction(func(tx *gorm.DB) error {
+        if err := s.create(tx, &hire, "rehire"); err != nil { return err }
+        var contacts []models.EmergencyContact
+        if err := tx.Where("employee_id = ?", old.ID).Find(&contacts).Error; err != nil { return err }
+        for i := range contacts { contacts[i].ID, contacts[i].EmployeeID = 0, hire.ID }
+        if len(contacts) > 0 {
+            if err := tx.Create(&contacts).Error; err != nil { return err }
+        }
+        return enableUser(tx, hire.UserID)
+    })
+    if err != nil { return nil, err }
+    return &hire, nil
+}
+
+// activeEmployee fails unless id is an existing, non-terminated employee.
+func (s *EmployeeService) activeEmployee(tx *gorm.DB, id uint) error {
+    return tx.Where("id = ? AND status <> ?", id, models.EmploymentTerminated).First(&models.Employee{}).Error
+}
+
+// RecordJobChange stores a job record built from the state as of c.EffectiveDate plus c's fields,
+// and carries the change into later records that do not override it (see CarryJobChange).
+// Changes that are already effective are applied to the employee immediately.
+func (s *EmployeeService) RecordJobChange(employeeID uint, c JobChange) (*models.JobRecord, error) {
+    if c.empty() {
+        return nil, errors.New("no job fields to change")
+    }
+    if c.Salary != nil && *c.Salary < 0 {
+        return nil, errors.New("salary must not be negative")
+    }
+    if cur, err := s.Get(employeeID); err != nil {
+        return nil, err
+    } else if cur.Status == models.EmploymentTerminated {
+        return nil, ErrEmployeeTerminated
+    }
+    if c.ManagerID != nil {
+        if *c.ManagerID == employeeID {
+            return nil, errors.New("employee cannot be their own manager")
+        }
+        if err := s.activeEmployee(s.db, *c.ManagerID); err != nil {
+            return nil, errors.New("manager not found")
+        }
+    }
+    var rec models.JobRecord
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        if err := guardPayroll(tx, c.EffectiveDate, time.Time{}); err != nil { return err }
+        var base models.JobRecord
+        err := tx.Where("employee_id = ? AND effective_date <= ?", employeeID, c.EffectiveDate).
+            Order("effective_date desc, id desc").First(&base).Error
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            // backdated before the first record: start from the current employee row
+            var e models.Employee
+            if err := tx.First(&e, employeeID).Error; err != nil { return err }
+            base = jobRecordFor(&e)
+        } else if err != nil {
+            return err
+        }
+        rec = base
+        rec.ID = 0
+        rec.CreatedAt, rec.UpdatedAt = time.Time{}, time.Time{}
+        rec.AppliedAt = nil
+        rec.EffectiveDate = c.EffectiveDate
+        rec.Reason = c.Reason
+        rec.CreatedBy = c.CreatedBy
+        if err := s.applyOrgChange(tx, c, &rec); err != nil { return err }
+        if c.Salary != nil { rec.Salary = *c.Salary }
+        if c.ManagerID != nil { rec.ManagerID = c.ManagerID }
+        if err := tx.Create(&rec).Error; err != nil { return err }
+        if base.ID == 0 {
+            // before the first record, which states the whole job: nothing to carry
+            return nil
+        }
+        var later []models.JobRecord
+        if err := tx.Where("employee_id = ? AND effective_date > ?", employeeID, c.EffectiveDate).
+            Order("effective_date, id").Find(&later).Error; err != nil { return err }
+        for _, i := range CarryJobChange(c, base, rec, later) {
+            if err := tx.Save(&later[i]).Error; err != nil { return err }
+        }
+        return nil
+    })
+    if err != nil { return nil, err }
+    if !rec.EffectiveDate.After(today()) {
+        now := time.Now()
+        if err := s.applyDueFor(employeeID, now); err != nil { return nil, err }
+        rec.AppliedAt = &now
+    }
+    return &rec, nil
+}
+
+// jobField is a group of job record fields a JobChange sets together.
+type jobField struct {
+    set  func(c JobChange) bool
+    same func(a, b *models.JobRecord) bool
+    copy func(dst, src *models.JobRecord)
+}
+
+func sameID(a, b *uint) bool { return a == nil && b == nil || a != nil && b != nil && *a == *b }
+
+var jobFields = []jobField{
+    {
+        func(c JobChange) bool { return c.Position != nil || c.PositionID != nil },
+        func(a, b *models.JobRecord) bool { return a.Position == b.Position && sameID(a.PositionID, b.PositionID) },
+        func(dst, src *models.JobRecord) { dst.Position, dst.PositionID = src.Position, src.PositionID },
+    },
+    {
+        func(c JobChange) bool { return c.Department != nil || c.DepartmentID != nil },
+        func(a, b *models.JobRecord) bool { return a.Department == b.Department && sameID(a.DepartmentID, b.DepartmentID) },
+        func(dst, src *models.JobRecord) { dst.Department, dst.DepartmentID = src.Department, src.DepartmentID },
+    },
+    {
+        func(c JobChange) bool { return c.Grade != nil || c.GradeID != nil },
+        func(a, b *models.JobRecord) bool { return a.Grade == b.Grade && sameID(a.GradeID, b.GradeID) },
+        func(dst, src *models.JobRecord) { dst.Grade, dst.GradeID = src.Grade, src.GradeID },
+    },
+    {
+        func(c JobChange) bool { return c.Location != nil || c.LocationID != nil },
+        func(a, b *models.JobRecord) bool { return a.Location == b.Location && sameID(a.LocationID, b.LocationID) },
+        func(dst, src *models.JobRecord) { dst.Location, dst.LocationID = src.Location, src.LocationID },
+    },
+    {
+        func(c JobChange) bool { return c.Salary != nil },
+        func(a, b *models.JobRecord) bool { return a.Salary == b.Salary },
+        func(dst, src *models.JobRecord) { dst.Salary = src.Salary },
+    },
+    {
+        func(c JobChange) bool { return c.ManagerID != nil },
+        func(a, b *models.JobRecord) bool { return sameID(a.ManagerID, b.ManagerID) },
+        func(dst, src *models.JobRecord) { dst.ManagerID = src.ManagerID },
+    },
+}
+
+// CarryJobChange carries the fields c sets, as recorded in rec, forward into the records dated
+// after it, oldest first. base is the record rec was built from. A field is carried until a later
+// record changes it from the value before it; later records are otherwise snapshots that would
+// undo the change when they take effect. It returns the indexes of the records it changed.
+func CarryJobChange(c JobChange, base, rec models.JobRecord, later []models.JobRecord) []int {
+    var carry []jobField
+    for _, f := range jobFields {
+        if f.set(c) { carry = append(carry, f) }
+    }
+    var changed []int
+    prev := base
+    for i := range later {
+        if len(carry) == 0 { break }
+        old := later[i]
+        kept := carry[:0]
+        for _, f := range carry {
+            if !f.same(&old, &prev) { continue } // overridden from here on
+            if !f.same(&later[i], &rec) {
+                f.copy(&later[i], &rec)
+                if len(changed) == 0 || changed[len(changed)-1] != i { changed = append(changed, i) }
+            }
+            kept = append(kept, f)
+        }
+        carry, prev = kept, old
+    }
+    return changed
+}
+
+// ApplyDueJobChanges mirrors every reached, not yet applied job record onto its employee.
+// It is run periodically by the scheduler so future-dated changes take effect on their date.
+func (s *EmployeeService) ApplyDueJobChanges(now time.Time) error {
+    var ids []uint
+    if err := s.db.Model(&models.JobRecord{}).
+        Where("applied_at IS NULL AND effective_date <= ?", now.UTC().Format("2006-01-02")).
+        Distinct().Pluck("employee_id", &ids).Error; err != nil {
+        return err
+    }
+    for _, id := range ids {
+        if err := s.applyDueFor(id, now); err != nil { return err }
+    }
+    return nil
+}
+
+// applyDueFor copies the latest reached job record onto the employee and marks all reached records applied.
+func (s *EmployeeService) applyDueFor(employeeID uint, now time.Time) error {
+    day := now.UTC().Format("2006-01-02")
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        var cur models.JobRecord
+        if err := tx.Where("employee_id = ? AND effective_date <= ?", employeeID, day).
+            Order("effective_date desc, id desc").First(&cur).Error; err != nil {
+            return err
+        }
+        var e models.Employee
+        if err := tx.First(&e, employeeID).Error; err != nil { return err }
+        // map updates bypass the column serializer
+        salary, err := encryption.SealValue(cur.Salary)
+        if err != nil { return err }
+        res := tx.Model(&models.Employee{}).Where("id = ? AND version = ?", e.ID, e.Version).
+            Updates(map[string]interface{}{
+                "position":      cur.Position,
+                "position_id":   cur.PositionID,
+                "department":    cur.Department,
+                "department_id": cur.DepartmentID,
+                "grade":         cur.Grade,
+                "grade_id":      cur.GradeID,
+                "salary":        salary,
+                "manager_id":    cur.ManagerID,
+                "location":      cur.Location,
+                "location_id":   cur.LocationID,
+                "version":       e.Version + 1,
+            })
+        if res.Error != nil { return res.Error }
+        if res.RowsAffected == 0 {
+            return errors.New("employee was modified concurrently")
+        }
+        return tx.Model(&models.JobRecord{}).
+            Where("employee_id = ? AND effective_date <= ? AND applied_at IS NULL", employeeID, day).
+            Update("applied_at", now).Error
+    })
+}
+
+// Timeline returns every job record of the employee, oldest first, including future-dated ones.
+func (s *EmployeeService) Timeline(employeeID uint) ([]models.JobRecord, error) {
+    var list []models.JobRecord
+    if err := s.db.Where("employee_id = ?", employeeID).Order("effective_date, id").Find(&list).Error; err != nil { return nil, err }
+    return list, nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/encryption"
+	"github.com/example/hrms-backend/models"
+)
+
+// encryptedTable lists the columns of a table stored through the encrypted serializer.
+type encryptedTable struct {
+	table   string
+	columns []string
+}
+
+var encryptedTables = []encryptedTable{
+	{table: "employees", columns: []string{"salary", "bank_account_number", "bank_routing_code"}},
+	{table: "job_records", columns: []string{"salary"}},
+	{table: "profile_change_requests", columns: []string{"changes", "previous"}},
+	{table: "payroll_lines", columns: []string{"gross", "deductions", "net", "items", "reimbursements"}},
+	{table: "payslip_preferences", columns: []string{"password"}},
+	{table: "tax_declarations", columns: []string{"amount"}},
+	{table: "payment_profiles", columns: []string{"account", "routing"}},
+	{table: "disbursements", columns: []string{"amount"}},
+	{table: "payment_files", columns: []string{"content"}},
+	{table: "compensation_proposals", columns: []string{"current_salary", "proposed_salary"}},
+	{table: "final_settlements", columns: []string{"annual_salary", "earnings", "deductions", "reimbursements", "net", "items"}},
+}
+
+const reencryptBatchSize = 500
+
+// EncryptionService brings encrypted columns up to date with the keyring: legacy plaintext is
+// encrypted and values under a retired key are re-wrapped with the primary key. Once it has
+// run, retired keys can be removed from the configuration.
+type EncryptionService struct {
+	db *gorm.DB
+}
+
+func NewEncryptionService(db *gorm.DB) *EncryptionService { return &EncryptionService{db: db} }
+
+// Rotate re-encrypts in the background; see Reencrypt.
+func (s *EncryptionService) Rotate(now time.Time) error {
+	n, err := s.Reencrypt()
+	if n > 0 {
+		log.Printf("encryption: re-encrypted %d rows under key %q", n, encryption.Default().Primary())
+	}
+	return err
+}
+
+// Reencrypt updates every row holding plaintext or a value under a non-primary key, in batches,
+// and backfills missing blind indexes. It returns the number of rows changed. Without a keyring
+// there is nothing to do.
+func (s *EncryptionService) Reencrypt() (int, error) {
+	ring := encryption.Default()
+	if ring == nil {
+		return 0, nil
+	}
+	total := 0
+	for _, t := range encryptedTables {
+		n, err := s.reencryptTable(ring, t)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", t.table, err)
+		}
+	}
+	n, err := s.backfillBankAccountIndex()
+	return total + n, err
+}
+
+func (s *EncryptionService) reencryptTable(ring *encryption.Keyring, t encryptedTable) (int, error) {
+	// "_" is a LIKE wildcard and may appear in key ids
+	current := strings.ReplaceAll(encryption.KeyPrefix(ring.Primary()), "_", `\_`) + "%"
+	stale := make([]string, 0, len(t.columns))
+	args := []interface{}{}
+	for _, c := range t.columns {
+		stale = append(stale, fmt.Sprintf("(%s <> '' AND %s NOT LIKE ?)", c, c))
+		args = append(args, current)
+	}
+	query := fmt.Sprintf("SELECT id, %s FROM %s WHERE id > ? AND (%s) ORDER BY id LIMIT %d",
+		strings.Join(t.columns, ", "), t.table, strings.Join(stale, " OR "), reencryptBatchSize)
+
+	changed := 0
+	var lastID uint
+	for {
+		rows, err := s.db.Raw(query, append([]interface{}{lastID}, args...)...).Rows()
+		if err != nil {
+			return changed, err
+		}
+		type row struct {
+			id   uint
+			vals []sql.NullString
+		}
+		var batch []row
+		for rows.Next() {
+			r := row{vals: make([]sql.NullString, len(t.columns))}
+			dest := []interface{}{&r.id}
+			for i := range r.vals {
+				dest = append(dest, &r.vals[i])
+			}
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return changed, err
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return changed, err
+		}
+		for _, r := range batch {
+			ok, err := s.rewrapRow(ring, t, r.id, r.vals)
+			if err != nil {
+				return changed, fmt.Errorf("row %d: %w", r.id, err)
+			}
+			if ok {
+				changed++
+			}
+			lastID = r.id
+		}
+		if len(batch) < reencryptBatchSize {
+			return changed, nil
+		}
+	}
+}
+
+// rewrapRow updates one row, guarded by its old values so a concurrent write is not overwritten;
+// such a row is picked up again by the next run.
+func (s *EncryptionService) rewrapRow(ring *encryption.Keyring, t encryptedTable, id uint, vals []sql.NullString) (bool, error) {
+	sets := []string{}
+	guards := []string{"id = ?"}
+	setArgs, guardArgs := []interface{}{}, []interface{}{id}
+	for i, c := range t.columns {
+		if !vals[i].Valid || vals[i].String == "" {
+			continue
+		}
+		out, changed, err := ring.Rewrap(vals[i].String)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", c, err)
+		}
+		if !changed {
+			continue
+		}
+		sets = append(sets, c+" = ?")
+		setArgs = append(setArgs, out)
+		guards = append(guards, c+" = ?")
+		guardArgs = append(guardArgs, vals[i].String)
+	}
+	if len(sets) == 0 {
+		return false, nil
+	}
+	res := s.db.Exec(fmt.Sprintf("UPDATE %s SET %s WHERE %s", t.table, strings.Join(sets, ", "), strings.Join(guards, " AND ")),
+		append(setArgs, guardArgs...)...)
+	return res.RowsAffected > 0, res.Error
+}
+
+// backfillBankAccountIndex indexes account numbers stored before blind indexes existed, and
+// re-indexes those indexed before numbers were normalized.
+func (s *EncryptionService) backfillBankAccountIndex() (int, error) {
+	changed := 0
+	var emps []models.Employee
+	err := s.db.Select("id", "bank_account_number", "bank_account_index").
+		Where("bank_account_number <> ''").
+		FindInBatches(&emps, reencryptBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, e := range emps {
+				idx := bankAccountIndex(e.BankAccountNumber)
+				if idx == e.BankAccountIndex {
+					continue
+				}
+				if err := s.db.Model(&models.Employee{}).Where("id = ?", e.ID).
+					UpdateColumn("bank_account_index", idx).Error; err != nil {
+					return err
+				}
+				changed++
+			}
+			return nil
+		}).Error
+	return changed, err
+}
@@ -0,0 +1,801 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/storage"
+)
+
+var (
+	ErrClaimNotDraft       = errors.New("only draft claims can be changed")
+	ErrClaimStatus         = errors.New("the claim is not awaiting this decision")
+	ErrNotClaimApprover    = errors.New("only the claimant's manager or HR can decide this claim")
+	ErrOwnClaim            = errors.New("you cannot decide your own claim")
+	ErrExpensePolicy       = errors.New("expense policy")
+	ErrExpenseCategoryUsed = errors.New("the category is used by expense items; archive it instead")
+	ErrNoExchangeRate      = errors.New("no exchange rate")
+	ErrClaimReasonMissing  = errors.New("a reason is required to reject a claim")
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// BaseCurrency is the currency expense claims are reimbursed in and category limits are set in,
+// from BASE_CURRENCY; it defaults to USD.
+func BaseCurrency() string {
+	if v := strings.ToUpper(strings.TrimSpace(os.Getenv("BASE_CURRENCY"))); currencyCode.MatchString(v) {
+		return v
+	}
+	return "USD"
+}
+
+// ExpenseService handles expense claims: categories and their policy limits, exchange rates,
+// claims with receipts, and the manager then finance approval. Approved claims are reimbursed by
+// the next payroll run or paid separately.
+type ExpenseService struct {
+	db     *gorm.DB
+	store  storage.BlobStore
+	notify *NotificationService
+}
+
+func NewExpenseService(db *gorm.DB, store storage.BlobStore) *ExpenseService {
+	return &ExpenseService{db: db, store: store, notify: NewNotificationService(db)}
+}
+
+// Categories
+
+func (s *ExpenseService) ListCategories(includeArchived bool) ([]models.ExpenseCategory, error) {
+	tx := s.db.Order("name")
+	if !includeArchived {
+		tx = tx.Where("NOT archived")
+	}
+	var list []models.ExpenseCategory
+	return list, tx.Find(&list).Error
+}
+
+// SaveCategory creates c, or replaces it when c.ID is set.
+func (s *ExpenseService) SaveCategory(c *models.ExpenseCategory) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.PerItemLimit < 0 || c.MonthlyLimit < 0 || c.ReceiptThreshold < 0 {
+		return errors.New("limits cannot be negative")
+	}
+	if c.ID == 0 {
+		return s.db.Create(c).Error
+	}
+	var cur models.ExpenseCategory
+	if err := s.db.First(&cur, c.ID).Error; err != nil {
+		return err
+	}
+	c.CreatedAt = cur.CreatedAt
+	return s.db.Save(c).Error
+}
+
+func (s *ExpenseService) DeleteCategory(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var c models.ExpenseCategory
+		if err := tx.First(&c, id).Error; err != nil {
+			return err
+		}
+		var n int64
+		if err := tx.Model(&models.ExpenseItem{}).Where("category_id = ?", id).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrExpenseCategoryUsed
+		}
+		return tx.Delete(&c).Error
+	})
+}
+
+// Exchange rates
+
+// ListRates returns the stored rates, newest first, optionally of one currency.
+func (s *ExpenseService) ListRates(currency string) ([]models.CurrencyRate, error) {
+	tx := s.db.Order("currency, effective_date DESC")
+	if currency != "" {
+		tx = tx.Where("currency = ?", strings.ToUpper(currency))
+	}
+	var list []models.CurrencyRate
+	return list, tx.Find(&list).Error
+}
+
+// SaveRate stores r, replacing the rate of the same currency and date. Rates change the base
+// amounts of draft claims only; submitted claims keep the rates they were submitted with.
+func (s *ExpenseService) SaveRate(r *models.CurrencyRate) error {
+	r.ID = 0
+	r.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+	if !currencyCode.MatchString(r.Currency) {
+		return errors.New("currency must be a three-letter ISO code")
+	}
+	if r.Currency == BaseCurrency() {
+		return fmt.Errorf("%s is the base currency", r.Currency)
+	}
+	if r.Rate <= 0 {
+		return errors.New("rate must be positive")
+	}
+	if r.EffectiveDate.IsZero() {
+		return errors.New("effective_date is required")
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}, {Name: "effective_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(r).Error
+}
+
+func (s *ExpenseService) DeleteRate(id uint) error {
+	res := s.db.Delete(&models.CurrencyRate{}, id)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return res.Error
+}
+
+// exchangeRate is the latest rate of currency on or before day.
+func exchangeRate(tx *gorm.DB, currency string, day time.Time) (float64, error) {
+	if currency == BaseCurrency() {
+		return 1, nil
+	}
+	var r models.CurrencyRate
+	err := tx.Where("currency = ? AND effective_date <= ?", currency, dateKey(day)).
+		Order("effective_date DESC").First(&r).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("%w for %s on %s", ErrNoExchangeRate, currency, dateKey(day))
+	}
+	return r.Rate, err
+}
+
+// price converts the claim's items to the base currency at the rates of their dates and
+// totals them.
+func price(tx *gorm.DB, claim *models.ExpenseClaim) error {
+	claim.Currency = BaseCurrency()
+	claim.Total = 0
+	for i := range claim.Items {
+		it := &claim.Items[i]
+		rate, err := exchangeRate(tx, it.Currency, it.Date)
+		if err != nil {
+			return err
+		}
+		it.Rate = rate
+		it.BaseAmount = roundMoney(it.Amount * rate)
+		claim.Total += it.BaseAmount
+	}
+	claim.Total = roundMoney(claim.Total)
+	return nil
+}
+
+// Policy
+
+// ExpenseMonth keys what an employee claimed in a category in a calendar month ("2006-01").
+type ExpenseMonth struct {
+	CategoryID uint
+	Month      string
+}
+
+// CheckExpensePolicy checks priced items against their categories. claimed holds what the
+// employee claimed per category and month on other claims that are submitted, approved or paid.
+// All violations are reported together.
+func CheckExpensePolicy(items []models.ExpenseItem, categories map[uint]models.ExpenseCategory, claimed map[ExpenseMonth]float64) error {
+	var problems []string
+	months := map[ExpenseMonth]float64{}
+	for i, it := range items {
+		c, ok := categories[it.CategoryID]
+		if !ok || c.Archived {
+			problems = append(problems, fmt.Sprintf("item %d: category %d is not available", i+1, it.CategoryID))
+			continue
+		}
+		if c.PerItemLimit > 0 && it.BaseAmount > c.PerItemLimit {
+			problems = append(problems, fmt.Sprintf("item %d: %.2f exceeds the %s limit of %.2f per item", i+1, it.BaseAmount, c.Name, c.PerItemLimit))
+		}
+		if c.ReceiptRequired && it.BaseAmount > c.ReceiptThreshold && len(it.Receipts) == 0 {
+			problems = append(problems, fmt.Sprintf("item %d: %s expenses over %.2f need a receipt", i+1, c.Name, c.ReceiptThreshold))
+		}
+		months[ExpenseMonth{CategoryID: it.CategoryID, Month: it.Date.Format("2006-01")}] += it.BaseAmount
+	}
+	keys := make([]ExpenseMonth, 0, len(months))
+	for k := range months {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Month != keys[j].Month {
+			return keys[i].Month < keys[j].Month
+		}
+		return keys[i].CategoryID < keys[j].CategoryID
+	})
+	for _, k := range keys {
+		c := categories[k.CategoryID]
+		if total := roundMoney(months[k] + claimed[k]); c.MonthlyLimit > 0 && total > c.MonthlyLimit {
+			problems = append(problems, fmt.Sprintf("%s in %s: %.2f exceeds the monthly limit of %.2f", c.Name, k.Month, total, c.MonthlyLimit))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrExpensePolicy, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkPolicy loads what CheckExpensePolicy needs for claim.
+func checkPolicy(tx *gorm.DB, claim *models.ExpenseClaim) error {
+	var cats []models.ExpenseCategory
+	if err := tx.Find(&cats).Error; err != nil {
+		return err
+	}
+	categories := make(map[uint]models.ExpenseCategory, len(cats))
+	for _, c := range cats {
+		categories[c.ID] = c
+	}
+	claimed := map[ExpenseMonth]float64{}
+	for _, it := range claim.Items {
+		k := ExpenseMonth{CategoryID: it.CategoryID, Month: it.Date.Format("2006-01")}
+		if _, done := claimed[k]; done {
+			continue
+		}
+		from := time.Date(it.Date.Year(), it.Date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		var sum float64
+		if err := tx.Model(&models.ExpenseItem{}).
+			Joins("JOIN expense_claims ON expense_claims.id = expense_items.claim_id").
+			Where("expense_claims.employee_id = ? AND expense_claims.id <> ? AND expense_claims.status NOT IN ?",
+				claim.EmployeeID, claim.ID, []models.ExpenseStatus{models.ExpenseDraft, models.ExpenseRejected}).
+			Where("expense_items.category_id = ? AND expense_items.date >= ? AND expense_items.date < ?",
+				it.CategoryID, dateKey(from), dateKey(from.AddDate(0, 1, 0))).
+			Select("COALESCE(SUM(expense_items.base_amount), 0)").Scan(&sum).Error; err != nil {
+			return err
+		}
+		claimed[k] = sum
+	}
+	return CheckExpensePolicy(claim.Items, categories, claimed)
+}
+
+// Claims
+
+// ExpenseItemInput is an item of a draft claim; ID refers to an existing item of the claim and
+// is 0 for new ones. Currency defaults to the base currency.
+type ExpenseItemInput struct {
+	ID          uint
+	CategoryID  uint
+	Date        time.Time
+	Merchant    string
+	Description string
+	Amount      float64
+	Currency    string
+}
+
+// ExpenseFilter narrows claim listings; zero values match everything.
+type ExpenseFilter struct {
+	EmployeeID uint
+	ManagerID  uint
+	Status     models.ExpenseStatus
+	Payout     models.ExpensePayout
+}
+
+func (f ExpenseFilter) apply(tx *gorm.DB) *gorm.DB {
+	if f.EmployeeID != 0 {
+		tx = tx.Where("expense_claims.employee_id = ?", f.EmployeeID)
+	}
+	if f.ManagerID != 0 {
+		tx = tx.Where("expense_claims.manager_id = ?", f.ManagerID)
+	}
+	if f.Status != "" {
+		tx = tx.Where("expense_claims.status = ?", f.Status)
+	}
+	if f.Payout != "" {
+		tx = tx.Where("expense_claims.payout = ?", f.Payout)
+	}
+	return tx
+}
+
+func (s *ExpenseService) ListClaims(f ExpenseFilter) ([]models.ExpenseClaim, error) {
+	var list []models.ExpenseClaim
+	err := f.apply(s.db.Model(&models.ExpenseClaim{})).Order("expense_claims.id DESC").Find(&list).Error
+	return list, err
+}
+
+func withItems(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("date, id") }).Preload("Items.Receipts")
+}
+
+func (s *ExpenseService) GetClaim(id uint) (*models.ExpenseClaim, error) {
+	var c models.ExpenseClaim
+	if err := withItems(s.db).First(&c, id).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// lockDraft loads the employee's draft claim for update.
+func lockDraft(tx *gorm.DB, employeeID, id uint) (*models.ExpenseClaim, error) {
+	var c models.ExpenseClaim
+	if err := withItems(tx.Clauses(clause.Locking{Strength: "UPDATE"})).
+		Where("id = ? AND employee_id = ?", id, employeeID).First(&c).Error; err != nil {
+		return nil, err
+	}
+	if c.Status != models.ExpenseDraft {
+		return nil, ErrClaimNotDraft
+	}
+	return &c, nil
+}
+
+// SaveDraft creates a draft claim when id is 0, or replaces the title and items of the
+// employee's draft. Items missing from items are removed with their receipts.
+func (s *ExpenseService) SaveDraft(employeeID, id uint, title string, items []ExpenseItemInput) (*models.ExpenseClaim, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, errors.New("title is required")
+	}
+	var orphans []string
+	claim := &models.ExpenseClaim{EmployeeID: employeeID, Status: models.ExpenseDraft}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if id != 0 {
+			var err error
+			if claim, err = lockDraft(tx, employeeID, id); err != nil {
+				return err
+			}
+		}
+		existing := map[uint]models.ExpenseItem{}
+		for _, it := range claim.Items {
+			existing[it.ID] = it
+		}
+		next := make([]models.ExpenseItem, 0, len(items))
+		for i, in := range items {
+			it, err := expenseItem(tx, in)
+			if err != nil {
+				return fmt.Errorf("item %d: %w", i+1, err)
+			}
+			if in.ID != 0 {
+				cur, ok := existing[in.ID]
+				if !ok {
+					return fmt.Errorf("item %d: no item %d on this claim", i+1, in.ID)
+				}
+				delete(existing, in.ID)
+				it.ID, it.CreatedAt, it.Receipts = cur.ID, cur.CreatedAt, cur.Receipts
+			}
+			next = append(next, it)
+		}
+		claim.Title, claim.Items = title, next
+		if err := price(tx, claim); err != nil {
+			return err
+		}
+		items := claim.Items
+		claim.Items = nil
+		if err := tx.Save(claim).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].ClaimID = claim.ID
+			receipts := items[i].Receipts
+			items[i].Receipts = nil
+			if err := tx.Save(&items[i]).Error; err != nil {
+				return err
+			}
+			items[i].Receipts = receipts
+		}
+		claim.Items = items
+		for _, it := range existing {
+			for _, r := range it.Receipts {
+				orphans = append(orphans, r.StorageKey)
+			}
+			// receipts go with the item through the foreign key
+			if err := tx.Delete(&it).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.deleteBlobs(orphans)
+	return claim, nil
+}
+
+func expenseItem(tx *gorm.DB, in ExpenseItemInput) (models.ExpenseItem, error) {
+	it := models.ExpenseItem{
+		CategoryID:  in.CategoryID,
+		Date:        in.Date,
+		Merchant:    strings.TrimSpace(in.Merchant),
+		Description: strings.TrimSpace(in.Description),
+		Amount:      roundMoney(in.Amount),
+		Currency:    strings.ToUpper(strings.TrimSpace(in.Currency)),
+	}
+	if it.Currency == "" {
+		it.Currency = BaseCurrency()
+	}
+	if !currencyCode.MatchString(it.Currency) {
+		return it, errors.New("currency must be a three-letter ISO code")
+	}
+	if it.Amount <= 0 {
+		return it, errors.New("amount must be positive")
+	}
+	if it.Date.IsZero() || it.Date.After(time.Now()) {
+		return it, errors.New("date is required and cannot be in the future")
+	}
+	var c models.ExpenseCategory
+	if err := tx.First(&c, in.CategoryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return it, fmt.Errorf("unknown category %d", in.CategoryID)
+		}
+		return it, err
+	}
+	if c.Archived {
+		return it, fmt.Errorf("category %s is archived", c.Name)
+	}
+	return it, nil
+}
+
+// DeleteDraft removes the employee's draft claim and its receipts.
+func (s *ExpenseService) DeleteDraft(employeeID, id uint) error {
+	var keys []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		claim, err := lockDraft(tx, employeeID, id)
+		if err != nil {
+			return err
+		}
+		for _, it := range claim.Items {
+			for _, r := range it.Receipts {
+				keys = append(keys, r.StorageKey)
+			}
+		}
+		return tx.Delete(claim).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.deleteBlobs(keys)
+	return nil
+}
+
+// deleteBlobs removes receipt files whose records are gone; a failure leaves an unreferenced blob.
+func (s *ExpenseService) deleteBlobs(keys []string) {
+	for _, k := range keys {
+		_ = s.store.Delete(k)
+	}
+}
+
+// Receipts
+
+// UploadReceipt stores a receipt for an item of the employee's draft claim.
+func (s *ExpenseService) UploadReceipt(employeeID, claimID, itemID, uploadedBy uint, fileName string, r io.Reader) (*models.ExpenseReceipt, error) {
+	var it models.ExpenseItem
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockDraft(tx, employeeID, claimID); err != nil {
+			return err
+		}
+		return tx.Where("id = ? AND claim_id = ?", itemID, claimID).First(&it).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, ct, err := readUpload(r, MaxUploadBytes(), defaultAllowedTypes)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("expenses/%d/%d%s", claimID, time.Now().UnixNano(), filepath.Ext(fileName))
+	if err := s.store.Put(key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	rec := models.ExpenseReceipt{
+		ItemID:      it.ID,
+		FileName:    filepath.Base(fileName),
+		ContentType: ct,
+		Size:        int64(len(data)),
+		StorageKey:  key,
+		UploadedBy:  uploadedBy,
+	}
+	if err := s.db.Create(&rec).Error; err != nil {
+		_ = s.store.Delete(key)
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// OpenReceipt returns a receipt of the claim and a reader over its content. Callers must close
+// the reader.
+func (s *ExpenseService) OpenReceipt(claimID, id uint) (*models.ExpenseReceipt, io.ReadCloser, error) {
+	var rec models.ExpenseReceipt
+	if err := s.db.Joins("JOIN expense_items ON expense_items.id = expense_receipts.item_id").
+		Where("expense_receipts.id = ? AND expense_items.claim_id = ?", id, claimID).First(&rec).Error; err != nil {
+		return nil, nil, err
+	}
+	rc, err := s.store.Open(rec.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &rec, rc, nil
+}
+
+// DeleteReceipt removes a receipt from the employee's draft claim.
+func (s *ExpenseService) DeleteReceipt(employeeID, claimID, id uint) error {
+	var rec models.ExpenseReceipt
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockDraft(tx, employeeID, claimID); err != nil {
+			return err
+		}
+		if err := tx.Joins("JOIN expense_items ON expense_items.id = expense_receipts.item_id").
+			Where("expense_receipts.id = ? AND expense_items.claim_id = ?", id, claimID).First(&rec).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&rec).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.store.Delete(rec.StorageKey)
+}
+
+// Approval
+
+// Submit sends the employee's draft for approval after re-pricing it and checking it against the
+// category policies. It goes to the employee's manager, or straight to finance without one.
+func (s *ExpenseService) Submit(employeeID, id uint) (*models.ExpenseClaim, error) {
+	var claim *models.ExpenseClaim
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if claim, err = lockDraft(tx, employeeID, id); err != nil {
+			return err
+		}
+		if len(claim.Items) == 0 {
+			return errors.New("a claim needs at least one item")
+		}
+		if err := price(tx, claim); err != nil {
+			return err
+		}
+		if err := checkPolicy(tx, claim); err != nil {
+			return err
+		}
+		var emp models.Employee
+		if err := tx.First(&emp, claim.EmployeeID).Error; err != nil {
+			return err
+		}
+		var mgr *models.Employee
+		if emp.ManagerID != nil {
+			var m models.Employee
+			err := tx.Where("id = ? AND status <> ?", *emp.ManagerID, models.EmploymentTerminated).First(&m).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err == nil {
+				mgr = &m
+			}
+		}
+		now := time.Now()
+		claim.SubmittedAt, claim.Status, claim.ManagerID = &now, models.ExpenseSubmitted, nil
+		if mgr != nil {
+			claim.ManagerID = &mgr.ID
+		} else {
+			claim.Status = models.ExpenseManagerApproved
+		}
+		for i := range claim.Items {
+			if err := tx.Model(&claim.Items[i]).UpdateColumns(map[string]interface{}{
+				"rate": claim.Items[i].Rate, "base_amount": claim.Items[i].BaseAmount,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(claim).Updates(map[string]interface{}{
+			"status": claim.Status, "submitted_at": now, "manager_id": claim.ManagerID,
+			"currency": claim.Currency, "total": claim.Total,
+		}).Error; err != nil {
+			return err
+		}
+		n := models.Notification{
+			Kind:       "EXPENSE_CLAIM",
+			Title:      fmt.Sprintf("Expense claim from %s awaiting approval", emp.Name),
+			Body:       fmt.Sprintf("%s: %.2f %s", claim.Title, claim.Total, claim.Currency),
+			EntityType: "expense_claim",
+			EntityID:   &claim.ID,
+		}
+		if mgr != nil {
+			return s.notify.Notify(tx, forUser(n, mgr.UserID))
+		}
+		return s.notify.NotifyRole(tx, models.RoleFinance, n)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// ClaimApprover is who decides a claim: the deciding user and, when they are an employee, their
+// employee record.
+type ClaimApprover struct {
+	UserID     uint
+	EmployeeID uint
+	HR         bool
+}
+
+// ClaimDecision is a decision on a claim. Rejections need a Note. Payout applies to finance
+// approval and defaults to payroll.
+type ClaimDecision struct {
+	Approve bool
+	Note    string
+	Payout  models.ExpensePayout
+}
+
+// ManagerDecide records the manager's decision on a submitted claim. HR can decide in the
+// manager's place.
+func (s *ExpenseService) ManagerDecide(id uint, by ClaimApprover, d ClaimDecision) (*models.ExpenseClaim, error) {
+	return s.decide(id, models.ExpenseSubmitted, by, d, func(claim *models.ExpenseClaim) error {
+		if by.HR || (claim.ManagerID != nil && by.EmployeeID == *claim.ManagerID) {
+			return nil
+		}
+		return ErrNotClaimApprover
+	})
+}
+
+// FinanceDecide records finance's decision on a manager-approved claim, choosing how an approved
+// claim is paid. Claims paid through payroll make draft runs stale.
+func (s *ExpenseService) FinanceDecide(id uint, by ClaimApprover, d ClaimDecision) (*models.ExpenseClaim, error) {
+	if d.Payout == "" {
+		d.Payout = models.ExpensePayoutPayroll
+	}
+	if d.Payout != models.ExpensePayoutPayroll && d.Payout != models.ExpensePayoutSeparate {
+		return nil, errors.New("payout must be PAYROLL or SEPARATE")
+	}
+	return s.decide(id, models.ExpenseManagerApproved, by, d, nil)
+}
+
+func (s *ExpenseService) decide(id uint, from models.ExpenseStatus, by ClaimApprover, d ClaimDecision, allowed func(*models.ExpenseClaim) error) (*models.ExpenseClaim, error) {
+	d.Note = strings.TrimSpace(d.Note)
+	if !d.Approve && d.Note == "" {
+		return nil, ErrClaimReasonMissing
+	}
+	var claim models.ExpenseClaim
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&claim, id).Error; err != nil {
+			return err
+		}
+		if claim.Status != from {
+			return ErrClaimStatus
+		}
+		if by.EmployeeID != 0 && by.EmployeeID == claim.EmployeeID {
+			return ErrOwnClaim
+		}
+		if allowed != nil {
+			if err := allowed(&claim); err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		updates := map[string]interface{}{"decision_note": d.Note}
+		switch {
+		case !d.Approve:
+			updates["status"] = models.ExpenseRejected
+		case from == models.ExpenseSubmitted:
+			updates["status"] = models.ExpenseManagerApproved
+		default:
+			updates["status"] = models.ExpenseApproved
+			updates["payout"] = d.Payout
+		}
+		if from == models.ExpenseSubmitted {
+			updates["manager_decided_by"], updates["manager_decided_at"] = by.UserID, now
+		} else {
+			updates["finance_decided_by"], updates["finance_decided_at"] = by.UserID, now
+		}
+		if err := tx.Model(&claim).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&claim, id).Error; err != nil {
+			return err
+		}
+		if claim.Status == models.ExpenseApproved && claim.Payout == models.ExpensePayoutPayroll {
+			if err := markDraftRunsStale(tx); err != nil {
+				return err
+			}
+		}
+		return s.notifyDecision(tx, &claim)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+func (s *ExpenseService) notifyDecision(tx *gorm.DB, claim *models.ExpenseClaim) error {
+	n := models.Notification{
+		Kind:       "EXPENSE_CLAIM",
+		EntityType: "expense_claim",
+		EntityID:   &claim.ID,
+	}
+	switch claim.Status {
+	case models.ExpenseManagerApproved:
+		var emp models.Employee
+		if err := tx.First(&emp, claim.EmployeeID).Error; err != nil {
+			return err
+		}
+		n.Title = fmt.Sprintf("Expense claim from %s awaiting finance approval", emp.Name)
+		n.Body = fmt.Sprintf("%s: %.2f %s", claim.Title, claim.Total, claim.Currency)
+		return s.notify.NotifyRole(tx, models.RoleFinance, n)
+	case models.ExpenseApproved:
+		n.Title = fmt.Sprintf("Expense claim %q approved", claim.Title)
+		n.Body = fmt.Sprintf("%.2f %s will be reimbursed", claim.Total, claim.Currency)
+		if claim.Payout == models.ExpensePayoutPayroll {
+			n.Body += " with your next salary"
+		}
+	case models.ExpenseRejected:
+		n.Title = fmt.Sprintf("Expense claim %q rejected", claim.Title)
+		n.Body = claim.DecisionNote
+	case models.ExpensePaid:
+		n.Title = fmt.Sprintf("Expense claim %q paid", claim.Title)
+		n.Body = fmt.Sprintf("%.2f %s, reference %s", claim.Total, claim.Currency, claim.PaymentReference)
+	}
+	var emp models.Employee
+	if err := tx.First(&emp, claim.EmployeeID).Error; err != nil {
+		return err
+	}
+	if emp.UserID == 0 {
+		return nil
+	}
+	return s.notify.Notify(tx, forUser(n, emp.UserID))
+}
+
+// MarkPaid records the separate payment of an approved claim.
+func (s *ExpenseService) MarkPaid(id uint, reference string, paidAt time.Time) (*models.ExpenseClaim, error) {
+	reference = strings.TrimSpace(reference)
+	if reference == "" {
+		return nil, errors.New("payment_reference is required")
+	}
+	if paidAt.IsZero() {
+		paidAt = time.Now()
+	}
+	var claim models.ExpenseClaim
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&claim, id).Error; err != nil {
+			return err
+		}
+		if claim.Status != models.ExpenseApproved || claim.Payout != models.ExpensePayoutSeparate {
+			return fmt.Errorf("%w: only approved claims paid separately can be marked paid", ErrClaimStatus)
+		}
+		claim.Status, claim.PaidAt, claim.PaymentReference = models.ExpensePaid, &paidAt, reference
+		if err := tx.Model(&claim).Updates(map[string]interface{}{
+			"status": claim.Status, "paid_at": paidAt, "payment_reference": reference,
+		}).Error; err != nil {
+			return err
+		}
+		return s.notifyDecision(tx, &claim)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+// Payroll
+
+// expenseReimbursements returns the approved claims to be reimbursed through payroll that no run
+// has reserved yet, by employee.
+func expenseReimbursements(tx *gorm.DB) (map[uint][]Reimbursement, error) {
+	var claims []models.ExpenseClaim
+	if err := tx.Where("status = ? AND payout = ? AND payroll_run_id IS NULL", models.ExpenseApproved, models.ExpensePayoutPayroll).
+		Order("id").Find(&claims).Error; err != nil {
+		return nil, err
+	}
+	out := map[uint][]Reimbursement{}
+	for _, c := range claims {
+		out[c.EmployeeID] = append(out[c.EmployeeID], Reimbursement{
+			ClaimID: c.ID,
+			Label:   fmt.Sprintf("Expense claim #%d: %s", c.ID, c.Title),
+			Amount:  c.Total,
+		})
+	}
+	return out, nil
+}
+
+// releaseClaims frees the unpaid claims reserved by a run, e.g. before it is recalculated.
+func releaseClaims(tx *gorm.DB, runID uint) error {
+	return tx.Model(&models.ExpenseClaim{}).Where("payroll_run_id = ? AND status = ?", runID, models.ExpenseApproved).
+		Update("payroll_run_id", nil).Error
+}
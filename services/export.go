@@ -0,0 +1,484 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/encryption"
+	"github.com/example/hrms-backend/models"
+)
+
+// ErrInvalidExport wraps problems with the export request itself, as opposed to database failures.
+var ErrInvalidExport = errors.New("invalid export")
+
+// ExportFormat is the file format of a data export.
+type ExportFormat string
+
+const (
+	ExportCSV    ExportFormat = "csv"
+	ExportXLSX   ExportFormat = "xlsx"
+	ExportNDJSON ExportFormat = "ndjson"
+)
+
+// ParseExportFormat accepts csv (the default), xlsx and ndjson (alias jsonl).
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "csv":
+		return ExportCSV, nil
+	case "xlsx":
+		return ExportXLSX, nil
+	case "ndjson", "jsonl":
+		return ExportNDJSON, nil
+	}
+	return "", fmt.Errorf("%w: unsupported format %q", ErrInvalidExport, s)
+}
+
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case ExportNDJSON:
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ExportRequest selects the format and columns of an export. With no Columns every column the
+// caller may see is exported; pay columns (salary and bank details) are dropped unless ViewSalary is set,
+// and custom field columns are limited to the fields visible to Role.
+type ExportRequest struct {
+	Format     ExportFormat
+	Columns    []string
+	ViewSalary bool
+	Role       models.UserRole
+}
+
+// exportColumn maps an exported column name to the SQL expression producing it. salary marks
+// pay columns, which need ViewSalary; decode turns the stored value into the exported one.
+type exportColumn struct {
+	name   string
+	expr   string
+	salary bool
+	decode func(interface{}) (interface{}, error)
+}
+
+// encryptedExportColumns decode the employee columns stored encrypted.
+var encryptedExportColumns = map[string]func(interface{}) (interface{}, error){
+	"salary":              decryptNumber,
+	"bank_account_number": decryptString,
+	"bank_routing_code":   decryptString,
+}
+
+func decryptString(v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	return encryption.Open(s)
+}
+
+func decryptNumber(v interface{}) (interface{}, error) {
+	s, err := decryptString(v)
+	if str, ok := s.(string); ok && err == nil && str != "" {
+		return strconv.ParseFloat(str, 64)
+	}
+	return s, err
+}
+
+var attendanceExportColumns = []exportColumn{
+	{name: "id", expr: "attendances.id"},
+	{name: "employee_id", expr: "attendances.employee_id"},
+	{name: "employee_name", expr: "employees.name"},
+	{name: "date", expr: "attendances.date"},
+	{name: "status", expr: "attendances.status"},
+	{name: "created_at", expr: "attendances.created_at"},
+	{name: "updated_at", expr: "attendances.updated_at"},
+}
+
+var leaveExportColumns = []exportColumn{
+	{name: "id", expr: "leaves.id"},
+	{name: "employee_id", expr: "leaves.employee_id"},
+	{name: "employee_name", expr: "employees.name"},
+	{name: "type", expr: "leaves.type"},
+	{name: "start_date", expr: "leaves.start_date"},
+	{name: "end_date", expr: "leaves.end_date"},
+	{name: "status", expr: "leaves.status"},
+	{name: "reason", expr: "leaves.reason"},
+	{name: "decided_by", expr: "leaves.decided_by"},
+	{name: "decided_at", expr: "leaves.decided_at"},
+	{name: "decision_comment", expr: "leaves.decision_comment"},
+	{name: "overridden_by", expr: "leaves.overridden_by"},
+	{name: "override_justification", expr: "leaves.override_justification"},
+	{name: "created_at", expr: "leaves.created_at"},
+}
+
+// expenseExportColumns include the claimant's bank details, so claims paid separately can be
+// handed to the bank.
+var expenseExportColumns = []exportColumn{
+	{name: "id", expr: "expense_claims.id"},
+	{name: "employee_id", expr: "expense_claims.employee_id"},
+	{name: "employee_name", expr: "employees.name"},
+	{name: "title", expr: "expense_claims.title"},
+	{name: "status", expr: "expense_claims.status"},
+	{name: "currency", expr: "expense_claims.currency"},
+	{name: "total", expr: "expense_claims.total"},
+	{name: "submitted_at", expr: "expense_claims.submitted_at"},
+	{name: "manager_decided_at", expr: "expense_claims.manager_decided_at"},
+	{name: "finance_decided_at", expr: "expense_claims.finance_decided_at"},
+	{name: "payout", expr: "expense_claims.payout"},
+	{name: "payroll_run_id", expr: "expense_claims.payroll_run_id"},
+	{name: "paid_at", expr: "expense_claims.paid_at"},
+	{name: "payment_reference", expr: "expense_claims.payment_reference"},
+	{name: "bank_account_holder", expr: "employees.bank_account_holder", salary: true},
+	{name: "bank_name", expr: "employees.bank_name", salary: true},
+	{name: "bank_account_number", expr: "employees.bank_account_number", salary: true, decode: decryptString},
+	{name: "bank_routing_code", expr: "employees.bank_routing_code", salary: true, decode: decryptString},
+}
+
+// employeeExportColumns lists the employee columns followed by one cf_<key> column per custom field.
+func employeeExportColumns(defs []models.CustomFieldDefinition) []exportColumn {
+	names := EmployeeColumns()
+	cols := make([]exportColumn, 0, len(names)+len(defs))
+	for _, n := range names {
+		if n == "custom_fields" {
+			continue
+		}
+		pay := n == "salary" || strings.HasPrefix(n, "bank_")
+		cols = append(cols, exportColumn{name: n, expr: "employees." + n, salary: pay, decode: encryptedExportColumns[n]})
+	}
+	for _, d := range defs {
+		// keys are restricted to [a-z0-9_], so they can be inlined
+		cols = append(cols, exportColumn{name: CustomFieldColumnPrefix + d.Key, expr: "employees.custom_fields->>'" + d.Key + "'"})
+	}
+	return cols
+}
+
+// selectColumns resolves the requested column names against the dataset's columns.
+func selectColumns(available []exportColumn, req ExportRequest) ([]exportColumn, error) {
+	byName := make(map[string]exportColumn, len(available))
+	for _, c := range available {
+		byName[c.name] = c
+	}
+	names := req.Columns
+	if len(names) == 0 {
+		for _, c := range available {
+			names = append(names, c.name)
+		}
+	}
+	cols := make([]exportColumn, 0, len(names))
+	for _, n := range names {
+		c, ok := byName[n]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidExport, n)
+		}
+		if c.salary && !req.ViewSalary {
+			continue
+		}
+		cols = append(cols, c)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("%w: no columns to export", ErrInvalidExport)
+	}
+	return cols, nil
+}
+
+// Export is an export whose query has already run; write it with Stream and always Close it.
+type Export struct {
+	Format  ExportFormat
+	Columns []string
+	rows    *sql.Rows
+	decode  []func(interface{}) (interface{}, error)
+}
+
+func openExport(tx *gorm.DB, cols []exportColumn, format ExportFormat) (*Export, error) {
+	exprs := make([]string, 0, len(cols))
+	names := make([]string, 0, len(cols))
+	decode := make([]func(interface{}) (interface{}, error), 0, len(cols))
+	for _, c := range cols {
+		exprs = append(exprs, c.expr+" AS "+c.name)
+		names = append(names, c.name)
+		decode = append(decode, c.decode)
+	}
+	rows, err := tx.Select(exprs).Rows()
+	if err != nil {
+		return nil, err
+	}
+	return &Export{Format: format, Columns: names, rows: rows, decode: decode}, nil
+}
+
+func (e *Export) Close() error { return e.rows.Close() }
+
+// Stream writes the rows to w one at a time, so memory use does not grow with the result set.
+// XLSX rows go through excelize's stream writer, which spills to a temporary file.
+func (e *Export) Stream(w io.Writer) error {
+	defer e.rows.Close()
+	var out rowWriter
+	switch e.Format {
+	case ExportXLSX:
+		out = newXLSXRowWriter(w)
+	case ExportNDJSON:
+		out = &ndjsonRowWriter{w: w}
+	default:
+		out = &csvRowWriter{w: csv.NewWriter(w)}
+	}
+	if err := out.header(e.Columns); err != nil {
+		return err
+	}
+	vals := make([]interface{}, len(e.Columns))
+	ptrs := make([]interface{}, len(e.Columns))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	for e.rows.Next() {
+		if err := e.rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, v := range vals {
+			v = exportValue(v)
+			if e.decode[i] != nil {
+				var err error
+				if v, err = e.decode[i](v); err != nil {
+					return fmt.Errorf("column %s: %w", e.Columns[i], err)
+				}
+			}
+			vals[i] = v
+		}
+		if err := out.row(vals); err != nil {
+			return err
+		}
+	}
+	if err := e.rows.Err(); err != nil {
+		return err
+	}
+	return out.close()
+}
+
+// exportValue normalizes driver values: bytes become strings and dates lose their midnight time.
+func exportValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case time.Time:
+		if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+			return t.Format("2006-01-02")
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	return v
+}
+
+type rowWriter interface {
+	header(cols []string) error
+	row(vals []interface{}) error
+	close() error
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) header(cols []string) error { return c.w.Write(cols) }
+
+func (c *csvRowWriter) row(vals []interface{}) error {
+	rec := make([]string, len(vals))
+	for i, v := range vals {
+		switch t := v.(type) {
+		case nil:
+		case string:
+			rec[i] = t
+		case float64:
+			rec[i] = strconv.FormatFloat(t, 'f', -1, 64)
+		default:
+			rec[i] = fmt.Sprint(t)
+		}
+	}
+	return c.w.Write(rec)
+}
+
+func (c *csvRowWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ndjsonRowWriter writes one JSON object per line, keeping keys in column order.
+type ndjsonRowWriter struct {
+	w    io.Writer
+	keys [][]byte
+	buf  bytes.Buffer
+}
+
+func (n *ndjsonRowWriter) header(cols []string) error {
+	for _, c := range cols {
+		k, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		n.keys = append(n.keys, k)
+	}
+	return nil
+}
+
+func (n *ndjsonRowWriter) row(vals []interface{}) error {
+	n.buf.Reset()
+	n.buf.WriteByte('{')
+	for i, v := range vals {
+		if i > 0 {
+			n.buf.WriteByte(',')
+		}
+		n.buf.Write(n.keys[i])
+		n.buf.WriteByte(':')
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		n.buf.Write(b)
+	}
+	n.buf.WriteString("}\n")
+	_, err := n.w.Write(n.buf.Bytes())
+	return err
+}
+
+func (n *ndjsonRowWriter) close() error { return nil }
+
+type xlsxRowWriter struct {
+	w    io.Writer
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	next int
+	err  error
+}
+
+func newXLSXRowWriter(w io.Writer) *xlsxRowWriter {
+	f := excelize.NewFile()
+	sw, err := f.NewStreamWriter("Sheet1")
+	return &xlsxRowWriter{w: w, file: f, sw: sw, next: 1, err: err}
+}
+
+func (x *xlsxRowWriter) header(cols []string) error {
+	row := make([]interface{}, len(cols))
+	for i, c := range cols {
+		row[i] = c
+	}
+	return x.row(row)
+}
+
+func (x *xlsxRowWriter) row(vals []interface{}) error {
+	if x.err != nil {
+		return x.err
+	}
+	cell, err := excelize.CoordinatesToCellName(1, x.next)
+	if err != nil {
+		return err
+	}
+	x.next++
+	return x.sw.SetRow(cell, vals)
+}
+
+func (x *xlsxRowWriter) close() error {
+	defer x.file.Close()
+	if x.err != nil {
+		return x.err
+	}
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(x.w)
+	return err
+}
+
+// ExportService builds employee, attendance, leave and expense claim exports using the same filters as the list endpoints.
+type ExportService struct {
+	db        *gorm.DB
+	employees *EmployeeService
+}
+
+func NewExportService(db *gorm.DB) *ExportService {
+	return &ExportService{db: db, employees: NewEmployeeService(db)}
+}
+
+// Employees exports every employee matching q; q's limit, offset and fields are ignored.
+// Only custom fields visible to the caller can be exported or filtered on, and looking up
+// bank accounts needs ViewSalary like the bank columns themselves.
+func (s *ExportService) Employees(req ExportRequest, q EmployeeQuery) (*Export, error) {
+	defs, err := customFieldDefinitions(s.db)
+	if err != nil {
+		return nil, err
+	}
+	visible := defs[:0]
+	for i := range defs {
+		if customFieldVisible(&defs[i], req.Role, false) {
+			visible = append(visible, defs[i])
+		}
+	}
+	if q.BankAccount != "" && !req.ViewSalary {
+		return nil, fmt.Errorf("%w: cannot filter by bank_account", ErrInvalidExport)
+	}
+	for k := range q.CustomFields {
+		if !containsCustomField(visible, k) {
+			return nil, fmt.Errorf("%w: unknown custom field %q", ErrInvalidExport, k)
+		}
+	}
+	cols, err := selectColumns(employeeExportColumns(visible), req)
+	if err != nil {
+		return nil, err
+	}
+	order, err := EmployeeOrder(q.Sort)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	tx, err := s.employees.filtered(q)
+	if errors.Is(err, ErrInvalidQuery) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return openExport(tx.Order(order), cols, req.Format)
+}
+
+func containsCustomField(defs []models.CustomFieldDefinition, key string) bool {
+	for _, d := range defs {
+		if d.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ExportService) Attendance(req ExportRequest, f AttendanceFilter) (*Export, error) {
+	cols, err := selectColumns(attendanceExportColumns, req)
+	if err != nil {
+		return nil, err
+	}
+	tx := s.db.Table("attendances").Joins("LEFT JOIN employees ON employees.id = attendances.employee_id")
+	return openExport(f.apply(tx).Order("attendances.date, attendances.id"), cols, req.Format)
+}
+
+func (s *ExportService) Leaves(req ExportRequest, f LeaveFilter) (*Export, error) {
+	cols, err := selectColumns(leaveExportColumns, req)
+	if err != nil {
+		return nil, err
+	}
+	tx := s.db.Table("leaves").Joins("LEFT JOIN employees ON employees.id = leaves.employee_id")
+	return openExport(f.apply(tx).Order("leaves.start_date, leaves.id"), cols, req.Format)
+}
+
+func (s *ExportService) Expenses(req ExportRequest, f ExpenseFilter) (*Export, error) {
+	cols, err := selectColumns(expenseExportColumns, req)
+	if err != nil {
+		return nil, err
+	}
+	tx := s.db.Table("expense_claims").Joins("LEFT JOIN employees ON employees.id = expense_claims.employee_id")
+	return openExport(f.apply(tx).Order("expense_claims.id"), cols, req.Format)
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/storage"
+)
+
+type LeaveAttachmentService struct {
+	db    *gorm.DB
+	store storage.BlobStore
+}
+
+func NewLeaveAttachmentService(db *gorm.DB, store storage.BlobStore) *LeaveAttachmentService {
+	return &LeaveAttachmentService{db: db, store: store}
+}
+
+// Upload validates and stores a file against a leave. Only pending leaves accept new evidence.
+func (s *LeaveAttachmentService) Upload(leaveID, uploadedBy uint, fileName string, r io.Reader) (*models.LeaveAttachment, error) {
+	var lv models.Leave
+	if err := s.db.First(&lv, leaveID).Error; err != nil {
+		return nil, err
+	}
+	if lv.Status != models.LeavePending {
+		return nil, errors.New("attachments can only be added to pending leaves")
+	}
+	data, ct, err := readUpload(r, MaxUploadBytes(), defaultAllowedTypes)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("leaves/%d/%d%s", leaveID, time.Now().UnixNano(), filepath.Ext(fileName))
+	if err := s.store.Put(key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	att := models.LeaveAttachment{
+		LeaveID:     leaveID,
+		FileName:    filepath.Base(fileName),
+		ContentType: ct,
+		Size:        int64(len(data)),
+		StorageKey:  key,
+		UploadedBy:  uploadedBy,
+	}
+	if err := s.db.Create(&att).Error; err != nil {
+		_ = s.store.Delete(key)
+		return nil, err
+	}
+	return &att, nil
+}
+
+func (s *LeaveAttachmentService) List(leaveID uint) ([]models.LeaveAttachment, error) {
+	var list []models.LeaveAttachment
+	if err := s.db.Where("leave_id = ?", leaveID).Order("created_at").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Open returns the attachment metadata and a reader over its content. Callers must close the reader.
+func (s *LeaveAttachmentService) Open(leaveID, id uint) (*models.LeaveAttachment, io.ReadCloser, error) {
+	var att models.LeaveAttachment
+	if err := s.db.Where("id = ? AND leave_id = ?", id, leaveID).First(&att).Error; err != nil {
+		return nil, nil, err
+	}
+	rc, err := s.store.Open(att.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &att, rc, nil
+}
+
+func (s *LeaveAttachmentService) Delete(leaveID, id uint) error {
+	var att models.LeaveAttachment
+	if err := s.db.Where("id = ? AND leave_id = ?", id, leaveID).First(&att).Error; err != nil {
+		return err
+	}
+	var lv models.Leave
+	if err := s.db.First(&lv, leaveID).Error; err != nil {
+		return err
+	}
+	if lv.Status != models.LeavePending {
+		return errors.New("attachments of decided leaves cannot be removed")
+	}
+	if err := s.db.Delete(&att).Error; err != nil {
+		return err
+	}
+	return s.store.Delete(att.StorageKey)
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+)
+
+// LeaveConstraintError explains which blackout or staffing rule blocks a leave request.
+type LeaveConstraintError struct {
+	Rule   string // "blackout", "max_out" or "min_on_duty"
+	RuleID uint
+	Date   time.Time
+	Detail string
+}
+
+func (e *LeaveConstraintError) Error() string {
+	return fmt.Sprintf("blocked by %s rule #%d on %s: %s", e.Rule, e.RuleID, e.Date.Format("2006-01-02"), e.Detail)
+}
+
+type LeaveRuleService struct {
+	db  *gorm.DB
+	org *OrgService
+}
+
+func NewLeaveRuleService(db *gorm.DB) *LeaveRuleService {
+	return &LeaveRuleService{db: db, org: NewOrgService(db)}
+}
+
+// Check validates lv against the blackouts and staffing rules of the employee's department.
+// Only approved leaves of other employees count towards staffing limits.
+func (s *LeaveRuleService) Check(tx *gorm.DB, lv *models.Leave) error {
+	var emp models.Employee
+	if err := tx.First(&emp, lv.EmployeeID).Error; err != nil {
+		return err
+	}
+	if emp.DepartmentID == nil {
+		return nil
+	}
+	deptID := *emp.DepartmentID
+
+	var blackouts []models.LeaveBlackout
+	if err := tx.Where("department_id = ? AND start_date <= ? AND end_date >= ?", deptID, lv.EndDate, lv.StartDate).
+		Find(&blackouts).Error; err != nil {
+		return err
+	}
+	var rules []models.StaffingRule
+	if err := tx.Where("department_id = ?", deptID).
+		Where("(start_date IS NULL OR start_date <= ?) AND (end_date IS NULL OR end_date >= ?)", lv.EndDate, lv.StartDate).
+		Order("id").Find(&rules).Error; err != nil {
+		return err
+	}
+	var headcount int64
+	var others []models.Leave
+	if len(blackouts) == 0 && len(rules) > 0 {
+		if err := tx.Model(&models.Employee{}).Where("department_id = ? AND status <> ?", deptID, models.EmploymentTerminated).Count(&headcount).Error; err != nil {
+			return err
+		}
+		if err := tx.Joins("JOIN employees ON employees.id = leaves.employee_id").
+			Where("employees.department_id = ? AND leaves.status = ? AND leaves.employee_id <> ?", deptID, models.LeaveApproved, lv.EmployeeID).
+			Where("leaves.start_date <= ? AND leaves.end_date >= ?", lv.EndDate, lv.StartDate).
+			Find(&others).Error; err != nil {
+			return err
+		}
+	}
+	return CheckLeaveRules(lv, emp.Department, blackouts, rules, int(headcount), others)
+}
+
+// CheckLeaveRules returns the first blackout or staffing rule lv breaks, as a
+// *LeaveConstraintError, or nil. A blackout overlapping the leave wins over staffing rules, the
+// earliest starting first. Staffing rules are checked day by day within their windows: lv and the
+// approved leaves of other employees in others count as out of the headcount people of dept.
+func CheckLeaveRules(lv *models.Leave, dept string, blackouts []models.LeaveBlackout, rules []models.StaffingRule, headcount int, others []models.Leave) error {
+	var hit *models.LeaveBlackout
+	for i := range blackouts {
+		b := &blackouts[i]
+		if b.StartDate.After(lv.EndDate) || b.EndDate.Before(lv.StartDate) {
+			continue
+		}
+		if hit == nil || b.StartDate.Before(hit.StartDate) {
+			hit = b
+		}
+	}
+	if hit != nil {
+		day := hit.StartDate
+		if day.Before(lv.StartDate) {
+			day = lv.StartDate
+		}
+		detail := fmt.Sprintf("%s is in a blackout period from %s to %s", hit.Department,
+			hit.StartDate.Format("2006-01-02"), hit.EndDate.Format("2006-01-02"))
+		if hit.Reason != "" {
+			detail += " (" + hit.Reason + ")"
+		}
+		return &LeaveConstraintError{Rule: "blackout", RuleID: hit.ID, Date: day, Detail: detail}
+	}
+
+	for day := lv.StartDate; len(rules) > 0 && !day.After(lv.EndDate); day = day.AddDate(0, 0, 1) {
+		out := map[uint]bool{lv.EmployeeID: true}
+		for _, o := range others {
+			if o.Status == models.LeaveApproved && !day.Before(o.StartDate) && !day.After(o.EndDate) {
+				out[o.EmployeeID] = true
+			}
+		}
+		for _, r := range rules {
+			if r.StartDate != nil && day.Before(*r.StartDate) || r.EndDate != nil && day.After(*r.EndDate) {
+				continue
+			}
+			if r.MaxOut > 0 && len(out) > r.MaxOut {
+				return &LeaveConstraintError{Rule: "max_out", RuleID: r.ID, Date: day,
+					Detail: fmt.Sprintf("at most %d of %s may be on leave; %d already are", r.MaxOut, dept, len(out)-1)}
+			}
+			if r.MinOnDuty > 0 && headcount-len(out) < r.MinOnDuty {
+				return &LeaveConstraintError{Rule: "min_on_duty", RuleID: r.ID, Date: day,
+					Detail: fmt.Sprintf("%s needs at least %d of %d people on duty", dept, r.MinOnDuty, headcount)}
+			}
+		}
+	}
+	return nil
+}
+
+func validRange(start, end *time.Time) error {
+	if start != nil && end != nil && end.Before(*start) {
+		return ErrInvalidLeaveRange
+	}
+	return nil
+}
+
+func (s *LeaveRuleService) ListBlackouts() ([]models.LeaveBlackout, error) {
+	var list []models.LeaveBlackout
+	if err := s.db.Order("start_date desc").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// resolveDepartment links a rule to an existing department given by id or name.
+func (s *LeaveRuleService) resolveDepartment(id uint, name string) (uint, string, error) {
+	ref := OrgRef{Name: name}
+	if id != 0 {
+		ref.ID = &id
+	} else if NormalizeName(name) == "" {
+		return 0, "", errors.New("department is required")
+	}
+	d, err := s.org.LookupDepartment(s.db, ref)
+	if err != nil {
+		return 0, "", err
+	}
+	return d.ID, d.Name, nil
+}
+
+func (s *LeaveRuleService) CreateBlackout(b *models.LeaveBlackout) error {
+	var err error
+	if b.DepartmentID, b.Department, err = s.resolveDepartment(b.DepartmentID, b.Department); err != nil {
+		return err
+	}
+	if err := validRange(&b.StartDate, &b.EndDate); err != nil {
+		return err
+	}
+	return s.db.Create(b).Error
+}
+
+func (s *LeaveRuleService) DeleteBlackout(id uint) error {
+	return s.db.Delete(&models.LeaveBlackout{}, id).Error
+}
+
+func (s *LeaveRuleService) ListStaffingRules() ([]models.StaffingRule, error) {
+	var list []models.StaffingRule
+	if err := s.db.Order("department, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *LeaveRuleService) CreateStaffingRule(r *models.StaffingRule) error {
+	var err error
+	if r.DepartmentID, r.Department, err = s.resolveDepartment(r.DepartmentID, r.Department); err != nil {
+		return err
+	}
+	if r.MaxOut < 0 || r.MinOnDuty < 0 || r.MaxOut == 0 && r.MinOnDuty == 0 {
+		return errors.New("set max_out or min_on_duty to a positive number")
+	}
+	if err := validRange(r.StartDate, r.EndDate); err != nil {
+		return err
+	}
+	return s.db.Create(r).Error
+}
+
+func (s *LeaveRuleService) DeleteStaffingRule(id uint) error {
+	return s.db.Delete(&models.StaffingRule{}, id).Error
+}
@@ -2,23 +2,100 @@ package services
 
 import (
     "errors"
+    "fmt"
+    "strings"
     "sync"
     "time"
 
     "gorm.io/gorm"
     "github.com/example/hrms-backend/models"
+    "github.com/example/hrms-backend/utils"
 )
 
+var ErrInvalidLeaveRange = errors.New("end date before start date")
+
+// AttachmentRequiredError reports the policy that blocks approving a leave without evidence.
+type AttachmentRequiredError struct {
+    Policy models.LeaveAttachmentPolicy
+}
+
+func (e *AttachmentRequiredError) Error() string {
+    msg := fmt.Sprintf("%s leave of %d or more days requires an attachment", e.Policy.LeaveType, e.Policy.MinDays)
+    if e.Policy.Description != "" {
+        msg += ": " + e.Policy.Description
+    }
+    return msg
+}
+
+var ErrRejectionReasonRequired = errors.New("a reason is required to reject a leave")
+
+// LeaveDecision carries who decided a leave, their comment and, for approvals, an optional rule override.
+type LeaveDecision struct {
+    DecidedBy     uint
+    Comment       string
+    Override      bool
+    Justification string
+}
+
 type LeaveService struct {
-    db *gorm.DB
-    mu sync.Mutex // protect approval/rejection state transitions
+    db    *gorm.DB
+    rules *LeaveRuleService
+    mu    sync.Mutex // protect apply checks and approval/rejection state transitions
 }
 
-func NewLeaveService(db *gorm.DB) *LeaveService { return &LeaveService{db: db} }
+func NewLeaveService(db *gorm.DB) *LeaveService { return &LeaveService{db: db, rules: NewLeaveRuleService(db)} }
 
-func (s *LeaveService) Apply(employeeID uint, start, end time.Time, reason string) error {
-    lv := models.Leave{EmployeeID: employeeID, StartDate: start, EndDate: end, Reason: reason, Status: models.LeavePending}
-    return s.db.Create(&lv).Error
+// leaveDays counts calendar days in the inclusive range [start, end].
+func leaveDays(start, end time.Time) int {
+    return int(end.Sub(start).Hours()/24) + 1
+}
+
+func validLeaveType(t models.LeaveType) bool {
+    switch t {
+    case models.LeaveAnnual, models.LeaveSick, models.LeaveCasual, models.LeaveUnpaid:
+        return true
+    }
+    return false
+}
+
+// Apply files a pending leave request. A request breaking a blackout or staffing rule is stored
+// with the violation in RuleWarning.
+func (s *LeaveService) Apply(employeeID uint, leaveType models.LeaveType, start, end time.Time, reason string) (*models.Leave, error) {
+    if leaveType == "" {
+        leaveType = models.LeaveAnnual
+    }
+    if !validLeaveType(leaveType) {
+        return nil, errors.New("invalid leave type")
+    }
+    if end.Before(start) {
+        return nil, ErrInvalidLeaveRange
+    }
+    lv := models.Leave{EmployeeID: employeeID, Type: leaveType, StartDate: start, EndDate: end, Reason: reason, Status: models.LeavePending}
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        // a broken blackout or staffing rule does not stop the request; it is enforced at approval,
+        // where HR may override it
+        if err := s.rules.Check(tx, &lv); err != nil {
+            var ce *LeaveConstraintError
+            if !errors.As(err, &ce) { return err }
+            lv.RuleWarning = ce.Error()
+        }
+        return tx.Create(&lv).Error
+    })
+    if err != nil { return nil, err }
+    return &lv, nil
+}
+
+// withDetails preloads the attachments and discussion returned with every leave.
+func withDetails(db *gorm.DB) *gorm.DB {
+    return db.Preload("Attachments").Preload("Comments", func(tx *gorm.DB) *gorm.DB { return tx.Order("created_at") })
+}
+
+func (s *LeaveService) Get(id uint) (*models.Leave, error) {
+    var m models.Leave
+    if err := withDetails(s.db).First(&m, id).Error; err != nil { return nil, err }
+    return &m, nil
 }
 
 func (s *LeaveService) DeleteMine(employeeID, id uint) error {
@@ -28,17 +105,56 @@ func (s *LeaveService) DeleteMine(employeeID, id uint) error {
 
 func (s *LeaveService) ListMine(employeeID uint) ([]models.Leave, error) {
     var list []models.Leave
-    if err := s.db.Where("employee_id = ?", employeeID).Order("created_at desc").Find(&list).Error; err != nil { return nil, err }
+    if err := withDetails(s.db).Where("employee_id = ?", employeeID).Order("created_at desc").Find(&list).Error; err != nil { return nil, err }
     return list, nil
 }
 
-func (s *LeaveService) ListAll() ([]models.Leave, error) {
+// LeaveFilter narrows HR listings and exports; zero values match everything.
+// From and To select leaves overlapping that date range.
+type LeaveFilter struct {
+    EmployeeIDs []uint
+    Statuses    []models.LeaveStatus
+    Types       []models.LeaveType
+    From        *time.Time
+    To          *time.Time
+}
+
+func (f LeaveFilter) apply(tx *gorm.DB) *gorm.DB {
+    if len(f.EmployeeIDs) > 0 { tx = tx.Where("leaves.employee_id IN ?", f.EmployeeIDs) }
+    if len(f.Statuses) > 0 { tx = tx.Where("leaves.status IN ?", f.Statuses) }
+    if len(f.Types) > 0 { tx = tx.Where("leaves.type IN ?", f.Types) }
+    if f.From != nil { tx = tx.Where("leaves.end_date >= ?", f.From.Format("2006-01-02")) }
+    if f.To != nil { tx = tx.Where("leaves.start_date <= ?", f.To.Format("2006-01-02")) }
+    return tx
+}
+
+func (s *LeaveService) ListAll(f LeaveFilter) ([]models.Leave, error) {
     var list []models.Leave
-    if err := s.db.Order("created_at desc").Find(&list).Error; err != nil { return nil, err }
+    if err := f.apply(withDetails(s.db).Model(&models.Leave{})).Order("created_at desc").Find(&list).Error; err != nil { return nil, err }
     return list, nil
 }
 
-func (s *LeaveService) setStatus(id uint, from models.LeaveStatus, to models.LeaveStatus) error {
+// checkAttachmentPolicy fails when a policy for the leave's type and duration demands evidence that is missing.
+func (s *LeaveService) checkAttachmentPolicy(tx *gorm.DB, m *models.Leave) error {
+    var policy models.LeaveAttachmentPolicy
+    err := tx.Where("leave_type = ? AND min_days <= ?", m.Type, leaveDays(m.StartDate, m.EndDate)).First(&policy).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil
+    } else if err != nil {
+        return err
+    }
+    var n int64
+    if err := tx.Model(&models.LeaveAttachment{}).Where("leave_id = ?", m.ID).Count(&n).Error; err != nil { return err }
+    if n == 0 {
+        return &AttachmentRequiredError{Policy: policy}
+    }
+    return nil
+}
+
+func (s *LeaveService) setStatus(id uint, from models.LeaveStatus, to models.LeaveStatus, d LeaveDecision) error {
+    if d.Override && utils.IsBlank(d.Justification) {
+        return errors.New("override requires a justification")
+    }
     s.mu.Lock()
     defer s.mu.Unlock()
     return s.db.Transaction(func(tx *gorm.DB) error {
@@ -47,13 +163,169 @@ func (s *LeaveService) setStatus(id uint, from models.LeaveStatus, to models.Lea
         if m.Status != from {
             return errors.New("invalid status transition")
         }
-        return tx.Model(&models.Leave{}).
-            Where("id = ? AND version = ?", m.ID, m.Version).
-            Updates(map[string]interface{}{"status": to, "version": m.Version + 1}).Error
+        now := time.Now()
+        updates := map[string]interface{}{
+            "status":           to,
+            "version":          m.Version + 1,
+            "decided_by":       d.DecidedBy,
+            "decided_at":       now,
+            "decision_comment": strings.TrimSpace(d.Comment),
+        }
+        if to == models.LeaveApproved {
+            if err := guardPayroll(tx, m.StartDate, m.EndDate); err != nil { return err }
+            if err := s.checkAttachmentPolicy(tx, &m); err != nil { return err }
+            // staffing may have changed since the request was filed, so rules are checked again
+            if err := s.rules.Check(tx, &m); err != nil {
+                var ce *LeaveConstraintError
+                if !errors.As(err, &ce) || !d.Override { return err }
+                updates["overridden_by"] = d.DecidedBy
+                updates["overridden_at"] = now
+                updates["override_justification"] = d.Justification
+            }
+        }
+        res := tx.Model(&models.Leave{}).Where("id = ? AND version = ?", m.ID, m.Version).Updates(updates)
+        if res.Error != nil { return res.Error }
+        if res.RowsAffected == 0 {
+            return errors.New("leave was modified concurrently")
+        }
+        return nil
     })
 }
 
-func (s *LeaveService) Approve(id uint) error { return s.setStatus(id, models.LeavePending, models.LeaveApproved) }
-func (s *LeaveService) Reject(id uint) error  { return s.setStatus(id, models.LeavePending, models.LeaveRejected) }
+func (s *LeaveService) Approve(id uint, d LeaveDecision) error {
+    return s.setStatus(id, models.LeavePending, models.LeaveApproved, d)
+}
+func (s *LeaveService) Reject(id uint, d LeaveDecision) error {
+    if utils.IsBlank(d.Comment) {
+        return ErrRejectionReasonRequired
+    }
+    return s.setStatus(id, models.LeavePending, models.LeaveRejected, d)
+}
+
+func (s *LeaveService) AddComment(leaveID, authorID uint, role models.UserRole, body string) (*models.LeaveComment, error) {
+    body = strings.TrimSpace(body)
+    if body == "" {
+        return nil, errors.New("comment body is required")
+    }
+    c := models.LeaveComment{LeaveID: leaveID, AuthorID: authorID, AuthorRole: role, Body: body}
+    if err := s.db.Create(&c).Error; err != nil { return nil, err }
+    return &c, nil
+}
 
+func (s *LeaveService) ListComments(leaveID uint) ([]models.LeaveComment, error) {
+    var list []models.LeaveComment
+    if err := s.db.Where("leave_id = ?", leaveID).Order("created_at").Find(&list).Error; err != nil { return nil, err }
+    return list, nil
+}
 
+func (s *LeaveService) ListAttachmentPolicies() ([]models.LeaveAttachmentPolicy, error) {
+    var list []models.LeaveAttachmentPolicy
+    if err := s.db.Order("leave_type").Find(&list).Error; err != nil { return nil, err }
+    return list, nil
+}
+
+// SaveAttachmentPolicy creates or replaces the policy for p.LeaveType.
+func (s *LeaveService) SaveAttachmentPolicy(p *models.LeaveAttachmentPolicy) error {
+    if !validLeaveType(p.LeaveType) {
+        return errors.New("invalid leave type")
+    }
+    if p.MinDays < 1 {
+        return errors.New("min_days must be at least 1")
+    }
+    var existing models.LeaveAttachmentPolicy
+    err := s.db.Where("leave_type = ?", p.LeaveType).First(&existing).Error
+    if err == nil {
+        p.ID = existing.ID
+        p.CreatedAt = existing.CreatedAt
+        return s.db.Save(p).Error
+    } else if !errors.Is(err, gorm.ErrRecordNotFound) {
+        return err
+    }
+    return s.db.Create(p).Error
+}
+
+func (s *LeaveService) DeleteAttachmentPolicy(id uint) error {
+    return s.db.Delete(&models.LeaveAttachmentPolicy{}, id).Error
+}
+
+func (s *LeaveService) ListEntitlements() ([]models.LeaveEntitlement, error) {
+    var list []models.LeaveEntitlement
+    if err := s.db.Order("leave_type").Find(&list).Error; err != nil { return nil, err }
+    return list, nil
+}
+
+// SaveEntitlement creates or replaces the entitlement for e.LeaveType.
+func (s *LeaveService) SaveEntitlement(e *models.LeaveEntitlement) error {
+    if !validLeaveType(e.LeaveType) {
+        return errors.New("invalid leave type")
+    }
+    if e.DaysPerYear < 0 || e.DaysPerYear > 366 {
+        return errors.New("days_per_year must be between 0 and 366")
+    }
+    var existing models.LeaveEntitlement
+    err := s.db.Where("leave_type = ?", e.LeaveType).First(&existing).Error
+    if err == nil {
+        e.ID = existing.ID
+        e.CreatedAt = existing.CreatedAt
+        return s.db.Save(e).Error
+    } else if !errors.Is(err, gorm.ErrRecordNotFound) {
+        return err
+    }
+    return s.db.Create(e).Error
+}
+
+func (s *LeaveService) DeleteEntitlement(id uint) error {
+    return s.db.Delete(&models.LeaveEntitlement{}, id).Error
+}
+
+// Balances returns the employee's leave balances for the calendar year of asOf.
+func (s *LeaveService) Balances(employeeID uint, asOf time.Time) ([]models.LeaveBalance, error) {
+    return leaveBalances(s.db, employeeID, asOf)
+}
+
+func leaveBalances(db *gorm.DB, employeeID uint, asOf time.Time) ([]models.LeaveBalance, error) {
+    var ents []models.LeaveEntitlement
+    if err := db.Order("leave_type").Find(&ents).Error; err != nil { return nil, err }
+    if len(ents) == 0 {
+        return []models.LeaveBalance{}, nil
+    }
+    yearStart := time.Date(asOf.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+    var leaves []models.Leave
+    if err := db.Where("employee_id = ? AND status = ? AND start_date <= ? AND end_date >= ?",
+        employeeID, models.LeaveApproved, asOf, yearStart).Find(&leaves).Error; err != nil { return nil, err }
+    return LeaveBalances(ents, leaves, asOf), nil
+}
+
+// LeaveBalances computes the balance of each entitlement as of a date from approved leaves,
+// counting only the days that fall between January 1 and asOf.
+func LeaveBalances(ents []models.LeaveEntitlement, leaves []models.Leave, asOf time.Time) []models.LeaveBalance {
+    y, m, d := asOf.Date()
+    end := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+    yearStart := time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+    taken := map[models.LeaveType]float64{}
+    for _, lv := range leaves {
+        if lv.Status != models.LeaveApproved {
+            continue
+        }
+        from, to := lv.StartDate, lv.EndDate
+        if from.Before(yearStart) {
+            from = yearStart
+        }
+        if to.After(end) {
+            to = end
+        }
+        if !to.Before(from) {
+            taken[lv.Type] += float64(leaveDays(from, to))
+        }
+    }
+    out := make([]models.LeaveBalance, 0, len(ents))
+    for _, e := range ents {
+        out = append(out, models.LeaveBalance{
+            LeaveType:   e.LeaveType,
+            Entitlement: e.DaysPerYear,
+            Taken:       taken[e.LeaveType],
+            Remaining:   e.DaysPerYear - taken[e.LeaveType],
+        })
+    }
+    return out
+}
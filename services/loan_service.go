@@ -0,0 +1,654 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/utils"
+)
+
+var (
+	ErrLoanStatus        = errors.New("the loan is not in a status that allows this")
+	ErrOwnLoan           = errors.New("you cannot decide your own loan")
+	ErrLoanReasonMissing = errors.New("a reason is required to reject a loan")
+	ErrLoanOverpaid      = errors.New("the repayment exceeds the outstanding balance")
+	ErrLoanRunPending    = errors.New("a payroll run under review holds a repayment of this loan; finalize or reopen it first")
+)
+
+// maxLoanInstallments caps repayment schedules at ten years.
+const maxLoanInstallments = 120
+
+// LoanSchedule splits amount into n monthly installments from first. Interest is charged at the
+// yearly rate (percent) / 12 on the principal still owed, and installments are equal except for
+// the last, which clears the rounding.
+func LoanSchedule(amount, rate float64, n int, first time.Time) []models.LoanInstallment {
+	if n <= 0 {
+		return nil
+	}
+	r := rate / 100 / 12
+	payment := amount / float64(n)
+	if r > 0 {
+		payment = amount * r / (1 - math.Pow(1+r, -float64(n)))
+	}
+	payment = roundMoney(payment)
+	balance := roundMoney(amount)
+	out := make([]models.LoanInstallment, n)
+	for k := range out {
+		interest := roundMoney(balance * r)
+		principal := roundMoney(payment - interest)
+		if k == n-1 || principal > balance {
+			principal = balance
+		}
+		balance = roundMoney(balance - principal)
+		out[k] = models.LoanInstallment{
+			Number:    k + 1,
+			DueDate:   utils.AddMonths(first, k),
+			Principal: principal,
+			Interest:  interest,
+			Amount:    roundMoney(principal + interest),
+			Balance:   balance,
+		}
+	}
+	return out
+}
+
+// LoanDue is what is left to repay of the installments due by until after repaid has been repaid.
+// Repaying ahead counts against the next installments.
+func LoanDue(schedule []models.LoanInstallment, repaid float64, until time.Time) float64 {
+	due := 0.0
+	for _, in := range schedule {
+		if !in.DueDate.After(until) {
+			due += in.Amount
+		}
+	}
+	return math.Max(0, roundMoney(due-repaid))
+}
+
+// LoanPayoff is what settles a loan on a day: the installments due by then in full and the
+// principal of the later ones, interest not yet charged being waived, less what was repaid.
+func LoanPayoff(schedule []models.LoanInstallment, repaid float64, on time.Time) float64 {
+	owed := 0.0
+	for _, in := range schedule {
+		if in.DueDate.After(on) {
+			owed += in.Principal
+		} else {
+			owed += in.Amount
+		}
+	}
+	return math.Max(0, roundMoney(owed-repaid))
+}
+
+// postedRepaid sums the repayments of a loan that are final.
+func postedRepaid(l *models.Loan) float64 {
+	repaid := 0.0
+	for _, r := range l.Repayments {
+		if r.Posted {
+			repaid += r.Amount
+		}
+	}
+	return roundMoney(repaid)
+}
+
+// fillLoan sets the repaid amount and the outstanding balance of a loan loaded with its schedule
+// and repayments. Only active loans have a balance.
+func fillLoan(l *models.Loan) {
+	l.Repaid = postedRepaid(l)
+	l.Outstanding = 0
+	if l.Status == models.LoanActive {
+		l.Outstanding = LoanPayoff(l.Schedule, l.Repaid, today())
+	}
+}
+
+// LoanService handles salary advances and loans: requests, approval with the repayment schedule,
+// repayment through payroll or directly, and settlement when an employee leaves.
+type LoanService struct {
+	db     *gorm.DB
+	notify *NotificationService
+}
+
+func NewLoanService(db *gorm.DB) *LoanService {
+	return &LoanService{db: db, notify: NewNotificationService(db)}
+}
+
+// LoanTerms are the amount and repayment terms of a loan. A zero FirstDueDate means a month from
+// today.
+type LoanTerms struct {
+	Amount       float64
+	InterestRate float64
+	Installments int
+	FirstDueDate time.Time
+}
+
+func (t *LoanTerms) validate() error {
+	t.Amount = roundMoney(t.Amount)
+	if t.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if t.InterestRate < 0 || t.InterestRate > 100 {
+		return errors.New("interest_rate must be between 0 and 100")
+	}
+	if t.Installments < 1 || t.Installments > maxLoanInstallments {
+		return fmt.Errorf("installments must be between 1 and %d", maxLoanInstallments)
+	}
+	if t.FirstDueDate.IsZero() {
+		t.FirstDueDate = utils.AddMonths(today(), 1)
+	}
+	if t.FirstDueDate.Before(today()) {
+		return errors.New("first_due_date cannot be in the past")
+	}
+	return nil
+}
+
+// PreviewSchedule returns the repayment schedule of terms without storing anything.
+func (s *LoanService) PreviewSchedule(t LoanTerms) ([]models.LoanInstallment, error) {
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return LoanSchedule(t.Amount, t.InterestRate, t.Installments, t.FirstDueDate), nil
+}
+
+// LoanRequest is an employee's request for an advance or loan.
+type LoanRequest struct {
+	Kind   models.LoanKind
+	Terms  LoanTerms
+	Reason string
+}
+
+// Request records an employee's loan request and asks finance to decide it.
+func (s *LoanService) Request(employeeID, by uint, req LoanRequest) (*models.Loan, error) {
+	if req.Kind == "" {
+		req.Kind = models.LoanAdvance
+	}
+	if req.Kind != models.LoanAdvance && req.Kind != models.LoanTerm {
+		return nil, errors.New("kind must be ADVANCE or LOAN")
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return nil, errors.New("reason is required")
+	}
+	if err := req.Terms.validate(); err != nil {
+		return nil, err
+	}
+	loan := &models.Loan{
+		EmployeeID:   employeeID,
+		Kind:         req.Kind,
+		Status:       models.LoanRequested,
+		Amount:       req.Terms.Amount,
+		InterestRate: req.Terms.InterestRate,
+		Installments: req.Terms.Installments,
+		FirstDueDate: req.Terms.FirstDueDate,
+		Reason:       req.Reason,
+		RequestedBy:  by,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var emp models.Employee
+		if err := tx.First(&emp, employeeID).Error; err != nil {
+			return err
+		}
+		if emp.Status == models.EmploymentTerminated {
+			return ErrEmployeeTerminated
+		}
+		if err := tx.Create(loan).Error; err != nil {
+			return err
+		}
+		return s.notify.NotifyRole(tx, models.RoleFinance, models.Notification{
+			Kind:       "LOAN",
+			Title:      fmt.Sprintf("%s requests %s awaiting approval", emp.Name, loanName(loan)),
+			Body:       fmt.Sprintf("%.2f over %d installments: %s", loan.Amount, loan.Installments, loan.Reason),
+			EntityType: "loan",
+			EntityID:   &loan.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loan, nil
+}
+
+func loanName(l *models.Loan) string {
+	if l.Kind == models.LoanTerm {
+		return fmt.Sprintf("loan #%d", l.ID)
+	}
+	return fmt.Sprintf("salary advance #%d", l.ID)
+}
+
+// Cancel withdraws an employee's request that is not decided yet.
+func (s *LoanService) Cancel(employeeID, id uint) (*models.Loan, error) {
+	res := s.db.Model(&models.Loan{}).Where("id = ? AND employee_id = ? AND status = ?", id, employeeID, models.LoanRequested).
+		Update("status", models.LoanCancelled)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		if _, err := s.get(s.db.Where("employee_id = ?", employeeID), id); err != nil {
+			return nil, err
+		}
+		return nil, ErrLoanStatus
+	}
+	return s.Get(id)
+}
+
+// LoanFilter selects loans; zero fields match all.
+type LoanFilter struct {
+	EmployeeID uint
+	Status     models.LoanStatus
+	Kind       models.LoanKind
+}
+
+func (f LoanFilter) apply(tx *gorm.DB) *gorm.DB {
+	if f.EmployeeID != 0 {
+		tx = tx.Where("employee_id = ?", f.EmployeeID)
+	}
+	if f.Status != "" {
+		tx = tx.Where("status = ?", f.Status)
+	}
+	if f.Kind != "" {
+		tx = tx.Where("kind = ?", f.Kind)
+	}
+	return tx
+}
+
+func withSchedule(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Schedule", func(db *gorm.DB) *gorm.DB { return db.Order("number") }).
+		Preload("Repayments", func(db *gorm.DB) *gorm.DB { return db.Order("date, id") })
+}
+
+// List returns the loans matching f, newest first, with their balances.
+func (s *LoanService) List(f LoanFilter) ([]models.Loan, error) {
+	var list []models.Loan
+	if err := withSchedule(f.apply(s.db)).Order("id DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for i := range list {
+		fillLoan(&list[i])
+	}
+	return list, nil
+}
+
+func (s *LoanService) get(tx *gorm.DB, id uint) (*models.Loan, error) {
+	var l models.Loan
+	if err := withSchedule(tx).First(&l, id).Error; err != nil {
+		return nil, err
+	}
+	fillLoan(&l)
+	return &l, nil
+}
+
+// Get returns a loan with its schedule, repayments and balance.
+func (s *LoanService) Get(id uint) (*models.Loan, error) {
+	return s.get(s.db, id)
+}
+
+// LoanApprover is who decides a loan: the deciding user and, when they are an employee, their
+// employee record.
+type LoanApprover struct {
+	UserID     uint
+	EmployeeID uint
+}
+
+// LoanDecision is a decision on a loan request. Rejections need a Note. An approval may change
+// the requested terms; zero fields keep them, and InterestRate applies as given.
+type LoanDecision struct {
+	Approve bool
+	Note    string
+	Terms   LoanTerms
+}
+
+// Decide approves or rejects a requested loan. Approval fixes the terms and the repayment
+// schedule, which the next payroll runs start deducting, so draft runs become stale.
+func (s *LoanService) Decide(id uint, by LoanApprover, d LoanDecision) (*models.Loan, error) {
+	d.Note = strings.TrimSpace(d.Note)
+	if !d.Approve && d.Note == "" {
+		return nil, ErrLoanReasonMissing
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var loan models.Loan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&loan, id).Error; err != nil {
+			return err
+		}
+		if loan.Status != models.LoanRequested {
+			return ErrLoanStatus
+		}
+		if by.EmployeeID != 0 && by.EmployeeID == loan.EmployeeID {
+			return ErrOwnLoan
+		}
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status": models.LoanRejected, "decided_by": by.UserID, "decided_at": now, "decision_note": d.Note,
+		}
+		if d.Approve {
+			var emp models.Employee
+			if err := tx.First(&emp, loan.EmployeeID).Error; err != nil {
+				return err
+			}
+			if emp.Status == models.EmploymentTerminated {
+				return ErrEmployeeTerminated
+			}
+			t := d.Terms
+			if t.Amount == 0 {
+				t.Amount = loan.Amount
+			}
+			if t.Installments == 0 {
+				t.Installments = loan.Installments
+			}
+			if t.FirstDueDate.IsZero() {
+				t.FirstDueDate = loan.FirstDueDate
+			}
+			if err := t.validate(); err != nil {
+				return err
+			}
+			schedule := LoanSchedule(t.Amount, t.InterestRate, t.Installments, t.FirstDueDate)
+			total := 0.0
+			for i := range schedule {
+				schedule[i].LoanID = loan.ID
+				total += schedule[i].Amount
+			}
+			if err := tx.Create(&schedule).Error; err != nil {
+				return err
+			}
+			updates["status"] = models.LoanActive
+			updates["amount"], updates["interest_rate"], updates["installments"] = t.Amount, t.InterestRate, t.Installments
+			updates["first_due_date"], updates["total"] = t.FirstDueDate, roundMoney(total)
+		}
+		if err := tx.Model(&loan).Updates(updates).Error; err != nil {
+			return err
+		}
+		if d.Approve {
+			if err := markDraftRunsStale(tx); err != nil {
+				return err
+			}
+		}
+		return s.notifyEmployee(tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// notifyEmployee tells the borrower where their loan stands.
+func (s *LoanService) notifyEmployee(tx *gorm.DB, id uint) error {
+	loan, err := s.get(tx, id)
+	if err != nil {
+		return err
+	}
+	var emp models.Employee
+	if err := tx.First(&emp, loan.EmployeeID).Error; err != nil {
+		return err
+	}
+	if emp.UserID == 0 {
+		return nil
+	}
+	n := models.Notification{Kind: "LOAN", EntityType: "loan", EntityID: &loan.ID}
+	switch loan.Status {
+	case models.LoanActive:
+		n.Title = fmt.Sprintf("Your %s was approved", loanName(loan))
+		n.Body = fmt.Sprintf("%.2f, repaid in %d monthly installments from %s", loan.Amount, loan.Installments, dateKey(loan.FirstDueDate))
+		if len(loan.Schedule) > 0 {
+			n.Body += fmt.Sprintf(" of %.2f", loan.Schedule[0].Amount)
+		}
+	case models.LoanRejected:
+		n.Title = fmt.Sprintf("Your %s was rejected", loanName(loan))
+		n.Body = loan.DecisionNote
+	case models.LoanClosed:
+		n.Title = fmt.Sprintf("Your %s is closed", loanName(loan))
+		n.Body = loan.ClosedReason
+	default:
+		return nil
+	}
+	return s.notify.Notify(tx, forUser(n, emp.UserID))
+}
+
+// Disburse records how and when an approved loan was paid to the employee.
+func (s *LoanService) Disburse(id uint, reference string, on time.Time) (*models.Loan, error) {
+	reference = strings.TrimSpace(reference)
+	if reference == "" {
+		return nil, errors.New("reference is required")
+	}
+	if on.IsZero() {
+		on = today()
+	}
+	res := s.db.Model(&models.Loan{}).Where("id = ? AND status IN ?", id, []models.LoanStatus{models.LoanActive, models.LoanClosed}).
+		Updates(map[string]interface{}{"disbursement_reference": reference, "disbursed_at": on})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		if _, err := s.Get(id); err != nil {
+			return nil, err
+		}
+		return nil, ErrLoanStatus
+	}
+	return s.Get(id)
+}
+
+// LoanPayment is a repayment outside payroll, or a write-off. A zero Amount settles the loan:
+// it pays off the balance on Date, which defaults to today.
+type LoanPayment struct {
+	Source    models.LoanRepaymentSource
+	Amount    float64
+	Date      time.Time
+	Reference string
+	By        uint
+}
+
+// RecordPayment records a direct repayment or a write-off of an active loan, closing the loan
+// once nothing is left to pay. Later installments are reduced by what is repaid ahead.
+func (s *LoanService) RecordPayment(id uint, p LoanPayment) (*models.Loan, error) {
+	if p.Source != models.LoanRepaidDirect && p.Source != models.LoanWrittenOff {
+		return nil, errors.New("source must be DIRECT or WRITE_OFF")
+	}
+	if p.Date.IsZero() {
+		p.Date = today()
+	}
+	p.Amount, p.Reference = roundMoney(p.Amount), strings.TrimSpace(p.Reference)
+	if p.Amount < 0 {
+		return nil, errors.New("amount cannot be negative")
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		loan, err := s.get(tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: clause.CurrentTable}}), id)
+		if err != nil {
+			return err
+		}
+		if loan.Status != models.LoanActive {
+			return ErrLoanStatus
+		}
+		var held int64
+		if err := tx.Model(&models.LoanRepayment{}).Joins("JOIN payroll_runs ON payroll_runs.id = loan_repayments.payroll_run_id").
+			Where("loan_repayments.loan_id = ? AND NOT loan_repayments.posted AND payroll_runs.status <> ?", id, models.PayrollDraft).
+			Count(&held).Error; err != nil {
+			return err
+		}
+		if held > 0 {
+			return ErrLoanRunPending
+		}
+		payoff := LoanPayoff(loan.Schedule, loan.Repaid, p.Date)
+		if p.Amount == 0 {
+			p.Amount = payoff
+		}
+		if p.Amount <= 0 || p.Amount > payoff {
+			return fmt.Errorf("%w of %.2f", ErrLoanOverpaid, payoff)
+		}
+		by := p.By
+		if err := tx.Create(&models.LoanRepayment{
+			LoanID: id, Source: p.Source, Date: p.Date, Amount: p.Amount, Posted: true, Reference: p.Reference, RecordedBy: &by,
+		}).Error; err != nil {
+			return err
+		}
+		if p.Amount == payoff {
+			reason := "settled"
+			if p.Source == models.LoanWrittenOff {
+				reason = "written off"
+			}
+			if err := closeLoan(tx, id, reason); err != nil {
+				return err
+			}
+			if err := s.notifyEmployee(tx, id); err != nil {
+				return err
+			}
+		}
+		return markDraftRunsStale(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func closeLoan(tx *gorm.DB, id uint, reason string) error {
+	return tx.Model(&models.Loan{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": models.LoanClosed, "closed_at": time.Now(), "closed_reason": reason}).Error
+}
+
+// Payoff quotes what settles an active loan on a day.
+func (s *LoanService) Payoff(id uint, on time.Time) (float64, error) {
+	loan, err := s.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	if loan.Status != models.LoanActive {
+		return 0, ErrLoanStatus
+	}
+	return LoanPayoff(loan.Schedule, loan.Repaid, on), nil
+}
+
+// notifyExitLoans tells finance about the active loans of an employee who leaves on exit. Their
+// balance falls due at once: the payroll run covering the last day deducts it from the final pay,
+// and what the pay does not cover is left to be repaid or written off.
+func notifyExitLoans(tx *gorm.DB, emp *models.Employee, exit time.Time) error {
+	var loans []models.Loan
+	if err := withSchedule(tx).Where("employee_id = ? AND status = ?", emp.ID, models.LoanActive).Order("id").Find(&loans).Error; err != nil {
+		return err
+	}
+	if len(loans) == 0 {
+		return nil
+	}
+	var lines []string
+	for i := range loans {
+		lines = append(lines, fmt.Sprintf("%s: %.2f", loanName(&loans[i]), LoanPayoff(loans[i].Schedule, postedRepaid(&loans[i]), exit)))
+	}
+	return NewNotificationService(tx).NotifyRole(tx, models.RoleFinance, models.Notification{
+		Kind:       "LOAN",
+		Title:      fmt.Sprintf("%s leaves on %s with outstanding loans", emp.Name, dateKey(exit)),
+		Body:       strings.Join(lines, "; ") + ". The balance is deducted from the final pay; settle or write off what it does not cover.",
+		EntityType: "employee",
+		EntityID:   &emp.ID,
+	})
+}
+
+// Payroll
+
+// loanDeductions returns the repayments due from the period's pay by employee: the installments
+// due by the end of the period that are not repaid yet, or, for employees who leave by then, the
+// whole balance on their last day. Repayments reserved by other draft runs count as repaid.
+func loanDeductions(tx *gorm.DB, period *models.PayPeriod) (map[uint][]LoanDeduction, error) {
+	var loans []models.Loan
+	if err := withSchedule(tx).Where("status = ?", models.LoanActive).Order("id").Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	if len(loans) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, 0, len(loans))
+	for _, l := range loans {
+		ids = append(ids, l.EmployeeID)
+	}
+	var emps []models.Employee
+	if err := tx.Select("id", "termination_date").Where("id IN ?", ids).Find(&emps).Error; err != nil {
+		return nil, err
+	}
+	exits := map[uint]*time.Time{}
+	for _, e := range emps {
+		exits[e.ID] = e.TerminationDate
+	}
+	out := map[uint][]LoanDeduction{}
+	for i := range loans {
+		l := &loans[i]
+		repaid := 0.0
+		for _, r := range l.Repayments {
+			repaid += r.Amount
+		}
+		d := LoanDeduction{LoanID: l.ID, Label: fmt.Sprintf("Repayment of %s", loanName(l))}
+		if exit := exits[l.EmployeeID]; exit != nil && !exit.After(period.EndDate) {
+			d.Amount, d.Basis = LoanPayoff(l.Schedule, repaid, *exit), "balance due on leaving"
+		} else {
+			d.Amount, d.Basis = LoanDue(l.Schedule, repaid, period.EndDate), fmt.Sprintf("installments due by %s", dateKey(period.EndDate))
+		}
+		if d.Amount > 0 {
+			out[l.EmployeeID] = append(out[l.EmployeeID], d)
+		}
+	}
+	return out, nil
+}
+
+// releaseLoanRepayments drops the repayments a draft run recorded, e.g. before it is
+// recalculated.
+func releaseLoanRepayments(tx *gorm.DB, runID uint) error {
+	return tx.Where("payroll_run_id = ? AND NOT posted", runID).Delete(&models.LoanRepayment{}).Error
+}
+
+// recordLoanRepayments records what the run's lines deduct by loan, dated on the pay date.
+func recordLoanRepayments(tx *gorm.DB, run *models.PayrollRun, period *models.PayPeriod, lines []models.PayrollLine) error {
+	var reps []models.LoanRepayment
+	for _, l := range lines {
+		for loanID, amount := range l.LoanRepayments {
+			runID := run.ID
+			reps = append(reps, models.LoanRepayment{
+				LoanID: loanID, Source: models.LoanRepaidPayroll, Date: period.PayDate, Amount: amount,
+				PayrollRunID: &runID, Reference: fmt.Sprintf("payroll %s", period.Name),
+			})
+		}
+	}
+	if len(reps) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(reps, 200).Error
+}
+
+// postLoanRepayments makes a finalized run's repayments final and closes the loans they pay off.
+func postLoanRepayments(tx *gorm.DB, run *models.PayrollRun) error {
+	var loanIDs []uint
+	if err := tx.Model(&models.LoanRepayment{}).Where("payroll_run_id = ? AND NOT posted", run.ID).
+		Distinct().Pluck("loan_id", &loanIDs).Error; err != nil {
+		return err
+	}
+	if len(loanIDs) == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.LoanRepayment{}).Where("payroll_run_id = ?", run.ID).Update("posted", true).Error; err != nil {
+		return err
+	}
+	var loans []models.Loan
+	if err := withSchedule(tx).Where("id IN ? AND status = ?", loanIDs, models.LoanActive).Find(&loans).Error; err != nil {
+		return err
+	}
+	svc := NewLoanService(tx)
+	for i := range loans {
+		l := &loans[i]
+		on := run.Period.EndDate
+		var emp models.Employee
+		if err := tx.Select("id", "termination_date").First(&emp, l.EmployeeID).Error; err != nil {
+			return err
+		}
+		if emp.TerminationDate != nil && emp.TerminationDate.Before(on) {
+			on = *emp.TerminationDate
+		}
+		if LoanPayoff(l.Schedule, postedRepaid(l), on) > 0 {
+			continue
+		}
+		if err := closeLoan(tx, l.ID, "repaid through payroll"); err != nil {
+			return err
+		}
+		if err := svc.notifyEmployee(tx, l.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
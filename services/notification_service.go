@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/hrms-backend/models"
+)
+
+// NotificationService stores in-app notifications and serves each user's inbox.
+type NotificationService struct {
+	db *gorm.DB
+}
+
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// Notify stores n for its user within tx. A notification whose DedupKey was already used is
+// silently dropped.
+func (s *NotificationService) Notify(tx *gorm.DB, n models.Notification) error {
+	n.ID, n.ReadAt = 0, nil
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&n).Error
+}
+
+// NotifyRole sends n to every enabled user with the role. A DedupKey is made per user by
+// appending the user id.
+func (s *NotificationService) NotifyRole(tx *gorm.DB, role models.UserRole, n models.Notification) error {
+	var ids []uint
+	if err := tx.Model(&models.User{}).Where("role = ? AND NOT disabled", role).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.Notify(tx, forUser(n, id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func forUser(n models.Notification, userID uint) models.Notification {
+	n.UserID = userID
+	if n.DedupKey != nil {
+		key := fmt.Sprintf("%s:%d", *n.DedupKey, userID)
+		n.DedupKey = &key
+	}
+	return n
+}
+
+// List returns the user's notifications, newest first.
+func (s *NotificationService) List(userID uint, unreadOnly bool, limit int) ([]models.Notification, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	tx := s.db.Where("user_id = ?", userID)
+	if unreadOnly {
+		tx = tx.Where("read_at IS NULL")
+	}
+	var list []models.Notification
+	if err := tx.Order("created_at DESC, id DESC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// MarkRead marks one of the user's notifications as read.
+func (s *NotificationService) MarkRead(userID, id uint) error {
+	var n models.Notification
+	if err := s.db.Where("user_id = ?", userID).First(&n, id).Error; err != nil {
+		return err
+	}
+	if n.ReadAt != nil {
+		return nil
+	}
+	return s.db.Model(&n).Update("read_at", time.Now()).Error
+}
+
+// MarkAllRead marks every unread notification of the user as read.
+func (s *NotificationService) MarkAllRead(userID uint) error {
+	return s.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now()).Error
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+)
+
+var (
+	ErrOrgNameRequired = errors.New("name is required")
+	ErrOrgInUse        = errors.New("still referenced by")
+)
+
+// NormalizeName trims and collapses whitespace; NameKey additionally lower-cases for uniqueness.
+func NormalizeName(s string) string { return strings.Join(strings.Fields(s), " ") }
+func NameKey(s string) string       { return strings.ToLower(NormalizeName(s)) }
+
+type OrgService struct{ db *gorm.DB }
+
+func NewOrgService(db *gorm.DB) *OrgService { return &OrgService{db: db} }
+
+// OrgRef identifies an org entity either by id or by (free-text) name.
+type OrgRef struct {
+	ID   *uint
+	Name string
+}
+
+func (r OrgRef) empty() bool { return r.ID == nil && NormalizeName(r.Name) == "" }
+
+// Departments
+
+func (s *OrgService) ListDepartments() ([]models.Department, error) {
+	var list []models.Department
+	if err := s.db.Order("name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *OrgService) GetDepartment(id uint) (*models.Department, error) {
+	var d models.Department
+	if err := s.db.First(&d, id).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// SubDepartments returns the direct children of a department.
+func (s *OrgService) SubDepartments(id uint) ([]models.Department, error) {
+	var list []models.Department
+	if err := s.db.Where("parent_id = ?", id).Order("name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *OrgService) validateDepartment(tx *gorm.DB, d *models.Department) error {
+	d.Name = NormalizeName(d.Name)
+	if d.Name == "" {
+		return ErrOrgNameRequired
+	}
+	d.NameKey = NameKey(d.Name)
+	if d.HeadID != nil {
+		if err := tx.First(&models.Employee{}, *d.HeadID).Error; err != nil {
+			return errors.New("head employee not found")
+		}
+	}
+	// walk up from the new parent to make sure the hierarchy stays acyclic
+	for p, hops := d.ParentID, 0; p != nil; hops++ {
+		if d.ID != 0 && *p == d.ID || hops > 100 {
+			return errors.New("department hierarchy would contain a cycle")
+		}
+		var parent models.Department
+		if err := tx.First(&parent, *p).Error; err != nil {
+			return errors.New("parent department not found")
+		}
+		p = parent.ParentID
+	}
+	return nil
+}
+
+func (s *OrgService) CreateDepartment(d *models.Department) error {
+	if err := s.validateDepartment(s.db, d); err != nil {
+		return err
+	}
+	return s.db.Create(d).Error
+}
+
+// UpdateDepartment saves d and propagates a rename to the denormalized name columns.
+func (s *OrgService) UpdateDepartment(d *models.Department) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var cur models.Department
+		if err := tx.First(&cur, d.ID).Error; err != nil {
+			return err
+		}
+		if err := s.validateDepartment(tx, d); err != nil {
+			return err
+		}
+		d.CreatedAt = cur.CreatedAt
+		if err := tx.Save(d).Error; err != nil {
+			return err
+		}
+		if cur.Name == d.Name {
+			return nil
+		}
+		return propagateName(tx, "department", d.ID, d.Name, "employees", "job_records", "leave_blackouts", "staffing_rules")
+	})
+}
+
+func (s *OrgService) DeleteDepartment(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.ensureUnused(tx, "department_id", id); err != nil {
+			return err
+		}
+		var children int64
+		if err := tx.Model(&models.Department{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return errors.New("department has sub-departments")
+		}
+		return tx.Delete(&models.Department{}, id).Error
+	})
+}
+
+// ResolveDepartment finds the referenced department, creating it when only a new name is given.
+func (s *OrgService) ResolveDepartment(tx *gorm.DB, ref OrgRef) (*models.Department, error) {
+	var d models.Department
+	if ref.ID != nil {
+		if err := tx.First(&d, *ref.ID).Error; err != nil {
+			return nil, errors.New("department not found")
+		}
+		return &d, nil
+	}
+	name := NormalizeName(ref.Name)
+	if name == "" {
+		return nil, ErrOrgNameRequired
+	}
+	err := tx.Where(models.Department{NameKey: NameKey(name)}).Attrs(models.Department{Name: name}).FirstOrCreate(&d).Error
+	return &d, err
+}
+
+// LookupDepartment finds an existing department by id or name without creating one.
+func (s *OrgService) LookupDepartment(tx *gorm.DB, ref OrgRef) (*models.Department, error) {
+	var d models.Department
+	q := tx.Where("name_key = ?", NameKey(ref.Name))
+	if ref.ID != nil {
+		q = tx.Where("id = ?", *ref.ID)
+	}
+	if err := q.First(&d).Error; err != nil {
+		return nil, errors.New("department not found")
+	}
+	return &d, nil
+}
+
+// Locations
+
+func (s *OrgService) ListLocations() ([]models.Location, error) {
+	var list []models.Location
+	if err := s.db.Order("name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *OrgService) GetLocation(id uint) (*models.Location, error) {
+	var l models.Location
+	if err := s.db.First(&l, id).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (s *OrgService) SaveLocation(l *models.Location) error {
+	l.Name = NormalizeName(l.Name)
+	if l.Name == "" {
+		return ErrOrgNameRequired
+	}
+	l.NameKey = NameKey(l.Name)
+	l.Country = strings.ToUpper(strings.TrimSpace(l.Country))
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if l.ID == 0 {
+			return tx.Create(l).Error
+		}
+		var cur models.Location
+		if err := tx.First(&cur, l.ID).Error; err != nil {
+			return err
+		}
+		l.CreatedAt = cur.CreatedAt
+		if err := tx.Save(l).Error; err != nil {
+			return err
+		}
+		return propagateName(tx, "location", l.ID, l.Name, "employees", "job_records")
+	})
+}
+
+func (s *OrgService) DeleteLocation(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.ensureUnused(tx, "location_id", id); err != nil {
+			return err
+		}
+		return tx.Delete(&models.Location{}, id).Error
+	})
+}
+
+func (s *OrgService) ResolveLocation(tx *gorm.DB, ref OrgRef) (*models.Location, error) {
+	var l models.Location
+	if ref.ID != nil {
+		if err := tx.First(&l, *ref.ID).Error; err != nil {
+			return nil, errors.New("location not found")
+		}
+		return &l, nil
+	}
+	name := NormalizeName(ref.Name)
+	if name == "" {
+		return nil, ErrOrgNameRequired
+	}
+	err := tx.Where(models.Location{NameKey: NameKey(name)}).Attrs(models.Location{Name: name}).FirstOrCreate(&l).Error
+	return &l, err
+}
+
+// Job grades
+
+func (s *OrgService) ListGrades() ([]models.JobGrade, error) {
+	var list []models.JobGrade
+	if err := s.db.Order("level, code").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *OrgService) GetGrade(id uint) (*models.JobGrade, error) {
+	var g models.JobGrade
+	if err := s.db.First(&g, id).Error; err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (s *OrgService) SaveGrade(g *models.JobGrade) error {
+	g.Code = NormalizeName(g.Code)
+	if g.Code == "" {
+		return errors.New("code is required")
+	}
+	if g.MinSalary < 0 || g.MaxSalary < 0 || g.MaxSalary > 0 && g.MaxSalary < g.MinSalary {
+		return errors.New("invalid salary band")
+	}
+	if g.NoticePeriodDays < 0 {
+		return errors.New("notice_period_days cannot be negative")
+	}
+	g.NameKey = NameKey(g.Code)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if g.ID == 0 {
+			return tx.Create(g).Error
+		}
+		var cur models.JobGrade
+		if err := tx.First(&cur, g.ID).Error; err != nil {
+			return err
+		}
+		g.CreatedAt = cur.CreatedAt
+		if err := tx.Save(g).Error; err != nil {
+			return err
+		}
+		return propagateName(tx, "grade", g.ID, g.Code, "employees", "job_records")
+	})
+}
+
+func (s *OrgService) DeleteGrade(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.ensureUnused(tx, "grade_id", id); err != nil {
+			return err
+		}
+		return tx.Delete(&models.JobGrade{}, id).Error
+	})
+}
+
+func (s *OrgService) ResolveGrade(tx *gorm.DB, ref OrgRef) (*models.JobGrade, error) {
+	var g models.JobGrade
+	if ref.ID != nil {
+		if err := tx.First(&g, *ref.ID).Error; err != nil {
+			return nil, errors.New("grade not found")
+		}
+		return &g, nil
+	}
+	code := NormalizeName(ref.Name)
+	if code == "" {
+		return nil, ErrOrgNameRequired
+	}
+	err := tx.Where(models.JobGrade{NameKey: NameKey(code)}).Attrs(models.JobGrade{Code: code}).FirstOrCreate(&g).Error
+	return &g, err
+}
+
+// Positions
+
+func (s *OrgService) ListPositions() ([]models.Position, error) {
+	var list []models.Position
+	if err := s.db.Order("title").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *OrgService) GetPosition(id uint) (*models.Position, error) {
+	var p models.Position
+	if err := s.db.First(&p, id).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *OrgService) SavePosition(p *models.Position) error {
+	p.Title = NormalizeName(p.Title)
+	if p.Title == "" {
+		return errors.New("title is required")
+	}
+	p.NameKey = NameKey(p.Title)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if p.DepartmentID != nil {
+			if err := tx.First(&models.Department{}, *p.DepartmentID).Error; err != nil {
+				return errors.New("department not found")
+			}
+		}
+		if p.GradeID != nil {
+			if err := tx.First(&models.JobGrade{}, *p.GradeID).Error; err != nil {
+				return errors.New("grade not found")
+			}
+		}
+		if p.ID == 0 {
+			return tx.Create(p).Error
+		}
+		var cur models.Position
+		if err := tx.First(&cur, p.ID).Error; err != nil {
+			return err
+		}
+		p.CreatedAt = cur.CreatedAt
+		if err := tx.Save(p).Error; err != nil {
+			return err
+		}
+		return propagateName(tx, "position", p.ID, p.Title, "employees", "job_records")
+	})
+}
+
+func (s *OrgService) DeletePosition(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.ensureUnused(tx, "position_id", id); err != nil {
+			return err
+		}
+		return tx.Delete(&models.Position{}, id).Error
+	})
+}
+
+func (s *OrgService) ResolvePosition(tx *gorm.DB, ref OrgRef) (*models.Position, error) {
+	var p models.Position
+	if ref.ID != nil {
+		if err := tx.First(&p, *ref.ID).Error; err != nil {
+			return nil, errors.New("position not found")
+		}
+		return &p, nil
+	}
+	title := NormalizeName(ref.Name)
+	if title == "" {
+		return nil, errors.New("title is required")
+	}
+	err := tx.Where(models.Position{NameKey: NameKey(title)}).Attrs(models.Position{Title: title}).FirstOrCreate(&p).Error
+	return &p, err
+}
+
+// propagateName writes an org entity's name to the denormalized column of every table holding it
+// next to <column>_id. Job records are included, future-dated ones too, so that applying them
+// does not bring back an old name.
+func propagateName(tx *gorm.DB, column string, id uint, name string, tables ...string) error {
+	for _, table := range tables {
+		if err := tx.Table(table).Where(column+"_id = ?", id).Update(column, name).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orgReferences lists, by referencing column, the tables that point at org entities. Job records
+// count even when future-dated, as they are copied onto the employee when they take effect.
+var orgReferences = map[string][]string{
+	"department_id": {"employees", "job_records", "positions", "leave_blackouts", "staffing_rules",
+		"checklist_templates", "deduction_rules", "compensation_budgets", "compensation_proposals"},
+	"position_id": {"employees", "job_records", "checklist_templates"},
+	"grade_id":    {"employees", "job_records", "positions"},
+	"location_id": {"employees", "job_records"},
+}
+
+// ensureUnused fails with ErrOrgInUse, naming the table, while any row still references id.
+func (s *OrgService) ensureUnused(tx *gorm.DB, column string, id uint) error {
+	for _, table := range orgReferences[column] {
+		var n int64
+		if err := tx.Table(table).Where(column+" = ?", id).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("%w %s", ErrOrgInUse, strings.ReplaceAll(table, "_", " "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/statutory"
+)
+
+// standardHoursPerYear converts annual salaries to the hourly rate overtime is paid at: 52 weeks
+// of 40 hours.
+const standardHoursPerYear = 2080
+
+// SalaryChange is an annual salary in effect from a date.
+type SalaryChange struct {
+	From   time.Time
+	Annual float64
+}
+
+// StructureChange is a salary structure in effect from a date; a nil Structure pays the whole
+// salary as basic pay.
+type StructureChange struct {
+	From      time.Time
+	Structure *models.SalaryStructure
+}
+
+// StatutoryInput holds the statutory rules that apply to an employee and what they need beyond
+// the period's pay: the tax year's earlier amounts and approved declarations. Nil Rules means
+// no statutory deductions.
+type StatutoryInput struct {
+	Rules            statutory.Rules
+	PeriodsRemaining int
+	YTD              map[string]float64
+	Declarations     map[string]float64
+}
+
+// Reimbursement is an approved expense claim repaid with the period's net pay.
+type Reimbursement struct {
+	ClaimID uint
+	Label   string
+	Amount  float64
+}
+
+// LoanDeduction is a loan repayment due from the period's pay.
+type LoanDeduction struct {
+	LoanID uint
+	Label  string
+	Amount float64
+	Basis  string
+}
+
+// PayInput is what the payroll calculation needs for one employee and period. Dates are calendar
+// dates at midnight UTC; EmployedTo is the last day of employment, nil while employed. Absences
+// exclude days on approved paid leave; Overtime holds approved entries only.
+type PayInput struct {
+	Frequency      models.PayFrequency
+	WorkingDays    []time.Time
+	EmployedFrom   time.Time
+	EmployedTo     *time.Time
+	Salaries       []SalaryChange
+	Structures     []StructureChange
+	Absences       []time.Time
+	UnpaidLeave    []time.Time
+	Overtime       []models.OvertimeEntry
+	DepartmentID   *uint
+	Statutory      StatutoryInput
+	Reimbursements []Reimbursement
+	Loans          []LoanDeduction
+}
+
+// WorkingDays lists the weekdays from start to end inclusive.
+func WorkingDays(start, end time.Time) []time.Time {
+	var days []time.Time
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if wd := d.Weekday(); wd != time.Saturday && wd != time.Sunday {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+func roundMoney(v float64) float64 { return math.Round(v*100) / 100 }
+
+func dateKey(d time.Time) string { return d.Format("2006-01-02") }
+
+func dateSet(days []time.Time) map[string]bool {
+	set := make(map[string]bool, len(days))
+	for _, d := range days {
+		set[dateKey(d)] = true
+	}
+	return set
+}
+
+func (in *PayInput) employedOn(d time.Time) bool {
+	return !d.Before(in.EmployedFrom) && (in.EmployedTo == nil || !d.After(*in.EmployedTo))
+}
+
+// structureOn returns the salary structure in effect on d, nil for none; changes must be in
+// ascending order.
+func (in *PayInput) structureOn(d time.Time) *models.SalaryStructure {
+	var st *models.SalaryStructure
+	for _, c := range in.Structures {
+		if c.From.After(d) {
+			break
+		}
+		st = c.Structure
+	}
+	return st
+}
+
+// salaryOn returns the annual salary in effect on d; changes must be in ascending order.
+func (in *PayInput) salaryOn(d time.Time) float64 {
+	annual := 0.0
+	for _, c := range in.Salaries {
+		if c.From.After(d) {
+			break
+		}
+		annual = c.Annual
+	}
+	return annual
+}
+
+// CalculatePay computes one employee's payroll line. Every working day of employment pays its
+// share of the monthly components in effect that day: the annual salary expanded by the
+// employee's salary structure, or all of it as basic pay without one. A day's share is the
+// monthly amount times 12 / periods per year / working days of the period, so joiners, leavers,
+// raises and structure changes are prorated. Absences and unpaid leave on those days are taken
+// back as loss of pay at the day's earnings. Approved overtime pays the hourly rate (annual /
+// 2080) times its multiplier. Deduction components of the structure, then statutory
+// deductions, then active deduction rules, then loan repayments apply to gross pay; none takes
+// more than the pay left. Reimbursements are added to net pay last.
+func CalculatePay(in PayInput, rules []models.DeductionRule) (models.PayrollLine, error) {
+	line := models.PayrollLine{WorkingDays: len(in.WorkingDays), Items: []models.PayrollItem{}}
+	perYear := float64(in.Frequency.PeriodsPerYear())
+	if perYear == 0 || len(in.WorkingDays) == 0 {
+		return line, nil
+	}
+
+	// group the days of employment by the salary and structure in effect
+	type segment struct {
+		annual               float64
+		structure            *models.SalaryStructure
+		days, absent, unpaid int
+	}
+	var segments []*segment
+	absent, unpaid := dateSet(in.Absences), dateSet(in.UnpaidLeave)
+	for _, d := range in.WorkingDays {
+		if !in.employedOn(d) {
+			continue
+		}
+		annual, st := in.salaryOn(d), in.structureOn(d)
+		var seg *segment
+		for _, s := range segments {
+			if s.annual == annual && s.structure == st {
+				seg = s
+				break
+			}
+		}
+		if seg == nil {
+			seg = &segment{annual: annual, structure: st}
+			segments = append(segments, seg)
+		}
+		seg.days++
+		line.EmployedDays++
+		switch {
+		case unpaid[dateKey(d)]:
+			seg.unpaid++
+			line.UnpaidLeaveDays++
+		case absent[dateKey(d)]:
+			seg.absent++
+			line.AbsentDays++
+		}
+	}
+
+	// sum each component's share over the segments
+	type total struct {
+		ComponentAmount
+		amount float64
+		days   int
+	}
+	var totals []*total
+	byKey := map[string]*total{}
+	add := func(c ComponentAmount, amount float64, days int) {
+		key := string(c.Kind) + "/" + c.Code
+		t, ok := byKey[key]
+		if !ok {
+			t = &total{ComponentAmount: c}
+			byKey[key] = t
+			totals = append(totals, t)
+		}
+		t.amount += amount
+		t.days += days
+	}
+	basic := ComponentAmount{Code: models.BasicComponent, Name: "Basic pay", Kind: models.PayrollEarning}
+	if len(segments) == 0 {
+		add(basic, 0, 0)
+	}
+	var absentLoss, unpaidLoss float64
+	for _, seg := range segments {
+		comps := []ComponentAmount{basic}
+		comps[0].Monthly = seg.annual / 12
+		if seg.structure != nil {
+			b, err := ExpandSalary(seg.structure, seg.annual)
+			if err != nil {
+				return line, fmt.Errorf("salary structure %s: %w", seg.structure.Name, err)
+			}
+			comps = b.Components
+		}
+		dayEarnings := 0.0
+		for _, c := range comps {
+			share := c.Monthly * 12 / perYear / float64(len(in.WorkingDays))
+			if c.Kind == models.PayrollEarning {
+				dayEarnings += share
+			}
+			add(c, share*float64(seg.days), seg.days)
+		}
+		absentLoss += dayEarnings * float64(seg.absent)
+		unpaidLoss += dayEarnings * float64(seg.unpaid)
+	}
+
+	earn := func(code, label string, amount float64, basis string) {
+		amount = roundMoney(amount)
+		line.Items = append(line.Items, models.PayrollItem{Code: code, Label: label, Kind: models.PayrollEarning, Amount: amount, Basis: basis})
+		line.Gross += amount
+	}
+	for _, t := range totals {
+		if t.Kind == models.PayrollEarning {
+			earn(t.Code, t.Name, t.amount, fmt.Sprintf("%d of %d working days", t.days, line.WorkingDays))
+		}
+	}
+	if line.AbsentDays > 0 {
+		earn("LOP_ABSENCE", "Loss of pay: absence", -absentLoss, fmt.Sprintf("%d days", line.AbsentDays))
+	}
+	if line.UnpaidLeaveDays > 0 {
+		earn("LOP_UNPAID_LEAVE", "Loss of pay: unpaid leave", -unpaidLoss, fmt.Sprintf("%d days", line.UnpaidLeaveDays))
+	}
+	var overtime float64
+	for _, o := range in.Overtime {
+		if !in.employedOn(o.Date) {
+			continue
+		}
+		line.OvertimeHours += o.Hours
+		overtime += o.Hours * in.salaryOn(o.Date) / standardHoursPerYear * o.Multiplier
+	}
+	if line.OvertimeHours > 0 {
+		earn("OVERTIME", "Overtime", overtime, fmt.Sprintf("%g hours", line.OvertimeHours))
+	}
+	line.Gross = roundMoney(line.Gross)
+
+	remaining := line.Gross
+	deduct := func(code, label string, amount float64, basis string) float64 {
+		amount = roundMoney(amount)
+		if amount > remaining {
+			amount, basis = remaining, basis+", limited to remaining pay"
+		}
+		if amount <= 0 {
+			return 0
+		}
+		remaining = roundMoney(remaining - amount)
+		line.Deductions += amount
+		line.Items = append(line.Items, models.PayrollItem{Code: code, Label: label, Kind: models.PayrollDeduction, Amount: amount, Basis: basis})
+		return amount
+	}
+	for _, t := range totals {
+		if t.Kind == models.PayrollDeduction {
+			deduct(t.Code, t.Name, t.amount, fmt.Sprintf("%d of %d working days", t.days, line.WorkingDays))
+		}
+	}
+	if st := in.Statutory; st.Rules != nil {
+		earnings := map[string]float64{}
+		for _, it := range line.Items {
+			if it.Kind == models.PayrollEarning {
+				earnings[it.Code] += it.Amount
+			}
+		}
+		ds, err := st.Rules.Calculate(statutory.Input{
+			PeriodsPerYear:   int(perYear),
+			PeriodsRemaining: st.PeriodsRemaining,
+			Gross:            line.Gross,
+			Earnings:         earnings,
+			YTD:              st.YTD,
+			Declarations:     st.Declarations,
+		})
+		if err != nil {
+			return line, fmt.Errorf("statutory deductions: %w", err)
+		}
+		for _, d := range ds {
+			deduct(d.Code, d.Label, d.Amount, d.Basis)
+		}
+	}
+	sorted := append([]models.DeductionRule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].SortOrder < sorted[j].SortOrder })
+	for _, r := range sorted {
+		if !r.Active || r.DepartmentID != nil && (in.DepartmentID == nil || *r.DepartmentID != *in.DepartmentID) {
+			continue
+		}
+		amount, basis := r.Rate, fmt.Sprintf("%.2f per period", r.Rate)
+		if r.Kind == models.DeductionPercent {
+			amount, basis = line.Gross*r.Rate/100, fmt.Sprintf("%g%% of %.2f", r.Rate, line.Gross)
+		}
+		if r.Cap > 0 && amount > r.Cap {
+			amount, basis = r.Cap, basis+fmt.Sprintf(", capped at %.2f", r.Cap)
+		}
+		deduct(r.Code, r.Name, amount, basis)
+	}
+	for _, l := range in.Loans {
+		if amount := deduct("LOAN", l.Label, l.Amount, l.Basis); amount > 0 {
+			if line.LoanRepayments == nil {
+				line.LoanRepayments = map[uint]float64{}
+			}
+			line.LoanRepayments[l.LoanID] += amount
+		}
+	}
+	line.Deductions = roundMoney(line.Deductions)
+	for _, r := range in.Reimbursements {
+		amount := roundMoney(r.Amount)
+		line.Reimbursements += amount
+		line.Items = append(line.Items, models.PayrollItem{Code: "REIMBURSEMENT", Label: r.Label, Kind: models.PayrollReimbursement,
+			Amount: amount, Basis: fmt.Sprintf("expense claim %d", r.ClaimID)})
+	}
+	line.Reimbursements = roundMoney(line.Reimbursements)
+	line.Net = roundMoney(line.Gross - line.Deductions + line.Reimbursements)
+	return line, nil
+}
@@ -0,0 +1,663 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/hrms-backend/models"
+)
+
+var (
+	// ErrPayrollLocked rejects changes to attendance, leave, overtime or job data of days covered
+	// by a reviewed, finalized or paid payroll run.
+	ErrPayrollLocked = errors.New("payroll is locked for this period")
+	// ErrPayrollStatus rejects an action the payroll run's status does not allow.
+	ErrPayrollStatus = errors.New("action not allowed in the payroll run's status")
+	// ErrPayrollStale stops the review of a run whose inputs changed after it was calculated.
+	ErrPayrollStale = errors.New("payroll inputs changed since the run was calculated; recalculate it first")
+	// ErrPayPeriodInUse stops deleting a period that has a payroll run.
+	ErrPayPeriodInUse = errors.New("pay period has a payroll run")
+)
+
+var deductionCode = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,39}$`)
+
+// PayrollService manages pay periods, deduction rules, overtime and payroll runs. A run moves
+// DRAFT -> REVIEWED -> FINALIZED -> PAID; a reviewed run can be reopened to DRAFT. From review on
+// the period is locked (see guardPayroll), and only draft runs can be recalculated or deleted.
+type PayrollService struct {
+	db *gorm.DB
+	mu sync.Mutex // serializes the pay period overlap check
+}
+
+func NewPayrollService(db *gorm.DB) *PayrollService { return &PayrollService{db: db} }
+
+// guardPayroll must run in every transaction that changes payroll inputs (attendance, approved
+// leave, overtime, employment dates, job records) of the days from..to; a zero to means every
+// later day. It fails with ErrPayrollLocked when a locked run covers one of the days, and marks
+// covering draft runs stale so they are recalculated before review. Marking first takes the row
+// locks of those runs, which orders it with a concurrent review.
+func guardPayroll(tx *gorm.DB, from, to time.Time) error {
+	periods := "period_id IN (SELECT id FROM pay_periods WHERE end_date >= ?)"
+	args := []interface{}{from.Format("2006-01-02")}
+	if !to.IsZero() {
+		periods = "period_id IN (SELECT id FROM pay_periods WHERE end_date >= ? AND start_date <= ?)"
+		args = append(args, to.Format("2006-01-02"))
+	}
+	if err := tx.Model(&models.PayrollRun{}).Where("status = ?", models.PayrollDraft).Where(periods, args...).
+		UpdateColumn("stale", true).Error; err != nil {
+		return err
+	}
+	var locked models.PayrollRun
+	err := tx.Preload("Period").Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("status <> ?", models.PayrollDraft).Where(periods, args...).First(&locked).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: run for %s is %s", ErrPayrollLocked, locked.Period.Name, strings.ToLower(string(locked.Status)))
+}
+
+// markDraftRunsStale flags every draft run for recalculation, e.g. after deduction rules changed.
+func markDraftRunsStale(tx *gorm.DB) error {
+	return tx.Model(&models.PayrollRun{}).Where("status = ?", models.PayrollDraft).UpdateColumn("stale", true).Error
+}
+
+// Periods
+
+func (s *PayrollService) ListPeriods() ([]models.PayPeriod, error) {
+	var list []models.PayPeriod
+	err := s.db.Order("start_date desc").Find(&list).Error
+	return list, err
+}
+
+// CreatePeriod adds a period. Frequency defaults to monthly, PayDate to the last day and Name to
+// the date range; periods may not overlap.
+func (s *PayrollService) CreatePeriod(p *models.PayPeriod) error {
+	if p.Frequency == "" {
+		p.Frequency = models.PayMonthly
+	}
+	if p.Frequency.PeriodsPerYear() == 0 {
+		return errors.New("invalid frequency")
+	}
+	if p.StartDate.IsZero() || p.EndDate.Before(p.StartDate) {
+		return errors.New("end_date must not be before start_date")
+	}
+	if p.EndDate.Sub(p.StartDate) > 31*24*time.Hour {
+		return errors.New("a pay period cannot be longer than a month")
+	}
+	if p.PayDate.IsZero() {
+		p.PayDate = p.EndDate
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		p.Name = p.StartDate.Format("2006-01-02") + " to " + p.EndDate.Format("2006-01-02")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var other models.PayPeriod
+		err := tx.Where("start_date <= ? AND end_date >= ?", p.EndDate.Format("2006-01-02"), p.StartDate.Format("2006-01-02")).
+			First(&other).Error
+		if err == nil {
+			return fmt.Errorf("overlaps pay period %s", other.Name)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		var n int64
+		if err := tx.Model(&models.PayPeriod{}).Where("name = ?", p.Name).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("pay period %q already exists", p.Name)
+		}
+		return tx.Create(p).Error
+	})
+}
+
+func (s *PayrollService) DeletePeriod(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&models.PayrollRun{}).Where("period_id = ?", id).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrPayPeriodInUse
+		}
+		res := tx.Delete(&models.PayPeriod{}, id)
+		if res.Error == nil && res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return res.Error
+	})
+}
+
+// Deduction rules
+
+func (s *PayrollService) ListDeductions() ([]models.DeductionRule, error) {
+	var list []models.DeductionRule
+	err := s.db.Order("sort_order, id").Find(&list).Error
+	return list, err
+}
+
+// SaveDeduction creates the rule (ID 0) or replaces an existing one. Draft runs are marked for
+// recalculation; locked runs keep the deductions they were calculated with.
+func (s *PayrollService) SaveDeduction(r *models.DeductionRule) error {
+	r.Code = strings.ToUpper(strings.TrimSpace(r.Code))
+	r.Name = strings.TrimSpace(r.Name)
+	switch {
+	case !deductionCode.MatchString(r.Code):
+		return errors.New("code must be upper case letters, digits and underscores")
+	case r.Name == "":
+		return errors.New("name is required")
+	case r.Kind != models.DeductionPercent && r.Kind != models.DeductionFixed:
+		return errors.New("kind must be PERCENT or FIXED")
+	case r.Rate < 0 || r.Kind == models.DeductionPercent && r.Rate > 100:
+		return errors.New("rate out of range")
+	case r.Cap < 0:
+		return errors.New("cap must not be negative")
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&models.DeductionRule{}).Where("code = ? AND id <> ?", r.Code, r.ID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("deduction %q already exists", r.Code)
+		}
+		if r.ID != 0 {
+			var cur models.DeductionRule
+			if err := tx.First(&cur, r.ID).Error; err != nil {
+				return err
+			}
+			r.CreatedAt = cur.CreatedAt
+		}
+		if err := tx.Save(r).Error; err != nil {
+			return err
+		}
+		return markDraftRunsStale(tx)
+	})
+}
+
+func (s *PayrollService) DeleteDeduction(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&models.DeductionRule{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return markDraftRunsStale(tx)
+	})
+}
+
+// Overtime
+
+// OvertimeFilter narrows overtime listings; zero values match everything.
+type OvertimeFilter struct {
+	EmployeeID uint
+	Status     models.OvertimeStatus
+	From       *time.Time
+	To         *time.Time
+}
+
+func (s *PayrollService) ListOvertime(f OvertimeFilter) ([]models.OvertimeEntry, error) {
+	tx := s.db.Model(&models.OvertimeEntry{})
+	if f.EmployeeID != 0 {
+		tx = tx.Where("employee_id = ?", f.EmployeeID)
+	}
+	if f.Status != "" {
+		tx = tx.Where("status = ?", f.Status)
+	}
+	if f.From != nil {
+		tx = tx.Where("date >= ?", f.From.Format("2006-01-02"))
+	}
+	if f.To != nil {
+		tx = tx.Where("date <= ?", f.To.Format("2006-01-02"))
+	}
+	var list []models.OvertimeEntry
+	err := tx.Order("date desc, id desc").Find(&list).Error
+	return list, err
+}
+
+// SubmitOvertime records overtime worked on a past day for approval. Multiplier defaults to 1.5.
+func (s *PayrollService) SubmitOvertime(o *models.OvertimeEntry) error {
+	if o.Date.IsZero() || o.Date.After(today()) {
+		return errors.New("date must be a past day")
+	}
+	if o.Hours <= 0 || o.Hours > 24 {
+		return errors.New("hours must be between 0 and 24")
+	}
+	if o.Multiplier == 0 {
+		o.Multiplier = 1.5
+	}
+	if o.Multiplier < 1 || o.Multiplier > 5 {
+		return errors.New("multiplier must be between 1 and 5")
+	}
+	o.ID, o.Status, o.DecidedBy, o.DecidedAt = 0, models.OvertimePending, nil, nil
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := guardPayroll(tx, o.Date, o.Date); err != nil {
+			return err
+		}
+		return tx.Create(o).Error
+	})
+}
+
+// DecideOvertime approves or rejects a pending entry.
+func (s *PayrollService) DecideOvertime(id uint, approve bool, by uint) (*models.OvertimeEntry, error) {
+	var o models.OvertimeEntry
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&o, id).Error; err != nil {
+			return err
+		}
+		if o.Status != models.OvertimePending {
+			return errors.New("overtime entry was already decided")
+		}
+		if approve {
+			if err := guardPayroll(tx, o.Date, o.Date); err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		o.Status, o.DecidedBy, o.DecidedAt = models.OvertimeRejected, &by, &now
+		if approve {
+			o.Status = models.OvertimeApproved
+		}
+		return tx.Model(&o).Updates(map[string]interface{}{"status": o.Status, "decided_by": by, "decided_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// DeleteMyOvertime withdraws an employee's pending entry.
+func (s *PayrollService) DeleteMyOvertime(employeeID, id uint) error {
+	res := s.db.Where("id = ? AND employee_id = ? AND status = ?", id, employeeID, models.OvertimePending).
+		Delete(&models.OvertimeEntry{})
+	if res.Error == nil && res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return res.Error
+}
+
+// Runs
+
+func (s *PayrollService) ListRuns() ([]models.PayrollRun, error) {
+	var list []models.PayrollRun
+	err := s.db.Preload("Period").Order("id desc").Find(&list).Error
+	return list, err
+}
+
+// GetRun returns a run with its period and lines.
+func (s *PayrollService) GetRun(id uint) (*models.PayrollRun, error) {
+	var run models.PayrollRun
+	err := s.db.Preload("Period").Preload("Lines", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("employee_name, employee_id")
+	}).First(&run, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// CreateRun calculates a draft run for a period; a period has at most one run.
+func (s *PayrollService) CreateRun(periodID, by uint) (*models.PayrollRun, error) {
+	var run models.PayrollRun
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var period models.PayPeriod
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&period, periodID).Error; err != nil {
+			return err
+		}
+		var n int64
+		if err := tx.Model(&models.PayrollRun{}).Where("period_id = ?", periodID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("pay period %s already has a payroll run", period.Name)
+		}
+		run = models.PayrollRun{PeriodID: periodID, Status: models.PayrollDraft, CreatedBy: by}
+		if err := tx.Create(&run).Error; err != nil {
+			return err
+		}
+		return s.calculate(tx, &run, &period)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetRun(run.ID)
+}
+
+// RecalculateRun replaces a draft run's lines with freshly calculated ones.
+func (s *PayrollService) RecalculateRun(id uint) (*models.PayrollRun, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		run, err := lockRun(tx, id, models.PayrollDraft)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("run_id = ?", run.ID).Delete(&models.PayrollLine{}).Error; err != nil {
+			return err
+		}
+		return s.calculate(tx, run, run.Period)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetRun(id)
+}
+
+// DeleteRun removes a draft run.
+func (s *PayrollService) DeleteRun(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		run, err := lockRun(tx, id, models.PayrollDraft)
+		if err != nil {
+			return err
+		}
+		if err := releaseClaims(tx, run.ID); err != nil {
+			return err
+		}
+		if err := releaseLoanRepayments(tx, run.ID); err != nil {
+			return err
+		}
+		return tx.Select("Lines").Delete(run).Error
+	})
+}
+
+// Review marks a draft run as checked, which locks its period.
+func (s *PayrollService) Review(id, by uint) (*models.PayrollRun, error) {
+	return s.transition(id, models.PayrollDraft, models.PayrollReviewed, func(_ *gorm.DB, run *models.PayrollRun) (map[string]interface{}, error) {
+		if run.Stale {
+			return nil, ErrPayrollStale
+		}
+		return map[string]interface{}{"reviewed_by": by, "reviewed_at": time.Now()}, nil
+	})
+}
+
+// Reopen sends a reviewed run back to draft, unlocking its period.
+func (s *PayrollService) Reopen(id uint) (*models.PayrollRun, error) {
+	return s.transition(id, models.PayrollReviewed, models.PayrollDraft, func(*gorm.DB, *models.PayrollRun) (map[string]interface{}, error) {
+		return map[string]interface{}{"reviewed_by": nil, "reviewed_at": nil}, nil
+	})
+}
+
+// Finalize freezes a reviewed run; its lines are final from then on, the expense claims it
+// reimburses are paid and its loan repayments are posted.
+func (s *PayrollService) Finalize(id, by uint) (*models.PayrollRun, error) {
+	return s.transition(id, models.PayrollReviewed, models.PayrollFinalized, func(tx *gorm.DB, run *models.PayrollRun) (map[string]interface{}, error) {
+		if err := tx.Model(&models.ExpenseClaim{}).Where("payroll_run_id = ? AND status = ?", run.ID, models.ExpenseApproved).
+			Updates(map[string]interface{}{"status": models.ExpensePaid, "paid_at": run.Period.PayDate,
+				"payment_reference": fmt.Sprintf("payroll %s", run.Period.Name)}).Error; err != nil {
+			return nil, err
+		}
+		if err := postLoanRepayments(tx, run); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"finalized_by": by, "finalized_at": time.Now()}, nil
+	})
+}
+
+// MarkPaid records that a finalized run has been paid out.
+func (s *PayrollService) MarkPaid(id, by uint) (*models.PayrollRun, error) {
+	return s.transition(id, models.PayrollFinalized, models.PayrollPaid, func(*gorm.DB, *models.PayrollRun) (map[string]interface{}, error) {
+		return map[string]interface{}{"paid_by": by, "paid_at": time.Now()}, nil
+	})
+}
+
+// lockRun loads a run with its period for update and checks that it is in status from.
+func lockRun(tx *gorm.DB, id uint, from models.PayrollRunStatus) (*models.PayrollRun, error) {
+	var run models.PayrollRun
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: clause.CurrentTable}}).
+		Preload("Period").First(&run, id).Error; err != nil {
+		return nil, err
+	}
+	if run.Status != from {
+		return nil, fmt.Errorf("%w: run is %s, expected %s", ErrPayrollStatus, run.Status, from)
+	}
+	return &run, nil
+}
+
+func (s *PayrollService) transition(id uint, from, to models.PayrollRunStatus, changes func(*gorm.DB, *models.PayrollRun) (map[string]interface{}, error)) (*models.PayrollRun, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		run, err := lockRun(tx, id, from)
+		if err != nil {
+			return err
+		}
+		updates, err := changes(tx, run)
+		if err != nil {
+			return err
+		}
+		updates["status"] = to
+		updates["version"] = run.Version + 1
+		return tx.Model(&models.PayrollRun{}).Where("id = ?", run.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetRun(id)
+}
+
+// calculate computes the lines of run for period and stores them with the run's totals. The
+// approved expense claims it reimburses are reserved for the run, and the loan repayments it
+// deducts are recorded.
+func (s *PayrollService) calculate(tx *gorm.DB, run *models.PayrollRun, period *models.PayPeriod) error {
+	if err := releaseClaims(tx, run.ID); err != nil {
+		return err
+	}
+	if err := releaseLoanRepayments(tx, run.ID); err != nil {
+		return err
+	}
+	reimbursements, err := expenseReimbursements(tx)
+	if err != nil {
+		return err
+	}
+	loans, err := loanDeductions(tx, period)
+	if err != nil {
+		return err
+	}
+	lines, days, err := payrollLines(tx, period, reimbursements, loans)
+	if err != nil {
+		return err
+	}
+	if err := recordLoanRepayments(tx, run, period, lines); err != nil {
+		return err
+	}
+	var claims []uint
+	for _, l := range lines {
+		for _, r := range reimbursements[l.EmployeeID] {
+			claims = append(claims, r.ClaimID)
+		}
+	}
+	if len(claims) > 0 {
+		if err := tx.Model(&models.ExpenseClaim{}).Where("id IN ?", claims).Update("payroll_run_id", run.ID).Error; err != nil {
+			return err
+		}
+	}
+	var gross, deductions, net float64
+	for i := range lines {
+		lines[i].RunID = run.ID
+		gross += lines[i].Gross
+		deductions += lines[i].Deductions
+		net += lines[i].Net
+	}
+	if len(lines) > 0 {
+		if err := tx.CreateInBatches(lines, 200).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.PayrollRun{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+		"working_days":     days,
+		"employees":        len(lines),
+		"total_gross":      roundMoney(gross),
+		"total_deductions": roundMoney(deductions),
+		"total_net":        roundMoney(net),
+		"calculated_at":    time.Now(),
+		"stale":            false,
+		"version":          run.Version + 1,
+	}).Error
+}
+
+// payrollLines gathers the inputs of everyone employed during period and calculates their pay,
+// adding the given reimbursements and deducting the given loan repayments by employee. Inactive
+// employees and leavers whose final settlement pays the period are not paid. The first job record
+// marks the start of employment.
+func payrollLines(tx *gorm.DB, period *models.PayPeriod, reimbursements map[uint][]Reimbursement, loans map[uint][]LoanDeduction) ([]models.PayrollLine, int, error) {
+	start, end := period.StartDate, period.EndDate
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+	days := WorkingDays(start, end)
+
+	var emps []models.Employee
+	if err := tx.Where("status <> ? AND (status <> ? OR termination_date >= ?)",
+		models.EmploymentInactive, models.EmploymentTerminated, from).Order("id").Find(&emps).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(emps) == 0 {
+		return nil, len(days), nil
+	}
+	ids := make([]uint, len(emps))
+	for i := range emps {
+		ids[i] = emps[i].ID
+	}
+
+	var jobs []models.JobRecord
+	if err := tx.Where("employee_id IN ? AND effective_date <= ?", ids, to).
+		Order("employee_id, effective_date, id").Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	salaries := map[uint][]SalaryChange{}
+	for _, j := range jobs {
+		salaries[j.EmployeeID] = append(salaries[j.EmployeeID], SalaryChange{From: j.EffectiveDate, Annual: j.Salary})
+	}
+
+	var assignments []models.SalaryStructureAssignment
+	if err := tx.Preload("Structure.Components").Where("employee_id IN ? AND effective_date <= ?", ids, to).
+		Order("employee_id, effective_date").Find(&assignments).Error; err != nil {
+		return nil, 0, err
+	}
+	// share one structure value per id, so days on the same structure form one segment
+	structures, structureChanges := map[uint]*models.SalaryStructure{}, map[uint][]StructureChange{}
+	for _, a := range assignments {
+		st, ok := structures[a.StructureID]
+		if !ok {
+			st = a.Structure
+			structures[a.StructureID] = st
+		}
+		structureChanges[a.EmployeeID] = append(structureChanges[a.EmployeeID], StructureChange{From: a.EffectiveDate, Structure: st})
+	}
+
+	absent, unpaidLeave, err := unpaidDays(tx, ids, start, end)
+	if err != nil {
+		return nil, 0, err
+	}
+	var overtime []models.OvertimeEntry
+	if err := tx.Where("employee_id IN ? AND status = ? AND date BETWEEN ? AND ?", ids, models.OvertimeApproved, from, to).
+		Find(&overtime).Error; err != nil {
+		return nil, 0, err
+	}
+	overtimeBy := map[uint][]models.OvertimeEntry{}
+	for _, o := range overtime {
+		overtimeBy[o.EmployeeID] = append(overtimeBy[o.EmployeeID], o)
+	}
+	var rules []models.DeductionRule
+	if err := tx.Where("active").Order("sort_order, id").Find(&rules).Error; err != nil {
+		return nil, 0, err
+	}
+	statutoryBy, err := statutoryInputs(tx, period, emps)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// a finalized final settlement pays leavers from its pay_from date
+	var settled []uint
+	if err := tx.Model(&models.FinalSettlement{}).Where("status = ? AND employee_id IN ? AND pay_from <= ?", models.SettlementFinalized, ids, to).
+		Pluck("employee_id", &settled).Error; err != nil {
+		return nil, 0, err
+	}
+	skip := map[uint]bool{}
+	for _, id := range settled {
+		skip[id] = true
+	}
+
+	var lines []models.PayrollLine
+	for _, e := range emps {
+		changes := salaries[e.ID]
+		if len(changes) == 0 || skip[e.ID] {
+			// hired after the period, or paid by a final settlement
+			continue
+		}
+		in := PayInput{
+			Frequency:      period.Frequency,
+			WorkingDays:    days,
+			EmployedFrom:   changes[0].From,
+			EmployedTo:     e.TerminationDate,
+			Salaries:       changes,
+			Structures:     structureChanges[e.ID],
+			Absences:       absent[e.ID],
+			UnpaidLeave:    unpaidLeave[e.ID],
+			Overtime:       overtimeBy[e.ID],
+			DepartmentID:   e.DepartmentID,
+			Statutory:      statutoryBy[e.ID],
+			Reimbursements: reimbursements[e.ID],
+			Loans:          loans[e.ID],
+		}
+		line, err := CalculatePay(in, rules)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", e.Name, err)
+		}
+		if line.EmployedDays == 0 && line.OvertimeHours == 0 && line.Reimbursements == 0 {
+			continue
+		}
+		line.EmployeeID, line.EmployeeName, line.Department = e.ID, e.Name, e.Department
+		lines = append(lines, line)
+	}
+	return lines, len(days), nil
+}
+
+// unpaidDays returns, by employee, the absences between start and end that approved paid leave
+// does not cover and the days of approved unpaid leave.
+func unpaidDays(tx *gorm.DB, ids []uint, start, end time.Time) (absent, unpaidLeave map[uint][]time.Time, err error) {
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+	var absences []models.Attendance
+	if err := tx.Where("employee_id IN ? AND status = ? AND date BETWEEN ? AND ?", ids, models.StatusAbsent, from, to).
+		Find(&absences).Error; err != nil {
+		return nil, nil, err
+	}
+	var leaves []models.Leave
+	if err := tx.Where("employee_id IN ? AND status = ? AND start_date <= ? AND end_date >= ?", ids, models.LeaveApproved, to, from).
+		Find(&leaves).Error; err != nil {
+		return nil, nil, err
+	}
+	paidLeave := map[uint]map[string]bool{}
+	unpaidLeave = map[uint][]time.Time{}
+	for _, lv := range leaves {
+		for d := lv.StartDate; !d.After(lv.EndDate); d = d.AddDate(0, 0, 1) {
+			if d.Before(start) || d.After(end) {
+				continue
+			}
+			if lv.Type == models.LeaveUnpaid {
+				unpaidLeave[lv.EmployeeID] = append(unpaidLeave[lv.EmployeeID], d)
+				continue
+			}
+			if paidLeave[lv.EmployeeID] == nil {
+				paidLeave[lv.EmployeeID] = map[string]bool{}
+			}
+			paidLeave[lv.EmployeeID][dateKey(d)] = true
+		}
+	}
+	absent = map[uint][]time.Time{}
+	for _, a := range absences {
+		if !paidLeave[a.EmployeeID][dateKey(a.Date)] {
+			absent[a.EmployeeID] = append(absent[a.EmployeeID], a.Date)
+		}
+	}
+	return absent, unpaidLeave, nil
+}
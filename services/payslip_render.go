@@ -0,0 +1,270 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/pdf"
+)
+
+// builtinPayslipTemplate is used while no default template has been configured.
+var builtinPayslipTemplate = models.PayslipTemplate{
+	Name:              "Built-in",
+	Title:             "Payslip",
+	BrandColor:        "#1F3A5F",
+	ShowYTD:           true,
+	ShowLeaveBalances: true,
+}
+
+// PayslipData is everything printed on one payslip.
+type PayslipData struct {
+	Template models.PayslipTemplate
+	Logo     []byte // JPEG, optional
+	Period   models.PayPeriod
+	Line     models.PayrollLine
+	Employee models.Employee
+	// YTD sums the items of the employee's finalized lines paid in the calendar year up to and
+	// including this one, by item code; the totals use the codes "GROSS", "DEDUCTIONS" and "NET".
+	YTD         map[string]float64
+	Balances    []models.LeaveBalance
+	Password    string
+	GeneratedAt time.Time
+}
+
+// money formats an amount with two decimals and thousands separators.
+func money(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac := s[:len(s)-3], s[len(s)-3:]
+	var b strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String() + frac
+}
+
+// maskAccount keeps the last four characters of an account number.
+func maskAccount(n string) string {
+	n = strings.ReplaceAll(n, " ", "")
+	if len(n) <= 4 {
+		return n
+	}
+	return strings.Repeat("*", 4) + n[len(n)-4:]
+}
+
+const (
+	slipMargin = 40.0
+	slipRight  = pdf.PageWidth - slipMargin
+	slipRow    = 16.0
+	slipBottom = pdf.PageHeight - 70
+)
+
+// slipLayout tracks the vertical position on the current page and starts new pages.
+type slipLayout struct {
+	doc        *pdf.Document
+	page       *pdf.Page
+	y          float64
+	r, g, b    float64
+	footer     string
+	pageNumber int
+}
+
+func (l *slipLayout) newPage() {
+	l.page = l.doc.AddPage()
+	l.pageNumber++
+	l.y = slipMargin
+	if l.footer != "" {
+		l.page.SetFillColor(0.4, 0.4, 0.4)
+		l.page.Text(slipMargin, pdf.PageHeight-40, pdf.Helvetica, 8, l.footer)
+	}
+	l.page.SetFillColor(0.4, 0.4, 0.4)
+	l.page.TextRight(slipRight, pdf.PageHeight-40, pdf.Helvetica, 8, fmt.Sprintf("Page %d", l.pageNumber))
+	l.page.SetFillColor(0, 0, 0)
+}
+
+// need starts a new page unless h more points fit on this one.
+func (l *slipLayout) need(h float64) {
+	if l.y+h > slipBottom {
+		l.newPage()
+	}
+}
+
+// heading prints a section title over a brand-colored rule.
+func (l *slipLayout) heading(title string, columns ...string) {
+	l.need(3 * slipRow)
+	l.y += slipRow
+	l.page.SetFillColor(l.r, l.g, l.b)
+	l.page.Rect(slipMargin, l.y-11, slipRight-slipMargin, slipRow)
+	l.page.SetFillColor(1, 1, 1)
+	l.page.Text(slipMargin+4, l.y, pdf.HelveticaBold, 9, title)
+	for i, c := range columns {
+		l.page.TextRight(slipRight-4-float64(len(columns)-1-i)*95, l.y, pdf.HelveticaBold, 9, c)
+	}
+	l.page.SetFillColor(0, 0, 0)
+	l.y += slipRow
+}
+
+// row prints a label with right-aligned values in the columns of the last heading.
+func (l *slipLayout) row(font pdf.Font, label string, values ...string) {
+	l.need(slipRow)
+	l.page.Text(slipMargin+4, l.y, font, 9, label)
+	for i, v := range values {
+		l.page.TextRight(slipRight-4-float64(len(values)-1-i)*95, l.y, font, 9, v)
+	}
+	l.page.SetStrokeColor(0.85, 0.85, 0.85)
+	l.page.Line(slipMargin, l.y+5, slipRight, l.y+5, 0.5)
+	l.y += slipRow
+}
+
+// RenderPayslip lays out a payslip as a PDF, protected by d.Password when it is set.
+func RenderPayslip(d *PayslipData) ([]byte, error) {
+	t := d.Template
+	doc := pdf.New()
+	title := t.Title
+	if title == "" {
+		title = "Payslip"
+	}
+	doc.SetInfo(fmt.Sprintf("%s %s - %s", title, d.Period.Name, d.Line.EmployeeName), t.CompanyName)
+	doc.SetPassword(d.Password)
+	r, g, b, err := pdf.ParseColor(t.BrandColor)
+	if err != nil {
+		r, g, b, _ = pdf.ParseColor(builtinPayslipTemplate.BrandColor)
+	}
+	l := &slipLayout{doc: doc, r: r, g: g, b: b, footer: t.FooterText}
+	l.newPage()
+	p := l.page
+
+	// header band with logo, company and title
+	const band = 80.0
+	p.SetFillColor(r, g, b)
+	p.Rect(0, 0, pdf.PageWidth, band)
+	x := slipMargin
+	if len(d.Logo) > 0 {
+		img, err := doc.AddJPEG(d.Logo)
+		if err != nil {
+			return nil, fmt.Errorf("logo: %w", err)
+		}
+		h := 50.0
+		w := h * float64(img.Width) / float64(img.Height)
+		if w > 150 {
+			w, h = 150, 150*float64(img.Height)/float64(img.Width)
+		}
+		p.Image(img, x, (band-h)/2, w, h)
+		x += w + 12
+	}
+	p.SetFillColor(1, 1, 1)
+	p.Text(x, 34, pdf.HelveticaBold, 15, t.CompanyName)
+	for i, line := range strings.Split(strings.TrimSpace(t.CompanyAddress), "\n") {
+		if i == 3 {
+			break
+		}
+		p.Text(x, 48+float64(i)*10, pdf.Helvetica, 8, strings.TrimSpace(line))
+	}
+	p.TextRight(slipRight, 34, pdf.HelveticaBold, 18, title)
+	p.TextRight(slipRight, 50, pdf.Helvetica, 10, d.Period.Name)
+	p.SetFillColor(0, 0, 0)
+	l.y = band + 28
+
+	// employee and period details in two columns
+	left := [][2]string{
+		{"Employee", d.Line.EmployeeName},
+		{"Employee ID", strconv.FormatUint(uint64(d.Line.EmployeeID), 10)},
+		{"Department", d.Line.Department},
+		{"Position", d.Employee.Position},
+	}
+	right := [][2]string{
+		{"Pay period", d.Period.StartDate.Format("02 Jan 2006") + " - " + d.Period.EndDate.Format("02 Jan 2006")},
+		{"Pay date", d.Period.PayDate.Format("02 Jan 2006")},
+		{"Working days", strconv.Itoa(d.Line.WorkingDays)},
+		{"Paid days", strconv.Itoa(d.Line.EmployedDays - d.Line.AbsentDays - d.Line.UnpaidLeaveDays)},
+	}
+	if t.ShowBankAccount && d.Employee.BankAccountNumber != "" {
+		left = append(left, [2]string{"Bank account", strings.TrimSpace(d.Employee.BankName + " " + maskAccount(d.Employee.BankAccountNumber))})
+	}
+	if d.Line.OvertimeHours > 0 {
+		right = append(right, [2]string{"Overtime hours", strconv.FormatFloat(d.Line.OvertimeHours, 'f', -1, 64)})
+	}
+	mid := pdf.PageWidth / 2
+	for i := 0; i < len(left) || i < len(right); i++ {
+		if i < len(left) {
+			p.Text(slipMargin, l.y, pdf.Helvetica, 9, left[i][0])
+			p.Text(slipMargin+80, l.y, pdf.HelveticaBold, 9, left[i][1])
+		}
+		if i < len(right) {
+			p.Text(mid+10, l.y, pdf.Helvetica, 9, right[i][0])
+			p.Text(mid+90, l.y, pdf.HelveticaBold, 9, right[i][1])
+		}
+		l.y += 14
+	}
+
+	// earnings, deductions and expense reimbursements
+	columns := []string{"Amount"}
+	if t.ShowYTD {
+		columns = append(columns, "Year to date")
+	}
+	values := func(code string, amount float64) []string {
+		if t.ShowYTD {
+			return []string{money(amount), money(d.YTD[code])}
+		}
+		return []string{money(amount)}
+	}
+	kinds := []models.PayrollItemKind{models.PayrollEarning, models.PayrollDeduction}
+	if d.Line.Reimbursements != 0 {
+		kinds = append(kinds, models.PayrollReimbursement)
+	}
+	for _, kind := range kinds {
+		heading, total, code, sum := "Earnings", "Gross pay", "GROSS", d.Line.Gross
+		switch kind {
+		case models.PayrollDeduction:
+			heading, total, code, sum = "Deductions", "Total deductions", "DEDUCTIONS", d.Line.Deductions
+		case models.PayrollReimbursement:
+			heading, total, code, sum = "Reimbursements", "Total reimbursements", "REIMBURSEMENTS", d.Line.Reimbursements
+		}
+		l.heading(heading, columns...)
+		for _, it := range d.Line.Items {
+			if it.Kind == kind {
+				l.row(pdf.Helvetica, it.Label, values(it.Code, it.Amount)...)
+			}
+		}
+		l.row(pdf.HelveticaBold, total, values(code, sum)...)
+	}
+	l.need(3 * slipRow)
+	l.y += slipRow
+	p = l.page
+	p.SetFillColor(0.93, 0.93, 0.93)
+	p.Rect(slipMargin, l.y-14, slipRight-slipMargin, 22)
+	p.SetFillColor(0, 0, 0)
+	p.Text(slipMargin+4, l.y, pdf.HelveticaBold, 12, "Net pay")
+	net := values("NET", d.Line.Net)
+	for i, v := range net {
+		p.TextRight(slipRight-4-float64(len(net)-1-i)*95, l.y, pdf.HelveticaBold, 12, v)
+	}
+	l.y += slipRow
+
+	if t.ShowLeaveBalances && len(d.Balances) > 0 {
+		l.heading(fmt.Sprintf("Leave balances as of %s", d.Period.EndDate.Format("02 Jan 2006")), "Entitlement", "Taken", "Remaining")
+		for _, bal := range d.Balances {
+			name := strings.ToUpper(string(bal.LeaveType[:1])) + strings.ToLower(string(bal.LeaveType[1:]))
+			l.row(pdf.Helvetica, name,
+				strconv.FormatFloat(bal.Entitlement, 'f', -1, 64),
+				strconv.FormatFloat(bal.Taken, 'f', -1, 64),
+				strconv.FormatFloat(bal.Remaining, 'f', -1, 64))
+		}
+	}
+
+	l.need(2 * slipRow)
+	l.y += slipRow
+	l.page.SetFillColor(0.4, 0.4, 0.4)
+	l.page.Text(slipMargin, l.y, pdf.Helvetica, 8,
+		"This is a computer-generated payslip generated on "+d.GeneratedAt.UTC().Format("02 Jan 2006 15:04 UTC")+".")
+	return doc.Bytes()
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/pdf"
+	"github.com/example/hrms-backend/storage"
+)
+
+var (
+	ErrPayslipForbidden = errors.New("not allowed to access this payslip")
+	ErrRunNotFinalized  = errors.New("payslips are generated for finalized runs only")
+)
+
+// maxLogoBytes bounds template logos; they are embedded in every payslip.
+const maxLogoBytes = 512 << 10
+
+// PayslipService renders payslips of finalized payroll runs into blob storage and manages the
+// templates they are rendered from.
+type PayslipService struct {
+	db    *gorm.DB
+	store storage.BlobStore
+}
+
+func NewPayslipService(db *gorm.DB, store storage.BlobStore) *PayslipService {
+	return &PayslipService{db: db, store: store}
+}
+
+// Templates
+
+func (s *PayslipService) ListTemplates() ([]models.PayslipTemplate, error) {
+	var list []models.PayslipTemplate
+	if err := s.db.Order("name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].HasLogo = list[i].LogoKey != ""
+	}
+	return list, nil
+}
+
+func (s *PayslipService) GetTemplate(id uint) (*models.PayslipTemplate, error) {
+	var t models.PayslipTemplate
+	if err := s.db.First(&t, id).Error; err != nil {
+		return nil, err
+	}
+	t.HasLogo = t.LogoKey != ""
+	return &t, nil
+}
+
+// SaveTemplate creates the template (ID 0) or replaces an existing one, keeping its logo. Making
+// a template the default clears the flag on the others.
+func (s *PayslipService) SaveTemplate(t *models.PayslipTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if t.BrandColor == "" {
+		t.BrandColor = builtinPayslipTemplate.BrandColor
+	}
+	if _, _, _, err := pdf.ParseColor(t.BrandColor); err != nil {
+		return errors.New("brand_color must look like #1F3A5F")
+	}
+	t.BrandColor = strings.ToUpper(t.BrandColor)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&models.PayslipTemplate{}).Where("name = ? AND id <> ?", t.Name, t.ID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("a template named %q already exists", t.Name)
+		}
+		if t.ID != 0 {
+			var cur models.PayslipTemplate
+			if err := tx.First(&cur, t.ID).Error; err != nil {
+				return err
+			}
+			t.CreatedAt, t.LogoKey = cur.CreatedAt, cur.LogoKey
+		}
+		if t.IsDefault {
+			if err := tx.Model(&models.PayslipTemplate{}).Where("is_default AND id <> ?", t.ID).
+				Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(t).Error; err != nil {
+			return err
+		}
+		t.HasLogo = t.LogoKey != ""
+		return nil
+	})
+}
+
+// DeleteTemplate removes a template and its logo. Payslips rendered from it are kept.
+func (s *PayslipService) DeleteTemplate(id uint) error {
+	var t models.PayslipTemplate
+	if err := s.db.First(&t, id).Error; err != nil {
+		return err
+	}
+	if err := s.db.Delete(&t).Error; err != nil {
+		return err
+	}
+	if t.LogoKey != "" {
+		return s.store.Delete(t.LogoKey)
+	}
+	return nil
+}
+
+// SetLogo replaces the template's logo with a JPEG upload; an empty upload removes it.
+func (s *PayslipService) SetLogo(id uint, r io.Reader) (*models.PayslipTemplate, error) {
+	t, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxLogoBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxLogoBytes {
+		return nil, ErrUploadTooLarge
+	}
+	key := ""
+	if len(data) > 0 {
+		if _, err := pdf.New().AddJPEG(data); err != nil {
+			return nil, errors.New("the logo must be a JPEG image")
+		}
+		key = fmt.Sprintf("payslips/templates/%d/%d.jpg", t.ID, time.Now().UnixNano())
+		if err := s.store.Put(key, bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.db.Model(t).Update("logo_key", key).Error; err != nil {
+		if key != "" {
+			_ = s.store.Delete(key)
+		}
+		return nil, err
+	}
+	old := t.LogoKey
+	t.LogoKey, t.HasLogo = key, key != ""
+	if old != "" {
+		if err := s.store.Delete(old); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// defaultTemplate returns the default template with its logo, or the built-in one.
+func (s *PayslipService) defaultTemplate() (*models.PayslipTemplate, []byte, error) {
+	var t models.PayslipTemplate
+	err := s.db.Where("is_default").First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		t = builtinPayslipTemplate
+		return &t, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if t.LogoKey == "" {
+		return &t, nil, nil
+	}
+	rc, err := s.store.Open(t.LogoKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("logo: %w", err)
+	}
+	defer rc.Close()
+	logo, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("logo: %w", err)
+	}
+	return &t, logo, nil
+}
+
+// Passwords
+
+// SetPassword sets the employee's payslip password, or removes it when empty, and regenerates
+// their existing payslips so that all of them open with the current password. It returns how
+// many payslips were regenerated.
+func (s *PayslipService) SetPassword(employeeID uint, password string) (int, error) {
+	if n := utf8.RuneCountInString(password); password != "" && (n < 6 || n > 32) {
+		return 0, errors.New("the password must have 6 to 32 characters")
+	}
+	if password == "" {
+		if err := s.db.Where("employee_id = ?", employeeID).Delete(&models.PayslipPreference{}).Error; err != nil {
+			return 0, err
+		}
+	} else {
+		var pref models.PayslipPreference
+		err := s.db.Where("employee_id = ?", employeeID).First(&pref).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, err
+		}
+		pref.EmployeeID, pref.Password = employeeID, password
+		if err := s.db.Save(&pref).Error; err != nil {
+			return 0, err
+		}
+	}
+	var runIDs []uint
+	if err := s.db.Model(&models.Payslip{}).Where("employee_id = ?", employeeID).Pluck("run_id", &runIDs).Error; err != nil {
+		return 0, err
+	}
+	tpl, logo, err := s.defaultTemplate()
+	if err != nil {
+		return 0, err
+	}
+	for i, runID := range runIDs {
+		var line models.PayrollLine
+		if err := s.db.Where("run_id = ? AND employee_id = ?", runID, employeeID).First(&line).Error; err != nil {
+			return i, err
+		}
+		var run models.PayrollRun
+		if err := s.db.Preload("Period").First(&run, runID).Error; err != nil {
+			return i, err
+		}
+		if _, err := s.generate(&run, &line, tpl, logo); err != nil {
+			return i, err
+		}
+	}
+	return len(runIDs), nil
+}
+
+// HasPassword reports whether the employee has set a payslip password.
+func (s *PayslipService) HasPassword(employeeID uint) (bool, error) {
+	var n int64
+	err := s.db.Model(&models.PayslipPreference{}).Where("employee_id = ?", employeeID).Count(&n).Error
+	return n > 0, err
+}
+
+// Generation
+
+// GenerateRun renders the payslips of every line of a finalized or paid run with the current
+// default template, replacing earlier ones. It returns the payslips.
+func (s *PayslipService) GenerateRun(runID uint) ([]models.Payslip, error) {
+	var run models.PayrollRun
+	if err := s.db.Preload("Period").First(&run, runID).Error; err != nil {
+		return nil, err
+	}
+	if run.Status != models.PayrollFinalized && run.Status != models.PayrollPaid {
+		return nil, ErrRunNotFinalized
+	}
+	var lines []models.PayrollLine
+	if err := s.db.Where("run_id = ?", runID).Order("employee_name, id").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	tpl, logo, err := s.defaultTemplate()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Payslip, 0, len(lines))
+	for i := range lines {
+		slip, err := s.generate(&run, &lines[i], tpl, logo)
+		if err != nil {
+			return out, fmt.Errorf("payslip of %s: %w", lines[i].EmployeeName, err)
+		}
+		out = append(out, *slip)
+	}
+	return out, nil
+}
+
+// ytd sums the employee's finalized lines paid from January 1 of the pay date's year up to it.
+func (s *PayslipService) ytd(employeeID uint, payDate time.Time) (map[string]float64, error) {
+	var lines []models.PayrollLine
+	yearStart := time.Date(payDate.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.db.Select("payroll_lines.*").
+		Joins("JOIN payroll_runs r ON r.id = payroll_lines.run_id").
+		Joins("JOIN pay_periods p ON p.id = r.period_id").
+		Where("payroll_lines.employee_id = ? AND r.status IN ? AND p.pay_date BETWEEN ? AND ?",
+			employeeID, []models.PayrollRunStatus{models.PayrollFinalized, models.PayrollPaid}, yearStart, payDate).
+		Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	sums := map[string]float64{}
+	for _, l := range lines {
+		for _, it := range l.Items {
+			sums[it.Code] = roundMoney(sums[it.Code] + it.Amount)
+		}
+		sums["GROSS"] = roundMoney(sums["GROSS"] + l.Gross)
+		sums["DEDUCTIONS"] = roundMoney(sums["DEDUCTIONS"] + l.Deductions)
+		sums["REIMBURSEMENTS"] = roundMoney(sums["REIMBURSEMENTS"] + l.Reimbursements)
+		sums["NET"] = roundMoney(sums["NET"] + l.Net)
+	}
+	return sums, nil
+}
+
+// generate renders and stores the payslip of one line, replacing an earlier file.
+func (s *PayslipService) generate(run *models.PayrollRun, line *models.PayrollLine, tpl *models.PayslipTemplate, logo []byte) (*models.Payslip, error) {
+	var emp models.Employee
+	if err := s.db.First(&emp, line.EmployeeID).Error; err != nil {
+		return nil, err
+	}
+	var pref models.PayslipPreference
+	if err := s.db.Where("employee_id = ?", line.EmployeeID).Limit(1).Find(&pref).Error; err != nil {
+		return nil, err
+	}
+	data := PayslipData{
+		Template:    *tpl,
+		Logo:        logo,
+		Period:      *run.Period,
+		Line:        *line,
+		Employee:    emp,
+		Password:    pref.Password,
+		GeneratedAt: time.Now(),
+	}
+	var err error
+	if tpl.ShowYTD {
+		if data.YTD, err = s.ytd(line.EmployeeID, run.Period.PayDate); err != nil {
+			return nil, err
+		}
+	}
+	if tpl.ShowLeaveBalances {
+		if data.Balances, err = leaveBalances(s.db, line.EmployeeID, run.Period.EndDate); err != nil {
+			return nil, err
+		}
+	}
+	file, err := RenderPayslip(&data)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("payslips/%d/%d-%d.pdf", line.EmployeeID, run.ID, time.Now().UnixNano())
+	if err := s.store.Put(key, bytes.NewReader(file)); err != nil {
+		return nil, err
+	}
+
+	var slip models.Payslip
+	if err := s.db.Where("run_id = ? AND employee_id = ?", run.ID, line.EmployeeID).Limit(1).Find(&slip).Error; err != nil {
+		_ = s.store.Delete(key)
+		return nil, err
+	}
+	old := slip.StorageKey
+	slip.RunID, slip.EmployeeID, slip.LineID = run.ID, line.EmployeeID, line.ID
+	slip.PeriodName, slip.PayDate = run.Period.Name, run.Period.PayDate
+	slip.TemplateID = nil
+	if tpl.ID != 0 {
+		slip.TemplateID = &tpl.ID
+	}
+	slip.FileName = fmt.Sprintf("payslip-%s-%d.pdf", fileSlug(run.Period.Name), line.EmployeeID)
+	slip.Size, slip.StorageKey = int64(len(file)), key
+	slip.Protected = pref.Password != ""
+	slip.GeneratedAt = data.GeneratedAt
+	if err := s.db.Save(&slip).Error; err != nil {
+		_ = s.store.Delete(key)
+		return nil, err
+	}
+	if old != "" {
+		_ = s.store.Delete(old)
+	}
+	return &slip, nil
+}
+
+// fileSlug turns a period name like "June 2026" into "june-2026" for file names.
+func fileSlug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// Access
+
+// ListMine returns the employee's payslips, newest first.
+func (s *PayslipService) ListMine(employeeID uint) ([]models.Payslip, error) {
+	list := []models.Payslip{}
+	if err := s.db.Where("employee_id = ?", employeeID).Order("pay_date DESC, id DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListRun returns the payslips of a run.
+func (s *PayslipService) ListRun(runID uint) ([]models.Payslip, error) {
+	list := []models.Payslip{}
+	if err := s.db.Where("run_id = ?", runID).Order("employee_id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Open returns a payslip and a reader over its PDF. Employees may open their own payslips only;
+// payroll staff may open any. Callers must close the reader.
+func (s *PayslipService) Open(v Viewer, id uint) (*models.Payslip, io.ReadCloser, error) {
+	var slip models.Payslip
+	if err := s.db.First(&slip, id).Error; err != nil {
+		return nil, nil, err
+	}
+	if !models.HasPermission(v.Role, models.PermRunPayroll) && (v.EmployeeID == 0 || v.EmployeeID != slip.EmployeeID) {
+		return nil, nil, ErrPayslipForbidden
+	}
+	rc, err := s.store.Open(slip.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &slip, rc, nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+)
+
+var ErrNotOnProbation = errors.New("employee is not on probation")
+
+// ProbationReminderDays is how many days before a probation ends the review reminder is sent,
+// configurable through PROBATION_REMINDER_DAYS.
+func ProbationReminderDays() int {
+	if v, err := strconv.Atoi(os.Getenv("PROBATION_REMINDER_DAYS")); err == nil && v >= 0 {
+		return v
+	}
+	return 14
+}
+
+// ProbationService tracks new hires' probation periods: extending them, confirming the employee
+// once probation is passed, and reminding HR and managers of upcoming reviews.
+type ProbationService struct {
+	db     *gorm.DB
+	notify *NotificationService
+}
+
+func NewProbationService(db *gorm.DB) *ProbationService {
+	return &ProbationService{db: db, notify: NewNotificationService(db)}
+}
+
+// onProbation loads the employee inside tx, failing unless they are on probation.
+func onProbation(tx *gorm.DB, id uint) (*models.Employee, error) {
+	var e models.Employee
+	if err := tx.First(&e, id).Error; err != nil {
+		return nil, err
+	}
+	if e.Status == models.EmploymentTerminated {
+		return nil, ErrEmployeeTerminated
+	}
+	if e.Status != models.EmploymentProbation {
+		return nil, ErrNotOnProbation
+	}
+	return &e, nil
+}
+
+// Extend moves the end of an employee's probation to a later date.
+func (s *ProbationService) Extend(id uint, end time.Time) (*models.Employee, error) {
+	if !end.After(today()) {
+		return nil, errors.New("probation end date must be in the future")
+	}
+	var e *models.Employee
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if e, err = onProbation(tx, id); err != nil {
+			return err
+		}
+		if e.ProbationEndDate != nil && !end.After(*e.ProbationEndDate) {
+			return errors.New("an extension must end after the current probation end date")
+		}
+		return s.update(tx, e, map[string]interface{}{"probation_end_date": end})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Confirm records that the employee passed probation and makes them ACTIVE. The employee is
+// notified. Failing a probation is a termination.
+func (s *ProbationService) Confirm(id, by uint, note string) (*models.Employee, error) {
+	var e *models.Employee
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if e, err = onProbation(tx, id); err != nil {
+			return err
+		}
+		if err := s.update(tx, e, map[string]interface{}{
+			"status":       models.EmploymentActive,
+			"confirmed_at": time.Now(),
+			"confirmed_by": by,
+		}); err != nil {
+			return err
+		}
+		body := "Congratulations, your probation has been confirmed."
+		if note = strings.TrimSpace(note); note != "" {
+			body += " " + note
+		}
+		return s.notify.Notify(tx, models.Notification{
+			UserID:     e.UserID,
+			Kind:       "PROBATION_CONFIRMED",
+			Title:      "Probation confirmed",
+			Body:       body,
+			EntityType: "employee",
+			EntityID:   &e.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// update applies changes with the employee's optimistic version check and reloads e.
+func (s *ProbationService) update(tx *gorm.DB, e *models.Employee, changes map[string]interface{}) error {
+	changes["version"] = e.Version + 1
+	res := tx.Model(&models.Employee{}).Where("id = ? AND version = ?", e.ID, e.Version).Updates(changes)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("employee was modified concurrently")
+	}
+	return tx.First(e, e.ID).Error
+}
+
+// SendReminders runs on the scheduler. HR and the employee's manager are reminded to review a
+// probation ProbationReminderDays before it ends, and told again once it has ended unconfirmed.
+// Each reminder is sent once per probation end date, so an extension triggers new ones.
+func (s *ProbationService) SendReminders(now time.Time) error {
+	y, m, d := now.UTC().Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	horizon := day.AddDate(0, 0, ProbationReminderDays())
+	var due []models.Employee
+	if err := s.db.Where("status = ? AND probation_end_date <= ?", models.EmploymentProbation, horizon).
+		Find(&due).Error; err != nil {
+		return err
+	}
+	for _, e := range due {
+		if err := s.remind(&e, day); err != nil {
+			return fmt.Errorf("employee %d: %w", e.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *ProbationService) remind(e *models.Employee, day time.Time) error {
+	end := e.ProbationEndDate.Format("2006-01-02")
+	n := models.Notification{
+		Kind:       "PROBATION_REVIEW",
+		Title:      fmt.Sprintf("Probation review due for %s", e.Name),
+		Body:       fmt.Sprintf("The probation of %s ends on %s. Confirm the employee or extend the probation.", e.Name, end),
+		EntityType: "employee",
+		EntityID:   &e.ID,
+	}
+	key := fmt.Sprintf("probation-review:%d:%s", e.ID, end)
+	if e.ProbationEndDate.Before(day) {
+		n.Kind = "PROBATION_OVERDUE"
+		n.Title = fmt.Sprintf("Probation of %s has ended unconfirmed", e.Name)
+		n.Body = fmt.Sprintf("The probation of %s ended on %s and has not been confirmed.", e.Name, end)
+		key = fmt.Sprintf("probation-overdue:%d:%s", e.ID, end)
+	}
+	n.DedupKey = &key
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.notify.NotifyRole(tx, models.RoleHR, n); err != nil {
+			return err
+		}
+		if e.ManagerID == nil {
+			return nil
+		}
+		var mgr models.Employee
+		err := tx.Where("id = ? AND status <> ?", *e.ManagerID, models.EmploymentTerminated).First(&mgr).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return s.notify.Notify(tx, forUser(n, mgr.UserID))
+	})
+}
@@ -0,0 +1,501 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/encryption"
+	"github.com/example/hrms-backend/models"
+)
+
+var (
+	ErrChangeRequestClosed = errors.New("change request is not pending")
+	ErrNotYourRequest      = errors.New("change request belongs to another employee")
+)
+
+// maxEmergencyContacts caps how many emergency contacts an employee keeps.
+const maxEmergencyContacts = 5
+
+// ProfileUpdate lists the personal fields to change; nil fields are left alone and an empty string
+// clears a field. EmergencyContacts, when set, replaces the whole list.
+type ProfileUpdate struct {
+	Name              *string                    `json:"name"`
+	PreferredName     *string                    `json:"preferred_name"`
+	Phone             *string                    `json:"phone"`
+	PersonalEmail     *string                    `json:"personal_email"`
+	AddressLine1      *string                    `json:"address_line1"`
+	AddressLine2      *string                    `json:"address_line2"`
+	City              *string                    `json:"city"`
+	PostalCode        *string                    `json:"postal_code"`
+	Country           *string                    `json:"country"`
+	BankAccountHolder *string                    `json:"bank_account_holder"`
+	BankName          *string                    `json:"bank_name"`
+	BankAccountNumber *string                    `json:"bank_account_number"`
+	BankRoutingCode   *string                    `json:"bank_routing_code"`
+	EmergencyContacts *[]models.EmergencyContact `json:"emergency_contacts"`
+}
+
+// profileField describes one personal column: whether a self-service change needs HR approval,
+// its current value on an employee, and how a new value is normalized and checked.
+type profileField struct {
+	sensitive bool
+	get       func(e *models.Employee) string
+	clean     func(v string) (string, error)
+}
+
+var (
+	phonePattern   = regexp.MustCompile(`^\+?[0-9 ()\-]{6,20}$`)
+	accountPattern = regexp.MustCompile(`^[A-Z0-9]{4,34}$`)
+	routingPattern = regexp.MustCompile(`^[A-Z0-9]{4,20}$`)
+)
+
+func maxLen(n int) func(string) (string, error) {
+	return func(v string) (string, error) {
+		if len(v) > n {
+			return "", fmt.Errorf("must be at most %d characters", n)
+		}
+		return v, nil
+	}
+}
+
+func cleanPhone(v string) (string, error) {
+	if v != "" && !phonePattern.MatchString(v) {
+		return "", errors.New("invalid phone number")
+	}
+	return v, nil
+}
+
+func cleanEmail(v string) (string, error) {
+	if v == "" {
+		return v, nil
+	}
+	a, err := mail.ParseAddress(v)
+	if err != nil || a.Address != v {
+		return "", errors.New("invalid email address")
+	}
+	return v, nil
+}
+
+// NormalizeCode uppercases an account or routing code and drops spaces.
+func NormalizeCode(v string) string { return strings.ToUpper(strings.ReplaceAll(v, " ", "")) }
+
+func cleanCode(pattern *regexp.Regexp) func(string) (string, error) {
+	return func(v string) (string, error) {
+		v = NormalizeCode(v)
+		if v != "" && !pattern.MatchString(v) {
+			return "", errors.New("invalid format")
+		}
+		return v, nil
+	}
+}
+
+var profileFields = map[string]profileField{
+	"name": {sensitive: true, get: func(e *models.Employee) string { return e.Name }, clean: func(v string) (string, error) {
+		if v == "" {
+			return "", errors.New("is required")
+		}
+		return maxLen(120)(v)
+	}},
+	"preferred_name": {get: func(e *models.Employee) string { return e.PreferredName }, clean: maxLen(120)},
+	"phone":          {get: func(e *models.Employee) string { return e.Phone }, clean: cleanPhone},
+	"personal_email": {get: func(e *models.Employee) string { return e.PersonalEmail }, clean: cleanEmail},
+	"address_line1":  {get: func(e *models.Employee) string { return e.AddressLine1 }, clean: maxLen(200)},
+	"address_line2":  {get: func(e *models.Employee) string { return e.AddressLine2 }, clean: maxLen(200)},
+	"city":           {get: func(e *models.Employee) string { return e.City }, clean: maxLen(120)},
+	"postal_code":    {get: func(e *models.Employee) string { return e.PostalCode }, clean: maxLen(20)},
+	"country": {get: func(e *models.Employee) string { return e.Country }, clean: func(v string) (string, error) {
+		v = strings.ToUpper(v)
+		if v != "" && (len(v) != 2 || strings.Trim(v, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "") {
+			return "", errors.New("must be a two-letter ISO country code")
+		}
+		return v, nil
+	}},
+	"bank_account_holder": {sensitive: true, get: func(e *models.Employee) string { return e.BankAccountHolder }, clean: maxLen(120)},
+	"bank_name":           {sensitive: true, get: func(e *models.Employee) string { return e.BankName }, clean: maxLen(120)},
+	"bank_account_number": {sensitive: true, get: func(e *models.Employee) string { return e.BankAccountNumber }, clean: cleanCode(accountPattern)},
+	"bank_routing_code":   {sensitive: true, get: func(e *models.Employee) string { return e.BankRoutingCode }, clean: cleanCode(routingPattern)},
+}
+
+// bankAccountIndex is the blind index of an account number, for lookups on the encrypted column.
+// The number is normalized first, so every caller indexes it the same way.
+func bankAccountIndex(number string) string {
+	return encryption.BlindIndex("bank_account_number", NormalizeCode(number))
+}
+
+// cleanBankDetails normalizes and validates the bank details of an employee saved outside the
+// profile workflow, as profile updates do.
+func cleanBankDetails(e *models.Employee) error {
+	for col, v := range map[string]*string{
+		"bank_account_holder": &e.BankAccountHolder,
+		"bank_name":           &e.BankName,
+		"bank_account_number": &e.BankAccountNumber,
+		"bank_routing_code":   &e.BankRoutingCode,
+	} {
+		val, err := profileFields[col].clean(strings.TrimSpace(*v))
+		if err != nil {
+			return fmt.Errorf("%s: %w", col, err)
+		}
+		*v = val
+	}
+	return nil
+}
+
+// sealChanges prepares a map update of profile columns. Map updates bypass the column
+// serializer, so encrypted columns are sealed here, and the account's blind index follows it.
+func sealChanges(changes map[string]interface{}) error {
+	if v, ok := changes["bank_account_number"].(string); ok {
+		changes["bank_account_index"] = bankAccountIndex(v)
+	}
+	for _, col := range []string{"bank_account_number", "bank_routing_code"} {
+		if v, ok := changes[col]; ok {
+			sealed, err := encryption.SealValue(v)
+			if err != nil {
+				return err
+			}
+			changes[col] = sealed
+		}
+	}
+	return nil
+}
+
+// values returns the set fields of u keyed by column name.
+func (u ProfileUpdate) values() map[string]*string {
+	return map[string]*string{
+		"name": u.Name, "preferred_name": u.PreferredName, "phone": u.Phone, "personal_email": u.PersonalEmail,
+		"address_line1": u.AddressLine1, "address_line2": u.AddressLine2, "city": u.City,
+		"postal_code": u.PostalCode, "country": u.Country, "bank_account_holder": u.BankAccountHolder,
+		"bank_name": u.BankName, "bank_account_number": u.BankAccountNumber, "bank_routing_code": u.BankRoutingCode,
+	}
+}
+
+// ProfileService handles personal details, emergency contacts and the approval of sensitive
+// self-service changes.
+type ProfileService struct {
+	db        *gorm.DB
+	employees *EmployeeService
+	notify    *NotificationService
+}
+
+func NewProfileService(db *gorm.DB) *ProfileService {
+	return &ProfileService{db: db, employees: NewEmployeeService(db), notify: NewNotificationService(db)}
+}
+
+// Get returns the employee with their emergency contacts.
+func (s *ProfileService) Get(employeeID uint) (*models.Employee, error) {
+	var e models.Employee
+	if err := s.db.Preload("EmergencyContacts", func(db *gorm.DB) *gorm.DB { return db.Order("priority, id") }).
+		First(&e, employeeID).Error; err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Mine returns the user's current employee record with their emergency contacts.
+func (s *ProfileService) Mine(userID uint) (*models.Employee, error) {
+	e, err := s.employees.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(e.ID)
+}
+
+// ProfileResult is the outcome of a profile update: the employee as it is now and, for a
+// self-service update touching sensitive fields, the change request awaiting HR.
+type ProfileResult struct {
+	Employee      *models.Employee             `json:"employee"`
+	ChangeRequest *models.ProfileChangeRequest `json:"change_request,omitempty"`
+}
+
+// Update changes an employee's personal details. With direct (HR editing) every change is applied
+// at once; otherwise sensitive fields are queued in the employee's pending change request, which
+// HR is notified about, and the rest is applied.
+func (s *ProfileService) Update(employeeID uint, u ProfileUpdate, by uint, direct bool) (*ProfileResult, error) {
+	cur, err := s.Get(employeeID)
+	if err != nil {
+		return nil, err
+	}
+	if cur.Status == models.EmploymentTerminated {
+		return nil, ErrEmployeeTerminated
+	}
+	apply := map[string]interface{}{}
+	pending := map[string]string{}
+	for col, v := range u.values() {
+		if v == nil {
+			continue
+		}
+		f := profileFields[col]
+		val, err := f.clean(strings.TrimSpace(*v))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", col, err)
+		}
+		if val == f.get(cur) {
+			continue
+		}
+		if f.sensitive && !direct {
+			pending[col] = val
+		} else {
+			apply[col] = val
+		}
+	}
+	var contacts []models.EmergencyContact
+	if u.EmergencyContacts != nil {
+		if contacts, err = cleanContacts(*u.EmergencyContacts); err != nil {
+			return nil, err
+		}
+	}
+	if len(apply) == 0 && len(pending) == 0 && u.EmergencyContacts == nil {
+		return nil, errors.New("nothing to update")
+	}
+
+	res := &ProfileResult{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if len(apply) > 0 {
+			if err := sealChanges(apply); err != nil {
+				return err
+			}
+			apply["version"] = cur.Version + 1
+			r := tx.Model(&models.Employee{}).Where("id = ? AND version = ?", cur.ID, cur.Version).Updates(apply)
+			if r.Error != nil {
+				return r.Error
+			}
+			if r.RowsAffected == 0 {
+				return errors.New("employee was modified concurrently")
+			}
+		}
+		if u.EmergencyContacts != nil {
+			if err := tx.Where("employee_id = ?", cur.ID).Delete(&models.EmergencyContact{}).Error; err != nil {
+				return err
+			}
+			for i := range contacts {
+				contacts[i].EmployeeID = cur.ID
+			}
+			if len(contacts) > 0 {
+				if err := tx.Create(&contacts).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if len(pending) > 0 {
+			req, err := s.queue(tx, cur, pending, by)
+			if err != nil {
+				return err
+			}
+			res.ChangeRequest = req
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Employee, err = s.Get(cur.ID); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// cleanContacts validates and normalizes a replacement list of emergency contacts.
+func cleanContacts(in []models.EmergencyContact) ([]models.EmergencyContact, error) {
+	if len(in) > maxEmergencyContacts {
+		return nil, fmt.Errorf("at most %d emergency contacts", maxEmergencyContacts)
+	}
+	out := make([]models.EmergencyContact, 0, len(in))
+	for i, c := range in {
+		c := models.EmergencyContact{
+			Priority:     c.Priority,
+			Name:         strings.TrimSpace(c.Name),
+			Relationship: strings.TrimSpace(c.Relationship),
+			Phone:        strings.TrimSpace(c.Phone),
+			Email:        strings.TrimSpace(c.Email),
+		}
+		if c.Name == "" || c.Phone == "" {
+			return nil, fmt.Errorf("emergency contact %d: name and phone are required", i+1)
+		}
+		if _, err := cleanPhone(c.Phone); err != nil {
+			return nil, fmt.Errorf("emergency contact %d: %w", i+1, err)
+		}
+		if _, err := cleanEmail(c.Email); err != nil {
+			return nil, fmt.Errorf("emergency contact %d: %w", i+1, err)
+		}
+		if c.Priority <= 0 {
+			c.Priority = i + 1
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// queue merges sensitive changes into the employee's pending change request, creating it when
+// there is none, and tells HR about it.
+func (s *ProfileService) queue(tx *gorm.DB, e *models.Employee, changes map[string]string, by uint) (*models.ProfileChangeRequest, error) {
+	var req models.ProfileChangeRequest
+	err := tx.Where("employee_id = ? AND status = ?", e.ID, models.ChangePending).First(&req).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		req = models.ProfileChangeRequest{
+			EmployeeID:  e.ID,
+			RequestedBy: by,
+			Changes:     map[string]string{},
+			Previous:    map[string]string{},
+			Status:      models.ChangePending,
+		}
+	case err != nil:
+		return nil, err
+	default:
+		req.Version++
+	}
+	for col, v := range changes {
+		req.Changes[col] = v
+		if _, ok := req.Previous[col]; !ok {
+			req.Previous[col] = profileFields[col].get(e)
+		}
+	}
+	if err := tx.Save(&req).Error; err != nil {
+		return nil, err
+	}
+	cols := make([]string, 0, len(req.Changes))
+	for col := range req.Changes {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	return &req, s.notify.NotifyRole(tx, models.RoleHR, models.Notification{
+		Kind:       "PROFILE_CHANGE",
+		Title:      fmt.Sprintf("Profile change awaiting approval for %s", e.Name),
+		Body:       "Requested changes: " + strings.Join(cols, ", "),
+		EntityType: "profile_change_request",
+		EntityID:   &req.ID,
+	})
+}
+
+// ChangeRequestFilter narrows change request listings; zero values match everything.
+type ChangeRequestFilter struct {
+	EmployeeID uint
+	Status     models.ChangeRequestStatus
+}
+
+func (s *ProfileService) ListChangeRequests(f ChangeRequestFilter) ([]models.ProfileChangeRequest, error) {
+	tx := s.db.Model(&models.ProfileChangeRequest{})
+	if f.EmployeeID != 0 {
+		tx = tx.Where("employee_id = ?", f.EmployeeID)
+	}
+	if f.Status != "" {
+		tx = tx.Where("status = ?", f.Status)
+	}
+	var list []models.ProfileChangeRequest
+	if err := tx.Order("created_at DESC, id DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Approve applies a pending change request to the employee and notifies them.
+func (s *ProfileService) Approve(id, by uint, note string) (*models.ProfileChangeRequest, error) {
+	return s.decide(id, by, note, models.ChangeApproved, func(tx *gorm.DB, req *models.ProfileChangeRequest) error {
+		var e models.Employee
+		if err := tx.First(&e, req.EmployeeID).Error; err != nil {
+			return err
+		}
+		if e.Status == models.EmploymentTerminated {
+			return ErrEmployeeTerminated
+		}
+		changes := map[string]interface{}{"version": e.Version + 1}
+		for col, v := range req.Changes {
+			if _, ok := profileFields[col]; !ok {
+				return fmt.Errorf("unknown field %q", col)
+			}
+			changes[col] = v
+		}
+		if err := sealChanges(changes); err != nil {
+			return err
+		}
+		r := tx.Model(&models.Employee{}).Where("id = ? AND version = ?", e.ID, e.Version).Updates(changes)
+		if r.Error != nil {
+			return r.Error
+		}
+		if r.RowsAffected == 0 {
+			return errors.New("employee was modified concurrently")
+		}
+		return nil
+	})
+}
+
+// Reject declines a pending change request; a comment explaining why is required.
+func (s *ProfileService) Reject(id, by uint, note string) (*models.ProfileChangeRequest, error) {
+	if strings.TrimSpace(note) == "" {
+		return nil, errors.New("a comment is required to reject a change request")
+	}
+	return s.decide(id, by, note, models.ChangeRejected, nil)
+}
+
+// Cancel withdraws the user's own pending change request.
+func (s *ProfileService) Cancel(id, userID uint) (*models.ProfileChangeRequest, error) {
+	return s.decide(id, userID, "", models.ChangeCancelled, func(tx *gorm.DB, req *models.ProfileChangeRequest) error {
+		if req.RequestedBy != userID {
+			return ErrNotYourRequest
+		}
+		return nil
+	})
+}
+
+// decide moves a pending request to status after running check, recording who decided and, for
+// HR decisions, notifying the employee.
+func (s *ProfileService) decide(id, by uint, note string, status models.ChangeRequestStatus,
+	check func(tx *gorm.DB, req *models.ProfileChangeRequest) error) (*models.ProfileChangeRequest, error) {
+	var req models.ProfileChangeRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&req, id).Error; err != nil {
+			return err
+		}
+		if req.Status != models.ChangePending {
+			return ErrChangeRequestClosed
+		}
+		if check != nil {
+			if err := check(tx, &req); err != nil {
+				return err
+			}
+		}
+		r := tx.Model(&models.ProfileChangeRequest{}).Where("id = ? AND version = ?", req.ID, req.Version).
+			Updates(map[string]interface{}{
+				"status":        status,
+				"decided_by":    by,
+				"decided_at":    time.Now(),
+				"decision_note": strings.TrimSpace(note),
+				"version":       req.Version + 1,
+			})
+		if r.Error != nil {
+			return r.Error
+		}
+		if r.RowsAffected == 0 {
+			return errors.New("change request was modified concurrently")
+		}
+		if err := tx.First(&req, id).Error; err != nil {
+			return err
+		}
+		if status == models.ChangeCancelled {
+			return nil
+		}
+		var e models.Employee
+		if err := tx.First(&e, req.EmployeeID).Error; err != nil {
+			return err
+		}
+		n := models.Notification{
+			UserID:     e.UserID,
+			Kind:       "PROFILE_CHANGE_" + string(status),
+			Title:      "Your profile change was " + strings.ToLower(string(status)),
+			Body:       req.DecisionNote,
+			EntityType: "profile_change_request",
+			EntityID:   &req.ID,
+		}
+		return s.notify.Notify(tx, n)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/example/hrms-backend/formula"
+	"github.com/example/hrms-backend/models"
+)
+
+// ctcVar is the formula variable holding the annual CTC being expanded.
+const ctcVar = "CTC"
+
+// ComponentAmount is one salary component's share of a CTC.
+type ComponentAmount struct {
+	Code    string                 `json:"code"`
+	Name    string                 `json:"name"`
+	Kind    models.PayrollItemKind `json:"kind"`
+	Monthly float64                `json:"monthly"`
+	Annual  float64                `json:"annual"`
+}
+
+// SalaryBreakdown is an annual CTC expanded into the monthly components of a structure. Net is
+// gross earnings less the structure's deductions, before payroll deduction rules.
+type SalaryBreakdown struct {
+	StructureID       uint              `json:"structure_id"`
+	StructureName     string            `json:"structure_name"`
+	CTC               float64           `json:"ctc"`
+	MonthlyCTC        float64           `json:"monthly_ctc"`
+	Components        []ComponentAmount `json:"components"`
+	GrossMonthly      float64           `json:"gross_monthly"`
+	DeductionsMonthly float64           `json:"deductions_monthly"`
+	NetMonthly        float64           `json:"net_monthly"`
+}
+
+// ExpandSalary computes the monthly amount of every component of st for an annual CTC.
+// Components are evaluated in dependency order: percent-of-basic components after BASIC, formulas
+// after the components they reference and the balance after every other earning. It fails on
+// dependency cycles, unknown references and negative amounts, including a balance that would be
+// negative because the other earnings exceed the monthly CTC.
+func ExpandSalary(st *models.SalaryStructure, ctc float64) (*SalaryBreakdown, error) {
+	order, exprs, err := componentOrder(st.Components)
+	if err != nil {
+		return nil, err
+	}
+	monthlyCTC := ctc / 12
+	vars := map[string]float64{ctcVar: ctc}
+	earned := 0.0
+	for _, c := range order {
+		var v float64
+		switch c.Type {
+		case models.ComponentFixed:
+			v = c.Amount
+		case models.ComponentPercentOfBasic:
+			v = vars[models.BasicComponent] * c.Percent / 100
+		case models.ComponentFormula:
+			if v, err = exprs[c.Code].Eval(vars); err != nil {
+				return nil, fmt.Errorf("component %s: %w", c.Code, err)
+			}
+		case models.ComponentBalance:
+			v = monthlyCTC - earned
+			if roundMoney(v) < 0 {
+				return nil, fmt.Errorf("earnings exceed the monthly CTC of %.2f by %.2f", monthlyCTC, -v)
+			}
+		}
+		v = roundMoney(v)
+		if v < 0 {
+			return nil, fmt.Errorf("component %s is negative (%.2f)", c.Code, v)
+		}
+		vars[c.Code] = v
+		if c.Kind == models.PayrollEarning {
+			earned += v
+		}
+	}
+
+	b := &SalaryBreakdown{StructureID: st.ID, StructureName: st.Name, CTC: ctc, MonthlyCTC: roundMoney(monthlyCTC)}
+	for _, c := range sortedComponents(st.Components) {
+		v := vars[c.Code]
+		b.Components = append(b.Components, ComponentAmount{Code: c.Code, Name: c.Name, Kind: c.Kind, Monthly: v, Annual: roundMoney(v * 12)})
+		if c.Kind == models.PayrollEarning {
+			b.GrossMonthly += v
+		} else {
+			b.DeductionsMonthly += v
+		}
+	}
+	b.GrossMonthly, b.DeductionsMonthly = roundMoney(b.GrossMonthly), roundMoney(b.DeductionsMonthly)
+	b.NetMonthly = roundMoney(b.GrossMonthly - b.DeductionsMonthly)
+	return b, nil
+}
+
+// sortedComponents returns comps in display order: SortOrder, then as given.
+func sortedComponents(comps []models.SalaryComponent) []models.SalaryComponent {
+	sorted := append([]models.SalaryComponent(nil), comps...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].SortOrder < sorted[j].SortOrder })
+	return sorted
+}
+
+// componentOrder sorts comps so every component comes after the ones it depends on, keeping
+// display order among independent ones, and returns the parsed formulas by code.
+func componentOrder(comps []models.SalaryComponent) ([]models.SalaryComponent, map[string]*formula.Expr, error) {
+	comps = sortedComponents(comps)
+	byCode := make(map[string]int, len(comps))
+	for i, c := range comps {
+		if _, dup := byCode[c.Code]; dup {
+			return nil, nil, fmt.Errorf("duplicate component code %s", c.Code)
+		}
+		byCode[c.Code] = i
+	}
+	basic, ok := byCode[models.BasicComponent]
+	if !ok || comps[basic].Kind != models.PayrollEarning {
+		return nil, nil, errors.New("a structure needs an earning component with code BASIC")
+	}
+
+	exprs := map[string]*formula.Expr{}
+	deps := make([][]int, len(comps))
+	balances := 0
+	for i, c := range comps {
+		switch c.Type {
+		case models.ComponentPercentOfBasic:
+			deps[i] = []int{basic}
+		case models.ComponentFormula:
+			e, err := formula.Parse(c.Formula)
+			if err != nil {
+				return nil, nil, fmt.Errorf("formula of %s: %w", c.Code, err)
+			}
+			exprs[c.Code] = e
+			for _, v := range e.Vars() {
+				if v == ctcVar {
+					continue
+				}
+				j, ok := byCode[v]
+				if !ok {
+					return nil, nil, fmt.Errorf("formula of %s references unknown component %s", c.Code, v)
+				}
+				deps[i] = append(deps[i], j)
+			}
+		case models.ComponentBalance:
+			if c.Kind != models.PayrollEarning {
+				return nil, nil, fmt.Errorf("balance component %s must be an earning", c.Code)
+			}
+			if balances++; balances > 1 {
+				return nil, nil, errors.New("a structure can have only one balance component")
+			}
+			for j, o := range comps {
+				if j != i && o.Kind == models.PayrollEarning {
+					deps[i] = append(deps[i], j)
+				}
+			}
+		}
+	}
+
+	// depth-first topological sort; visiting in display order keeps that order where possible
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(comps))
+	order := make([]models.SalaryComponent, 0, len(comps))
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("components depend on each other: %s", strings.Join(append(path, comps[i].Code), " -> "))
+		}
+		state[i] = visiting
+		for _, j := range deps[i] {
+			if err := visit(j, append(path, comps[i].Code)); err != nil {
+				return err
+			}
+		}
+		state[i] = done
+		order = append(order, comps[i])
+		return nil
+	}
+	for i := range comps {
+		if err := visit(i, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	return order, exprs, nil
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+)
+
+var (
+	// ErrSalaryStructureInUse stops deleting a structure assigned to an employee.
+	ErrSalaryStructureInUse = errors.New("salary structure is assigned to employees")
+	// ErrNoSalaryStructure is returned for an employee without a structure in effect.
+	ErrNoSalaryStructure = errors.New("employee has no salary structure in effect")
+)
+
+// SalaryStructureService manages salary structures, their assignment to employees and the
+// expansion of an employee's salary into monthly components.
+type SalaryStructureService struct {
+	db *gorm.DB
+}
+
+func NewSalaryStructureService(db *gorm.DB) *SalaryStructureService {
+	return &SalaryStructureService{db: db}
+}
+
+func withComponents(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Components", func(tx *gorm.DB) *gorm.DB { return tx.Order("sort_order, id") })
+}
+
+func (s *SalaryStructureService) List() ([]models.SalaryStructure, error) {
+	var list []models.SalaryStructure
+	err := withComponents(s.db).Order("name").Find(&list).Error
+	return list, err
+}
+
+func (s *SalaryStructureService) Get(id uint) (*models.SalaryStructure, error) {
+	var st models.SalaryStructure
+	if err := withComponents(s.db).First(&st, id).Error; err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// normalizeStructure checks a structure's fields, formulas and the dependencies between its
+// components.
+func normalizeStructure(st *models.SalaryStructure) error {
+	st.Name = strings.TrimSpace(st.Name)
+	if st.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(st.Components) == 0 {
+		return errors.New("a structure needs components")
+	}
+	for i := range st.Components {
+		c := &st.Components[i]
+		c.ID, c.StructureID = 0, st.ID
+		c.Code = strings.ToUpper(strings.TrimSpace(c.Code))
+		c.Name = strings.TrimSpace(c.Name)
+		c.Formula = strings.TrimSpace(c.Formula)
+		if c.Kind == "" {
+			c.Kind = models.PayrollEarning
+		}
+		switch {
+		case !deductionCode.MatchString(c.Code) || c.Code == ctcVar:
+			return fmt.Errorf("component code %q must be upper case letters, digits and underscores, other than CTC", c.Code)
+		case c.Name == "":
+			return fmt.Errorf("component %s needs a name", c.Code)
+		case c.Kind != models.PayrollEarning && c.Kind != models.PayrollDeduction:
+			return fmt.Errorf("component %s: kind must be EARNING or DEDUCTION", c.Code)
+		}
+		switch c.Type {
+		case models.ComponentFixed:
+			if c.Amount < 0 {
+				return fmt.Errorf("component %s: amount must not be negative", c.Code)
+			}
+			c.Percent, c.Formula = 0, ""
+		case models.ComponentPercentOfBasic:
+			if c.Percent < 0 || c.Percent > 1000 {
+				return fmt.Errorf("component %s: percent out of range", c.Code)
+			}
+			if c.Code == models.BasicComponent {
+				return errors.New("BASIC cannot be a percentage of itself")
+			}
+			c.Amount, c.Formula = 0, ""
+		case models.ComponentFormula:
+			if c.Formula == "" {
+				return fmt.Errorf("component %s: formula is required", c.Code)
+			}
+			c.Amount, c.Percent = 0, 0
+		case models.ComponentBalance:
+			c.Amount, c.Percent, c.Formula = 0, 0, ""
+		default:
+			return fmt.Errorf("component %s: type must be FIXED, PERCENT_OF_BASIC, FORMULA or BALANCE", c.Code)
+		}
+	}
+	// amounts that only fail for some salaries are checked on assignment and in payroll runs
+	_, _, err := componentOrder(st.Components)
+	return err
+}
+
+// Save creates the structure (ID 0) or replaces an existing one with its components. Draft
+// payroll runs are marked for recalculation.
+func (s *SalaryStructureService) Save(st *models.SalaryStructure) error {
+	if err := normalizeStructure(st); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&models.SalaryStructure{}).Where("name = ? AND id <> ?", st.Name, st.ID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("salary structure %q already exists", st.Name)
+		}
+		comps := st.Components
+		st.Components = nil
+		if st.ID != 0 {
+			var cur models.SalaryStructure
+			if err := tx.First(&cur, st.ID).Error; err != nil {
+				return err
+			}
+			st.CreatedAt = cur.CreatedAt
+			if err := tx.Where("structure_id = ?", st.ID).Delete(&models.SalaryComponent{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(st).Error; err != nil {
+			return err
+		}
+		for i := range comps {
+			comps[i].StructureID = st.ID
+		}
+		if err := tx.Create(&comps).Error; err != nil {
+			return err
+		}
+		st.Components = comps
+		return markDraftRunsStale(tx)
+	})
+}
+
+func (s *SalaryStructureService) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&models.SalaryStructureAssignment{}).Where("structure_id = ?", id).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrSalaryStructureInUse
+		}
+		res := tx.Select("Components").Delete(&models.SalaryStructure{ID: id})
+		if res.Error == nil && res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return res.Error
+	})
+}
+
+// Preview expands a structure for an annual CTC.
+func (s *SalaryStructureService) Preview(id uint, ctc float64) (*SalaryBreakdown, error) {
+	if ctc < 0 {
+		return nil, errors.New("ctc must not be negative")
+	}
+	st, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return ExpandSalary(st, ctc)
+}
+
+// salaryAsOf returns the annual salary in effect for an employee on day: the latest job record,
+// else the employee's current salary.
+func salaryAsOf(tx *gorm.DB, employeeID uint, day time.Time) (float64, error) {
+	var rec models.JobRecord
+	err := tx.Where("employee_id = ? AND effective_date <= ?", employeeID, day.Format("2006-01-02")).
+		Order("effective_date desc, id desc").First(&rec).Error
+	if err == nil {
+		return rec.Salary, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	var e models.Employee
+	if err := tx.First(&e, employeeID).Error; err != nil {
+		return 0, err
+	}
+	return e.Salary, nil
+}
+
+func (s *SalaryStructureService) ListAssignments(employeeID uint) ([]models.SalaryStructureAssignment, error) {
+	var list []models.SalaryStructureAssignment
+	err := s.db.Preload("Structure").Where("employee_id = ?", employeeID).Order("effective_date desc").Find(&list).Error
+	return list, err
+}
+
+// Assign puts an employee on a structure from a.EffectiveDate. The employee's salary on that
+// date must expand cleanly; a later raise that does not is reported by the payroll run.
+func (s *SalaryStructureService) Assign(a *models.SalaryStructureAssignment) error {
+	if a.EffectiveDate.IsZero() {
+		return errors.New("effective_date is required")
+	}
+	a.ID, a.Structure = 0, nil
+	a.Note = strings.TrimSpace(a.Note)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var e models.Employee
+		if err := tx.First(&e, a.EmployeeID).Error; err != nil {
+			return err
+		}
+		if e.Status == models.EmploymentTerminated {
+			return ErrEmployeeTerminated
+		}
+		var st models.SalaryStructure
+		if err := withComponents(tx).First(&st, a.StructureID).Error; err != nil {
+			return fmt.Errorf("salary structure: %w", err)
+		}
+		salary, err := salaryAsOf(tx, a.EmployeeID, a.EffectiveDate)
+		if err != nil {
+			return err
+		}
+		if _, err := ExpandSalary(&st, salary); err != nil {
+			return fmt.Errorf("structure %s does not fit a salary of %.2f: %w", st.Name, salary, err)
+		}
+		if err := guardPayroll(tx, a.EffectiveDate, time.Time{}); err != nil {
+			return err
+		}
+		var n int64
+		if err := tx.Model(&models.SalaryStructureAssignment{}).
+			Where("employee_id = ? AND effective_date = ?", a.EmployeeID, a.EffectiveDate.Format("2006-01-02")).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return errors.New("the employee already has a structure assignment on that date")
+		}
+		if err := tx.Create(a).Error; err != nil {
+			return err
+		}
+		a.Structure = &st
+		return nil
+	})
+}
+
+// DeleteAssignment removes an assignment, e.g. one recorded by mistake.
+func (s *SalaryStructureService) DeleteAssignment(employeeID, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var a models.SalaryStructureAssignment
+		if err := tx.Where("id = ? AND employee_id = ?", id, employeeID).First(&a).Error; err != nil {
+			return err
+		}
+		if err := guardPayroll(tx, a.EffectiveDate, time.Time{}); err != nil {
+			return err
+		}
+		return tx.Delete(&a).Error
+	})
+}
+
+// EmployeeBreakdown expands the salary of an employee on day with the structure then in effect.
+func (s *SalaryStructureService) EmployeeBreakdown(employeeID uint, day time.Time) (*SalaryBreakdown, error) {
+	var a models.SalaryStructureAssignment
+	err := s.db.Where("employee_id = ? AND effective_date <= ?", employeeID, day.Format("2006-01-02")).
+		Order("effective_date desc").First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := s.db.First(&models.Employee{}, employeeID).Error; err != nil {
+			return nil, err
+		}
+		return nil, ErrNoSalaryStructure
+	}
+	if err != nil {
+		return nil, err
+	}
+	st, err := s.Get(a.StructureID)
+	if err != nil {
+		return nil, err
+	}
+	salary, err := salaryAsOf(s.db, employeeID, day)
+	if err != nil {
+		return nil, err
+	}
+	return ExpandSalary(st, salary)
+}
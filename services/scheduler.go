@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type scheduledTask struct {
+	name     string
+	interval time.Duration
+	run      func(now time.Time) error
+}
+
+// Scheduler runs periodic background jobs such as applying future-dated changes.
+type Scheduler struct {
+	tasks []scheduledTask
+}
+
+func NewScheduler() *Scheduler { return &Scheduler{} }
+
+// Every registers fn to run once at start and then every interval.
+func (s *Scheduler) Every(name string, interval time.Duration, fn func(now time.Time) error) {
+	s.tasks = append(s.tasks, scheduledTask{name: name, interval: interval, run: fn})
+}
+
+// Start launches one goroutine per task; they stop when ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, t := range s.tasks {
+		go func(t scheduledTask) {
+			ticker := time.NewTicker(t.interval)
+			defer ticker.Stop()
+			for {
+				if err := t.run(time.Now()); err != nil {
+					log.Printf("scheduler: %s failed: %v", t.name, err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(t)
+	}
+}
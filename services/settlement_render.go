@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/pdf"
+)
+
+// SettlementData is everything printed on a final settlement statement. Branding comes from the
+// default payslip template.
+type SettlementData struct {
+	Template    models.PayslipTemplate
+	Logo        []byte // JPEG, optional
+	Settlement  models.FinalSettlement
+	Employee    models.Employee
+	GeneratedAt time.Time
+}
+
+// RenderSettlement lays out a final settlement statement as a PDF. Drafts are marked as such.
+func RenderSettlement(d *SettlementData) ([]byte, error) {
+	st := d.Settlement
+	doc := pdf.New()
+	doc.SetInfo(fmt.Sprintf("Final settlement - %s", d.Employee.Name), d.Template.CompanyName)
+	title := "Final settlement"
+	if st.Status != models.SettlementFinalized {
+		title = "Final settlement (draft)"
+	}
+	l, err := letterhead(doc, d.Template, d.Logo, title)
+	if err != nil {
+		return nil, err
+	}
+	p := l.page
+	p.TextRight(slipRight, l.y, pdf.Helvetica, 10, d.GeneratedAt.Format("02 January 2006"))
+	p.Text(slipMargin, l.y, pdf.HelveticaBold, 10, d.Employee.Name)
+	l.y += 14
+	for _, s := range []string{d.Employee.Position, d.Employee.Department} {
+		if s != "" {
+			p.Text(slipMargin, l.y, pdf.Helvetica, 10, s)
+			l.y += 14
+		}
+	}
+
+	l.heading("Exit", "")
+	l.row(pdf.Helvetica, "Last working day", st.LastWorkingDay.Format("02 Jan 2006"))
+	l.row(pdf.Helvetica, "Paid from", st.PayFrom.Format("02 Jan 2006"))
+	l.row(pdf.Helvetica, "Annual salary", money(st.AnnualSalary))
+	l.row(pdf.Helvetica, "Working days paid / unpaid", fmt.Sprintf("%d / %d", st.WorkedDays, st.UnpaidDays))
+	if st.NoticePeriodDays > 0 {
+		l.row(pdf.Helvetica, "Notice period / served", fmt.Sprintf("%d / %d days", st.NoticePeriodDays, st.NoticeServedDays))
+	}
+
+	section := func(title string, kind models.PayrollItemKind, total float64) {
+		var items []models.PayrollItem
+		for _, it := range st.Items {
+			if it.Kind == kind {
+				items = append(items, it)
+			}
+		}
+		if len(items) == 0 {
+			return
+		}
+		l.heading(title, "Amount")
+		for _, it := range items {
+			l.row(pdf.Helvetica, it.Label, money(it.Amount))
+			if it.Basis != "" {
+				l.need(slipRow)
+				l.page.SetFillColor(0.4, 0.4, 0.4)
+				l.page.Text(slipMargin+12, l.y-4, pdf.Helvetica, 7, it.Basis)
+				l.page.SetFillColor(0, 0, 0)
+				l.y += 8
+			}
+		}
+		l.row(pdf.HelveticaBold, "Total "+title, money(total))
+	}
+	section("Earnings", models.PayrollEarning, st.Earnings)
+	section("Deductions", models.PayrollDeduction, st.Deductions)
+	section("Reimbursements", models.PayrollReimbursement, st.Reimbursements)
+
+	l.heading("Settlement", "Amount")
+	if st.Net < 0 {
+		l.row(pdf.HelveticaBold, "Owed by the employee", money(-st.Net))
+	} else {
+		l.row(pdf.HelveticaBold, "Payable to the employee", money(st.Net))
+	}
+	return doc.Bytes()
+}
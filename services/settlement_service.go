@@ -0,0 +1,575 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/storage"
+)
+
+var (
+	ErrSettlementStatus   = errors.New("the settlement is finalized")
+	ErrSettlementStale    = errors.New("the settlement's inputs changed since it was calculated; recalculate it")
+	ErrSettlementRunsOpen = errors.New("payroll runs paying the employee are not finalized")
+	ErrNotLeaving         = errors.New("the employee is not leaving: terminate them, start their offboarding or give the last working day")
+	ErrNotTerminated      = errors.New("the employee must be terminated before the settlement is finalized")
+)
+
+// encashableLeave are the leave types whose untaken days are paid out on leaving.
+var encashableLeave = map[models.LeaveType]bool{models.LeaveAnnual: true}
+
+// SettlementInput is what the final settlement calculation needs for one leaver. EmployedFrom is
+// the start of employment; Absences and UnpaidLeave are the unpaid days between PayFrom and
+// LastWorkingDay; Leaves are the approved leaves of the year of the last working day.
+type SettlementInput struct {
+	AnnualSalary     float64
+	EmployedFrom     time.Time
+	PayFrom          time.Time
+	LastWorkingDay   time.Time
+	Absences         []time.Time
+	UnpaidLeave      []time.Time
+	Entitlements     []models.LeaveEntitlement
+	Leaves           []models.Leave
+	NoticeDate       *time.Time
+	NoticePeriodDays int
+	Loans            []LoanDeduction
+	Reimbursements   []Reimbursement
+	Adjustments      []models.PayrollItem
+}
+
+// settlementTotals sums the items of a settlement.
+func settlementTotals(st *models.FinalSettlement) {
+	st.Earnings, st.Deductions, st.Reimbursements = 0, 0, 0
+	for _, it := range st.Items {
+		switch it.Kind {
+		case models.PayrollEarning:
+			st.Earnings += it.Amount
+		case models.PayrollDeduction:
+			st.Deductions += it.Amount
+		case models.PayrollReimbursement:
+			st.Reimbursements += it.Amount
+		}
+	}
+	st.Earnings, st.Deductions, st.Reimbursements = roundMoney(st.Earnings), roundMoney(st.Deductions), roundMoney(st.Reimbursements)
+	st.Net = roundMoney(st.Earnings - st.Deductions + st.Reimbursements)
+}
+
+// CalculateSettlement computes a final settlement. Salary is paid for the working days from
+// PayFrom to the last working day like payroll pays them: each day is the annual salary / 12
+// shared over the working days of its month, and absences and unpaid leave are not paid.
+// Untaken days of encashable leave accrued to the last working day since the start of its year or
+// of employment, if later (the entitlement prorated by calendar days), are paid, and days taken
+// beyond it recovered, at the annual salary / 365 a day; so are the calendar days of notice not
+// served between the notice date and the last working day. Reimbursements and adjustments are
+// added as given. Loans are recovered from what is left, in order, and LoanRepayments records how
+// much of each; the rest stays outstanding on the loan. Other deductions are not limited to what
+// is paid: a negative Net is owed by the employee.
+func CalculateSettlement(in SettlementInput) models.FinalSettlement {
+	st := models.FinalSettlement{
+		AnnualSalary:     in.AnnualSalary,
+		PayFrom:          in.PayFrom,
+		LastWorkingDay:   in.LastWorkingDay,
+		NoticeDate:       in.NoticeDate,
+		NoticePeriodDays: in.NoticePeriodDays,
+		Items:            []models.PayrollItem{},
+	}
+	add := func(kind models.PayrollItemKind, code, label string, amount float64, basis string) {
+		if amount = roundMoney(amount); amount != 0 {
+			st.Items = append(st.Items, models.PayrollItem{Code: code, Label: label, Kind: kind, Amount: amount, Basis: basis})
+		}
+	}
+	lwd := in.LastWorkingDay
+
+	unpaid := dateSet(append(append([]time.Time(nil), in.Absences...), in.UnpaidLeave...))
+	salary := 0.0
+	for m := time.Date(in.PayFrom.Year(), in.PayFrom.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(lwd); m = m.AddDate(0, 1, 0) {
+		days := WorkingDays(m, m.AddDate(0, 1, -1))
+		for _, d := range days {
+			if d.Before(in.PayFrom) || d.After(lwd) {
+				continue
+			}
+			if unpaid[dateKey(d)] {
+				st.UnpaidDays++
+				continue
+			}
+			st.WorkedDays++
+			salary += in.AnnualSalary / 12 / float64(len(days))
+		}
+	}
+	if st.WorkedDays > 0 {
+		add(models.PayrollEarning, "SALARY", "Salary to last working day", salary,
+			fmt.Sprintf("%d working days from %s, %d unpaid", st.WorkedDays+st.UnpaidDays, dateKey(in.PayFrom), st.UnpaidDays))
+	}
+
+	dayRate := in.AnnualSalary / 365
+	yearStart := time.Date(lwd.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	yearDays := yearStart.AddDate(1, 0, 0).Sub(yearStart).Hours() / 24
+	accrualStart := yearStart
+	if in.EmployedFrom.After(yearStart) {
+		accrualStart = in.EmployedFrom
+	}
+	elapsed := lwd.Sub(accrualStart).Hours()/24 + 1
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	for _, b := range LeaveBalances(in.Entitlements, in.Leaves, lwd) {
+		if !encashableLeave[b.LeaveType] {
+			continue
+		}
+		accrued := math.Round(b.Entitlement*elapsed/yearDays*100) / 100
+		days := math.Round((accrued-b.Taken)*100) / 100
+		name := strings.ToLower(string(b.LeaveType))
+		switch {
+		case days > 0:
+			add(models.PayrollEarning, "LEAVE_ENCASHMENT", fmt.Sprintf("Leave encashment: %s", name), days*dayRate,
+				fmt.Sprintf("%g of %g days accrued untaken at %.2f a day", days, accrued, dayRate))
+		case days < 0:
+			add(models.PayrollDeduction, "LEAVE_EXCESS", fmt.Sprintf("Leave taken beyond accrual: %s", name), -days*dayRate,
+				fmt.Sprintf("%g days taken of %g accrued at %.2f a day", b.Taken, accrued, dayRate))
+		}
+	}
+
+	if in.NoticePeriodDays > 0 {
+		if in.NoticeDate != nil && !in.NoticeDate.After(lwd) {
+			st.NoticeServedDays = int(lwd.Sub(*in.NoticeDate).Hours() / 24)
+		}
+		if st.NoticeServedDays > in.NoticePeriodDays {
+			st.NoticeServedDays = in.NoticePeriodDays
+		}
+		st.ShortfallDays = in.NoticePeriodDays - st.NoticeServedDays
+		if st.ShortfallDays > 0 {
+			add(models.PayrollDeduction, "NOTICE_SHORTFALL", "Notice period not served", float64(st.ShortfallDays)*dayRate,
+				fmt.Sprintf("%d of %d days' notice at %.2f a day", st.ShortfallDays, in.NoticePeriodDays, dayRate))
+		}
+	}
+
+	for _, r := range in.Reimbursements {
+		add(models.PayrollReimbursement, "REIMBURSEMENT", r.Label, r.Amount, fmt.Sprintf("expense claim %d", r.ClaimID))
+	}
+	st.Items = append(st.Items, in.Adjustments...)
+	settlementTotals(&st)
+	st.LoanRepayments = map[uint]float64{}
+	left := st.Net
+	for _, l := range in.Loans {
+		amount := roundMoney(math.Max(0, math.Min(l.Amount, left)))
+		basis := l.Basis
+		if amount < roundMoney(l.Amount) {
+			basis = fmt.Sprintf("%s, limited to remaining pay; %.2f stays outstanding", basis, roundMoney(l.Amount-amount))
+		}
+		add(models.PayrollDeduction, "LOAN", l.Label, amount, basis)
+		if amount > 0 {
+			st.LoanRepayments[l.LoanID] = amount
+		}
+		left -= amount
+	}
+	settlementTotals(&st)
+	return st
+}
+
+// SettlementService calculates what leavers are owed in a reviewable statement that HR
+// finalizes.
+type SettlementService struct {
+	db       *gorm.DB
+	payslips *PayslipService
+	notify   *NotificationService
+}
+
+func NewSettlementService(db *gorm.DB, store storage.BlobStore) *SettlementService {
+	return &SettlementService{db: db, payslips: NewPayslipService(db, store), notify: NewNotificationService(db)}
+}
+
+// SettlementRequest sets the inputs HR decides. A zero LastWorkingDay means the termination
+// date, or the last working day of the offboarding. A nil NoticeDate means the day the
+// offboarding started; a nil NoticePeriodDays the notice of the employee's grade.
+type SettlementRequest struct {
+	LastWorkingDay   time.Time
+	NoticeDate       *time.Time
+	NoticePeriodDays *int
+	By               uint
+}
+
+// lastOffboarding returns the employee's latest offboarding that was not cancelled, if any.
+func lastOffboarding(tx *gorm.DB, employeeID uint) (*models.Checklist, error) {
+	var cl models.Checklist
+	err := tx.Where("employee_id = ? AND kind = ? AND status <> ?", employeeID, models.ChecklistOffboarding, models.ChecklistCancelled).
+		Order("created_at DESC").First(&cl).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cl, nil
+}
+
+// settlementInput gathers the inputs of emp's settlement. Adjustments are left to the caller.
+func settlementInput(tx *gorm.DB, emp *models.Employee, req SettlementRequest) (SettlementInput, error) {
+	in := SettlementInput{AnnualSalary: emp.Salary, LastWorkingDay: req.LastWorkingDay, NoticeDate: req.NoticeDate}
+	// the first job record marks the start of employment
+	var start sql.NullTime
+	if err := tx.Model(&models.JobRecord{}).Where("employee_id = ?", emp.ID).Select("MIN(effective_date)").Row().Scan(&start); err != nil {
+		return in, err
+	}
+	if !start.Valid {
+		return in, errors.New("the employee has no job history")
+	}
+	in.EmployedFrom = time.Date(start.Time.Year(), start.Time.Month(), start.Time.Day(), 0, 0, 0, 0, time.UTC)
+	offboarding, err := lastOffboarding(tx, emp.ID)
+	if err != nil {
+		return in, err
+	}
+	switch {
+	case emp.TerminationDate != nil:
+		if !in.LastWorkingDay.IsZero() && !in.LastWorkingDay.Equal(*emp.TerminationDate) {
+			return in, fmt.Errorf("the last working day of a terminated employee is their termination date, %s", dateKey(*emp.TerminationDate))
+		}
+		in.LastWorkingDay = *emp.TerminationDate
+	case !in.LastWorkingDay.IsZero():
+	case offboarding != nil:
+		in.LastWorkingDay = offboarding.ReferenceDate
+	default:
+		return in, ErrNotLeaving
+	}
+	lwd := in.LastWorkingDay
+	if in.NoticeDate == nil && offboarding != nil {
+		y, m, d := offboarding.CreatedAt.Date()
+		started := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		in.NoticeDate = &started
+	}
+	if req.NoticePeriodDays != nil {
+		in.NoticePeriodDays = *req.NoticePeriodDays
+	} else if emp.GradeID != nil {
+		var g models.JobGrade
+		if err := tx.First(&g, *emp.GradeID).Error; err != nil {
+			return in, err
+		}
+		in.NoticePeriodDays = g.NoticePeriodDays
+	}
+	if in.NoticePeriodDays < 0 {
+		return in, errors.New("notice_period_days cannot be negative")
+	}
+
+	// pay from the day after the last pay period the employee was paid in, or from the start
+	var last sql.NullTime
+	if err := tx.Table("payroll_lines").
+		Joins("JOIN payroll_runs ON payroll_runs.id = payroll_lines.run_id").
+		Joins("JOIN pay_periods ON pay_periods.id = payroll_runs.period_id").
+		Where("payroll_lines.employee_id = ?", emp.ID).Select("MAX(pay_periods.end_date)").Row().Scan(&last); err != nil {
+		return in, err
+	}
+	in.PayFrom = in.EmployedFrom
+	if last.Valid {
+		next := last.Time.AddDate(0, 0, 1)
+		in.PayFrom = time.Date(next.Year(), next.Month(), next.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	if !in.PayFrom.After(lwd) {
+		absent, unpaidLeave, err := unpaidDays(tx, []uint{emp.ID}, in.PayFrom, lwd)
+		if err != nil {
+			return in, err
+		}
+		in.Absences, in.UnpaidLeave = absent[emp.ID], unpaidLeave[emp.ID]
+	}
+	if err := tx.Order("leave_type").Find(&in.Entitlements).Error; err != nil {
+		return in, err
+	}
+	if err := tx.Where("employee_id = ? AND status = ? AND start_date <= ? AND end_date >= ?",
+		emp.ID, models.LeaveApproved, lwd, time.Date(lwd.Year(), 1, 1, 0, 0, 0, 0, time.UTC)).Find(&in.Leaves).Error; err != nil {
+		return in, err
+	}
+
+	var loans []models.Loan
+	if err := withSchedule(tx).Where("employee_id = ? AND status = ?", emp.ID, models.LoanActive).Order("id").Find(&loans).Error; err != nil {
+		return in, err
+	}
+	for i := range loans {
+		repaid := 0.0
+		for _, r := range loans[i].Repayments {
+			repaid += r.Amount
+		}
+		if amount := LoanPayoff(loans[i].Schedule, repaid, lwd); amount > 0 {
+			in.Loans = append(in.Loans, LoanDeduction{
+				LoanID: loans[i].ID, Label: fmt.Sprintf("Balance of %s", loanName(&loans[i])), Amount: amount,
+				Basis: fmt.Sprintf("payoff on %s", dateKey(lwd)),
+			})
+		}
+	}
+	reimbursements, err := expenseReimbursements(tx)
+	if err != nil {
+		return in, err
+	}
+	in.Reimbursements = reimbursements[emp.ID]
+	return in, nil
+}
+
+// adjustments returns the HR adjustments among items.
+func adjustments(items []models.PayrollItem) []models.PayrollItem {
+	var out []models.PayrollItem
+	for _, it := range items {
+		if it.Code == "ADJUSTMENT" {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// Calculate creates the employee's draft settlement, or recalculates it with the given inputs.
+// HR adjustments are kept.
+func (s *SettlementService) Calculate(employeeID uint, req SettlementRequest) (*models.FinalSettlement, error) {
+	var st models.FinalSettlement
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var emp models.Employee
+		if err := tx.First(&emp, employeeID).Error; err != nil {
+			return err
+		}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("employee_id = ?", employeeID).First(&st).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if st.Status == models.SettlementFinalized {
+			return ErrSettlementStatus
+		}
+		in, err := settlementInput(tx, &emp, req)
+		if err != nil {
+			return err
+		}
+		in.Adjustments = adjustments(st.Items)
+		next := CalculateSettlement(in)
+		next.ID, next.CreatedAt, next.Version = st.ID, st.CreatedAt, st.Version+1
+		next.EmployeeID, next.EmployeeName, next.Status = emp.ID, emp.Name, models.SettlementDraft
+		next.CalculatedBy, next.CalculatedAt = req.By, time.Now()
+		st = next
+		return tx.Save(&st).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// Recalculate refreshes a draft settlement from the current records, keeping its notice inputs.
+func (s *SettlementService) Recalculate(id, by uint) (*models.FinalSettlement, error) {
+	st, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	days := st.NoticePeriodDays
+	return s.Calculate(st.EmployeeID, SettlementRequest{LastWorkingDay: st.LastWorkingDay, NoticeDate: st.NoticeDate, NoticePeriodDays: &days, By: by})
+}
+
+func (s *SettlementService) Get(id uint) (*models.FinalSettlement, error) {
+	var st models.FinalSettlement
+	if err := s.db.First(&st, id).Error; err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// List returns the settlements, optionally in one status, newest first.
+func (s *SettlementService) List(status models.SettlementStatus) ([]models.FinalSettlement, error) {
+	tx := s.db.Order("id DESC")
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	var list []models.FinalSettlement
+	return list, tx.Find(&list).Error
+}
+
+// lockDraftSettlement loads a draft settlement for update.
+func lockDraftSettlement(tx *gorm.DB, id uint) (*models.FinalSettlement, error) {
+	var st models.FinalSettlement
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&st, id).Error; err != nil {
+		return nil, err
+	}
+	if st.Status != models.SettlementDraft {
+		return nil, ErrSettlementStatus
+	}
+	return &st, nil
+}
+
+// SettlementAdjustment is an amount HR adds to a settlement: positive amounts are paid,
+// negative ones recovered.
+type SettlementAdjustment struct {
+	Label  string
+	Amount float64
+	Basis  string
+}
+
+// SetAdjustments replaces the HR adjustments of a draft settlement.
+func (s *SettlementService) SetAdjustments(id uint, adj []SettlementAdjustment) (*models.FinalSettlement, error) {
+	items := make([]models.PayrollItem, 0, len(adj))
+	for i, a := range adj {
+		a.Label, a.Amount = strings.TrimSpace(a.Label), roundMoney(a.Amount)
+		if a.Label == "" || a.Amount == 0 {
+			return nil, fmt.Errorf("adjustment %d: label and a non-zero amount are required", i+1)
+		}
+		it := models.PayrollItem{Code: "ADJUSTMENT", Label: a.Label, Kind: models.PayrollEarning, Amount: a.Amount, Basis: strings.TrimSpace(a.Basis)}
+		if a.Amount < 0 {
+			it.Kind, it.Amount = models.PayrollDeduction, -a.Amount
+		}
+		items = append(items, it)
+	}
+	var st *models.FinalSettlement
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if st, err = lockDraftSettlement(tx, id); err != nil {
+			return err
+		}
+		kept := make([]models.PayrollItem, 0, len(st.Items)+len(items))
+		for _, it := range st.Items {
+			if it.Code != "ADJUSTMENT" {
+				kept = append(kept, it)
+			}
+		}
+		st.Items = append(kept, items...)
+		settlementTotals(st)
+		st.Version++
+		return tx.Save(st).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// DeleteDraft removes a settlement that is not finalized.
+func (s *SettlementService) DeleteDraft(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		st, err := lockDraftSettlement(tx, id)
+		if err != nil {
+			return err
+		}
+		return tx.Delete(st).Error
+	})
+}
+
+// Finalize closes a draft settlement of a terminated employee once every payroll run paying
+// them is finalized. The figures must still match the records. Loans are repaid with what the
+// settlement recovers and closed when that clears them; claims are marked paid, and later payroll
+// runs no longer pay the employee.
+func (s *SettlementService) Finalize(id, by uint) (*models.FinalSettlement, error) {
+	var st *models.FinalSettlement
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if st, err = lockDraftSettlement(tx, id); err != nil {
+			return err
+		}
+		var emp models.Employee
+		if err := tx.First(&emp, st.EmployeeID).Error; err != nil {
+			return err
+		}
+		if emp.Status != models.EmploymentTerminated || emp.TerminationDate == nil {
+			return ErrNotTerminated
+		}
+		var open int64
+		if err := tx.Model(&models.PayrollLine{}).Joins("JOIN payroll_runs ON payroll_runs.id = payroll_lines.run_id").
+			Where("payroll_lines.employee_id = ? AND payroll_runs.status IN ?", emp.ID, []models.PayrollRunStatus{models.PayrollDraft, models.PayrollReviewed}).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrSettlementRunsOpen
+		}
+		days := st.NoticePeriodDays
+		in, err := settlementInput(tx, &emp, SettlementRequest{NoticeDate: st.NoticeDate, NoticePeriodDays: &days})
+		if err != nil {
+			return err
+		}
+		in.Adjustments = adjustments(st.Items)
+		check := CalculateSettlement(in)
+		if !check.PayFrom.Equal(st.PayFrom) || !check.LastWorkingDay.Equal(st.LastWorkingDay) || check.Net != st.Net ||
+			check.Earnings != st.Earnings || check.Deductions != st.Deductions || check.Reimbursements != st.Reimbursements {
+			return ErrSettlementStale
+		}
+
+		ref := fmt.Sprintf("final settlement #%d", st.ID)
+		outstanding := 0.0
+		for _, l := range in.Loans {
+			amount := check.LoanRepayments[l.LoanID]
+			if amount > 0 {
+				if err := tx.Create(&models.LoanRepayment{
+					LoanID: l.LoanID, Source: models.LoanRepaidSettlement, Date: st.LastWorkingDay, Amount: amount,
+					Posted: true, Reference: ref, RecordedBy: &by,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			if amount < roundMoney(l.Amount) {
+				// the rest stays outstanding on the loan for finance to recover
+				outstanding += l.Amount - amount
+				continue
+			}
+			if err := closeLoan(tx, l.LoanID, "settled in "+ref); err != nil {
+				return err
+			}
+		}
+		var claims []uint
+		for _, r := range in.Reimbursements {
+			claims = append(claims, r.ClaimID)
+		}
+		if len(claims) > 0 {
+			if err := tx.Model(&models.ExpenseClaim{}).Where("id IN ?", claims).Updates(map[string]interface{}{
+				"status": models.ExpensePaid, "paid_at": time.Now(), "payment_reference": ref,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		st.Status, st.FinalizedBy, st.FinalizedAt = models.SettlementFinalized, &by, &now
+		if err := tx.Model(st).Updates(map[string]interface{}{
+			"status": st.Status, "finalized_by": by, "finalized_at": now, "version": st.Version + 1,
+		}).Error; err != nil {
+			return err
+		}
+		if err := markDraftRunsStale(tx); err != nil {
+			return err
+		}
+		owed := fmt.Sprintf("%.2f is owed to %s", st.Net, emp.Name)
+		if st.Net < 0 {
+			owed = fmt.Sprintf("%s owes %.2f", emp.Name, -st.Net)
+		}
+		if outstanding > 0 {
+			owed += fmt.Sprintf("; %.2f of their loans stays outstanding", roundMoney(outstanding))
+		}
+		return s.notify.NotifyRole(tx, models.RoleFinance, models.Notification{
+			Kind:       "FINAL_SETTLEMENT",
+			Title:      fmt.Sprintf("Final settlement of %s finalized", emp.Name),
+			Body:       fmt.Sprintf("%s for the period from %s to %s.", owed, dateKey(st.PayFrom), dateKey(st.LastWorkingDay)),
+			EntityType: "final_settlement",
+			EntityID:   &st.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// Statement renders a settlement as a PDF on the default payslip template's branding.
+func (s *SettlementService) Statement(id uint) ([]byte, *models.FinalSettlement, error) {
+	st, err := s.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	var emp models.Employee
+	if err := s.db.First(&emp, st.EmployeeID).Error; err != nil {
+		return nil, nil, err
+	}
+	tpl, logo, err := s.payslips.defaultTemplate()
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := RenderSettlement(&SettlementData{Template: *tpl, Logo: logo, Settlement: *st, Employee: emp, GeneratedAt: time.Now()})
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, st, nil
+}
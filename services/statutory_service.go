@@ -0,0 +1,367 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/statutory"
+)
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// StatutoryService manages the versioned statutory rule sets and employees' tax declarations,
+// and prepares the statutory input of payroll runs.
+type StatutoryService struct {
+	db *gorm.DB
+}
+
+func NewStatutoryService(db *gorm.DB) *StatutoryService { return &StatutoryService{db: db} }
+
+// TaxYear returns the first and last day of the tax year containing day, for a tax year
+// starting on the first of startMonth.
+func TaxYear(day time.Time, startMonth time.Month) (time.Time, time.Time) {
+	y := day.Year()
+	if day.Month() < startMonth {
+		y--
+	}
+	start := time.Date(y, startMonth, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(1, 0, -1)
+}
+
+// PeriodsRemaining estimates how many periods of a frequency are left in a tax year ending on
+// yearEnd, counting the one starting on start.
+func PeriodsRemaining(start, yearEnd time.Time, f models.PayFrequency) int {
+	days := yearEnd.Sub(start).Hours()/24 + 1
+	n := int(math.Round(days / (365.25 / float64(f.PeriodsPerYear()))))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// EngineInfo describes a registered rule engine.
+type EngineInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (s *StatutoryService) ListEngines() []EngineInfo {
+	var out []EngineInfo
+	for _, n := range statutory.Engines() {
+		e, _ := statutory.Lookup(n)
+		out = append(out, EngineInfo{Name: n, Description: e.Describe()})
+	}
+	return out
+}
+
+// Rule sets
+
+// withCategories fills in the declaration categories the rule set recognises.
+func withCategories(rs *models.StatutoryRuleSet) {
+	if rules, err := statutory.Parse(rs.Engine, rs.Params); err == nil {
+		rs.Categories = rules.Categories()
+	}
+}
+
+// ListRuleSets returns the rule sets by country, newest version first.
+func (s *StatutoryService) ListRuleSets() ([]models.StatutoryRuleSet, error) {
+	var list []models.StatutoryRuleSet
+	if err := s.db.Order("country, effective_from DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for i := range list {
+		withCategories(&list[i])
+	}
+	return list, nil
+}
+
+func (s *StatutoryService) GetRuleSet(id uint) (*models.StatutoryRuleSet, error) {
+	var rs models.StatutoryRuleSet
+	if err := s.db.First(&rs, id).Error; err != nil {
+		return nil, err
+	}
+	withCategories(&rs)
+	return &rs, nil
+}
+
+// SaveRuleSet creates the rule set (ID 0) or replaces an existing one. Versions in effect for a
+// locked payroll period cannot change; add a new version instead.
+func (s *StatutoryService) SaveRuleSet(rs *models.StatutoryRuleSet) error {
+	rs.Name = strings.TrimSpace(rs.Name)
+	rs.Country = strings.ToUpper(strings.TrimSpace(rs.Country))
+	rs.Engine = strings.ToUpper(strings.TrimSpace(rs.Engine))
+	switch {
+	case rs.Name == "":
+		return errors.New("name is required")
+	case rs.Country != "" && !countryCode.MatchString(rs.Country):
+		return errors.New("country must be an ISO 3166 alpha-2 code, or empty for the default rules")
+	case rs.EffectiveFrom.IsZero():
+		return errors.New("effective_from is required")
+	}
+	if rs.TaxYearStartMonth == 0 {
+		rs.TaxYearStartMonth = 1
+	}
+	if rs.TaxYearStartMonth < 1 || rs.TaxYearStartMonth > 12 {
+		return errors.New("tax_year_start_month must be between 1 and 12")
+	}
+	rules, err := statutory.Parse(rs.Engine, rs.Params)
+	if err != nil {
+		return err
+	}
+	rs.Categories = rules.Categories()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		from := rs.EffectiveFrom
+		if rs.ID != 0 {
+			var cur models.StatutoryRuleSet
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cur, rs.ID).Error; err != nil {
+				return err
+			}
+			rs.CreatedAt, rs.CreatedBy = cur.CreatedAt, cur.CreatedBy
+			if cur.EffectiveFrom.Before(from) {
+				from = cur.EffectiveFrom
+			}
+		}
+		var n int64
+		if err := tx.Model(&models.StatutoryRuleSet{}).Where("country = ? AND effective_from = ? AND id <> ?",
+			rs.Country, rs.EffectiveFrom.Format("2006-01-02"), rs.ID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return errors.New("a rule set for this country already takes effect on that date")
+		}
+		if err := guardPayroll(tx, from, time.Time{}); err != nil {
+			return err
+		}
+		return tx.Save(rs).Error
+	})
+}
+
+// DeleteRuleSet removes a version that no locked payroll period used.
+func (s *StatutoryService) DeleteRuleSet(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var rs models.StatutoryRuleSet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rs, id).Error; err != nil {
+			return err
+		}
+		if err := guardPayroll(tx, rs.EffectiveFrom, time.Time{}); err != nil {
+			return err
+		}
+		return tx.Delete(&rs).Error
+	})
+}
+
+// Preview computes the deductions of a rule set for a sample input.
+func (s *StatutoryService) Preview(id uint, in statutory.Input) ([]statutory.Deduction, error) {
+	rs, err := s.GetRuleSet(id)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := statutory.Parse(rs.Engine, rs.Params)
+	if err != nil {
+		return nil, err
+	}
+	if in.Gross == 0 {
+		for _, v := range in.Earnings {
+			in.Gross += v
+		}
+	}
+	return rules.Calculate(in)
+}
+
+// Declarations
+
+// DeclarationFilter narrows declaration listings; zero values match everything.
+type DeclarationFilter struct {
+	EmployeeID uint
+	TaxYear    int
+	Status     models.TaxDeclarationStatus
+}
+
+func (s *StatutoryService) ListDeclarations(f DeclarationFilter) ([]models.TaxDeclaration, error) {
+	tx := s.db.Model(&models.TaxDeclaration{})
+	if f.EmployeeID != 0 {
+		tx = tx.Where("employee_id = ?", f.EmployeeID)
+	}
+	if f.TaxYear != 0 {
+		tx = tx.Where("tax_year = ?", f.TaxYear)
+	}
+	if f.Status != "" {
+		tx = tx.Where("status = ?", f.Status)
+	}
+	list := []models.TaxDeclaration{}
+	err := tx.Order("tax_year desc, id desc").Find(&list).Error
+	return list, err
+}
+
+// SubmitDeclaration records a declaration for approval. Categories are the exemption codes of
+// the rule sets; amounts of categories the employee's rules do not know are ignored.
+func (s *StatutoryService) SubmitDeclaration(d *models.TaxDeclaration) error {
+	d.Category = strings.ToUpper(strings.TrimSpace(d.Category))
+	d.Description = strings.TrimSpace(d.Description)
+	switch {
+	case !deductionCode.MatchString(d.Category):
+		return errors.New("category must be upper case letters, digits and underscores")
+	case d.Amount <= 0:
+		return errors.New("amount must be positive")
+	case d.TaxYear < 2000 || d.TaxYear > today().Year()+1:
+		return errors.New("invalid tax_year")
+	}
+	d.ID, d.Status, d.DecidedBy, d.DecidedAt, d.DecisionNote = 0, models.DeclarationPending, nil, nil, ""
+	return s.db.Create(d).Error
+}
+
+// DecideDeclaration approves or rejects a pending declaration. Approved amounts count from the
+// next calculation on, so draft payroll runs become stale.
+func (s *StatutoryService) DecideDeclaration(id uint, approve bool, by uint, note string) (*models.TaxDeclaration, error) {
+	var d models.TaxDeclaration
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&d, id).Error; err != nil {
+			return err
+		}
+		if d.Status != models.DeclarationPending {
+			return errors.New("declaration was already decided")
+		}
+		now := time.Now()
+		d.Status, d.DecidedBy, d.DecidedAt, d.DecisionNote = models.DeclarationRejected, &by, &now, strings.TrimSpace(note)
+		if approve {
+			d.Status = models.DeclarationApproved
+			if err := markDraftRunsStale(tx); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&d).Updates(map[string]interface{}{
+			"status": d.Status, "decided_by": by, "decided_at": now, "decision_note": d.DecisionNote,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// DeleteMyDeclaration withdraws an employee's pending declaration.
+func (s *StatutoryService) DeleteMyDeclaration(employeeID, id uint) error {
+	res := s.db.Where("id = ? AND employee_id = ? AND status = ?", id, employeeID, models.DeclarationPending).
+		Delete(&models.TaxDeclaration{})
+	if res.Error == nil && res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return res.Error
+}
+
+// Payroll input
+
+// statutoryInputs selects each employee's rule set for a period, by their country with the
+// default rules as fallback, and gathers the tax year's earlier finalized pay and approved
+// declarations. Employees without applicable rules get no entry.
+func statutoryInputs(tx *gorm.DB, period *models.PayPeriod, emps []models.Employee) (map[uint]StatutoryInput, error) {
+	var sets []models.StatutoryRuleSet
+	if err := tx.Where("effective_from <= ?", period.EndDate.Format("2006-01-02")).
+		Order("effective_from DESC").Find(&sets).Error; err != nil {
+		return nil, err
+	}
+	if len(sets) == 0 {
+		return nil, nil
+	}
+	current := map[string]*models.StatutoryRuleSet{}
+	for i := range sets {
+		if _, ok := current[sets[i].Country]; !ok {
+			current[sets[i].Country] = &sets[i]
+		}
+	}
+	parsed := map[uint]statutory.Rules{}
+	type year struct{ start, end time.Time }
+	years := map[uint]year{}
+	earliest := period.StartDate
+	for _, rs := range current {
+		rules, err := statutory.Parse(rs.Engine, rs.Params)
+		if err != nil {
+			return nil, fmt.Errorf("statutory rule set %s: %w", rs.Name, err)
+		}
+		parsed[rs.ID] = rules
+		start, end := TaxYear(period.EndDate, time.Month(rs.TaxYearStartMonth))
+		years[rs.ID] = year{start, end}
+		if start.Before(earliest) {
+			earliest = start
+		}
+	}
+
+	ids := make([]uint, len(emps))
+	for i := range emps {
+		ids[i] = emps[i].ID
+	}
+	// earlier finalized runs of the tax years, by the end of their period
+	var runs []models.PayrollRun
+	if err := tx.Preload("Period").Joins("JOIN pay_periods p ON p.id = payroll_runs.period_id").
+		Where("payroll_runs.status IN ? AND p.end_date >= ? AND p.end_date < ?",
+			[]models.PayrollRunStatus{models.PayrollFinalized, models.PayrollPaid},
+			earliest.Format("2006-01-02"), period.StartDate.Format("2006-01-02")).
+		Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	periodEnd := map[uint]time.Time{}
+	runIDs := make([]uint, 0, len(runs))
+	for _, r := range runs {
+		periodEnd[r.ID] = r.Period.EndDate
+		runIDs = append(runIDs, r.ID)
+	}
+	var lines []models.PayrollLine
+	if len(runIDs) > 0 {
+		if err := tx.Where("run_id IN ? AND employee_id IN ?", runIDs, ids).Find(&lines).Error; err != nil {
+			return nil, err
+		}
+	}
+	linesBy := map[uint][]models.PayrollLine{}
+	for _, l := range lines {
+		linesBy[l.EmployeeID] = append(linesBy[l.EmployeeID], l)
+	}
+	var decls []models.TaxDeclaration
+	if err := tx.Where("employee_id IN ? AND status = ?", ids, models.DeclarationApproved).Find(&decls).Error; err != nil {
+		return nil, err
+	}
+	declsBy := map[uint][]models.TaxDeclaration{}
+	for _, d := range decls {
+		declsBy[d.EmployeeID] = append(declsBy[d.EmployeeID], d)
+	}
+
+	out := map[uint]StatutoryInput{}
+	for _, e := range emps {
+		rs, ok := current[strings.ToUpper(e.Country)]
+		if !ok {
+			if rs, ok = current[""]; !ok {
+				continue
+			}
+		}
+		y := years[rs.ID]
+		in := StatutoryInput{
+			Rules:            parsed[rs.ID],
+			PeriodsRemaining: PeriodsRemaining(period.StartDate, y.end, period.Frequency),
+			YTD:              map[string]float64{},
+			Declarations:     map[string]float64{},
+		}
+		for _, l := range linesBy[e.ID] {
+			if periodEnd[l.RunID].Before(y.start) {
+				continue
+			}
+			for _, it := range l.Items {
+				in.YTD[it.Code] = roundMoney(in.YTD[it.Code] + it.Amount)
+			}
+			in.YTD["GROSS"] = roundMoney(in.YTD["GROSS"] + l.Gross)
+		}
+		for _, d := range declsBy[e.ID] {
+			if d.TaxYear == y.start.Year() {
+				in.Declarations[d.Category] += d.Amount
+			}
+		}
+		out[e.ID] = in
+	}
+	return out, nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+var (
+	ErrUploadTooLarge  = errors.New("file exceeds maximum upload size")
+	ErrUploadEmpty     = errors.New("file is empty")
+	ErrUploadBadFormat = errors.New("file type not allowed")
+)
+
+// defaultAllowedTypes are the sniffed content types accepted for evidence uploads.
+var defaultAllowedTypes = []string{"application/pdf", "image/jpeg", "image/png"}
+
+// MaxUploadBytes is the upload size limit, configurable through UPLOAD_MAX_BYTES.
+func MaxUploadBytes() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+		return v
+	}
+	return 5 << 20
+}
+
+// readUpload buffers r, enforcing maxBytes and sniffing the content type against allowed.
+// The declared client content type is ignored; only the file's bytes are trusted.
+func readUpload(r io.Reader, maxBytes int64, allowed []string) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, "", ErrUploadTooLarge
+	}
+	if len(data) == 0 {
+		return nil, "", ErrUploadEmpty
+	}
+	ct := http.DetectContentType(data)
+	for _, a := range allowed {
+		if ct == a {
+			return data, ct, nil
+		}
+	}
+	return nil, "", ErrUploadBadFormat
+}
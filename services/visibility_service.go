@@ -0,0 +1,132 @@
+package services
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/shaping"
+)
+
+// VisibilityService holds the response shaping policies that need the database: who reports to
+// whom, which employee records are the caller's own, and custom field definitions.
+type VisibilityService struct {
+	db *gorm.DB
+}
+
+func NewVisibilityService(db *gorm.DB) *VisibilityService { return &VisibilityService{db: db} }
+
+// Register installs the policies with package shaping.
+func (s *VisibilityService) Register() {
+	shaping.Register(models.Employee{}, s.Employee)
+	shaping.Register(models.JobRecord{}, s.JobRecord)
+}
+
+// Employee limits an employee record to the fields of the viewer's audience; see
+// models.EmployeeFieldVisible.
+func (s *VisibilityService) Employee(v *shaping.Viewer, rec interface{}) (func(string) bool, error) {
+	e := rec.(*models.Employee)
+	aud, err := s.EmployeeAudience(v, e)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.CustomFields) > 0 && models.EmployeeFieldVisible(aud, v.Role, "custom_fields") {
+		defs, err := s.customFields(v)
+		if err != nil {
+			return nil, err
+		}
+		// rec is a shallow copy that still shares the map with the original
+		values := make(models.CustomFieldValues, len(e.CustomFields))
+		for k, val := range e.CustomFields {
+			values[k] = val
+		}
+		e.CustomFields = values
+		redactCustomFields(e, defs, v.Role, aud == models.AudienceSelf)
+	}
+	return func(f string) bool { return models.EmployeeFieldVisible(aud, v.Role, f) }, nil
+}
+
+// EmployeeAudience tells how the viewer relates to e. Only MANAGER users get the manager view of
+// their reports.
+func (s *VisibilityService) EmployeeAudience(v *shaping.Viewer, e *models.Employee) (models.Audience, error) {
+	if e.UserID != 0 && e.UserID == v.UserID {
+		return models.AudienceSelf, nil
+	}
+	if models.HasPermission(v.Role, models.PermViewEmployees) {
+		return models.AudienceFull, nil
+	}
+	if v.Role == models.RoleManager && e.ID != 0 {
+		reports, err := s.reports(v)
+		if err != nil {
+			return "", err
+		}
+		if reports[e.ID] {
+			return models.AudienceManager, nil
+		}
+	}
+	return models.AudiencePeer, nil
+}
+
+// JobRecord hides salary history from callers without salary access, except on their own records.
+func (s *VisibilityService) JobRecord(v *shaping.Viewer, rec interface{}) (func(string) bool, error) {
+	if models.HasPermission(v.Role, models.PermViewSalary) {
+		return nil, nil
+	}
+	own, err := s.ownEmployees(v)
+	if err != nil {
+		return nil, err
+	}
+	if own[rec.(*models.JobRecord).EmployeeID] {
+		return nil, nil
+	}
+	return func(f string) bool { return f != "salary" }, nil
+}
+
+// reports returns the ids of the employees reporting to the viewer, directly or indirectly.
+func (s *VisibilityService) reports(v *shaping.Viewer) (map[uint]bool, error) {
+	res, err := v.Memo("reports", func() (interface{}, error) {
+		var ids []uint
+		err := s.db.Raw(`WITH RECURSIVE chain AS (
+				SELECT e.id FROM employees e JOIN employees m ON e.manager_id = m.id
+				WHERE m.user_id = ? AND m.status <> ?
+				UNION
+				SELECT e.id FROM employees e JOIN chain c ON e.manager_id = c.id
+			) SELECT id FROM chain`, v.UserID, models.EmploymentTerminated).Scan(&ids).Error
+		return idSet(ids), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(map[uint]bool), nil
+}
+
+// ownEmployees returns the ids of the viewer's employee records, including earlier employments.
+func (s *VisibilityService) ownEmployees(v *shaping.Viewer) (map[uint]bool, error) {
+	res, err := v.Memo("own_employees", func() (interface{}, error) {
+		var ids []uint
+		err := s.db.Model(&models.Employee{}).Where("user_id = ?", v.UserID).Pluck("id", &ids).Error
+		return idSet(ids), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(map[uint]bool), nil
+}
+
+func (s *VisibilityService) customFields(v *shaping.Viewer) ([]models.CustomFieldDefinition, error) {
+	res, err := v.Memo("custom_fields", func() (interface{}, error) {
+		defs, err := customFieldDefinitions(s.db)
+		return defs, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]models.CustomFieldDefinition), nil
+}
+
+func idSet(ids []uint) map[uint]bool {
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
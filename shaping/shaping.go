@@ -0,0 +1,290 @@
+// Package shaping redacts response data for the caller. Policies registered per record type
+// decide which json fields of each record a viewer sees; Shape applies them anywhere in a
+// response value, including inside slices, maps and other structs.
+package shaping
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/example/hrms-backend/models"
+)
+
+// Viewer is the authenticated caller a response is shaped for.
+type Viewer struct {
+	UserID uint
+	Role   models.UserRole
+	memo   map[string]memoEntry
+}
+
+type memoEntry struct {
+	val interface{}
+	err error
+}
+
+func NewViewer(userID uint, role models.UserRole) *Viewer {
+	return &Viewer{UserID: userID, Role: role}
+}
+
+// Memo returns the result of load under key, calling load once per viewer. Policies use it for
+// lookups shared by every record of a response, such as the caller's reports.
+func (v *Viewer) Memo(key string, load func() (interface{}, error)) (interface{}, error) {
+	if e, ok := v.memo[key]; ok {
+		return e.val, e.err
+	}
+	val, err := load()
+	if v.memo == nil {
+		v.memo = map[string]memoEntry{}
+	}
+	v.memo[key] = memoEntry{val, err}
+	return val, err
+}
+
+// Policy redacts one record for v. rec points to a copy of the record, which the policy may
+// modify; keep reports which json fields of it v sees, nil keeping them all.
+type Policy func(v *Viewer, rec interface{}) (keep func(field string) bool, err error)
+
+var (
+	mu       sync.RWMutex
+	policies = map[reflect.Type]Policy{}
+	// affected caches needsShaping.
+	affected = map[reflect.Type]bool{}
+)
+
+// Register installs p for records of sample's type, a struct value. Call it at startup.
+func Register(sample interface{}, p Policy) {
+	t := reflect.TypeOf(sample)
+	if t.Kind() != reflect.Struct {
+		panic("shaping: Register needs a struct value, got " + t.String())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	policies[t] = p
+	affected = map[reflect.Type]bool{}
+}
+
+func policyFor(t reflect.Type) (Policy, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := policies[t]
+	return p, ok
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// needsShaping reports whether values of t can hold a record with a policy.
+func needsShaping(t reflect.Type) bool {
+	mu.RLock()
+	res, ok := affected[t]
+	mu.RUnlock()
+	if ok {
+		return res
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	res = contains(t, map[reflect.Type]bool{})
+	affected[t] = res
+	return res
+}
+
+// contains walks t with mu held; seen breaks cycles through recursive types.
+func contains(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if _, ok := policies[t]; ok {
+		return true
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		// encodes itself, so its fields cannot be shaped
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return contains(t.Elem(), seen)
+	case reflect.Interface:
+		// the dynamic type is checked when the value is shaped
+		return true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() && contains(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Shape returns data as v may see it. Values holding no record with a policy are returned as they
+// are; the others are converted to their generic JSON form with the redacted fields removed.
+func Shape(v *Viewer, data interface{}) (interface{}, error) {
+	if v == nil || data == nil {
+		return data, nil
+	}
+	return shapeValue(v, reflect.ValueOf(data))
+}
+
+func shapeValue(v *Viewer, rv reflect.Value) (interface{}, error) {
+	if !needsShaping(rv.Type()) {
+		return rv.Interface(), nil
+	}
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return shapeValue(v, rv.Elem())
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		fallthrough
+	case reflect.Array:
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			var err error
+			if out[i], err = shapeValue(v, rv.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			val, err := shapeValue(v, iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			out[fmt.Sprint(iter.Key().Interface())] = val
+		}
+		return out, nil
+	case reflect.Struct:
+		return shapeStruct(v, rv)
+	}
+	return rv.Interface(), nil
+}
+
+func shapeStruct(v *Viewer, rv reflect.Value) (interface{}, error) {
+	rec := reflect.New(rv.Type())
+	rec.Elem().Set(rv)
+	var keep func(string) bool
+	if p, ok := policyFor(rv.Type()); ok {
+		var err error
+		if keep, err = p(v, rec.Interface()); err != nil {
+			return nil, err
+		}
+	}
+	obj, err := toObject(rec.Interface())
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range jsonFields(rv.Type(), nil) {
+		if keep != nil && !keep(f.name) {
+			continue
+		}
+		if _, present := obj[f.name]; !present || !needsShaping(f.typ) {
+			continue
+		}
+		fv, err := rec.Elem().FieldByIndexErr(f.index)
+		if err != nil {
+			continue
+		}
+		if obj[f.name], err = shapeValue(v, fv); err != nil {
+			return nil, err
+		}
+	}
+	if keep != nil {
+		for k := range obj {
+			if !keep(k) {
+				delete(obj, k)
+			}
+		}
+	}
+	return obj, nil
+}
+
+// toObject converts a struct to its generic JSON object, keeping numbers exact.
+func toObject(rec interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil || obj == nil {
+		return nil, errors.New("shaping: record does not encode as a JSON object")
+	}
+	return obj, nil
+}
+
+type jsonField struct {
+	name  string
+	index []int
+	typ   reflect.Type
+}
+
+// jsonFields lists the exported fields of t under their json names, promoting the fields of
+// untagged embedded structs as encoding/json does.
+func jsonFields(t reflect.Type, index []int) []jsonField {
+	var out []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		idx := append(append([]int{}, index...), i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				out = append(out, jsonFields(ft, idx)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		out = append(out, jsonField{name: name, index: idx, typ: f.Type})
+	}
+	return out
+}
+
+// Writer carries a request's viewer to the response helpers, which shape data through it.
+type Writer struct {
+	http.ResponseWriter
+	Viewer *Viewer
+}
+
+func NewWriter(w http.ResponseWriter, v *Viewer) *Writer {
+	return &Writer{ResponseWriter: w, Viewer: v}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *Writer) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// ViewerOf returns the viewer w carries, or nil for unauthenticated responses.
+func ViewerOf(w http.ResponseWriter) *Viewer {
+	if sw, ok := w.(*Writer); ok {
+		return sw.Viewer
+	}
+	return nil
+}
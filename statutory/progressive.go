@@ -0,0 +1,230 @@
+package statutory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+)
+
+func init() { Register("PROGRESSIVE", progressiveEngine{}) }
+
+var code = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,39}$`)
+
+// Slab taxes the part of annual taxable income up to UpTo (and above the previous slab) at Rate
+// percent. UpTo is 0 on the last slab, which has no upper limit.
+type Slab struct {
+	UpTo float64 `json:"up_to"`
+	Rate float64 `json:"rate"`
+}
+
+// Exemption lets declarations of Category reduce taxable income by up to Cap a year (0 for no
+// limit).
+type Exemption struct {
+	Category string  `json:"category"`
+	Label    string  `json:"label"`
+	Cap      float64 `json:"cap"`
+}
+
+// Contribution is a percentage of a base withheld every period, e.g. social security or
+// pension. Base is "GROSS" or an earning's item code. PeriodCap limits the amount per period;
+// AnnualBaseCap stops the contribution once the year's base reaches it, like a wage base limit.
+// PreTax contributions are deducted from taxable income.
+type Contribution struct {
+	Code          string  `json:"code"`
+	Name          string  `json:"name"`
+	Base          string  `json:"base"`
+	Rate          float64 `json:"rate"`
+	PeriodCap     float64 `json:"period_cap"`
+	AnnualBaseCap float64 `json:"annual_base_cap"`
+	PreTax        bool    `json:"pre_tax"`
+}
+
+// ProgressiveParams are the parameters of the PROGRESSIVE engine.
+type ProgressiveParams struct {
+	TaxCode           string  `json:"tax_code"`
+	TaxLabel          string  `json:"tax_label"`
+	StandardDeduction float64 `json:"standard_deduction"`
+	Slabs             []Slab  `json:"slabs"`
+	// SurtaxPercent adds a percentage of the tax on top, e.g. a health and education cess.
+	SurtaxPercent float64        `json:"surtax_percent"`
+	Exemptions    []Exemption    `json:"exemptions"`
+	Contributions []Contribution `json:"contributions"`
+}
+
+// progressiveEngine is the reference engine: contributions first, then income tax on slabs.
+//
+// Income tax is withheld on the cumulative method. Taxable income for the year is projected as
+// what was earned so far plus this period's pay for every remaining period, less pre-tax
+// contributions, the standard deduction and declared exemptions. The tax on it, less the tax
+// already withheld, is spread over the remaining periods, so raises and declarations even out
+// by the end of the year.
+type progressiveEngine struct{}
+
+func (progressiveEngine) Describe() string {
+	return "Income tax on progressive slabs withheld cumulatively, with percentage contributions and capped exemptions"
+}
+
+func (progressiveEngine) Parse(params json.RawMessage) (Rules, error) {
+	var p ProgressiveParams
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+	if p.TaxCode == "" {
+		p.TaxCode = "INCOME_TAX"
+	}
+	if p.TaxLabel == "" {
+		p.TaxLabel = "Income tax"
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func validRate(r float64) bool { return r >= 0 && r <= 100 }
+
+func (p *ProgressiveParams) validate() error {
+	codes := map[string]bool{}
+	unique := func(c string) error {
+		if !code.MatchString(c) {
+			return fmt.Errorf("code %q must be upper case letters, digits and underscores", c)
+		}
+		if codes[c] {
+			return fmt.Errorf("code %s is used twice", c)
+		}
+		codes[c] = true
+		return nil
+	}
+	if err := unique(p.TaxCode); err != nil {
+		return err
+	}
+	if p.StandardDeduction < 0 || p.SurtaxPercent < 0 {
+		return errors.New("standard_deduction and surtax_percent cannot be negative")
+	}
+	for i, s := range p.Slabs {
+		last := i == len(p.Slabs)-1
+		switch {
+		case !validRate(s.Rate):
+			return fmt.Errorf("slab %d: rate must be between 0 and 100", i+1)
+		case last && s.UpTo != 0:
+			return errors.New("the last slab must have no upper limit (up_to 0)")
+		case !last && (s.UpTo <= 0 || i > 0 && s.UpTo <= p.Slabs[i-1].UpTo):
+			return fmt.Errorf("slab %d: up_to must be positive and above the previous slab", i+1)
+		}
+	}
+	categories := map[string]bool{}
+	for _, e := range p.Exemptions {
+		if !code.MatchString(e.Category) || categories[e.Category] {
+			return fmt.Errorf("exemption category %q is invalid or used twice", e.Category)
+		}
+		categories[e.Category] = true
+		if e.Cap < 0 {
+			return fmt.Errorf("exemption %s: cap cannot be negative", e.Category)
+		}
+	}
+	for i := range p.Contributions {
+		c := &p.Contributions[i]
+		if err := unique(c.Code); err != nil {
+			return err
+		}
+		if c.Name == "" {
+			c.Name = c.Code
+		}
+		if c.Base == "" {
+			c.Base = "GROSS"
+		}
+		if !code.MatchString(c.Base) {
+			return fmt.Errorf("contribution %s: invalid base %q", c.Code, c.Base)
+		}
+		if !validRate(c.Rate) || c.PeriodCap < 0 || c.AnnualBaseCap < 0 {
+			return fmt.Errorf("contribution %s: rate must be between 0 and 100 and caps cannot be negative", c.Code)
+		}
+	}
+	return nil
+}
+
+func (p *ProgressiveParams) Categories() []string {
+	out := make([]string, len(p.Exemptions))
+	for i, e := range p.Exemptions {
+		out[i] = e.Category
+	}
+	return out
+}
+
+// Tax returns the tax on an annual taxable income by the slabs, before surtax.
+func (p *ProgressiveParams) Tax(income float64) float64 {
+	tax, lower := 0.0, 0.0
+	for _, s := range p.Slabs {
+		upper := income
+		if s.UpTo != 0 && s.UpTo < income {
+			upper = s.UpTo
+		}
+		if upper > lower {
+			tax += (upper - lower) * s.Rate / 100
+		}
+		if s.UpTo == 0 || income <= s.UpTo {
+			break
+		}
+		lower = s.UpTo
+	}
+	return tax
+}
+
+func round(v float64) float64 { return math.Round(v*100) / 100 }
+
+func (p *ProgressiveParams) Calculate(in Input) ([]Deduction, error) {
+	periods := in.PeriodsRemaining
+	if periods < 1 {
+		periods = 1
+	}
+	out := []Deduction{}
+	var preTax, ytdPreTax float64
+	for _, c := range p.Contributions {
+		base := in.Base(c.Base)
+		basis := fmt.Sprintf("%g%% of %s %.2f", c.Rate, c.Base, base)
+		if c.AnnualBaseCap > 0 {
+			if room := math.Max(c.AnnualBaseCap-in.YTD[c.Base], 0); room < base {
+				base = room
+				basis = fmt.Sprintf("%g%% of %.2f, the rest of the %.2f annual limit", c.Rate, base, c.AnnualBaseCap)
+			}
+		}
+		amount := round(base * c.Rate / 100)
+		if c.PeriodCap > 0 && amount > c.PeriodCap {
+			amount, basis = c.PeriodCap, basis+fmt.Sprintf(", capped at %.2f", c.PeriodCap)
+		}
+		if c.PreTax {
+			preTax += amount
+			ytdPreTax += in.YTD[c.Code]
+		}
+		if amount > 0 {
+			out = append(out, Deduction{Code: c.Code, Label: c.Name, Amount: amount, Basis: basis})
+		}
+	}
+	if len(p.Slabs) == 0 {
+		return out, nil
+	}
+
+	taxable := in.YTD["GROSS"] - ytdPreTax + (in.Gross-preTax)*float64(periods)
+	relief := p.StandardDeduction
+	for _, e := range p.Exemptions {
+		d := math.Max(in.Declarations[e.Category], 0)
+		if e.Cap > 0 && d > e.Cap {
+			d = e.Cap
+		}
+		relief += d
+	}
+	taxable = math.Max(taxable-relief, 0)
+	annual := round(p.Tax(taxable) * (1 + p.SurtaxPercent/100))
+	withheld := in.YTD[p.TaxCode]
+	if due := round((annual - withheld) / float64(periods)); due > 0 {
+		out = append(out, Deduction{Code: p.TaxCode, Label: p.TaxLabel, Amount: due,
+			Basis: fmt.Sprintf("annual tax %.2f on projected taxable income %.2f, less %.2f withheld, over %d periods",
+				annual, taxable, withheld, periods)})
+	}
+	return out, nil
+}
@@ -0,0 +1,109 @@
+// Package statutory computes the deductions the law requires from pay: income tax, social
+// security, pension and the like. The rules differ per country and change every year, so they
+// are interpreted by pluggable engines: an Engine turns the parameters of a rule set into Rules,
+// and Rules compute one employee's deductions for one pay period.
+//
+// Engines register themselves by name, much like database drivers; the payroll keeps versioned
+// rule sets naming the engine and holding its parameters.
+package statutory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Input is an employee's pay for one period, as far as statutory deductions need it. Amounts
+// are per period; YTD covers the earlier periods of the same tax year.
+type Input struct {
+	PeriodsPerYear int `json:"periods_per_year"`
+	// PeriodsRemaining counts the periods left in the tax year, this one included.
+	PeriodsRemaining int                `json:"periods_remaining"`
+	Gross            float64            `json:"gross"`
+	Earnings         map[string]float64 `json:"earnings"`
+	// YTD holds earlier amounts by item code, earnings and deductions alike, and gross pay
+	// under "GROSS".
+	YTD map[string]float64 `json:"ytd"`
+	// Declarations are the employee's approved declared amounts for the tax year by category,
+	// e.g. investments that reduce taxable income.
+	Declarations map[string]float64 `json:"declarations"`
+}
+
+// Base returns the period amount a rate applies to: gross pay for "GROSS", else the earning
+// with that item code.
+func (in *Input) Base(code string) float64 {
+	if code == "GROSS" {
+		return in.Gross
+	}
+	return in.Earnings[code]
+}
+
+// Deduction is one amount withheld from pay; Basis explains how it was computed.
+type Deduction struct {
+	Code   string  `json:"code"`
+	Label  string  `json:"label"`
+	Amount float64 `json:"amount"`
+	Basis  string  `json:"basis,omitempty"`
+}
+
+// Rules are the parsed parameters of a rule set.
+type Rules interface {
+	// Categories lists the declaration categories the rules take into account.
+	Categories() []string
+	// Calculate returns the deductions of one period, in the order they apply.
+	Calculate(in Input) ([]Deduction, error)
+}
+
+// Engine interprets rule set parameters.
+type Engine interface {
+	// Describe says in one sentence what the engine computes.
+	Describe() string
+	// Parse validates params and returns the rules they describe.
+	Parse(params json.RawMessage) (Rules, error)
+}
+
+var (
+	mu      sync.RWMutex
+	engines = map[string]Engine{}
+)
+
+// Register makes an engine available under name. It panics if the name is taken, as
+// registration happens in init functions.
+func Register(name string, e Engine) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := engines[name]; dup {
+		panic(fmt.Sprintf("statutory: engine %s registered twice", name))
+	}
+	engines[name] = e
+}
+
+// Lookup returns the engine registered under name.
+func Lookup(name string) (Engine, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := engines[name]
+	return e, ok
+}
+
+// Engines returns the names of the registered engines, sorted.
+func Engines() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(engines))
+	for n := range engines {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse looks up the engine and parses params with it.
+func Parse(engine string, params json.RawMessage) (Rules, error) {
+	e, ok := Lookup(engine)
+	if !ok {
+		return nil, fmt.Errorf("unknown engine %q", engine)
+	}
+	return e.Parse(params)
+}
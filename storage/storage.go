@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned when a blob does not exist in the store.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore persists opaque binary objects under string keys.
+type BlobStore interface {
+	Put(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+var (
+	defaultStore BlobStore
+	storeOnce    sync.Once
+)
+
+// Default returns the process-wide blob store configured from the environment. STORAGE_BACKEND
+// selects "local" (the default, below STORAGE_DIR) or "s3", configured through the S3_* variables.
+// A broken S3 configuration is fatal, as nothing could be stored.
+func Default() BlobStore {
+	storeOnce.Do(func() {
+		if strings.EqualFold(os.Getenv("STORAGE_BACKEND"), "s3") {
+			s, err := NewS3Store(S3Config{
+				Endpoint:  os.Getenv("S3_ENDPOINT"),
+				Region:    os.Getenv("S3_REGION"),
+				Bucket:    os.Getenv("S3_BUCKET"),
+				AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+				SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+				PathStyle: os.Getenv("S3_PATH_STYLE") != "false",
+			})
+			if err != nil {
+				log.Fatalf("storage: %v", err)
+			}
+			defaultStore = s
+			return
+		}
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "./data/blobs"
+		}
+		defaultStore = NewLocalStore(dir)
+	})
+	return defaultStore
+}
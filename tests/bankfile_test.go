@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/hrms-backend/bankfile"
+)
+
+func TestBankDetailValidation(t *testing.T) {
+	cases := []struct {
+		name  string
+		check func(string) error
+		value string
+		ok    bool
+	}{
+		{"IBAN DE", bankfile.ValidateIBAN, "DE89 3704 0044 0532 0130 00", true},
+		{"IBAN GB", bankfile.ValidateIBAN, "GB82WEST12345698765432", true},
+		{"IBAN lower case", bankfile.ValidateIBAN, "nl91abna0417164300", true},
+		{"IBAN check digits", bankfile.ValidateIBAN, "DE88370400440532013000", false},
+		{"IBAN country length", bankfile.ValidateIBAN, "DE8937040044053201300", false},
+		{"IBAN format", bankfile.ValidateIBAN, "1234567890123456", false},
+		{"ABA", bankfile.ValidateABA, "021000021", true},
+		{"ABA Boston", bankfile.ValidateABA, "011000015", true},
+		{"ABA check digit", bankfile.ValidateABA, "021000022", false},
+		{"ABA length", bankfile.ValidateABA, "02100002", false},
+		{"BIC 8", bankfile.ValidateBIC, "DEUTDEFF", true},
+		{"BIC 11", bankfile.ValidateBIC, "DEUTDEFF500", true},
+		{"BIC 9", bankfile.ValidateBIC, "DEUTDEFF5", false},
+	}
+	for _, c := range cases {
+		if err := c.check(c.value); (err == nil) != c.ok {
+			t.Errorf("%s: %q gave %v", c.name, c.value, err)
+		}
+	}
+}
+
+func bankBatch() *bankfile.Batch {
+	return &bankfile.Batch{
+		ID:          "PAYROLL-7-1",
+		Description: "Salary June 2026",
+		PayDate:     day("2026-06-30"),
+		CreatedAt:   time.Date(2026, 6, 28, 9, 30, 0, 0, time.UTC),
+		Payments: []bankfile.Payment{
+			{Reference: "PR7-E1-1", PayeeID: "1", Name: "Zoë Müller", Account: "123456789", Routing: "021000021", Amount: 4575.5},
+			{Reference: "PR7-E2-1", PayeeID: "2", Name: "Sam Lee", Account: "987654321", Routing: "011000015", Amount: 3000},
+		},
+	}
+}
+
+func TestNACHAFile(t *testing.T) {
+	b := bankBatch()
+	b.Originator = bankfile.Originator{Name: "Acme Corp", ID: "1234567890", BankName: "Chase", Account: "5550001", Routing: "021000021", Currency: "USD"}
+	out, err := bankfile.Write(bankfile.NACHA, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) != 10 {
+		t.Fatalf("%d records, want one block of 10", len(lines))
+	}
+	for i, l := range lines {
+		if len(l) != 94 {
+			t.Errorf("record %d has %d characters", i+1, len(l))
+		}
+	}
+	if entry := lines[2]; entry[:12] != "622021000021" || entry[29:39] != "0000457550" || strings.TrimSpace(entry[54:76]) != "ZOE MULLER" {
+		t.Errorf("entry %q", entry)
+	}
+	// entry hash 02100002 + 01100001; credits 7575.50
+	control := lines[4]
+	if control[:4] != "8220" || control[4:10] != "000002" || control[10:20] != "0003200003" || control[32:44] != "000000757550" {
+		t.Errorf("batch control %q", control)
+	}
+	if lines[5][:1] != "9" || lines[9] != strings.Repeat("9", 94) {
+		t.Errorf("file control or padding %q", lines[5])
+	}
+}
+
+func TestSEPAFile(t *testing.T) {
+	b := bankBatch()
+	b.Originator = bankfile.Originator{Name: "Acme GmbH", Account: "DE89370400440532013000", Routing: "COBADEFFXXX", Currency: "EUR"}
+	b.Payments[0].Account, b.Payments[0].Routing = "DE02120300000000202051", ""
+	b.Payments[1].Account, b.Payments[1].Routing = "NL91ABNA0417164300", "ABNANL2A"
+	out, err := bankfile.Write(bankfile.SEPA, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Init struct {
+			NbOfTxs string `xml:"GrpHdr>NbOfTxs"`
+			CtrlSum string `xml:"GrpHdr>CtrlSum"`
+			Txs     []struct {
+				ID     string `xml:"PmtId>EndToEndId"`
+				Amount string `xml:"Amt>InstdAmt"`
+				Name   string `xml:"Cdtr>Nm"`
+				IBAN   string `xml:"CdtrAcct>Id>IBAN"`
+				BIC    string `xml:"CdtrAgt>FinInstnId>BIC"`
+			} `xml:"PmtInf>CdtTrfTxInf"`
+		} `xml:"CstmrCdtTrfInitn"`
+	}
+	if err := xml.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Init.NbOfTxs != "2" || doc.Init.CtrlSum != "7575.50" || len(doc.Init.Txs) != 2 {
+		t.Fatalf("header %+v", doc.Init)
+	}
+	tx := doc.Init.Txs[0]
+	if tx.ID != "PR7-E1-1" || tx.Amount != "4575.50" || tx.Name != "Zoe Muller" || tx.IBAN != "DE02120300000000202051" {
+		t.Errorf("transaction %+v", tx)
+	}
+	if doc.Init.Txs[1].BIC != "ABNANL2A" {
+		t.Errorf("creditor agent %+v", doc.Init.Txs[1])
+	}
+
+	// a payment with a bad IBAN fails the file; services leave such payments out beforehand
+	b.Payments[1].Account = "NL91ABNA0417164301"
+	if _, err := bankfile.Write(bankfile.SEPA, b, nil); err == nil {
+		t.Error("invalid IBAN accepted")
+	}
+}
+
+func TestCSVFile(t *testing.T) {
+	layout, err := bankfile.ParseCSVLayout(json.RawMessage(`{
+		"delimiter": ";", "date_format": "DD.MM.YYYY", "decimal_comma": true,
+		"columns": [
+			{"header": "Type", "field": "constant", "value": "SAL"},
+			{"header": "Beneficiary", "field": "name"},
+			{"header": "Account", "field": "account"},
+			{"header": "Amount", "field": "amount"},
+			{"header": "Date", "field": "pay_date"}
+		]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bankBatch()
+	b.Originator = bankfile.Originator{Name: "Acme", Account: "1", Currency: "GBP"}
+	out, err := bankfile.Write(bankfile.CSV, b, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Type;Beneficiary;Account;Amount;Date\nSAL;Zoë Müller;123456789;4575,50;30.06.2026\nSAL;Sam Lee;987654321;3000,00;30.06.2026\n"
+	if string(out) != want {
+		t.Errorf("csv\n%s\nwant\n%s", out, want)
+	}
+
+	for _, bad := range []string{
+		`{"columns": []}`,
+		`{"columns": [{"header": "X", "field": "salary"}]}`,
+		`{"delimiter": ";;", "columns": [{"header": "X", "field": "name"}]}`,
+		`{"date_format": "D/M/Y", "columns": [{"header": "X", "field": "name"}]}`,
+	} {
+		if _, err := bankfile.ParseCSVLayout(json.RawMessage(bad)); err == nil {
+			t.Errorf("layout %s accepted", bad)
+		}
+	}
+}
+
+func TestCheckPayment(t *testing.T) {
+	cases := []struct {
+		name   string
+		format bankfile.Format
+		p      bankfile.Payment
+		ok     bool
+	}{
+		{"nacha", bankfile.NACHA, bankfile.Payment{Name: "A", Account: "123", Routing: "021000021", Amount: 1}, true},
+		{"nacha bad routing", bankfile.NACHA, bankfile.Payment{Name: "A", Account: "123", Routing: "021000022", Amount: 1}, false},
+		{"nacha long account", bankfile.NACHA, bankfile.Payment{Name: "A", Account: "123456789012345678", Routing: "021000021", Amount: 1}, false},
+		{"sepa without BIC", bankfile.SEPA, bankfile.Payment{Name: "A", Account: "GB82WEST12345698765432", Amount: 1}, true},
+		{"sepa account number", bankfile.SEPA, bankfile.Payment{Name: "A", Account: "12345678", Amount: 1}, false},
+		{"csv", bankfile.CSV, bankfile.Payment{Name: "A", Account: "12345678", Amount: 1}, true},
+		{"no account", bankfile.CSV, bankfile.Payment{Name: "A", Amount: 1}, false},
+		{"zero amount", bankfile.CSV, bankfile.Payment{Name: "A", Account: "1", Amount: 0.004}, false},
+	}
+	for _, c := range cases {
+		if err := bankfile.CheckPayment(c.format, c.p); (err == nil) != c.ok {
+			t.Errorf("%s: %v", c.name, err)
+		}
+	}
+}
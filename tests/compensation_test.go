@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+)
+
+func letterData() *services.LetterData {
+	return &services.LetterData{
+		Template:    models.PayslipTemplate{CompanyName: "Acme Ltd", BrandColor: "#336699"},
+		Cycle:       models.CompensationCycle{Name: "2026 annual", EffectiveDate: day("2026-07-01")},
+		Proposal:    models.CompensationProposal{CurrentSalary: 60000, ProposedSalary: 64500},
+		Employee:    models.Employee{ID: 12, Name: "Ada Lovelace", Position: "Engineer", Department: "R&D"},
+		GeneratedAt: time.Date(2026, 6, 20, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestFillLetter(t *testing.T) {
+	d := letterData()
+	got := services.FillLetter("{{employee_name}} ({{employee_id}}): {{current_salary}} -> {{new_salary}}, +{{increase}} / {{increase_percent}}% from {{effective_date}}. {{unknown}}", d)
+	want := "Ada Lovelace (12): 60,000.00 -> 64,500.00, +4,500.00 / 7.5% from 01 July 2026. {{unknown}}"
+	if got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+
+	builtin := services.FillLetter("", d)
+	for _, s := range []string{"Dear Ada Lovelace,", "2026 annual", "64,500.00", "01 July 2026"} {
+		if !strings.Contains(builtin, s) {
+			t.Errorf("built-in letter lacks %q", s)
+		}
+	}
+	if strings.Contains(builtin, "{{") {
+		t.Errorf("built-in letter has unfilled placeholders: %s", builtin)
+	}
+}
+
+func TestRenderLetter(t *testing.T) {
+	d := letterData()
+	d.Text = strings.Repeat("A long paragraph that has to be wrapped over several lines of the letter. ", 60)
+	doc, err := services.RenderLetter(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(doc, []byte("%PDF-")) {
+		t.Fatal("not a PDF")
+	}
+	checkXref(t, doc)
+	// the text runs over to a second page
+	if !bytes.Contains(doc, []byte("/Count 2 ")) {
+		t.Error("long letter does not span two pages")
+	}
+}
+
+func TestProposalIncrease(t *testing.T) {
+	p := models.CompensationProposal{CurrentSalary: 50000, ProposedSalary: 52000.5}
+	if got := p.Increase(); got != 2000.5 {
+		t.Errorf("increase %v, want 2000.5", got)
+	}
+}
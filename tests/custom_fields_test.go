@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+)
+
+func customFieldDefs() []models.CustomFieldDefinition {
+	lo, hi := 0.0, 100.0
+	return []models.CustomFieldDefinition{
+		{Key: "tshirt_size", Type: models.CustomFieldSelect, Options: []string{"S", "M", "L"}, Required: true},
+		{Key: "cost_center", Type: models.CustomFieldText, Pattern: `CC-[0-9]{4}`},
+		{Key: "union_member", Type: models.CustomFieldBoolean},
+		{Key: "fte_percent", Type: models.CustomFieldNumber, Min: &lo, Max: &hi},
+		{Key: "badge_expiry", Type: models.CustomFieldDate},
+	}
+}
+
+func TestApplyCustomFields(t *testing.T) {
+	cases := []struct {
+		name    string
+		cur     models.CustomFieldValues
+		patch   models.CustomFieldValues
+		want    models.CustomFieldValues
+		wantErr bool
+	}{
+		{
+			name:  "normalizes json values",
+			patch: models.CustomFieldValues{"tshirt_size": "m", "union_member": true, "fte_percent": 80.0},
+			want:  models.CustomFieldValues{"tshirt_size": "M", "union_member": true, "fte_percent": 80.0},
+		},
+		{
+			name:  "parses import strings",
+			patch: models.CustomFieldValues{"tshirt_size": "L", "union_member": "yes", "fte_percent": "50", "badge_expiry": "2027-01-31", "cost_center": "CC-0042"},
+			want:  models.CustomFieldValues{"tshirt_size": "L", "union_member": true, "fte_percent": 50.0, "badge_expiry": "2027-01-31", "cost_center": "CC-0042"},
+		},
+		{
+			name:  "merges and clears",
+			cur:   models.CustomFieldValues{"tshirt_size": "S", "cost_center": "CC-0001"},
+			patch: models.CustomFieldValues{"cost_center": nil, "union_member": "false"},
+			want:  models.CustomFieldValues{"tshirt_size": "S", "union_member": false},
+		},
+		{name: "missing required", patch: models.CustomFieldValues{"union_member": true}, wantErr: true},
+		{name: "clearing required", cur: models.CustomFieldValues{"tshirt_size": "S"}, patch: models.CustomFieldValues{"tshirt_size": ""}, wantErr: true},
+		{name: "unknown option", patch: models.CustomFieldValues{"tshirt_size": "XXL"}, wantErr: true},
+		{name: "unknown field", patch: models.CustomFieldValues{"tshirt_size": "S", "shoe_size": "42"}, wantErr: true},
+		{name: "pattern mismatch", patch: models.CustomFieldValues{"tshirt_size": "S", "cost_center": "CC-12345"}, wantErr: true},
+		{name: "above max", patch: models.CustomFieldValues{"tshirt_size": "S", "fte_percent": 120.0}, wantErr: true},
+		{name: "not a number", patch: models.CustomFieldValues{"tshirt_size": "S", "fte_percent": "full"}, wantErr: true},
+		{name: "bad date", patch: models.CustomFieldValues{"tshirt_size": "S", "badge_expiry": "31/01/2027"}, wantErr: true},
+		{name: "wrong json type", patch: models.CustomFieldValues{"tshirt_size": "S", "cost_center": 42.0}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := services.ApplyCustomFields(customFieldDefs(), tc.cur, tc.patch, true)
+			if tc.wantErr {
+				if !errors.Is(err, services.ErrInvalidCustomField) {
+					t.Fatalf("got %v, %v; want ErrInvalidCustomField", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyCustomFieldsWithoutRequired(t *testing.T) {
+	got, err := services.ApplyCustomFields(customFieldDefs(), nil, models.CustomFieldValues{"union_member": "no"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (models.CustomFieldValues{"union_member": false}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,25 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/example/hrms-backend/utils"
+)
+
+func TestAddMonths(t *testing.T) {
+	for _, c := range []struct {
+		from   string
+		months int
+		want   string
+	}{
+		{"2026-01-31", 1, "2026-02-28"},
+		{"2028-01-31", 1, "2028-02-29"},
+		{"2026-01-31", 3, "2026-04-30"},
+		{"2026-03-15", 6, "2026-09-15"},
+		{"2026-11-30", 3, "2027-02-28"},
+	} {
+		if got := utils.AddMonths(day(c.from), c.months); !got.Equal(day(c.want)) {
+			t.Errorf("%s + %d months = %s, want %s", c.from, c.months, got.Format("2006-01-02"), c.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/example/hrms-backend/services"
+)
+
+func TestReadEmployeeRowsCSV(t *testing.T) {
+	file := "\ufeffName,  Manager   Username ,SALARY,cf_Badge\n" +
+		" Ada Lovelace ,alan, 1200.50 ,B-7\n" +
+		"Short\n"
+	rows, err := services.ReadEmployeeRows(strings.NewReader(file), "CSV")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]string{
+		{"name": "Ada Lovelace", "manager_username": "alan", "salary": "1200.50", "cf_badge": "B-7"},
+		{"name": "Short"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %v\nwant %v", rows, want)
+	}
+
+	for _, bad := range []struct{ file, format string }{
+		{"", "csv"},
+		{"a,\"b\n", "csv"},
+		{"name\n", "ods"},
+	} {
+		if _, err := services.ReadEmployeeRows(strings.NewReader(bad.file), bad.format); err == nil {
+			t.Errorf("%s %q was accepted", bad.format, bad.file)
+		}
+	}
+}
+
+func TestReadEmployeeRowsXLSX(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := f.GetSheetName(0)
+	for cell, v := range map[string]string{"A1": "User Name", "B1": "Department", "A2": "ada", "B2": " R&D "} {
+		if err := f.SetCellValue(sheet, cell, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := services.ReadEmployeeRows(&buf, "xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []map[string]string{{"user_name": "ada", "department": "R&D"}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %v, want %v", rows, want)
+	}
+}
+
+func TestImportEmployee(t *testing.T) {
+	rec := func(salary string) map[string]string {
+		return map[string]string{"name": " Ada  Lovelace ", "position": "Engineer", "department": "R&D", "salary": salary}
+	}
+	e, errs := services.ImportEmployee(nil, rec("1,200.50"))
+	if len(errs) != 0 || e.Name != "Ada Lovelace" || e.Salary != 1200.5 {
+		t.Errorf("got %q at %v with %v", e.Name, e.Salary, errs)
+	}
+	for _, bad := range []string{"", "abc", "0", "-5", "NaN", "Inf", "1.2.3"} {
+		if _, errs := services.ImportEmployee(nil, rec(bad)); len(errs) != 1 || !strings.Contains(errs[0], "salary") {
+			t.Errorf("salary %q: %v", bad, errs)
+		}
+	}
+	if _, errs := services.ImportEmployee(nil, map[string]string{"salary": "1"}); len(errs) != 3 {
+		t.Errorf("missing name, position and department: %v", errs)
+	}
+}
+
+func TestImportDuplicates(t *testing.T) {
+	d := services.NewImportDuplicates()
+	if errs := d.Claim(2, "alice", 0); len(errs) != 0 {
+		t.Errorf("first claim: %v", errs)
+	}
+	if errs := d.Claim(3, "Alice", 0); len(errs) != 1 || !strings.Contains(errs[0], "row 2") {
+		t.Errorf("same username in another case: %v", errs)
+	}
+	if errs := d.Claim(4, "bob", 9); len(errs) != 0 {
+		t.Errorf("new user: %v", errs)
+	}
+	if errs := d.Claim(5, "robert", 9); len(errs) != 1 || !strings.Contains(errs[0], "row 4") {
+		t.Errorf("same user under another name: %v", errs)
+	}
+	if !d.Has("BOB") || d.Has("carol") {
+		t.Error("Has does not match claimed usernames case-insensitively")
+	}
+}
+
+func TestImportOrder(t *testing.T) {
+	cases := []struct {
+		name          string
+		rows          []services.ImportLink
+		order, cyclic []int
+	}{
+		{
+			name:  "file order without managers in the file",
+			rows:  []services.ImportLink{{Username: "a"}, {Username: "b", Manager: ""}, {Username: "c"}},
+			order: []int{0, 1, 2},
+		},
+		{
+			name: "reports listed before their managers",
+			rows: []services.ImportLink{
+				{Username: "dev", Manager: "lead"},
+				{Username: "lead", Manager: "cto"},
+				{Username: "cto"},
+				{Username: "ops", Manager: "cto"},
+			},
+			order: []int{2, 3, 1, 0},
+		},
+		{
+			name: "manager chain loops",
+			rows: []services.ImportLink{
+				{Username: "a", Manager: "b"},
+				{Username: "b", Manager: "a"},
+				{Username: "c"},
+				{Username: "d", Manager: "d"},
+			},
+			order:  []int{2},
+			cyclic: []int{0, 1, 3},
+		},
+	}
+	for _, c := range cases {
+		order, cyclic := services.ImportOrder(c.rows)
+		if !reflect.DeepEqual(order, c.order) || !reflect.DeepEqual(cyclic, c.cyclic) {
+			t.Errorf("%s: order %v cyclic %v, want %v and %v", c.name, order, cyclic, c.order, c.cyclic)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/example/hrms-backend/services"
+)
+
+func TestSearchTSQuery(t *testing.T) {
+	for _, c := range []struct{ in, want string }{
+		{"", ""},
+		{"   ", ""},
+		{"Jo Smi", "jo:* & smi:*"},
+		{"O'Brien", "o:* & brien:*"},
+		{"a&b|c!", "a:* & b:* & c:*"},
+		{"'); DROP TABLE employees; --", "drop:* & table:* & employees:*"},
+		{"*:* & !", ""},
+		{"Zoë 42", "zoë:* & 42:*"},
+	} {
+		if got := services.SearchTSQuery(c.in); got != c.want {
+			t.Errorf("SearchTSQuery(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestEmployeeOrder(t *testing.T) {
+	for _, c := range []struct {
+		sort []string
+		want string // "" for an error
+	}{
+		{nil, "employees.id ASC"},
+		{[]string{"name"}, "employees.name ASC, employees.id ASC"},
+		{[]string{"-created_at", "department"}, "employees.created_at DESC, employees.department ASC, employees.id ASC"},
+		{[]string{"salary"}, ""},
+		{[]string{"unknown"}, ""},
+		{[]string{"-"}, ""},
+		{[]string{"--name"}, ""},
+		{[]string{"name desc"}, ""},
+		{[]string{"name; DROP TABLE employees"}, ""},
+		{[]string{"Name"}, ""},
+	} {
+		got, err := services.EmployeeOrder(c.sort)
+		switch {
+		case c.want == "" && err == nil:
+			t.Errorf("EmployeeOrder(%q) = %q, want an error", c.sort, got)
+		case c.want != "" && (err != nil || got != c.want):
+			t.Errorf("EmployeeOrder(%q) = %q, %v; want %q", c.sort, got, err, c.want)
+		}
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	for _, c := range []struct {
+		fields []string
+		ok     bool
+	}{
+		{nil, true},
+		{[]string{"id", "name", "department", "salary", "created_at"}, true},
+		{[]string{"bank_account_index"}, false}, // not serialized
+		{[]string{"custom_fields"}, true},
+		{[]string{"unknown"}, false},
+		{[]string{"-name"}, false},
+		{[]string{"name,position"}, false},
+		{[]string{"name; DROP TABLE employees"}, false},
+	} {
+		if err := services.ValidateFields(c.fields); (err == nil) != c.ok {
+			t.Errorf("ValidateFields(%q) = %v, want ok %v", c.fields, err, c.ok)
+		}
+	}
+}
+
+func TestSparseFields(t *testing.T) {
+	type item struct {
+		ID   uint   `json:"id"`
+		Name string `json:"name"`
+		City string `json:"city"`
+	}
+	items := []item{{1, "Ada", "London"}, {2, "Alan", "Wilmslow"}}
+	got, err := services.SparseFields(items, []string{"name", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{{"name": "Ada", "missing": nil}, {"name": "Alan", "missing": nil}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if same, _ := services.SparseFields(items, nil); !reflect.DeepEqual(same, items) {
+		t.Errorf("no fields changed the items: %v", same)
+	}
+	if _, err := services.SparseFields(item{ID: 1}, []string{"id"}); err == nil {
+		t.Error("a single object was accepted as a list")
+	}
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/example/hrms-backend/encryption"
+)
+
+func testKey(b byte) []byte { return bytes.Repeat([]byte{b}, encryption.KeySize) }
+
+func testKeyring(t *testing.T, primary string, ids ...string) *encryption.Keyring {
+	t.Helper()
+	ks := &encryption.KeySet{Primary: primary, Keys: map[string][]byte{}, IndexKey: testKey(0xff)}
+	for i, id := range ids {
+		ks.Keys[id] = testKey(byte(i + 1))
+	}
+	k, err := encryption.NewKeyring(ks)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return k
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	k := testKeyring(t, "k1", "k1")
+	a, err := k.Encrypt([]byte("85000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := k.Encrypt([]byte("85000"))
+	if a == b {
+		t.Error("equal plaintexts encrypted to the same ciphertext")
+	}
+	if !strings.HasPrefix(a, encryption.KeyPrefix("k1")) || strings.Contains(a, "85000") {
+		t.Errorf("unexpected ciphertext %q", a)
+	}
+	plain, err := k.Decrypt(a)
+	if err != nil || string(plain) != "85000" {
+		t.Fatalf("Decrypt = %q, %v", plain, err)
+	}
+
+	i := len(a) - 10
+	flipped := byte('A')
+	if a[i] == 'A' {
+		flipped = 'B'
+	}
+	tampered := a[:i] + string(flipped) + a[i+1:]
+	if _, err := k.Decrypt(tampered); err == nil {
+		t.Error("tampered ciphertext decrypted")
+	}
+}
+
+func TestRewrapRotatesKey(t *testing.T) {
+	old := testKeyring(t, "k1", "k1")
+	ct, _ := old.Encrypt([]byte("DE89370400440532013000"))
+
+	rotated := testKeyring(t, "k2", "k1", "k2")
+	out, changed, err := rotated.Rewrap(ct)
+	if err != nil || !changed {
+		t.Fatalf("Rewrap = %v, %v", changed, err)
+	}
+	if !strings.HasPrefix(out, encryption.KeyPrefix("k2")) {
+		t.Errorf("rewrapped value not under primary key: %q", out)
+	}
+	// the data itself is untouched, only the data key is re-wrapped
+	if out[strings.LastIndex(out, ":"):] != ct[strings.LastIndex(ct, ":"):] {
+		t.Error("rewrap re-encrypted the data")
+	}
+
+	// once rewrapped, the old key can be retired
+	retired, err := encryption.NewKeyring(&encryption.KeySet{
+		Primary: "k2", Keys: map[string][]byte{"k2": testKey(2)}, IndexKey: testKey(0xff),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := retired.Decrypt(out)
+	if err != nil || string(plain) != "DE89370400440532013000" {
+		t.Fatalf("Decrypt after rotation = %q, %v", plain, err)
+	}
+	if _, err := retired.Decrypt(ct); !errors.Is(err, encryption.ErrUnknownKey) {
+		t.Errorf("Decrypt under retired key: got %v, want ErrUnknownKey", err)
+	}
+
+	if _, changed, _ := rotated.Rewrap(out); changed {
+		t.Error("value under the primary key was rewrapped again")
+	}
+	out, changed, err = rotated.Rewrap("legacy plaintext")
+	if err != nil || !changed || !encryption.IsEncrypted(out) {
+		t.Errorf("plaintext Rewrap = %q, %v, %v", out, changed, err)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	k := testKeyring(t, "k1", "k1")
+	a := k.BlindIndex("bank_account_number", "12345678")
+	if a == "" || a != k.BlindIndex("bank_account_number", "12345678") {
+		t.Fatal("blind index is not deterministic")
+	}
+	if a == k.BlindIndex("bank_routing_code", "12345678") {
+		t.Error("purposes share an index")
+	}
+	if a == k.BlindIndex("bank_account_number", "12345679") {
+		t.Error("different values share an index")
+	}
+	if k.BlindIndex("bank_account_number", "") != "" {
+		t.Error("empty value has an index")
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+	t.Setenv("ENCRYPTION_KEY_FILE", "")
+	t.Setenv("ENCRYPTION_KEYS", "2024-06:"+k2+", 2023-01:"+k1)
+	t.Setenv("ENCRYPTION_INDEX_KEY", base64.StdEncoding.EncodeToString(testKey(9)))
+	ks, err := encryption.ProviderFromEnv().Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ks.Primary != "2024-06" || len(ks.Keys) != 2 || !bytes.Equal(ks.Keys["2023-01"], testKey(1)) {
+		t.Errorf("unexpected key set %+v", ks)
+	}
+
+	t.Setenv("ENCRYPTION_KEYS", "")
+	if _, err := (encryption.EnvProvider{}).Load(); !errors.Is(err, encryption.ErrNoKeys) {
+		t.Errorf("empty ENCRYPTION_KEYS: got %v, want ErrNoKeys", err)
+	}
+	t.Setenv("ENCRYPTION_KEYS", "k1:"+k1+",k1:"+k2)
+	if _, err := (encryption.EnvProvider{}).Load(); err == nil {
+		t.Error("duplicate key ids accepted")
+	}
+	t.Setenv("ENCRYPTION_KEYS", "k1:c2hvcnQ=")
+	ks, err = (encryption.EnvProvider{}).Load()
+	if err == nil {
+		_, err = encryption.NewKeyring(ks)
+	}
+	if err == nil {
+		t.Error("short key accepted")
+	}
+}
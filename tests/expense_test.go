@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+)
+
+func TestCheckExpensePolicy(t *testing.T) {
+	cats := map[uint]models.ExpenseCategory{
+		1: {ID: 1, Name: "Meals", PerItemLimit: 50, MonthlyLimit: 200},
+		2: {ID: 2, Name: "Travel", ReceiptRequired: true, ReceiptThreshold: 25},
+		3: {ID: 3, Name: "Old", Archived: true},
+	}
+	item := func(cat uint, date string, amount float64, receipts int) models.ExpenseItem {
+		return models.ExpenseItem{CategoryID: cat, Date: day(date), BaseAmount: amount, Receipts: make([]models.ExpenseReceipt, receipts)}
+	}
+	cases := []struct {
+		name    string
+		items   []models.ExpenseItem
+		claimed map[services.ExpenseMonth]float64
+		want    []string
+	}{
+		{
+			name:  "within limits",
+			items: []models.ExpenseItem{item(1, "2026-06-03", 50, 0), item(2, "2026-06-04", 25, 0), item(2, "2026-06-05", 80, 1)},
+		},
+		{
+			name:  "per item limit",
+			items: []models.ExpenseItem{item(1, "2026-06-03", 50.01, 0)},
+			want:  []string{"item 1: 50.01 exceeds the Meals limit of 50.00 per item"},
+		},
+		{
+			name:  "receipt above threshold",
+			items: []models.ExpenseItem{item(2, "2026-06-03", 10, 0), item(2, "2026-06-03", 25.5, 0)},
+			want:  []string{"item 2: Travel expenses over 25.00 need a receipt"},
+		},
+		{
+			name:    "monthly limit counts other claims of the month",
+			items:   []models.ExpenseItem{item(1, "2026-06-03", 40, 0), item(1, "2026-07-01", 40, 0)},
+			claimed: map[services.ExpenseMonth]float64{{CategoryID: 1, Month: "2026-06"}: 170},
+			want:    []string{"Meals in 2026-06: 210.00 exceeds the monthly limit of 200.00"},
+		},
+		{
+			name:  "archived and unknown categories",
+			items: []models.ExpenseItem{item(3, "2026-06-03", 1, 0), item(9, "2026-06-03", 1, 0)},
+			want:  []string{"item 1: category 3 is not available", "item 2: category 9 is not available"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := services.CheckExpensePolicy(c.items, cats, c.claimed)
+			if len(c.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, services.ErrExpensePolicy) {
+				t.Fatalf("got %v, want a policy error", err)
+			}
+			if got := strings.TrimPrefix(err.Error(), "expense policy: "); got != strings.Join(c.want, "; ") {
+				t.Errorf("got %q, want %q", got, strings.Join(c.want, "; "))
+			}
+		})
+	}
+}
+
+func TestCalculatePayWithReimbursements(t *testing.T) {
+	in := services.PayInput{
+		Frequency:    models.PayMonthly,
+		WorkingDays:  services.WorkingDays(day("2026-06-01"), day("2026-06-30")),
+		EmployedFrom: day("2025-01-01"),
+		Salaries:     []services.SalaryChange{{From: day("2025-01-01"), Annual: 66000}},
+		Reimbursements: []services.Reimbursement{
+			{ClaimID: 4, Label: "Expense claim #4: Conference", Amount: 120.4},
+			{ClaimID: 7, Label: "Expense claim #7: Taxi", Amount: 30.1},
+		},
+	}
+	rules := []models.DeductionRule{{Code: "PEN", Name: "Pension", Kind: models.DeductionPercent, Rate: 10, Active: true}}
+	line, err := services.CalculatePay(in, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reimbursements are not pay: gross and percentage deductions ignore them
+	if line.Gross != 5500 || line.Deductions != 550 {
+		t.Fatalf("gross %v deductions %v, want 5500 and 550", line.Gross, line.Deductions)
+	}
+	if line.Reimbursements != 150.5 || line.Net != 5100.5 {
+		t.Errorf("reimbursements %v net %v, want 150.5 and 5100.5", line.Reimbursements, line.Net)
+	}
+	var n int
+	for _, it := range line.Items {
+		if it.Kind == models.PayrollReimbursement {
+			n++
+			if it.Code != "REIMBURSEMENT" {
+				t.Errorf("reimbursement item code %q", it.Code)
+			}
+		}
+	}
+	if n != 2 {
+		t.Errorf("%d reimbursement items, want 2", n)
+	}
+}
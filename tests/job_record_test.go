@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+)
+
+func TestCarryJobChange(t *testing.T) {
+	hire := models.JobRecord{ID: 1, EffectiveDate: day("2026-01-01"), Position: "Engineer", Department: "R&D", Salary: 50000}
+	// recorded first: the review cycle's raise from July and a move to Sales in September
+	later := []models.JobRecord{
+		{ID: 2, EffectiveDate: day("2026-07-01"), Position: "Engineer", Department: "R&D", Salary: 55000},
+		{ID: 3, EffectiveDate: day("2026-09-01"), Position: "Account Manager", Department: "Sales", Salary: 55000},
+	}
+	// then a promotion from June 15, built on the hire record
+	position := "Senior Engineer"
+	c := services.JobChange{Position: &position}
+	promo := hire
+	promo.ID, promo.EffectiveDate, promo.Position = 4, day("2026-06-15"), position
+
+	changed := services.CarryJobChange(c, hire, promo, later)
+	if len(changed) != 1 || changed[0] != 0 {
+		t.Fatalf("changed %v, want only the July record", changed)
+	}
+	if later[0].Position != "Senior Engineer" || later[0].Salary != 55000 {
+		t.Errorf("July record %q at %v, want the promotion kept with the raise", later[0].Position, later[0].Salary)
+	}
+	// the September move overrides the position
+	if later[1].Position != "Account Manager" {
+		t.Errorf("September record %q, want its own position kept", later[1].Position)
+	}
+
+	// a raise recorded after both, backdated to June, stops at the July raise
+	salary := 52000.0
+	raise := hire
+	raise.ID, raise.EffectiveDate, raise.Salary = 5, day("2026-06-01"), salary
+	if changed := services.CarryJobChange(services.JobChange{Salary: &salary}, hire, raise, later); len(changed) != 0 {
+		t.Errorf("changed %v, want none", changed)
+	}
+	if later[0].Salary != 55000 || later[1].Salary != 55000 {
+		t.Errorf("later salaries %v and %v, want 55000", later[0].Salary, later[1].Salary)
+	}
+}
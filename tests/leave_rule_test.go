@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+)
+
+func approvedLeave(emp uint, from, to string) models.Leave {
+	return models.Leave{EmployeeID: emp, Status: models.LeaveApproved, StartDate: day(from), EndDate: day(to)}
+}
+
+func TestCheckLeaveRules(t *testing.T) {
+	from, to := day("2026-03-15"), day("2026-03-31")
+	cases := []struct {
+		name      string
+		leave     models.Leave
+		blackouts []models.LeaveBlackout
+		rules     []models.StaffingRule
+		headcount int
+		others    []models.Leave
+		rule      string // "" for none
+		ruleID    uint
+		date      string
+	}{
+		{name: "no rules", leave: approvedLeave(1, "2026-03-10", "2026-03-20")},
+		{
+			name:  "overlapping blackouts report the earliest",
+			leave: approvedLeave(1, "2026-03-10", "2026-03-20"),
+			blackouts: []models.LeaveBlackout{
+				{ID: 1, StartDate: day("2026-03-15"), EndDate: day("2026-03-31")},
+				{ID: 2, StartDate: day("2026-03-01"), EndDate: day("2026-03-12")},
+				{ID: 3, StartDate: day("2026-03-21"), EndDate: day("2026-03-25")},
+			},
+			rule: "blackout", ruleID: 2, date: "2026-03-10",
+		},
+		{
+			name:      "blackout after the leave",
+			leave:     approvedLeave(1, "2026-03-10", "2026-03-20"),
+			blackouts: []models.LeaveBlackout{{ID: 3, StartDate: day("2026-03-21"), EndDate: day("2026-03-25")}},
+		},
+		{
+			name:   "max out reached",
+			leave:  approvedLeave(1, "2026-03-10", "2026-03-12"),
+			rules:  []models.StaffingRule{{ID: 7, MaxOut: 2}},
+			others: []models.Leave{approvedLeave(2, "2026-03-01", "2026-03-10")},
+		},
+		{
+			name:   "max out exceeded",
+			leave:  approvedLeave(1, "2026-03-10", "2026-03-12"),
+			rules:  []models.StaffingRule{{ID: 7, MaxOut: 2}},
+			others: []models.Leave{approvedLeave(2, "2026-03-01", "2026-03-10"), approvedLeave(3, "2026-03-09", "2026-03-11")},
+			rule:   "max_out", ruleID: 7, date: "2026-03-10",
+		},
+		{
+			name:      "min on duty at the threshold",
+			leave:     approvedLeave(1, "2026-03-10", "2026-03-12"),
+			rules:     []models.StaffingRule{{ID: 8, MinOnDuty: 3}},
+			headcount: 5,
+			others:    []models.Leave{approvedLeave(2, "2026-03-12", "2026-03-20")},
+		},
+		{
+			name:      "min on duty below the threshold",
+			leave:     approvedLeave(1, "2026-03-10", "2026-03-12"),
+			rules:     []models.StaffingRule{{ID: 8, MinOnDuty: 3}},
+			headcount: 5,
+			others:    []models.Leave{approvedLeave(2, "2026-03-12", "2026-03-20"), approvedLeave(3, "2026-03-01", "2026-03-31")},
+			rule:      "min_on_duty", ruleID: 8, date: "2026-03-12",
+		},
+		{
+			name:   "pending and own leaves do not count",
+			leave:  approvedLeave(1, "2026-03-10", "2026-03-12"),
+			rules:  []models.StaffingRule{{ID: 7, MaxOut: 1}},
+			others: []models.Leave{{EmployeeID: 2, Status: models.LeavePending, StartDate: day("2026-03-10"), EndDate: day("2026-03-12")}, approvedLeave(1, "2026-03-01", "2026-03-31")},
+		},
+		{
+			name:   "leave spanning into a rule window",
+			leave:  approvedLeave(1, "2026-03-12", "2026-03-16"),
+			rules:  []models.StaffingRule{{ID: 9, MaxOut: 1, StartDate: &from, EndDate: &to}},
+			others: []models.Leave{approvedLeave(2, "2026-03-10", "2026-03-20")},
+			rule:   "max_out", ruleID: 9, date: "2026-03-15",
+		},
+		{
+			name:   "leave ending before a rule window",
+			leave:  approvedLeave(1, "2026-03-12", "2026-03-14"),
+			rules:  []models.StaffingRule{{ID: 9, MaxOut: 1, StartDate: &from, EndDate: &to}},
+			others: []models.Leave{approvedLeave(2, "2026-03-10", "2026-03-20")},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := services.CheckLeaveRules(&c.leave, "Support", c.blackouts, c.rules, c.headcount, c.others)
+			if c.rule == "" {
+				if err != nil {
+					t.Fatalf("unexpected %v", err)
+				}
+				return
+			}
+			var ce *services.LeaveConstraintError
+			if !errors.As(err, &ce) {
+				t.Fatalf("got %v, want a %s violation", err, c.rule)
+			}
+			if ce.Rule != c.rule || ce.RuleID != c.ruleID || !ce.Date.Equal(day(c.date)) {
+				t.Errorf("%s #%d on %s, want %s #%d on %s", ce.Rule, ce.RuleID, ce.Date.Format(time.DateOnly), c.rule, c.ruleID, c.date)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+)
+
+func TestLoanSchedule(t *testing.T) {
+	// interest-free: equal shares, the last clearing the rounding
+	s := services.LoanSchedule(1000, 0, 3, day("2026-01-31"))
+	want := []float64{333.33, 333.33, 333.34}
+	for i, in := range s {
+		if in.Amount != want[i] || in.Interest != 0 {
+			t.Errorf("installment %d: %v + %v interest, want %v", in.Number, in.Principal, in.Interest, want[i])
+		}
+	}
+	// due dates stay at the end of short months
+	for i, d := range []string{"2026-01-31", "2026-02-28", "2026-03-31"} {
+		if !s[i].DueDate.Equal(day(d)) {
+			t.Errorf("installment %d due %s, want %s", i+1, s[i].DueDate.Format("2006-01-02"), d)
+		}
+	}
+
+	// 12% a year is 1% a month on the principal still owed
+	s = services.LoanSchedule(12000, 12, 12, day("2026-07-25"))
+	if len(s) != 12 {
+		t.Fatalf("%d installments, want 12", len(s))
+	}
+	if s[0].Interest != 120 || s[0].Amount != 1066.19 {
+		t.Errorf("first installment %v with %v interest, want 1066.19 with 120", s[0].Amount, s[0].Interest)
+	}
+	principal := 0.0
+	for _, in := range s {
+		principal += in.Principal
+	}
+	if math.Abs(principal-12000) > 0.001 || s[11].Balance != 0 {
+		t.Errorf("principal repaid %v, final balance %v; want 12000 and 0", principal, s[11].Balance)
+	}
+	if math.Abs(s[11].Amount-s[0].Amount) > 0.05 {
+		t.Errorf("last installment %v differs from %v by more than rounding", s[11].Amount, s[0].Amount)
+	}
+}
+
+func TestLoanDueAndPayoff(t *testing.T) {
+	s := services.LoanSchedule(1200, 12, 3, day("2026-01-31"))
+	// 1% a month: 408.03 a month, the first with 12 interest
+	if got := services.LoanDue(s, 0, day("2026-01-30")); got != 0 {
+		t.Errorf("due before the first installment: %v", got)
+	}
+	if got := services.LoanDue(s, 0, day("2026-02-28")); got != 816.06 {
+		t.Errorf("due by February %v, want 816.06", got)
+	}
+	// a partial deduction is caught up with the next installment
+	if got := services.LoanDue(s, 300, day("2026-02-28")); got != 516.06 {
+		t.Errorf("due after repaying 300: %v, want 516.06", got)
+	}
+	// settling after the first installment waives the later interest
+	if got := services.LoanPayoff(s, s[0].Amount, day("2026-02-10")); got != s[0].Balance {
+		t.Errorf("payoff %v, want the remaining principal %v", got, s[0].Balance)
+	}
+	if got := services.LoanPayoff(s, 2000, day("2026-02-10")); got != 0 {
+		t.Errorf("payoff after overpaying: %v", got)
+	}
+}
+
+func TestCalculatePayWithLoans(t *testing.T) {
+	in := services.PayInput{
+		Frequency:    models.PayMonthly,
+		WorkingDays:  services.WorkingDays(day("2026-06-01"), day("2026-06-30")),
+		EmployedFrom: day("2025-01-01"),
+		Salaries:     []services.SalaryChange{{From: day("2025-01-01"), Annual: 66000}},
+		Loans: []services.LoanDeduction{
+			{LoanID: 3, Label: "Repayment of salary advance #3", Amount: 500, Basis: "installments due"},
+			{LoanID: 5, Label: "Repayment of loan #5", Amount: 9000, Basis: "balance due on leaving"},
+		},
+	}
+	rules := []models.DeductionRule{{Code: "PEN", Name: "Pension", Kind: models.DeductionPercent, Rate: 10, Active: true}}
+	line, err := services.CalculatePay(in, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 5500 gross less 550 pension leaves 4950: 500 for the advance, the remaining 4450 for the loan
+	if line.Gross != 5500 || line.Deductions != 5500 || line.Net != 0 {
+		t.Fatalf("gross %v deductions %v net %v, want 5500, 5500 and 0", line.Gross, line.Deductions, line.Net)
+	}
+	if line.LoanRepayments[3] != 500 || line.LoanRepayments[5] != 4450 {
+		t.Errorf("loan repayments %v, want 500 on 3 and 4450 on 5", line.LoanRepayments)
+	}
+	for _, it := range line.Items {
+		if it.Code == "LOAN" && it.Amount == 4450 && it.Basis != "balance due on leaving, limited to remaining pay" {
+			t.Errorf("limited repayment basis %q", it.Basis)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestWorkingDays(t *testing.T) {
+	days := services.WorkingDays(day("2026-06-01"), day("2026-06-30"))
+	if len(days) != 22 {
+		t.Fatalf("June 2026 has %d working days, want 22", len(days))
+	}
+	if got := services.WorkingDays(day("2026-06-06"), day("2026-06-07")); len(got) != 0 {
+		t.Errorf("weekend has %d working days", len(got))
+	}
+}
+
+func TestCalculatePay(t *testing.T) {
+	// June 2026 has 22 working days; 66000 a year pays 5500 a month, 250 a working day.
+	june := services.WorkingDays(day("2026-06-01"), day("2026-06-30"))
+	base := func() services.PayInput {
+		dept := uint(3)
+		return services.PayInput{
+			Frequency:    models.PayMonthly,
+			WorkingDays:  june,
+			EmployedFrom: day("2025-01-01"),
+			Salaries:     []services.SalaryChange{{From: day("2025-01-01"), Annual: 66000}},
+			DepartmentID: &dept,
+		}
+	}
+	otherDept := uint(9)
+	cases := []struct {
+		name         string
+		in           func(*services.PayInput)
+		rules        []models.DeductionRule
+		employedDays int
+		gross, ded   float64
+		items        []string
+	}{
+		{
+			name:         "full month",
+			employedDays: 22, gross: 5500,
+			items: []string{"BASIC"},
+		},
+		{
+			name:         "joiner is prorated",
+			in:           func(in *services.PayInput) { in.EmployedFrom = day("2026-06-15") },
+			employedDays: 12, gross: 3000,
+			items: []string{"BASIC"},
+		},
+		{
+			name: "leaver is paid up to the last day",
+			in: func(in *services.PayInput) {
+				last := day("2026-06-10")
+				in.EmployedTo = &last
+			},
+			employedDays: 8, gross: 2000,
+			items: []string{"BASIC"},
+		},
+		{
+			name: "mid-period raise",
+			in: func(in *services.PayInput) {
+				in.Salaries = append(in.Salaries, services.SalaryChange{From: day("2026-06-15"), Annual: 79200})
+			},
+			employedDays: 22, gross: 6100,
+			items: []string{"BASIC"},
+		},
+		{
+			name: "loss of pay for absences and unpaid leave",
+			in: func(in *services.PayInput) {
+				// the weekend absence costs nothing; a day both absent and on unpaid leave counts once
+				in.Absences = []time.Time{day("2026-06-02"), day("2026-06-03"), day("2026-06-06"), day("2026-06-22")}
+				in.UnpaidLeave = []time.Time{day("2026-06-22")}
+			},
+			employedDays: 22, gross: 4750,
+			items: []string{"BASIC", "LOP_ABSENCE", "LOP_UNPAID_LEAVE"},
+		},
+		{
+			name: "overtime at the hourly rate",
+			in: func(in *services.PayInput) {
+				in.Overtime = []models.OvertimeEntry{{Date: day("2026-06-12"), Hours: 4, Multiplier: 1.5}}
+			},
+			employedDays: 22, gross: 5690.38,
+			items: []string{"BASIC", "OVERTIME"},
+		},
+		{
+			name: "deductions in order with caps and department scope",
+			rules: []models.DeductionRule{
+				{Code: "UNION", Kind: models.DeductionFixed, Rate: 50, Active: true, SortOrder: 2},
+				{Code: "PENSION", Kind: models.DeductionPercent, Rate: 5, Cap: 200, Active: true, SortOrder: 1},
+				{Code: "PARKING", Kind: models.DeductionFixed, Rate: 100, Active: true, DepartmentID: &otherDept},
+				{Code: "OLD", Kind: models.DeductionFixed, Rate: 100},
+			},
+			employedDays: 22, gross: 5500, ded: 250,
+			items: []string{"BASIC", "PENSION", "UNION"},
+		},
+		{
+			name:         "deductions never exceed gross pay",
+			in:           func(in *services.PayInput) { in.EmployedFrom = day("2026-06-30") },
+			rules:        []models.DeductionRule{{Code: "LOAN", Kind: models.DeductionFixed, Rate: 300, Active: true}},
+			employedDays: 1, gross: 250, ded: 250,
+			items: []string{"BASIC", "LOAN"},
+		},
+		{
+			name:         "not employed in the period",
+			in:           func(in *services.PayInput) { in.EmployedFrom = day("2026-07-01") },
+			rules:        []models.DeductionRule{{Code: "UNION", Kind: models.DeductionFixed, Rate: 50, Active: true}},
+			employedDays: 0, gross: 0,
+			items: []string{"BASIC"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			in := base()
+			if c.in != nil {
+				c.in(&in)
+			}
+			line, err := services.CalculatePay(in, c.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line.EmployedDays != c.employedDays || line.Gross != c.gross || line.Deductions != c.ded || line.Net != c.gross-c.ded {
+				t.Errorf("got employed %d, gross %.2f, deductions %.2f, net %.2f; want %d, %.2f, %.2f, %.2f",
+					line.EmployedDays, line.Gross, line.Deductions, line.Net, c.employedDays, c.gross, c.ded, c.gross-c.ded)
+			}
+			var codes []string
+			sum := 0.0
+			for _, it := range line.Items {
+				codes = append(codes, it.Code)
+				if it.Kind == models.PayrollEarning {
+					sum += it.Amount
+				}
+			}
+			if !reflect.DeepEqual(codes, c.items) {
+				t.Errorf("items %v, want %v", codes, c.items)
+			}
+			if diff := sum - line.Gross; diff > 0.005 || diff < -0.005 {
+				t.Errorf("earnings add up to %.2f, gross is %.2f", sum, line.Gross)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/pdf"
+	"github.com/example/hrms-backend/services"
+)
+
+// checkXref verifies that every cross-reference entry points at the start of its object.
+func checkXref(t *testing.T, doc []byte) {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(doc)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	start, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(doc[start:], []byte("xref\n0 ")) {
+		t.Fatalf("startxref %d does not point at the xref table", start)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(doc[start:], -1)
+	if len(entries) == 0 {
+		t.Fatal("empty xref table")
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := strconv.Itoa(i+1) + " 0 obj\n"; !bytes.HasPrefix(doc[off:], []byte(want)) {
+			t.Errorf("object %d: offset %d points at %q", i+1, off, doc[off:off+10])
+		}
+	}
+}
+
+func TestPDF(t *testing.T) {
+	var logo bytes.Buffer
+	if err := jpeg.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 4, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	build := func(password string) []byte {
+		d := pdf.New()
+		d.SetInfo("Statement (June)", "ACME")
+		d.SetPassword(password)
+		img, err := d.AddJPEG(logo.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Width != 4 || img.Height != 2 {
+			t.Errorf("image size %dx%d", img.Width, img.Height)
+		}
+		d.AddPage().Image(img, 10, 10, 40, 20)
+		d.AddPage().Text(10, 10, pdf.Helvetica, 10, "second page")
+		out, err := d.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	plain := build("")
+	checkXref(t, plain)
+	if !bytes.Contains(plain, []byte(`/Title (Statement \(June\))`)) || bytes.Contains(plain, []byte("/Encrypt")) {
+		t.Error("unprotected document should have a plain title and no encryption")
+	}
+	if !bytes.Contains(plain, []byte("/Count 2")) {
+		t.Error("page count")
+	}
+	protected := build("secret")
+	checkXref(t, protected)
+	if !bytes.Contains(protected, []byte("/Encrypt")) || !bytes.Contains(protected, []byte("/CFM /AESV2")) {
+		t.Error("protected document has no AES encryption dictionary")
+	}
+	if bytes.Contains(protected, []byte("Statement")) {
+		t.Error("protected document leaks its title")
+	}
+
+	if w := pdf.Width(pdf.Helvetica, 10, "Pay"); w != 17.23 {
+		t.Errorf("Width = %v, want 17.23", w)
+	}
+	if _, err := pdf.New().AddJPEG([]byte("not an image")); err == nil {
+		t.Error("AddJPEG accepted garbage")
+	}
+	if r, g, b, err := pdf.ParseColor("#FF8000"); err != nil || r != 1 || g != 128.0/255 || b != 0 {
+		t.Errorf("ParseColor = %v %v %v %v", r, g, b, err)
+	}
+	if _, _, _, err := pdf.ParseColor("orange"); err == nil {
+		t.Error("ParseColor accepted a name")
+	}
+}
+
+func TestLeaveBalances(t *testing.T) {
+	ents := []models.LeaveEntitlement{{LeaveType: models.LeaveAnnual, DaysPerYear: 20}, {LeaveType: models.LeaveSick, DaysPerYear: 10}}
+	leaves := []models.Leave{
+		{Type: models.LeaveAnnual, Status: models.LeaveApproved, StartDate: day("2025-12-30"), EndDate: day("2026-01-02")}, // 2 days in 2026
+		{Type: models.LeaveAnnual, Status: models.LeaveApproved, StartDate: day("2026-03-02"), EndDate: day("2026-03-06")},
+		{Type: models.LeaveAnnual, Status: models.LeavePending, StartDate: day("2026-04-01"), EndDate: day("2026-04-01")},
+		{Type: models.LeaveSick, Status: models.LeaveApproved, StartDate: day("2026-06-29"), EndDate: day("2026-07-02")}, // 2 days by June 30
+	}
+	got := services.LeaveBalances(ents, leaves, day("2026-06-30"))
+	want := []models.LeaveBalance{
+		{LeaveType: models.LeaveAnnual, Entitlement: 20, Taken: 7, Remaining: 13},
+		{LeaveType: models.LeaveSick, Entitlement: 10, Taken: 2, Remaining: 8},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("balances %+v, want %+v", got, want)
+	}
+}
+
+func TestRenderPayslip(t *testing.T) {
+	data := services.PayslipData{
+		Template: models.PayslipTemplate{CompanyName: "ACME", BrandColor: "#336699", ShowYTD: true, ShowLeaveBalances: true},
+		Period:   models.PayPeriod{Name: "June 2026", StartDate: day("2026-06-01"), EndDate: day("2026-06-30"), PayDate: day("2026-06-30")},
+		Line: models.PayrollLine{EmployeeID: 7, EmployeeName: "Ada Lovelace", Gross: 5000, Deductions: 600, Net: 4400, Items: []models.PayrollItem{
+			{Code: "BASIC", Label: "Basic pay", Kind: models.PayrollEarning, Amount: 5000},
+			{Code: "TAX", Label: "Income tax", Kind: models.PayrollDeduction, Amount: 600},
+		}},
+		YTD:         map[string]float64{"BASIC": 30000, "TAX": 3600, "GROSS": 30000, "DEDUCTIONS": 3600, "NET": 26400},
+		Balances:    []models.LeaveBalance{{LeaveType: models.LeaveAnnual, Entitlement: 20, Taken: 5, Remaining: 15}},
+		GeneratedAt: time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC),
+	}
+	// enough items to need a second page
+	for i := 0; i < 60; i++ {
+		data.Line.Items = append(data.Line.Items, models.PayrollItem{Code: "X" + strconv.Itoa(i), Label: "Allowance", Kind: models.PayrollEarning})
+	}
+	out, err := services.RenderPayslip(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkXref(t, out)
+	if !bytes.Contains(out, []byte("Ada Lovelace")) || !bytes.Contains(out, []byte("/Count 2")) {
+		t.Error("unprotected payslip should name the employee in its title and span two pages")
+	}
+
+	data.Password = "s3cret!"
+	out, err = services.RenderPayslip(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkXref(t, out)
+	if !bytes.Contains(out, []byte("/Encrypt")) || bytes.Contains(out, []byte("Ada Lovelace")) {
+		t.Error("protected payslip is not encrypted")
+	}
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/hrms-backend/formula"
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+)
+
+func TestFormula(t *testing.T) {
+	vars := map[string]float64{"BASIC": 4, "CTC": 600000}
+	cases := []struct {
+		src  string
+		want float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"-BASIC + 10", 6},
+		{"basic * 2", 8},
+		{"min(BASIC * 0.12, 1800)", 0.48},
+		{"max(1, 2, 3)", 3},
+		{"round(10 / 3, 2)", 3.33},
+		{"floor(2.7) + ceil(2.1) + abs(-1)", 6},
+		{"if(CTC > 600000, 2500, 1600)", 1600},
+		{"if(CTC >= 600000, 2500, 1600)", 2500},
+		{"if(1, 5, 1 / 0)", 5},
+		{"2 != 2", 0},
+	}
+	for _, c := range cases {
+		e, err := formula.Parse(c.src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", c.src, err)
+			continue
+		}
+		if got, err := e.Eval(vars); err != nil || got != c.want {
+			t.Errorf("Eval(%q) = %v, %v; want %v", c.src, got, err, c.want)
+		}
+	}
+
+	for _, src := range []string{"", "1 +", "(1", "1 = 2", "foo(1)", "min()", "round(1, 2, 3)", "1..2", "2 3"} {
+		if _, err := formula.Parse(src); err == nil {
+			t.Errorf("Parse(%q) succeeded", src)
+		}
+	}
+	e, _ := formula.Parse("1 / (BASIC - 4)")
+	if _, err := e.Eval(vars); !errors.Is(err, formula.ErrDivisionByZero) {
+		t.Errorf("division by zero: %v", err)
+	}
+	e, _ = formula.Parse("HRA + 1")
+	if _, err := e.Eval(vars); err == nil {
+		t.Error("unknown variable evaluated")
+	}
+	e, _ = formula.Parse("BASIC + hra * CTC - basic")
+	if got := e.Vars(); !reflect.DeepEqual(got, []string{"BASIC", "CTC", "HRA"}) {
+		t.Errorf("Vars = %v", got)
+	}
+}
+
+// standardStructure splits a monthly CTC into 40% basic, half of basic as housing, a fixed
+// transport allowance and the rest as special allowance, with a capped pension deduction.
+func standardStructure() *models.SalaryStructure {
+	return &models.SalaryStructure{ID: 1, Name: "Standard", Components: []models.SalaryComponent{
+		{Code: "PF", Name: "Pension", Kind: models.PayrollDeduction, Type: models.ComponentFormula, Formula: "min(BASIC * 0.12, 1800)", SortOrder: 5},
+		{Code: "SPECIAL", Name: "Special allowance", Kind: models.PayrollEarning, Type: models.ComponentBalance, SortOrder: 4},
+		{Code: "BASIC", Name: "Basic", Kind: models.PayrollEarning, Type: models.ComponentFormula, Formula: "CTC / 12 * 0.4", SortOrder: 1},
+		{Code: "HRA", Name: "Housing", Kind: models.PayrollEarning, Type: models.ComponentPercentOfBasic, Percent: 50, SortOrder: 2},
+		{Code: "TRANSPORT", Name: "Transport", Kind: models.PayrollEarning, Type: models.ComponentFixed, Amount: 1600, SortOrder: 3},
+	}}
+}
+
+func TestExpandSalary(t *testing.T) {
+	b, err := services.ExpandSalary(standardStructure(), 600000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	var order []string
+	for _, c := range b.Components {
+		got[c.Code] = c.Monthly
+		order = append(order, c.Code)
+	}
+	want := map[string]float64{"BASIC": 20000, "HRA": 10000, "TRANSPORT": 1600, "SPECIAL": 18400, "PF": 1800}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("components %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(order, []string{"BASIC", "HRA", "TRANSPORT", "SPECIAL", "PF"}) {
+		t.Errorf("components in order %v", order)
+	}
+	if b.MonthlyCTC != 50000 || b.GrossMonthly != 50000 || b.DeductionsMonthly != 1800 || b.NetMonthly != 48200 {
+		t.Errorf("totals %+v", b)
+	}
+
+	invalid := []struct {
+		name   string
+		change func(*models.SalaryStructure)
+		ctc    float64
+		want   string
+	}{
+		{"earnings exceed ctc", nil, 12000, "exceed the monthly CTC"},
+		{"missing basic", func(s *models.SalaryStructure) { s.Components[2].Code = "BASE" }, 600000, "BASIC"},
+		{"unknown reference", func(s *models.SalaryStructure) { s.Components[0].Formula = "BONUS * 2" }, 600000, "unknown component BONUS"},
+		{"cycle", func(s *models.SalaryStructure) { s.Components[2].Formula = "HRA * 2" }, 600000, "depend on each other"},
+		{"two balances", func(s *models.SalaryStructure) { s.Components[4].Type = models.ComponentBalance }, 600000, "only one balance"},
+		{"negative", func(s *models.SalaryStructure) { s.Components[0].Formula = "-1" }, 600000, "negative"},
+	}
+	for _, c := range invalid {
+		st := standardStructure()
+		if c.change != nil {
+			c.change(st)
+		}
+		if _, err := services.ExpandSalary(st, c.ctc); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: error %v, want %q", c.name, err, c.want)
+		}
+	}
+}
+
+func TestCalculatePayWithStructure(t *testing.T) {
+	// 66000 a year is 5500 a month, 250 a working day in June 2026. From June 15 the structure
+	// splits it into basic 2200, housing 1100, transport 1600 and special 600, less pension 264.
+	in := services.PayInput{
+		Frequency:    models.PayMonthly,
+		WorkingDays:  services.WorkingDays(day("2026-06-01"), day("2026-06-30")),
+		EmployedFrom: day("2025-01-01"),
+		Salaries:     []services.SalaryChange{{From: day("2025-01-01"), Annual: 66000}},
+		Structures:   []services.StructureChange{{From: day("2026-06-15"), Structure: standardStructure()}},
+		Absences:     []time.Time{day("2026-06-16")},
+	}
+	line, err := services.CalculatePay(in, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, it := range line.Items {
+		got[it.Code] = it.Amount
+	}
+	want := map[string]float64{
+		"BASIC":       3700, // 10 days of 5500 plus 12 days of 2200
+		"HRA":         600,
+		"TRANSPORT":   872.73,
+		"SPECIAL":     327.27,
+		"LOP_ABSENCE": -250,
+		"PF":          144,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("items %v, want %v", got, want)
+	}
+	if line.Gross != 5250 || line.Deductions != 144 || line.Net != 5106 {
+		t.Errorf("gross %.2f, deductions %.2f, net %.2f", line.Gross, line.Deductions, line.Net)
+	}
+}
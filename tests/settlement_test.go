@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+)
+
+func settlementInput() services.SettlementInput {
+	notice := day("2026-05-29")
+	return services.SettlementInput{
+		AnnualSalary:     73000, // 200 a calendar day
+		EmployedFrom:     day("2024-03-01"),
+		PayFrom:          day("2026-06-01"),
+		LastWorkingDay:   day("2026-06-12"),
+		Absences:         []time.Time{day("2026-06-03")},
+		Entitlements:     []models.LeaveEntitlement{{LeaveType: models.LeaveAnnual, DaysPerYear: 20}, {LeaveType: models.LeaveSick, DaysPerYear: 10}},
+		Leaves:           []models.Leave{{Type: models.LeaveAnnual, Status: models.LeaveApproved, StartDate: day("2026-03-02"), EndDate: day("2026-03-06")}},
+		NoticeDate:       &notice,
+		NoticePeriodDays: 30,
+		Loans:            []services.LoanDeduction{{LoanID: 4, Label: "Repayment of loan #4", Amount: 5000, Basis: "balance due on leaving"}},
+		Reimbursements:   []services.Reimbursement{{ClaimID: 9, Label: "Travel", Amount: 120}},
+	}
+}
+
+func TestCalculateSettlement(t *testing.T) {
+	st := services.CalculateSettlement(settlementInput())
+	// 10 of June's 22 working days to the 12th, one absent
+	if st.WorkedDays != 9 || st.UnpaidDays != 1 {
+		t.Errorf("worked %d unpaid %d, want 9 and 1", st.WorkedDays, st.UnpaidDays)
+	}
+	// 163 days into the year accrue 8.93 of 20 days; 5 taken; 14 of 30 days' notice served
+	// the loan is recovered from the 194.64 left after the shortfall
+	want := map[string]float64{"SALARY": 2488.64, "LEAVE_ENCASHMENT": 786, "NOTICE_SHORTFALL": 3200, "LOAN": 194.64, "REIMBURSEMENT": 120}
+	if len(st.Items) != len(want) {
+		t.Errorf("%d items, want %d: %+v", len(st.Items), len(want), st.Items)
+	}
+	for _, it := range st.Items {
+		if it.Amount != want[it.Code] {
+			t.Errorf("%s %v, want %v", it.Code, it.Amount, want[it.Code])
+		}
+	}
+	if st.NoticeServedDays != 14 || st.ShortfallDays != 16 {
+		t.Errorf("notice served %d short %d, want 14 and 16", st.NoticeServedDays, st.ShortfallDays)
+	}
+	if st.Earnings != 3274.64 || st.Deductions != 3394.64 || st.Reimbursements != 120 || st.Net != 0 {
+		t.Errorf("earnings %v deductions %v reimbursements %v net %v", st.Earnings, st.Deductions, st.Reimbursements, st.Net)
+	}
+	// the rest of the loan stays outstanding
+	if st.LoanRepayments[4] != 194.64 {
+		t.Errorf("loan repayments %v, want 194.64 on 4", st.LoanRepayments)
+	}
+	for _, it := range st.Items {
+		if it.Code == "LOAN" && !strings.Contains(it.Basis, "4805.36 stays outstanding") {
+			t.Errorf("limited loan basis %q", it.Basis)
+		}
+	}
+
+	// a shortfall beyond what is paid is owed by the employee, and nothing is left for the loan
+	in := settlementInput()
+	in.NoticePeriodDays = 90
+	st = services.CalculateSettlement(in)
+	if st.ShortfallDays != 76 || st.Net != 3274.64+120-15200 || len(st.LoanRepayments) != 0 {
+		t.Errorf("shortfall %d net %v loan repayments %v, want 76, %v and none", st.ShortfallDays, st.Net, st.LoanRepayments, 3274.64+120-15200)
+	}
+}
+
+func TestCalculateSettlementMidYearJoiner(t *testing.T) {
+	st := services.CalculateSettlement(services.SettlementInput{
+		AnnualSalary:   73000,
+		EmployedFrom:   day("2026-09-01"),
+		PayFrom:        day("2026-09-01"),
+		LastWorkingDay: day("2026-09-30"),
+		Entitlements:   []models.LeaveEntitlement{{LeaveType: models.LeaveAnnual, DaysPerYear: 20}},
+	})
+	// 30 days employed accrue 1.64 days, not the 14.96 accrued since January
+	want := map[string]float64{"SALARY": 6083.33, "LEAVE_ENCASHMENT": 328}
+	if len(st.Items) != len(want) {
+		t.Errorf("%d items, want %d: %+v", len(st.Items), len(want), st.Items)
+	}
+	for _, it := range st.Items {
+		if it.Amount != want[it.Code] {
+			t.Errorf("%s %v, want %v", it.Code, it.Amount, want[it.Code])
+		}
+	}
+}
+
+func TestCalculateSettlementExcessLeave(t *testing.T) {
+	in := settlementInput()
+	in.Leaves = append(in.Leaves, models.Leave{Type: models.LeaveAnnual, Status: models.LeaveApproved, StartDate: day("2026-01-05"), EndDate: day("2026-01-11")})
+	in.NoticeDate = nil
+	in.NoticePeriodDays = 0
+	in.Loans[0].Amount = 100
+	in.Adjustments = []models.PayrollItem{{Code: "ADJUSTMENT", Label: "Retention bonus", Kind: models.PayrollEarning, Amount: 1000}}
+	st := services.CalculateSettlement(in)
+	// 12 days taken of 8.93 accrued
+	for _, it := range st.Items {
+		switch it.Code {
+		case "LEAVE_ENCASHMENT", "NOTICE_SHORTFALL":
+			t.Errorf("unexpected %s item", it.Code)
+		case "LEAVE_EXCESS":
+			if it.Kind != models.PayrollDeduction || it.Amount != 614 {
+				t.Errorf("leave excess %v %s, want a 614 deduction", it.Amount, it.Kind)
+			}
+		}
+	}
+	// the loan is recovered in full
+	if st.LoanRepayments[4] != 100 || st.Net != 2488.64+1000-614+120-100 {
+		t.Errorf("loan repayments %v net %v, want 100 on 4 and %v", st.LoanRepayments, st.Net, 2488.64+1000-614+120-100)
+	}
+}
+
+func TestRenderSettlement(t *testing.T) {
+	st := services.CalculateSettlement(settlementInput())
+	st.Status = models.SettlementFinalized
+	doc, err := services.RenderSettlement(&services.SettlementData{
+		Template:    models.PayslipTemplate{CompanyName: "Acme Ltd", BrandColor: "#336699"},
+		Settlement:  st,
+		Employee:    models.Employee{ID: 12, Name: "Ada Lovelace", Position: "Engineer", Department: "R&D"},
+		GeneratedAt: time.Date(2026, 6, 15, 9, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(doc, []byte("%PDF-")) {
+		t.Fatal("not a PDF")
+	}
+	checkXref(t, doc)
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/shaping"
+	"github.com/example/hrms-backend/utils"
+)
+
+func TestEmployeeFieldVisible(t *testing.T) {
+	cases := []struct {
+		aud   models.Audience
+		role  models.UserRole
+		field string
+		want  bool
+	}{
+		{models.AudienceSelf, models.RoleEmployee, "salary", true},
+		{models.AudienceSelf, models.RoleEmployee, "bank_account_number", true},
+		{models.AudienceFull, models.RoleHR, "salary", true},
+		{models.AudienceFull, models.RoleHR, "termination_reason", true},
+		{models.AudienceFull, models.RoleAuditor, "salary", false},
+		{models.AudienceFull, models.RoleAuditor, "bank_routing_code", false},
+		{models.AudienceFull, models.RoleAuditor, "termination_reason", true},
+		{models.AudienceManager, models.RoleManager, "grade", true},
+		{models.AudienceManager, models.RoleManager, "emergency_contacts", true},
+		{models.AudienceManager, models.RoleManager, "salary", false},
+		{models.AudienceManager, models.RoleManager, "personal_email", false},
+		{models.AudiencePeer, models.RoleEmployee, "name", true},
+		{models.AudiencePeer, models.RoleEmployee, "department", true},
+		{models.AudiencePeer, models.RoleEmployee, "grade", false},
+		{models.AudiencePeer, models.RoleEmployee, "phone", false},
+		{models.AudiencePeer, models.RoleEmployee, "custom_fields", false},
+	}
+	for _, c := range cases {
+		if got := models.EmployeeFieldVisible(c.aud, c.role, c.field); got != c.want {
+			t.Errorf("EmployeeFieldVisible(%s, %s, %s) = %v, want %v", c.aud, c.role, c.field, got, c.want)
+		}
+	}
+}
+
+// shapedRecord is a record type with a test policy: only its owner sees Secret.
+type shapedRecord struct {
+	ID     uint   `json:"id"`
+	Owner  uint   `json:"owner"`
+	Secret string `json:"secret"`
+	Note   string `json:"note,omitempty"`
+}
+
+type shapedWrapper struct {
+	Title string                  `json:"title"`
+	Main  *shapedRecord           `json:"main"`
+	Bykey map[string]shapedRecord `json:"by_key"`
+	shapedEmbedded
+}
+
+type shapedEmbedded struct {
+	Items []shapedRecord `json:"items"`
+}
+
+func init() {
+	shaping.Register(shapedRecord{}, func(v *shaping.Viewer, rec interface{}) (func(string) bool, error) {
+		r := rec.(*shapedRecord)
+		r.Note = "seen by " + string(v.Role)
+		if r.Owner == v.UserID {
+			return nil, nil
+		}
+		return func(f string) bool { return f != "secret" }, nil
+	})
+}
+
+func toJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestShape(t *testing.T) {
+	v := shaping.NewViewer(7, models.RoleEmployee)
+	mine := shapedRecord{ID: 1, Owner: 7, Secret: "a"}
+	theirs := shapedRecord{ID: 2, Owner: 8, Secret: "b"}
+	data := shapedWrapper{
+		Title:          "t",
+		Main:           &theirs,
+		Bykey:          map[string]shapedRecord{"x": mine},
+		shapedEmbedded: shapedEmbedded{Items: []shapedRecord{mine, theirs}},
+	}
+	got, err := shaping.Shape(v, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"by_key":{"x":{"id":1,"note":"seen by EMPLOYEE","owner":7,"secret":"a"}},` +
+		`"items":[{"id":1,"note":"seen by EMPLOYEE","owner":7,"secret":"a"},{"id":2,"note":"seen by EMPLOYEE","owner":8}],` +
+		`"main":{"id":2,"note":"seen by EMPLOYEE","owner":8},"title":"t"}`
+	if s := toJSON(t, got); s != want {
+		t.Errorf("Shape =\n%s\nwant\n%s", s, want)
+	}
+	if theirs.Note != "" || theirs.Secret != "b" {
+		t.Error("policy modified the original record")
+	}
+
+	// values without shaped records, and responses without a viewer, pass through unchanged
+	plain := map[string]int{"a": 1}
+	if got, _ := shaping.Shape(v, plain); !reflect.DeepEqual(got, plain) {
+		t.Errorf("unshaped value changed: %v", got)
+	}
+	if got, _ := shaping.Shape(nil, data); !reflect.DeepEqual(got, data) {
+		t.Error("value changed without a viewer")
+	}
+}
+
+func TestViewerMemo(t *testing.T) {
+	v := shaping.NewViewer(1, models.RoleManager)
+	calls := 0
+	load := func() (interface{}, error) { calls++; return calls, nil }
+	a, _ := v.Memo("k", load)
+	b, _ := v.Memo("k", load)
+	if a != 1 || b != 1 || calls != 1 {
+		t.Errorf("Memo loaded %d times, returned %v and %v", calls, a, b)
+	}
+}
+
+func TestSuccessShapesForViewer(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := shaping.NewWriter(rec, shaping.NewViewer(7, models.RoleEmployee))
+	utils.Success(w, "ok", []shapedRecord{{ID: 2, Owner: 8, Secret: "b"}}, http.StatusOK)
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0]["secret"] != nil || resp.Data[0]["id"] != 2.0 {
+		t.Errorf("unexpected response %s", rec.Body.String())
+	}
+}
@@ -304,6 +304,14 @@ func TestEmployeeCRUD(t *testing.T) {
 		requireStatus(t, resp, http.StatusNoContent, "Delete employee")
 		t.Log("✅ Delete Employee OK")
 	}
+
+	// Delete is a soft termination; terminating again conflicts
+	{
+		url := fmt.Sprintf(baseURL+"/employees/%d/terminate", createdEmployeeID)
+		resp := mustHTTP(t, http.MethodPost, url, map[string]any{"reason": "duplicate"}, hrToken)
+		requireStatus(t, resp, http.StatusConflict, "Terminate terminated employee")
+		t.Log("✅ Soft Delete OK")
+	}
 }
 
 func TestAttendanceFlow(t *testing.T) {
@@ -321,7 +329,7 @@ func TestAttendanceFlow(t *testing.T) {
 			"salary":     75000.0,
 		}
 		resp := mustHTTP(t, http.MethodPost, baseURL+"/employees", body, hrToken)
-		// 201 if created; 400 if duplicate on unique user_id. Accept 201 or 400 here.
+		// 201 if created; 400 if the user already has an active employee. Accept 201 or 400 here.
 		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusBadRequest {
 			requireStatus(t, resp, http.StatusCreated, "Create employee for attendance setup")
 		} else {
@@ -442,6 +450,34 @@ func TestLeaveFlow(t *testing.T) {
 			t.Logf("⚠️ Approve leave returned %d: %s", resp.StatusCode, string(b))
 		}
 	}
+
+	// Rejection requires a reason, which is returned to the employee
+	{
+		body := map[string]any{
+			"start_date": time.Now().AddDate(0, 0, 10).Format("2006-01-02"),
+			"end_date":   time.Now().AddDate(0, 0, 10).Format("2006-01-02"),
+			"reason":     "Errand",
+		}
+		resp := mustHTTP(t, http.MethodPost, baseURL+"/leaves", body, empToken)
+		requireStatus(t, resp, http.StatusCreated, "Apply leave for rejection")
+		parsed := readJSON[map[string]any](t, resp)
+		id, _ := parsed.Data["id"].(float64)
+		url := fmt.Sprintf(baseURL+"/leaves/%d/reject", int64(id))
+
+		resp = mustHTTP(t, http.MethodPost, url, map[string]any{}, hrToken)
+		requireStatus(t, resp, http.StatusBadRequest, "Reject leave without reason")
+		_ = resp.Body.Close()
+
+		resp = mustHTTP(t, http.MethodPost, url, map[string]any{"reason": "Release week"}, hrToken)
+		requireStatus(t, resp, http.StatusOK, "Reject leave with reason")
+		_ = resp.Body.Close()
+
+		url = fmt.Sprintf(baseURL+"/leaves/%d/comments", int64(id))
+		resp = mustHTTP(t, http.MethodPost, url, map[string]any{"body": "Can I take it next week instead?"}, empToken)
+		requireStatus(t, resp, http.StatusCreated, "Comment on leave")
+		_ = resp.Body.Close()
+		t.Log("✅ Reject Leave With Reason OK")
+	}
 }
 
 func TestDataVerificationAndSummary(t *testing.T) {
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/example/hrms-backend/models"
+	"github.com/example/hrms-backend/services"
+	"github.com/example/hrms-backend/statutory"
+)
+
+// referenceRules: nothing on the first 10000, 10% to 40000, 20% above, with a 4% surtax;
+// social security 6% of gross up to 60000 a year and a pre-tax pension of 5% of basic, at most
+// 200 a period.
+const referenceRules = `{
+	"standard_deduction": 5000,
+	"slabs": [{"up_to": 10000, "rate": 0}, {"up_to": 40000, "rate": 10}, {"up_to": 0, "rate": 20}],
+	"surtax_percent": 4,
+	"exemptions": [{"category": "INVEST", "label": "Investments", "cap": 3000}],
+	"contributions": [
+		{"code": "SOCIAL_SECURITY", "name": "Social security", "rate": 6, "annual_base_cap": 60000},
+		{"code": "PENSION", "name": "Pension", "base": "BASIC", "rate": 5, "period_cap": 200, "pre_tax": true}
+	]
+}`
+
+func parseRules(t *testing.T, params string) statutory.Rules {
+	t.Helper()
+	rules, err := statutory.Parse("PROGRESSIVE", json.RawMessage(params))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return rules
+}
+
+func TestProgressiveTax(t *testing.T) {
+	p := parseRules(t, referenceRules).(*statutory.ProgressiveParams)
+	cases := []struct{ income, tax float64 }{
+		{0, 0},
+		{5000, 0},
+		{10000, 0},
+		{25000, 1500},
+		{40000, 3000},
+		{60000, 7000},
+	}
+	for _, c := range cases {
+		if got := p.Tax(c.income); math.Abs(got-c.tax) > 0.005 {
+			t.Errorf("Tax(%g) = %g, want %g", c.income, got, c.tax)
+		}
+	}
+	if got := p.Categories(); !reflect.DeepEqual(got, []string{"INVEST"}) {
+		t.Errorf("categories %v", got)
+	}
+}
+
+func TestProgressiveCalculate(t *testing.T) {
+	rules := parseRules(t, referenceRules)
+	monthly := func() statutory.Input {
+		return statutory.Input{PeriodsPerYear: 12, PeriodsRemaining: 12, Gross: 5000, Earnings: map[string]float64{"BASIC": 5000}}
+	}
+	cases := []struct {
+		name string
+		in   func(*statutory.Input)
+		want map[string]float64
+	}{
+		{
+			// taxable (5000 - 200) * 12 - 5000 = 52600; tax 5520 plus surtax 5740.80
+			name: "first period of the year",
+			want: map[string]float64{"SOCIAL_SECURITY": 300, "PENSION": 200, "INCOME_TAX": 478.40},
+		},
+		{
+			// the declaration is capped at 3000: taxable 49600, tax 4920 plus surtax 5116.80
+			name: "declarations reduce taxable income up to the cap",
+			in:   func(in *statutory.Input) { in.Declarations = map[string]float64{"INVEST": 5000, "OTHER": 900} },
+			want: map[string]float64{"SOCIAL_SECURITY": 300, "PENSION": 200, "INCOME_TAX": 426.40},
+		},
+		{
+			// taxable 58000 - 1200 + 4800 * 6 - 5000 = 80600; tax 11120 plus surtax 11564.80,
+			// less 2000 withheld over 6 periods; social security only on the 2000 left to the limit
+			name: "cumulative with year to date",
+			in: func(in *statutory.Input) {
+				in.PeriodsRemaining = 6
+				in.YTD = map[string]float64{"GROSS": 58000, "PENSION": 1200, "INCOME_TAX": 2000}
+			},
+			want: map[string]float64{"SOCIAL_SECURITY": 120, "PENSION": 200, "INCOME_TAX": 1594.13},
+		},
+		{
+			name: "wage base limit reached",
+			in: func(in *statutory.Input) {
+				in.PeriodsRemaining = 2
+				in.YTD = map[string]float64{"GROSS": 70000, "PENSION": 2000, "INCOME_TAX": 9000}
+			},
+			// taxable 70000 - 2000 + 4800 * 2 - 5000 = 72600; tax 9520 plus surtax 9900.80
+			want: map[string]float64{"PENSION": 200, "INCOME_TAX": 450.40},
+		},
+		{
+			name: "tax already withheld in full",
+			in: func(in *statutory.Input) {
+				in.YTD = map[string]float64{"INCOME_TAX": 99999}
+			},
+			want: map[string]float64{"SOCIAL_SECURITY": 300, "PENSION": 200},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			in := monthly()
+			if c.in != nil {
+				c.in(&in)
+			}
+			ds, err := rules.Calculate(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]float64{}
+			for _, d := range ds {
+				got[d.Code] = d.Amount
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("deductions %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestStatutoryParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"last slab limited":  `{"slabs": [{"up_to": 1000, "rate": 10}]}`,
+		"slabs descending":   `{"slabs": [{"up_to": 1000, "rate": 0}, {"up_to": 500, "rate": 10}, {"up_to": 0, "rate": 20}]}`,
+		"rate above 100":     `{"slabs": [{"up_to": 0, "rate": 120}]}`,
+		"unknown field":      `{"slab": []}`,
+		"duplicate code":     `{"contributions": [{"code": "INCOME_TAX", "rate": 1}]}`,
+		"invalid base":       `{"contributions": [{"code": "SS", "base": "gross", "rate": 1}]}`,
+		"negative cap":       `{"exemptions": [{"category": "INVEST", "cap": -1}]}`,
+		"duplicate category": `{"exemptions": [{"category": "INVEST"}, {"category": "INVEST"}]}`,
+	}
+	for name, params := range cases {
+		if _, err := statutory.Parse("PROGRESSIVE", json.RawMessage(params)); err == nil {
+			t.Errorf("%s: parsed %s", name, params)
+		}
+	}
+	if _, err := statutory.Parse("NOPE", json.RawMessage(`{}`)); err == nil {
+		t.Error("unknown engine parsed")
+	}
+}
+
+func TestTaxYear(t *testing.T) {
+	cases := []struct {
+		day        string
+		startMonth int
+		start, end string
+	}{
+		{"2026-06-01", 1, "2026-01-01", "2026-12-31"},
+		{"2026-03-15", 4, "2025-04-01", "2026-03-31"},
+		{"2026-04-01", 4, "2026-04-01", "2027-03-31"},
+	}
+	for _, c := range cases {
+		start, end := services.TaxYear(day(c.day), time.Month(c.startMonth))
+		if !start.Equal(day(c.start)) || !end.Equal(day(c.end)) {
+			t.Errorf("TaxYear(%s, %d) = %s..%s, want %s..%s", c.day, c.startMonth, start.Format("2006-01-02"), end.Format("2006-01-02"), c.start, c.end)
+		}
+	}
+	periods := []struct {
+		start string
+		freq  models.PayFrequency
+		want  int
+	}{
+		{"2026-01-01", models.PayMonthly, 12},
+		{"2026-06-01", models.PayMonthly, 7},
+		{"2026-12-01", models.PayMonthly, 1},
+		{"2026-06-01", models.PayWeekly, 30},
+		{"2026-12-28", models.PayWeekly, 1},
+	}
+	for _, p := range periods {
+		if got := services.PeriodsRemaining(day(p.start), day("2026-12-31"), p.freq); got != p.want {
+			t.Errorf("PeriodsRemaining(%s, %s) = %d, want %d", p.start, p.freq, got, p.want)
+		}
+	}
+}
+
+func TestCalculatePayWithStatutory(t *testing.T) {
+	rules := parseRules(t, `{"slabs": [{"up_to": 0, "rate": 10}], "contributions": [{"code": "PENSION", "rate": 5}]}`)
+	in := services.PayInput{
+		Frequency:    models.PayMonthly,
+		WorkingDays:  services.WorkingDays(day("2026-06-01"), day("2026-06-30")),
+		EmployedFrom: day("2025-01-01"),
+		Salaries:     []services.SalaryChange{{From: day("2025-01-01"), Annual: 66000}},
+		Statutory:    services.StatutoryInput{Rules: rules, PeriodsRemaining: 7},
+	}
+	line, err := services.CalculatePay(in, []models.DeductionRule{{Code: "UNION", Name: "Union dues", Kind: models.DeductionFixed, Rate: 100, Active: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 5500 gross: pension 275, tax 10% of 5500 * 7 over 7 periods, then the union dues
+	var codes []string
+	for _, it := range line.Items {
+		if it.Kind == models.PayrollDeduction {
+			codes = append(codes, it.Code)
+		}
+	}
+	if want := []string{"PENSION", "INCOME_TAX", "UNION"}; !reflect.DeepEqual(codes, want) {
+		t.Errorf("deductions %v, want %v", codes, want)
+	}
+	if line.Gross != 5500 || line.Deductions != 925 || line.Net != 4575 {
+		t.Errorf("gross %.2f deductions %.2f net %.2f, want 5500 925 4575", line.Gross, line.Deductions, line.Net)
+	}
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+// DateLayout is the wire format for calendar dates in requests.
+const DateLayout = "2006-01-02"
+
+func ParseDate(s string) (time.Time, error) {
+	return time.Parse(DateLayout, strings.TrimSpace(s))
+}
+
+// ParseOptionalDate returns nil for a blank string.
+func ParseOptionalDate(s string) (*time.Time, error) {
+	if IsBlank(s) {
+		return nil, nil
+	}
+	t, err := ParseDate(s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// AddMonths moves d by months, keeping the day of the month where the target month has it and
+// the month's last day otherwise.
+func AddMonths(d time.Time, months int) time.Time {
+	y, m, day := d.Date()
+	first := time.Date(y, m+time.Month(months), 1, 0, 0, 0, 0, d.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
@@ -2,25 +2,61 @@ package utils
 
 import (
     "encoding/json"
+    "log"
     "net/http"
+
+    "github.com/example/hrms-backend/shaping"
 )
 
 type APIResponse struct {
     Status  bool        `json:"status"`
     Message string      `json:"message"`
     Data    interface{} `json:"data,omitempty"`
+    Meta    interface{} `json:"meta,omitempty"`
 }
 
+// Success, SuccessWithMeta and ErrorWithData redact data for the caller (see package shaping)
+// when w belongs to an authenticated request.
 func Success(w http.ResponseWriter, message string, data interface{}, code int) {
+    data, ok := shape(w, data)
+    if !ok { return }
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(code)
     _ = json.NewEncoder(w).Encode(APIResponse{Status: true, Message: message, Data: data})
 }
 
+// SuccessWithMeta is Success plus metadata such as pagination totals.
+func SuccessWithMeta(w http.ResponseWriter, message string, data interface{}, meta interface{}, code int) {
+    data, ok := shape(w, data)
+    if !ok { return }
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(code)
+    _ = json.NewEncoder(w).Encode(APIResponse{Status: true, Message: message, Data: data, Meta: meta})
+}
+
 func Error(w http.ResponseWriter, message string, code int) {
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(code)
     _ = json.NewEncoder(w).Encode(APIResponse{Status: false, Message: message})
 }
 
+// ErrorWithData is Error plus a payload describing the failure, e.g. per-row validation results.
+func ErrorWithData(w http.ResponseWriter, message string, data interface{}, code int) {
+    data, ok := shape(w, data)
+    if !ok { return }
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(code)
+    _ = json.NewEncoder(w).Encode(APIResponse{Status: false, Message: message, Data: data})
+}
 
+// shape redacts data for the viewer of w. When redaction fails nothing is disclosed: an error
+// response is written instead and ok is false.
+func shape(w http.ResponseWriter, data interface{}) (interface{}, bool) {
+    shaped, err := shaping.Shape(shaping.ViewerOf(w), data)
+    if err != nil {
+        log.Printf("response shaping failed: %v", err)
+        Error(w, "error", http.StatusInternalServerError)
+        return nil, false
+    }
+    return shaped, true
+}